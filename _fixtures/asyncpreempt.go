@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+var counters [4]int
+
+func spin(i int) {
+	for {
+		counters[i]++
+	}
+}
+
+func done() {
+	fmt.Println(counters)
+}
+
+func main() {
+	for i := range counters {
+		go spin(i)
+	}
+	for i := 0; i < 10; i++ {
+		// stopping the world requires preempting the spinning goroutines
+		runtime.GC()
+	}
+	done()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+//go:noinline
+func work(n int) int {
+	return n + 1
+}
+
+//go:noinline
+func spawned(pid int) {
+	fmt.Println("spawned", pid)
+}
+
+func start(arg string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0], arg)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.SysProcAttr = &syscall.SysProcAttr{Pdeathsig: syscall.SIGKILL}
+	if err := cmd.Start(); err != nil {
+		panic(err)
+	}
+	return cmd
+}
+
+func main() {
+	switch os.Args[1] {
+	case "worker", "other":
+		for i := 0; ; i++ {
+			work(i)
+			time.Sleep(10 * time.Millisecond)
+		}
+	case "master":
+		start("worker")
+		fmt.Println("ready")
+		// more children are started once the file passed as argument exists
+		for {
+			if _, err := os.Stat(os.Args[2]); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		spawned(start("worker").Process.Pid)
+		spawned(start("other").Process.Pid)
+		for {
+			time.Sleep(time.Second)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type astruct struct {
+	A int16
+	B uint32
+	C *int64
+}
+
+var (
+	gi8  int8    = -2
+	gi16 int16   = -0x102
+	gi32 int32   = -0x1020304
+	gi64 int64   = 0x0102030405060708
+	gu16 uint16  = 0xbeef
+	gu32 uint32  = 0xdeadbeef
+	gu64 uint64  = 0xfedcba9876543210
+	gf32 float32 = 1.5
+	gf64 float64 = -2.25
+	gstr         = "a big endian string"
+	garr         = [3]int32{1, -2, 3}
+	gsl          = []int64{10, 20, 30}
+	gs           = astruct{A: -7, B: 0x01020304, C: &gi64}
+	gptr         = &gs
+)
+
+func main() {
+	runtime.Breakpoint()
+	fmt.Println(gi8, gi16, gi32, gi64, gu16, gu32, gu64, gf32, gf64, gstr, garr, gsl, gs, gptr)
+}
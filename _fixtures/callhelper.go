@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sync/atomic"
+)
+
+var runs int32
+
+//go:noinline
+func square(x int) int {
+	return x * x
+}
+
+func main() {
+	if atomic.AddInt32(&runs, 1) != 1 {
+		// main.main is the start function of the call helper goroutine of
+		// the debugger, which must never execute it.
+		os.Exit(3)
+	}
+	fmt.Println(square(2))
+	panic("boom")
+}
@@ -0,0 +1,29 @@
+package main
+
+// #cgo CFLAGS: -g0 -O2 -fomit-frame-pointer -fno-asynchronous-unwind-tables
+/*
+#include <stddef.h>
+
+__attribute__((noinline)) void sigsegv(int x) {
+	int *p = NULL;
+	*(volatile int *)p = x;
+}
+
+__attribute__((noinline)) void testfn(int x) {
+	sigsegv(x + 1);
+	__asm__ volatile("");
+}
+*/
+import "C"
+
+import "runtime"
+
+func gofunc(x int) {
+	localvar := x * 2
+	C.testfn(C.int(localvar))
+	runtime.KeepAlive(localvar)
+}
+
+func main() {
+	gofunc(21)
+}
@@ -0,0 +1,21 @@
+package main
+
+// #cgo CFLAGS: -g -Wall -O0
+/*
+#include <stdlib.h>
+
+static int cmp(const void *a, const void *b) {
+	int *p = NULL;
+	*p = *(const int *)a;
+	return 0;
+}
+void sortints(void) {
+	int v[] = {2, 1};
+	qsort(v, 2, sizeof(int), cmp);
+}
+*/
+import "C"
+
+func main() {
+	C.sortints()
+}
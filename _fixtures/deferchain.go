@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type closer struct {
+	name string
+}
+
+func (c *closer) close() {
+	fmt.Println("closing", c.name)
+}
+
+func report(n int, msg string) {
+	fmt.Println(n, msg)
+}
+
+func work(c *closer) {
+	count := 3
+	defer func() {
+		fmt.Println("done", count)
+	}()
+	count++
+	runtime.Breakpoint()
+}
+
+func main() {
+	n := 42
+	msg := "cleanup"
+	c := &closer{"file"}
+	defer report(n, msg)
+	defer c.close()
+	work(c)
+}
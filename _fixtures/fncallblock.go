@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+var ch = make(chan int)
+var release bool
+
+func block() int {
+	return <-ch
+}
+
+func releaser() {
+	for !release {
+		time.Sleep(10 * time.Millisecond)
+	}
+	ch <- 1
+}
+
+func done() {
+	fmt.Println("done")
+}
+
+func main() {
+	go releaser()
+	runtime.Breakpoint()
+	done()
+	fmt.Println(block, release)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+var ch = make(chan int)
+var done = make(chan bool)
+
+func double(n int) int {
+	return 2 * n
+}
+
+func waiter(x int) {
+	y := <-ch
+	fmt.Println(x, y)
+	done <- true
+}
+
+func main() {
+	go waiter(5)
+	time.Sleep(100 * time.Millisecond)
+	runtime.Breakpoint()
+	ch <- 1
+	<-done
+}
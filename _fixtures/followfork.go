@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+//go:noinline
+func child(n int) int {
+	return n * 2
+}
+
+//go:noinline
+func parent(pid int) {
+	fmt.Println("parent of", pid)
+}
+
+//go:noinline
+func execed() {
+	fmt.Println("execed", os.Getpid())
+}
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "execed" {
+		execed()
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "exec" {
+		exe, _ := os.Executable()
+		err := syscall.Exec(exe, []string{exe, "execed"}, os.Environ())
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	// The child of a raw fork only has the thread that called fork, it
+	// does not use the runtime.
+	syscall.ForkLock.Lock()
+	pid, _, errno := syscall.RawSyscall6(syscall.SYS_CLONE, uintptr(syscall.SIGCHLD), 0, 0, 0, 0, 0)
+	if errno != 0 {
+		panic(errno)
+	}
+	if pid == 0 {
+		syscall.RawSyscall(syscall.SYS_EXIT_GROUP, uintptr(child(21)-42), 0, 0)
+	}
+	syscall.ForkLock.Unlock()
+	parent(int(pid))
+	var ws syscall.WaitStatus
+	syscall.Wait4(int(pid), &ws, 0, nil)
+	fmt.Println("child exited with", ws.ExitStatus())
+}
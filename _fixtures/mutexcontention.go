@@ -0,0 +1,29 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"time"
+)
+
+var mu sync.Mutex
+
+func contend(wg *sync.WaitGroup) {
+	for i := 0; i < 100; i++ {
+		mu.Lock()
+		time.Sleep(10 * time.Microsecond)
+		mu.Unlock()
+	}
+	wg.Done()
+}
+
+func main() {
+	runtime.SetMutexProfileFraction(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go contend(&wg)
+	}
+	wg.Wait()
+	runtime.Breakpoint()
+}
@@ -0,0 +1,13 @@
+package main
+
+import "fmt"
+
+// new shadows the builtin of the same name.
+func new(n int) int {
+	return n * 2
+}
+
+func main() {
+	n := 21
+	fmt.Println(new(n))
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Fprintln(os.Stdout, "hello stdout")
+	fmt.Fprintln(os.Stderr, "hello stderr")
+}
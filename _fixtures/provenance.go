@@ -0,0 +1,16 @@
+package main
+
+//go:noinline
+func compute(a int) int {
+	return a * 3
+}
+
+//go:noinline
+func sink(a, b int) {
+}
+
+func main() {
+	x := compute(2)
+	y := 7
+	sink(x, y)
+}
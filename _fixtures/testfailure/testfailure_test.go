@@ -0,0 +1,13 @@
+package testfailure
+
+import "testing"
+
+func TestPass(t *testing.T) {
+}
+
+func TestFailure(t *testing.T) {
+	t.Run("subtest", func(t *testing.T) {
+		t.Logf("checking")
+		t.Errorf("expected %d\ngot %d", 1, 2)
+	})
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"time"
+)
+
+func main() {
+	start := time.Date(2021, time.June, 1, 12, 0, 0, 0, time.UTC)
+	deadline := start.Add(2 * time.Second)
+	now := time.Now()
+	elapsed := 750 * time.Millisecond
+	runtime.Breakpoint()
+	fmt.Println(start, deadline, now, elapsed)
+}
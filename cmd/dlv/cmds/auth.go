@@ -0,0 +1,137 @@
+package cmds
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+
+	"github.com/go-delve/delve/service"
+	"github.com/go-delve/delve/service/rpc2"
+)
+
+// apiAuthTokenEnv is the environment variable used as the authentication
+// token when --api-auth-token is not specified, it keeps the token out of
+// the command line of Delve, which other users can read.
+const apiAuthTokenEnv = "DLV_API_AUTH_TOKEN"
+
+var (
+	// apiAuthToken is the token clients must authenticate with, see
+	// service.Config.AuthToken.
+	apiAuthToken string
+	// tlsCert and tlsKey are the certificate, and its private key, used by
+	// the headless server to serve TLS connections.
+	tlsCert, tlsKey string
+	// tlsCA is the certificate of the authority used to verify the
+	// certificate of the headless server the client connects to.
+	tlsCA string
+)
+
+// authToken returns the authentication token specified with
+// --api-auth-token or with the DLV_API_AUTH_TOKEN environment variable.
+func authToken() string {
+	if apiAuthToken != "" {
+		return apiAuthToken
+	}
+	return os.Getenv(apiAuthTokenEnv)
+}
+
+// listen returns the listener of a headless server, it serves TLS
+// connections if a certificate was specified.
+func listen(addr string) (net.Listener, error) {
+	if (tlsCert == "") != (tlsKey == "") {
+		return nil, errors.New("--tls-cert and --tls-key must be used together")
+	}
+	listener, err := service.Listen(addr)
+	if err != nil || tlsCert == "" {
+		return listener, err
+	}
+	cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("could not load TLS certificate: %v", err)
+	}
+	return tls.NewListener(listener, &tls.Config{Certificates: []tls.Certificate{cert}}), nil
+}
+
+// dial connects to the headless server at addr.
+func dial(addr string) (net.Conn, error) {
+	conn, err := service.Dial(addr)
+	if err != nil {
+		return nil, err
+	}
+	return secureConn(conn, addr)
+}
+
+// secureConn returns conn, a connection to the headless server at addr,
+// wrapped in a TLS client if a certificate authority was specified with
+// --tls-ca.
+func secureConn(conn net.Conn, addr string) (net.Conn, error) {
+	if tlsCA == "" {
+		return conn, nil
+	}
+	if !service.IsTCPAddress(addr) {
+		conn.Close()
+		return nil, errors.New("--tls-ca can only be used with TCP addresses")
+	}
+	pem, err := ioutil.ReadFile(tlsCA)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(pem) {
+		conn.Close()
+		return nil, fmt.Errorf("no certificates found in %s", tlsCA)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, &tls.Config{RootCAs: roots, ServerName: host})
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// newClient returns a client for the server at the other end of conn,
+// authenticated with the token specified by the user, if any.
+func newClient(conn net.Conn) (*rpc2.RPCClient, error) {
+	token := authToken()
+	if token == "" {
+		return rpc2.NewClientFromConn(conn), nil
+	}
+	return rpc2.NewAuthenticatedClient(conn, token)
+}
+
+// continueHeadless connects to the headless server listening at addr,
+// started by this instance of Delve, and resumes the target.
+func continueHeadless(addr string) error {
+	var conn net.Conn
+	var err error
+	if tlsCert != "" {
+		// The certificate of the server is not verified, this is our own
+		// listener and its certificate might not be valid for addr.
+		conn, err = service.Dial(addr)
+		if err == nil {
+			conn = tls.Client(conn, &tls.Config{InsecureSkipVerify: true})
+		}
+	} else {
+		conn, err = service.Dial(addr)
+	}
+	if err != nil {
+		return err
+	}
+	client, err := newClient(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	return client.Disconnect(true) // true = continue after disconnect
+}
@@ -0,0 +1,502 @@
+package cmds
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/go-delve/delve/pkg/goversion"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// targetInfo describes an executable file, as inspected by checkTarget.
+type targetInfo struct {
+	path   string
+	format string
+	goos   string
+	goarch string
+
+	goVersion     string            // version of Go used to build the target, from the build info or the debug info
+	buildSettings map[string]string // build settings recorded by Go 1.18 and later
+
+	stripped bool // the symbol table is missing
+	pie      bool
+	cgo      bool
+
+	bi        *proc.BinaryInfo
+	loadErr   error // error loading the debug info
+	optimized bool
+	trimpath  bool // paths of source files are not absolute, for example because of -trimpath
+
+	// buildInfo is the contents of the section that may contain the build
+	// info written by the Go linker, readData reads the data at the
+	// specified virtual address.
+	buildInfo []byte
+	readData  func(addr, size uint64) ([]byte, error)
+}
+
+// targetCheck is a feature of Delve and how well it works against the
+// target.
+type targetCheck struct {
+	feature string
+	status  string // "ok", "limited" or "no"
+	note    string
+}
+
+// checkTarget inspects the executable at path and writes to w a report of
+// the Delve features that will work when debugging it, with suggestions
+// on how to improve its debuggability.
+func checkTarget(w io.Writer, path string, debugInfoDirs []string) error {
+	info, err := inspectTarget(path, debugInfoDirs)
+	if err != nil {
+		return err
+	}
+	defer info.bi.Close()
+
+	checks, suggestions := info.checks()
+
+	tw := tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	yesno := func(b bool) string {
+		if b {
+			return "yes"
+		}
+		return "no"
+	}
+	fmt.Fprintf(tw, "Target:\t%s\n", info.path)
+	fmt.Fprintf(tw, "Format:\t%s %s/%s\n", info.format, info.goos, info.goarch)
+	goVersion := info.goVersion
+	if goVersion == "" {
+		goVersion = "unknown"
+	}
+	fmt.Fprintf(tw, "Go version:\t%s\n", goVersion)
+	if len(info.buildSettings) > 0 {
+		keys := make([]string, 0, len(info.buildSettings))
+		for k := range info.buildSettings {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		settings := make([]string, 0, len(keys))
+		for _, k := range keys {
+			settings = append(settings, k+"="+info.buildSettings[k])
+		}
+		fmt.Fprintf(tw, "Build settings:\t%s\n", strings.Join(settings, " "))
+	}
+	fmt.Fprintf(tw, "Debug info:\t%s\n", yesno(info.loadErr == nil))
+	fmt.Fprintf(tw, "Symbol table:\t%s\n", yesno(!info.stripped))
+	if info.loadErr == nil {
+		fmt.Fprintf(tw, "Optimized:\t%s\n", yesno(info.optimized))
+		fmt.Fprintf(tw, "Trimmed paths:\t%s\n", yesno(info.trimpath))
+	}
+	fmt.Fprintf(tw, "PIE:\t%s\n", yesno(info.pie))
+	fmt.Fprintf(tw, "Cgo:\t%s\n", yesno(info.cgo))
+	tw.Flush()
+
+	fmt.Fprintf(w, "\nFeatures:\n")
+	tw = tabwriter.NewWriter(w, 0, 8, 1, ' ', 0)
+	for _, check := range checks {
+		fmt.Fprintf(tw, "\t%s\t%s", check.status, check.feature)
+		if check.note != "" {
+			fmt.Fprintf(tw, ": %s", check.note)
+		}
+		fmt.Fprintf(tw, "\n")
+	}
+	tw.Flush()
+
+	if len(suggestions) > 0 {
+		fmt.Fprintf(w, "\nSuggestions:\n")
+		for _, s := range suggestions {
+			fmt.Fprintf(w, "\t- %s\n", s)
+		}
+	}
+	return nil
+}
+
+// inspectTarget reads the headers, the build info and the debug info of
+// the executable at path.
+func inspectTarget(path string, debugInfoDirs []string) (*targetInfo, error) {
+	info := &targetInfo{path: path}
+
+	var entryPoint uint64
+	if f, err := elf.Open(path); err == nil {
+		defer f.Close()
+		entryPoint = info.inspectElf(f)
+	} else if f, err := macho.Open(path); err == nil {
+		defer f.Close()
+		info.inspectMacho(f)
+	} else if f, err := pe.Open(path); err == nil {
+		defer f.Close()
+		info.inspectPE(f)
+	} else {
+		return nil, fmt.Errorf("%s: not an ELF, Mach-O or PE executable", path)
+	}
+
+	info.readBuildInfo()
+
+	info.bi = proc.NewBinaryInfo(info.goos, info.goarch)
+	if info.bi.Arch == nil {
+		info.loadErr = fmt.Errorf("unsupported architecture %s", info.goarch)
+		return info, nil
+	}
+	info.loadErr = info.bi.LoadBinaryInfo(path, entryPoint, debugInfoDirs)
+	if info.loadErr != nil {
+		return info, nil
+	}
+	if producer := info.bi.Producer(); producer != "" && info.goVersion == "" {
+		info.goVersion = strings.TrimPrefix(producer, "Go cmd/compile ")
+		if i := strings.Index(info.goVersion, ";"); i >= 0 {
+			info.goVersion = info.goVersion[:i]
+		}
+	}
+	if fn := info.bi.LookupFunc["main.main"]; fn != nil {
+		info.optimized = fn.Optimized()
+	}
+	info.trimpath = info.buildSettings["-trimpath"] == "true"
+	if !info.trimpath && len(info.bi.Sources) > 0 {
+		info.trimpath = true
+		for _, file := range info.bi.Sources {
+			if filepath.IsAbs(file) || strings.HasPrefix(file, "/") || strings.Contains(file, ":\\") {
+				info.trimpath = false
+				break
+			}
+		}
+	}
+	if !info.cgo {
+		for _, fn := range info.bi.Functions {
+			if strings.HasPrefix(fn.Name, "x_cgo_") || strings.HasPrefix(fn.Name, "_cgo_") {
+				info.cgo = true
+				break
+			}
+		}
+	}
+	return info, nil
+}
+
+func (info *targetInfo) inspectElf(f *elf.File) (entryPoint uint64) {
+	info.format = "ELF"
+	switch f.OSABI {
+	case elf.ELFOSABI_FREEBSD:
+		info.goos = "freebsd"
+	case elf.ELFOSABI_OPENBSD:
+		info.goos = "openbsd"
+	default:
+		info.goos = "linux"
+	}
+	switch f.Machine {
+	case elf.EM_X86_64:
+		info.goarch = "amd64"
+	case elf.EM_AARCH64:
+		info.goarch = "arm64"
+	case elf.EM_386:
+		info.goarch = "386"
+	case elf.EM_S390:
+		info.goarch = "s390x"
+	case elf.EM_RISCV:
+		info.goarch = "riscv64"
+	case elf.Machine(258): // EM_LOONGARCH
+		info.goarch = "loong64"
+	default:
+		info.goarch = f.Machine.String()
+	}
+	info.stripped = f.Section(".symtab") == nil
+	info.pie = f.Type == elf.ET_DYN
+	for _, lib := range mustImportedLibraries(f.ImportedLibraries()) {
+		if strings.HasPrefix(lib, "libc.") || strings.HasPrefix(lib, "libpthread.") {
+			info.cgo = true
+		}
+	}
+
+	info.readData = func(addr, size uint64) ([]byte, error) {
+		for _, prog := range f.Progs {
+			if prog.Type == elf.PT_LOAD && prog.Vaddr <= addr && addr+size <= prog.Vaddr+prog.Filesz {
+				buf := make([]byte, size)
+				_, err := prog.ReadAt(buf, int64(addr-prog.Vaddr))
+				return buf, err
+			}
+		}
+		return nil, errors.New("address not mapped")
+	}
+	if sec := f.Section(".go.buildinfo"); sec != nil {
+		info.buildInfo = readPrefix(sec, sec.Size)
+	} else {
+		for _, prog := range f.Progs {
+			if prog.Type == elf.PT_LOAD && prog.Flags&elf.PF_W != 0 {
+				info.buildInfo = readPrefix(prog, prog.Filesz)
+				break
+			}
+		}
+	}
+
+	// Position independent executables are analyzed as if they were loaded
+	// at the address specified in their header.
+	return f.Entry
+}
+
+// maxBuildInfoScan is the maximum number of bytes searched for the build
+// info when the executable does not have a section dedicated to it.
+const maxBuildInfoScan = 64 * 1024
+
+func readPrefix(r io.ReaderAt, size uint64) []byte {
+	if size > maxBuildInfoScan {
+		size = maxBuildInfoScan
+	}
+	buf := make([]byte, size)
+	n, _ := r.ReadAt(buf, 0)
+	return buf[:n]
+}
+
+func mustImportedLibraries(libs []string, err error) []string {
+	if err != nil {
+		return nil
+	}
+	return libs
+}
+
+func (info *targetInfo) inspectMacho(f *macho.File) {
+	info.format = "Mach-O"
+	info.goos = "darwin"
+	switch f.Cpu {
+	case macho.CpuAmd64:
+		info.goarch = "amd64"
+	case macho.CpuArm64:
+		info.goarch = "arm64"
+	default:
+		info.goarch = f.Cpu.String()
+	}
+	info.stripped = f.Symtab == nil || len(f.Symtab.Syms) == 0
+	const machoFlagPIE = 0x200000
+	info.pie = f.Flags&machoFlagPIE != 0
+
+	info.readData = func(addr, size uint64) ([]byte, error) {
+		for _, sec := range f.Sections {
+			if sec.Addr <= addr && addr+size <= sec.Addr+sec.Size {
+				buf := make([]byte, size)
+				_, err := sec.ReadAt(buf, int64(addr-sec.Addr))
+				return buf, err
+			}
+		}
+		return nil, errors.New("address not mapped")
+	}
+	if sec := f.Section("__go_buildinfo"); sec != nil {
+		info.buildInfo = readPrefix(sec, sec.Size)
+	} else if sec := f.Section("__data"); sec != nil {
+		info.buildInfo = readPrefix(sec, sec.Size)
+	}
+}
+
+func (info *targetInfo) inspectPE(f *pe.File) {
+	info.format = "PE"
+	info.goos = "windows"
+	var imageBase uint64
+	var dllCharacteristics uint16
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		imageBase = uint64(oh.ImageBase)
+		dllCharacteristics = oh.DllCharacteristics
+	case *pe.OptionalHeader64:
+		imageBase = oh.ImageBase
+		dllCharacteristics = oh.DllCharacteristics
+	}
+	switch f.Machine {
+	case pe.IMAGE_FILE_MACHINE_AMD64:
+		info.goarch = "amd64"
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		info.goarch = "arm64"
+	case pe.IMAGE_FILE_MACHINE_I386:
+		info.goarch = "386"
+	default:
+		info.goarch = fmt.Sprintf("machine %#x", f.Machine)
+	}
+	info.stripped = len(f.Symbols) == 0
+	const imageDllCharacteristicsDynamicBase = 0x40
+	info.pie = dllCharacteristics&imageDllCharacteristicsDynamicBase != 0
+
+	info.readData = func(addr, size uint64) ([]byte, error) {
+		addr -= imageBase
+		for _, sec := range f.Sections {
+			if uint64(sec.VirtualAddress) <= addr && addr+size <= uint64(sec.VirtualAddress+sec.Size) {
+				buf := make([]byte, size)
+				_, err := sec.ReadAt(buf, int64(addr-uint64(sec.VirtualAddress)))
+				return buf, err
+			}
+		}
+		return nil, errors.New("address not mapped")
+	}
+	if sec := f.Section(".data"); sec != nil {
+		info.buildInfo = readPrefix(sec, uint64(sec.Size))
+	}
+}
+
+// buildInfoMagic is the prefix of the build info written by the Go linker,
+// see debug/buildinfo in the standard library.
+var buildInfoMagic = []byte("\xff Go buildinf:")
+
+// readBuildInfo reads the version of Go and the build settings from the
+// build info of the target, if it has one.
+func (info *targetInfo) readBuildInfo() {
+	data := info.buildInfo
+	for {
+		i := bytes.Index(data, buildInfoMagic)
+		if i < 0 || len(data)-i < 32 {
+			return
+		}
+		if i%16 == 0 {
+			data = data[i:]
+			break
+		}
+		data = data[(i+15)&^15:]
+	}
+
+	const (
+		flagsEndian     = 0x1
+		flagsInlineStrs = 0x2
+	)
+	ptrSize := int(data[14])
+	flags := data[15]
+	var bo binary.ByteOrder = binary.LittleEndian
+	if flags&flagsEndian != 0 {
+		bo = binary.BigEndian
+	}
+
+	var vers, mod string
+	if flags&flagsInlineStrs != 0 {
+		// Go 1.18 and later write the strings after the header.
+		var rest []byte
+		vers, rest = decodeVarintString(data[32:])
+		mod, _ = decodeVarintString(rest)
+	} else {
+		readPtr := func(b []byte) uint64 {
+			if ptrSize == 4 {
+				return uint64(bo.Uint32(b))
+			}
+			return bo.Uint64(b)
+		}
+		readString := func(addr uint64) string {
+			hdr, err := info.readData(addr, uint64(2*ptrSize))
+			if err != nil {
+				return ""
+			}
+			buf, err := info.readData(readPtr(hdr), readPtr(hdr[ptrSize:]))
+			if err != nil {
+				return ""
+			}
+			return string(buf)
+		}
+		if ptrSize != 4 && ptrSize != 8 || len(data) < 16+2*ptrSize {
+			return
+		}
+		vers = readString(readPtr(data[16:]))
+		mod = readString(readPtr(data[16+ptrSize:]))
+	}
+
+	info.goVersion = vers
+
+	// The module info is surrounded by 16 byte sentinels.
+	if len(mod) >= 33 && mod[len(mod)-17] == '\n' {
+		mod = mod[16 : len(mod)-16]
+	}
+	for _, line := range strings.Split(mod, "\n") {
+		if !strings.HasPrefix(line, "build\t") {
+			continue
+		}
+		kv := strings.SplitN(strings.TrimPrefix(line, "build\t"), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if info.buildSettings == nil {
+			info.buildSettings = make(map[string]string)
+		}
+		info.buildSettings[kv[0]] = kv[1]
+	}
+}
+
+func decodeVarintString(data []byte) (string, []byte) {
+	n, w := binary.Uvarint(data)
+	if w <= 0 || n > uint64(len(data)-w) {
+		return "", nil
+	}
+	return string(data[w : w+int(n)]), data[w+int(n):]
+}
+
+// checks returns the list of Delve features and how well they will work
+// against the target, followed by a list of suggestions to improve its
+// debuggability.
+func (info *targetInfo) checks() ([]targetCheck, []string) {
+	var checks []targetCheck
+	var suggestions []string
+
+	check := func(feature string, err error) {
+		if err != nil {
+			checks = append(checks, targetCheck{feature, "no", err.Error()})
+		} else {
+			checks = append(checks, targetCheck{feature, "ok", ""})
+		}
+	}
+	limited := func(feature, note string) {
+		checks = append(checks, targetCheck{feature, "limited", note})
+	}
+
+	switch {
+	case info.goVersion == "":
+		limited("Go version", "could not determine the version of Go used to build the target")
+	default:
+		err := goversion.Compatible(info.goVersion)
+		check("Go version", err)
+		if err != nil {
+			suggestions = append(suggestions, fmt.Sprintf("use a version of Go supported by this version of Delve (%d.%d to %d.%d) or a version of Delve that supports %s", goversion.MinSupportedVersionOfGoMajor, goversion.MinSupportedVersionOfGoMinor, goversion.MaxSupportedVersionOfGoMajor, goversion.MaxSupportedVersionOfGoMinor, info.goVersion))
+		}
+	}
+
+	if info.bi.Arch == nil {
+		check("debugging", info.loadErr)
+		return checks, suggestions
+	}
+
+	if info.loadErr != nil {
+		err := info.loadErr
+		if err == proc.ErrNoDebugInfoFound {
+			err = errors.New("the target does not have debug info")
+			suggestions = append(suggestions, "do not strip the target and do not build it with -ldflags='-s' or -ldflags='-w'")
+		}
+		check("breakpoints and stepping", err)
+		check("variables and expressions", err)
+		check("goroutines", err)
+		check("function calls", err)
+		check("disassembly", nil)
+		return checks, suggestions
+	}
+
+	if info.optimized {
+		limited("breakpoints and stepping", "stepping may skip lines and breakpoints may not stop on inlined calls")
+		limited("variables and expressions", "some variables may be unreadable or optimized away")
+		suggestions = append(suggestions, "disable optimizations and inlining by building the target with -gcflags='all=-N -l'")
+	} else {
+		check("breakpoints and stepping", nil)
+		check("variables and expressions", nil)
+	}
+	check("goroutines", nil)
+	check("function calls", proc.CheckFunctionCalls(info.bi))
+	check("disassembly", nil)
+
+	if info.trimpath {
+		limited("source listing", "source file paths are not absolute")
+		suggestions = append(suggestions, "do not build the target with -trimpath or use the substitute-path configuration option to map its paths to the source files")
+	} else {
+		check("source listing", nil)
+	}
+
+	if info.cgo {
+		limited("cgo", "C code can be debugged only if it was compiled with debug info")
+		suggestions = append(suggestions, "compile C code with debug info and without optimizations by setting CGO_CFLAGS='-O0 -g'")
+	}
+
+	return checks, suggestions
+}
@@ -1,9 +1,21 @@
 package cmds
 
 import (
+	"bytes"
+	"net"
+	"os"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+
+	protest "github.com/go-delve/delve/pkg/proc/test"
 )
 
+func TestMain(m *testing.M) {
+	os.Exit(protest.RunTestsWithFixtures(m))
+}
+
 func TestParseRedirects(t *testing.T) {
 	testCases := []struct {
 		in     []string
@@ -57,3 +69,102 @@ func TestParseRedirects(t *testing.T) {
 		}
 	}
 }
+
+func TestWaitForDeployServer(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses /bin/sh")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	// the deploy command fails before the server starts
+	hook := newDeployHook("exit 3", nil)
+	if err := hook.start(); err != nil {
+		t.Fatal(err)
+	}
+	_, err = waitForDeployServer(addr, hook, 5*time.Second)
+	if err == nil || !strings.Contains(err.Error(), "deploy command failed") {
+		t.Errorf("wrong error for failed deploy command: %v", err)
+	}
+
+	// the deploy command succeeds and the server starts later
+	hook = newDeployHook(`test "$1" = arg1`, []string{"arg1"})
+	if err := hook.start(); err != nil {
+		t.Fatal(err)
+	}
+	defer hook.stop()
+	go func() {
+		time.Sleep(time.Second)
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return
+		}
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	conn, err := waitForDeployServer(addr, hook, 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not connect: %v", err)
+	}
+	conn.Close()
+}
+
+func TestComplete(t *testing.T) {
+	root := New(false)
+	testCases := []struct {
+		args      []string
+		tgt       []string
+		directive int
+	}{
+		{[]string{"ver"}, []string{"version\tPrints version."}, compDirectiveNoFileComp},
+		{[]string{"completion", "f"}, []string{"fish"}, compDirectiveNoFileComp},
+		{[]string{"debug", "--backend", "l"}, []string{"lldb"}, compDirectiveNoFileComp},
+		{[]string{"attach", "--continu"}, []string{"--continue\tContinue the debugged process on start. The headless instance keeps running when its client disconnects, a new client can connect to it later."}, compDirectiveNoFileComp},
+		{[]string{"exec", "--tty", "/dev/pts/0", ""}, nil, compDirectiveDefault},
+		{[]string{"exec", "./prog", "--", "-"}, nil, compDirectiveDefault},
+	}
+	for _, tc := range testCases {
+		out, directive := complete(root, tc.args)
+		if strings.Join(out, "\n") != strings.Join(tc.tgt, "\n") || directive != tc.directive {
+			t.Errorf("complete(%q): got %q %d, expected %q %d", tc.args, out, directive, tc.tgt, tc.directive)
+		}
+	}
+}
+
+func TestCheckTarget(t *testing.T) {
+	testCases := []struct {
+		flags protest.BuildFlags
+		tgt   []string
+	}{
+		{0, []string{"Debug info: yes", "Optimized: no", "PIE: no", "ok breakpoints and stepping", "ok variables and expressions"}},
+		{protest.EnableOptimization | protest.EnableInlining, []string{"Debug info: yes", "Optimized: yes", "limited variables and expressions:", "-gcflags='all=-N -l'"}},
+		{protest.LinkStrip, []string{"Debug info: no", "Symbol table: no", "no breakpoints and stepping: the target does not have debug info", "ok disassembly"}},
+	}
+	if runtime.GOOS == "linux" && runtime.GOARCH == "amd64" {
+		testCases = append(testCases, struct {
+			flags protest.BuildFlags
+			tgt   []string
+		}{protest.BuildModePIE, []string{"Debug info: yes", "PIE: yes"}})
+	}
+	for _, tc := range testCases {
+		fixture := protest.BuildFixture("testvariables2", tc.flags)
+		var buf bytes.Buffer
+		if err := checkTarget(&buf, fixture.Path, nil); err != nil {
+			t.Fatalf("checkTarget(%d): %v", tc.flags, err)
+		}
+		// collapse the padding inserted by tabwriter
+		out := strings.Join(strings.Fields(buf.String()), " ")
+		for _, tgt := range tc.tgt {
+			if !strings.Contains(out, tgt) {
+				t.Errorf("checkTarget(%d): %q not found in:\n%s", tc.flags, tgt, buf.String())
+			}
+		}
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/go-delve/delve/pkg/config"
 	"github.com/go-delve/delve/pkg/gobuild"
@@ -45,10 +46,16 @@ var (
 	apiVersion int
 	// acceptMulti allows multiple clients to connect to the same server
 	acceptMulti bool
+	// maxQueuedCalls is the maximum number of function calls a client can
+	// have waiting, see debugger.Config.MaxQueuedCalls.
+	maxQueuedCalls int
 	// addr is the debugging server listen address.
 	addr string
 	// initFile is the path to initialization file.
 	initFile string
+	// projectFile is the path to a project file, saved with the 'project'
+	// command of the terminal client.
+	projectFile string
 	// buildFlags is the flags passed during compiler invocation.
 	buildFlags string
 	// workingDir is the working directory for running the program.
@@ -60,6 +67,33 @@ var (
 	tty string
 	// disableASLR is used to disable ASLR
 	disableASLR bool
+	// captureOutput is true if the output of the target is captured and
+	// reported as events of the API.
+	captureOutput bool
+	// autoDetach is the maximum time an attached process can stay
+	// stopped, see debugger.Config.AutoDetach.
+	autoDetach time.Duration
+	// stubAddr is the address of the remote stub controlling the target,
+	// see debugger.Config.StubAddr.
+	stubAddr string
+	// strict is true if only read-only inspection of the attached process
+	// is allowed, see debugger.Config.Strict.
+	strict bool
+	// passSignals is the comma separated list of signals delivered to the
+	// target without stopping it, see debugger.Config.PassSignals.
+	passSignals string
+	// followFork is true if the children of the target are debugged as
+	// separate targets, see debugger.Config.FollowFork.
+	followFork bool
+	// callHelper is true if function calls can be executed on a goroutine
+	// created by the debugger, see debugger.Config.CallHelper.
+	callHelper bool
+	// attachChildren is the pattern of the command lines of the child
+	// processes attached to, see debugger.Config.AttachChildren.
+	attachChildren string
+	// attachContainer is the ID or name of the container of the process
+	// to attach to.
+	attachContainer string
 
 	// backend selection
 	backend string
@@ -69,6 +103,10 @@ var (
 	// versions.
 	checkGoVersion bool
 
+	// checkTargetPath is the path of the executable inspected by 'dlv
+	// version --check-target'.
+	checkTargetPath string
+
 	// rootCommand is the root of the command tree.
 	rootCommand *cobra.Command
 
@@ -76,6 +114,7 @@ var (
 	traceExecFile   string
 	traceTestBinary bool
 	traceStackDepth int
+	traceAggregate  bool
 
 	// redirect specifications for target process
 	redirects []string
@@ -94,7 +133,10 @@ The goal of this tool is to provide a simple yet powerful interface for debuggin
 
 Pass flags to the program you are debugging using ` + "`--`" + `, for example:
 
-` + "`dlv exec ./hello -- server --config conf/config.toml`"
+` + "`dlv exec ./hello -- server --config conf/config.toml`" + `
+
+A debugging session saved with the 'project save' command of the terminal
+client can be started again using ` + "`dlv --project <file>`" + `.`
 
 // New returns an initialized command tree.
 func New(docCall bool) *cobra.Command {
@@ -114,9 +156,10 @@ func New(docCall bool) *cobra.Command {
 		Use:   "dlv",
 		Short: "Delve is a debugger for the Go programming language.",
 		Long:  dlvCommandLongDesc,
+		Run:   projectCmd,
 	}
 
-	rootCommand.PersistentFlags().StringVarP(&addr, "listen", "l", "127.0.0.1:0", "Debugging server listen address.")
+	rootCommand.PersistentFlags().StringVarP(&addr, "listen", "l", "127.0.0.1:0", "Debugging server listen address. Besides host:port it can be a unix domain socket (unix:/path/to/socket), an abstract unix domain socket on Linux (unix:@name) or a named pipe on Windows (npipe:///pipe/name).")
 
 	rootCommand.PersistentFlags().BoolVarP(&log, "log", "", false, "Enable debugging server logging.")
 	rootCommand.PersistentFlags().StringVarP(&logOutput, "log-output", "", "", `Comma separated list of components that should produce debug output (see 'dlv help log')`)
@@ -124,16 +167,26 @@ func New(docCall bool) *cobra.Command {
 
 	rootCommand.PersistentFlags().BoolVarP(&headless, "headless", "", false, "Run debug server only, in headless mode.")
 	rootCommand.PersistentFlags().BoolVarP(&acceptMulti, "accept-multiclient", "", false, "Allows a headless server to accept multiple client connections.")
+	rootCommand.PersistentFlags().IntVar(&maxQueuedCalls, "max-queued-calls", 0, "Maximum number of function calls of a client that can wait for the function calls of other clients, with --accept-multiclient. Further calls fail immediately. Zero means no limit.")
 	rootCommand.PersistentFlags().IntVar(&apiVersion, "api-version", 1, "Selects API version when headless. New clients should use v2. Can be reset via RPCServer.SetApiVersion. See Documentation/api/json-rpc/README.md.")
 	rootCommand.PersistentFlags().StringVar(&initFile, "init", "", "Init file, executed by the terminal client.")
+	rootCommand.PersistentFlags().StringVar(&projectFile, "project", "", "Project file, restored by the terminal client. When no command is specified the target is started as described by the project file.")
 	rootCommand.PersistentFlags().StringVar(&buildFlags, "build-flags", buildFlagsDefault, "Build flags, to be passed to the compiler. For example: --build-flags=\"-tags=integration -mod=vendor -cover -v\"")
 	rootCommand.PersistentFlags().StringVar(&workingDir, "wd", "", "Working directory for running the program.")
 	rootCommand.PersistentFlags().BoolVarP(&checkGoVersion, "check-go-version", "", true, "Checks that the version of Go in use is compatible with Delve.")
 	rootCommand.PersistentFlags().BoolVarP(&checkLocalConnUser, "only-same-user", "", true, "Only connections from the same user that started this instance of Delve are allowed to connect.")
+	rootCommand.PersistentFlags().StringVar(&apiAuthToken, "api-auth-token", "", "Token clients must authenticate with before the headless server accepts their requests, the connect command authenticates with it. Defaults to the value of the "+apiAuthTokenEnv+" environment variable.")
+	rootCommand.PersistentFlags().StringVar(&tlsCert, "tls-cert", "", "Certificate used by the headless server to serve TLS connections, requires --tls-key.")
+	rootCommand.PersistentFlags().StringVar(&tlsKey, "tls-key", "", "Private key of the certificate specified with --tls-cert.")
+	rootCommand.PersistentFlags().StringVar(&tlsCA, "tls-ca", "", "Certificate of the authority used to verify the certificate of the headless server the connect command connects to with TLS.")
 	rootCommand.PersistentFlags().StringVar(&backend, "backend", "default", `Backend selection (see 'dlv help backend').`)
 	rootCommand.PersistentFlags().StringArrayVarP(&redirects, "redirect", "r", []string{}, "Specifies redirect rules for target process (see 'dlv help redirect')")
 	rootCommand.PersistentFlags().BoolVar(&allowNonTerminalInteractive, "allow-non-terminal-interactive", false, "Allows interactive sessions of Delve that don't have a terminal as stdin, stdout and stderr")
 	rootCommand.PersistentFlags().BoolVar(&disableASLR, "disable-aslr", false, "Disables address space randomization")
+	rootCommand.PersistentFlags().StringVar(&passSignals, "pass-signals", "", "Comma separated list of signals that the lldb and rr backends deliver to the target without stopping it, 'none' stops for every signal. Defaults to SIGURG,SIGPROF.")
+	rootCommand.PersistentFlags().BoolVar(&followFork, "follow-fork", false, "Debugs the processes created by the target with fork and vfork, and the processes that replace their executable with exec, as separate targets (see the 'target' command). Only supported by the native backend on linux.")
+	rootCommand.PersistentFlags().BoolVar(&callHelper, "call-helper", false, "Function calls that can not be executed on the selected goroutine, because it is stopped inside the runtime (for example at an unrecovered panic), are executed on a goroutine created by the debugger. Requires a target built with Go 1.18 or later, running with GOMAXPROCS > 1.")
+	rootCommand.PersistentFlags().BoolVar(&captureOutput, "capture-output", false, "Reports the output of the target process, unless redirected, as output events of the API (see RPCServer.WaitForEvents) and keeps the most recent output for clients that connect later (see RPCServer.GetOutput). It is still written to the output of Delve.")
 
 	// 'attach' subcommand.
 	attachCommand := &cobra.Command{
@@ -144,16 +197,25 @@ func New(docCall bool) *cobra.Command {
 This command will cause Delve to take control of an already running process, and
 begin a new debug session.  When exiting the debug session you will have the
 option to let the process continue or kill it.
+
+With --container the pid is interpreted in the PID namespace of the given
+container and can be omitted to attach to the init process of the container.
+The executable and the separate debug info are looked up in the filesystem of
+the container. Only supported on linux.
 `,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) == 0 {
+			if len(args) == 0 && attachContainer == "" {
 				return errors.New("you must provide a PID")
 			}
 			return nil
 		},
 		Run: attachCmd,
 	}
-	attachCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start.")
+	attachCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start. The headless instance keeps running when its client disconnects, a new client can connect to it later.")
+	attachCommand.Flags().DurationVar(&autoDetach, "auto-detach", 0, "Detach from the process, resuming it, if it stays stopped for longer than the given duration or if all clients disconnect (with --accept-multiclient) while it is stopped. Intended for debugging production processes.")
+	attachCommand.Flags().BoolVar(&strict, "strict", false, "Only allow read-only inspection of the process: function calls, setting variables and writing memory are refused. Intended for debugging production processes.")
+	attachCommand.Flags().StringVar(&attachChildren, "children", "", "Also attach to the existing and future child processes whose command line matches the given regular expression, debugging each one as a separate target (see the 'target' command). Intended for master/worker architectures. Only supported by the native backend on linux.")
+	attachCommand.Flags().StringVar(&attachContainer, "container", "", "ID or name of the docker, containerd or podman container of the process.")
 	rootCommand.AddCommand(attachCommand)
 
 	// 'connect' subcommand.
@@ -183,9 +245,18 @@ to be launched or process to be attached to. The following modes are supported:
 - launch + debug (builds and launches, like 'dlv debug')
 - launch + test (builds and tests, like 'dlv test')
 - attach + local (attaches to a running process, like 'dlv attach')
-The server does not yet accept multiple client connections (--accept-multiclient).
+The server does not accept multiple client connections in parallel. With --accept-multiclient
+a client can disconnect without terminating the debuggee and the debug session (target process
+and breakpoints) will be kept for the next client, which can adopt it with an attach request
+in 'remote' mode. The session is also kept if the connection to the client is lost, for example
+because the editor crashed. This preserves the debug state across editor reloads. The target is
+halted while no client is connected, unless the keepRunningOnDisconnect launch/attach attribute
+is set. Processes attached to through DAP are never detached automatically, --auto-detach is
+only supported by 'dlv attach' and 'dlv stub'.
 While --continue is not supported, stopOnEntry launch/attach attribute can be used to control if
-execution is resumed at the start of the debug session.`,
+execution is resumed at the start of the debug session.
+When the server is started with --api-auth-token clients must specify the token as the authToken
+launch/attach attribute, until then the server only accepts initialize requests.`,
 		Run: dapCmd,
 	}
 	rootCommand.AddCommand(dapCommand)
@@ -203,8 +274,12 @@ session.`,
 		Run: debugCmd,
 	}
 	debugCommand.Flags().String("output", "./__debug_bin", "Output path for the binary.")
-	debugCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start.")
+	debugCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start. The headless instance keeps running when its client disconnects, a new client can connect to it later.")
 	debugCommand.Flags().StringVar(&tty, "tty", "", "TTY to use for the target program")
+	debugCommand.Flags().StringVar(&targetOS, "target-os", "", "Cross-compile the program for the specified operating system and debug it remotely (see 'dlv help deploy').")
+	debugCommand.Flags().StringVar(&targetArch, "target-arch", "", "Cross-compile the program for the specified architecture and debug it remotely (see 'dlv help deploy').")
+	debugCommand.Flags().StringVar(&deployCommand, "deploy", "", "Command used to deploy a cross-compiled program to the remote machine (see 'dlv help deploy').")
+	debugCommand.Flags().StringVar(&remoteAddr, "remote", "", "Address of the headless instance of Delve started by the deploy command (see 'dlv help deploy').")
 	rootCommand.AddCommand(debugCommand)
 
 	// 'exec' subcommand.
@@ -229,7 +304,7 @@ or later, -gcflags="-N -l" on earlier versions of Go.`,
 		},
 	}
 	execCommand.Flags().StringVar(&tty, "tty", "", "TTY to use for the target program")
-	execCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start.")
+	execCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start. The headless instance keeps running when its client disconnects, a new client can connect to it later.")
 	rootCommand.AddCommand(execCommand)
 
 	// Deprecated 'run' subcommand.
@@ -280,7 +355,8 @@ only see the output of the trace operations you can redirect stdout.`,
 	traceCommand.Flags().IntVarP(&traceAttachPid, "pid", "p", 0, "Pid to attach to.")
 	traceCommand.Flags().StringVarP(&traceExecFile, "exec", "e", "", "Binary file to exec and trace.")
 	traceCommand.Flags().BoolVarP(&traceTestBinary, "test", "t", false, "Trace a test binary.")
-	traceCommand.Flags().IntVarP(&traceStackDepth, "stack", "s", 0, "Show stack trace with given depth.")
+	traceCommand.Flags().IntVarP(&traceStackDepth, "stack", "s", 0, "Show stack trace with given depth (-1 for the whole stack).")
+	traceCommand.Flags().BoolVar(&traceAggregate, "aggregate", false, "Count the distinct stacks calling the traced functions instead of printing each call, print them when the program exits.")
 	traceCommand.Flags().String("output", "debug", "Output path for the binary.")
 	rootCommand.AddCommand(traceCommand)
 
@@ -293,7 +369,10 @@ The core command will open the specified core file and the associated
 executable and let you examine the state of the process when the
 core dump was taken.
 
-Currently supports linux/amd64 and linux/arm64 core files, windows/amd64 minidumps and core files generated by Delve's 'dump' command.`,
+Currently supports linux/amd64, linux/arm64, linux/loong64, linux/riscv64 and linux/s390x core files, windows/amd64 minidumps and core files generated by Delve's 'dump' command.
+
+Core files compressed with gzip or zstd are decompressed to a temporary
+file first, reading zstd compressed files requires the zstd command.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 2 {
 				return errors.New("you must provide a core file and an executable")
@@ -304,16 +383,74 @@ Currently supports linux/amd64 and linux/arm64 core files, windows/amd64 minidum
 	}
 	rootCommand.AddCommand(coreCommand)
 
+	// 'stub' subcommand.
+	stubCommand := &cobra.Command{
+		Use:   "stub <addr> <executable>",
+		Short: "Debug a process controlled by a remote debug stub.",
+		Long: `Debug a process controlled by a remote debug stub.
+
+The stub command connects to a gdb remote protocol stub, listening on addr
+and already attached to the target process, and uses it only to control the
+process and to access its memory and registers. Debug info is read from the
+local copy of the executable, breakpoints and expressions are resolved and
+evaluated locally.
+
+This keeps the memory and CPU used on the machine running the target to a
+minimum, for example in a Kubernetes pod where the stub can be started with:
+
+	lldb-server gdbserver --attach <pid> 0.0.0.0:<port>
+
+Generic gdb stubs, like the ones of qemu-user (-g <port>) and qemu-system
+(-gdb tcp::<port>), can also be used: the features they do not support, like
+listing threads, are emulated or disabled.
+
+Delve does not provide a stub of its own: lldb-server (debugserver on macOS)
+or another gdb stub must be available on the machine running the target. If
+it can not be installed there, for example in a container image without
+lldb-server, use 'dlv attach' on that machine instead.
+
+The executable must be exactly the same as the one running in the target
+process. Separate debug info is looked up in the local debug info
+directories.`,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 2 {
+				return errors.New("you must provide the address of the stub and a local copy of the executable")
+			}
+			return nil
+		},
+		Run: stubCmd,
+	}
+	stubCommand.Flags().DurationVar(&autoDetach, "auto-detach", 0, "Detach from the process, resuming it, if it stays stopped for longer than the given duration or if all clients disconnect (with --accept-multiclient) while it is stopped.")
+	stubCommand.Flags().BoolVar(&strict, "strict", false, "Only allow read-only inspection of the process: function calls, setting variables and writing memory are refused.")
+	rootCommand.AddCommand(stubCommand)
+
 	// 'version' subcommand.
 	versionCommand := &cobra.Command{
 		Use:   "version",
 		Short: "Prints version.",
+		Long: `Prints version.
+
+If the --check-target flag is specified the version command inspects the
+given executable instead and reports the version of Go used to build it,
+whether it contains debug info, the Delve features that will work when
+debugging it and how to build it to improve its debuggability.`,
 		Run: func(cmd *cobra.Command, args []string) {
+			if checkTargetPath != "" {
+				if err := checkTarget(os.Stdout, checkTargetPath, conf.DebugInfoDirectories); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				return
+			}
 			fmt.Printf("Delve Debugger\n%s\n", version.DelveVersion)
 		},
 	}
+	versionCommand.Flags().StringVar(&checkTargetPath, "check-target", "", "Inspects the specified executable and reports which features of Delve can be used to debug it.")
 	rootCommand.AddCommand(versionCommand)
 
+	// 'completion' subcommand.
+	rootCommand.AddCommand(newCompletionCommands()...)
+
 	if path, _ := exec.LookPath("rr"); path != "" || docCall {
 		replayCommand := &cobra.Command{
 			Use:   "replay [trace directory]",
@@ -396,6 +533,12 @@ File redirects can also be changed using the 'restart' command.
 `,
 	})
 
+	rootCommand.AddCommand(&cobra.Command{
+		Use:   "deploy",
+		Short: "Help about debugging cross-compiled programs on a remote machine.",
+		Long:  deployUsage,
+	})
+
 	rootCommand.DisableAutoGenTag = true
 
 	return rootCommand
@@ -412,9 +555,6 @@ func dapCmd(cmd *cobra.Command, args []string) {
 		if headless {
 			fmt.Fprintf(os.Stderr, "Warning: dap mode is always headless\n")
 		}
-		if acceptMulti {
-			fmt.Fprintf(os.Stderr, "Warning: accept-multiclient mode not supported with dap\n")
-		}
 		if initFile != "" {
 			fmt.Fprint(os.Stderr, "Warning: init file ignored with dap\n")
 		}
@@ -435,7 +575,7 @@ func dapCmd(cmd *cobra.Command, args []string) {
 			fmt.Fprintf(os.Stderr, "Warning: program flags ignored with dap; specify via launch/attach request instead\n")
 		}
 
-		listener, err := net.Listen("tcp", addr)
+		listener, err := listen(addr)
 		if err != nil {
 			fmt.Printf("couldn't start listener: %s\n", err)
 			return 1
@@ -444,6 +584,7 @@ func dapCmd(cmd *cobra.Command, args []string) {
 		server := dap.NewServer(&service.Config{
 			Listener:       listener,
 			DisconnectChan: disconnectChan,
+			AuthToken:      authToken(),
 			Debugger: debugger.Config{
 				Backend:              backend,
 				Foreground:           headless && tty == "",
@@ -452,6 +593,7 @@ func dapCmd(cmd *cobra.Command, args []string) {
 				TTY:                  tty,
 			},
 			CheckLocalConnUser: checkLocalConnUser,
+			AcceptMulti:        acceptMulti,
 		})
 		defer server.Stop()
 
@@ -471,18 +613,26 @@ func debugCmd(cmd *cobra.Command, args []string) {
 		}
 
 		dlvArgs, targetArgs := splitArgs(cmd, args)
-		err = gobuild.GoBuild(debugname, dlvArgs, buildFlags)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			return 1
+		if targetOS != "" || targetArch != "" {
+			return deployDebug(debugname, dlvArgs, targetArgs)
 		}
-		defer gobuild.Remove(debugname)
-		processArgs := append([]string{debugname}, targetArgs...)
-		return execute(0, processArgs, conf, "", debugger.ExecutingGeneratedFile, dlvArgs, buildFlags)
+		return buildAndDebug(debugname, dlvArgs, targetArgs)
 	}()
 	os.Exit(status)
 }
 
+// buildAndDebug compiles pkgs and starts debugging the executable.
+func buildAndDebug(debugname string, pkgs, targetArgs []string) int {
+	err := gobuild.GoBuild(debugname, pkgs, buildFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer gobuild.Remove(debugname)
+	processArgs := append([]string{debugname}, targetArgs...)
+	return execute(0, processArgs, conf, "", debugger.ExecutingGeneratedFile, pkgs, buildFlags)
+}
+
 func traceCmd(cmd *cobra.Command, args []string) {
 	status := func() int {
 		err := logflags.Setup(log, logOutput, logDest)
@@ -574,16 +724,20 @@ func traceCmd(cmd *cobra.Command, args []string) {
 		}
 		for i := range funcs {
 			_, err = client.CreateBreakpoint(&api.Breakpoint{
-				FunctionName: funcs[i],
-				Tracepoint:   true,
-				Line:         -1,
-				Stacktrace:   traceStackDepth,
-				LoadArgs:     &terminal.ShortLoadConfig,
+				FunctionName:    funcs[i],
+				Tracepoint:      true,
+				Line:            -1,
+				Stacktrace:      traceStackDepth,
+				AggregateStacks: traceAggregate,
+				LoadArgs:        &terminal.ShortLoadConfig,
 			})
 			if err != nil && !isBreakpointExistsErr(err) {
 				fmt.Fprintln(os.Stderr, err)
 				return 1
 			}
+			if traceAggregate {
+				continue
+			}
 			addrs, err := client.FunctionReturnLocations(funcs[i])
 			if err != nil {
 				fmt.Fprintln(os.Stderr, err)
@@ -625,25 +779,31 @@ func testCmd(cmd *cobra.Command, args []string) {
 		}
 
 		dlvArgs, targetArgs := splitArgs(cmd, args)
-		err = gobuild.GoTestBuild(debugname, dlvArgs, buildFlags)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
-			return 1
-		}
-		defer gobuild.Remove(debugname)
-		processArgs := append([]string{debugname}, targetArgs...)
+		return buildAndDebugTest(debugname, dlvArgs, targetArgs)
+	}()
+	os.Exit(status)
+}
 
-		if workingDir == "" {
-			if len(dlvArgs) == 1 {
-				workingDir = getPackageDir(dlvArgs[0])
-			} else {
-				workingDir = "."
-			}
+// buildAndDebugTest compiles the test binary of pkgs and starts debugging
+// it.
+func buildAndDebugTest(debugname string, pkgs, targetArgs []string) int {
+	err := gobuild.GoTestBuild(debugname, pkgs, buildFlags)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer gobuild.Remove(debugname)
+	processArgs := append([]string{debugname}, targetArgs...)
+
+	if workingDir == "" {
+		if len(pkgs) == 1 {
+			workingDir = getPackageDir(pkgs[0])
+		} else {
+			workingDir = "."
 		}
+	}
 
-		return execute(0, processArgs, conf, "", debugger.ExecutingGeneratedTest, dlvArgs, buildFlags)
-	}()
-	os.Exit(status)
+	return execute(0, processArgs, conf, "", debugger.ExecutingGeneratedTest, pkgs, buildFlags)
 }
 
 func getPackageDir(pkg string) string {
@@ -662,19 +822,103 @@ func getPackageDir(pkg string) string {
 	return listout.Dir
 }
 
+// projectCmd starts the target as described by the project file specified
+// with --project, the --build-flags, --wd and --backend flags take
+// precedence over the parameters saved in the project file.
+func projectCmd(cmd *cobra.Command, args []string) {
+	if projectFile == "" {
+		cmd.Help()
+		return
+	}
+	status := func() int {
+		p, err := api.ReadProject(projectFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		flags := cmd.Flags()
+		if !flags.Changed("build-flags") && p.BuildFlags != "" {
+			buildFlags = p.BuildFlags
+		}
+		if !flags.Changed("wd") && p.WorkingDir != "" {
+			workingDir = p.WorkingDir
+		}
+		if !flags.Changed("backend") && p.Backend != "" {
+			backend = p.Backend
+		}
+		targetArgs := p.Args
+
+		switch p.Kind {
+		case api.ProjectDebug, api.ProjectTest:
+			debugname, err := filepath.Abs("./__debug_bin")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%v\n", err)
+				return 1
+			}
+			if p.Kind == api.ProjectTest {
+				return buildAndDebugTest(debugname, p.Packages, targetArgs)
+			}
+			return buildAndDebug(debugname, p.Packages, targetArgs)
+		case api.ProjectExec:
+			processArgs := append([]string{p.Program}, targetArgs...)
+			return execute(0, processArgs, conf, "", debugger.ExecutingExistingFile, processArgs, buildFlags)
+		default:
+			fmt.Fprintf(os.Stderr, "project file %s does not describe how to start the target, use it with 'dlv attach', 'dlv core' or 'dlv connect'\n", projectFile)
+			return 1
+		}
+	}()
+	os.Exit(status)
+}
+
 func attachCmd(cmd *cobra.Command, args []string) {
-	pid, err := strconv.Atoi(args[0])
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Invalid pid: %s\n", args[0])
-		os.Exit(1)
+	dlvArgs := args
+	var pid int
+	if len(args) > 0 {
+		var err error
+		pid, err = strconv.Atoi(args[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid pid: %s\n", args[0])
+			os.Exit(1)
+		}
+		args = args[1:]
+	}
+	conf := conf
+	if attachContainer != "" {
+		hostPid, root, err := containerProcess(attachContainer, pid)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		pid = hostPid
+		if len(args) > 0 {
+			args = append([]string{filepath.Join(root, args[0])}, args[1:]...)
+		}
+		conf = containerConfig(conf, root)
 	}
-	os.Exit(execute(pid, args[1:], conf, "", debugger.ExecutingOther, args, buildFlags))
+	os.Exit(execute(pid, args, conf, "", debugger.ExecutingOther, dlvArgs, buildFlags))
+}
+
+// containerConfig returns a copy of conf that also looks for separate debug
+// info in the container filesystem at root, before the host directories.
+func containerConfig(conf *config.Config, root string) *config.Config {
+	c := *conf
+	c.DebugInfoDirectories = make([]string, 0, 2*len(conf.DebugInfoDirectories))
+	for _, dir := range conf.DebugInfoDirectories {
+		c.DebugInfoDirectories = append(c.DebugInfoDirectories, filepath.Join(root, dir))
+	}
+	c.DebugInfoDirectories = append(c.DebugInfoDirectories, conf.DebugInfoDirectories...)
+	return &c
 }
 
 func coreCmd(cmd *cobra.Command, args []string) {
 	os.Exit(execute(0, []string{args[0]}, conf, args[1], debugger.ExecutingOther, args, buildFlags))
 }
 
+func stubCmd(cmd *cobra.Command, args []string) {
+	stubAddr = args[0]
+	os.Exit(execute(0, []string{args[1]}, conf, "", debugger.ExecutingOther, args, buildFlags))
+}
+
 func connectCmd(cmd *cobra.Command, args []string) {
 	addr := args[0]
 	if addr == "" {
@@ -724,11 +968,18 @@ func splitArgs(cmd *cobra.Command, args []string) ([]string, []string) {
 
 func connect(addr string, clientConn net.Conn, conf *config.Config, kind debugger.ExecuteKind) int {
 	// Create and start a terminal - attach to running instance
-	var client *rpc2.RPCClient
-	if clientConn != nil {
-		client = rpc2.NewClientFromConn(clientConn)
-	} else {
-		client = rpc2.NewClient(addr)
+	if clientConn == nil {
+		var err error
+		clientConn, err = dial(addr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not connect to %s: %v\n", addr, err)
+			return 1
+		}
+	}
+	client, err := newClient(clientConn)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not authenticate: %v\n", err)
+		return 1
 	}
 	if client.IsMulticlient() {
 		state, _ := client.GetStateNonBlocking()
@@ -746,6 +997,14 @@ func connect(addr string, clientConn net.Conn, conf *config.Config, kind debugge
 	}
 	term := terminal.New(client, conf)
 	term.InitFile = initFile
+	if projectFile != "" {
+		p, err := api.ReadProject(projectFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			return 1
+		}
+		term.Project = p
+	}
 	status, err := term.Run()
 	if err != nil {
 		fmt.Println(err)
@@ -763,15 +1022,14 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 	if headless && (initFile != "") {
 		fmt.Fprint(os.Stderr, "Warning: init file ignored with --headless\n")
 	}
+	if headless && (projectFile != "") {
+		fmt.Fprint(os.Stderr, "Warning: breakpoints and settings of the project file ignored with --headless\n")
+	}
 	if continueOnStart {
 		if !headless {
 			fmt.Fprint(os.Stderr, "Error: --continue only works with --headless; use an init file\n")
 			return 1
 		}
-		if !acceptMulti {
-			fmt.Fprint(os.Stderr, "Error: --continue requires --accept-multiclient\n")
-			return 1
-		}
 	}
 
 	if !headless && acceptMulti {
@@ -812,7 +1070,7 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 
 	// Make a TCP listener
 	if headless {
-		listener, err = net.Listen("tcp", addr)
+		listener, err = listen(addr)
 	} else {
 		listener, clientConn = service.ListenerPipe()
 	}
@@ -837,11 +1095,14 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 			Listener:           listener,
 			ProcessArgs:        processArgs,
 			AcceptMulti:        acceptMulti,
+			KeepAlive:          continueOnStart,
 			APIVersion:         apiVersion,
 			CheckLocalConnUser: checkLocalConnUser,
+			AuthToken:          authToken(),
 			DisconnectChan:     disconnectChan,
 			Debugger: debugger.Config{
 				AttachPid:            attachPid,
+				StubAddr:             stubAddr,
 				WorkingDir:           workingDir,
 				Backend:              backend,
 				CoreFile:             coreFile,
@@ -854,6 +1115,14 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 				TTY:                  tty,
 				Redirects:            redirects,
 				DisableASLR:          disableASLR,
+				CaptureOutput:        captureOutput,
+				AutoDetach:           autoDetach,
+				MaxQueuedCalls:       maxQueuedCalls,
+				CallHelper:           callHelper,
+				Strict:               strict,
+				PassSignals:          passSignalsList(),
+				FollowFork:           followFork,
+				AttachChildren:       attachChildren,
 			},
 		})
 	default:
@@ -881,8 +1150,10 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 	var status int
 	if headless {
 		if continueOnStart {
-			client := rpc2.NewClient(listener.Addr().String())
-			client.Disconnect(true) // true = continue after disconnect
+			if err := continueHeadless(service.ListenAddress(listener.Addr())); err != nil {
+				fmt.Fprintf(os.Stderr, "could not continue: %v\n", err)
+				return 1
+			}
 		}
 		waitForDisconnectSignal(disconnectChan)
 		err = server.Stop()
@@ -893,7 +1164,7 @@ func execute(attachPid int, processArgs []string, conf *config.Config, coreFile
 		return status
 	}
 
-	return connect(listener.Addr().String(), clientConn, conf, kind)
+	return connect(service.ListenAddress(listener.Addr()), clientConn, conf, kind)
 }
 
 func parseRedirects(redirects []string) ([3]string, error) {
@@ -916,3 +1187,15 @@ func parseRedirects(redirects []string) ([3]string, error) {
 	}
 	return r, nil
 }
+
+// passSignalsList parses the value of --pass-signals, it returns nil if
+// the flag was not specified.
+func passSignalsList() []string {
+	switch passSignals {
+	case "":
+		return nil
+	case "none":
+		return []string{}
+	}
+	return strings.Split(passSignals, ",")
+}
@@ -0,0 +1,538 @@
+package cmds
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const completionCommandLongDesc = `Generates a completion script for the specified shell.
+
+The script completes subcommands and flags of dlv, as well as their
+arguments: the PIDs of running processes for 'dlv attach', package paths for
+'dlv debug', 'dlv test' and 'dlv trace' and core files for 'dlv core'.
+
+To load the completions in the current shell:
+
+	bash:		source <(dlv completion bash)
+	zsh:		source <(dlv completion zsh)
+	fish:		dlv completion fish | source
+	powershell:	dlv completion powershell | Out-String | Invoke-Expression
+
+To load them in every new session, add the command to the startup file of
+the shell (~/.bashrc, ~/.zshrc, ~/.config/fish/config.fish or $PROFILE).
+`
+
+// completeCommandName is the name of the hidden command called by the
+// completion scripts, it receives the words of the command line after 'dlv',
+// the last one being the word to complete, and prints the candidates one
+// per line, optionally followed by a tab and a description, followed by a
+// line containing ':' and the completion directive.
+const completeCommandName = "__complete"
+
+// Completion directives, a bit mask telling the completion script what to
+// do with the candidates. Their values are the same used by the completion
+// scripts of cobra.
+const (
+	// compDirectiveDefault lets the shell complete file names if there are
+	// no candidates.
+	compDirectiveDefault = 0
+	// compDirectiveNoFileComp prevents the shell from completing file names
+	// if there are no candidates.
+	compDirectiveNoFileComp = 4
+)
+
+var completionScripts = map[string]string{
+	"bash":       bashCompletionScript,
+	"zsh":        zshCompletionScript,
+	"fish":       fishCompletionScript,
+	"powershell": powershellCompletionScript,
+}
+
+func completionShells() []string {
+	r := make([]string, 0, len(completionScripts))
+	for shell := range completionScripts {
+		r = append(r, shell)
+	}
+	sort.Strings(r)
+	return r
+}
+
+func newCompletionCommands() []*cobra.Command {
+	completionCommand := &cobra.Command{
+		Use:   "completion <" + strings.Join(completionShells(), "|") + ">",
+		Short: "Generate a shell completion script.",
+		Long:  completionCommandLongDesc,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return fmt.Errorf("you must specify a shell (%s)", strings.Join(completionShells(), ", "))
+			}
+			if _, ok := completionScripts[args[0]]; !ok {
+				return fmt.Errorf("unsupported shell %q (supported shells: %s)", args[0], strings.Join(completionShells(), ", "))
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Print(completionScripts[args[0]])
+		},
+	}
+
+	completeCommand := &cobra.Command{
+		Use:                completeCommandName,
+		Short:              "Complete a dlv command line, used by the completion scripts.",
+		Hidden:             true,
+		DisableFlagParsing: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			candidates, directive := complete(rootCommand, args)
+			for _, candidate := range candidates {
+				fmt.Println(candidate)
+			}
+			fmt.Printf(":%d\n", directive)
+		},
+	}
+
+	return []*cobra.Command{completionCommand, completeCommand}
+}
+
+// complete returns the completion candidates for the last element of args,
+// args are the words of the command line after the name of the root
+// command.
+func complete(root *cobra.Command, args []string) ([]string, int) {
+	toComplete := ""
+	if len(args) > 0 {
+		toComplete = args[len(args)-1]
+		args = args[:len(args)-1]
+	}
+	if toComplete == `""` {
+		// PowerShell drops empty arguments when calling native commands,
+		// the completion script passes the empty word quoted instead.
+		toComplete = ""
+	}
+	for _, arg := range args {
+		if arg == "--" {
+			// arguments of the target process
+			return nil, compDirectiveDefault
+		}
+	}
+
+	cmd, rest, _ := root.Find(args)
+	if cmd == nil {
+		cmd = root
+	}
+
+	if n := len(rest); n > 0 && flagNeedsValue(cmd, rest[n-1]) {
+		return completeFlagValue(cmd, lookupFlag(cmd, rest[n-1]), toComplete)
+	}
+	if strings.HasPrefix(toComplete, "-") {
+		if strings.Contains(toComplete, "=") {
+			return nil, compDirectiveDefault
+		}
+		return filterCandidates(flagCandidates(cmd), toComplete), compDirectiveNoFileComp
+	}
+
+	var positional []string
+	for i := 0; i < len(rest); i++ {
+		if strings.HasPrefix(rest[i], "-") && len(rest[i]) > 1 {
+			if flagNeedsValue(cmd, rest[i]) {
+				i++
+			}
+			continue
+		}
+		positional = append(positional, rest[i])
+	}
+
+	candidates, directive := completeArg(cmd, len(positional), toComplete)
+	return filterCandidates(candidates, toComplete), directive
+}
+
+// completeArg returns the candidates for the argument number n of cmd.
+func completeArg(cmd *cobra.Command, n int, toComplete string) ([]string, int) {
+	if cmd.HasSubCommands() {
+		if n != 0 {
+			return nil, compDirectiveNoFileComp
+		}
+		var r []string
+		for _, sub := range cmd.Commands() {
+			if sub.IsAvailableCommand() {
+				r = append(r, sub.Name()+"\t"+sub.Short)
+			}
+		}
+		return r, compDirectiveNoFileComp
+	}
+
+	switch cmd.Name() {
+	case "attach":
+		if n == 0 {
+			return processCandidates(), compDirectiveNoFileComp
+		}
+	case "debug", "trace":
+		if n == 0 {
+			return packageCandidates(toComplete, true), compDirectiveDefault
+		}
+		if cmd.Name() == "trace" && n == 1 {
+			return nil, compDirectiveNoFileComp
+		}
+	case "test":
+		if n == 0 {
+			return packageCandidates(toComplete, false), compDirectiveDefault
+		}
+	case "core":
+		if n == 1 {
+			return coreFileCandidates(toComplete), compDirectiveDefault
+		}
+	case "completion":
+		if n == 0 {
+			return completionShells(), compDirectiveNoFileComp
+		}
+		return nil, compDirectiveNoFileComp
+	case "version", "run":
+		return nil, compDirectiveNoFileComp
+	}
+	return nil, compDirectiveDefault
+}
+
+// completeFlagValue returns the candidates for the value of flag f.
+func completeFlagValue(cmd *cobra.Command, f *pflag.Flag, toComplete string) ([]string, int) {
+	switch {
+	case f.Name == "backend":
+		return filterCandidates([]string{"default", "native", "lldb", "rr"}, toComplete), compDirectiveNoFileComp
+	case f.Name == "pid" && cmd.Name() == "trace":
+		return filterCandidates(processCandidates(), toComplete), compDirectiveNoFileComp
+	case f.Name == "api-version":
+		return filterCandidates([]string{"1", "2"}, toComplete), compDirectiveNoFileComp
+	}
+	return nil, compDirectiveDefault
+}
+
+// lookupFlag returns the flag of cmd, or the persistent flag of one of its
+// parents, named by arg, which can be either '--name' or '-shorthand'.
+func lookupFlag(cmd *cobra.Command, arg string) *pflag.Flag {
+	var lookup func(fs *pflag.FlagSet) *pflag.Flag
+	switch {
+	case strings.HasPrefix(arg, "--"):
+		lookup = func(fs *pflag.FlagSet) *pflag.Flag {
+			return fs.Lookup(arg[2:])
+		}
+	case strings.HasPrefix(arg, "-") && len(arg) == 2:
+		lookup = func(fs *pflag.FlagSet) *pflag.Flag {
+			var r *pflag.Flag
+			fs.VisitAll(func(f *pflag.Flag) {
+				if f.Shorthand == arg[1:] {
+					r = f
+				}
+			})
+			return r
+		}
+	default:
+		return nil
+	}
+	if f := lookup(cmd.Flags()); f != nil {
+		return f
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if f := lookup(c.PersistentFlags()); f != nil {
+			return f
+		}
+	}
+	return nil
+}
+
+// flagNeedsValue returns true if arg is a flag that takes its value from
+// the next argument.
+func flagNeedsValue(cmd *cobra.Command, arg string) bool {
+	if strings.Contains(arg, "=") {
+		return false
+	}
+	f := lookupFlag(cmd, arg)
+	return f != nil && f.NoOptDefVal == ""
+}
+
+// flagCandidates returns the flags that can be used with cmd.
+func flagCandidates(cmd *cobra.Command) []string {
+	seen := make(map[string]bool)
+	var r []string
+	add := func(f *pflag.Flag) {
+		if f.Hidden || f.Deprecated != "" || seen[f.Name] {
+			return
+		}
+		seen[f.Name] = true
+		r = append(r, "--"+f.Name+"\t"+f.Usage)
+	}
+	cmd.Flags().VisitAll(add)
+	for c := cmd; c != nil; c = c.Parent() {
+		c.PersistentFlags().VisitAll(add)
+	}
+	sort.Strings(r)
+	return r
+}
+
+// filterCandidates returns the candidates that start with prefix.
+func filterCandidates(candidates []string, prefix string) []string {
+	r := candidates[:0]
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, prefix) {
+			r = append(r, candidate)
+		}
+	}
+	return r
+}
+
+// processCandidates returns the PIDs of the running processes, with their
+// command line as description.
+func processCandidates() []string {
+	var r []string
+	add := func(pid int, cmdline string) {
+		if pid != os.Getpid() && pid != os.Getppid() {
+			r = append(r, strconv.Itoa(pid)+"\t"+cmdline)
+		}
+	}
+
+	if runtime.GOOS == "linux" {
+		fis, err := ioutil.ReadDir("/proc")
+		if err != nil {
+			return nil
+		}
+		for _, fi := range fis {
+			pid, err := strconv.Atoi(fi.Name())
+			if err != nil || !fi.IsDir() {
+				continue
+			}
+			buf, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+			if err != nil || len(buf) == 0 {
+				// the process exited or is a kernel thread
+				continue
+			}
+			add(pid, strings.Replace(strings.TrimSuffix(string(buf), "\x00"), "\x00", " ", -1))
+		}
+		return r
+	}
+
+	if runtime.GOOS == "windows" {
+		out, err := exec.Command("tasklist", "/fo", "csv", "/nh").Output()
+		if err != nil {
+			return nil
+		}
+		for _, line := range strings.Split(string(out), "\n") {
+			fields := strings.Split(strings.TrimSpace(line), ",")
+			if len(fields) < 2 {
+				continue
+			}
+			if pid, err := strconv.Atoi(strings.Trim(fields[1], `"`)); err == nil {
+				add(pid, strings.Trim(fields[0], `"`))
+			}
+		}
+		return r
+	}
+
+	out, err := exec.Command("ps", "-A", "-o", "pid=", "-o", "command=").Output()
+	if err != nil {
+		return nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields) < 2 {
+			continue
+		}
+		if pid, err := strconv.Atoi(fields[0]); err == nil {
+			add(pid, strings.TrimSpace(fields[1]))
+		}
+	}
+	return r
+}
+
+// packageCandidates returns the packages in the module or GOPATH directory
+// containing the current directory, as relative paths if toComplete is a
+// relative path and as import paths otherwise. If mainOnly is set only main
+// packages are returned.
+func packageCandidates(toComplete string, mainOnly bool) []string {
+	out, err := exec.Command("go", "list", "-e", "-f", "{{.Name}} {{.ImportPath}} {{.Dir}}", "./...").Output()
+	if err != nil {
+		return nil
+	}
+	wd, _ := os.Getwd()
+	relative := toComplete == "" || strings.HasPrefix(toComplete, ".")
+	var r []string
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) != 3 || (mainOnly && fields[0] != "main") {
+			continue
+		}
+		if !relative {
+			r = append(r, fields[1])
+			continue
+		}
+		rel, err := filepath.Rel(wd, fields[2])
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if rel != "." {
+			rel = "." + string(filepath.Separator) + rel
+		}
+		r = append(r, rel)
+	}
+	return r
+}
+
+// coreFileCandidates returns the files in the directory of toComplete that
+// look like core dumps: files named 'core' or 'core.*', files with the
+// '.core' or '.dmp' extensions and ELF core files.
+func coreFileCandidates(toComplete string) []string {
+	dir := filepath.Dir(toComplete)
+	if strings.HasSuffix(toComplete, string(filepath.Separator)) {
+		dir = toComplete
+	}
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var r []string
+	for _, fi := range fis {
+		name := fi.Name()
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		if name != "core" && !strings.HasPrefix(name, "core.") && !strings.HasSuffix(name, ".core") && !strings.HasSuffix(name, ".dmp") && !isELFCore(filepath.Join(dir, name)) {
+			continue
+		}
+		if dir == "." && !strings.HasPrefix(toComplete, ".") {
+			r = append(r, name)
+		} else {
+			r = append(r, filepath.Join(dir, name))
+		}
+	}
+	return r
+}
+
+// isELFCore returns true if path is an ELF file of type ET_CORE.
+func isELFCore(path string) bool {
+	fh, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer fh.Close()
+	var hdr [18]byte
+	if _, err := fh.Read(hdr[:]); err != nil || !bytes.Equal(hdr[:4], []byte("\x7fELF")) {
+		return false
+	}
+	const etCore = 4
+	if hdr[5] == 2 { // big endian
+		return hdr[16] == 0 && hdr[17] == etCore
+	}
+	return hdr[16] == etCore && hdr[17] == 0
+}
+
+const bashCompletionScript = `# bash completion for dlv
+
+_dlv_complete() {
+	local cur out directive line
+	cur="${COMP_WORDS[COMP_CWORD]}"
+	out=$("${COMP_WORDS[0]}" ` + completeCommandName + ` "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null)
+	directive=${out##*:}
+	out=${out%:*}
+
+	COMPREPLY=()
+	while IFS='' read -r line; do
+		line=${line%%$'\t'*}
+		[[ -n $line ]] && COMPREPLY+=("$line")
+	done <<< "$out"
+
+	if [[ ${#COMPREPLY[@]} -eq 0 && $((directive & 4)) -eq 0 ]]; then
+		compopt -o default 2>/dev/null
+	fi
+}
+
+complete -F _dlv_complete dlv
+`
+
+const zshCompletionScript = `#compdef dlv
+
+_dlv() {
+	local out directive line
+	local -a completions
+	out=$("${words[1]}" ` + completeCommandName + ` "${(@)words[2,$CURRENT]}" 2>/dev/null)
+	directive=${out##*:}
+	out=${out%:*}
+
+	for line in "${(@f)out}"; do
+		[[ -z $line ]] && continue
+		if [[ $line == *$'\t'* ]]; then
+			completions+=("${${line%%$'\t'*}//:/\\:}:${line#*$'\t'}")
+		else
+			completions+=("${line//:/\\:}")
+		fi
+	done
+
+	if (( ${#completions} > 0 )); then
+		_describe 'dlv' completions
+	elif (( (directive & 4) == 0 )); then
+		_files
+	fi
+}
+
+if [ "$funcstack[1]" = "_dlv" ]; then
+	_dlv "$@"
+else
+	compdef _dlv dlv
+fi
+`
+
+const fishCompletionScript = `# fish completion for dlv
+
+function __dlv_complete
+	set -l args (commandline -opc)
+	set -l out ($args[1] ` + completeCommandName + ` $args[2..-1] (commandline -ct) 2>/dev/null)
+	set -l directive (string replace ':' '' -- $out[-1])
+	set -e out[-1]
+	if test (count $out) -eq 0
+		if test (math "bitand($directive, 4)") -eq 0
+			__fish_complete_path (commandline -ct)
+		end
+		return
+	end
+	printf '%s\n' $out
+end
+
+complete -c dlv -f -a '(__dlv_complete)'
+`
+
+const powershellCompletionScript = `# powershell completion for dlv
+
+Register-ArgumentCompleter -Native -CommandName 'dlv' -ScriptBlock {
+	param($wordToComplete, $commandAst, $cursorPosition)
+
+	$words = @($commandAst.CommandElements | Where-Object { $_.Extent.StartOffset -lt $cursorPosition } | ForEach-Object { $_.ToString() })
+	$arguments = @($words | Select-Object -Skip 1)
+	if ($wordToComplete -eq '') {
+		# empty arguments are dropped when calling native commands
+		$arguments += '""'
+	}
+
+	$out = @(& $words[0] ` + completeCommandName + ` @arguments 2>$null)
+	if ($out.Count -eq 0) {
+		return
+	}
+	$directive = [int]($out[-1].TrimStart(':'))
+	$out = @($out | Select-Object -First ($out.Count - 1))
+
+	if ($out.Count -eq 0 -and ($directive -band 4) -ne 0) {
+		return ''
+	}
+	foreach ($line in $out) {
+		$value, $description = $line -split "` + "`" + `t", 2
+		if (-not $description) {
+			$description = $value
+		}
+		[System.Management.Automation.CompletionResult]::new($value, $value, 'ParameterValue', $description)
+	}
+}
+`
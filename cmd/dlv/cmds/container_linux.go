@@ -0,0 +1,130 @@
+package cmds
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// containerProcess resolves a process of the container with the given ID
+// or name to a process of the host.
+// If pid is zero the init process of the container is returned, otherwise
+// pid is interpreted in the PID namespace of the container.
+// The returned root is the root of the container filesystem, as seen
+// from the host.
+func containerProcess(container string, pid int) (hostPid int, root string, err error) {
+	initPid, err := containerInitPid(container)
+	if err != nil {
+		return 0, "", err
+	}
+	hostPid = initPid
+	if pid != 0 {
+		hostPid, err = namespacePid(initPid, pid)
+		if err != nil {
+			return 0, "", fmt.Errorf("container %s: %v", container, err)
+		}
+	}
+	return hostPid, fmt.Sprintf("/proc/%d/root", hostPid), nil
+}
+
+// containerInitPid returns the host PID of the init process of a container.
+// Processes are first matched by the container ID appearing in their
+// cgroup, which works for docker, containerd and podman without talking to
+// the container runtime, then the name is resolved with 'docker inspect'.
+func containerInitPid(container string) (int, error) {
+	if container == "" {
+		return 0, fmt.Errorf("empty container ID")
+	}
+	if pid := cgroupInitPid(container); pid != 0 {
+		return pid, nil
+	}
+	out, err := exec.Command("docker", "inspect", "--format", "{{.State.Pid}}", container).Output()
+	if err != nil {
+		return 0, fmt.Errorf("could not find container %s", container)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil || pid == 0 {
+		return 0, fmt.Errorf("container %s is not running", container)
+	}
+	return pid, nil
+}
+
+// cgroupInitPid returns the host PID of the process with PID 1 in its own
+// namespace whose cgroup path contains container, or 0.
+func cgroupInitPid(container string) int {
+	for _, pid := range hostPids() {
+		cgroup, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+		if err != nil || !strings.Contains(string(cgroup), container) {
+			continue
+		}
+		nspid := processNSpid(pid)
+		if len(nspid) > 1 && nspid[len(nspid)-1] == 1 {
+			return pid
+		}
+	}
+	return 0
+}
+
+// namespacePid returns the host PID of the process with the given pid in
+// the PID namespace of initPid.
+func namespacePid(initPid, pid int) (int, error) {
+	ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", initPid))
+	if err != nil {
+		return 0, err
+	}
+	for _, hostPid := range hostPids() {
+		nspid := processNSpid(hostPid)
+		if len(nspid) == 0 || nspid[len(nspid)-1] != pid {
+			continue
+		}
+		if ns2, _ := os.Readlink(fmt.Sprintf("/proc/%d/ns/pid", hostPid)); ns2 == ns {
+			return hostPid, nil
+		}
+	}
+	return 0, fmt.Errorf("no process with pid %d", pid)
+}
+
+// hostPids returns the PIDs of all the processes visible in /proc.
+func hostPids() []int {
+	dirs, _ := filepath.Glob("/proc/[0-9]*")
+	pids := make([]int, 0, len(dirs))
+	for _, dir := range dirs {
+		pid, err := strconv.Atoi(filepath.Base(dir))
+		if err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids
+}
+
+// processNSpid returns the PIDs of a process in the nested PID namespaces
+// it belongs to, from the outermost to the innermost one.
+func processNSpid(pid int) []int {
+	fh, err := os.Open(fmt.Sprintf("/proc/%d/status", pid))
+	if err != nil {
+		return nil
+	}
+	defer fh.Close()
+	s := bufio.NewScanner(fh)
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "NSpid:") {
+			continue
+		}
+		var r []int
+		for _, field := range strings.Fields(line[len("NSpid:"):]) {
+			n, err := strconv.Atoi(field)
+			if err != nil {
+				return nil
+			}
+			r = append(r, n)
+		}
+		return r
+	}
+	return nil
+}
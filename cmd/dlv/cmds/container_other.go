@@ -0,0 +1,9 @@
+// +build !linux
+
+package cmds
+
+import "errors"
+
+func containerProcess(container string, pid int) (hostPid int, root string, err error) {
+	return 0, "", errors.New("attaching to a container process is only supported on linux")
+}
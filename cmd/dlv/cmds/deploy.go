@@ -0,0 +1,192 @@
+package cmds
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+
+	"github.com/go-delve/delve/pkg/gobuild"
+	"github.com/go-delve/delve/service"
+	"github.com/go-delve/delve/service/debugger"
+)
+
+const (
+	// deployTimeout is how long we wait for the headless instance of Delve
+	// started by the deploy command to accept connections.
+	deployTimeout = time.Minute
+	// deployRetryInterval is the time between connection attempts.
+	deployRetryInterval = 500 * time.Millisecond
+)
+
+var (
+	// targetOS and targetArch select the platform the program is
+	// cross-compiled for by 'dlv debug'.
+	targetOS, targetArch string
+	// deployCommand is the command used to copy the cross-compiled program
+	// to the remote machine and start a headless instance of Delve there.
+	deployCommand string
+	// remoteAddr is the address of the headless instance of Delve started
+	// by deployCommand.
+	remoteAddr string
+)
+
+const deployUsage = `The --target-os and --target-arch arguments of 'dlv debug' cross-compile
+the program and debug it on a remote machine, for example a board or a
+phone that can not run the Go toolchain:
+
+	dlv debug --target-os=linux --target-arch=arm64 --remote=device:2345 \
+		--deploy='scp "$DLV_DEPLOY_BINARY" device:/tmp/__debug_bin && ssh device dlv exec --headless -l :$DLV_DEPLOY_PORT /tmp/__debug_bin -- "$@"'
+
+After building the program Delve runs the deploy command, specified with
+--deploy or with the 'deploy-command' option of the configuration file,
+using the shell ('cmd /C' on Windows). The deploy command must copy the
+executable to the remote machine (for example using scp or adb push) and
+start a headless instance of Delve there, listening on the address
+specified with --remote. The following environment variables are set for
+the deploy command:
+
+	DLV_DEPLOY_BINARY	path of the executable on the local machine
+	DLV_DEPLOY_ADDR		value of --remote
+	DLV_DEPLOY_PORT		port of --remote
+	DLV_DEPLOY_GOOS		operating system of the remote machine
+	DLV_DEPLOY_GOARCH	architecture of the remote machine
+
+Arguments for the program, specified after '--', are passed to the deploy
+command as positional parameters ("$@").
+
+Delve connects to the address specified with --remote as soon as it
+accepts connections and stops the deploy command, if it is still running,
+when the debugging session ends. The remote end must be a headless
+instance of Delve, gdb stubs are not supported.
+`
+
+// deployDebug cross-compiles the program, runs the deploy command and
+// connects to the headless instance of Delve it starts.
+func deployDebug(debugname string, pkgs, targetArgs []string) int {
+	deploy := deployCommand
+	if deploy == "" && conf != nil {
+		deploy = conf.DeployCommand
+	}
+	if deploy == "" {
+		fmt.Fprintf(os.Stderr, "--target-os and --target-arch require a deploy command, see 'dlv help deploy'\n")
+		return 1
+	}
+	if remoteAddr == "" {
+		fmt.Fprintf(os.Stderr, "--target-os and --target-arch require a remote address, see 'dlv help deploy'\n")
+		return 1
+	}
+	_, port, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid remote address %q: %v\n", remoteAddr, err)
+		return 1
+	}
+
+	goos, goarch := targetOS, targetArch
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+
+	if err := gobuild.GoCrossBuild(debugname, pkgs, buildFlags, goos, goarch); err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	defer gobuild.Remove(debugname)
+
+	hook := newDeployHook(deploy, targetArgs)
+	hook.cmd.Env = append(os.Environ(),
+		"DLV_DEPLOY_BINARY="+debugname,
+		"DLV_DEPLOY_ADDR="+remoteAddr,
+		"DLV_DEPLOY_PORT="+port,
+		"DLV_DEPLOY_GOOS="+goos,
+		"DLV_DEPLOY_GOARCH="+goarch)
+	if err := hook.start(); err != nil {
+		fmt.Fprintf(os.Stderr, "could not run deploy command: %v\n", err)
+		return 1
+	}
+	defer hook.stop()
+
+	conn, err := waitForDeployServer(remoteAddr, hook, deployTimeout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		return 1
+	}
+	conn, err = secureConn(conn, remoteAddr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not connect to %s: %v\n", remoteAddr, err)
+		return 1
+	}
+	return connect(remoteAddr, conn, conf, debugger.ExecutingOther)
+}
+
+// deployHook is an execution of the deploy command.
+type deployHook struct {
+	cmd  *exec.Cmd
+	done chan struct{} // closed when cmd exits
+	err  error         // exit status of cmd, valid after done is closed
+}
+
+// newDeployHook returns a hook that runs deploy using the shell.
+func newDeployHook(deploy string, targetArgs []string) *deployHook {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", append([]string{"/C", deploy}, targetArgs...)...)
+	} else {
+		cmd = exec.Command("/bin/sh", append([]string{"-c", deploy, "dlv-deploy"}, targetArgs...)...)
+	}
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return &deployHook{cmd: cmd, done: make(chan struct{})}
+}
+
+func (hook *deployHook) start() error {
+	if err := hook.cmd.Start(); err != nil {
+		return err
+	}
+	go func() {
+		hook.err = hook.cmd.Wait()
+		close(hook.done)
+	}()
+	return nil
+}
+
+// stop kills the deploy command if it is still running.
+func (hook *deployHook) stop() {
+	select {
+	case <-hook.done:
+	default:
+		hook.cmd.Process.Kill()
+		<-hook.done
+	}
+}
+
+// waitForDeployServer connects to addr, retrying until it succeeds or the
+// timeout expires. The deploy command is allowed to exit before addr
+// accepts connections (for example after starting Delve in the background
+// on the remote machine) but only if it succeeds.
+func waitForDeployServer(addr string, hook *deployHook, timeout time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	done := hook.done
+	for {
+		conn, err := service.DialTimeout(addr, deployRetryInterval)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("could not connect to %s: %v", addr, err)
+		}
+		select {
+		case <-done:
+			if hook.err != nil {
+				return nil, fmt.Errorf("deploy command failed: %v", hook.err)
+			}
+			done = nil
+		case <-time.After(deployRetryInterval):
+		}
+	}
+}
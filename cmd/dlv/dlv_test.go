@@ -252,6 +252,54 @@ func TestContinue(t *testing.T) {
 	cmd.Wait()
 }
 
+// TestContinueReconnect verifies that a headless instance started with
+// --continue keeps running when its client disconnects.
+func TestContinueReconnect(t *testing.T) {
+	const listenAddr = "127.0.0.1:40574"
+
+	dlvbin, tmpdir := getDlvBin(t)
+	defer os.RemoveAll(tmpdir)
+
+	fixture := protest.BuildFixture("loopprog", 0)
+	cmd := exec.Command(dlvbin, "exec", "--headless", "--continue", "--listen", listenAddr, fixture.Path)
+	stdout, err := cmd.StdoutPipe()
+	assertNoError(err, t, "stdout pipe")
+	defer stdout.Close()
+
+	assertNoError(cmd.Start(), t, "start headless instance")
+	defer cmd.Process.Kill()
+
+	scan := bufio.NewScanner(stdout)
+	// wait for the debugger to start
+	for scan.Scan() {
+		t.Log(scan.Text())
+		if strings.HasPrefix(scan.Text(), "API server listening") {
+			break
+		}
+	}
+
+	// connect and disconnect without detaching, twice
+	for i := 0; i < 2; i++ {
+		client := rpc2.NewClient(listenAddr)
+		if !client.IsMulticlient() {
+			t.Errorf("client %d: headless instance does not keep running after clients disconnect", i)
+		}
+		state, err := client.GetStateNonBlocking()
+		assertNoError(err, t, "GetStateNonBlocking")
+		if !state.Running {
+			t.Errorf("client %d: target is not running", i)
+		}
+		assertNoError(client.Disconnect(false), t, "Disconnect")
+	}
+
+	// and detach from and kill the headless instance
+	client := rpc2.NewClient(listenAddr)
+	if err := client.Detach(true); err != nil {
+		t.Fatalf("error detaching from headless instance: %v", err)
+	}
+	cmd.Wait()
+}
+
 // TestChildProcessExitWhenNoDebugInfo verifies that the child process exits when dlv launch the binary without debug info
 func TestChildProcessExitWhenNoDebugInfo(t *testing.T) {
 	if runtime.GOOS == "darwin" {
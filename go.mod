@@ -14,10 +14,10 @@ require (
 	github.com/peterh/liner v0.0.0-20170317030525-88609521dc4b
 	github.com/sirupsen/logrus v1.6.0
 	github.com/spf13/cobra v0.0.0-20170417170307-b6cb39589372
-	github.com/spf13/pflag v0.0.0-20170417173400-9e4c21054fa1 // indirect
+	github.com/spf13/pflag v0.0.0-20170417173400-9e4c21054fa1
 	go.starlark.net v0.0.0-20200821142938-949cc6f4b097
 	golang.org/x/arch v0.0.0-20190927153633-4e8777c89be4
-	golang.org/x/sys v0.0.0-20200625212154-ddb9806d33ae
+	golang.org/x/sys v0.0.0-20220520151302-bc2c85ada10a
 	golang.org/x/tools v0.0.0-20191127201027-ecd32218bd7f
 	gopkg.in/yaml.v2 v2.2.1
 )
@@ -53,6 +53,17 @@ type Config struct {
 	// expression for its argument.
 	ShowLocationExpr bool `yaml:"show-location-expr"`
 
+	// If CheckBugPatterns is true every time the program stops the current
+	// stack frame is inspected for common mistakes (slices sharing the same
+	// backing array, writes to nil maps and goroutines sharing the same loop
+	// variable) and a warning is printed for each one of them.
+	CheckBugPatterns bool `yaml:"check-bug-patterns"`
+
+	// If PseudoSource is true, when the source file of a function can not be
+	// found a pseudo-source listing of the function (basic blocks, calls
+	// and decoded conditions) is printed in its place.
+	PseudoSource bool `yaml:"pseudo-source"`
+
 	// Source list line-number color (3/4 bit color codes as defined
 	// here: https://en.wikipedia.org/wiki/ANSI_escape_code#Colors),
 	// or a string containing a terminal escape sequence.
@@ -80,6 +91,11 @@ type Config struct {
 	// DebugFileDirectories is the list of directories Delve will use
 	// in order to resolve external debug info files.
 	DebugInfoDirectories []string `yaml:"debug-info-directories"`
+
+	// DeployCommand is the command used by 'dlv debug --target-os/--target-arch'
+	// to copy the executable to a remote machine and start a headless
+	// instance of Delve there, see 'dlv help deploy'.
+	DeployCommand string `yaml:"deploy-command,omitempty"`
 }
 
 func (c *Config) GetSourceListLineCount() int {
@@ -269,11 +285,22 @@ substitute-path:
 # Uncomment the following line to make the whatis command also print the DWARF location expression of its argument.
 # show-location-expr: true
 
+# Uncomment the following line to print a warning for likely mistakes (for
+# example two slices sharing the same backing array) every time the program stops.
+# check-bug-patterns: true
+
+# Uncomment the following line to print a pseudo-source listing of functions
+# whose source files are not available, instead of an error.
+# pseudo-source: true
+
 # Allow user to specify output syntax flavor of assembly, one of this list "intel"(default), "gnu", "go".
 # disassemble-flavor: intel
 
 # List of directories to use when searching for separate debug info files.
 debug-info-directories: ["/usr/lib/debug/.build-id"]
+
+# Command used to deploy cross compiled executables to a remote machine (see 'dlv help deploy').
+# deploy-command: 'scp "$DLV_DEPLOY_BINARY" device:/tmp/__debug_bin && ssh device dlv exec --headless -l :$DLV_DEPLOY_PORT /tmp/__debug_bin -- "$@"'
 `)
 	return err
 }
@@ -0,0 +1,252 @@
+// Package delve is the API used to embed Delve in other programs, to
+// inspect running Go processes and core files programmatically.
+//
+// # Stability
+//
+// Unlike the other packages of Delve, and pkg/proc in particular, this
+// package follows semantic versioning: exported identifiers will not be
+// removed or changed in incompatible ways unless the major version of
+// APIVersion is incremented, new functionality increments the minor
+// version.
+// The types of values returned by this package are aliases of the types
+// of service/api, which is also used by the JSON-RPC API and is subject
+// to the same guarantees, fields can be added to them in minor versions.
+//
+// # Concurrency
+//
+// The methods of Target are safe for concurrent use, a call to Halt can be
+// used to stop a call to Continue from another goroutine. While the target
+// is running the other methods block until it stops.
+package delve
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+	"github.com/go-delve/delve/service/debugger"
+)
+
+// APIVersion is the version of the embedding API.
+const APIVersion = "1.0.0"
+
+// Types describing the state of the target, see the documentation of
+// service/api.
+type (
+	Breakpoint = api.Breakpoint
+	Goroutine  = api.Goroutine
+	Location   = api.Location
+	LoadConfig = api.LoadConfig
+	Stackframe = api.Stackframe
+	State      = api.DebuggerState
+	Variable   = api.Variable
+)
+
+// DefaultLoadConfig is the configuration used to load variables by the
+// print command of the terminal client.
+var DefaultLoadConfig = LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+
+// ErrTargetExited is returned by the methods of Target that resume the
+// target when it exits.
+var ErrTargetExited = errors.New("target exited")
+
+// Config configures how a target is opened.
+type Config struct {
+	// WorkingDir is the working directory of processes started by Launch.
+	WorkingDir string
+	// Backend is one of "default", "native", "lldb" or "rr", see 'dlv help
+	// backend'. An empty backend is the same as "default".
+	Backend string
+	// DebugInfoDirectories are the directories where separate debug info
+	// files are searched.
+	DebugInfoDirectories []string
+	// Foreground lets processes started by Launch read from the terminal.
+	Foreground bool
+}
+
+// Target is a process or a core file being inspected.
+type Target struct {
+	d *debugger.Debugger
+
+	mu        sync.Mutex
+	renderers map[string]Renderer
+}
+
+func open(cfg Config, dcfg debugger.Config, args []string) (*Target, error) {
+	dcfg.WorkingDir = cfg.WorkingDir
+	dcfg.Backend = cfg.Backend
+	if dcfg.Backend == "" {
+		dcfg.Backend = "default"
+	}
+	dcfg.DebugInfoDirectories = cfg.DebugInfoDirectories
+	dcfg.Foreground = cfg.Foreground
+	dcfg.ExecuteKind = debugger.ExecutingOther
+	d, err := debugger.New(&dcfg, args)
+	if err != nil {
+		return nil, err
+	}
+	return &Target{d: d}, nil
+}
+
+// Launch starts the executable args[0] with arguments args[1:], the
+// process is stopped before executing its first instruction.
+func Launch(args []string, cfg Config) (*Target, error) {
+	if len(args) == 0 {
+		return nil, errors.New("no executable specified")
+	}
+	return open(cfg, debugger.Config{ExecuteKind: debugger.ExecutingExistingFile}, args)
+}
+
+// Attach attaches to the running process pid and stops it. If exe is not
+// empty it is used as the executable file of the process.
+func Attach(pid int, exe string, cfg Config) (*Target, error) {
+	var args []string
+	if exe != "" {
+		args = []string{exe}
+	}
+	return open(cfg, debugger.Config{AttachPid: pid}, args)
+}
+
+// OpenCore opens the core file core of executable exe.
+func OpenCore(core, exe string, cfg Config) (*Target, error) {
+	return open(cfg, debugger.Config{CoreFile: core}, []string{exe})
+}
+
+// Detach detaches from the target, leaving it running. If kill is true
+// the process is killed. Processes started by Launch are always killed.
+func (t *Target) Detach(kill bool) error {
+	return t.d.Detach(kill)
+}
+
+// State returns the current state of the target.
+func (t *Target) State() (*State, error) {
+	return t.d.State(false)
+}
+
+func (t *Target) command(name string) (*State, error) {
+	state, err := t.d.Command(&api.DebuggerCommand{Name: name}, nil)
+	if _, exited := err.(proc.ErrProcessExited); exited || (err == nil && state.Exited) {
+		return state, ErrTargetExited
+	}
+	return state, err
+}
+
+// Continue resumes the target until it hits a breakpoint, it is stopped
+// by Halt or it exits, in which case ErrTargetExited is returned.
+func (t *Target) Continue() (*State, error) {
+	return t.command(api.Continue)
+}
+
+// Next steps the current goroutine to the next source line, stepping over
+// function calls.
+func (t *Target) Next() (*State, error) {
+	return t.command(api.Next)
+}
+
+// Step steps the current goroutine to the next source line, entering
+// function calls.
+func (t *Target) Step() (*State, error) {
+	return t.command(api.Step)
+}
+
+// StepOut resumes the target until the current function returns.
+func (t *Target) StepOut() (*State, error) {
+	return t.command(api.StepOut)
+}
+
+// Halt stops the target, it can be called while another goroutine is
+// waiting for Continue to return.
+func (t *Target) Halt() error {
+	_, err := t.d.Command(&api.DebuggerCommand{Name: api.Halt}, nil)
+	return err
+}
+
+// SwitchGoroutine makes goid the current goroutine.
+func (t *Target) SwitchGoroutine(goid int) error {
+	_, err := t.d.Command(&api.DebuggerCommand{Name: api.SwitchGoroutine, GoroutineID: goid}, nil)
+	return err
+}
+
+// Goroutines returns all the goroutines of the target.
+func (t *Target) Goroutines() ([]*Goroutine, error) {
+	gs, _, err := t.d.Goroutines(0, 0)
+	if err != nil {
+		return nil, err
+	}
+	t.d.LockTarget()
+	defer t.d.UnlockTarget()
+	return api.ConvertGoroutines(t.d.Target(), gs), nil
+}
+
+// Stacktrace returns at most depth frames of the stack of goroutine goid,
+// -1 is the current goroutine. If cfg is not nil the local variables and
+// the arguments of each frame are loaded.
+func (t *Target) Stacktrace(goid, depth int, cfg *LoadConfig) ([]Stackframe, error) {
+	frames, err := t.d.Stacktrace(goid, depth, 0)
+	if err != nil {
+		return nil, err
+	}
+	r, err := t.d.ConvertStacktrace(frames, api.LoadConfigToProc(cfg))
+	if err != nil {
+		return nil, err
+	}
+	for i := range r {
+		for j := range r[i].Locals {
+			t.render(&r[i].Locals[j])
+		}
+		for j := range r[i].Arguments {
+			t.render(&r[i].Arguments[j])
+		}
+	}
+	return r, nil
+}
+
+// Eval evaluates expr in the scope of frame of goroutine goid, -1 is the
+// current goroutine. See Documentation/cli/expr.md for the syntax of
+// expressions.
+func (t *Target) Eval(goid, frame int, expr string, cfg LoadConfig) (*Variable, error) {
+	v, err := t.d.EvalVariableInScope(goid, frame, 0, expr, *api.LoadConfigToProc(&cfg))
+	if err != nil {
+		return nil, err
+	}
+	r := api.ConvertVar(v)
+	t.render(r)
+	return r, nil
+}
+
+// FindLocation returns the locations matching spec, see 'dlv help break'
+// for the syntax of locations.
+func (t *Target) FindLocation(spec string) ([]Location, error) {
+	return t.d.FindLocation(-1, 0, 0, spec, false, nil)
+}
+
+// SetBreakpoint sets a breakpoint at the location spec, which must match
+// a single location. If cond is not empty the target only stops when cond
+// evaluates to true.
+func (t *Target) SetBreakpoint(spec, cond string) (*Breakpoint, error) {
+	locs, err := t.FindLocation(spec)
+	if err != nil {
+		return nil, err
+	}
+	if len(locs) != 1 {
+		return nil, fmt.Errorf("location %q is ambiguous, it matches %d locations", spec, len(locs))
+	}
+	return t.d.CreateBreakpoint(&api.Breakpoint{Addr: locs[0].PC, Addrs: locs[0].PCs, Cond: cond})
+}
+
+// ClearBreakpoint removes the breakpoint with the given ID.
+func (t *Target) ClearBreakpoint(id int) error {
+	bp := t.d.FindBreakpoint(id)
+	if bp == nil {
+		return fmt.Errorf("no breakpoint with id %d", id)
+	}
+	_, err := t.d.ClearBreakpoint(bp)
+	return err
+}
+
+// Breakpoints returns the breakpoints of the target.
+func (t *Target) Breakpoints() []*Breakpoint {
+	return t.d.Breakpoints()
+}
@@ -0,0 +1,139 @@
+package delve
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"testing"
+
+	protest "github.com/go-delve/delve/pkg/proc/test"
+)
+
+var testBackend string
+
+func TestMain(m *testing.M) {
+	flag.StringVar(&testBackend, "backend", "", "selects backend")
+	flag.Parse()
+	protest.DefaultTestBackend(&testBackend)
+	os.Exit(protest.RunTestsWithFixtures(m))
+}
+
+func TestLaunch(t *testing.T) {
+	fixture := protest.BuildFixture("increment", 0)
+	tgt, err := Launch([]string{fixture.Path}, Config{Backend: testBackend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tgt.Detach(true)
+
+	bp, err := tgt.SetBreakpoint("main.Increment", "y == 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, err := tgt.Continue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.CurrentThread == nil || state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.ID != bp.ID {
+		t.Fatalf("target did not stop at breakpoint %d: %#v", bp.ID, state.CurrentThread)
+	}
+
+	v, err := tgt.Eval(-1, 0, "y", DefaultLoadConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Value != "1" {
+		t.Errorf("wrong value of y: %s", v.Value)
+	}
+
+	frames, err := tgt.Stacktrace(-1, 10, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// main.main -> Increment(3) -> Increment(1)
+	if len(frames) < 3 || frames[0].Function.Name() != "main.Increment" || frames[2].Function.Name() != "main.main" {
+		t.Errorf("wrong stacktrace %#v", frames)
+	}
+
+	gs, err := tgt.Goroutines()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gs) == 0 {
+		t.Errorf("no goroutines")
+	}
+
+	if err := tgt.ClearBreakpoint(bp.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(tgt.Breakpoints()) != 0 {
+		t.Errorf("breakpoint not cleared: %v", tgt.Breakpoints())
+	}
+	if _, err := tgt.Continue(); err != ErrTargetExited {
+		t.Errorf("expected ErrTargetExited, got %v", err)
+	}
+}
+
+func TestBuiltinsAndRenderers(t *testing.T) {
+	fixture := protest.BuildFixture("increment", 0)
+	tgt, err := Launch([]string{fixture.Path}, Config{Backend: testBackend})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tgt.Detach(true)
+
+	twice := func(args []*Variable) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("wrong number of arguments: %d", len(args))
+		}
+		n, err := strconv.ParseInt(args[0].Value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		return 2 * n, nil
+	}
+	if err := tgt.RegisterBuiltin("twice", twice); err != nil {
+		t.Fatal(err)
+	}
+	if err := tgt.RegisterBuiltin("twice", twice); err == nil {
+		t.Errorf("registering a builtin twice should fail")
+	}
+	if err := tgt.RegisterBuiltin("len", twice); err == nil {
+		t.Errorf("registering a builtin with a reserved name should fail")
+	}
+
+	if _, err := tgt.SetBreakpoint("main.Increment", "twice(y) == 2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tgt.Continue(); err != nil {
+		t.Fatal(err)
+	}
+	v, err := tgt.Eval(-1, 0, "twice(y)", DefaultLoadConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Value != "2" {
+		t.Errorf("wrong value of twice(y): %s", v.Value)
+	}
+
+	if err := tgt.RegisterRenderer("uint", func(v *Variable) string { return "y is " + v.Value }); err != nil {
+		t.Fatal(err)
+	}
+	v, err = tgt.Eval(-1, 0, "y", DefaultLoadConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Value != "y is 1" {
+		t.Errorf("wrong rendered value of y: %s", v.Value)
+	}
+	if err := tgt.UnregisterRenderer("uint"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tgt.UnregisterBuiltin("twice"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tgt.Eval(-1, 0, "twice(y)", DefaultLoadConfig); err == nil {
+		t.Errorf("twice(y) evaluated after UnregisterBuiltin")
+	}
+}
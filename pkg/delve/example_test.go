@@ -0,0 +1,130 @@
+package delve_test
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-delve/delve/pkg/delve"
+)
+
+// This example launches a program, stops at the first call of a function
+// and prints the value of one of its arguments and the stack.
+func ExampleLaunch() {
+	tgt, err := delve.Launch([]string{"./myprogram", "arg1"}, delve.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tgt.Detach(true)
+
+	if _, err := tgt.SetBreakpoint("main.handleRequest", ""); err != nil {
+		log.Fatal(err)
+	}
+	if _, err := tgt.Continue(); err != nil {
+		log.Fatal(err)
+	}
+
+	v, err := tgt.Eval(-1, 0, "req.URL.Path", delve.DefaultLoadConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("path:", v.Value)
+
+	frames, err := tgt.Stacktrace(-1, 20, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, frame := range frames {
+		fmt.Printf("%s\n\t%s:%d\n", frame.Function.Name(), frame.File, frame.Line)
+	}
+}
+
+// This example lists the goroutines of a core file that are blocked.
+func ExampleOpenCore() {
+	tgt, err := delve.OpenCore("core", "./myprogram", delve.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tgt.Detach(false)
+
+	gs, err := tgt.Goroutines()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, g := range gs {
+		if g.WaitReason != 0 {
+			fmt.Printf("goroutine %d at %s:%d\n", g.ID, g.UserCurrentLoc.File, g.UserCurrentLoc.Line)
+		}
+	}
+}
+
+// This example stops a process attached to by its pid after 5 seconds and
+// prints the value of a global variable.
+func ExampleTarget_Halt() {
+	tgt, err := delve.Attach(1234, "", delve.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tgt.Detach(false)
+
+	go func() {
+		time.Sleep(5 * time.Second)
+		tgt.Halt()
+	}()
+	if _, err := tgt.Continue(); err != nil {
+		log.Fatal(err)
+	}
+	v, err := tgt.Eval(-1, 0, "main.requestCount", delve.DefaultLoadConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println("requests:", v.Value)
+}
+
+// This example adds a builtin that looks up a pod by name in a map of the
+// target, and a renderer that displays pods by their name and phase.
+func ExampleTarget_RegisterBuiltin() {
+	tgt, err := delve.Attach(1234, "", delve.Config{})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tgt.Detach(false)
+
+	err = tgt.RegisterBuiltin("podof", func(args []*delve.Variable) (interface{}, error) {
+		if len(args) != 1 || args[0].Type != "string" {
+			return nil, errors.New("podof takes a pod name")
+		}
+		return delve.Expr(fmt.Sprintf("main.controller.pods[%q]", args[0].Value)), nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = tgt.RegisterRenderer("*k8s.io/api/core/v1.Pod", func(v *delve.Variable) string {
+		if len(v.Children) == 0 {
+			return v.Value
+		}
+		pod := v.Children[0]
+		var name, phase string
+		for _, field := range pod.Children {
+			for _, f := range field.Children {
+				switch f.Name {
+				case "Name":
+					name = f.Value
+				case "Phase":
+					phase = f.Value
+				}
+			}
+		}
+		return fmt.Sprintf("pod %s (%s)", name, phase)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	v, err := tgt.Eval(-1, 0, `podof("web-0")`, delve.DefaultLoadConfig)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(v.Value)
+}
@@ -0,0 +1,135 @@
+package delve
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+)
+
+// Builtin is a function that can be called in the expressions evaluated
+// by Eval and in breakpoint conditions, see RegisterBuiltin.
+// It receives the values of the arguments of the call, loaded with
+// DefaultLoadConfig, and returns a bool, a string, an int, an int64, an
+// uint64, a float64 or an Expr.
+type Builtin func(args []*Variable) (interface{}, error)
+
+// Expr is an expression returned by a Builtin, it is evaluated in the
+// scope of the call and its value becomes the value of the call.
+// For example a builtin podof(name) could return
+//
+//	Expr(fmt.Sprintf("pods[%q]", name))
+type Expr string
+
+// Renderer returns the text displayed as the value of v, see
+// RegisterRenderer.
+type Renderer func(v *Variable) string
+
+// RegisterBuiltin makes fn callable as name in the expressions evaluated
+// on t. Names of the predeclared identifiers of Go, of the builtins of
+// Delve (see Documentation/cli/expr.md) and of builtins already registered
+// are rejected. Variables of the target with the same name as a builtin
+// take precedence over it.
+// Builtins stay registered if the target is restarted. fn is called while
+// the target is being inspected and must not call the methods of t.
+func (t *Target) RegisterBuiltin(name string, fn Builtin) error {
+	if fn == nil {
+		return errors.New("nil builtin")
+	}
+	return t.d.RegisterBuiltin(name, proc.CustomBuiltin{
+		Fn: func(scope *proc.EvalScope, args []*proc.Variable) (*proc.Variable, error) {
+			apiargs := make([]*Variable, len(args))
+			for i := range args {
+				apiargs[i] = api.ConvertVar(args[i])
+			}
+			r, err := fn(apiargs)
+			if err != nil {
+				return nil, err
+			}
+			return builtinResult(scope, r)
+		},
+		LoadConfig: *api.LoadConfigToProc(&DefaultLoadConfig),
+	})
+}
+
+// UnregisterBuiltin removes a builtin registered with RegisterBuiltin.
+func (t *Target) UnregisterBuiltin(name string) error {
+	return t.d.UnregisterBuiltin(name)
+}
+
+func builtinResult(scope *proc.EvalScope, r interface{}) (*proc.Variable, error) {
+	var val constant.Value
+	switch r := r.(type) {
+	case bool:
+		val = constant.MakeBool(r)
+	case string:
+		val = constant.MakeString(r)
+	case int:
+		val = constant.MakeInt64(int64(r))
+	case int64:
+		val = constant.MakeInt64(r)
+	case uint64:
+		val = constant.MakeUint64(r)
+	case float64:
+		val = constant.MakeFloat64(r)
+	case Expr:
+		return scope.EvalExpression(string(r), *api.LoadConfigToProc(&DefaultLoadConfig))
+	default:
+		return nil, fmt.Errorf("unsupported return value of type %T", r)
+	}
+	return proc.NewConstant(val, scope.Mem), nil
+}
+
+// RegisterRenderer sets the function used to display the values of type
+// typeName, the fully qualified name of a type (for example
+// "k8s.io/api/core/v1.Pod"), returned by Eval and Stacktrace. Each type
+// can have only one renderer.
+func (t *Target) RegisterRenderer(typeName string, r Renderer) error {
+	if r == nil {
+		return errors.New("nil renderer")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.renderers[typeName]; exists {
+		return fmt.Errorf("renderer for %s already registered", typeName)
+	}
+	if t.renderers == nil {
+		t.renderers = make(map[string]Renderer)
+	}
+	t.renderers[typeName] = r
+	return nil
+}
+
+// UnregisterRenderer removes the renderer of typeName registered with
+// RegisterRenderer.
+func (t *Target) UnregisterRenderer(typeName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, exists := t.renderers[typeName]; !exists {
+		return fmt.Errorf("no renderer registered for %s", typeName)
+	}
+	delete(t.renderers, typeName)
+	return nil
+}
+
+// render replaces the values of v and of its children with the text
+// returned by their renderers.
+func (t *Target) render(v *Variable) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.renderLocked(v)
+}
+
+func (t *Target) renderLocked(v *Variable) {
+	if len(t.renderers) == 0 {
+		return
+	}
+	for i := range v.Children {
+		t.renderLocked(&v.Children[i])
+	}
+	if r := t.renderers[v.Type]; r != nil {
+		v.Value = r(v)
+	}
+}
@@ -0,0 +1,410 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/hashicorp/golang-lru/simplelru"
+)
+
+const (
+	cieCacheSize = 64   // number of decoded CIEs kept for each section
+	fdeCacheSize = 1024 // number of decoded FDEs kept for each section
+)
+
+// Index finds the Frame Description Entry covering a PC among the
+// entries of a set of .debug_frame and .eh_frame sections.
+// Entries are only decoded when they are needed and the most recently
+// used ones are cached, which keeps the cost of loading huge executables
+// and shared libraries low.
+// The zero value is an empty index.
+type Index struct {
+	sections []*section
+}
+
+// section is a .debug_frame or .eh_frame section added to an Index.
+type section struct {
+	data        []byte
+	order       binary.ByteOrder
+	staticBase  uint64
+	ptrSize     int
+	ehFrameAddr uint64 // address of the .eh_frame section, zero for .debug_frame
+
+	// hdr is the binary search table of the .eh_frame_hdr section, when
+	// available it is used to find FDEs instead of refs.
+	hdr *ehFrameHdr
+	// refs are the address ranges covered by the FDEs of the section,
+	// sorted by their start address.
+	refs []fdeRef
+	// delta is added to the start address of all FDEs, see
+	// TranslateDebugFrame.
+	delta uint64
+
+	mu   sync.Mutex
+	cies *simplelru.LRU // section offset -> *CommonInformationEntry
+	fdes *simplelru.LRU // section offset -> *FrameDescriptionEntry
+}
+
+// fdeRef is the address range covered by the FDE at offset off of its
+// section.
+type fdeRef struct {
+	begin, end uint64
+	off        int
+}
+
+// ehFrameHdr is the binary search table of a .eh_frame_hdr section, see
+// https://refspecs.linuxfoundation.org/LSB_5.0.0/LSB-Core-generic/LSB-Core-generic/ehframechpt.html.
+type ehFrameHdr struct {
+	addr      uint64 // address of the .eh_frame_hdr section
+	table     []byte
+	tableOff  int // offset of table in the .eh_frame_hdr section
+	enc       ptrEnc
+	entrySize int
+	count     int
+}
+
+// AddDebugFrame adds the .debug_frame section data to the index.
+// The address ranges of its FDEs are read right away, to report
+// malformed sections, their instructions are only decoded on demand.
+func (idx *Index) AddDebugFrame(data []byte, order binary.ByteOrder, staticBase uint64, ptrSize int) error {
+	s := newSection(data, order, staticBase, ptrSize, 0)
+	if err := s.scan(); err != nil {
+		return err
+	}
+	idx.sections = append(idx.sections, s)
+	return nil
+}
+
+// AddEHFrame adds the .eh_frame section data, mapped at ehFrameAddr, to
+// the index. If hdrData, the .eh_frame_hdr section mapped at hdrAddr, is
+// not nil and contains a binary search table FDEs are looked up using it,
+// without reading the .eh_frame section in advance.
+func (idx *Index) AddEHFrame(data []byte, hdrData []byte, hdrAddr uint64, order binary.ByteOrder, staticBase uint64, ptrSize int, ehFrameAddr uint64) error {
+	if ehFrameAddr == 0 {
+		return errors.New("unknown .eh_frame address")
+	}
+	s := newSection(data, order, staticBase, ptrSize, ehFrameAddr)
+	if hdrData != nil {
+		s.hdr = s.parseEHFrameHdr(hdrData, hdrAddr)
+	}
+	if s.hdr == nil {
+		if err := s.scan(); err != nil {
+			return err
+		}
+	}
+	idx.sections = append(idx.sections, s)
+	return nil
+}
+
+// FDEForPC returns the Frame Description Entry for the given PC.
+// Sections are searched in the order they were added.
+func (idx *Index) FDEForPC(pc uint64) (*FrameDescriptionEntry, error) {
+	for _, s := range idx.sections {
+		fde, err := s.fdeForPC(pc)
+		if err != nil {
+			return nil, err
+		}
+		if fde != nil {
+			return fde, nil
+		}
+	}
+	return nil, &ErrNoFDEForPC{pc}
+}
+
+// FirstDebugFrameFDE returns the FDE with the lowest start address among
+// the FDEs of the .debug_frame sections of the index.
+func (idx *Index) FirstDebugFrameFDE() *FrameDescriptionEntry {
+	var r *FrameDescriptionEntry
+	for _, s := range idx.sections {
+		if s.ehFrameAddr != 0 || len(s.refs) == 0 {
+			continue
+		}
+		if r != nil && r.Begin() <= s.refs[0].begin {
+			continue
+		}
+		s.mu.Lock()
+		fde, err := s.fdeAt(s.refs[0].off)
+		s.mu.Unlock()
+		if err == nil {
+			r = fde
+		}
+	}
+	return r
+}
+
+// TranslateDebugFrame moves the beginning of the FDEs of the .debug_frame
+// sections of the index forward by delta.
+func (idx *Index) TranslateDebugFrame(delta uint64) {
+	for _, s := range idx.sections {
+		if s.ehFrameAddr != 0 {
+			continue
+		}
+		s.mu.Lock()
+		s.delta += delta
+		for i := range s.refs {
+			s.refs[i].begin += delta
+			s.refs[i].end += delta
+		}
+		s.fdes.Purge()
+		s.mu.Unlock()
+	}
+}
+
+func newSection(data []byte, order binary.ByteOrder, staticBase uint64, ptrSize int, ehFrameAddr uint64) *section {
+	s := &section{data: data, order: order, staticBase: staticBase, ptrSize: ptrSize, ehFrameAddr: ehFrameAddr}
+	s.cies, _ = simplelru.NewLRU(cieCacheSize, nil)
+	s.fdes, _ = simplelru.NewLRU(fdeCacheSize, nil)
+	return s
+}
+
+// scan reads the address ranges of all the FDEs of the section.
+func (s *section) scan() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ctx := &parseContext{
+		staticBase:  s.staticBase,
+		order:       s.order,
+		ptrSize:     s.ptrSize,
+		ehFrameAddr: s.ehFrameAddr,
+		frame:       &FrameDescriptionEntry{},
+	}
+	r := bytes.NewReader(nil)
+	for off := 0; off+4 <= len(s.data); {
+		length := s.order.Uint32(s.data[off:])
+		next := off + 4 + int(length)
+		if length == 0 {
+			off = next
+			continue
+		}
+		if off+8 > len(s.data) {
+			return fmt.Errorf("truncated entry at %#x", off)
+		}
+		if cieOff, isFDE := s.cieOffset(off); isFDE {
+			cie, err := s.cieAt(cieOff)
+			if err != nil {
+				return err
+			}
+			ctx.frame.CIE = cie
+			r.Reset(s.data[off+8:])
+			ctx.readFDERange(r, off+8)
+			s.refs = append(s.refs, fdeRef{begin: ctx.frame.begin, end: ctx.frame.End(), off: off})
+		}
+		off = next
+	}
+	sort.SliceStable(s.refs, func(i, j int) bool {
+		return s.refs[i].begin < s.refs[j].begin
+	})
+	return nil
+}
+
+// parseEHFrameHdr returns the binary search table of the .eh_frame_hdr
+// section data, mapped at addr, or nil if it doesn't have one or it uses
+// an encoding that doesn't allow binary searching it.
+func (s *section) parseEHFrameHdr(data []byte, addr uint64) *ehFrameHdr {
+	if len(data) < 4 || data[0] != 1 {
+		return nil
+	}
+	ehFramePtrEnc, countEnc, tableEnc := ptrEnc(data[1]), ptrEnc(data[2]), ptrEnc(data[3])
+	if ehFramePtrEnc == ptrEncOmit || countEnc == ptrEncOmit || countEnc&0xf0 != 0 || tableEnc == ptrEncOmit {
+		return nil
+	}
+	switch tableEnc & 0xf0 {
+	case ptrEncAbs, ptrEncPCRel, ptrEncDataRel:
+	default:
+		return nil
+	}
+	var entrySize int
+	switch tableEnc & 0x0f {
+	case ptrEncAbs, ptrEncSigned:
+		entrySize = 2 * s.ptrSize
+	case ptrEncUdata2, ptrEncSdata2:
+		entrySize = 2 * 2
+	case ptrEncUdata4, ptrEncSdata4:
+		entrySize = 2 * 4
+	case ptrEncUdata8, ptrEncSdata8:
+		entrySize = 2 * 8
+	default:
+		return nil
+	}
+	ctx := &parseContext{order: s.order, ptrSize: s.ptrSize}
+	r := bytes.NewReader(data[4:])
+	ctx.readEncodedPtr(0, r, ehFramePtrEnc&0x0f)
+	count := ctx.readEncodedPtr(0, r, countEnc)
+	tableOff := len(data) - r.Len()
+	if count > uint64(r.Len()/entrySize) {
+		return nil
+	}
+	return &ehFrameHdr{
+		addr:      addr,
+		table:     data[tableOff:],
+		tableOff:  tableOff,
+		enc:       tableEnc,
+		entrySize: entrySize,
+		count:     int(count),
+	}
+}
+
+// entry returns the start address, relative to the static base, and the
+// address of the FDE of the i-th entry of the table.
+func (hdr *ehFrameHdr) entry(i int, order binary.ByteOrder, ptrSize int) (begin, fdeAddr uint64) {
+	ctx := &parseContext{order: order, ptrSize: ptrSize}
+	off := i * hdr.entrySize
+	r := bytes.NewReader(hdr.table[off : off+hdr.entrySize])
+	read := func() uint64 {
+		pos := uint64(hdr.tableOff + off + hdr.entrySize - r.Len())
+		v := ctx.readEncodedPtr(0, r, hdr.enc&0x0f)
+		switch hdr.enc & 0xf0 {
+		case ptrEncPCRel:
+			v += hdr.addr + pos
+		case ptrEncDataRel:
+			v += hdr.addr
+		}
+		return v
+	}
+	begin = read()
+	fdeAddr = read()
+	return begin, fdeAddr
+}
+
+// fdeForPC returns the FDE of the section covering pc, or nil.
+func (s *section) fdeForPC(pc uint64) (*FrameDescriptionEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var off int
+	if s.hdr != nil {
+		i := sort.Search(s.hdr.count, func(i int) bool {
+			begin, _ := s.hdr.entry(i, s.order, s.ptrSize)
+			return begin+s.staticBase > pc
+		}) - 1
+		if i < 0 {
+			return nil, nil
+		}
+		_, fdeAddr := s.hdr.entry(i, s.order, s.ptrSize)
+		off = int(fdeAddr - s.ehFrameAddr)
+	} else {
+		i := sort.Search(len(s.refs), func(i int) bool {
+			return s.refs[i].end > pc
+		})
+		if i == len(s.refs) || s.refs[i].begin > pc {
+			return nil, nil
+		}
+		off = s.refs[i].off
+	}
+	fde, err := s.fdeAt(off)
+	if err != nil {
+		return nil, err
+	}
+	if !fde.Cover(pc) {
+		return nil, nil
+	}
+	return fde, nil
+}
+
+// fdeAt returns the FDE at offset off of the section, decoding it if it
+// isn't cached. Must be called with s.mu held.
+func (s *section) fdeAt(off int) (*FrameDescriptionEntry, error) {
+	if fde, ok := s.fdes.Get(off); ok {
+		return fde.(*FrameDescriptionEntry), nil
+	}
+	fde, err := s.parseFDE(off)
+	if err != nil {
+		return nil, err
+	}
+	s.fdes.Add(off, fde)
+	return fde, nil
+}
+
+// parseFDE decodes the FDE at offset off of the section. Must be called
+// with s.mu held.
+func (s *section) parseFDE(off int) (*FrameDescriptionEntry, error) {
+	if !s.validOffset(off) {
+		return nil, fmt.Errorf("invalid FDE offset %#x", off)
+	}
+	cieOff, isFDE := s.cieOffset(off)
+	if !isFDE {
+		return nil, fmt.Errorf("no FDE at %#x", off)
+	}
+	cie, err := s.cieAt(cieOff)
+	if err != nil {
+		return nil, err
+	}
+	ctx, err := s.parseEntry(off, map[int]*CommonInformationEntry{cieOff: cie})
+	if err != nil {
+		return nil, err
+	}
+	ctx.frame.order = s.order
+	ctx.frame.begin += s.delta
+	return ctx.frame, nil
+}
+
+// cieAt returns the CIE at offset off of the section, decoding it if it
+// isn't cached. Must be called with s.mu held.
+func (s *section) cieAt(off int) (*CommonInformationEntry, error) {
+	if cie, ok := s.cies.Get(off); ok {
+		return cie.(*CommonInformationEntry), nil
+	}
+	if !s.validOffset(off) {
+		return nil, fmt.Errorf("invalid CIE offset %#x", off)
+	}
+	if _, isFDE := s.cieOffset(off); isFDE {
+		return nil, fmt.Errorf("no CIE at %#x", off)
+	}
+	ctx, err := s.parseEntry(off, map[int]*CommonInformationEntry{})
+	if err != nil {
+		return nil, err
+	}
+	s.cies.Add(off, ctx.common)
+	return ctx.common, nil
+}
+
+// validOffset returns true if an entry can start at offset off of the
+// section.
+func (s *section) validOffset(off int) bool {
+	return off >= 0 && off+8 <= len(s.data)
+}
+
+// cieOffset returns the offset of the CIE of the entry at offset off of
+// the section and true if the entry is an FDE, or false if it is a CIE.
+func (s *section) cieOffset(off int) (int, bool) {
+	cieid := s.order.Uint32(s.data[off+4:])
+	if s.ehFrameAddr != 0 {
+		if cieid == 0 {
+			return 0, false
+		}
+		return off + 4 - int(cieid), true
+	}
+	if cieid == 0xffffffff {
+		return 0, false
+	}
+	return int(cieid), true
+}
+
+// parseEntry decodes the CIE or FDE at offset off of the section, ciemap
+// must contain the CIE of FDEs.
+func (s *section) parseEntry(off int, ciemap map[int]*CommonInformationEntry) (*parseContext, error) {
+	ctx := &parseContext{
+		buf:         bytes.NewBuffer(s.data[off:]),
+		totalLen:    len(s.data),
+		staticBase:  s.staticBase,
+		order:       s.order,
+		ptrSize:     s.ptrSize,
+		ehFrameAddr: s.ehFrameAddr,
+		ciemap:      ciemap,
+	}
+	fn := parselength(ctx)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+	if ctx.length == 0 {
+		return nil, fmt.Errorf("empty entry at %#x", off)
+	}
+	fn(ctx)
+	if ctx.err != nil {
+		return nil, ctx.err
+	}
+	return ctx, nil
+}
@@ -0,0 +1,123 @@
+package frame
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+)
+
+// checkIndex checks that idx finds the same FDEs as fdes, which were
+// parsed eagerly.
+func checkIndex(t *testing.T, idx *Index, fdes FrameDescriptionEntries) {
+	t.Helper()
+	if len(fdes) == 0 {
+		t.Fatal("no FDEs")
+	}
+	for _, want := range fdes {
+		for _, pc := range []uint64{want.Begin(), want.End() - 1} {
+			fde, err := idx.FDEForPC(pc)
+			if err != nil {
+				t.Fatalf("[pc = %#x] %v", pc, err)
+			}
+			if fde.Begin() != want.Begin() || fde.End() != want.End() || !bytes.Equal(fde.Instructions, want.Instructions) {
+				t.Errorf("[pc = %#x] got FDE %#x-%#x expected %#x-%#x", pc, fde.Begin(), fde.End(), want.Begin(), want.End())
+			}
+		}
+	}
+	lowest := fdes[0].Begin()
+	for _, fde := range fdes {
+		if fde.Begin() < lowest {
+			lowest = fde.Begin()
+		}
+	}
+	for _, pc := range []uint64{0, lowest - 1, ^uint64(0)} {
+		if fde, err := idx.FDEForPC(pc); err == nil {
+			t.Errorf("[pc = %#x] expected error got FDE %#x-%#x", pc, fde.Begin(), fde.End())
+		}
+	}
+}
+
+func TestIndexDebugFrame(t *testing.T) {
+	data, err := ioutil.ReadFile("testdata/frame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const staticBase = 0x1000
+	fdes, err := Parse(data, binary.LittleEndian, staticBase, 8, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var idx Index
+	if err := idx.AddDebugFrame(data, binary.LittleEndian, staticBase, 8); err != nil {
+		t.Fatal(err)
+	}
+	checkIndex(t, &idx, fdes)
+
+	first := idx.FirstDebugFrameFDE()
+	if first == nil || first.Begin() != fdes[0].Begin() {
+		t.Fatalf("wrong first FDE %v", first)
+	}
+	idx.TranslateDebugFrame(0x10)
+	fde, err := idx.FDEForPC(fdes[0].Begin() + 0x10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fde.Begin() != fdes[0].Begin()+0x10 {
+		t.Errorf("FDE not translated: %#x", fde.Begin())
+	}
+}
+
+func TestIndexEHFrame(t *testing.T) {
+	// testdata/eh_frame and testdata/eh_frame_hdr are the .eh_frame and
+	// .eh_frame_hdr sections of a small C program.
+	const (
+		ehFrameAddr    = 0x402050
+		ehFrameHdrAddr = 0x402008
+		staticBase     = 0x10000
+	)
+	data, err := ioutil.ReadFile("testdata/eh_frame")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hdr, err := ioutil.ReadFile("testdata/eh_frame_hdr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fdes, err := Parse(data, binary.LittleEndian, staticBase, 8, ehFrameAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("hdr", func(t *testing.T) {
+		var idx Index
+		if err := idx.AddEHFrame(data, hdr, ehFrameHdrAddr, binary.LittleEndian, staticBase, 8, ehFrameAddr); err != nil {
+			t.Fatal(err)
+		}
+		if idx.sections[0].hdr == nil || idx.sections[0].hdr.count != len(fdes) {
+			t.Fatalf("binary search table not used")
+		}
+		checkIndex(t, &idx, fdes)
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		var idx Index
+		if err := idx.AddEHFrame(data, nil, 0, binary.LittleEndian, staticBase, 8, ehFrameAddr); err != nil {
+			t.Fatal(err)
+		}
+		checkIndex(t, &idx, fdes)
+	})
+}
+
+func BenchmarkIndexDebugFrame(b *testing.B) {
+	data, err := ioutil.ReadFile("testdata/frame")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var idx Index
+		idx.AddDebugFrame(data, binary.LittleEndian, 0, ptrSizeByRuntimeArch())
+	}
+}
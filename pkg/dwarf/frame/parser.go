@@ -16,6 +16,7 @@ type parsefunc func(*parseContext) parsefunc
 
 type parseContext struct {
 	staticBase uint64
+	order      binary.ByteOrder
 
 	buf         *bytes.Buffer
 	totalLen    int
@@ -37,7 +38,7 @@ type parseContext struct {
 func Parse(data []byte, order binary.ByteOrder, staticBase uint64, ptrSize int, ehFrameAddr uint64) (FrameDescriptionEntries, error) {
 	var (
 		buf  = bytes.NewBuffer(data)
-		pctx = &parseContext{buf: buf, totalLen: len(data), entries: newFrameIndex(), staticBase: staticBase, ptrSize: ptrSize, ehFrameAddr: ehFrameAddr, ciemap: map[int]*CommonInformationEntry{}}
+		pctx = &parseContext{buf: buf, totalLen: len(data), entries: newFrameIndex(), staticBase: staticBase, order: order, ptrSize: ptrSize, ehFrameAddr: ehFrameAddr, ciemap: map[int]*CommonInformationEntry{}}
 	)
 
 	for fn := parselength; buf.Len() != 0; {
@@ -71,7 +72,7 @@ func (ctx *parseContext) offset() int {
 
 func parselength(ctx *parseContext) parsefunc {
 	start := ctx.offset()
-	binary.Read(ctx.buf, binary.LittleEndian, &ctx.length) //TODO(aarzilli): this does not support 64bit DWARF
+	binary.Read(ctx.buf, ctx.order, &ctx.length) //TODO(aarzilli): this does not support 64bit DWARF
 
 	if ctx.length == 0 {
 		// ZERO terminator
@@ -79,7 +80,7 @@ func parselength(ctx *parseContext) parsefunc {
 	}
 
 	var cieid uint32
-	binary.Read(ctx.buf, binary.LittleEndian, &cieid)
+	binary.Read(ctx.buf, ctx.order, &cieid)
 
 	ctx.length -= 4 // take off the length of the CIE id / CIE pointer.
 
@@ -108,15 +109,7 @@ func parseFDE(ctx *parseContext) parsefunc {
 	r := ctx.buf.Next(int(ctx.length))
 
 	reader := bytes.NewReader(r)
-	num := ctx.readEncodedPtr(addrSum(ctx.ehFrameAddr+uint64(startOff), reader), reader, ctx.frame.CIE.ptrEncAddr)
-	ctx.frame.begin = num + ctx.staticBase
-
-	// For the size field in .eh_frame only the size encoding portion of the
-	// address pointer encoding is considered.
-	// See decode_frame_entry_1 in gdb/dwarf2-frame.c.
-	// For .debug_frame ptrEncAddr is always ptrEncAbs and never has flags.
-	sizePtrEnc := ctx.frame.CIE.ptrEncAddr & 0x0f
-	ctx.frame.size = ctx.readEncodedPtr(0, reader, sizePtrEnc)
+	ctx.readFDERange(reader, startOff)
 
 	// Insert into the tree after setting address range begin
 	// otherwise compares won't work.
@@ -141,6 +134,20 @@ func parseFDE(ctx *parseContext) parsefunc {
 	return parselength
 }
 
+// readFDERange reads the begin and size fields of ctx.frame from reader,
+// startOff is the offset in the section of the first byte of reader.
+func (ctx *parseContext) readFDERange(reader *bytes.Reader, startOff int) {
+	num := ctx.readEncodedPtr(addrSum(ctx.ehFrameAddr+uint64(startOff), reader), reader, ctx.frame.CIE.ptrEncAddr)
+	ctx.frame.begin = num + ctx.staticBase
+
+	// For the size field in .eh_frame only the size encoding portion of the
+	// address pointer encoding is considered.
+	// See decode_frame_entry_1 in gdb/dwarf2-frame.c.
+	// For .debug_frame ptrEncAddr is always ptrEncAbs and never has flags.
+	sizePtrEnc := ctx.frame.CIE.ptrEncAddr & 0x0f
+	ctx.frame.size = ctx.readEncodedPtr(0, reader, sizePtrEnc)
+}
+
 func addrSum(base uint64, buf *bytes.Reader) uint64 {
 	n, _ := buf.Seek(0, io.SeekCurrent)
 	return base + uint64(n)
@@ -239,21 +246,21 @@ func (ctx *parseContext) readEncodedPtr(addr uint64, buf util.ByteReaderWithLen,
 
 	switch ptrEnc & 0xf {
 	case ptrEncAbs, ptrEncSigned:
-		ptr, _ = util.ReadUintRaw(buf, binary.LittleEndian, ctx.ptrSize)
+		ptr, _ = util.ReadUintRaw(buf, ctx.order, ctx.ptrSize)
 	case ptrEncUleb:
 		ptr, _ = util.DecodeULEB128(buf)
 	case ptrEncUdata2:
-		ptr, _ = util.ReadUintRaw(buf, binary.LittleEndian, 2)
+		ptr, _ = util.ReadUintRaw(buf, ctx.order, 2)
 	case ptrEncSdata2:
-		ptr, _ = util.ReadUintRaw(buf, binary.LittleEndian, 2)
+		ptr, _ = util.ReadUintRaw(buf, ctx.order, 2)
 		ptr = uint64(int16(ptr))
 	case ptrEncUdata4:
-		ptr, _ = util.ReadUintRaw(buf, binary.LittleEndian, 4)
+		ptr, _ = util.ReadUintRaw(buf, ctx.order, 4)
 	case ptrEncSdata4:
-		ptr, _ = util.ReadUintRaw(buf, binary.LittleEndian, 4)
+		ptr, _ = util.ReadUintRaw(buf, ctx.order, 4)
 		ptr = uint64(int32(ptr))
 	case ptrEncUdata8, ptrEncSdata8:
-		ptr, _ = util.ReadUintRaw(buf, binary.LittleEndian, 8)
+		ptr, _ = util.ReadUintRaw(buf, ctx.order, 8)
 	case ptrEncSleb:
 		n, _ := util.DecodeSLEB128(buf)
 		ptr = uint64(n)
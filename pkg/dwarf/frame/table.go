@@ -24,7 +24,7 @@ type FrameContext struct {
 	CFA           DWRule
 	Regs          map[uint64]DWRule
 	initialRegs   map[uint64]DWRule
-	prevRegs      map[uint64]DWRule
+	stateStack    []rowState // states saved by DW_CFA_remember_state
 	buf           *bytes.Buffer
 	cie           *CommonInformationEntry
 	RetAddrReg    uint64
@@ -123,7 +123,6 @@ func executeCIEInstructions(cie *CommonInformationEntry) *FrameContext {
 		Regs:          make(map[uint64]DWRule),
 		RetAddrReg:    cie.ReturnAddressRegister,
 		initialRegs:   make(map[uint64]DWRule),
-		prevRegs:      make(map[uint64]DWRule),
 		codeAlignment: cie.CodeAlignmentFactor,
 		dataAlignment: cie.DataAlignmentFactor,
 		buf:           bytes.NewBuffer(initialInstructions),
@@ -307,12 +306,29 @@ func register(frame *FrameContext) {
 	frame.Regs[reg1] = DWRule{Reg: reg2, Rule: RuleRegister}
 }
 
+// rowState is the state of a row of the call frame table, saved by
+// DW_CFA_remember_state and restored by DW_CFA_restore_state.
+type rowState struct {
+	cfa  DWRule
+	regs map[uint64]DWRule
+}
+
 func rememberstate(frame *FrameContext) {
-	frame.prevRegs = frame.Regs
+	regs := make(map[uint64]DWRule, len(frame.Regs))
+	for reg, rule := range frame.Regs {
+		regs[reg] = rule
+	}
+	frame.stateStack = append(frame.stateStack, rowState{cfa: frame.CFA, regs: regs})
 }
 
 func restorestate(frame *FrameContext) {
-	frame.Regs = frame.prevRegs
+	if len(frame.stateStack) == 0 {
+		return
+	}
+	state := frame.stateStack[len(frame.stateStack)-1]
+	frame.stateStack = frame.stateStack[:len(frame.stateStack)-1]
+	frame.CFA = state.cfa
+	frame.Regs = state.regs
 }
 
 func restoreextended(frame *FrameContext) {
@@ -0,0 +1,52 @@
+package frame
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestRememberRestoreState(t *testing.T) {
+	cie := &CommonInformationEntry{
+		CodeAlignmentFactor:   1,
+		DataAlignmentFactor:   -8,
+		ReturnAddressRegister: 16,
+		InitialInstructions:   []byte{DW_CFA_def_cfa, 7, 8},
+	}
+	fde := &FrameDescriptionEntry{
+		CIE: cie,
+		Instructions: []byte{
+			DW_CFA_advance_loc | 1,
+			DW_CFA_def_cfa_offset, 16,
+			DW_CFA_offset | 6, 2,
+			DW_CFA_advance_loc | 1,
+			DW_CFA_remember_state,
+			DW_CFA_def_cfa_offset, 8,
+			DW_CFA_restore | 6,
+			DW_CFA_advance_loc | 1,
+			DW_CFA_restore_state,
+		},
+		begin: 0x100,
+		size:  0x10,
+		order: binary.LittleEndian,
+	}
+
+	for _, tc := range []struct {
+		pc        uint64
+		cfaOffset int64
+		r6        Rule
+	}{
+		{0x100, 8, RuleUndefined},
+		{0x101, 16, RuleOffset},
+		{0x102, 8, RuleUndefined},
+		{0x103, 16, RuleOffset},
+		{0x104, 16, RuleOffset},
+	} {
+		frame := fde.EstablishFrame(tc.pc)
+		if frame.CFA.Offset != tc.cfaOffset {
+			t.Errorf("%#x: CFA offset %d expected %d", tc.pc, frame.CFA.Offset, tc.cfaOffset)
+		}
+		if frame.Regs[6].Rule != tc.r6 {
+			t.Errorf("%#x: rule for register 6 %v expected %v", tc.pc, frame.Regs[6].Rule, tc.r6)
+		}
+	}
+}
@@ -48,6 +48,7 @@ type DebugLineInfo struct {
 	// if normalizeBackslash is true all backslashes (\) will be converted into forward slashes (/)
 	normalizeBackslash bool
 	ptrSize            int
+	order              binary.ByteOrder
 	endSeqIsValid      bool
 }
 
@@ -62,7 +63,7 @@ type FileEntry struct {
 type DebugLines []*DebugLineInfo
 
 // ParseAll parses all debug_line segments found in data
-func ParseAll(data []byte, debugLineStr []byte, logfn func(string, ...interface{}), staticBase uint64, normalizeBackslash bool, ptrSize int) DebugLines {
+func ParseAll(data []byte, debugLineStr []byte, logfn func(string, ...interface{}), staticBase uint64, normalizeBackslash bool, ptrSize int, order binary.ByteOrder) DebugLines {
 	var (
 		lines = make(DebugLines, 0)
 		buf   = bytes.NewBuffer(data)
@@ -70,15 +71,16 @@ func ParseAll(data []byte, debugLineStr []byte, logfn func(string, ...interface{
 
 	// We have to parse multiple file name tables here.
 	for buf.Len() > 0 {
-		lines = append(lines, Parse("", buf, debugLineStr, logfn, staticBase, normalizeBackslash, ptrSize))
+		lines = append(lines, Parse("", buf, debugLineStr, logfn, staticBase, normalizeBackslash, ptrSize, order))
 	}
 
 	return lines
 }
 
 // Parse parses a single debug_line segment from buf. Compdir is the
-// DW_AT_comp_dir attribute of the associated compile unit.
-func Parse(compdir string, buf *bytes.Buffer, debugLineStr []byte, logfn func(string, ...interface{}), staticBase uint64, normalizeBackslash bool, ptrSize int) *DebugLineInfo {
+// DW_AT_comp_dir attribute of the associated compile unit. Order is the
+// byte order of the target architecture.
+func Parse(compdir string, buf *bytes.Buffer, debugLineStr []byte, logfn func(string, ...interface{}), staticBase uint64, normalizeBackslash bool, ptrSize int, order binary.ByteOrder) *DebugLineInfo {
 	dbl := new(DebugLineInfo)
 	dbl.Logf = logfn
 	if logfn == nil {
@@ -86,6 +88,7 @@ func Parse(compdir string, buf *bytes.Buffer, debugLineStr []byte, logfn func(st
 	}
 	dbl.staticBase = staticBase
 	dbl.ptrSize = ptrSize
+	dbl.order = order
 	dbl.Lookup = make(map[string]*FileEntry)
 	dbl.IncludeDirs = append(dbl.IncludeDirs, compdir)
 
@@ -123,14 +126,14 @@ func Parse(compdir string, buf *bytes.Buffer, debugLineStr []byte, logfn func(st
 func parseDebugLinePrologue(dbl *DebugLineInfo, buf *bytes.Buffer) {
 	p := new(DebugLinePrologue)
 
-	p.UnitLength = binary.LittleEndian.Uint32(buf.Next(4))
-	p.Version = binary.LittleEndian.Uint16(buf.Next(2))
+	p.UnitLength = dbl.order.Uint32(buf.Next(4))
+	p.Version = dbl.order.Uint16(buf.Next(2))
 	if p.Version >= 5 {
 		dbl.ptrSize = int(buf.Next(1)[0])  // address_size
 		dbl.ptrSize += int(buf.Next(1)[0]) // segment_selector_size
 	}
 
-	p.Length = binary.LittleEndian.Uint32(buf.Next(4))
+	p.Length = dbl.order.Uint32(buf.Next(4))
 	p.MinInstrLength = uint8(buf.Next(1)[0])
 	if p.Version >= 4 {
 		p.MaxOpPerInstr = uint8(buf.Next(1)[0])
@@ -143,7 +146,7 @@ func parseDebugLinePrologue(dbl *DebugLineInfo, buf *bytes.Buffer) {
 	p.OpcodeBase = uint8(buf.Next(1)[0])
 
 	p.StdOpLengths = make([]uint8, p.OpcodeBase-1)
-	binary.Read(buf, binary.LittleEndian, &p.StdOpLengths)
+	binary.Read(buf, dbl.order, &p.StdOpLengths)
 
 	dbl.Prologue = p
 }
@@ -169,7 +172,7 @@ func parseIncludeDirs2(info *DebugLineInfo, buf *bytes.Buffer) bool {
 
 // parseIncludeDirs5 parses the directory table for DWARF version 5.
 func parseIncludeDirs5(info *DebugLineInfo, buf *bytes.Buffer) bool {
-	dirEntryFormReader := readEntryFormat(buf, info.Logf)
+	dirEntryFormReader := readEntryFormat(buf, info.Logf, info.order)
 	if dirEntryFormReader == nil {
 		return false
 	}
@@ -272,7 +275,7 @@ func pathIsAbs(s string) bool {
 
 // parseFileEntries5 parses the file table for DWARF 5
 func parseFileEntries5(info *DebugLineInfo, buf *bytes.Buffer) bool {
-	fileEntryFormReader := readEntryFormat(buf, info.Logf)
+	fileEntryFormReader := readEntryFormat(buf, info.Logf, info.order)
 	if fileEntryFormReader == nil {
 		return false
 	}
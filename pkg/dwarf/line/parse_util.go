@@ -44,6 +44,7 @@ var ErrBufferUnderflow = errors.New("buffer underflow")
 
 type formReader struct {
 	logf         func(string, ...interface{})
+	order        binary.ByteOrder
 	contentTypes []uint64
 	formCodes    []uint64
 
@@ -59,13 +60,14 @@ type formReader struct {
 	nexti int
 }
 
-func readEntryFormat(buf *bytes.Buffer, logf func(string, ...interface{})) *formReader {
+func readEntryFormat(buf *bytes.Buffer, logf func(string, ...interface{}), order binary.ByteOrder) *formReader {
 	if buf.Len() < 1 {
 		return nil
 	}
 	count := buf.Next(1)[0]
 	r := &formReader{
 		logf:         logf,
+		order:        order,
 		contentTypes: make([]uint64, count),
 		formCodes:    make([]uint64, count),
 	}
@@ -109,14 +111,14 @@ func (rdr *formReader) next(buf *bytes.Buffer) bool {
 			rdr.err = ErrBufferUnderflow
 			return false
 		}
-		rdr.readBlock(buf, uint64(binary.LittleEndian.Uint16(buf.Next(2))))
+		rdr.readBlock(buf, uint64(rdr.order.Uint16(buf.Next(2))))
 
 	case _DW_FORM_block4:
 		if buf.Len() < 4 {
 			rdr.err = ErrBufferUnderflow
 			return false
 		}
-		rdr.readBlock(buf, uint64(binary.LittleEndian.Uint32(buf.Next(4))))
+		rdr.readBlock(buf, uint64(rdr.order.Uint32(buf.Next(4))))
 
 	case _DW_FORM_data1, _DW_FORM_flag, _DW_FORM_strx1:
 		if buf.Len() < 1 {
@@ -130,21 +132,21 @@ func (rdr *formReader) next(buf *bytes.Buffer) bool {
 			rdr.err = ErrBufferUnderflow
 			return false
 		}
-		rdr.u64 = uint64(binary.LittleEndian.Uint16(buf.Next(2)))
+		rdr.u64 = uint64(rdr.order.Uint16(buf.Next(2)))
 
 	case _DW_FORM_data4, _DW_FORM_line_strp, _DW_FORM_sec_offset, _DW_FORM_strp, _DW_FORM_strx4:
 		if buf.Len() < 4 {
 			rdr.err = ErrBufferUnderflow
 			return false
 		}
-		rdr.u64 = uint64(binary.LittleEndian.Uint32(buf.Next(4)))
+		rdr.u64 = uint64(rdr.order.Uint32(buf.Next(4)))
 
 	case _DW_FORM_data8:
 		if buf.Len() < 8 {
 			rdr.err = ErrBufferUnderflow
 			return false
 		}
-		rdr.u64 = binary.LittleEndian.Uint64(buf.Next(8))
+		rdr.u64 = rdr.order.Uint64(buf.Next(8))
 
 	case _DW_FORM_data16:
 		rdr.readBlock(buf, 16)
@@ -163,7 +165,7 @@ func (rdr *formReader) next(buf *bytes.Buffer) bool {
 			rdr.err = ErrBufferUnderflow
 			return false
 		}
-		rdr.u64 = uint64(binary.LittleEndian.Uint32(append(buf.Next(3), 0x0)))
+		rdr.u64 = uint64(rdr.order.Uint32(append(buf.Next(3), 0x0)))
 
 	case ^uint64(0):
 		// do nothing
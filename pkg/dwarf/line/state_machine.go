@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/go-delve/delve/pkg/dwarf/util"
 )
@@ -140,7 +141,7 @@ func (lineInfo *DebugLineInfo) AllPCsForFileLines(f string, m map[int][]uint64)
 			}
 			break
 		}
-		if sm.address != lastAddr && sm.isStmt && sm.valid && sm.file == f {
+		if sm.address != lastAddr && sm.stmt() && sm.valid && sm.file == f {
 			if pcs, ok := m[sm.line]; ok {
 				pcs = append(pcs, sm.address)
 				m[sm.line] = pcs
@@ -151,6 +152,28 @@ func (lineInfo *DebugLineInfo) AllPCsForFileLines(f string, m map[int][]uint64)
 	return
 }
 
+// AllStmtLinesForFile adds to m all the lines of file f that have at
+// least one instruction with the is_stmt flag set.
+func (lineInfo *DebugLineInfo) AllStmtLinesForFile(f string, m map[int]bool) {
+	if lineInfo == nil {
+		return
+	}
+
+	sm := newStateMachine(lineInfo, lineInfo.Instructions, lineInfo.ptrSize)
+
+	for {
+		if err := sm.next(); err != nil {
+			if lineInfo.Logf != nil {
+				lineInfo.Logf("AllStmtLinesForFile error: %v", err)
+			}
+			break
+		}
+		if sm.stmt() && sm.valid && sm.file == f {
+			m[sm.line] = true
+		}
+	}
+}
+
 var NoSourceError = errors.New("no source available")
 
 // AllPCsBetween returns all PC addresses between begin and end (including both begin and end)
@@ -179,7 +202,7 @@ func (lineInfo *DebugLineInfo) AllPCsBetween(begin, end uint64, excludeFile stri
 		if (sm.address > end) && (end >= sm.lastAddress) {
 			break
 		}
-		if sm.address >= begin && sm.address <= end && sm.address > lastaddr && sm.isStmt && !sm.endSeq && ((sm.file != excludeFile) || (sm.line != excludeLine)) {
+		if sm.address >= begin && sm.address <= end && sm.address > lastaddr && sm.stmt() && !sm.endSeq && ((sm.file != excludeFile) || (sm.line != excludeLine)) {
 			lastaddr = sm.address
 			pcs = append(pcs, sm.address)
 		}
@@ -187,6 +210,15 @@ func (lineInfo *DebugLineInfo) AllPCsBetween(begin, end uint64, excludeFile stri
 	return pcs, nil
 }
 
+// stmt returns true if the current instruction is the beginning of a
+// statement. Every instruction of an assembly file is considered a
+// statement: each line of an assembly file is an instruction and stepping
+// should stop on all of them, regardless of how the assembler set the
+// is_stmt flag.
+func (sm *StateMachine) stmt() bool {
+	return sm.isStmt || strings.HasSuffix(sm.file, ".s")
+}
+
 // copy returns a copy of this state machine, running the returned state
 // machine will not affect sm.
 func (sm *StateMachine) copy() *StateMachine {
@@ -298,7 +330,7 @@ func (lineInfo *DebugLineInfo) LineToPC(filename string, lineno int) uint64 {
 			break
 		}
 		if sm.line == lineno && sm.file == filename && sm.valid {
-			if sm.isStmt {
+			if sm.stmt() {
 				return sm.address
 			} else if fallbackPC == 0 {
 				fallbackPC = sm.address
@@ -331,7 +363,7 @@ func (lineInfo *DebugLineInfo) LineToPCIn(filename string, lineno int, basePC, s
 				break
 			}
 			if sm.line == lineno && sm.file == filename && sm.address >= startPC {
-				if sm.isStmt {
+				if sm.stmt() {
 					return sm.address
 				} else {
 					fallbackPC = sm.address
@@ -389,7 +421,7 @@ func (lineInfo *DebugLineInfo) FirstStmtForLine(start, end uint64) (pc uint64, f
 				first = false
 				file, line = sm.file, sm.line
 			}
-			if sm.isStmt && sm.file == file && sm.line == line {
+			if sm.stmt() && sm.file == file && sm.line == line {
 				return sm.address, sm.file, sm.line, true
 			}
 		}
@@ -533,7 +565,7 @@ func constaddpc(sm *StateMachine, buf *bytes.Buffer) {
 
 func fixedadvancepc(sm *StateMachine, buf *bytes.Buffer) {
 	var operand uint16
-	binary.Read(buf, binary.LittleEndian, &operand)
+	binary.Read(buf, sm.dbl.order, &operand)
 
 	sm.address += uint64(operand)
 }
@@ -544,7 +576,7 @@ func endsequence(sm *StateMachine, buf *bytes.Buffer) {
 }
 
 func setaddress(sm *StateMachine, buf *bytes.Buffer) {
-	addr, err := util.ReadUintRaw(buf, binary.LittleEndian, sm.ptrSize)
+	addr, err := util.ReadUintRaw(buf, sm.dbl.order, sm.ptrSize)
 	if err != nil {
 		panic(err)
 	}
@@ -74,7 +74,7 @@ func TestGrafana(t *testing.T) {
 		}
 		cuname, _ := e.Val(dwarf.AttrName).(string)
 
-		lineInfo := Parse(e.Val(dwarf.AttrCompDir).(string), debugLineBuffer, nil, t.Logf, 0, false, 8)
+		lineInfo := Parse(e.Val(dwarf.AttrCompDir).(string), debugLineBuffer, nil, t.Logf, 0, false, 8, binary.LittleEndian)
 		lineInfo.endSeqIsValid = true
 		sm := newStateMachine(lineInfo, lineInfo.Instructions, 8)
 
@@ -211,6 +211,7 @@ func TestMultipleSequences(t *testing.T) {
 		FileNames:    []*FileEntry{&FileEntry{Path: thefile}},
 		Instructions: instr.Bytes(),
 		ptrSize:      ptrSize,
+		order:        binary.LittleEndian,
 	}
 
 	// Test that PCToLine is correct for all three sequences
@@ -153,7 +153,11 @@ func callframecfa(opcode Opcode, ctxt *context) error {
 
 func addr(opcode Opcode, ctxt *context) error {
 	buf := ctxt.buf.Next(ctxt.ptrSize)
-	stack, err := util.ReadUintRaw(bytes.NewReader(buf), binary.LittleEndian, ctxt.ptrSize)
+	order := ctxt.ByteOrder
+	if order == nil {
+		order = binary.LittleEndian
+	}
+	stack, err := util.ReadUintRaw(bytes.NewReader(buf), order, ctxt.ptrSize)
 	if err != nil {
 		return err
 	}
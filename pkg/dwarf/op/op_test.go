@@ -1,6 +1,7 @@
 package op
 
 import (
+	"encoding/binary"
 	"testing"
 	"unsafe"
 )
@@ -23,3 +24,22 @@ func TestExecuteStackProgram(t *testing.T) {
 		t.Fatalf("actual %d != expected %d", actual, expected)
 	}
 }
+
+func TestExecuteStackProgramAddrByteOrder(t *testing.T) {
+	instructions := []byte{byte(DW_OP_addr), 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x02, 0x03}
+	for _, tc := range []struct {
+		order    binary.ByteOrder
+		expected int64
+	}{
+		{binary.LittleEndian, 0x0302010000000000},
+		{binary.BigEndian, 0x010203},
+	} {
+		actual, _, err := ExecuteStackProgram(DwarfRegisters{ByteOrder: tc.order}, instructions, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != tc.expected {
+			t.Errorf("%v: actual %#x != expected %#x", tc.order, actual, tc.expected)
+		}
+	}
+}
@@ -29,7 +29,8 @@ const (
 //
 // If the VariablesOnlyVisible flag is set, only variables visible at 'pc' will be
 // returned. If the VariablesSkipInlinedSubroutines is set, variables from
-// inlined subroutines will be skipped.
+// inlined subroutines contained in 'root' will be skipped ('root' itself can
+// be an inlined subroutine).
 func Variables(root *godwarf.Tree, pc uint64, line int, flags VariablesFlags) []Variable {
 	return variablesInternal(nil, root, 0, pc, line, flags)
 }
@@ -39,7 +40,7 @@ func Variables(root *godwarf.Tree, pc uint64, line int, flags VariablesFlags) []
 func variablesInternal(v []Variable, root *godwarf.Tree, depth int, pc uint64, line int, flags VariablesFlags) []Variable {
 	switch root.Tag {
 	case dwarf.TagInlinedSubroutine:
-		if flags&VariablesSkipInlinedSubroutines != 0 {
+		if flags&VariablesSkipInlinedSubroutines != 0 && depth > 0 {
 			return v
 		}
 		fallthrough
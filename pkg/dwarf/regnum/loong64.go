@@ -0,0 +1,56 @@
+package regnum
+
+import (
+	"fmt"
+)
+
+// The mapping between hardware registers and DWARF registers is specified
+// in the LoongArch ELF psABI, section DWARF Register Numbers
+// https://github.com/loongson/la-abi-specs
+
+const (
+	LOONG64_R0         = 0  // R1 through R31 follow
+	LOONG64_LR         = 1  // also R1, the return address register (RA)
+	LOONG64_TP         = 2  // also R2, the thread pointer
+	LOONG64_SP         = 3  // also R3
+	LOONG64_BP         = 22 // also R22, used as frame pointer by gcc, holds the current g in go
+	LOONG64_F0         = 32 // F1 through F31 follow
+	LOONG64_PC         = 64 // not defined by the psABI, the ERA register
+	_LOONG64_MaxRegNum = LOONG64_PC
+)
+
+func LOONG64ToName(num uint64) string {
+	switch {
+	case num <= 31:
+		return fmt.Sprintf("R%d", num)
+	case num >= LOONG64_F0 && num <= 63:
+		return fmt.Sprintf("F%d", num-LOONG64_F0)
+	case num == LOONG64_PC:
+		return "PC"
+	default:
+		return fmt.Sprintf("unknown%d", num)
+	}
+}
+
+func LOONG64MaxRegNum() uint64 {
+	return _LOONG64_MaxRegNum
+}
+
+var LOONG64NameToDwarf = func() map[string]int {
+	r := make(map[string]int)
+	for i := 0; i <= 31; i++ {
+		r[fmt.Sprintf("r%d", i)] = LOONG64_R0 + i
+	}
+	r["ra"] = LOONG64_LR
+	r["lr"] = LOONG64_LR
+	r["tp"] = LOONG64_TP
+	r["sp"] = LOONG64_SP
+	r["pc"] = LOONG64_PC
+	r["era"] = LOONG64_PC
+
+	for i := 0; i <= 31; i++ {
+		r[fmt.Sprintf("f%d", i)] = LOONG64_F0 + i
+	}
+
+	return r
+}()
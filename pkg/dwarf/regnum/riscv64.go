@@ -0,0 +1,54 @@
+package regnum
+
+import (
+	"fmt"
+)
+
+// The mapping between hardware registers and DWARF registers is specified
+// in the RISC-V ELF psABI, section DWARF Register Numbers
+// https://github.com/riscv-non-isa/riscv-elf-psabi-doc
+
+const (
+	RISCV64_X0         = 0  // X1 through X31 follow
+	RISCV64_LR         = 1  // also X1, the return address register (RA)
+	RISCV64_SP         = 2  // also X2
+	RISCV64_BP         = 8  // also X8, used as frame pointer by gcc but not by go
+	RISCV64_G          = 27 // also X27, holds the current g
+	RISCV64_F0         = 32 // F1 through F31 follow
+	RISCV64_PC         = 65 // not defined by the psABI
+	_RISCV64_MaxRegNum = RISCV64_PC
+)
+
+func RISCV64ToName(num uint64) string {
+	switch {
+	case num <= 31:
+		return fmt.Sprintf("X%d", num)
+	case num >= RISCV64_F0 && num <= 63:
+		return fmt.Sprintf("F%d", num-RISCV64_F0)
+	case num == RISCV64_PC:
+		return "PC"
+	default:
+		return fmt.Sprintf("unknown%d", num)
+	}
+}
+
+func RISCV64MaxRegNum() uint64 {
+	return _RISCV64_MaxRegNum
+}
+
+var RISCV64NameToDwarf = func() map[string]int {
+	r := make(map[string]int)
+	for i := 0; i <= 31; i++ {
+		r[fmt.Sprintf("x%d", i)] = RISCV64_X0 + i
+	}
+	r["ra"] = RISCV64_LR
+	r["lr"] = RISCV64_LR
+	r["sp"] = RISCV64_SP
+	r["pc"] = RISCV64_PC
+
+	for i := 0; i <= 31; i++ {
+		r[fmt.Sprintf("f%d", i)] = RISCV64_F0 + i
+	}
+
+	return r
+}()
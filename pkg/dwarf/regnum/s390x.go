@@ -0,0 +1,68 @@
+package regnum
+
+import (
+	"fmt"
+)
+
+// The mapping between hardware registers and DWARF registers is specified
+// in the ELF Application Binary Interface s390x Supplement, section 1.6.3
+// https://github.com/IBM/s390x-abi
+
+const (
+	S390X_R0         = 0  // R1 through R15 follow
+	S390X_BP         = 11 // also R11, used as frame pointer by gcc but not by go
+	S390X_G          = 13 // also R13, holds the current g
+	S390X_LR         = 14 // also R14
+	S390X_SP         = 15 // also R15
+	S390X_F0         = 16 // the remaining floating point registers follow in the order described by s390xFPRegs
+	S390X_A0         = 48 // A1 through A15 follow
+	S390X_PSWM       = 64 // PSW mask
+	S390X_PC         = 65 // PSW address
+	_S390X_MaxRegNum = S390X_PC
+)
+
+// s390xFPRegs is the order in which floating point registers are assigned
+// DWARF register numbers, starting from S390X_F0.
+var s390xFPRegs = [16]int{0, 2, 4, 6, 1, 3, 5, 7, 8, 10, 12, 14, 9, 11, 13, 15}
+
+func S390XToName(num uint64) string {
+	switch {
+	case num <= 15:
+		return fmt.Sprintf("R%d", num)
+	case num >= S390X_F0 && num <= 31:
+		return fmt.Sprintf("F%d", s390xFPRegs[num-S390X_F0])
+	case num >= S390X_A0 && num <= 63:
+		return fmt.Sprintf("A%d", num-S390X_A0)
+	case num == S390X_PSWM:
+		return "PSWM"
+	case num == S390X_PC:
+		return "PC"
+	default:
+		return fmt.Sprintf("unknown%d", num)
+	}
+}
+
+func S390XMaxRegNum() uint64 {
+	return _S390X_MaxRegNum
+}
+
+var S390XNameToDwarf = func() map[string]int {
+	r := make(map[string]int)
+	for i := 0; i <= 15; i++ {
+		r[fmt.Sprintf("r%d", i)] = S390X_R0 + i
+	}
+	for i, n := range s390xFPRegs {
+		r[fmt.Sprintf("f%d", n)] = S390X_F0 + i
+	}
+	for i := 0; i <= 15; i++ {
+		r[fmt.Sprintf("a%d", i)] = S390X_A0 + i
+		r[fmt.Sprintf("acr%d", i)] = S390X_A0 + i // name used by gdbserver
+	}
+	r["lr"] = S390X_LR
+	r["sp"] = S390X_SP
+	r["pswm"] = S390X_PSWM
+	r["pc"] = S390X_PC
+	r["pswa"] = S390X_PC
+
+	return r
+}()
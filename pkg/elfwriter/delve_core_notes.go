@@ -6,6 +6,11 @@ const (
 
 	DelveHeaderTargetPidPrefix  = "Target Pid: "
 	DelveHeaderEntryPointPrefix = "Entry Point: "
+
+	// Owners of the LC_NOTE load commands used to store the Delve header
+	// and thread notes in Mach-O core files.
+	DelveHeaderNoteOwner = "Delve Header"
+	DelveThreadNoteOwner = "Delve Thread"
 )
 
 //TODO(aarzilli): these constants probably need to be in a better place.
@@ -59,6 +59,17 @@ func GoBuild(debugname string, pkgs []string, buildflags string) error {
 	return gocommandRun("build", args...)
 }
 
+// GoCrossBuild is like GoBuild but builds the executable for the operating
+// system 'goos' and the architecture 'goarch'.
+func GoCrossBuild(debugname string, pkgs []string, buildflags, goos, goarch string) error {
+	args := goBuildArgs(debugname, pkgs, buildflags, false)
+	_, goBuild := gocommandExecCmd("build", args...)
+	goBuild.Env = append(os.Environ(), "GOOS="+goos, "GOARCH="+goarch)
+	goBuild.Stderr = os.Stdout
+	goBuild.Stdout = os.Stderr
+	return goBuild.Run()
+}
+
 // GoBuildCombinedOutput builds non-test files in 'pkgs' with the specified 'buildflags'
 // and writes the output at 'debugname'.
 func GoBuildCombinedOutput(debugname string, pkgs []string, buildflags string) (string, []byte, error) {
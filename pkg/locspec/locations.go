@@ -446,7 +446,20 @@ func crossPlatformPath(path string) string {
 }
 
 // SubstitutePath applies the specified path substitution rules to path.
+// Rules are evaluated in order, the first one that matches is used.
 func SubstitutePath(path string, rules [][2]string) string {
+	path = crossPlatformPath(path)
+	for _, r := range rules {
+		if substPath, ok := SubstitutePathRule(path, r); ok {
+			return substPath
+		}
+	}
+	return path
+}
+
+// SubstitutePathRule replaces the directory prefix rule[0] of path with
+// rule[1]. Returns false if path is not in directory rule[0].
+func SubstitutePathRule(path string, rule [2]string) (string, bool) {
 	path = crossPlatformPath(path)
 	// On windows paths returned from headless server are as c:/dir/dir
 	// though os.PathSeparator is '\\'
@@ -455,21 +468,19 @@ func SubstitutePath(path string, rules [][2]string) string {
 	if strings.Contains(path, "\\") { //dependent on the path
 		separator = "\\"
 	}
-	for _, r := range rules {
-		from := crossPlatformPath(r[0])
-		to := r[1]
+	from := crossPlatformPath(rule[0])
+	to := rule[1]
 
-		if !strings.HasSuffix(from, separator) {
-			from = from + separator
-		}
-		if !strings.HasSuffix(to, separator) {
-			to = to + separator
-		}
-		if strings.HasPrefix(path, from) {
-			return strings.Replace(path, from, to, 1)
-		}
+	if !strings.HasSuffix(from, separator) {
+		from = from + separator
 	}
-	return path
+	if !strings.HasSuffix(to, separator) {
+		to = to + separator
+	}
+	if strings.HasPrefix(path, from) {
+		return strings.Replace(path, from, to, 1), true
+	}
+	return path, false
 }
 
 func addressesToLocation(addrs []uint64) api.Location {
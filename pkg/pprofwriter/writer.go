@@ -0,0 +1,272 @@
+// Package pprofwriter writes stack samples in the profile.proto format used
+// by pprof and in the "folded" text format used by flamegraph tools.
+// Only the subset of profile.proto needed to describe stack samples is
+// implemented, notably mappings are never written.
+package pprofwriter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Frame is a single frame of a stack sample.
+type Frame struct {
+	PC       uint64
+	Function string
+	File     string
+	Line     int
+}
+
+// Sample is a stack with its associated value. Stack is ordered from the
+// innermost frame (the leaf) to the outermost frame (the root).
+type Sample struct {
+	Stack  []Frame
+	Value  int64
+	Labels map[string]string
+}
+
+// Merge returns a new list of samples where samples with identical stacks
+// and labels are replaced by a single sample whose value is their sum.
+// The order of the first occurrence of each distinct sample is preserved.
+func Merge(samples []Sample) []Sample {
+	idx := map[string]int{}
+	r := []Sample{}
+	for _, s := range samples {
+		k := sampleKey(&s)
+		if i, ok := idx[k]; ok {
+			r[i].Value += s.Value
+			continue
+		}
+		idx[k] = len(r)
+		r = append(r, s)
+	}
+	return r
+}
+
+func sampleKey(s *Sample) string {
+	var buf strings.Builder
+	for _, f := range s.Stack {
+		fmt.Fprintf(&buf, "%#x %s %s:%d\n", f.PC, f.Function, f.File, f.Line)
+	}
+	keys := make([]string, 0, len(s.Labels))
+	for k := range s.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%q=%q\n", k, s.Labels[k])
+	}
+	return buf.String()
+}
+
+// WriteFolded writes samples in the folded stack format, one line per
+// distinct stack, with frames separated by ';' starting from the root
+// and followed by the value of the sample.
+func WriteFolded(w io.Writer, samples []Sample) error {
+	bw := bufio.NewWriter(w)
+	for _, s := range Merge(samples) {
+		for i := len(s.Stack) - 1; i >= 0; i-- {
+			fn := s.Stack[i].Function
+			if fn == "" {
+				fn = "?"
+			}
+			bw.WriteString(strings.Replace(fn, ";", ":", -1))
+			if i != 0 {
+				bw.WriteByte(';')
+			}
+		}
+		fmt.Fprintf(bw, " %d\n", s.Value)
+	}
+	return bw.Flush()
+}
+
+// Profile field numbers, see https://github.com/google/pprof/blob/master/proto/profile.proto
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+	sampleLabel      = 3
+
+	labelKey = 1
+	labelStr = 2
+
+	locationID      = 1
+	locationAddress = 3
+	locationLine    = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+)
+
+// WriteProto writes samples as a gzip compressed profile.proto message.
+// The values of the samples are described by sampleType and unit (for
+// example "goroutine" and "count").
+func WriteProto(w io.Writer, sampleType, unit string, samples []Sample) error {
+	b := &builder{strings: map[string]int{"": 0}, stringTable: []string{""}, functions: map[string]uint64{}, locations: map[string]uint64{}}
+
+	var vt protobuf
+	vt.int64(valueTypeType, b.str(sampleType))
+	vt.int64(valueTypeUnit, b.str(unit))
+	b.profile.message(profileSampleType, &vt)
+
+	for _, s := range Merge(samples) {
+		var sm protobuf
+		locs := make([]uint64, len(s.Stack))
+		for i := range s.Stack {
+			locs[i] = b.location(&s.Stack[i])
+		}
+		sm.uint64s(sampleLocationID, locs)
+		sm.uint64s(sampleValue, []uint64{uint64(s.Value)})
+		keys := make([]string, 0, len(s.Labels))
+		for k := range s.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			var lbl protobuf
+			lbl.int64(labelKey, b.str(k))
+			lbl.int64(labelStr, b.str(s.Labels[k]))
+			sm.message(sampleLabel, &lbl)
+		}
+		b.profile.message(profileSample, &sm)
+	}
+
+	b.profile.buf = append(b.profile.buf, b.locationsBuf.buf...)
+	b.profile.buf = append(b.profile.buf, b.functionsBuf.buf...)
+	for _, s := range b.stringTable {
+		b.profile.string(profileStringTable, s)
+	}
+	b.profile.int64(profileDefaultSampleType, b.str(sampleType))
+
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b.profile.buf); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+type builder struct {
+	profile      protobuf
+	locationsBuf protobuf
+	functionsBuf protobuf
+
+	strings     map[string]int
+	stringTable []string
+	functions   map[string]uint64
+	locations   map[string]uint64
+}
+
+func (b *builder) str(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return int64(i)
+	}
+	b.strings[s] = len(b.stringTable)
+	b.stringTable = append(b.stringTable, s)
+	return int64(len(b.stringTable) - 1)
+}
+
+func (b *builder) function(f *Frame) uint64 {
+	k := f.Function + "\x00" + f.File
+	if id, ok := b.functions[k]; ok {
+		return id
+	}
+	id := uint64(len(b.functions) + 1)
+	b.functions[k] = id
+	var fn protobuf
+	fn.uint64(functionID, id)
+	fn.int64(functionName, b.str(f.Function))
+	fn.int64(functionSystemName, b.str(f.Function))
+	fn.int64(functionFilename, b.str(f.File))
+	b.functionsBuf.message(profileFunction, &fn)
+	return id
+}
+
+func (b *builder) location(f *Frame) uint64 {
+	k := fmt.Sprintf("%#x %s %s:%d", f.PC, f.Function, f.File, f.Line)
+	if id, ok := b.locations[k]; ok {
+		return id
+	}
+	id := uint64(len(b.locations) + 1)
+	b.locations[k] = id
+	var loc, line protobuf
+	loc.uint64(locationID, id)
+	if f.PC != 0 {
+		loc.uint64(locationAddress, f.PC)
+	}
+	line.uint64(lineFunctionID, b.function(f))
+	line.int64(lineLine, int64(f.Line))
+	loc.message(locationLine, &line)
+	b.locationsBuf.message(profileLocation, &loc)
+	return id
+}
+
+// protobuf is a minimal protocol buffers encoder.
+type protobuf struct {
+	buf []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (p *protobuf) varint(x uint64) {
+	for x >= 0x80 {
+		p.buf = append(p.buf, byte(x)|0x80)
+		x >>= 7
+	}
+	p.buf = append(p.buf, byte(x))
+}
+
+func (p *protobuf) tag(field, wire int) {
+	p.varint(uint64(field)<<3 | uint64(wire))
+}
+
+func (p *protobuf) uint64(field int, x uint64) {
+	p.tag(field, wireVarint)
+	p.varint(x)
+}
+
+func (p *protobuf) int64(field int, x int64) {
+	p.uint64(field, uint64(x))
+}
+
+func (p *protobuf) uint64s(field int, xs []uint64) {
+	var packed protobuf
+	for _, x := range xs {
+		packed.varint(x)
+	}
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(packed.buf)))
+	p.buf = append(p.buf, packed.buf...)
+}
+
+func (p *protobuf) string(field int, s string) {
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(s)))
+	p.buf = append(p.buf, s...)
+}
+
+func (p *protobuf) message(field int, m *protobuf) {
+	p.tag(field, wireBytes)
+	p.varint(uint64(len(m.buf)))
+	p.buf = append(p.buf, m.buf...)
+}
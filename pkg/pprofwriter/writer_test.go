@@ -0,0 +1,50 @@
+package pprofwriter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"testing"
+)
+
+var testSamples = []Sample{
+	{Stack: []Frame{{PC: 0x30, Function: "main.leaf", File: "main.go", Line: 3}, {PC: 0x10, Function: "main.main", File: "main.go", Line: 10}}, Value: 1},
+	{Stack: []Frame{{PC: 0x50, Function: "runtime.gopark", File: "proc.go", Line: 300}}, Value: 1},
+	{Stack: []Frame{{PC: 0x30, Function: "main.leaf", File: "main.go", Line: 3}, {PC: 0x10, Function: "main.main", File: "main.go", Line: 10}}, Value: 1},
+	{Stack: []Frame{{PC: 0x30, Function: "main.leaf", File: "main.go", Line: 3}, {PC: 0x10, Function: "main.main", File: "main.go", Line: 10}}, Value: 1, Labels: map[string]string{"k": "v"}},
+}
+
+func TestWriteFolded(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteFolded(&buf, testSamples); err != nil {
+		t.Fatal(err)
+	}
+	const tgt = "main.main;main.leaf 2\nruntime.gopark 1\nmain.main;main.leaf 1\n"
+	if buf.String() != tgt {
+		t.Errorf("got:\n%s\nexpected:\n%s", buf.String(), tgt)
+	}
+}
+
+func TestWriteProto(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteProto(&buf, "goroutine", "count", testSamples); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"goroutine", "count", "main.leaf", "main.main", "runtime.gopark", "main.go", "proc.go"} {
+		if n := bytes.Count(data, []byte(s)); n != 1 {
+			t.Errorf("string %q found %d times in the string table, expected once", s, n)
+		}
+	}
+	// The first field must be sample_type, encoded as a length delimited message.
+	if len(data) == 0 || data[0] != profileSampleType<<3|wireBytes {
+		t.Errorf("unexpected first byte %#x", data[0])
+	}
+}
@@ -0,0 +1,129 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// Annotation is a name and a note assigned by the user to a goroutine or
+// to a range of addresses. Notes on breakpoints are stored in the
+// breakpoints themselves, see Breakpoint.Note.
+type Annotation struct {
+	// GoroutineID is the ID of the annotated goroutine, if it is zero the
+	// annotation is for the Size bytes starting at Addr.
+	GoroutineID int
+	Addr        uint64
+	Size        uint64
+
+	Name string
+	Note string
+}
+
+// annotations contains the annotations of a target.
+type annotations struct {
+	goroutines map[int]*Annotation
+	addrs      []*Annotation // sorted by Addr, at most one annotation for each address
+}
+
+// SetAnnotation adds a, replacing the annotation of the same goroutine or
+// starting at the same address. If both the name and the note of a are
+// empty the annotation is removed instead. Names of goroutines must be
+// unique. The annotated goroutine does not need to exist, so that
+// annotations can be restored at the start of a new debugging session.
+func (t *Target) SetAnnotation(a Annotation) error {
+	ann := &t.annotations
+	remove := a.Name == "" && a.Note == ""
+	if a.GoroutineID != 0 {
+		if a.Addr != 0 || a.Size != 0 {
+			return errors.New("an annotation can not be for both a goroutine and an address")
+		}
+		if remove {
+			delete(ann.goroutines, a.GoroutineID)
+			return nil
+		}
+		if err := ann.checkGoroutineName(a.GoroutineID, a.Name); err != nil {
+			return err
+		}
+		if ann.goroutines == nil {
+			ann.goroutines = make(map[int]*Annotation)
+		}
+		ann.goroutines[a.GoroutineID] = &a
+		return nil
+	}
+
+	if a.Addr == 0 {
+		return errors.New("annotation without a goroutine or an address")
+	}
+	if a.Size == 0 {
+		a.Size = 1
+	}
+	i := sort.Search(len(ann.addrs), func(i int) bool { return ann.addrs[i].Addr >= a.Addr })
+	found := i < len(ann.addrs) && ann.addrs[i].Addr == a.Addr
+	switch {
+	case remove && found:
+		ann.addrs = append(ann.addrs[:i], ann.addrs[i+1:]...)
+	case remove:
+		// nothing to do
+	case found:
+		ann.addrs[i] = &a
+	default:
+		ann.addrs = append(ann.addrs, nil)
+		copy(ann.addrs[i+1:], ann.addrs[i:])
+		ann.addrs[i] = &a
+	}
+	return nil
+}
+
+// checkGoroutineName returns an error if a goroutine other than gid is
+// named name.
+func (ann *annotations) checkGoroutineName(gid int, name string) error {
+	if name == "" {
+		return nil
+	}
+	for othergid, other := range ann.goroutines {
+		if other.Name == name && othergid != gid {
+			return fmt.Errorf("goroutine %d is already named %q", othergid, name)
+		}
+	}
+	return nil
+}
+
+// Annotations returns all annotations of t, the annotations of goroutines
+// sorted by goroutine ID followed by the annotations of addresses sorted
+// by address.
+func (t *Target) Annotations() []Annotation {
+	ann := &t.annotations
+	r := make([]Annotation, 0, len(ann.goroutines)+len(ann.addrs))
+	for _, a := range ann.goroutines {
+		r = append(r, *a)
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].GoroutineID < r[j].GoroutineID })
+	for _, a := range ann.addrs {
+		r = append(r, *a)
+	}
+	return r
+}
+
+// GoroutineAnnotation returns the annotation of goroutine gid.
+func (t *Target) GoroutineAnnotation(gid int) (Annotation, bool) {
+	a := t.annotations.goroutines[gid]
+	if a == nil {
+		return Annotation{}, false
+	}
+	return *a, true
+}
+
+// AddressAnnotation returns the annotation of the range of addresses
+// containing addr. If ranges overlap the one starting closest to addr is
+// returned.
+func (t *Target) AddressAnnotation(addr uint64) (Annotation, bool) {
+	addrs := t.annotations.addrs
+	i := sort.Search(len(addrs), func(i int) bool { return addrs[i].Addr > addr })
+	for i--; i >= 0; i-- {
+		if addr < addrs[i].Addr+addrs[i].Size {
+			return *addrs[i], true
+		}
+	}
+	return Annotation{}, false
+}
@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"encoding/binary"
 	"fmt"
 	"strings"
 
@@ -13,6 +14,7 @@ type Arch struct {
 	Name string // architecture name
 
 	ptrSize                  int
+	byteOrder                binary.ByteOrder
 	maxInstructionLength     int
 	prologues                []opcodeSeq
 	breakpointInstruction    []byte
@@ -24,6 +26,9 @@ type Arch struct {
 	SPRegNum                 uint64
 	BPRegNum                 uint64
 	ContextRegNum            uint64 // register used to pass a closure context when calling a function pointer
+	// intArgRegs are the registers used, in order, to pass integer arguments
+	// by the register based Go calling convention (ABIInternal).
+	intArgRegs []uint64
 
 	// asmDecode decodes the assembly instruction starting at mem[0:] into asmInst.
 	// It assumes that the Loc and AtPC fields of asmInst have already been filled.
@@ -76,6 +81,15 @@ const (
 	mask32 = 0xffffffff
 )
 
+// ByteOrder returns the byte order used by the architecture to store
+// integers in memory.
+func (a *Arch) ByteOrder() binary.ByteOrder {
+	if a.byteOrder == nil {
+		return binary.LittleEndian
+	}
+	return a.byteOrder
+}
+
 // PtrSize returns the size of a pointer for the architecture.
 func (a *Arch) PtrSize() int {
 	return a.ptrSize
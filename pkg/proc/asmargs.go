@@ -0,0 +1,82 @@
+package proc
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"fmt"
+	"path/filepath"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/util"
+)
+
+// asmArgFrameWords is the number of words of the argument frame of an
+// assembly function returned by assemblyFunctionTree. The size of the
+// argument frame is not recorded in the debug information.
+const asmArgFrameWords = 4
+
+// assembly returns true if fn was written in assembly.
+func (fn *Function) assembly() bool {
+	if fn.cu == nil || fn.cu.lineInfo == nil {
+		return false
+	}
+	file, _ := fn.cu.lineInfo.PCToLine(fn.Entry, fn.Entry)
+	return filepath.Ext(file) == ".s"
+}
+
+// assemblyFunctionTree returns a copy of tree, the DIE of an assembly
+// function, with fake formal parameters describing where its arguments
+// could be according to the Go calling conventions, since assembly
+// functions have no debug information for their arguments:
+//   - if the binary uses the register based calling convention, the
+//     registers used to pass integer arguments, named after the register
+//   - the first asmArgFrameWords words of the argument frame of the stack
+//     based calling convention, used by default by assembly functions,
+//     named after their offset from the FP pseudo-register (for example
+//     "+8(FP)")
+func assemblyFunctionTree(bi *BinaryInfo, tree *godwarf.Tree) (*godwarf.Tree, error) {
+	typ, err := bi.findType("uintptr")
+	if err != nil {
+		return nil, err
+	}
+
+	m := func(name string, loc []byte) *godwarf.Tree {
+		var e fakeEntry = map[dwarf.Attr]interface{}{
+			dwarf.AttrName:     name,
+			dwarf.AttrType:     typ.Common().Offset,
+			dwarf.AttrLocation: loc,
+			dwarf.AttrVarParam: false,
+		}
+		return &godwarf.Tree{Entry: e, Tag: dwarf.TagFormalParameter}
+	}
+
+	r := *tree
+	r.Children = nil
+	if bi.regabi {
+		for _, regnum := range bi.Arch.intArgRegs {
+			name, _, _ := bi.Arch.DwarfRegisterToString(int(regnum), nil)
+			loc := []byte{byte(op.DW_OP_regx)}
+			var buf bytes.Buffer
+			util.EncodeULEB128(&buf, regnum)
+			r.Children = append(r.Children, m(name, append(loc, buf.Bytes()...)))
+		}
+	}
+
+	// 0(FP) is the address of the first argument, right above the return
+	// address if it is saved on the stack by the call instruction, or right
+	// above the slot where the link register is saved by the caller.
+	var off uint64
+	if bi.Arch.usesLR {
+		off = uint64(bi.Arch.PtrSize())
+	}
+	for i := 0; i < asmArgFrameWords; i++ {
+		var buf bytes.Buffer
+		buf.WriteByte(byte(op.DW_OP_call_frame_cfa))
+		buf.WriteByte(byte(op.DW_OP_plus_uconst))
+		util.EncodeULEB128(&buf, off)
+		r.Children = append(r.Children, m(fmt.Sprintf("+%d(FP)", i*bi.Arch.PtrSize()), buf.Bytes()))
+		off += uint64(bi.Arch.PtrSize())
+	}
+	return &r, nil
+}
@@ -0,0 +1,44 @@
+package proc
+
+import "sync/atomic"
+
+// Starting with Go 1.14 the runtime preempts goroutines asynchronously by
+// sending SIGURG to the thread that is running them. These signals are an
+// implementation detail of the runtime and are sent very frequently, every
+// backend must deliver them to the target (or discard them while single
+// stepping, the runtime will send them again) without ever reporting them
+// as a reason to stop.
+// The number of signals handled this way is counted for diagnostic
+// purposes.
+
+// AsyncPreemptCounter counts the asynchronous preemption signals that a
+// backend handled without reporting them, backends use it to implement the
+// AsyncPreemptSignals method.
+type AsyncPreemptCounter struct {
+	n uint64
+}
+
+// Hide records that an asynchronous preemption signal was handled without
+// reporting it.
+func (c *AsyncPreemptCounter) Hide() {
+	atomic.AddUint64(&c.n, 1)
+}
+
+// AsyncPreemptSignals returns the number of asynchronous preemption
+// signals that were hidden.
+func (c *AsyncPreemptCounter) AsyncPreemptSignals() uint64 {
+	return atomic.LoadUint64(&c.n)
+}
+
+// AsyncPreemptSignals returns the number of asynchronous preemption
+// signals received by the target that the backend did not report. Signals
+// that the operating system or the debug stub deliver directly to the
+// target are not seen, and therefore not counted, by the backend. The
+// second return value is false if the backend does not keep count.
+func (t *Target) AsyncPreemptSignals() (uint64, bool) {
+	c, ok := t.proc.(interface{ AsyncPreemptSignals() uint64 })
+	if !ok {
+		return 0, false
+	}
+	return c.AsyncPreemptSignals(), true
+}
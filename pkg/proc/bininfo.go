@@ -60,6 +60,12 @@ type BinaryInfo struct {
 
 	// SymNames maps addr to a description *elf.Symbol of this addr.
 	SymNames map[uint64]*elf.Symbol
+	// symAddrs contains the keys of SymNames, sorted, it is updated by
+	// loadSymbolName along with SymNames, see symbolForPC.
+	symAddrs []uint64
+	// symbolizers resolve addresses that do not belong to any image, see
+	// Target.AddSymbolizer.
+	symbolizers []Symbolizer
 
 	// Images is a list of loaded shared libraries (also known as
 	// shared objects on linux or DLLs on windows).
@@ -80,7 +86,7 @@ type BinaryInfo struct {
 	// than one item in the slice.
 	PackageMap map[string][]string
 
-	frameEntries frame.FrameDescriptionEntries
+	frameEntries frame.Index
 
 	types       map[string]dwarfRef
 	packageVars []packageVar // packageVars is a list of all global/package variables in debug_info, sorted by address
@@ -119,15 +125,23 @@ var (
 	ErrNoDebugInfoFound = errors.New("could not open debug info")
 )
 
+// _EM_LOONGARCH is the ELF machine type of loong64 executables, it is not
+// defined by debug/elf before Go 1.19.
+const _EM_LOONGARCH elf.Machine = 258
+
 var (
 	supportedLinuxArch = map[elf.Machine]bool{
 		elf.EM_X86_64:  true,
 		elf.EM_AARCH64: true,
 		elf.EM_386:     true,
+		elf.EM_S390:    true,
+		elf.EM_RISCV:   true,
+		_EM_LOONGARCH:  true,
 	}
 
 	supportedWindowsArch = map[_PEMachine]bool{
 		_IMAGE_FILE_MACHINE_AMD64: true,
+		_IMAGE_FILE_MACHINE_ARM64: true,
 	}
 
 	supportedDarwinArch = map[macho.Cpu]bool{
@@ -146,10 +160,24 @@ func (err *ErrFunctionNotFound) Error() string {
 	return fmt.Sprintf("could not find function %s\n", err.FuncName)
 }
 
+// maxAsmLineSkip is the maximum number of lines without instructions
+// skipped by FindFileLocation in assembly files.
+const maxAsmLineSkip = 20
+
 // FindFileLocation returns the PC for a given file:line.
 // Assumes that `file` is normalized to lower case and '/' on Windows.
 func FindFileLocation(p Process, fileName string, lineno int) ([]uint64, error) {
 	pcs, err := p.BinInfo().LineToPC(fileName, lineno)
+	if lerr, ok := err.(*ErrCouldNotFindLine); ok && lerr.fileFound && filepath.Ext(fileName) == ".s" {
+		// Lines of assembly files without instructions, like labels and
+		// directives, resolve to the next line with instructions.
+		for i := 1; i <= maxAsmLineSkip && err != nil; i++ {
+			pcs, err = p.BinInfo().LineToPC(fileName, lineno+i)
+		}
+		if err != nil {
+			err = lerr
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -439,6 +467,12 @@ func NewBinaryInfo(goos, goarch string) *BinaryInfo {
 		r.Arch = AMD64Arch(goos)
 	case "arm64":
 		r.Arch = ARM64Arch(goos)
+	case "s390x":
+		r.Arch = S390XArch(goos)
+	case "riscv64":
+		r.Arch = RISCV64Arch(goos)
+	case "loong64":
+		r.Arch = LOONG64Arch(goos)
 	}
 	return r
 }
@@ -460,7 +494,7 @@ func loadBinaryInfo(bi *BinaryInfo, image *Image, path string, entryPoint uint64
 	defer wg.Wait()
 
 	switch bi.GOOS {
-	case "linux", "freebsd":
+	case "linux", "freebsd", "openbsd":
 		return loadBinaryInfoElf(bi, image, path, entryPoint, &wg)
 	case "windows":
 		return loadBinaryInfoPE(bi, image, path, entryPoint, &wg)
@@ -597,6 +631,31 @@ func (bi *BinaryInfo) AllPCsForFileLines(filename string, linenos []int) map[int
 	return r
 }
 
+// StmtLinesForFile returns, in increasing order, the lines of filename
+// where a breakpoint can be set: the lines that have a statement and the
+// lines of calls to inlined functions.
+func (bi *BinaryInfo) StmtLinesForFile(filename string) []int {
+	m := make(map[int]bool)
+	for _, image := range bi.Images {
+		for _, cu := range image.compileUnits {
+			if cu.lineInfo != nil && cu.lineInfo.Lookup[filename] != nil {
+				cu.lineInfo.AllStmtLinesForFile(filename, m)
+			}
+		}
+	}
+	for fl := range bi.inlinedCallLines {
+		if fl.file == filename {
+			m[fl.line] = true
+		}
+	}
+	r := make([]int, 0, len(m))
+	for line := range m {
+		r = append(r, line)
+	}
+	sort.Ints(r)
+	return r
+}
+
 // PCToFunc returns the concrete function containing the given PC address.
 // If the PC address belongs to an inlined call it will return the containing function.
 func (bi *BinaryInfo) PCToFunc(pc uint64) *Function {
@@ -663,7 +722,7 @@ type Image struct {
 	compileUnits []*compileUnit // compileUnits is sorted by increasing DWARF offset
 
 	dwarfTreeCache      *simplelru.LRU
-	runtimeMallocgcTree *godwarf.Tree // patched version of runtime.mallocgc's DIE
+	runtimePatchedTrees map[dwarf.Offset]*godwarf.Tree // patched versions of the DIEs of regabiRuntimeFuncs
 
 	// runtimeTypeToDIE maps between the offset of a runtime._type in
 	// runtime.moduledata.types and the offset of the DIE in debug_info. This
@@ -673,6 +732,10 @@ type Image struct {
 
 	loadErrMu sync.Mutex
 	loadErr   error
+
+	// lowpc and highpc delimit the range of addresses where the image is
+	// loaded, highpc is excluded.
+	lowpc, highpc uint64
 }
 
 func (image *Image) registerRuntimeTypeToDIE(entry *dwarf.Entry, ardr *reader.Reader) {
@@ -783,14 +846,39 @@ func (image *Image) setLoadError(fmtstr string, args ...interface{}) {
 	image.loadErrMu.Unlock()
 }
 
+// AddrRange returns the range of addresses where the image is loaded,
+// highpc is excluded. Returns 0, 0 if the range is not known.
+func (image *Image) AddrRange() (lowpc, highpc uint64) {
+	return image.lowpc, image.highpc
+}
+
+// addAddrRange extends the range of addresses of the image to include the
+// segment [start, end), specified using unrelocated addresses.
+func (image *Image) addAddrRange(start, end uint64) {
+	start += image.StaticBase
+	end += image.StaticBase
+	if image.highpc == 0 || start < image.lowpc {
+		image.lowpc = start
+	}
+	if end > image.highpc {
+		image.highpc = end
+	}
+}
+
+// HasDebugInfo returns true if DWARF debug info was found for the image,
+// either in the image itself or in a separate file.
+func (image *Image) HasDebugInfo() bool {
+	return image.dwarf != nil
+}
+
 // LoadError returns any error incurred while loading this image.
 func (image *Image) LoadError() error {
 	return image.loadErr
 }
 
 func (image *Image) getDwarfTree(off dwarf.Offset) (*godwarf.Tree, error) {
-	if image.runtimeMallocgcTree != nil && off == image.runtimeMallocgcTree.Offset {
-		return image.runtimeMallocgcTree, nil
+	if tree, ok := image.runtimePatchedTrees[off]; ok {
+		return tree, nil
 	}
 	if r, ok := image.dwarfTreeCache.Get(off); ok {
 		return r.(*godwarf.Tree), nil
@@ -818,7 +906,7 @@ func (bi *BinaryInfo) LoadImageFromData(dwdata *dwarf.Data, debugFrameBytes, deb
 	image.dwarfTreeCache, _ = simplelru.NewLRU(dwarfTreeCacheSize, nil)
 
 	if debugFrameBytes != nil {
-		bi.frameEntries, _ = frame.Parse(debugFrameBytes, frame.DwarfEndian(debugFrameBytes), 0, bi.Arch.PtrSize(), 0)
+		bi.frameEntries.AddDebugFrame(debugFrameBytes, frame.DwarfEndian(debugFrameBytes), 0, bi.Arch.PtrSize())
 	}
 
 	image.loclist2 = loclist.NewDwarf2Reader(debugLocBytes, bi.Arch.PtrSize())
@@ -1021,23 +1109,24 @@ func (bi *BinaryInfo) funcToImage(fn *Function) *Image {
 // parseDebugFrameGeneral parses a debug_frame and a eh_frame section.
 // At least one of the two must be present and parsed correctly, if
 // debug_frame is present it must be parsable correctly.
-func (bi *BinaryInfo) parseDebugFrameGeneral(image *Image, debugFrameBytes []byte, debugFrameName string, debugFrameErr error, ehFrameBytes []byte, ehFrameAddr uint64, ehFrameName string, byteOrder binary.ByteOrder) {
+// If the eh_frame_hdr section is present FDEs of eh_frame are found
+// using its binary search table, instead of reading eh_frame in advance.
+func (bi *BinaryInfo) parseDebugFrameGeneral(image *Image, debugFrameBytes []byte, debugFrameName string, debugFrameErr error, ehFrameBytes []byte, ehFrameAddr uint64, ehFrameHdrBytes []byte, ehFrameHdrAddr uint64, ehFrameName string, byteOrder binary.ByteOrder) {
 	if debugFrameBytes == nil && ehFrameBytes == nil {
 		image.setLoadError("could not get %s section: %v", debugFrameName, debugFrameErr)
 		return
 	}
 
 	if debugFrameBytes != nil {
-		fe, err := frame.Parse(debugFrameBytes, byteOrder, image.StaticBase, bi.Arch.PtrSize(), 0)
+		err := bi.frameEntries.AddDebugFrame(debugFrameBytes, byteOrder, image.StaticBase, bi.Arch.PtrSize())
 		if err != nil {
 			image.setLoadError("could not parse %s section: %v", debugFrameName, err)
 			return
 		}
-		bi.frameEntries = bi.frameEntries.Append(fe)
 	}
 
 	if ehFrameBytes != nil && ehFrameAddr > 0 {
-		fe, err := frame.Parse(ehFrameBytes, byteOrder, image.StaticBase, bi.Arch.PtrSize(), ehFrameAddr)
+		err := bi.frameEntries.AddEHFrame(ehFrameBytes, ehFrameHdrBytes, ehFrameHdrAddr, byteOrder, image.StaticBase, bi.Arch.PtrSize(), ehFrameAddr)
 		if err != nil {
 			if debugFrameBytes == nil {
 				image.setLoadError("could not parse %s section: %v", ehFrameName, err)
@@ -1046,7 +1135,6 @@ func (bi *BinaryInfo) parseDebugFrameGeneral(image *Image, debugFrameBytes []byt
 			bi.logger.Warnf("could not parse %s section: %v", ehFrameName, err)
 			return
 		}
-		bi.frameEntries = bi.frameEntries.Append(fe)
 	}
 }
 
@@ -1123,7 +1211,7 @@ func parseBuildID(exe *elf.File) (string, string, error) {
 
 	br := buildid.Open()
 	bh := new(buildIDHeader)
-	if err := binary.Read(br, binary.LittleEndian, bh); err != nil {
+	if err := binary.Read(br, exe.ByteOrder, bh); err != nil {
 		return "", "", errors.New("can't read build-id header: " + err.Error())
 	}
 
@@ -1177,6 +1265,11 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 	} else {
 		image.StaticBase = addr
 	}
+	for _, prog := range elfFile.Progs {
+		if prog.Type == elf.PT_LOAD {
+			image.addAddrRange(prog.Vaddr, prog.Vaddr+prog.Memsz)
+		}
+	}
 
 	dwarfFile := elfFile
 
@@ -1187,6 +1280,15 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 		var serr error
 		sepFile, dwarfFile, serr = bi.openSeparateDebugInfo(image, elfFile, bi.debugInfoDirectories)
 		if serr != nil {
+			if image.index != 0 {
+				// Shared objects without debug symbols (for example C libraries
+				// called through cgo) still have an .eh_frame section and a
+				// symbol table, which are enough to unwind through their frames
+				// and symbolize them.
+				wg.Add(2)
+				go bi.parseDebugFrameElf(image, elfFile, elfFile.ByteOrder, wg)
+				go bi.loadSymbolName(image, elfFile, wg)
+			}
 			return serr
 		}
 		image.sepDebugCloser = sepFile
@@ -1217,7 +1319,7 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 	image.debugLineStr = debugLineStrBytes
 
 	wg.Add(3)
-	go bi.parseDebugFrameElf(image, dwarfFile, debugInfoBytes, wg)
+	go bi.parseDebugFrameElf(image, dwarfFile, frame.DwarfEndian(debugInfoBytes), wg)
 	go bi.loadDebugInfoMaps(image, debugInfoBytes, debugLineBytes, wg, nil)
 	go bi.loadSymbolName(image, elfFile, wg)
 	if image.index == 0 {
@@ -1228,26 +1330,46 @@ func loadBinaryInfoElf(bi *BinaryInfo, image *Image, path string, addr uint64, w
 	return nil
 }
 
-//  _STT_FUNC is a code object, see /usr/include/elf.h for a full definition.
-const _STT_FUNC = 2
-
 func (bi *BinaryInfo) loadSymbolName(image *Image, file *elf.File, wg *sync.WaitGroup) {
 	defer wg.Done()
 	if bi.SymNames == nil {
 		bi.SymNames = make(map[uint64]*elf.Symbol)
 	}
 	symSecs, _ := file.Symbols()
-	if symSecs != nil {
+	// Stripped shared objects only have the dynamic symbol table.
+	dynSymSecs, _ := file.DynamicSymbols()
+	for _, symSecs := range [][]elf.Symbol{symSecs, dynSymSecs} {
 		for _, symSec := range symSecs {
-			if symSec.Info == _STT_FUNC { // TODO(chainhelen), need to parse others types.
+			if elf.ST_TYPE(symSec.Info) == elf.STT_FUNC && symSec.Value != 0 { // TODO(chainhelen), need to parse others types.
+				if _, dup := bi.SymNames[symSec.Value+image.StaticBase]; dup {
+					continue
+				}
 				s := symSec
 				bi.SymNames[symSec.Value+image.StaticBase] = &s
+				bi.symAddrs = append(bi.symAddrs, symSec.Value+image.StaticBase)
 			}
 		}
 	}
+	sort.Slice(bi.symAddrs, func(i, j int) bool { return bi.symAddrs[i] < bi.symAddrs[j] })
+}
+
+// symbolForPC returns the name and address of the ELF function symbol
+// containing pc. It is used to symbolize frames of functions without debug
+// symbols.
+func (bi *BinaryInfo) symbolForPC(pc uint64) (string, uint64) {
+	i := sort.Search(len(bi.symAddrs), func(i int) bool { return bi.symAddrs[i] > pc }) - 1
+	if i < 0 {
+		return "", 0
+	}
+	addr := bi.symAddrs[i]
+	sym := bi.SymNames[addr]
+	if sym.Size != 0 && pc >= addr+sym.Size {
+		return "", 0
+	}
+	return sym.Name, addr
 }
 
-func (bi *BinaryInfo) parseDebugFrameElf(image *Image, exe *elf.File, debugInfoBytes []byte, wg *sync.WaitGroup) {
+func (bi *BinaryInfo) parseDebugFrameElf(image *Image, exe *elf.File, byteOrder binary.ByteOrder, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	debugFrameData, debugFrameErr := godwarf.GetDebugSectionElf(exe, "frame")
@@ -1258,8 +1380,15 @@ func (bi *BinaryInfo) parseDebugFrameElf(image *Image, exe *elf.File, debugInfoB
 		ehFrameAddr = ehFrameSection.Addr
 		ehFrameData, _ = ehFrameSection.Data()
 	}
+	ehFrameHdrSection := exe.Section(".eh_frame_hdr")
+	var ehFrameHdrData []byte
+	var ehFrameHdrAddr uint64
+	if ehFrameHdrSection != nil {
+		ehFrameHdrAddr = ehFrameHdrSection.Addr
+		ehFrameHdrData, _ = ehFrameHdrSection.Data()
+	}
 
-	bi.parseDebugFrameGeneral(image, debugFrameData, ".debug_frame", debugFrameErr, ehFrameData, ehFrameAddr, ".eh_frame", frame.DwarfEndian(debugInfoBytes))
+	bi.parseDebugFrameGeneral(image, debugFrameData, ".debug_frame", debugFrameErr, ehFrameData, ehFrameAddr, ehFrameHdrData, ehFrameHdrAddr, ".eh_frame", byteOrder)
 }
 
 func (bi *BinaryInfo) setGStructOffsetElf(image *Image, exe *elf.File, wg *sync.WaitGroup) {
@@ -1311,6 +1440,28 @@ func (bi *BinaryInfo) setGStructOffsetElf(image *Image, exe *elf.File, wg *sync.
 
 		bi.gStructOffset = tlsg.Value + uint64(bi.Arch.PtrSize()*2) + ((tls.Vaddr - uint64(bi.Arch.PtrSize()*2)) & (tls.Align - 1))
 
+	case elf.EM_S390:
+		// Go code keeps the pointer to g in R13, it is only saved in TLS by cgo
+		// programs. The TLS block ends at the thread pointer, like on x86.
+		tlsg := getSymbol(image, exe, "runtime.tls_g")
+		if tlsg == nil || tls == nil {
+			bi.gStructOffset = 0
+			return
+		}
+		memsz := tls.Memsz + (-tls.Vaddr-tls.Memsz)&(tls.Align-1)
+		bi.gStructOffset = ^(memsz) + 1 + tlsg.Value // -tls.Memsz + tlsg.Value
+
+	case elf.EM_RISCV, _EM_LOONGARCH:
+		// Go code keeps the pointer to g in X27 on riscv64 and in R22 on
+		// loong64, it is only saved in TLS by cgo programs. The thread pointer
+		// points to the start of the TLS block.
+		tlsg := getSymbol(image, exe, "runtime.tls_g")
+		if tlsg == nil || tls == nil {
+			bi.gStructOffset = 0
+			return
+		}
+		bi.gStructOffset = tlsg.Value
+
 	default:
 		// we should never get here
 		panic("architecture not supported")
@@ -1366,6 +1517,10 @@ func loadBinaryInfoPE(bi *BinaryInfo, image *Image, path string, entryPoint uint
 			return ErrCouldNotDetermineRelocation
 		}
 	}
+	for _, sec := range peFile.Sections {
+		start := opth.ImageBase + uint64(sec.VirtualAddress)
+		image.addAddrRange(start, start+uint64(sec.VirtualSize))
+	}
 
 	image.dwarfReader = image.dwarf.Reader()
 
@@ -1384,11 +1539,13 @@ func loadBinaryInfoPE(bi *BinaryInfo, image *Image, path string, entryPoint uint
 	go bi.parseDebugFramePE(image, peFile, debugInfoBytes, wg)
 	go bi.loadDebugInfoMaps(image, debugInfoBytes, debugLineBytes, wg, nil)
 
-	// Use ArbitraryUserPointer (0x28) as pointer to pointer
-	// to G struct per:
-	// https://golang.org/src/runtime/cgo/gcc_windows_amd64.c
+	if cpuArch == _IMAGE_FILE_MACHINE_AMD64 {
+		// Use ArbitraryUserPointer (0x28) as pointer to pointer
+		// to G struct per:
+		// https://golang.org/src/runtime/cgo/gcc_windows_amd64.c
 
-	bi.gStructOffset = 0x28
+		bi.gStructOffset = 0x28
+	}
 	return nil
 }
 
@@ -1409,7 +1566,7 @@ func (bi *BinaryInfo) parseDebugFramePE(image *Image, exe *pe.File, debugInfoByt
 	defer wg.Done()
 
 	debugFrameBytes, err := godwarf.GetDebugSectionPE(exe, "frame")
-	bi.parseDebugFrameGeneral(image, debugFrameBytes, ".debug_frame", err, nil, 0, "", frame.DwarfEndian(debugInfoBytes))
+	bi.parseDebugFrameGeneral(image, debugFrameBytes, ".debug_frame", err, nil, 0, nil, 0, "", frame.DwarfEndian(debugInfoBytes))
 }
 
 // MACH-O ////////////////////////////////////////////////////////////
@@ -1429,6 +1586,11 @@ func loadBinaryInfoMacho(bi *BinaryInfo, image *Image, path string, entryPoint u
 		// (which is 0x100000000)
 		image.StaticBase = entryPoint - 0x100000000
 	}
+	for _, load := range exe.Loads {
+		if seg, ok := load.(*macho.Segment); ok && seg.Name != "__PAGEZERO" {
+			image.addAddrRange(seg.Addr, seg.Addr+seg.Memsz)
+		}
+	}
 
 	image.closer = exe
 	if !supportedDarwinArch[exe.Cpu] {
@@ -1486,7 +1648,7 @@ func (bi *BinaryInfo) parseDebugFrameMacho(image *Image, exe *macho.File, debugI
 		ehFrameBytes, _ = ehFrameSection.Data()
 	}
 
-	bi.parseDebugFrameGeneral(image, debugFrameBytes, "__debug_frame", debugFrameErr, ehFrameBytes, ehFrameAddr, "__eh_frame", frame.DwarfEndian(debugInfoBytes))
+	bi.parseDebugFrameGeneral(image, debugFrameBytes, "__debug_frame", debugFrameErr, ehFrameBytes, ehFrameAddr, nil, 0, "__eh_frame", frame.DwarfEndian(debugInfoBytes))
 }
 
 // macOSDebugFrameBugWorkaround applies a workaround for:
@@ -1536,16 +1698,9 @@ func (bi *BinaryInfo) macOSDebugFrameBugWorkaround() {
 	}
 
 	// Find lowest FDE in debug_frame
-	var fde *frame.FrameDescriptionEntry
-	for i := range bi.frameEntries {
-		if bi.frameEntries[i].CIE.CIE_id == ^uint32(0) {
-			fde = bi.frameEntries[i]
-			break
-		}
-	}
-
+	fde := bi.frameEntries.FirstDebugFrameFDE()
 	if fde == nil {
-		bi.logger.Warnf("debug_frame workaround not applied because there are no debug_frame entries (%d)", len(bi.frameEntries))
+		bi.logger.Warn("debug_frame workaround not applied because there are no debug_frame entries")
 		return
 	}
 
@@ -1560,11 +1715,7 @@ func (bi *BinaryInfo) macOSDebugFrameBugWorkaround() {
 
 	bi.logger.Infof("applying debug_frame workaround +%#x: function %s (at %#x-%#x) and FDE %#x-%#x", delta, fn.Name, fn.Entry, fn.End, fde.Begin(), fde.End())
 
-	for i := range bi.frameEntries {
-		if bi.frameEntries[i].CIE.CIE_id == ^uint32(0) {
-			bi.frameEntries[i].Translate(delta)
-		}
-	}
+	bi.frameEntries.TranslateDebugFrame(delta)
 }
 
 // Do not call this function directly it isn't able to deal correctly with package paths
@@ -1736,10 +1887,10 @@ func (bi *BinaryInfo) loadDebugInfoMaps(image *Image, debugInfoBytes, debugLineB
 					logger := logrus.New().WithFields(logrus.Fields{"layer": "dwarf-line"})
 					logger.Logger.Level = logrus.DebugLevel
 					logfn = func(fmt string, args ...interface{}) {
-						logger.Printf(fmt, args)
+						logger.Printf(fmt, args...)
 					}
 				}
-				cu.lineInfo = line.Parse(compdir, bytes.NewBuffer(debugLineBytes[lineInfoOffset:]), image.debugLineStr, logfn, image.StaticBase, bi.GOOS == "windows", bi.Arch.PtrSize())
+				cu.lineInfo = line.Parse(compdir, bytes.NewBuffer(debugLineBytes[lineInfoOffset:]), image.debugLineStr, logfn, image.StaticBase, bi.GOOS == "windows", bi.Arch.PtrSize(), bi.Arch.ByteOrder())
 			}
 			cu.producer, _ = entry.Val(dwarf.AttrProducer).(string)
 			if cu.isgo && cu.producer != "" {
@@ -1796,18 +1947,26 @@ func (bi *BinaryInfo) loadDebugInfoMaps(image *Image, debugInfoBytes, debugLineB
 	bi.Sources = uniq(bi.Sources)
 
 	if bi.regabi {
-		// prepare patch for runtime.mallocgc's DIE
-		fn := bi.LookupFunc["runtime.mallocgc"]
-		if fn != nil {
+		// prepare patches for the DIEs of the runtime functions used by call
+		// injection
+		for _, fnname := range regabiRuntimeFuncs {
+			fn := bi.LookupFunc[fnname]
+			if fn == nil {
+				continue
+			}
 			tree, err := image.getDwarfTree(fn.offset)
-			if err == nil {
-				tree.Children, err = regabiMallocgcWorkaround(bi)
-				if err != nil {
-					bi.logger.Errorf("could not patch runtime.mallogc: %v", err)
-				} else {
-					image.runtimeMallocgcTree = tree
-				}
+			if err != nil {
+				continue
+			}
+			tree.Children, err = regabiRuntimeFuncWorkaround(bi, fnname)
+			if err != nil {
+				bi.logger.Errorf("could not patch %s: %v", fnname, err)
+				continue
+			}
+			if image.runtimePatchedTrees == nil {
+				image.runtimePatchedTrees = make(map[dwarf.Offset]*godwarf.Tree)
 			}
+			image.runtimePatchedTrees[tree.Offset] = tree
 		}
 	}
 
@@ -1858,7 +2017,7 @@ func (bi *BinaryInfo) loadDebugInfoMapsCompileUnit(ctxt *loadDebugInfoMapsContex
 				var addr uint64
 				if loc, ok := entry.Val(dwarf.AttrLocation).([]byte); ok {
 					if len(loc) == bi.Arch.PtrSize()+1 && op.Opcode(loc[0]) == op.DW_OP_addr {
-						addr, _ = util.ReadUintRaw(bytes.NewReader(loc[1:]), binary.LittleEndian, bi.Arch.PtrSize())
+						addr, _ = util.ReadUintRaw(bytes.NewReader(loc[1:]), bi.Arch.ByteOrder(), bi.Arch.PtrSize())
 					}
 				}
 				if !cu.isgo {
@@ -8,6 +8,8 @@ import (
 	"go/parser"
 	"go/token"
 	"reflect"
+	"sort"
+	"strings"
 )
 
 const (
@@ -18,8 +20,13 @@ const (
 	// process dies because of a fatal runtime error.
 	FatalThrow = "runtime-fatal-throw"
 
+	// TestFailure is the name given to the breakpoint triggered when a test
+	// of a test binary fails, see GetTestFailure.
+	TestFailure = "test-failure"
+
 	unrecoveredPanicID = -1
 	fatalThrowID       = -2
+	testFailureID      = -3
 )
 
 // Breakpoint represents a physical breakpoint. Stores information on the break
@@ -34,6 +41,7 @@ type Breakpoint struct {
 	Addr         uint64 // Address breakpoint is set for.
 	OriginalData []byte // If software breakpoint, the data we replace with breakpoint instruction.
 	Name         string // User defined name of the breakpoint
+	Note         string // User defined note of the breakpoint
 	LogicalID    int    // ID of the logical breakpoint that owns this physical breakpoint
 
 	WatchExpr    string
@@ -51,13 +59,29 @@ type Breakpoint struct {
 	Tracepoint    bool // Tracepoint flag
 	TraceReturn   bool
 	Goroutine     bool     // Retrieve goroutine information
-	Stacktrace    int      // Number of stack frames to retrieve
+	Stacktrace    int      // Number of stack frames to retrieve, or StacktraceAll
+	// AggregateStacks: if set the breakpoint never stops the target, instead
+	// the stacks that reach it are recorded and counted, see StackRecords.
+	AggregateStacks bool
+	// SerializeHits: if set, when the breakpoint is hit by a goroutine at
+	// the same time another goroutine stops the target, the hit is not
+	// reported with the stop, it is reported by a following call to
+	// Continue, see (*Target).PendingHits.
+	SerializeHits bool
 	Variables     []string // Variables to evaluate
 	LoadArgs      *LoadConfig
 	LoadLocals    *LoadConfig
+	// LoadLocalsPaths, if not empty, are the selector paths of the locals
+	// loaded with LoadLocals, the other locals are loaded shallowly, see
+	// EvalScope.LocalVariablesInterest.
+	LoadLocalsPaths []string
 	HitCount      map[int]uint64 // Number of times a breakpoint has been reached in a certain goroutine
 	TotalHitCount uint64         // Number of times a breakpoint has been reached
 
+	// reachCount is the number of times the breakpoint has been reached,
+	// regardless of its condition, it is returned by the hitcount builtin.
+	reachCount uint64
+
 	// DeferReturns: when kind == NextDeferBreakpoint this breakpoint
 	// will also check if the caller is runtime.gopanic or if the return
 	// address is in the DeferReturns array.
@@ -82,8 +106,23 @@ type Breakpoint struct {
 	// ReturnInfo describes how to collect return variables when this
 	// breakpoint is hit as a return breakpoint.
 	returnInfo *returnBreakpointInfo
+
+	// stackRecords maps the call path of each stack recorded by an
+	// AggregateStacks breakpoint to its record.
+	stackRecords map[string]*StackRecord
 }
 
+// StackRecord is a call path that reached an AggregateStacks breakpoint and
+// the number of times it did.
+type StackRecord struct {
+	Count uint64
+	Stack []Location
+}
+
+// defaultAggregateStackDepth is the depth of the stacks recorded by
+// AggregateStacks breakpoints that do not specify one.
+const defaultAggregateStackDepth = 50
+
 // BreakpointKind determines the behavior of delve when the
 // breakpoint is reached.
 type BreakpointKind uint16
@@ -171,7 +210,13 @@ type returnBreakpointInfo struct {
 // CheckCondition evaluates bp's condition on thread.
 func (bp *Breakpoint) CheckCondition(thread Thread) BreakpointState {
 	bpstate := BreakpointState{Breakpoint: bp, Active: false, Internal: false, CondError: nil}
+	if bp.IsUser() {
+		bp.reachCount++
+	}
 	bpstate.checkCond(thread)
+	if bpstate.Active && bp.Name == TestFailure && isParentTestFailure(thread) {
+		bpstate.Active = false
+	}
 	// Update the breakpoint hit counts.
 	if bpstate.Breakpoint != nil && bpstate.Active {
 		if g, err := GetG(thread); err == nil {
@@ -180,9 +225,71 @@ func (bp *Breakpoint) CheckCondition(thread Thread) BreakpointState {
 		bpstate.TotalHitCount++
 	}
 	bpstate.checkHitCond(thread)
+	if bpstate.Active && !bpstate.Internal && bp.AggregateStacks {
+		bp.recordStack(thread)
+		bpstate.Active = false
+	}
 	return bpstate
 }
 
+// recordStack adds the stack of thread to the stack records of bp.
+func (bp *Breakpoint) recordStack(thread Thread) {
+	depth := bp.Stacktrace
+	if depth == 0 {
+		depth = defaultAggregateStackDepth
+	}
+	frames, err := ThreadStacktrace(thread, depth)
+	if err != nil {
+		return
+	}
+	var key strings.Builder
+	stack := make([]Location, 0, len(frames))
+	for _, frame := range frames {
+		if frame.Err != nil {
+			break
+		}
+		fmt.Fprintf(&key, "%#x:%d ", frame.Call.PC, frame.Call.Line)
+		stack = append(stack, frame.Call)
+	}
+	if bp.stackRecords == nil {
+		bp.stackRecords = make(map[string]*StackRecord)
+	}
+	r := bp.stackRecords[key.String()]
+	if r == nil {
+		r = &StackRecord{Stack: stack}
+		bp.stackRecords[key.String()] = r
+	}
+	r.Count++
+}
+
+// StackRecords returns the stacks recorded by an AggregateStacks
+// breakpoint, the most frequent first.
+func (bp *Breakpoint) StackRecords() []StackRecord {
+	r := make([]StackRecord, 0, len(bp.stackRecords))
+	for _, rec := range bp.stackRecords {
+		r = append(r, *rec)
+	}
+	SortStackRecords(r)
+	return r
+}
+
+// SortStackRecords sorts records by decreasing count, records with the
+// same count are sorted by the addresses of their frames.
+func SortStackRecords(records []StackRecord) {
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Count != records[j].Count {
+			return records[i].Count > records[j].Count
+		}
+		si, sj := records[i].Stack, records[j].Stack
+		for k := 0; k < len(si) && k < len(sj); k++ {
+			if si[k].PC != sj[k].PC {
+				return si[k].PC < sj[k].PC
+			}
+		}
+		return len(si) < len(sj)
+	})
+}
+
 func (bpstate *BreakpointState) checkCond(thread Thread) {
 	if bpstate.Cond == nil && bpstate.internalCond == nil {
 		bpstate.Active = true
@@ -202,7 +309,7 @@ func (bpstate *BreakpointState) checkCond(thread Thread) {
 	}
 	if bpstate.IsInternal() {
 		// Check internalCondition if this is also an internal breakpoint
-		bpstate.Active, bpstate.CondError = evalBreakpointCondition(thread, bpstate.internalCond)
+		bpstate.Active, bpstate.CondError = evalBreakpointCondition(bpstate.Breakpoint, thread, bpstate.internalCond)
 		bpstate.Active = bpstate.Active && nextDeferOk
 		if bpstate.Active || bpstate.CondError != nil {
 			bpstate.Internal = true
@@ -211,7 +318,7 @@ func (bpstate *BreakpointState) checkCond(thread Thread) {
 	}
 	if bpstate.IsUser() {
 		// Check normal condition if this is also a user breakpoint
-		bpstate.Active, bpstate.CondError = evalBreakpointCondition(thread, bpstate.Cond)
+		bpstate.Active, bpstate.CondError = evalBreakpointCondition(bpstate.Breakpoint, thread, bpstate.Cond)
 	}
 }
 
@@ -285,7 +392,7 @@ func (bp *Breakpoint) IsUser() bool {
 	return bp.Kind&UserBreakpoint != 0
 }
 
-func evalBreakpointCondition(thread Thread, cond ast.Expr) (bool, error) {
+func evalBreakpointCondition(bp *Breakpoint, thread Thread, cond ast.Expr) (bool, error) {
 	if cond == nil {
 		return true, nil
 	}
@@ -296,6 +403,7 @@ func evalBreakpointCondition(thread Thread, cond ast.Expr) (bool, error) {
 			return true, err
 		}
 	}
+	scope.bp = bp
 	v, err := scope.evalAST(cond)
 	if err != nil {
 		return true, fmt.Errorf("error evaluating expression: %v", err)
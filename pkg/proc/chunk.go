@@ -0,0 +1,135 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// valueChunkSize is the size of the chunks of memory read by
+// WriteExpressionValue.
+const valueChunkSize = 1024 * 1024
+
+// ValueChunk is a part of the elements of a string, array or slice.
+type ValueChunk struct {
+	// Len is the length of the whole value.
+	Len int64
+	// Offset is the index of the first element of the chunk.
+	Offset int64
+	// Data is the content of the elements of the chunk, it is set for
+	// strings and for arrays and slices of bytes.
+	Data []byte
+	// Elems are the elements of the chunk, for other arrays and slices.
+	Elems []Variable
+}
+
+// EvalExpressionChunk evaluates expr, which must be a string, an array or
+// a slice, and returns at most count of its elements starting at offset.
+// It allows clients to fetch very large values incrementally, without
+// loading them entirely.
+func (scope *EvalScope) EvalExpressionChunk(expr string, offset, count int64, cfg LoadConfig) (*ValueChunk, error) {
+	v, err := scope.evalSequence(expr)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || count <= 0 {
+		return nil, errors.New("invalid chunk")
+	}
+	r := &ValueChunk{Len: v.Len, Offset: offset}
+	if offset >= v.Len {
+		return r, nil
+	}
+	if offset+count > v.Len {
+		count = v.Len - offset
+	}
+	chunk, err := v.reslice(offset, offset+count)
+	if err != nil {
+		return nil, err
+	}
+	if isByteSequence(v) {
+		r.Data = make([]byte, count)
+		if _, err := chunk.mem.ReadMemory(r.Data, chunk.Base); err != nil {
+			return nil, err
+		}
+		return r, nil
+	}
+	cfg.MaxArrayValues = int(count)
+	chunk.loadValue(cfg)
+	if chunk.Unreadable != nil {
+		return nil, chunk.Unreadable
+	}
+	r.Elems = chunk.Children
+	return r, nil
+}
+
+// WriteExpressionValue evaluates expr, which must be a string, an array
+// or a slice, and writes the content of its elements to w, without
+// loading the whole value in memory. It returns the number of bytes
+// written.
+func (scope *EvalScope) WriteExpressionValue(w io.Writer, expr string) (int64, error) {
+	v, err := scope.evalSequence(expr)
+	if err != nil {
+		return 0, err
+	}
+	mem := v.mem
+	if v.Kind != reflect.Array {
+		mem = DereferenceMemory(mem)
+	}
+	size := v.Len * v.stride
+	buf := make([]byte, valueChunkSize)
+	var written int64
+	for written < size {
+		if size-written < int64(len(buf)) {
+			buf = buf[:size-written]
+		}
+		if _, err := mem.ReadMemory(buf, v.Base+uint64(written)); err != nil {
+			return written, err
+		}
+		n, err := w.Write(buf)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// evalSequence evaluates expr, without loading its elements, and checks
+// that it is a string, an array or a slice.
+func (scope *EvalScope) evalSequence(expr string) (*Variable, error) {
+	v, err := scope.EvalExpression(expr, loadSingleValue)
+	if err != nil {
+		return nil, err
+	}
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	switch v.Kind {
+	case reflect.String, reflect.Array, reflect.Slice:
+	default:
+		return nil, fmt.Errorf("%s is not a string, an array or a slice", expr)
+	}
+	if v.Flags&VariableCPtr != 0 {
+		return nil, fmt.Errorf("the length of %s is unknown", expr)
+	}
+	return v, nil
+}
+
+// isByteSequence returns true if v is a string or an array or slice of
+// bytes.
+func isByteSequence(v *Variable) bool {
+	if v.Kind == reflect.String {
+		return true
+	}
+	if v.stride != 1 {
+		return false
+	}
+	switch resolveTypedef(v.fieldType).(type) {
+	case *godwarf.UintType, *godwarf.IntType:
+		return true
+	}
+	return false
+}
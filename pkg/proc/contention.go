@@ -0,0 +1,172 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"sort"
+)
+
+// ContentionProfileKind selects which of the runtime's contention profiles
+// is read by GetContentionProfile.
+type ContentionProfileKind uint8
+
+const (
+	// MutexProfile is the profile of contended mutexes, enabled in the target
+	// by runtime.SetMutexProfileFraction.
+	MutexProfile ContentionProfileKind = iota
+	// BlockProfile is the profile of blocking events, enabled in the target
+	// by runtime.SetBlockProfileRate.
+	BlockProfile
+)
+
+// ContentionRecord is a call site of a contention profile.
+type ContentionRecord struct {
+	Count  int64
+	Cycles int64
+	// Stack contains the program counters of the call site, innermost first.
+	// Each entry is the PC of the call instruction rather than the return
+	// address recorded by the runtime.
+	Stack []uint64
+}
+
+// ContentionProfile is the content of a runtime contention profile.
+type ContentionProfile struct {
+	// Rate is the value of runtime.mutexprofilerate or
+	// runtime.blockprofilerate, 0 means that the profile is disabled.
+	Rate    int64
+	Records []ContentionRecord
+}
+
+// maxContentionBuckets bounds the number of profile buckets read by
+// GetContentionProfile, to avoid looping forever on corrupted memory.
+const maxContentionBuckets = 1 << 20
+
+// GetContentionProfile reads the mutex or block profile collected by the
+// runtime of the target, without requiring the target to export it.
+// Records are sorted by decreasing number of cycles spent waiting.
+func GetContentionProfile(t *Target, kind ContentionProfileKind) (*ContentionProfile, error) {
+	if _, err := t.Valid(); err != nil {
+		return nil, err
+	}
+	bi := t.BinInfo()
+	mem := t.Memory()
+	scope := globalScope(bi, bi.Images[0], mem)
+
+	var rateName, bucketsName string
+	switch kind {
+	case MutexProfile:
+		rateName, bucketsName = "mutexprofilerate", "xbuckets"
+	case BlockProfile:
+		rateName, bucketsName = "blockprofilerate", "bbuckets"
+	default:
+		return nil, fmt.Errorf("unknown contention profile kind %d", kind)
+	}
+
+	r := &ContentionProfile{}
+
+	ratev, err := scope.findGlobal("runtime", rateName)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := readNumber(ratev)
+	if err != nil {
+		return nil, fmt.Errorf("could not read runtime.%s: %v", rateName, err)
+	}
+	r.Rate = rate
+
+	bucketsv, err := scope.findGlobal("runtime", bucketsName)
+	if err != nil {
+		return nil, err
+	}
+	btyp, err := bi.findType("runtime.bucket")
+	if err != nil {
+		return nil, err
+	}
+	rectyp, err := bi.findType("runtime.blockRecord")
+	if err != nil {
+		return nil, err
+	}
+	ptrSize := int64(bi.Arch.PtrSize())
+
+	// Starting with Go 1.19 the list head is an atomic.UnsafePointer, whose
+	// only non-zero sized field is the pointer, at offset 0.
+	baddr, err := readUintRaw(mem, bucketsv.Addr, ptrSize, bi.Arch.ByteOrder())
+	if err != nil {
+		return nil, err
+	}
+	for baddr != 0 {
+		if len(r.Records) >= maxContentionBuckets {
+			return nil, errors.New("too many profile buckets")
+		}
+		fr := runtimeStructReader{v: newVariable("", baddr, btyp, bi, mem)}
+		nstk := fr.int("nstk")
+		next := fr.ptr("allnext")
+		if fr.err != nil {
+			return nil, fmt.Errorf("could not read profile bucket at %#x: %v", baddr, fr.err)
+		}
+		if nstk < 0 || nstk > 1024 {
+			return nil, fmt.Errorf("profile bucket at %#x has an invalid stack size %d", baddr, nstk)
+		}
+
+		stkaddr := baddr + uint64(btyp.Size())
+		rec := ContentionRecord{Stack: make([]uint64, 0, nstk)}
+		for i := int64(0); i < nstk; i++ {
+			pc, err := readUintRaw(mem, stkaddr+uint64(i*ptrSize), ptrSize, bi.Arch.ByteOrder())
+			if err != nil {
+				return nil, err
+			}
+			if pc != 0 {
+				// the runtime records return addresses
+				pc--
+			}
+			rec.Stack = append(rec.Stack, pc)
+		}
+
+		// The blockRecord follows the stack, the type of its count field
+		// changed from int64 to float64 in Go 1.17.
+		recv := newVariable("", stkaddr+uint64(nstk*ptrSize), rectyp, bi, mem)
+		for _, f := range []struct {
+			name string
+			dst  *int64
+		}{{"count", &rec.Count}, {"cycles", &rec.Cycles}} {
+			fv, err := recv.structMember(f.name)
+			if err != nil {
+				return nil, err
+			}
+			n, err := readNumber(fv)
+			if err != nil {
+				return nil, fmt.Errorf("could not read profile bucket at %#x: %v", baddr, err)
+			}
+			*f.dst = n
+		}
+
+		r.Records = append(r.Records, rec)
+		baddr = next
+	}
+
+	sort.SliceStable(r.Records, func(i, j int) bool { return r.Records[i].Cycles > r.Records[j].Cycles })
+	return r, nil
+}
+
+// readNumber loads v and returns its value, v must be either an integer or
+// a floating point number, which is truncated.
+func readNumber(v *Variable) (int64, error) {
+	v.loadValue(loadSingleValue)
+	if v.Unreadable != nil {
+		return 0, v.Unreadable
+	}
+	if v.Value == nil {
+		return 0, fmt.Errorf("%s is not a number", v.Name)
+	}
+	switch v.Value.Kind() {
+	case constant.Int:
+		n, _ := constant.Int64Val(v.Value)
+		return n, nil
+	case constant.Float:
+		n, _ := constant.Float64Val(v.Value)
+		return int64(n), nil
+	default:
+		return 0, fmt.Errorf("%s is not a number", v.Name)
+	}
+}
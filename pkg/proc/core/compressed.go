@@ -0,0 +1,132 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// sparseBlockSize is the size of the blocks checked for zeroes while
+// decompressing a core file.
+const sparseBlockSize = 64 * 1024
+
+// ErrZstdNotFound is returned when opening a zstd compressed core file
+// if the zstd command is not installed.
+var ErrZstdNotFound = errors.New("core file is compressed with zstd but the zstd command was not found")
+
+// decompressCore checks whether the core file at corePath is compressed
+// with gzip or zstd and, if it is, decompresses it into a temporary file.
+// The path of the temporary file is returned, or the empty string if
+// corePath is not compressed.
+func decompressCore(corePath string) (string, error) {
+	fh, err := os.Open(corePath)
+	if err != nil {
+		return "", err
+	}
+	defer fh.Close()
+
+	magic := make([]byte, len(zstdMagic))
+	n, _ := io.ReadFull(fh, magic)
+	magic = magic[:n]
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	var r io.Reader
+	var wait func() error
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gzr, err := gzip.NewReader(fh)
+		if err != nil {
+			return "", fmt.Errorf("could not decompress core file: %v", err)
+		}
+		r = gzr
+	case bytes.HasPrefix(magic, zstdMagic):
+		// There is no zstd decoder in the standard library, use the zstd
+		// command to decompress the file.
+		zstdPath, err := exec.LookPath("zstd")
+		if err != nil {
+			return "", ErrZstdNotFound
+		}
+		cmd := exec.Command(zstdPath, "-d", "-c", "-q")
+		cmd.Stdin = fh
+		stderr := new(bytes.Buffer)
+		cmd.Stderr = stderr
+		r, err = cmd.StdoutPipe()
+		if err != nil {
+			return "", err
+		}
+		if err := cmd.Start(); err != nil {
+			return "", err
+		}
+		wait = func() error {
+			if err := cmd.Wait(); err != nil {
+				return fmt.Errorf("%v: %s", err, stderr.String())
+			}
+			return nil
+		}
+	default:
+		return "", nil
+	}
+
+	out, err := ioutil.TempFile("", "dlv-core-")
+	if err != nil {
+		if wait != nil {
+			wait()
+		}
+		return "", err
+	}
+
+	err = copySparse(out, r)
+	if wait != nil {
+		if werr := wait(); err == nil {
+			err = werr
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(out.Name())
+		return "", fmt.Errorf("could not decompress core file: %v", err)
+	}
+	return out.Name(), nil
+}
+
+// copySparse copies the contents of r to w without writing blocks that
+// only contain zeroes. Most of the memory saved in a core file is never
+// touched by the target process, on file systems that support sparse
+// files the zero blocks of the decompressed core file do not use space.
+func copySparse(w *os.File, r io.Reader) error {
+	buf := make([]byte, sparseBlockSize)
+	zero := make([]byte, sparseBlockSize)
+	var off int64
+	for {
+		n, err := io.ReadFull(r, buf)
+		if n > 0 && !bytes.Equal(buf[:n], zero[:n]) {
+			if _, err := w.WriteAt(buf[:n], off); err != nil {
+				return err
+			}
+		}
+		off += int64(n)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// Extends the file if it ends with zero blocks.
+	return w.Truncate(off)
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"sort"
 
-	"github.com/go-delve/delve/pkg/elfwriter"
 	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/elfwriter"
 	"github.com/go-delve/delve/pkg/proc"
 )
 
@@ -42,6 +44,12 @@ func (r *splicedMemory) Add(reader proc.MemoryReader, off, length uint64) {
 		return
 	}
 	end := off + length - 1
+	if n := len(r.readers); n == 0 || r.readers[n-1].offset+r.readers[n-1].length <= off {
+		// Fast path for regions added in order, core files can have
+		// thousands of them.
+		r.readers = append(r.readers, readerEntry{off, length, reader})
+		return
+	}
 	newReaders := make([]readerEntry, 0, len(r.readers))
 	add := func(e readerEntry) {
 		if e.length == 0 {
@@ -76,7 +84,7 @@ func (r *splicedMemory) Add(reader proc.MemoryReader, off, length uint64) {
 				add(readerEntry{off, length, reader})
 				inserted = true
 			}
-			overlap := entry.offset - off
+			overlap := end + 1 - entry.offset
 			entry.offset += overlap
 			entry.length -= overlap
 			add(entry)
@@ -98,18 +106,15 @@ func (r *splicedMemory) Add(reader proc.MemoryReader, off, length uint64) {
 
 // ReadMemory implements MemoryReader.ReadMemory.
 func (r *splicedMemory) ReadMemory(buf []byte, addr uint64) (n int, err error) {
-	started := false
-	for _, entry := range r.readers {
+	// Skip all the regions that end before addr.
+	start := sort.Search(len(r.readers), func(i int) bool {
+		return r.readers[i].offset+r.readers[i].length > addr
+	})
+	for _, entry := range r.readers[start:] {
 		if entry.offset+entry.length <= addr {
-			if !started {
-				continue
-			}
 			return n, fmt.Errorf("hit unmapped area at %v after %v bytes", addr, n)
 		}
 
-		// The reading of the memory has been started after the first iteration
-		started = true
-
 		// Don't go past the region.
 		pb := buf
 		if addr+uint64(len(buf)) > entry.offset+entry.length {
@@ -151,6 +156,18 @@ func (r *offsetReaderAt) ReadMemory(buf []byte, addr uint64) (n int, err error)
 	return r.reader.ReadAt(buf, int64(addr-r.offset))
 }
 
+// zeroReader is a MemoryReader for regions of memory that only contain
+// zeroes and are not stored in the core file.
+type zeroReader struct{}
+
+// ReadMemory fills buf with zeroes.
+func (zeroReader) ReadMemory(buf []byte, addr uint64) (n int, err error) {
+	for i := range buf {
+		buf[i] = 0
+	}
+	return len(buf), nil
+}
+
 // process represents a core file.
 type process struct {
 	mem     proc.MemoryReader
@@ -161,6 +178,10 @@ type process struct {
 
 	bi          *proc.BinaryInfo
 	breakpoints proc.BreakpointMap
+
+	// tmpCorePath is the path of the temporary file containing the
+	// decompressed core file, if it could not be removed after opening it.
+	tmpCorePath string
 }
 
 var _ proc.ProcessInternal = &process{}
@@ -194,7 +215,7 @@ var (
 
 type openFn func(string, string) (*process, proc.Thread, error)
 
-var openFns = []openFn{readLinuxOrPlatformIndependentCore, readAMD64Minidump}
+var openFns = []openFn{readLinuxOrPlatformIndependentCore, readAMD64Minidump, readDarwinCore}
 
 // ErrUnrecognizedFormat is returned when the core file is not recognized as
 // any of the supported formats.
@@ -204,9 +225,16 @@ var ErrUnrecognizedFormat = errors.New("unrecognized core format")
 // If the DWARF information cannot be found in the binary, Delve will look
 // for external debug files in the directories passed in.
 func OpenCore(corePath, exePath string, debugInfoDirs []string) (*proc.Target, error) {
+	tmpCorePath, err := decompressCore(corePath)
+	if err != nil {
+		return nil, err
+	}
+	if tmpCorePath != "" {
+		corePath = tmpCorePath
+	}
+
 	var p *process
 	var currentThread proc.Thread
-	var err error
 	for _, openFn := range openFns {
 		p, currentThread, err = openFn(corePath, exePath)
 		if err != ErrUnrecognizedFormat {
@@ -214,9 +242,20 @@ func OpenCore(corePath, exePath string, debugInfoDirs []string) (*proc.Target, e
 		}
 	}
 	if err != nil {
+		if tmpCorePath != "" {
+			os.Remove(tmpCorePath)
+		}
 		return nil, err
 	}
 
+	if tmpCorePath != "" {
+		// Files that are still open can not be removed on windows, try
+		// again when detaching.
+		if os.Remove(tmpCorePath) != nil {
+			p.tmpCorePath = tmpCorePath
+		}
+	}
+
 	if currentThread == nil {
 		return nil, ErrNoThreads
 	}
@@ -430,6 +469,9 @@ func (p *process) Memory() proc.MemoryReadWriter {
 // effect as you cannot detach from a core file
 // and have it continue execution or exit.
 func (p *process) Detach(bool) error {
+	if p.tmpCorePath != "" {
+		os.Remove(p.tmpCorePath)
+	}
 	return nil
 }
 
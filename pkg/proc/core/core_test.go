@@ -2,10 +2,14 @@ package core
 
 import (
 	"bytes"
+	"compress/gzip"
+	"debug/elf"
+	"encoding/binary"
 	"flag"
 	"fmt"
 	"go/constant"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"os/exec"
 	"path"
@@ -17,6 +21,7 @@ import (
 
 	"github.com/go-delve/delve/pkg/goversion"
 	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/linutil"
 	"github.com/go-delve/delve/pkg/proc/test"
 )
 
@@ -198,6 +203,90 @@ func TestSplicedReader(t *testing.T) {
 	}
 }
 
+// fillReader is a memory region of length bytes at off, all set to b.
+type fillReader struct {
+	off, length uint64
+	b           byte
+}
+
+func (r *fillReader) ReadMemory(buf []byte, addr uint64) (int, error) {
+	if addr < r.off || addr+uint64(len(buf)) > r.off+r.length {
+		return 0, fmt.Errorf("read of %d bytes at %#x outside of region %d", len(buf), addr, r.b)
+	}
+	for i := range buf {
+		buf[i] = r.b
+	}
+	return len(buf), nil
+}
+
+func TestSplicedMemoryOutOfOrder(t *testing.T) {
+	// Adds regions in random order, overlapping each other or leaving gaps
+	// between them, and checks the result against a byte by byte model of
+	// the address space.
+	const size = 64
+	rnd := rand.New(rand.NewSource(1))
+	for iter := 0; iter < 500; iter++ {
+		mem := &splicedMemory{}
+		var model [size]int // region covering each address, 0 if unmapped
+		var adds []string
+		for i := 1; i <= 1+rnd.Intn(6); i++ {
+			off := uint64(rnd.Intn(size - 1))
+			length := uint64(1 + rnd.Intn(size-int(off)))
+			adds = append(adds, fmt.Sprintf("%d:[%d,%d)", i, off, off+length))
+			mem.Add(&fillReader{off, length, byte(i)}, off, length)
+			for addr := off; addr < off+length; addr++ {
+				model[addr] = i
+			}
+		}
+
+		for i, e := range mem.readers {
+			if i > 0 && mem.readers[i-1].offset+mem.readers[i-1].length > e.offset {
+				t.Fatalf("%v: regions not sorted or overlapping %v", adds, mem.readers)
+			}
+		}
+
+		for addr := uint64(0); addr < size; addr++ {
+			buf := []byte{0}
+			n, err := mem.ReadMemory(buf, addr)
+			switch {
+			case model[addr] == 0 && err == nil:
+				t.Fatalf("%v: read of unmapped address %d succeeded", adds, addr)
+			case model[addr] != 0 && (err != nil || n != 1 || buf[0] != byte(model[addr])):
+				t.Fatalf("%v: read at %d: got %d %v %d, expected %d", adds, addr, n, err, buf[0], model[addr])
+			}
+		}
+
+		// Read from each address to the end of the address space, reads must
+		// stop at the first unmapped address and fail if it is followed by
+		// another region, they are short reads otherwise.
+		for addr := uint64(0); addr < size; addr++ {
+			if model[addr] == 0 {
+				continue
+			}
+			end := addr
+			for end < size && model[end] != 0 {
+				end++
+			}
+			gap := false
+			for next := end; next < size; next++ {
+				if model[next] != 0 {
+					gap = true
+				}
+			}
+			buf := make([]byte, size-addr)
+			n, err := mem.ReadMemory(buf, addr)
+			if n != int(end-addr) || gap != (err != nil) {
+				t.Fatalf("%v: read across the gap at %d from %d: got %d %v", adds, end, addr, n, err)
+			}
+			for i := 0; i < n; i++ {
+				if buf[i] != byte(model[addr+uint64(i)]) {
+					t.Fatalf("%v: read from %d: got %v", adds, addr, buf[:n])
+				}
+			}
+		}
+	}
+}
+
 func withCoreFile(t *testing.T, name, args string) *proc.Target {
 	// This is all very fragile and won't work on hosts with non-default core patterns.
 	// Might be better to check in the binary and core?
@@ -523,3 +612,137 @@ func procdump(t *testing.T, exePath string) string {
 	t.Fatalf("could not find dump file")
 	return ""
 }
+
+func TestLinuxCoreArchNotes(t *testing.T) {
+	// Decodes the thread notes of cores produced on other architectures,
+	// this must not depend on the architecture Delve is running on.
+	const pid, pc, sp = 1234, 0x401000, 0xc000040f00
+
+	writeNote := func(buf *bytes.Buffer, order binary.ByteOrder, typ elf.NType, desc interface{}) {
+		var descbuf bytes.Buffer
+		binary.Write(&descbuf, order, desc)
+		binary.Write(buf, order, elfNotesHdr{Namesz: 5, Descsz: uint32(descbuf.Len()), Type: uint32(typ)})
+		buf.WriteString("CORE\x00\x00\x00\x00")
+		buf.Write(descbuf.Bytes())
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+
+	for _, tc := range []struct {
+		machine  elf.Machine
+		order    binary.ByteOrder
+		prstatus interface{}
+		fpregs   interface{}
+	}{
+		{_EM_AARCH64, binary.LittleEndian,
+			&linuxPrStatusARM64{Pid: pid, Reg: linutil.ARM64PtraceRegs{Pc: pc, Sp: sp}},
+			nil},
+		{_EM_S390, binary.BigEndian,
+			&linuxPrStatusS390X{Pid: pid, Reg: linutil.S390XPtraceRegs{Psw: linutil.S390XPtracePsw{Addr: pc}, Gprs: [16]uint64{15: sp}}},
+			&linutil.S390XPtraceFpRegs{Fprs: [16]uint64{1: 0x3ff0000000000000}}},
+		{_EM_RISCV, binary.LittleEndian,
+			&linuxPrStatusRISCV64{Pid: pid, Reg: linutil.RISCV64PtraceRegs{Pc: pc, Regs: [31]uint64{1: sp}}},
+			&linutil.RISCV64PtraceFpRegs{F: [32]uint64{1: 0x3ff0000000000000}}},
+		{_EM_LOONGARCH, binary.LittleEndian,
+			&linuxPrStatusLOONG64{Pid: pid, Reg: linutil.LOONG64PtraceRegs{Era: pc, Regs: [32]uint64{3: sp}}},
+			&linutil.LOONG64PtraceFpRegs{F: [32]uint64{1: 0x3ff0000000000000}}},
+	} {
+		arch := linuxCoreArchs[tc.machine]
+		t.Run(arch.goarch, func(t *testing.T) {
+			var buf bytes.Buffer
+			writeNote(&buf, tc.order, elf.NT_PRSTATUS, tc.prstatus)
+			if tc.fpregs != nil {
+				writeNote(&buf, tc.order, arch.fpNoteType, tc.fpregs)
+			}
+
+			r := bytes.NewReader(buf.Bytes())
+			var notes []*note
+			for r.Len() > 0 {
+				note, err := readNote(r, tc.order, arch)
+				assertNoError(err, t, "readNote")
+				notes = append(notes, note)
+			}
+
+			p := &process{Threads: map[int]*thread{}, bi: proc.NewBinaryInfo("linux", arch.goarch)}
+			th := linuxThreadsFromNotes(p, notes)
+			if th == nil || th.ThreadID() != pid {
+				t.Fatalf("wrong thread %v", th)
+			}
+			regs, err := th.Registers()
+			assertNoError(err, t, "Registers")
+			if regs.PC() != pc || regs.SP() != sp {
+				t.Errorf("wrong registers PC=%#x SP=%#x", regs.PC(), regs.SP())
+			}
+			if tc.fpregs != nil {
+				regsv, err := regs.Slice(true)
+				assertNoError(err, t, "Slice")
+				found := false
+				for _, reg := range regsv {
+					if reg.Name == "F1" {
+						found = true
+						if reg.Reg.Uint64Val != 0x3ff0000000000000 {
+							t.Errorf("wrong value of F1 %#x", reg.Reg.Uint64Val)
+						}
+					}
+				}
+				if !found {
+					t.Errorf("F1 not found")
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressCore(t *testing.T) {
+	// Mostly zeroes with some data in the middle and an odd size, like the
+	// memory of a process.
+	data := make([]byte, 5*sparseBlockSize+100)
+	for i := 0; i < 1000; i++ {
+		data[2*sparseBlockSize+i] = byte(i)
+	}
+	dir, err := ioutil.TempDir("", "dlv-core-test")
+	assertNoError(err, t, "TempDir")
+	defer os.RemoveAll(dir)
+
+	plainPath := filepath.Join(dir, "core")
+	assertNoError(ioutil.WriteFile(plainPath, data, 0600), t, "WriteFile")
+	tmpPath, err := decompressCore(plainPath)
+	assertNoError(err, t, "decompressCore(uncompressed)")
+	if tmpPath != "" {
+		t.Errorf("uncompressed core file was decompressed to %s", tmpPath)
+	}
+
+	check := func(corePath string) {
+		tmpPath, err := decompressCore(corePath)
+		assertNoError(err, t, "decompressCore")
+		if tmpPath == "" {
+			t.Fatalf("%s was not decompressed", corePath)
+		}
+		defer os.Remove(tmpPath)
+		out, err := ioutil.ReadFile(tmpPath)
+		assertNoError(err, t, "ReadFile")
+		if !bytes.Equal(out, data) {
+			t.Errorf("wrong contents of decompressed file (size %d, expected %d)", len(out), len(data))
+		}
+	}
+
+	var gzbuf bytes.Buffer
+	gzw := gzip.NewWriter(&gzbuf)
+	gzw.Write(data)
+	gzw.Close()
+	gzPath := filepath.Join(dir, "core.gz")
+	assertNoError(ioutil.WriteFile(gzPath, gzbuf.Bytes(), 0600), t, "WriteFile")
+	check(gzPath)
+
+	if _, err := exec.LookPath("zstd"); err != nil {
+		t.Log("zstd not installed, skipping zstd core file")
+		return
+	}
+	zstdPath := filepath.Join(dir, "core.zst")
+	cmd := exec.Command("zstd", "-q", "-o", zstdPath, plainPath)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("zstd: %v %s", err, out)
+	}
+	check(zstdPath)
+}
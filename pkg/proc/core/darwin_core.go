@@ -0,0 +1,101 @@
+package core
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"fmt"
+	"os"
+
+	"github.com/go-delve/delve/pkg/elfwriter"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+const (
+	_MH_CORE = 0x4  // file type of Mach-O core files
+	_LC_NOTE = 0x31 // load command type of notes
+)
+
+// readDarwinCore reads a Mach-O core file generated by Delve. Threads are
+// read from the LC_NOTE load commands written by Delve, the LC_THREAD load
+// commands are ignored.
+func readDarwinCore(corePath, exePath string) (*process, proc.Thread, error) {
+	coreFh, err := os.Open(corePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	coreFile, err := macho.NewFile(coreFh)
+	if err != nil {
+		coreFh.Close()
+		if _, isfmterr := err.(*macho.FormatError); isfmterr {
+			return nil, nil, ErrUnrecognizedFormat
+		}
+		return nil, nil, err
+	}
+
+	if coreFile.Type != _MH_CORE {
+		return nil, nil, fmt.Errorf("%s is not a core file", corePath)
+	}
+
+	var notes []*note
+	for _, load := range coreFile.Loads {
+		raw := load.Raw()
+		if len(raw) < 40 || coreFile.ByteOrder.Uint32(raw) != _LC_NOTE {
+			continue
+		}
+		owner := string(bytes.TrimRight(raw[8:24], "\x00"))
+		off := coreFile.ByteOrder.Uint64(raw[24:])
+		size := coreFile.ByteOrder.Uint64(raw[32:])
+
+		var typ elf.NType
+		switch owner {
+		case elfwriter.DelveHeaderNoteOwner:
+			typ = elfwriter.DelveHeaderNoteType
+		case elfwriter.DelveThreadNoteOwner:
+			typ = elfwriter.DelveThreadNodeType
+		default:
+			continue
+		}
+
+		desc := make([]byte, size)
+		if _, err := coreFh.ReadAt(desc, int64(off)); err != nil {
+			return nil, nil, fmt.Errorf("could not read note %q: %v", owner, err)
+		}
+		notes = append(notes, &note{Type: typ, Name: owner, Desc: desc})
+	}
+
+	if len(notes) == 0 || notes[0].Type != elfwriter.DelveHeaderNoteType {
+		return nil, nil, fmt.Errorf("%s was not generated by Delve, only Mach-O core files created by the dump command are supported", corePath)
+	}
+
+	exe, err := macho.Open(exePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer exe.Close()
+	if exe.Cpu != coreFile.Cpu {
+		return nil, nil, fmt.Errorf("architecture mismatch between core file (%v) and executable file (%v)", coreFile.Cpu, exe.Cpu)
+	}
+
+	memory := &splicedMemory{}
+	for _, load := range coreFile.Loads {
+		if seg, isseg := load.(*macho.Segment); isseg && seg.Filesz > 0 {
+			memory.Add(&offsetReaderAt{reader: seg, offset: seg.Addr}, seg.Addr, seg.Filesz)
+		}
+	}
+
+	goos, goarch, err := platformFromNotes(notes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	p := &process{
+		mem:         memory,
+		Threads:     map[int]*thread{},
+		bi:          proc.NewBinaryInfo(goos, goarch),
+		breakpoints: proc.NewBreakpointMap(),
+	}
+
+	currentThread, err := threadsFromDelveNotes(p, notes)
+	return p, currentThread, err
+}
@@ -39,47 +39,117 @@ const _NT_FPREGSET elf.NType = 0x2
 const (
 	_EM_AARCH64          = 183
 	_EM_X86_64           = 62
+	_EM_S390             = 22
+	_EM_RISCV            = 243
+	_EM_LOONGARCH        = 258
 	_ARM_FP_HEADER_START = 512
 )
 
 const elfErrorBadMagicNumber = "bad magic number"
 
-func linuxThreadsFromNotes(p *process, notes []*note, machineType elf.Machine) proc.Thread {
+// linuxCoreArch describes how the thread notes of a linux core file
+// produced on a given architecture are decoded. None of this depends on
+// the architecture Delve is running on.
+type linuxCoreArch struct {
+	goarch string
+	// newPrStatus returns the descriptor of a NT_PRSTATUS note.
+	newPrStatus func() linuxPrStatus
+	// fpNoteType is the type of the note holding the floating point
+	// registers of a thread, it follows the NT_PRSTATUS note of the thread.
+	fpNoteType elf.NType
+	// readFpregs decodes the descriptor of a fpNoteType note.
+	readFpregs func(desc []byte, order binary.ByteOrder) ([]proc.Register, error)
+}
+
+// linuxPrStatus is a decoded NT_PRSTATUS note.
+type linuxPrStatus interface {
+	pid() int
+	// registers returns the general purpose registers saved in the note
+	// together with the floating point registers fpregs.
+	registers(fpregs []proc.Register) proc.Registers
+}
+
+var linuxCoreArchs = map[elf.Machine]*linuxCoreArch{
+	_EM_X86_64: {
+		goarch:      "amd64",
+		newPrStatus: func() linuxPrStatus { return &linuxPrStatusAMD64{} },
+		fpNoteType:  _NT_X86_XSTATE,
+		readFpregs: func(desc []byte, order binary.ByteOrder) ([]proc.Register, error) {
+			var fpregs amd64util.AMD64Xstate
+			if err := amd64util.AMD64XstateRead(desc, true, &fpregs); err != nil {
+				return nil, err
+			}
+			return fpregs.Decode(), nil
+		},
+	},
+	_EM_AARCH64: {
+		goarch:      "arm64",
+		newPrStatus: func() linuxPrStatus { return &linuxPrStatusARM64{} },
+		fpNoteType:  _NT_FPREGSET,
+		readFpregs: func(desc []byte, order binary.ByteOrder) ([]proc.Register, error) {
+			fpregs := &linutil.ARM64PtraceFpRegs{}
+			rdr := bytes.NewReader(desc[:_ARM_FP_HEADER_START])
+			if err := binary.Read(rdr, order, fpregs.Byte()); err != nil {
+				return nil, err
+			}
+			return fpregs.Decode(), nil
+		},
+	},
+	_EM_S390: {
+		goarch:      "s390x",
+		newPrStatus: func() linuxPrStatus { return &linuxPrStatusS390X{} },
+		fpNoteType:  _NT_FPREGSET,
+		readFpregs: func(desc []byte, order binary.ByteOrder) ([]proc.Register, error) {
+			fpregs := &linutil.S390XPtraceFpRegs{}
+			if err := binary.Read(bytes.NewReader(desc), order, fpregs); err != nil {
+				return nil, err
+			}
+			return fpregs.Decode(), nil
+		},
+	},
+	_EM_RISCV: {
+		goarch:      "riscv64",
+		newPrStatus: func() linuxPrStatus { return &linuxPrStatusRISCV64{} },
+		fpNoteType:  _NT_FPREGSET,
+		readFpregs: func(desc []byte, order binary.ByteOrder) ([]proc.Register, error) {
+			fpregs := &linutil.RISCV64PtraceFpRegs{}
+			if err := binary.Read(bytes.NewReader(desc), order, fpregs); err != nil {
+				return nil, err
+			}
+			return fpregs.Decode(), nil
+		},
+	},
+	_EM_LOONGARCH: {
+		goarch:      "loong64",
+		newPrStatus: func() linuxPrStatus { return &linuxPrStatusLOONG64{} },
+		fpNoteType:  _NT_FPREGSET,
+		readFpregs: func(desc []byte, order binary.ByteOrder) ([]proc.Register, error) {
+			fpregs := &linutil.LOONG64PtraceFpRegs{}
+			if err := binary.Read(bytes.NewReader(desc), order, fpregs); err != nil {
+				return nil, err
+			}
+			return fpregs.Decode(), nil
+		},
+	},
+}
+
+func linuxThreadsFromNotes(p *process, notes []*note) proc.Thread {
 	var currentThread proc.Thread
-	var lastThreadAMD *linuxAMD64Thread
-	var lastThreadARM *linuxARM64Thread
+	var lastThread *linuxThread
 	for _, note := range notes {
-		switch note.Type {
-		case elf.NT_PRSTATUS:
-			if machineType == _EM_X86_64 {
-				t := note.Desc.(*linuxPrStatusAMD64)
-				lastThreadAMD = &linuxAMD64Thread{linutil.AMD64Registers{Regs: &t.Reg}, t}
-				p.Threads[int(t.Pid)] = &thread{lastThreadAMD, p, proc.CommonThread{}}
-				if currentThread == nil {
-					currentThread = p.Threads[int(t.Pid)]
-				}
-			} else if machineType == _EM_AARCH64 {
-				t := note.Desc.(*linuxPrStatusARM64)
-				lastThreadARM = &linuxARM64Thread{linutil.ARM64Registers{Regs: &t.Reg}, t}
-				p.Threads[int(t.Pid)] = &thread{lastThreadARM, p, proc.CommonThread{}}
-				if currentThread == nil {
-					currentThread = p.Threads[int(t.Pid)]
-				}
-			}
-		case _NT_FPREGSET:
-			if machineType == _EM_AARCH64 {
-				if lastThreadARM != nil {
-					lastThreadARM.regs.Fpregs = note.Desc.(*linutil.ARM64PtraceFpRegs).Decode()
-				}
+		switch desc := note.Desc.(type) {
+		case linuxPrStatus:
+			lastThread = &linuxThread{t: desc}
+			p.Threads[desc.pid()] = &thread{lastThread, p, proc.CommonThread{}}
+			if currentThread == nil {
+				currentThread = p.Threads[desc.pid()]
 			}
-		case _NT_X86_XSTATE:
-			if machineType == _EM_X86_64 {
-				if lastThreadAMD != nil {
-					lastThreadAMD.regs.Fpregs = note.Desc.(*amd64util.AMD64Xstate).Decode()
-				}
+		case linuxFpregs:
+			if lastThread != nil {
+				lastThread.fpregs = desc
 			}
-		case elf.NT_PRPSINFO:
-			p.pid = int(note.Desc.(*linuxPrPsInfo).Pid)
+		case *linuxPrPsInfo:
+			p.pid = int(desc.Pid)
 		}
 	}
 	return currentThread
@@ -107,7 +177,7 @@ func readLinuxOrPlatformIndependentCore(corePath, exePath string) (*process, pro
 	}
 
 	machineType := coreFile.Machine
-	notes, platformIndependentDelveCore, err := readNotes(coreFile, machineType)
+	notes, platformIndependentDelveCore, err := readNotes(coreFile, linuxCoreArchs[machineType])
 	if err != nil {
 		return nil, nil, err
 	}
@@ -141,17 +211,14 @@ func readLinuxOrPlatformIndependentCore(corePath, exePath string) (*process, pro
 		}
 		bi = proc.NewBinaryInfo(goos, goarch)
 	} else {
-		switch machineType {
-		case _EM_X86_64:
-			bi = proc.NewBinaryInfo("linux", "amd64")
-		case _EM_AARCH64:
-			bi = proc.NewBinaryInfo("linux", "arm64")
-		default:
-			return nil, nil, fmt.Errorf("unsupported machine type")
+		arch := linuxCoreArchs[machineType]
+		if arch == nil {
+			return nil, nil, fmt.Errorf("unsupported machine type %v", machineType)
 		}
+		bi = proc.NewBinaryInfo("linux", arch.goarch)
 	}
 
-	entryPoint := findEntryPoint(notes, bi.Arch.PtrSize())
+	entryPoint := findEntryPoint(notes, bi.Arch.PtrSize(), bi.Arch.ByteOrder())
 
 	p := &process{
 		mem:         memory,
@@ -166,40 +233,25 @@ func readLinuxOrPlatformIndependentCore(corePath, exePath string) (*process, pro
 		return p, currentThread, err
 	}
 
-	currentThread := linuxThreadsFromNotes(p, notes, machineType)
+	currentThread := linuxThreadsFromNotes(p, notes)
 	return p, currentThread, nil
 }
 
-type linuxAMD64Thread struct {
-	regs linutil.AMD64Registers
-	t    *linuxPrStatusAMD64
+// linuxThread is a thread of a linux core file.
+type linuxThread struct {
+	t      linuxPrStatus
+	fpregs []proc.Register
 }
 
-type linuxARM64Thread struct {
-	regs linutil.ARM64Registers
-	t    *linuxPrStatusARM64
-}
+// linuxFpregs are the decoded floating point registers of a thread.
+type linuxFpregs []proc.Register
 
-func (t *linuxAMD64Thread) registers() (proc.Registers, error) {
-	var r linutil.AMD64Registers
-	r.Regs = t.regs.Regs
-	r.Fpregs = t.regs.Fpregs
-	return &r, nil
+func (t *linuxThread) registers() (proc.Registers, error) {
+	return t.t.registers(t.fpregs), nil
 }
 
-func (t *linuxARM64Thread) registers() (proc.Registers, error) {
-	var r linutil.ARM64Registers
-	r.Regs = t.regs.Regs
-	r.Fpregs = t.regs.Fpregs
-	return &r, nil
-}
-
-func (t *linuxAMD64Thread) pid() int {
-	return int(t.t.Pid)
-}
-
-func (t *linuxARM64Thread) pid() int {
-	return int(t.t.Pid)
+func (t *linuxThread) pid() int {
+	return t.t.pid()
 }
 
 // Note is a note from the PT_NOTE prog.
@@ -216,7 +268,9 @@ type note struct {
 }
 
 // readNotes reads all the notes from the notes prog in core.
-func readNotes(core *elf.File, machineType elf.Machine) ([]*note, bool, error) {
+// Arch describes the architecture the core file was produced on, it is
+// nil if the architecture is not supported.
+func readNotes(core *elf.File, arch *linuxCoreArch) ([]*note, bool, error) {
 	var notesProg *elf.Prog
 	for _, prog := range core.Progs {
 		if prog.Type == elf.PT_NOTE {
@@ -231,7 +285,7 @@ func readNotes(core *elf.File, machineType elf.Machine) ([]*note, bool, error) {
 	hasElfPrStatus := false
 	notes := []*note{}
 	for {
-		note, err := readNote(r, machineType)
+		note, err := readNote(r, core.ByteOrder, arch)
 		if err == io.EOF {
 			break
 		}
@@ -253,13 +307,14 @@ func readNotes(core *elf.File, machineType elf.Machine) ([]*note, bool, error) {
 }
 
 // readNote reads a single note from r, decoding the descriptor if possible.
-func readNote(r io.ReadSeeker, machineType elf.Machine) (*note, error) {
+// Order is the byte order of the core file.
+func readNote(r io.ReadSeeker, order binary.ByteOrder, arch *linuxCoreArch) (*note, error) {
 	// Notes are laid out as described in the SysV ABI:
 	// http://www.sco.com/developers/gabi/latest/ch5.pheader.html#note_section
 	note := &note{}
 	hdr := &elfNotesHdr{}
 
-	err := binary.Read(r, binary.LittleEndian, hdr)
+	err := binary.Read(r, order, hdr)
 	if err != nil {
 		return nil, err // don't wrap so readNotes sees EOF.
 	}
@@ -280,19 +335,16 @@ func readNote(r io.ReadSeeker, machineType elf.Machine) (*note, error) {
 	descReader := bytes.NewReader(desc)
 	switch note.Type {
 	case elf.NT_PRSTATUS:
-		if machineType == _EM_X86_64 {
-			note.Desc = &linuxPrStatusAMD64{}
-		} else if machineType == _EM_AARCH64 {
-			note.Desc = &linuxPrStatusARM64{}
-		} else {
+		if arch == nil {
 			return nil, fmt.Errorf("unsupported machine type")
 		}
-		if err := binary.Read(descReader, binary.LittleEndian, note.Desc); err != nil {
+		note.Desc = arch.newPrStatus()
+		if err := binary.Read(descReader, order, note.Desc); err != nil {
 			return nil, fmt.Errorf("reading NT_PRSTATUS: %v", err)
 		}
 	case elf.NT_PRPSINFO:
 		note.Desc = &linuxPrPsInfo{}
-		if err := binary.Read(descReader, binary.LittleEndian, note.Desc); err != nil {
+		if err := binary.Read(descReader, order, note.Desc); err != nil {
 			return nil, fmt.Errorf("reading NT_PRPSINFO: %v", err)
 		}
 	case _NT_FILE:
@@ -301,36 +353,26 @@ func readNote(r io.ReadSeeker, machineType elf.Machine) (*note, error) {
 		// many entries, and then the file name of each entry,
 		// null-delimited. Not reading the names here.
 		data := &linuxNTFile{}
-		if err := binary.Read(descReader, binary.LittleEndian, &data.linuxNTFileHdr); err != nil {
+		if err := binary.Read(descReader, order, &data.linuxNTFileHdr); err != nil {
 			return nil, fmt.Errorf("reading NT_FILE header: %v", err)
 		}
 		for i := 0; i < int(data.Count); i++ {
 			entry := &linuxNTFileEntry{}
-			if err := binary.Read(descReader, binary.LittleEndian, entry); err != nil {
+			if err := binary.Read(descReader, order, entry); err != nil {
 				return nil, fmt.Errorf("reading NT_FILE entry %v: %v", i, err)
 			}
 			data.entries = append(data.entries, entry)
 		}
 		note.Desc = data
-	case _NT_X86_XSTATE:
-		if machineType == _EM_X86_64 {
-			var fpregs amd64util.AMD64Xstate
-			if err := amd64util.AMD64XstateRead(desc, true, &fpregs); err != nil {
-				return nil, err
-			}
-			note.Desc = &fpregs
-		}
 	case _NT_AUXV, elfwriter.DelveHeaderNoteType, elfwriter.DelveThreadNodeType:
 		note.Desc = desc
-	case _NT_FPREGSET:
-		if machineType == _EM_AARCH64 {
-			fpregs := &linutil.ARM64PtraceFpRegs{}
-			rdr := bytes.NewReader(desc[:_ARM_FP_HEADER_START])
-			if err := binary.Read(rdr, binary.LittleEndian, fpregs.Byte()); err != nil {
-				return nil, err
-			}
-			note.Desc = fpregs
+	}
+	if arch != nil && note.Type == arch.fpNoteType {
+		fpregs, err := arch.readFpregs(desc, order)
+		if err != nil {
+			return nil, err
 		}
+		note.Desc = linuxFpregs(fpregs)
 	}
 	if err := skipPadding(r, 4); err != nil {
 		return nil, fmt.Errorf("aligning after desc: %v", err)
@@ -387,16 +429,21 @@ func buildMemory(core, exeELF *elf.File, exe io.ReaderAt, notes []*note) proc.Me
 					offset: prog.Vaddr,
 				}
 				memory.Add(r, prog.Vaddr, prog.Filesz)
+				if elfFile == core && prog.Memsz > prog.Filesz {
+					// Sparse segment, the part that is not stored in the
+					// file only contains zeroes.
+					memory.Add(zeroReader{}, prog.Vaddr+prog.Filesz, prog.Memsz-prog.Filesz)
+				}
 			}
 		}
 	}
 	return memory
 }
 
-func findEntryPoint(notes []*note, ptrSize int) uint64 {
+func findEntryPoint(notes []*note, ptrSize int, order binary.ByteOrder) uint64 {
 	for _, note := range notes {
 		if note.Type == _NT_AUXV {
-			return linutil.EntryPointFromAuxv(note.Desc.([]byte), ptrSize)
+			return linutil.EntryPointFromAuxv(note.Desc.([]byte), ptrSize, order)
 		}
 	}
 	return 0
@@ -432,6 +479,12 @@ type linuxPrStatusAMD64 struct {
 	Fpvalid                      int32
 }
 
+func (t *linuxPrStatusAMD64) pid() int { return int(t.Pid) }
+
+func (t *linuxPrStatusAMD64) registers(fpregs []proc.Register) proc.Registers {
+	return &linutil.AMD64Registers{Regs: &t.Reg, Fpregs: fpregs}
+}
+
 // LinuxPrStatusARM64 is a copy of the prstatus kernel struct.
 type linuxPrStatusARM64 struct {
 	Siginfo                      linuxSiginfo
@@ -445,6 +498,69 @@ type linuxPrStatusARM64 struct {
 	Fpvalid                      int32
 }
 
+func (t *linuxPrStatusARM64) pid() int { return int(t.Pid) }
+
+func (t *linuxPrStatusARM64) registers(fpregs []proc.Register) proc.Registers {
+	return &linutil.ARM64Registers{Regs: &t.Reg, Fpregs: fpregs}
+}
+
+// LinuxPrStatusS390X is a copy of the prstatus kernel struct.
+type linuxPrStatusS390X struct {
+	Siginfo                      linuxSiginfo
+	Cursig                       uint16
+	_                            [2]uint8
+	Sigpend                      uint64
+	Sighold                      uint64
+	Pid, Ppid, Pgrp, Sid         int32
+	Utime, Stime, CUtime, CStime linuxCoreTimeval
+	Reg                          linutil.S390XPtraceRegs
+	Fpvalid                      int32
+}
+
+func (t *linuxPrStatusS390X) pid() int { return int(t.Pid) }
+
+func (t *linuxPrStatusS390X) registers(fpregs []proc.Register) proc.Registers {
+	return &linutil.S390XRegisters{Regs: &t.Reg, Fpregs: fpregs}
+}
+
+// LinuxPrStatusRISCV64 is a copy of the prstatus kernel struct.
+type linuxPrStatusRISCV64 struct {
+	Siginfo                      linuxSiginfo
+	Cursig                       uint16
+	_                            [2]uint8
+	Sigpend                      uint64
+	Sighold                      uint64
+	Pid, Ppid, Pgrp, Sid         int32
+	Utime, Stime, CUtime, CStime linuxCoreTimeval
+	Reg                          linutil.RISCV64PtraceRegs
+	Fpvalid                      int32
+}
+
+func (t *linuxPrStatusRISCV64) pid() int { return int(t.Pid) }
+
+func (t *linuxPrStatusRISCV64) registers(fpregs []proc.Register) proc.Registers {
+	return &linutil.RISCV64Registers{Regs: &t.Reg, Fpregs: fpregs}
+}
+
+// LinuxPrStatusLOONG64 is a copy of the prstatus kernel struct.
+type linuxPrStatusLOONG64 struct {
+	Siginfo                      linuxSiginfo
+	Cursig                       uint16
+	_                            [2]uint8
+	Sigpend                      uint64
+	Sighold                      uint64
+	Pid, Ppid, Pgrp, Sid         int32
+	Utime, Stime, CUtime, CStime linuxCoreTimeval
+	Reg                          linutil.LOONG64PtraceRegs
+	Fpvalid                      int32
+}
+
+func (t *linuxPrStatusLOONG64) pid() int { return int(t.Pid) }
+
+func (t *linuxPrStatusLOONG64) registers(fpregs []proc.Register) proc.Registers {
+	return &linutil.LOONG64Registers{Regs: &t.Reg, Fpregs: fpregs}
+}
+
 // LinuxSiginfo is a copy of the
 // siginfo kernel struct.
 type linuxSiginfo struct {
@@ -18,65 +18,92 @@ package minidump
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"os"
 	"unicode/utf16"
 	"unsafe"
 
 	"github.com/go-delve/delve/pkg/proc/winutil"
 )
 
+// minidumpFile is an open minidump file. Full memory minidumps can be
+// several gigabytes large, only the structures describing the contents of
+// the file are read into memory, the memory of the target process is read
+// from the file on demand.
+type minidumpFile struct {
+	r    io.ReaderAt
+	size int
+}
+
+var errOutOfRange = errors.New("past the end of file")
+
+// readAt reads sz bytes at offset off of the file.
+func (file *minidumpFile) readAt(off, sz int) ([]byte, error) {
+	if off < 0 || sz < 0 || off > file.size || sz > file.size-off {
+		return nil, errOutOfRange
+	}
+	out := make([]byte, sz)
+	if _, err := file.r.ReadAt(out, int64(off)); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// minidumpBuf reads little endian values from a region of a minidump file.
 type minidumpBuf struct {
-	buf  []byte
+	file *minidumpFile
+	buf  []byte // contents of the file starting at offset base
+	base int
 	kind string
 	off  int
 	err  error
 	ctx  string
 }
 
-func (buf *minidumpBuf) u16() uint16 {
-	const stride = 2
+func (buf *minidumpBuf) read(stride int) []byte {
 	if buf.err != nil {
-		return 0
+		return nil
 	}
-	if buf.off+stride >= len(buf.buf) {
+	if buf.off < buf.base || buf.off+stride > buf.base+len(buf.buf) {
 		buf.err = fmt.Errorf("minidump %s truncated at offset %#x while %s", buf.kind, buf.off, buf.ctx)
+		return nil
 	}
-	r := binary.LittleEndian.Uint16(buf.buf[buf.off : buf.off+stride])
+	r := buf.buf[buf.off-buf.base : buf.off-buf.base+stride]
 	buf.off += stride
 	return r
 }
 
-func (buf *minidumpBuf) u32() uint32 {
-	const stride = 4
-	if buf.err != nil {
+func (buf *minidumpBuf) u16() uint16 {
+	r := buf.read(2)
+	if r == nil {
 		return 0
 	}
-	if buf.off+stride >= len(buf.buf) {
-		buf.err = fmt.Errorf("minidump %s truncated at offset %#x while %s", buf.kind, buf.off, buf.ctx)
+	return binary.LittleEndian.Uint16(r)
+}
+
+func (buf *minidumpBuf) u32() uint32 {
+	r := buf.read(4)
+	if r == nil {
+		return 0
 	}
-	r := binary.LittleEndian.Uint32(buf.buf[buf.off : buf.off+stride])
-	buf.off += stride
-	return r
+	return binary.LittleEndian.Uint32(r)
 }
 
 func (buf *minidumpBuf) u64() uint64 {
-	const stride = 8
-	if buf.err != nil {
+	r := buf.read(8)
+	if r == nil {
 		return 0
 	}
-	if buf.off+stride >= len(buf.buf) {
-		buf.err = fmt.Errorf("minidump %s truncated at offset %#x while %s", buf.kind, buf.off, buf.ctx)
-	}
-	r := binary.LittleEndian.Uint64(buf.buf[buf.off : buf.off+stride])
-	buf.off += stride
-	return r
+	return binary.LittleEndian.Uint64(r)
 }
 
 func streamBuf(stream *Stream, buf *minidumpBuf, name string) *minidumpBuf {
 	return &minidumpBuf{
-		buf:  buf.buf,
+		file: buf.file,
+		buf:  stream.RawData,
+		base: stream.Offset,
 		kind: "stream",
 		off:  stream.Offset,
 		err:  nil,
@@ -96,8 +123,10 @@ func (err ErrNotAMinidump) Error() string {
 }
 
 const (
-	minidumpSignature = 0x504d444d // 'MDMP'
-	minidumpVersion   = 0xa793
+	minidumpSignature     = 0x504d444d // 'MDMP'
+	minidumpVersion       = 0xa793
+	minidumpHeaderSize    = 32
+	minidumpDirectorySize = 12
 )
 
 // Minidump represents a minidump file
@@ -110,6 +139,10 @@ type Minidump struct {
 	Threads []Thread
 	Modules []Module
 
+	// Exception is the exception that caused the minidump to be written,
+	// it is nil if the minidump was not written because of an exception.
+	Exception *Exception
+
 	Pid uint32
 
 	MemoryRanges []MemoryRange
@@ -117,6 +150,8 @@ type Minidump struct {
 
 	streamNum uint32
 	streamOff uint32
+
+	fh *os.File
 }
 
 // Stream represents one (uninterpreted) stream in a minidump file.
@@ -138,6 +173,16 @@ type Thread struct {
 	Context       winutil.CONTEXT
 }
 
+// Exception represents the contents of the Exception stream.
+// See: https://docs.microsoft.com/en-us/windows/win32/api/minidumpapiset/ns-minidumpapiset-minidump_exception_stream
+type Exception struct {
+	ThreadID uint32
+	Code     uint32
+	Flags    uint32
+	Address  uint64
+	Context  winutil.CONTEXT // context of the thread when the exception happened
+}
+
 // Module represents an entry in the ModuleList stream.
 // See: https://docs.microsoft.com/en-us/windows/win32/api/minidumpapiset/ns-minidumpapiset-minidump_module
 type Module struct {
@@ -174,11 +219,13 @@ type VSFixedFileInfo struct {
 }
 
 // MemoryRange represents a region of memory saved to the core file, it's constructed after either:
-// 1. parsing an entry in the Memory64List stream.
+// 1. parsing an entry in the Memory64List or MemoryList streams.
 // 2. parsing the stack field of an entry in the ThreadList stream.
 type MemoryRange struct {
 	Addr uint64
-	Data []byte
+	Size uint64
+
+	data io.ReaderAt // section of the minidump file containing this region
 }
 
 // ReadMemory reads len(buf) bytes of memory starting at addr into buf from this memory region.
@@ -186,11 +233,10 @@ func (m *MemoryRange) ReadMemory(buf []byte, addr uint64) (int, error) {
 	if len(buf) == 0 {
 		return 0, nil
 	}
-	if (uint64(addr) < m.Addr) || (uint64(addr)+uint64(len(buf)) > m.Addr+uint64(len(m.Data))) {
+	if (uint64(addr) < m.Addr) || (uint64(addr)+uint64(len(buf)) > m.Addr+m.Size) {
 		return 0, io.EOF
 	}
-	copy(buf, m.Data[uint64(addr)-m.Addr:])
-	return len(buf), nil
+	return m.data.ReadAt(buf, int64(addr-m.Addr))
 }
 
 // MemoryInfo reprents an entry in the MemoryInfoList stream.
@@ -317,15 +363,37 @@ const (
 )
 
 // Open reads the minidump file at path and returns it as a Minidump structure.
+// The file is kept open to read the memory of the target process.
 func Open(path string, logfn func(fmt string, args ...interface{})) (*Minidump, error) {
-	rawbuf, err := ioutil.ReadFile(path) //TODO(aarzilli): mmap?
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	mdmp, err := open(fh, logfn)
+	if err != nil {
+		fh.Close()
+		return nil, err
+	}
+	return mdmp, nil
+}
+
+func open(fh *os.File, logfn func(fmt string, args ...interface{})) (*Minidump, error) {
+	fi, err := fh.Stat()
+	if err != nil {
+		return nil, err
+	}
+	file := &minidumpFile{r: fh, size: int(fi.Size())}
+	if file.size < minidumpHeaderSize {
+		return nil, ErrNotAMinidump{"size", uint32(file.size)}
+	}
+	hdr, err := file.readAt(0, minidumpHeaderSize)
 	if err != nil {
 		return nil, err
 	}
 
-	buf := &minidumpBuf{buf: rawbuf, kind: "file"}
+	buf := &minidumpBuf{file: file, buf: hdr, kind: "file"}
 
-	var mdmp Minidump
+	mdmp := Minidump{fh: fh}
 
 	readMinidumpHeader(&mdmp, buf)
 	if buf.err != nil {
@@ -352,11 +420,10 @@ func Open(path string, logfn func(fmt string, args ...interface{})) (*Minidump,
 		}
 
 		sb := streamBuf(stream, buf, "system info")
-		if buf.err != nil {
-			return nil, buf.err
-		}
-
 		arch := Arch(sb.u16())
+		if sb.err != nil {
+			return nil, sb.err
+		}
 
 		if logfn != nil {
 			logfn("Found processor architecture %s\n", arch.String())
@@ -372,31 +439,42 @@ func Open(path string, logfn func(fmt string, args ...interface{})) (*Minidump,
 		if logfn != nil {
 			logfn("Stream %d: type:%s off:%#x size:%#x\n", i, stream.Type, stream.Offset, len(stream.RawData))
 		}
+		var sb *minidumpBuf
 		switch stream.Type {
 		case ThreadListStream:
-			readThreadList(&mdmp, streamBuf(stream, buf, "thread list"))
+			sb = streamBuf(stream, buf, "thread list")
+			readThreadList(&mdmp, sb)
 			if logfn != nil {
 				for i := range mdmp.Threads {
 					logfn("\tID:%#x TEB:%#x\n", mdmp.Threads[i].ID, mdmp.Threads[i].TEB)
 				}
 			}
 		case ModuleListStream:
-			readModuleList(&mdmp, streamBuf(stream, buf, "module list"))
+			sb = streamBuf(stream, buf, "module list")
+			readModuleList(&mdmp, sb)
 			if logfn != nil {
 				for i := range mdmp.Modules {
 					logfn("\tName:%q BaseOfImage:%#x SizeOfImage:%#x\n", mdmp.Modules[i].Name, mdmp.Modules[i].BaseOfImage, mdmp.Modules[i].SizeOfImage)
 				}
 			}
 		case ExceptionStream:
-			//TODO(aarzilli): this stream contains the exception that made the
-			//process stop and caused the minidump to be taken. If we ever start
-			//caring about this we should parse this.
+			sb = streamBuf(stream, buf, "exception")
+			readException(&mdmp, sb)
+			if logfn != nil && mdmp.Exception != nil {
+				logfn("\tThread:%#x Code:%#x Address:%#x\n", mdmp.Exception.ThreadID, mdmp.Exception.Code, mdmp.Exception.Address)
+			}
+		case MemoryListStream:
+			sb = streamBuf(stream, buf, "memory list")
+			readMemoryList(&mdmp, sb, logfn)
 		case Memory64ListStream:
-			readMemory64List(&mdmp, streamBuf(stream, buf, "memory64 list"), logfn)
+			sb = streamBuf(stream, buf, "memory64 list")
+			readMemory64List(&mdmp, sb, logfn)
 		case MemoryInfoListStream:
-			readMemoryInfoList(&mdmp, streamBuf(stream, buf, "memory info list"), logfn)
+			sb = streamBuf(stream, buf, "memory info list")
+			readMemoryInfoList(&mdmp, sb, logfn)
 		case MiscInfoStream:
-			readMiscInfo(&mdmp, streamBuf(stream, buf, "misc info"))
+			sb = streamBuf(stream, buf, "misc info")
+			readMiscInfo(&mdmp, sb)
 			if logfn != nil {
 				logfn("\tPid: %#x\n", mdmp.Pid)
 			}
@@ -409,14 +487,20 @@ func Open(path string, logfn func(fmt string, args ...interface{})) (*Minidump,
 				logfn("\t%s\n", string(stream.RawData))
 			}
 		}
-		if buf.err != nil {
-			return nil, buf.err
+		if sb != nil && sb.err != nil {
+			return nil, sb.err
 		}
 	}
 
 	return &mdmp, nil
 }
 
+// Close closes the minidump file, the contents of MemoryRanges can no
+// longer be read after Close is called.
+func (mdmp *Minidump) Close() error {
+	return mdmp.fh.Close()
+}
+
 // decodeUTF16 converts a NUL-terminated UTF16LE string to (non NUL-terminated) UTF8.
 func decodeUTF16(in []byte) string {
 	utf16encoded := []uint16{}
@@ -475,7 +559,14 @@ func readMinidumpHeader(mdmp *Minidump, buf *minidumpBuf) {
 
 // readDirectory reads the list of streams (i.e. the minidum "directory")
 func readDirectory(mdmp *Minidump, buf *minidumpBuf) {
+	buf.ctx = "reading stream directory"
 	buf.off = int(mdmp.streamOff)
+	buf.base = buf.off
+	buf.buf, buf.err = buf.file.readAt(buf.off, int(mdmp.streamNum)*minidumpDirectorySize)
+	if buf.err != nil {
+		buf.err = fmt.Errorf("stream directory at %#x: %v", buf.off, buf.err)
+		return
+	}
 
 	mdmp.Streams = make([]Stream, mdmp.streamNum)
 	for i := range mdmp.Streams {
@@ -498,27 +589,31 @@ func readLocationDescriptor(buf *minidumpBuf) (off int, rawData []byte) {
 	if buf.err != nil {
 		return off, nil
 	}
-	end := off + int(sz)
-	if off >= len(buf.buf) || end > len(buf.buf) {
-		buf.err = fmt.Errorf("location starting at %#x of size %#x is past the end of file, while %s", off, sz, buf.ctx)
+	rawData, err := buf.file.readAt(off, int(sz))
+	if err != nil {
+		buf.err = fmt.Errorf("location starting at %#x of size %#x: %v, while %s", off, sz, err, buf.ctx)
 		return 0, nil
 	}
-	rawData = buf.buf[off:end]
 	return
 }
 
-func readString(buf *minidumpBuf) string {
-	startOff := buf.off
-	sz := buf.u32()
+// readString reads the MINIDUMP_STRING at offset off of the file.
+func readString(buf *minidumpBuf, off int) string {
 	if buf.err != nil {
 		return ""
 	}
-	end := buf.off + int(sz)
-	if buf.off >= len(buf.buf) || end > len(buf.buf) {
-		buf.err = fmt.Errorf("string starting at %#x of size %#x is past the end of file, while %s", startOff, sz, buf.ctx)
+	szbuf, err := buf.file.readAt(off, 4)
+	if err != nil {
+		buf.err = fmt.Errorf("string starting at %#x: %v, while %s", off, err, buf.ctx)
 		return ""
 	}
-	return decodeUTF16(buf.buf[buf.off:end])
+	sz := binary.LittleEndian.Uint32(szbuf)
+	rawData, err := buf.file.readAt(off+4, int(sz))
+	if err != nil {
+		buf.err = fmt.Errorf("string starting at %#x of size %#x: %v, while %s", off, sz, err, buf.ctx)
+		return ""
+	}
+	return decodeUTF16(rawData)
 }
 
 // readThreadList reads a thread list stream and adds the threads to the minidump.
@@ -545,11 +640,40 @@ func readThreadList(mdmp *Minidump, buf *minidumpBuf) {
 
 		readMemoryDescriptor(mdmp, buf)                    // thread stack
 		_, rawThreadContext := readLocationDescriptor(buf) // thread context
-		thread.Context = *((*winutil.CONTEXT)(unsafe.Pointer(&rawThreadContext[0])))
 		if buf.err != nil {
 			return
 		}
+		readContext(&thread.Context, rawThreadContext)
+	}
+}
+
+// readContext copies the CONTEXT structure saved in raw to ctx. The saved
+// structure is shorter than CONTEXT if the minidump only contains some of
+// the registers.
+func readContext(ctx *winutil.CONTEXT, raw []byte) {
+	copy((*[unsafe.Sizeof(winutil.CONTEXT{})]byte)(unsafe.Pointer(ctx))[:], raw)
+}
+
+// readException reads an exception stream.
+func readException(mdmp *Minidump, buf *minidumpBuf) {
+	exc := &Exception{}
+	exc.ThreadID = buf.u32()
+	buf.u32() // alignment
+	exc.Code = buf.u32()
+	exc.Flags = buf.u32()
+	buf.u64() // ExceptionRecord, address of a chained exception
+	exc.Address = buf.u64()
+	buf.u32() // NumberParameters
+	buf.u32() // alignment
+	for i := 0; i < 15; i++ {
+		buf.u64() // ExceptionInformation
+	}
+	_, rawThreadContext := readLocationDescriptor(buf)
+	if buf.err != nil {
+		return
 	}
+	readContext(&exc.Context, rawThreadContext)
+	mdmp.Exception = exc
 }
 
 // readModuleList reads a module list stream and adds the modules to the minidump.
@@ -585,15 +709,30 @@ func readModuleList(mdmp *Minidump, buf *minidumpBuf) {
 			return
 		}
 
-		nameBuf := minidumpBuf{buf: buf.buf, kind: "file", off: nameOff, err: nil, ctx: buf.ctx}
-		module.Name = readString(&nameBuf)
-		if nameBuf.err != nil {
-			buf.err = nameBuf.err
+		module.Name = readString(buf, nameOff)
+		if buf.err != nil {
 			return
 		}
 	}
 }
 
+// readMemoryList reads a _MINIDUMP_MEMORY_LIST structure, containing the
+// description of the process memory in minidumps without full memory.
+// See: https://docs.microsoft.com/en-us/windows/win32/api/minidumpapiset/ns-minidumpapiset-minidump_memory_list
+func readMemoryList(mdmp *Minidump, buf *minidumpBuf, logfn func(fmt string, args ...interface{})) {
+	rangesNum := buf.u32()
+	for i := uint32(0); i < rangesNum; i++ {
+		readMemoryDescriptor(mdmp, buf)
+		if buf.err != nil {
+			return
+		}
+		if logfn != nil {
+			m := &mdmp.MemoryRanges[len(mdmp.MemoryRanges)-1]
+			logfn("\tMemory %d addr:%#x size:%#x\n", i, m.Addr, m.Size)
+		}
+	}
+}
+
 // readMemory64List reads a _MINIDUMP_MEMORY64_LIST structure, containing
 // the description of the process memory.
 // See: https://docs.microsoft.com/en-us/windows/win32/api/minidumpapiset/ns-minidumpapiset-minidump_memory64_list
@@ -610,12 +749,12 @@ func readMemory64List(mdmp *Minidump, buf *minidumpBuf, logfn func(fmt string, a
 		sz := buf.u64()
 
 		end := baseOff + int(sz)
-		if baseOff >= len(buf.buf) || end > len(buf.buf) {
+		if baseOff > buf.file.size || end > buf.file.size || end < baseOff {
 			buf.err = fmt.Errorf("memory range at %#x of size %#x is past the end of file, while %s", baseOff, sz, buf.ctx)
 			return
 		}
 
-		mdmp.addMemory(addr, buf.buf[baseOff:end])
+		mdmp.addMemory(buf.file, addr, baseOff, sz)
 
 		if logfn != nil {
 			logfn("\tMemory %d addr:%#x size:%#x FileOffset:%#x\n", i, addr, sz, baseOff)
@@ -671,16 +810,18 @@ func readMiscInfo(mdmp *Minidump, buf *minidumpBuf) {
 // readMemoryDescriptor reads a memory descriptor struct and adds it to the memory map of the minidump.
 func readMemoryDescriptor(mdmp *Minidump, buf *minidumpBuf) {
 	addr := buf.u64()
+	sz := buf.u32()
+	off := int(buf.u32())
 	if buf.err != nil {
 		return
 	}
-	_, rawData := readLocationDescriptor(buf)
-	if buf.err != nil {
+	if off > buf.file.size || int(sz) > buf.file.size-off {
+		buf.err = fmt.Errorf("memory range at %#x of size %#x is past the end of file, while %s", off, sz, buf.ctx)
 		return
 	}
-	mdmp.addMemory(addr, rawData)
+	mdmp.addMemory(buf.file, addr, off, uint64(sz))
 }
 
-func (mdmp *Minidump) addMemory(addr uint64, data []byte) {
-	mdmp.MemoryRanges = append(mdmp.MemoryRanges, MemoryRange{addr, data})
+func (mdmp *Minidump) addMemory(file *minidumpFile, addr uint64, off int, sz uint64) {
+	mdmp.MemoryRanges = append(mdmp.MemoryRanges, MemoryRange{addr, sz, io.NewSectionReader(file.r, int64(off), int64(sz))})
 }
@@ -0,0 +1,181 @@
+package minidump
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"testing"
+	"unicode/utf16"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc/winutil"
+)
+
+// writeTestMinidump writes a minidump similar to the ones written by WER,
+// with a thread that received an exception, its stack in a MemoryList
+// stream and the rest of memory in a Memory64List stream.
+func writeTestMinidump(t *testing.T) []byte {
+	const (
+		numStreams = 6
+		tid        = 10
+		stackAddr  = 0x7000
+		stackSize  = 0x100
+		memAddr    = 0x400000
+		memSize    = 0x1000
+	)
+	name := utf16.Encode([]rune(`C:\dir\prog.exe`))
+	ctxSize := int(unsafe.Sizeof(winutil.CONTEXT{}))
+
+	miscOff := minidumpHeaderSize + numStreams*minidumpDirectorySize
+	threadListOff := miscOff + 24
+	moduleListOff := threadListOff + 4 + 48
+	nameOff := moduleListOff + 4 + 108
+	excOff := nameOff + 4 + 2*len(name)
+	memListOff := excOff + 168
+	mem64ListOff := memListOff + 4 + 16
+	ctxOff := mem64ListOff + 16 + 16
+	excCtxOff := ctxOff + ctxSize
+	stackOff := excCtxOff + ctxSize
+	memOff := stackOff + stackSize
+
+	buf := new(bytes.Buffer)
+	w := func(vs ...interface{}) {
+		for _, v := range vs {
+			binary.Write(buf, binary.LittleEndian, v)
+		}
+	}
+	check := func(off int) {
+		if buf.Len() != off {
+			t.Fatalf("wrong offset %#x, expected %#x", buf.Len(), off)
+		}
+	}
+	context := func(rip uint64) []byte {
+		ctx := &winutil.CONTEXT{Rip: rip}
+		return (*[unsafe.Sizeof(winutil.CONTEXT{})]byte)(unsafe.Pointer(ctx))[:]
+	}
+
+	w(uint32(minidumpSignature), uint16(minidumpVersion), uint16(0), uint32(numStreams), uint32(minidumpHeaderSize), uint32(0), uint32(0), uint64(FileWithFullMemory))
+	for _, stream := range []struct {
+		typ       StreamType
+		size, off int
+	}{
+		{MiscInfoStream, 24, miscOff},
+		{ThreadListStream, 4 + 48, threadListOff},
+		{ModuleListStream, 4 + 108, moduleListOff},
+		{ExceptionStream, 168, excOff},
+		{MemoryListStream, 4 + 16, memListOff},
+		{Memory64ListStream, 16 + 16, mem64ListOff},
+	} {
+		w(uint32(stream.typ), uint32(stream.size), uint32(stream.off))
+	}
+
+	check(miscOff)
+	w(uint32(24), uint32(1), uint32(1234), [3]uint32{})
+
+	check(threadListOff)
+	w(uint32(1), uint32(tid), uint32(0), uint32(0), uint32(0), uint64(0x1234))
+	w(uint64(stackAddr), uint32(stackSize), uint32(stackOff))
+	w(uint32(ctxSize), uint32(ctxOff))
+
+	check(moduleListOff)
+	w(uint32(1), uint64(memAddr), uint32(memSize), uint32(0), uint32(0), uint32(nameOff), [13]uint32{}, [4]uint64{})
+	check(nameOff)
+	w(uint32(2*len(name)), name)
+
+	check(excOff)
+	w(uint32(tid), uint32(0), uint32(0xc0000005), uint32(0), uint64(0), uint64(memAddr+0x10), uint32(0), uint32(0), [15]uint64{})
+	w(uint32(ctxSize), uint32(excCtxOff))
+
+	check(memListOff)
+	w(uint32(1), uint64(stackAddr), uint32(stackSize), uint32(stackOff))
+
+	check(mem64ListOff)
+	w(uint64(1), uint64(memOff), uint64(memAddr), uint64(memSize))
+
+	check(ctxOff)
+	w(context(memAddr + 0x20))
+	w(context(memAddr + 0x10))
+
+	check(stackOff)
+	for i := 0; i < stackSize; i++ {
+		w(uint8(i))
+	}
+	check(memOff)
+	for i := 0; i < memSize; i++ {
+		w(uint8(i % 251))
+	}
+
+	return buf.Bytes()
+}
+
+func openTestMinidump(t *testing.T, buf []byte) (*Minidump, error) {
+	fh, err := ioutil.TempFile("", "minidump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(fh.Name())
+	if _, err := fh.Write(buf); err != nil {
+		t.Fatal(err)
+	}
+	fh.Close()
+	return Open(fh.Name(), t.Logf)
+}
+
+func TestOpen(t *testing.T) {
+	mdmp, err := openTestMinidump(t, writeTestMinidump(t))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer mdmp.Close()
+
+	if mdmp.Pid != 1234 {
+		t.Errorf("wrong pid %d", mdmp.Pid)
+	}
+	if len(mdmp.Threads) != 1 || mdmp.Threads[0].ID != 10 || mdmp.Threads[0].TEB != 0x1234 || mdmp.Threads[0].Context.Rip != 0x400020 {
+		t.Errorf("wrong threads %#v", mdmp.Threads)
+	}
+	if len(mdmp.Modules) != 1 || mdmp.Modules[0].Name != `C:\dir\prog.exe` || mdmp.Modules[0].BaseOfImage != 0x400000 {
+		t.Errorf("wrong modules %#v", mdmp.Modules)
+	}
+	if exc := mdmp.Exception; exc == nil || exc.ThreadID != 10 || exc.Code != 0xc0000005 || exc.Address != 0x400010 || exc.Context.Rip != 0x400010 {
+		t.Errorf("wrong exception %#v", mdmp.Exception)
+	}
+
+	read := func(addr uint64, n int) []byte {
+		for i := range mdmp.MemoryRanges {
+			m := &mdmp.MemoryRanges[i]
+			if addr >= m.Addr && addr < m.Addr+m.Size {
+				buf := make([]byte, n)
+				_, err := m.ReadMemory(buf, addr)
+				if err != nil {
+					t.Errorf("ReadMemory(%#x): %v", addr, err)
+				}
+				return buf
+			}
+		}
+		t.Errorf("address %#x not found", addr)
+		return nil
+	}
+
+	if buf := read(0x7010, 4); !bytes.Equal(buf, []byte{0x10, 0x11, 0x12, 0x13}) {
+		t.Errorf("wrong stack contents %x", buf)
+	}
+	if buf := read(0x400000+300, 2); !bytes.Equal(buf, []byte{300 % 251, 301 % 251}) {
+		t.Errorf("wrong memory contents %x", buf)
+	}
+}
+
+func TestOpenTruncated(t *testing.T) {
+	buf := writeTestMinidump(t)
+	_, err := openTestMinidump(t, buf[:len(buf)-0x100])
+	if err == nil {
+		t.Errorf("no error opening truncated minidump")
+	}
+	t.Logf("truncated: %v", err)
+
+	_, err = openTestMinidump(t, buf[:10])
+	if _, isNotAMinidump := err.(ErrNotAMinidump); !isNotAMinidump {
+		t.Errorf("wrong error opening short file: %v", err)
+	}
+}
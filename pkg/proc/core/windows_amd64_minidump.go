@@ -1,6 +1,8 @@
 package core
 
 import (
+	"strings"
+
 	"github.com/go-delve/delve/pkg/logflags"
 	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/core/minidump"
@@ -25,13 +27,22 @@ func readAMD64Minidump(minidumpPath, exePath string) (*process, proc.Thread, err
 
 	for i := range mdmp.MemoryRanges {
 		m := &mdmp.MemoryRanges[i]
-		memory.Add(m, m.Addr, uint64(len(m.Data)))
+		memory.Add(m, m.Addr, m.Size)
 	}
 
+	// The entry point of the target is the base address of the executable
+	// module. It is usually, but not always, the first module.
 	entryPoint := uint64(0)
 	if len(mdmp.Modules) > 0 {
 		entryPoint = mdmp.Modules[0].BaseOfImage
 	}
+	exeName := windowsBaseName(exePath)
+	for i := range mdmp.Modules {
+		if strings.EqualFold(windowsBaseName(mdmp.Modules[i].Name), exeName) {
+			entryPoint = mdmp.Modules[i].BaseOfImage
+			break
+		}
+	}
 
 	p := &process{
 		mem:         memory,
@@ -50,9 +61,26 @@ func readAMD64Minidump(minidumpPath, exePath string) (*process, proc.Thread, err
 	if len(mdmp.Threads) > 0 {
 		currentThread = p.Threads[int(mdmp.Threads[0].ID)]
 	}
+	if mdmp.Exception != nil {
+		// Minidumps written by WER or by procdump when a crash happens
+		// describe the exception that caused the dump, the thread that
+		// received the exception is the most interesting one.
+		if th, ok := p.Threads[int(mdmp.Exception.ThreadID)]; ok {
+			currentThread = th
+		}
+	}
 	return p, currentThread, nil
 }
 
+// windowsBaseName returns the last element of path, which can use either
+// slashes or backslashes as separators.
+func windowsBaseName(path string) string {
+	if i := strings.LastIndexAny(path, `/\`); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
 type windowsAMD64Thread struct {
 	th *minidump.Thread
 }
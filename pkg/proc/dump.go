@@ -1,11 +1,13 @@
 package proc
 
 import (
+	"bufio"
 	"bytes"
 	"debug/elf"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"io"
 	"runtime"
 	"sync"
 
@@ -86,6 +88,12 @@ func (state *DumpState) memDone(delta uint64) {
 	state.Mutex.Unlock()
 }
 
+func (state *DumpState) setAllDone() {
+	state.Mutex.Lock()
+	state.AllDone = true
+	state.Mutex.Unlock()
+}
+
 func (state *DumpState) isCanceled() bool {
 	state.Mutex.Lock()
 	defer state.Mutex.Unlock()
@@ -112,6 +120,22 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 
 	bi := t.BinInfo()
 
+	switch {
+	case flags&DumpPlatformIndependent == 0 && bi.GOOS == "windows" && bi.Arch.Name == "amd64":
+		t.dumpMinidump(out, state)
+	case flags&DumpPlatformIndependent == 0 && bi.GOOS == "darwin":
+		t.dumpMachO(out, state)
+	default:
+		t.dumpELF(out, flags, state)
+	}
+}
+
+// dumpELF writes an ELF core file to out, this is the native format on
+// linux, freebsd and openbsd and the platform independent format used
+// everywhere else.
+func (t *Target) dumpELF(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *DumpState) {
+	bi := t.BinInfo()
+
 	var fhdr elf.FileHeader
 	fhdr.Class = elf.ELFCLASS64
 	fhdr.Data = elf.ELFDATA2LSB
@@ -122,6 +146,8 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 		fhdr.OSABI = elf.ELFOSABI_LINUX
 	case "freebsd":
 		fhdr.OSABI = elf.ELFOSABI_FREEBSD
+	case "openbsd":
+		fhdr.OSABI = elf.ELFOSABI_OPENBSD
 	default:
 		// There is no OSABI value for windows or macOS because nobody generates ELF core dumps on those systems.
 		fhdr.OSABI = 0xff
@@ -136,6 +162,12 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 		fhdr.Machine = elf.EM_386
 	case "arm64":
 		fhdr.Machine = elf.EM_AARCH64
+	case "s390x":
+		fhdr.Machine = elf.EM_S390
+	case "riscv64":
+		fhdr.Machine = elf.EM_RISCV
+	case "loong64":
+		fhdr.Machine = _EM_LOONGARCH
 	default:
 		panic("not implemented")
 	}
@@ -155,7 +187,7 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 	notes = append(notes, elfwriter.Note{
 		Type: elfwriter.DelveHeaderNoteType,
 		Name: "Delve Header",
-		Data: []byte(fmt.Sprintf("%s/%s\n%s\n%s%d\n%s%#x\n", bi.GOOS, bi.Arch.Name, version.DelveVersion.String(), elfwriter.DelveHeaderTargetPidPrefix, t.Pid(), elfwriter.DelveHeaderEntryPointPrefix, entryPoint)),
+		Data: t.dumpHeaderNote(entryPoint),
 	})
 
 	threads := t.ThreadList()
@@ -185,24 +217,12 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 		}
 	}
 
-	memmap, err := t.proc.MemoryMap()
+	memmapFilter, err := t.dumpMemoryMap(state)
 	if err != nil {
 		state.setErr(err)
 		return
 	}
 
-	memmapFilter := make([]MemoryMapEntry, 0, len(memmap))
-	memtot := uint64(0)
-	for i := range memmap {
-		mme := &memmap[i]
-		if t.shouldDumpMemory(mme) {
-			memmapFilter = append(memmapFilter, *mme)
-			memtot += mme.Size
-		}
-	}
-
-	state.setMemTotal(memtot)
-
 	for i := range memmapFilter {
 		mme := &memmapFilter[i]
 		if w.Err != nil {
@@ -221,9 +241,37 @@ func (t *Target) Dump(out elfwriter.WriteCloserSeeker, flags DumpFlags, state *D
 	if w.Err != nil {
 		state.setErr(fmt.Errorf("error writing to output file: %v", w.Err))
 	}
-	state.Mutex.Lock()
-	state.AllDone = true
-	state.Mutex.Unlock()
+	state.setAllDone()
+}
+
+// dumpHeaderNote returns the contents of the note describing the target
+// process (operating system, architecture, pid and entry point).
+func (t *Target) dumpHeaderNote(entryPoint uint64) []byte {
+	bi := t.BinInfo()
+	return []byte(fmt.Sprintf("%s/%s\n%s\n%s%d\n%s%#x\n", bi.GOOS, bi.Arch.Name, version.DelveVersion.String(), elfwriter.DelveHeaderTargetPidPrefix, t.Pid(), elfwriter.DelveHeaderEntryPointPrefix, entryPoint))
+}
+
+// dumpMemoryMap returns the list of memory mappings that should be saved
+// in the core file and sets the total amount of memory that will be
+// written.
+func (t *Target) dumpMemoryMap(state *DumpState) ([]MemoryMapEntry, error) {
+	memmap, err := t.proc.MemoryMap()
+	if err != nil {
+		return nil, err
+	}
+
+	memmapFilter := make([]MemoryMapEntry, 0, len(memmap))
+	memtot := uint64(0)
+	for i := range memmap {
+		mme := &memmap[i]
+		if t.shouldDumpMemory(mme) {
+			memmapFilter = append(memmapFilter, *mme)
+			memtot += mme.Size
+		}
+	}
+
+	state.setMemTotal(memtot)
+	return memmapFilter, nil
 }
 
 // dumpThreadNotes appends notes describing a thread (thread id and its
@@ -311,16 +359,21 @@ func (t *Target) dumpMemory(state *DumpState, w *elfwriter.Writer, mme *MemoryMa
 		Align:  0,
 	})
 
+	t.dumpMemoryContents(state, mme, func(buf []byte) error {
+		w.Write(buf)
+		return w.Err
+	})
+}
+
+// dumpMemoryContents reads the memory described by mme and passes it to
+// write in chunks, memory that can not be read is replaced by zeroes.
+func (t *Target) dumpMemoryContents(state *DumpState, mme *MemoryMapEntry, write func([]byte) error) {
 	buf := make([]byte, 1024*1024)
 	addr := mme.Addr
 	sz := mme.Size
 	mem := t.Memory()
 
 	for sz > 0 {
-		if w.Err != nil {
-			state.setErr(fmt.Errorf("error writing to output file: %v", w.Err))
-			return
-		}
 		if state.isCanceled() {
 			return
 		}
@@ -336,7 +389,10 @@ func (t *Target) dumpMemory(state *DumpState, w *elfwriter.Writer, mme *MemoryMa
 		// (*ProcessInternal).MemoryMap gave us a bad mapping that can't be read
 		// and the behavior that's maximally useful to the user is to generate an
 		// incomplete dump.
-		w.Write(chunk)
+		if werr := write(chunk); werr != nil {
+			state.setErr(fmt.Errorf("error writing to output file: %v", werr))
+			return
+		}
 		addr += uint64(len(chunk))
 		sz -= uint64(len(chunk))
 		if err == nil {
@@ -381,6 +437,55 @@ func (t *Target) shouldDumpMemory(mme *MemoryMapEntry) bool {
 	return true
 }
 
+// dumpWriter writes sequentially to a core file using little endian byte
+// order, keeping track of the current offset and of the first error.
+type dumpWriter struct {
+	w   *bufio.Writer
+	off uint64
+	err error
+}
+
+func newDumpWriter(out io.Writer) *dumpWriter {
+	return &dumpWriter{w: bufio.NewWriter(out)}
+}
+
+// write writes data, which must be either a byte slice or a value that can
+// be encoded by encoding/binary.
+func (w *dumpWriter) write(data interface{}) {
+	if w.err != nil {
+		return
+	}
+	if buf, ok := data.([]byte); ok {
+		_, w.err = w.w.Write(buf)
+		w.off += uint64(len(buf))
+		return
+	}
+	w.err = binary.Write(w.w, binary.LittleEndian, data)
+	w.off += uint64(binary.Size(data))
+}
+
+func (w *dumpWriter) writeChunk(buf []byte) error {
+	w.write(buf)
+	return w.err
+}
+
+// pad writes zeroes until the current offset is off.
+func (w *dumpWriter) pad(off uint64) {
+	if w.off < off {
+		w.write(make([]byte, off-w.off))
+	}
+}
+
+func (w *dumpWriter) flush() {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+}
+
+func dumpAlign(off, align uint64) uint64 {
+	return uint64(alignAddr(int64(off), int64(align)))
+}
+
 type internalError struct {
 	Err   interface{}
 	Stack []internalErrorFrame
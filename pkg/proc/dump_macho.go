@@ -0,0 +1,260 @@
+package proc
+
+import (
+	"debug/macho"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+	"github.com/go-delve/delve/pkg/elfwriter"
+)
+
+// Mach-O definitions missing from debug/macho, see
+// <mach-o/loader.h> and <mach/thread_status.h>.
+const (
+	machoTypeCore    = 0x4  // MH_CORE
+	machoLoadCmdNote = 0x31 // LC_NOTE
+
+	machoCPUSubtypeAMD64 = 3 // CPU_SUBTYPE_X86_64_ALL
+	machoCPUSubtypeARM64 = 0 // CPU_SUBTYPE_ARM64_ALL
+
+	machoThreadStateAMD64 = 4 // x86_THREAD_STATE64
+	machoThreadStateARM64 = 6 // ARM_THREAD_STATE64
+
+	machoVMProtRead    = 0x1
+	machoVMProtWrite   = 0x2
+	machoVMProtExecute = 0x4
+
+	machoHeaderSize    = 32
+	machoNoteCmdSize   = 40
+	machoSegmentSize   = 72
+	machoSegmentAlign  = 0x4000
+	machoNoteOwnerSize = 16
+)
+
+// machoNote is a LC_NOTE load command and its contents.
+type machoNote struct {
+	owner string
+	data  []byte
+}
+
+// dumpMachO writes a Mach-O core file to out. Each thread is described by
+// a LC_THREAD load command, so that the core file can be read by other
+// debuggers, and by a LC_NOTE containing its platform independent
+// description, which is what Delve uses.
+func (t *Target) dumpMachO(out elfwriter.WriteCloserSeeker, state *DumpState) {
+	bi := t.BinInfo()
+
+	var hdr macho.FileHeader
+	hdr.Magic = macho.Magic64
+	hdr.Type = machoTypeCore
+
+	switch bi.Arch.Name {
+	case "amd64":
+		hdr.Cpu = macho.CpuAmd64
+		hdr.SubCpu = machoCPUSubtypeAMD64
+	case "arm64":
+		hdr.Cpu = macho.CpuArm64
+		hdr.SubCpu = machoCPUSubtypeARM64
+	default:
+		panic("not implemented")
+	}
+
+	entryPoint, err := t.EntryPoint()
+	if err != nil {
+		state.setErr(err)
+		return
+	}
+
+	notes := []machoNote{{owner: elfwriter.DelveHeaderNoteOwner, data: t.dumpHeaderNote(entryPoint)}}
+	var threadCmds [][]byte
+
+	threads := t.ThreadList()
+	state.setThreadsTotal(len(threads))
+
+	for _, th := range threads {
+		if state.isCanceled() {
+			return
+		}
+		regs, err := th.Registers()
+		if err != nil {
+			state.setErr(err)
+			return
+		}
+		threadCmds = append(threadCmds, machoThreadCommand(bi.Arch, regs))
+		threadNotes := t.dumpThreadNotes(nil, state, th)
+		if len(threadNotes) == 0 {
+			// the error was already recorded in state by dumpThreadNotes
+			return
+		}
+		notes = append(notes, machoNote{owner: elfwriter.DelveThreadNoteOwner, data: threadNotes[0].Data})
+		state.threadDone()
+	}
+
+	memmap, err := t.dumpMemoryMap(state)
+	if err != nil {
+		state.setErr(err)
+		return
+	}
+
+	// Compute the layout of the file: the header and all load commands are
+	// followed by the contents of the notes and then by the contents of the
+	// memory segments.
+
+	hdr.Ncmd = uint32(len(threadCmds) + len(notes) + len(memmap))
+	for _, cmd := range threadCmds {
+		hdr.Cmdsz += uint32(len(cmd))
+	}
+	hdr.Cmdsz += uint32(len(notes)*machoNoteCmdSize + len(memmap)*machoSegmentSize)
+
+	off := uint64(machoHeaderSize + hdr.Cmdsz)
+	noteOffs := make([]uint64, len(notes))
+	for i := range notes {
+		noteOffs[i] = off
+		off += uint64(len(notes[i].data))
+	}
+	off = dumpAlign(off, machoSegmentAlign)
+	segmentOffs := make([]uint64, len(memmap))
+	for i := range memmap {
+		segmentOffs[i] = off
+		off += memmap[i].Size
+	}
+
+	w := newDumpWriter(out)
+
+	w.write(hdr.Magic)
+	w.write(hdr.Cpu)
+	w.write(hdr.SubCpu)
+	w.write(hdr.Type)
+	w.write(hdr.Ncmd)
+	w.write(hdr.Cmdsz)
+	w.write(hdr.Flags)
+	w.write(uint32(0)) // reserved
+
+	for _, cmd := range threadCmds {
+		w.write(cmd)
+	}
+
+	for i := range notes {
+		w.write(uint32(machoLoadCmdNote))
+		w.write(uint32(machoNoteCmdSize))
+		w.write(machoFixedString(notes[i].owner))
+		w.write(noteOffs[i])
+		w.write(uint64(len(notes[i].data)))
+	}
+
+	for i := range memmap {
+		mme := &memmap[i]
+		var prot uint32
+		if mme.Read {
+			prot |= machoVMProtRead
+		}
+		if mme.Write {
+			prot |= machoVMProtWrite
+		}
+		if mme.Exec {
+			prot |= machoVMProtExecute
+		}
+		w.write(uint32(macho.LoadCmdSegment64))
+		w.write(uint32(machoSegmentSize))
+		w.write(machoFixedString(""))
+		w.write(mme.Addr)       // vmaddr
+		w.write(mme.Size)       // vmsize
+		w.write(segmentOffs[i]) // fileoff
+		w.write(mme.Size)       // filesize
+		w.write(prot)           // maxprot
+		w.write(prot)           // initprot
+		w.write(uint32(0))      // nsects
+		w.write(uint32(0))      // flags
+	}
+
+	for i := range notes {
+		w.write(notes[i].data)
+	}
+
+	for i := range memmap {
+		if w.err != nil {
+			break
+		}
+		if state.isCanceled() {
+			return
+		}
+		w.pad(segmentOffs[i])
+		t.dumpMemoryContents(state, &memmap[i], w.writeChunk)
+	}
+
+	w.flush()
+	if w.err != nil {
+		state.setErr(fmt.Errorf("error writing to output file: %v", w.err))
+		return
+	}
+	state.setAllDone()
+}
+
+// machoThreadCommand returns a LC_THREAD load command describing the
+// general purpose registers of a thread.
+func machoThreadCommand(arch *Arch, regs Registers) []byte {
+	dregs := arch.RegistersToDwarfRegisters(0, regs)
+
+	var flavor uint32
+	var state []uint32
+	appendReg := func(v uint64) {
+		state = append(state, uint32(v), uint32(v>>32))
+	}
+
+	switch arch.Name {
+	case "amd64":
+		// struct x86_thread_state64
+		flavor = machoThreadStateAMD64
+		for _, n := range []uint64{
+			regnum.AMD64_Rax, regnum.AMD64_Rbx, regnum.AMD64_Rcx, regnum.AMD64_Rdx,
+			regnum.AMD64_Rdi, regnum.AMD64_Rsi, regnum.AMD64_Rbp, regnum.AMD64_Rsp,
+			regnum.AMD64_R8, regnum.AMD64_R9, regnum.AMD64_R10, regnum.AMD64_R11,
+			regnum.AMD64_R12, regnum.AMD64_R13, regnum.AMD64_R14, regnum.AMD64_R15,
+			regnum.AMD64_Rip, regnum.AMD64_Rflags, regnum.AMD64_Cs, regnum.AMD64_Fs, regnum.AMD64_Gs} {
+			appendReg(dregs.Uint64Val(n))
+		}
+	case "arm64":
+		// struct arm_thread_state64
+		flavor = machoThreadStateARM64
+		for n := uint64(regnum.ARM64_X0); n <= regnum.ARM64_LR; n++ {
+			appendReg(dregs.Uint64Val(n))
+		}
+		appendReg(dregs.Uint64Val(regnum.ARM64_SP))
+		appendReg(dregs.Uint64Val(regnum.ARM64_PC))
+		state = append(state, uint32(machoRegisterByName(regs, "PSTATE")), 0)
+	default:
+		panic("not implemented")
+	}
+
+	buf := make([]byte, 16+4*len(state))
+	binary.LittleEndian.PutUint32(buf[0:], uint32(macho.LoadCmdThread))
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(buf)))
+	binary.LittleEndian.PutUint32(buf[8:], flavor)
+	binary.LittleEndian.PutUint32(buf[12:], uint32(len(state)))
+	for i, v := range state {
+		binary.LittleEndian.PutUint32(buf[16+4*i:], v)
+	}
+	return buf
+}
+
+// machoRegisterByName returns the value of the register called name, or
+// zero if regs does not have a register with that name.
+func machoRegisterByName(regs Registers, name string) uint64 {
+	regsv, _ := regs.Slice(false)
+	for _, reg := range regsv {
+		if strings.EqualFold(reg.Name, name) {
+			return reg.Reg.Uint64Val
+		}
+	}
+	return 0
+}
+
+// machoFixedString returns s as a zero padded 16 byte array, which is how
+// Mach-O stores segment names and note owners.
+func machoFixedString(s string) []byte {
+	buf := make([]byte, machoNoteOwnerSize)
+	copy(buf, s)
+	return buf
+}
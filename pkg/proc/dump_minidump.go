@@ -0,0 +1,275 @@
+package proc
+
+import (
+	"debug/pe"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf16"
+
+	"github.com/go-delve/delve/pkg/elfwriter"
+)
+
+// Minidump definitions, see:
+// https://docs.microsoft.com/en-us/windows/win32/api/minidumpapiset/
+const (
+	minidumpSignature = 0x504d444d // 'MDMP'
+	minidumpVersion   = 0xa793
+
+	minidumpWithFullMemory = 0x2 // MiniDumpWithFullMemory
+
+	minidumpThreadListStream   = 3
+	minidumpModuleListStream   = 4
+	minidumpSystemInfoStream   = 7
+	minidumpMemory64ListStream = 9
+	minidumpMiscInfoStream     = 15
+
+	minidumpProcessorArchitectureAMD64 = 9 // PROCESSOR_ARCHITECTURE_AMD64
+	minidumpPlatformWin32NT            = 2 // VER_PLATFORM_WIN32_NT
+	minidumpMisc1ProcessID             = 0x1
+
+	minidumpHeaderSize         = 32
+	minidumpDirectorySize      = 12
+	minidumpSystemInfoSize     = 56
+	minidumpMiscInfoSize       = 24
+	minidumpThreadSize         = 48
+	minidumpModuleSize         = 108
+	minidumpMemoryDescSize     = 16
+	minidumpAMD64ContextSize   = 1232
+	minidumpAMD64ContextAlign  = 16
+	minidumpVSFixedFileInfoLen = 13 // number of 32bit words in VS_FIXEDFILEINFO
+)
+
+// minidumpContextRegisters is implemented by the registers of windows
+// threads, it returns the CONTEXT structure they were read from.
+type minidumpContextRegisters interface {
+	ContextBytes() []byte
+}
+
+// dumpMinidump writes a minidump containing the full memory of the target
+// process to out.
+func (t *Target) dumpMinidump(out elfwriter.WriteCloserSeeker, state *DumpState) {
+	bi := t.BinInfo()
+
+	entryPoint, err := t.EntryPoint()
+	if err != nil {
+		state.setErr(err)
+		return
+	}
+
+	threads := t.ThreadList()
+	state.setThreadsTotal(len(threads))
+
+	threadRegs := make([]Registers, len(threads))
+	for i, th := range threads {
+		if state.isCanceled() {
+			return
+		}
+		regs, err := th.Registers()
+		if err != nil {
+			state.setErr(err)
+			return
+		}
+		if _, ok := regs.(minidumpContextRegisters); !ok {
+			state.setErr(fmt.Errorf("can not save registers of thread %d in a minidump", th.ThreadID()))
+			return
+		}
+		threadRegs[i] = regs
+	}
+
+	memmap, err := t.dumpMemoryMap(state)
+	if err != nil {
+		state.setErr(err)
+		return
+	}
+
+	exePath := bi.Images[0].Path
+	var sizeOfImage uint32
+	if exe, err := pe.Open(exePath); err == nil {
+		if opthdr, ok := exe.OptionalHeader.(*pe.OptionalHeader64); ok {
+			sizeOfImage = opthdr.SizeOfImage
+		}
+		exe.Close()
+	}
+	moduleName := utf16.Encode([]rune(exePath))
+
+	// Compute the layout of the file, all streams are placed after the stream
+	// directory, the contents of memory is the last thing in the file.
+
+	const numStreams = 5
+	off := uint64(minidumpHeaderSize + numStreams*minidumpDirectorySize)
+
+	systemInfoOff := off
+	off += minidumpSystemInfoSize
+	csdVersionOff := off
+	off += 6 // empty MINIDUMP_STRING
+	off = dumpAlign(off, 4)
+
+	miscInfoOff := off
+	off += minidumpMiscInfoSize
+
+	threadListOff := off
+	threadListSize := uint64(4 + len(threads)*minidumpThreadSize)
+	off += threadListSize
+	off = dumpAlign(off, minidumpAMD64ContextAlign)
+	contextOffs := make([]uint64, len(threads))
+	for i := range threads {
+		contextOffs[i] = off
+		off += minidumpAMD64ContextSize
+	}
+
+	moduleListOff := off
+	moduleListSize := uint64(4 + minidumpModuleSize)
+	off += moduleListSize
+	off = dumpAlign(off, 4)
+	moduleNameOff := off
+	off += uint64(4 + 2*len(moduleName) + 2)
+
+	off = dumpAlign(off, 8)
+	memoryListOff := off
+	memoryListSize := uint64(16 + len(memmap)*minidumpMemoryDescSize)
+	off += memoryListSize
+	memoryOffs := make([]uint64, len(memmap))
+	for i := range memmap {
+		memoryOffs[i] = off
+		off += memmap[i].Size
+	}
+
+	if memoryListOff+memoryListSize > 1<<32 {
+		// only the contents of memory can be placed past the first 4GB
+		state.setErr(errors.New("too many threads to write a minidump"))
+		return
+	}
+
+	w := newDumpWriter(out)
+
+	// MINIDUMP_HEADER
+	w.write(uint32(minidumpSignature))
+	w.write(uint32(minidumpVersion))
+	w.write(uint32(numStreams))
+	w.write(uint32(minidumpHeaderSize)) // StreamDirectoryRva
+	w.write(uint32(0))                  // CheckSum
+	w.write(uint32(time.Now().Unix()))  // TimeDateStamp
+	w.write(uint64(minidumpWithFullMemory))
+
+	// MINIDUMP_DIRECTORY
+	for _, stream := range []struct {
+		typ       uint32
+		size, off uint64
+	}{
+		{minidumpSystemInfoStream, minidumpSystemInfoSize, systemInfoOff},
+		{minidumpMiscInfoStream, minidumpMiscInfoSize, miscInfoOff},
+		{minidumpThreadListStream, threadListSize, threadListOff},
+		{minidumpModuleListStream, moduleListSize, moduleListOff},
+		{minidumpMemory64ListStream, memoryListSize, memoryListOff},
+	} {
+		w.write(stream.typ)
+		w.write(uint32(stream.size))
+		w.write(uint32(stream.off))
+	}
+
+	// MINIDUMP_SYSTEM_INFO
+	w.write(uint16(minidumpProcessorArchitectureAMD64))
+	w.write(uint16(0))                       // ProcessorLevel
+	w.write(uint16(0))                       // ProcessorRevision
+	w.write(uint8(0))                        // NumberOfProcessors
+	w.write(uint8(0))                        // ProductType
+	w.write(uint32(0))                       // MajorVersion
+	w.write(uint32(0))                       // MinorVersion
+	w.write(uint32(0))                       // BuildNumber
+	w.write(uint32(minidumpPlatformWin32NT)) // PlatformId
+	w.write(uint32(csdVersionOff))           // CSDVersionRva
+	w.write(uint16(0))                       // SuiteMask
+	w.write(uint16(0))                       // Reserved2
+	w.write(make([]byte, 24))                // Cpu
+	w.write(uint32(0))                       // CSDVersion, empty string
+	w.write(uint16(0))
+	w.pad(miscInfoOff)
+
+	// MINIDUMP_MISC_INFO
+	w.write(uint32(minidumpMiscInfoSize))
+	w.write(uint32(minidumpMisc1ProcessID))
+	w.write(uint32(t.Pid()))
+	w.write(make([]byte, 12)) // process times
+
+	// MINIDUMP_THREAD_LIST
+	w.write(uint32(len(threads)))
+	for i, th := range threads {
+		regs := threadRegs[i]
+		w.write(uint32(th.ThreadID()))
+		w.write(uint32(0)) // SuspendCount
+		w.write(uint32(0)) // PriorityClass
+		w.write(uint32(0)) // Priority
+		w.write(regs.TLS())
+
+		// The stack of the thread is the memory mapping containing the stack
+		// pointer, it is also part of the memory list.
+		stackAddr, stackSize, stackOff := regs.SP(), uint64(0), uint64(0)
+		for j := range memmap {
+			mme := &memmap[j]
+			if regs.SP() >= mme.Addr && regs.SP() < mme.Addr+mme.Size && memoryOffs[j]+mme.Size <= 1<<32 {
+				stackAddr, stackSize, stackOff = mme.Addr, mme.Size, memoryOffs[j]
+				break
+			}
+		}
+		w.write(stackAddr)
+		w.write(uint32(stackSize))
+		w.write(uint32(stackOff))
+
+		w.write(uint32(minidumpAMD64ContextSize))
+		w.write(uint32(contextOffs[i]))
+	}
+	for i := range threads {
+		w.pad(contextOffs[i])
+		ctx := threadRegs[i].(minidumpContextRegisters).ContextBytes()
+		if len(ctx) > minidumpAMD64ContextSize {
+			ctx = ctx[:minidumpAMD64ContextSize]
+		}
+		w.write(ctx)
+		state.threadDone()
+	}
+	w.pad(moduleListOff)
+
+	// MINIDUMP_MODULE_LIST
+	w.write(uint32(1))
+	w.write(entryPoint) // BaseOfImage
+	w.write(sizeOfImage)
+	w.write(uint32(0)) // CheckSum
+	w.write(uint32(0)) // TimeDateStamp
+	w.write(uint32(moduleNameOff))
+	w.write(make([]byte, 4*minidumpVSFixedFileInfoLen))
+	w.write(uint64(0)) // CvRecord
+	w.write(uint64(0)) // MiscRecord
+	w.write(uint64(0)) // Reserved0
+	w.write(uint64(0)) // Reserved1
+	w.pad(moduleNameOff)
+	w.write(uint32(2 * len(moduleName)))
+	w.write(moduleName)
+	w.write(uint16(0))
+	w.pad(memoryListOff)
+
+	// MINIDUMP_MEMORY64_LIST
+	w.write(uint64(len(memmap)))
+	w.write(memoryListOff + memoryListSize) // BaseRva
+	for i := range memmap {
+		w.write(memmap[i].Addr)
+		w.write(memmap[i].Size)
+	}
+
+	for i := range memmap {
+		if w.err != nil {
+			break
+		}
+		if state.isCanceled() {
+			return
+		}
+		t.dumpMemoryContents(state, &memmap[i], w.writeChunk)
+	}
+
+	w.flush()
+	if w.err != nil {
+		state.setErr(fmt.Errorf("error writing to output file: %v", w.err))
+		return
+	}
+	state.setAllDone()
+}
@@ -15,6 +15,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-delve/delve/pkg/dwarf/godwarf"
 	"github.com/go-delve/delve/pkg/dwarf/op"
@@ -36,6 +37,9 @@ type EvalScope struct {
 
 	frameOffset int64
 
+	// bp is the breakpoint whose condition is being evaluated, if any.
+	bp *Breakpoint
+
 	// When the following pointer is not nil this EvalScope was created
 	// by CallFunction and the expression evaluation is executing on a
 	// different goroutine from the debugger's main goroutine.
@@ -120,6 +124,9 @@ func FrameToScope(t *Target, bi *BinaryInfo, thread MemoryReadWriter, g *G, fram
 	} else {
 		maxaddr = uint64(frames[0].Regs.CFA)
 	}
+	// Writes made through the scope, by setting variables or calling
+	// functions, are recorded.
+	thread = t.auditMemory(thread)
 	if maxaddr > minaddr && maxaddr-minaddr < maxFramePrefetchSize {
 		thread = cacheMemory(thread, minaddr, int(maxaddr-minaddr))
 	}
@@ -212,8 +219,15 @@ func (scope *EvalScope) Locals() ([]*Variable, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(dwarfTree.Children) == 0 && scope.Fn.assembly() {
+		if asmTree, err := assemblyFunctionTree(scope.BinInfo, dwarfTree); err == nil {
+			dwarfTree = asmTree
+		}
+	}
 
-	variablesFlags := reader.VariablesOnlyVisible
+	// Inlined calls are separate stack frames, with their own scope, their
+	// variables do not belong to the frame of the function containing them.
+	variablesFlags := reader.VariablesOnlyVisible | reader.VariablesSkipInlinedSubroutines
 	if scope.BinInfo.Producer() != "" && goversion.ProducerAfterOrEqual(scope.BinInfo.Producer(), 1, 15) {
 		variablesFlags |= reader.VariablesTrustDeclLine
 	}
@@ -381,6 +395,9 @@ func (scope *EvalScope) EvalVariable(name string, cfg LoadConfig) (*Variable, er
 
 // SetVariable sets the value of the named variable
 func (scope *EvalScope) SetVariable(name, value string) error {
+	if err := scope.target.checkSafety("setting a variable"); err != nil {
+		return err
+	}
 	t, err := parser.ParseExpr(name)
 	if err != nil {
 		return err
@@ -696,9 +713,25 @@ func (scope *EvalScope) evalToplevelTypeCast(t ast.Expr, cfg LoadConfig) (*Varia
 	return nil, nil
 }
 
+// evalAST evaluates t. When function calls are enabled the result is
+// recorded so that its address can be updated if a function call made
+// while evaluating the rest of the expression moves the goroutine stack.
 func (scope *EvalScope) evalAST(t ast.Expr) (*Variable, error) {
+	v, err := scope.evalASTNode(t)
+	if v != nil && scope.callCtx != nil {
+		scope.callCtx.vars = append(scope.callCtx.vars, v)
+	}
+	return v, err
+}
+
+func (scope *EvalScope) evalASTNode(t ast.Expr) (*Variable, error) {
 	switch node := t.(type) {
 	case *ast.CallExpr:
+		if fnnode, ok := removeParen(node.Fun).(*ast.Ident); ok && fnnode.Name == "new" {
+			// the argument of the new builtin is a type, evalTypeCast would try
+			// to evaluate it as an expression
+			return evalFunctionCall(scope, node)
+		}
 		if len(node.Args) == 1 {
 			v, err := scope.evalTypeCast(node)
 			if err == nil || err != reader.TypeNotFoundErr {
@@ -887,6 +920,15 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 			x, _ := constant.Float64Val(argv.Value)
 			v.Value = constant.MakeInt64(int64(x))
 			return v, nil
+		case reflect.String:
+			if styp.String() == timeDurationTypeName && argv.isStringLiteral() {
+				d, err := parseDurationLiteral(argv)
+				if err != nil {
+					return nil, err
+				}
+				v.Value = constant.MakeInt64(int64(d))
+				return v, nil
+			}
 		}
 	case *godwarf.FloatType:
 		switch argv.Kind {
@@ -955,9 +997,47 @@ func (scope *EvalScope) evalBuiltinCall(node *ast.CallExpr) (*Variable, error) {
 		return callBuiltinWithArgs(imagBuiltin)
 	case "real":
 		return callBuiltinWithArgs(realBuiltin)
+	case "callerin":
+		return callBuiltinWithArgs(scope.callerinBuiltin)
+	case "new", "now", "hitcount", "goid", "label":
+		// These are only builtins if the target does not define a symbol
+		// with the same name.
+		if scope.symbolExists(fnnode.Name) {
+			return nil, nil
+		}
+		switch fnnode.Name {
+		case "new":
+			return scope.newBuiltin(node)
+		case "now":
+			return callBuiltinWithArgs(scope.nowBuiltin)
+		case "hitcount":
+			return callBuiltinWithArgs(scope.hitcountBuiltin)
+		case "goid":
+			return callBuiltinWithArgs(scope.goidBuiltin)
+		case "label":
+			return callBuiltinWithArgs(scope.labelBuiltin)
+		}
 	}
 
-	return nil, nil
+	return scope.evalCustomBuiltinCall(node, fnnode.Name)
+}
+
+// symbolExists returns true if name is a local variable or a package
+// level symbol of the current package.
+func (scope *EvalScope) symbolExists(name string) bool {
+	if vars, err := scope.Locals(); err == nil {
+		for i := range vars {
+			if vars[i].Name == name {
+				return true
+			}
+		}
+	}
+	if scope.Fn != nil {
+		if _, err := scope.findGlobal(scope.Fn.PackageName(), name); err == nil {
+			return true
+		}
+	}
+	return false
 }
 
 func capBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
@@ -1122,6 +1202,156 @@ func realBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
 	return newConstant(constant.Real(arg.Value), arg.mem), nil
 }
 
+// callerinBuiltin implements callerin(fnname, depth), which returns true
+// if the function of the current frame was called, directly or through
+// at most depth-1 other functions, by the function named fnname.
+// Only depth frames are unwound so that the builtin can be used cheaply
+// in breakpoint conditions.
+func (scope *EvalScope) callerinBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("wrong number of arguments to callerin: %d", len(args))
+	}
+
+	fnname := args[0]
+	fnname.loadValue(loadSingleValue)
+	if fnname.Unreadable != nil {
+		return nil, fnname.Unreadable
+	}
+	if fnname.Value == nil || fnname.Value.Kind() != constant.String {
+		return nil, fmt.Errorf("invalid argument %s (type %s) to callerin", exprToString(nodeargs[0]), fnname.TypeString())
+	}
+	name := constant.StringVal(fnname.Value)
+
+	depth := args[1]
+	depth.loadValue(loadSingleValue)
+	if depth.Unreadable != nil {
+		return nil, depth.Unreadable
+	}
+	if depth.Value == nil || depth.Value.Kind() != constant.Int {
+		return nil, fmt.Errorf("invalid argument %s (type %s) to callerin", exprToString(nodeargs[1]), depth.TypeString())
+	}
+	n, _ := constant.Int64Val(depth.Value)
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid argument %s to callerin: depth must be positive", exprToString(nodeargs[1]))
+	}
+
+	var stackhi uint64
+	if scope.g != nil {
+		stackhi = scope.g.stack.hi
+	}
+	it := newStackIterator(scope.BinInfo, scope.Mem, scope.Regs, stackhi, scope.g, 0)
+	frames, err := it.stacktrace(int(n))
+	if err != nil {
+		return nil, err
+	}
+
+	found := false
+	// frames[0] is the current frame, its callers follow.
+	for i := 1; i < len(frames); i++ {
+		if fn := frames[i].Call.Fn; fn != nil && (fn.Name == name || strings.HasSuffix(fn.Name, "/"+name)) {
+			found = true
+			break
+		}
+	}
+	return newConstant(constant.MakeBool(found), scope.Mem), nil
+}
+
+// newBuiltin implements new(T): it allocates a zeroed object of type T in
+// the heap of the target and returns a pointer to it. Since the object is
+// allocated by calling runtime.newobject it can only be used when function
+// calls are allowed, the returned pointer can then be assigned to a
+// variable or passed to other calls.
+func (scope *EvalScope) newBuiltin(node *ast.CallExpr) (*Variable, error) {
+	if len(node.Args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments to new: %d", len(node.Args))
+	}
+	typ, err := scope.BinInfo.findTypeExpr(removeParen(node.Args[0]))
+	if err != nil {
+		return nil, err
+	}
+	if scope.callCtx == nil {
+		return nil, errFuncCallNotAllowed
+	}
+	if scope.callCtx.cancelled {
+		return nil, errFuncCallCancelled
+	}
+	typeAddr, _, found, err := dwarfToRuntimeType(scope.BinInfo, scope.Mem, typ)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find runtime type of %s", typ)
+	}
+	addr, err := funcCallNewobject(scope, typeAddr)
+	if err != nil {
+		return nil, err
+	}
+	return newVariable("", addr, typ, scope.BinInfo, scope.Mem).pointerToVariable(), nil
+}
+
+// nowBuiltin returns the time of the debugger's clock, formatted in RFC3339
+// so that it can be compared with values of type time.Time.
+func (scope *EvalScope) nowBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments to now: %d", len(args))
+	}
+	return newConstant(constant.MakeString(time.Now().Format(time.RFC3339Nano)), scope.Mem), nil
+}
+
+// hitcountBuiltin returns the number of times the breakpoint whose
+// condition is being evaluated, or the breakpoint the current goroutine is
+// stopped at, has been reached, including the current one, regardless of
+// its condition.
+func (scope *EvalScope) hitcountBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments to hitcount: %d", len(args))
+	}
+	bp := scope.bp
+	if bp == nil && scope.g != nil && scope.g.Thread != nil {
+		if bpstate := scope.g.Thread.Breakpoint(); bpstate != nil {
+			bp = bpstate.Breakpoint
+		}
+	}
+	if bp == nil {
+		return nil, errors.New("hitcount can only be used at a breakpoint")
+	}
+	return newConstant(constant.MakeUint64(bp.reachCount), scope.Mem), nil
+}
+
+// goidBuiltin returns the ID of the current goroutine, or 0 if there is
+// no current goroutine.
+func (scope *EvalScope) goidBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments to goid: %d", len(args))
+	}
+	goid := 0
+	if scope.g != nil {
+		goid = scope.g.ID
+	}
+	return newConstant(constant.MakeInt64(int64(goid)), scope.Mem), nil
+}
+
+// labelBuiltin returns the value of the pprof label of the current
+// goroutine with the given key, or an empty string.
+func (scope *EvalScope) labelBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments to label: %d", len(args))
+	}
+	key := args[0]
+	key.loadValue(loadSingleValue)
+	if key.Unreadable != nil {
+		return nil, key.Unreadable
+	}
+	if key.Value == nil || key.Value.Kind() != constant.String {
+		return nil, fmt.Errorf("invalid argument %s (type %s) to label", exprToString(nodeargs[0]), key.TypeString())
+	}
+	value := ""
+	if scope.g != nil {
+		value = scope.g.Labels()[constant.StringVal(key.Value)]
+	}
+	return newConstant(constant.MakeString(value), scope.Mem), nil
+}
+
 // Evaluates identifier expressions
 func (scope *EvalScope) evalIdent(node *ast.Ident) (*Variable, error) {
 	switch node.Name {
@@ -1593,6 +1823,14 @@ func (scope *EvalScope) evalBinary(node *ast.BinaryExpr) (*Variable, error) {
 		return nil, errOperationOnSpecialFloat
 	}
 
+	xv, yv, err = convertDurationLiterals(xv, yv)
+	if err != nil {
+		return nil, err
+	}
+	if r, err := evalTimeCompare(node.Op, xv, yv); r != nil || err != nil {
+		return r, err
+	}
+
 	typ, err := negotiateType(node.Op, xv, yv)
 	if err != nil {
 		return nil, err
@@ -1962,8 +2200,23 @@ func (v *Variable) mapAccess(idx *Variable) (*Variable, error) {
 
 // LoadResliced returns a new array, slice or map that starts at index start and contains
 // up to cfg.MaxArrayValues children.
+// For strings it returns the substring that starts at byte start and contains up to
+// cfg.MaxStringLen bytes, for channels an array of up to cfg.MaxArrayValues of the
+// values buffered in the channel, in the order they will be received, starting with
+// the start-th value.
 func (v *Variable) LoadResliced(start int, cfg LoadConfig) (newV *Variable, err error) {
 	switch v.Kind {
+	case reflect.String:
+		low, high := int64(start), int64(start+cfg.MaxStringLen)
+		if high > v.Len {
+			high = v.Len
+		}
+		newV, err = v.reslice(low, high)
+		if err != nil {
+			return nil, err
+		}
+	case reflect.Chan:
+		return v.chanBuffer(start, cfg.MaxArrayValues, cfg)
 	case reflect.Array, reflect.Slice:
 		low, high := int64(start), int64(start+cfg.MaxArrayValues)
 		if high > v.Len {
@@ -1979,7 +2232,7 @@ func (v *Variable) LoadResliced(start int, cfg LoadConfig) (newV *Variable, err
 		newV.loaded = false
 		newV.mapSkip = start
 	default:
-		return nil, fmt.Errorf("variable to reslice is not an array, slice, or map")
+		return nil, fmt.Errorf("variable to reslice is not an array, slice, map, string or channel")
 	}
 	newV.loadValue(cfg)
 	return newV, nil
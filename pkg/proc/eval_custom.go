@@ -0,0 +1,120 @@
+package proc
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+)
+
+// CustomBuiltin is a function, registered by a program embedding Delve,
+// that can be called in expressions like the builtin functions.
+type CustomBuiltin struct {
+	// Fn is called with the arguments of the call, evaluated in scope and
+	// loaded using LoadConfig.
+	Fn func(scope *EvalScope, args []*Variable) (*Variable, error)
+	// LoadConfig is used to load the arguments before calling Fn.
+	LoadConfig LoadConfig
+}
+
+// reservedBuiltinNames are the names that can not be used for custom
+// builtins: the predeclared identifiers of Go and the builtins implemented
+// by the expression evaluator.
+var reservedBuiltinNames = map[string]bool{
+	// Go predeclared identifiers
+	"any": true, "bool": true, "byte": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true,
+	"int16": true, "int32": true, "int64": true, "rune": true, "string": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"uintptr": true, "true": true, "false": true, "iota": true, "nil": true,
+	"append": true, "cap": true, "close": true, "complex": true, "copy": true,
+	"delete": true, "imag": true, "len": true, "make": true, "new": true,
+	"panic": true, "print": true, "println": true, "real": true, "recover": true,
+
+	// Delve builtins
+	"callerin": true, "now": true, "hitcount": true, "goid": true, "label": true,
+}
+
+// RegisterBuiltin makes b callable in the expressions evaluated on t as
+// name. Returns an error if name is not a valid identifier, is already
+// registered or is reserved by the Go language or by Delve.
+// Like the now, hitcount, goid and label builtins custom builtins are
+// shadowed by the local variables and the package variables of the current
+// function with the same name.
+func (t *Target) RegisterBuiltin(name string, b CustomBuiltin) error {
+	if !token.IsIdentifier(name) {
+		return fmt.Errorf("invalid builtin name %q", name)
+	}
+	if reservedBuiltinNames[name] {
+		return fmt.Errorf("can not register builtin %q: name is reserved", name)
+	}
+	if _, exists := t.customBuiltins[name]; exists {
+		return fmt.Errorf("builtin %q already registered", name)
+	}
+	if b.Fn == nil {
+		return fmt.Errorf("builtin %q has no implementation", name)
+	}
+	if t.customBuiltins == nil {
+		t.customBuiltins = make(map[string]CustomBuiltin)
+	}
+	t.customBuiltins[name] = b
+	return nil
+}
+
+// UnregisterBuiltin removes the custom builtin name, registered by
+// RegisterBuiltin.
+func (t *Target) UnregisterBuiltin(name string) error {
+	if _, exists := t.customBuiltins[name]; !exists {
+		return fmt.Errorf("builtin %q not registered", name)
+	}
+	delete(t.customBuiltins, name)
+	return nil
+}
+
+// CustomBuiltins returns the custom builtins registered on t, indexed by
+// name.
+func (t *Target) CustomBuiltins() map[string]CustomBuiltin {
+	r := make(map[string]CustomBuiltin, len(t.customBuiltins))
+	for name, b := range t.customBuiltins {
+		r[name] = b
+	}
+	return r
+}
+
+// NewConstant returns a variable holding val, to be used as the return
+// value of custom builtins.
+func NewConstant(val constant.Value, mem MemoryReadWriter) *Variable {
+	return newConstant(val, mem)
+}
+
+// evalCustomBuiltinCall evaluates a call to a custom builtin. Returns nil
+// if node is not a call to a custom builtin.
+func (scope *EvalScope) evalCustomBuiltinCall(node *ast.CallExpr, name string) (*Variable, error) {
+	if scope.target == nil {
+		return nil, nil
+	}
+	b, ok := scope.target.customBuiltins[name]
+	if !ok || scope.symbolExists(name) {
+		return nil, nil
+	}
+	args := make([]*Variable, len(node.Args))
+	for i := range node.Args {
+		v, err := scope.evalAST(node.Args[i])
+		if err != nil {
+			return nil, err
+		}
+		v.loadValue(b.LoadConfig)
+		if v.Unreadable != nil {
+			return nil, v.Unreadable
+		}
+		args[i] = v
+	}
+	r, err := b.Fn(scope, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	if r == nil {
+		return nil, fmt.Errorf("%s: no return value", name)
+	}
+	return r, nil
+}
@@ -0,0 +1,320 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"reflect"
+	"time"
+)
+
+// Expressions involving time.Time and time.Duration are common in
+// breakpoint conditions, for example:
+//
+//	elapsed > 500*time.Millisecond
+//	elapsed > "1.5s"
+//	deadline.Before(now)
+//	deadline < "2021-06-01T00:00:00Z"
+//
+// The functions in this file evaluate them without injecting function
+// calls into the target: string literals are parsed as durations or as
+// RFC3339 timestamps, values of time.Time can be ordered and a few of
+// their methods, and of the methods of time.Duration, are implemented
+// by the debugger.
+
+const (
+	timeTimeTypeName     = "time.Time"
+	timeDurationTypeName = "time.Duration"
+)
+
+// Constants used by the runtime representation of time.Time, see
+// $GOROOT/src/time/time.go.
+const (
+	timeHasMonotonic  = 1 << 63
+	timeNsecMask      = 1<<30 - 1
+	timeNsecShift     = 30
+	timeSecondsPerDay = 86400
+	// seconds between January 1st of year 1 and January 1st 1885
+	timeWallToInternal int64 = (1884*365 + 1884/4 - 1884/100 + 1884/400) * timeSecondsPerDay
+	// seconds between January 1st of year 1 and January 1st 1970
+	timeUnixToInternal int64 = (1969*365 + 1969/4 - 1969/100 + 1969/400) * timeSecondsPerDay
+)
+
+// timeLiteralLayouts are the formats accepted for string literals
+// compared with values of time.Time.
+var timeLiteralLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+func (v *Variable) isTimeTime() bool {
+	return v.Kind == reflect.Struct && v.DwarfType != nil && v.DwarfType.String() == timeTimeTypeName
+}
+
+func (v *Variable) isTimeDuration() bool {
+	return v.Kind == reflect.Int64 && v.DwarfType != nil && v.DwarfType.String() == timeDurationTypeName
+}
+
+// isStringLiteral returns true if v is an untyped string constant.
+func (v *Variable) isStringLiteral() bool {
+	return v.DwarfType == nil && v.Value != nil && v.Value.Kind() == constant.String
+}
+
+// timeValue returns the instant represented by v, a variable of type
+// time.Time, or by a string literal. The location of v is not used.
+func (v *Variable) timeValue() (time.Time, error) {
+	if v.isStringLiteral() {
+		s := constant.StringVal(v.Value)
+		for _, layout := range timeLiteralLayouts {
+			if t, err := time.Parse(layout, s); err == nil {
+				return t, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("can not convert %q to %s, use the RFC3339 format", s, timeTimeTypeName)
+	}
+	if !v.isTimeTime() {
+		return time.Time{}, fmt.Errorf("can not convert %s to %s", v.TypeString(), timeTimeTypeName)
+	}
+	loadInt := func(name string) (uint64, bool) {
+		f := v.loadFieldNamed(name)
+		if f == nil || f.Value == nil || f.Value.Kind() != constant.Int {
+			return 0, false
+		}
+		if n, ok := constant.Uint64Val(f.Value); ok {
+			return n, true
+		}
+		n, _ := constant.Int64Val(f.Value)
+		return uint64(n), true
+	}
+	var sec int64
+	var nsec int64
+	if wall, ok := loadInt("wall"); ok {
+		ext, ok := loadInt("ext")
+		if !ok {
+			return time.Time{}, errors.New("could not read time.Time")
+		}
+		// Go 1.9 and later
+		nsec = int64(wall & timeNsecMask)
+		if wall&timeHasMonotonic != 0 {
+			sec = timeWallToInternal + int64(wall<<1>>(timeNsecShift+1))
+		} else {
+			sec = int64(ext)
+		}
+	} else if s, ok := loadInt("sec"); ok {
+		n, ok := loadInt("nsec")
+		if !ok {
+			return time.Time{}, errors.New("could not read time.Time")
+		}
+		sec, nsec = int64(s), int64(n)
+	} else {
+		return time.Time{}, errors.New("could not read time.Time")
+	}
+	return time.Unix(sec-timeUnixToInternal, nsec).UTC(), nil
+}
+
+// parseDurationLiteral parses v, a string literal, as a time.Duration.
+func parseDurationLiteral(v *Variable) (time.Duration, error) {
+	s := constant.StringVal(v.Value)
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("can not convert %q to %s: %v", s, timeDurationTypeName, err)
+	}
+	return d, nil
+}
+
+// convertDurationLiterals replaces a string literal operand of a binary
+// expression with the duration it represents if the other operand has type
+// time.Duration.
+func convertDurationLiterals(xv, yv *Variable) (*Variable, *Variable, error) {
+	convert := func(lit, other *Variable) (*Variable, error) {
+		if !lit.isStringLiteral() || !other.isTimeDuration() {
+			return lit, nil
+		}
+		d, err := parseDurationLiteral(lit)
+		if err != nil {
+			return nil, err
+		}
+		return newConstant(constant.MakeInt64(int64(d)), lit.mem), nil
+	}
+	var err error
+	xv, err = convert(xv, yv)
+	if err != nil {
+		return nil, nil, err
+	}
+	yv, err = convert(yv, xv)
+	if err != nil {
+		return nil, nil, err
+	}
+	return xv, yv, nil
+}
+
+// evalTimeCompare compares the instants represented by xv and yv if one of
+// them has type time.Time and the other one has type time.Time or is a
+// string literal. Two values of type time.Time are compared for equality
+// as structs, like the Go language does, but can also be ordered.
+// Returns nil if the expression does not involve time.Time.
+func evalTimeCompare(op token.Token, xv, yv *Variable) (*Variable, error) {
+	switch op {
+	case token.EQL, token.NEQ:
+		if xv.isTimeTime() && yv.isTimeTime() {
+			return nil, nil
+		}
+	case token.LSS, token.GTR, token.LEQ, token.GEQ:
+	default:
+		return nil, nil
+	}
+	if !(xv.isTimeTime() && (yv.isTimeTime() || yv.isStringLiteral())) && !(yv.isTimeTime() && xv.isStringLiteral()) {
+		return nil, nil
+	}
+	xt, err := xv.timeValue()
+	if err != nil {
+		return nil, err
+	}
+	yt, err := yv.timeValue()
+	if err != nil {
+		return nil, err
+	}
+	var r bool
+	switch op {
+	case token.EQL:
+		r = xt.Equal(yt)
+	case token.NEQ:
+		r = !xt.Equal(yt)
+	case token.LSS:
+		r = xt.Before(yt)
+	case token.GTR:
+		r = xt.After(yt)
+	case token.LEQ:
+		r = !xt.After(yt)
+	case token.GEQ:
+		r = !xt.Before(yt)
+	}
+	return newConstant(constant.MakeBool(r), xv.mem), nil
+}
+
+// evalTimeMethodCall evaluates calls to some of the methods of time.Time
+// and time.Duration. Returns nil if node is not a call to one of them.
+func (scope *EvalScope) evalTimeMethodCall(node *ast.CallExpr) (*Variable, error) {
+	sel, ok := node.Fun.(*ast.SelectorExpr)
+	if !ok || hasCallExpr(sel.X) {
+		// Do not evaluate the receiver twice if it could inject a function call.
+		return nil, nil
+	}
+	switch sel.Sel.Name {
+	case "Before", "After", "Equal", "Sub", "IsZero", "Unix", "UnixNano",
+		"Hours", "Minutes", "Seconds", "Milliseconds", "Microseconds", "Nanoseconds", "String":
+	default:
+		return nil, nil
+	}
+	recv, err := scope.evalAST(sel.X)
+	if err != nil {
+		return nil, nil
+	}
+	switch {
+	case recv.isTimeTime():
+		return scope.evalTimeTimeMethod(recv, sel.Sel.Name, node)
+	case recv.isTimeDuration():
+		return evalTimeDurationMethod(recv, sel.Sel.Name, node)
+	}
+	return nil, nil
+}
+
+func (scope *EvalScope) evalTimeTimeMethod(recv *Variable, name string, node *ast.CallExpr) (*Variable, error) {
+	t, err := recv.timeValue()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "IsZero":
+		return timeMethodResult(node, constant.MakeBool(t.IsZero()), recv.mem)
+	case "Unix":
+		return timeMethodResult(node, constant.MakeInt64(t.Unix()), recv.mem)
+	case "UnixNano":
+		return timeMethodResult(node, constant.MakeInt64(t.UnixNano()), recv.mem)
+	case "Before", "After", "Equal", "Sub":
+		// see below
+	default:
+		return nil, nil
+	}
+	if len(node.Args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments to %s.%s: %d", timeTimeTypeName, name, len(node.Args))
+	}
+	argv, err := scope.evalAST(node.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	argv.loadValue(loadSingleValue)
+	if argv.Unreadable != nil {
+		return nil, argv.Unreadable
+	}
+	u, err := argv.timeValue()
+	if err != nil {
+		return nil, err
+	}
+	switch name {
+	case "Before":
+		return newConstant(constant.MakeBool(t.Before(u)), recv.mem), nil
+	case "After":
+		return newConstant(constant.MakeBool(t.After(u)), recv.mem), nil
+	case "Equal":
+		return newConstant(constant.MakeBool(t.Equal(u)), recv.mem), nil
+	default: // Sub
+		typ, err := scope.BinInfo.findType(timeDurationTypeName)
+		if err != nil {
+			return newConstant(constant.MakeInt64(int64(t.Sub(u))), recv.mem), nil
+		}
+		r := newVariable("", 0, typ, scope.BinInfo, scope.Mem)
+		r.Value = constant.MakeInt64(int64(t.Sub(u)))
+		r.loaded = true
+		return r, nil
+	}
+}
+
+func evalTimeDurationMethod(recv *Variable, name string, node *ast.CallExpr) (*Variable, error) {
+	recv.loadValue(loadSingleValue)
+	if recv.Unreadable != nil {
+		return nil, recv.Unreadable
+	}
+	n, _ := constant.Int64Val(recv.Value)
+	d := time.Duration(n)
+	var r constant.Value
+	switch name {
+	case "Hours":
+		r = constant.MakeFloat64(d.Hours())
+	case "Minutes":
+		r = constant.MakeFloat64(d.Minutes())
+	case "Seconds":
+		r = constant.MakeFloat64(d.Seconds())
+	case "Milliseconds":
+		r = constant.MakeInt64(int64(d / time.Millisecond))
+	case "Microseconds":
+		r = constant.MakeInt64(int64(d / time.Microsecond))
+	case "Nanoseconds":
+		r = constant.MakeInt64(int64(d))
+	case "String":
+		r = constant.MakeString(d.String())
+	default:
+		return nil, nil
+	}
+	return timeMethodResult(node, r, recv.mem)
+}
+
+// timeMethodResult returns a constant with value r, checking that the
+// method call in node has no arguments.
+func timeMethodResult(node *ast.CallExpr, r constant.Value, mem MemoryReadWriter) (*Variable, error) {
+	if len(node.Args) != 0 {
+		return nil, fmt.Errorf("wrong number of arguments to %s: %d", exprToString(node.Fun), len(node.Args))
+	}
+	return newConstant(r, mem), nil
+}
+
+// hasCallExpr returns true if expr contains a call expression.
+func hasCallExpr(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if _, ok := n.(*ast.CallExpr); ok {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
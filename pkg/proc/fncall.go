@@ -1,8 +1,8 @@
 package proc
 
 import (
+	"bytes"
 	"debug/dwarf"
-	"encoding/binary"
 	"errors"
 	"fmt"
 	"go/ast"
@@ -62,6 +62,7 @@ var (
 	errNotAGoFunction             = errors.New("not a Go function")
 	errFuncCallNotAllowed         = errors.New("function calls not allowed without using 'call'")
 	errFuncCallNotAllowedStrAlloc = errors.New("literal string can not be allocated because function calls are not allowed without using 'call'")
+	errFuncCallCancelled          = errors.New("function call cancelled")
 )
 
 type functionCallState struct {
@@ -117,6 +118,14 @@ type callContext struct {
 	// stacks is a slice of known goroutine stacks used to check for
 	// inappropriate escapes
 	stacks []stack
+
+	// cancelled is true if the user abandoned the evaluation, see
+	// (*Target).CancelCall.
+	cancelled bool
+
+	// vars contains all the values computed while evaluating the expression,
+	// their addresses are updated by relocateStack.
+	vars []*Variable
 }
 
 type continueRequest struct {
@@ -132,6 +141,7 @@ type callInjection struct {
 	continueCompleted chan<- *G
 	continueRequest   <-chan continueRequest
 	startThreadID     int
+	callCtx           *callContext
 }
 
 func (callCtx *callContext) doContinue() *G {
@@ -139,6 +149,45 @@ func (callCtx *callContext) doContinue() *G {
 	return <-callCtx.continueCompleted
 }
 
+// relocateStack is called when an injected function call moved the stack
+// of the goroutine used for evaluation from oldstack to newstack.
+// The runtime adjusts the pointers into the stack that are stored in
+// memory, relocateStack does the same for the values that Delve computed
+// while evaluating the expression up to this point, so that evaluating the
+// rest of the expression doesn't read from the old stack.
+func (callCtx *callContext) relocateStack(oldstack, newstack stack) {
+	fncallLog("stack moved from %#x-%#x to %#x-%#x", oldstack.lo, oldstack.hi, newstack.lo, newstack.hi)
+	delta := newstack.hi - oldstack.hi
+	seen := make(map[*Variable]bool)
+	for _, v := range callCtx.vars {
+		v.relocateStack(oldstack, delta, seen)
+	}
+}
+
+// relocateStack adds delta to the addresses of v, and its children, that
+// point inside oldstack.
+func (v *Variable) relocateStack(oldstack stack, delta uint64, seen map[*Variable]bool) {
+	if seen[v] || v.Flags&VariableFakeAddress != 0 {
+		return
+	}
+	seen[v] = true
+	inOldStack := func(addr uint64) bool {
+		return addr >= oldstack.lo && addr < oldstack.hi
+	}
+	if inOldStack(v.Addr) {
+		v.Addr += delta
+	}
+	if inOldStack(v.Base) {
+		v.Base += delta
+	}
+	if inOldStack(v.closureAddr) {
+		v.closureAddr += delta
+	}
+	for i := range v.Children {
+		v.Children[i].relocateStack(oldstack, delta, seen)
+	}
+}
+
 func (callCtx *callContext) doReturn(ret *Variable, err error) {
 	if callCtx == nil {
 		return
@@ -152,19 +201,35 @@ func (callCtx *callContext) doReturn(ret *Variable, err error) {
 func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig, checkEscape bool) error {
 	bi := t.BinInfo()
 	if !t.SupportsFunctionCalls() {
-		return errFuncCallUnsupportedBackend
+		return funcCallUnsupportedError(bi)
+	}
+	if err := t.checkSafety("function call"); err != nil {
+		return err
 	}
 
 	// check that the target goroutine is running
-	if g == nil {
+	if g == nil && !t.callHelper.enabled {
 		return errNoGoroutine
 	}
-	if g.Status != Grunning || g.Thread == nil {
-		return errGoroutineNotRunning
+	if g != nil {
+		if callinj := t.fncallForG[g.ID]; callinj != nil && callinj.continueCompleted != nil {
+			return errFuncCallInProgress
+		}
 	}
 
-	if callinj := t.fncallForG[g.ID]; callinj != nil && callinj.continueCompleted != nil {
-		return errFuncCallInProgress
+	switch {
+	case t.callHelper.enabled && (g == nil || !canHostCall(g)):
+		var err error
+		g, err = t.callHelperGoroutine()
+		if err != nil {
+			return err
+		}
+	case g.Status != Grunning || g.Thread == nil:
+		var err error
+		g, err = waitGoroutineThread(t, g)
+		if err != nil {
+			return err
+		}
 	}
 
 	dbgcallfn, _ := debugCallFunction(bi)
@@ -192,6 +257,7 @@ func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig
 		continueCompleted: continueCompleted,
 		continueRequest:   continueRequest,
 		startThreadID:     0,
+		callCtx:           scope.callCtx,
 	}
 
 	go scope.EvalExpression(expr, retLoadCfg)
@@ -204,7 +270,80 @@ func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig
 	return finishEvalExpressionWithCalls(t, g, contReq, ok)
 }
 
+// waitGoroutineThread resumes the target process until goroutine g, which
+// is not running on a thread, is scheduled and returns to the first
+// function of its stack that isn't part of the runtime, where a function
+// call can be injected.
+// Returns the goroutine g, now running on a thread. If the target stops for
+// any other reason before g is scheduled an error is returned.
+func waitGoroutineThread(t *Target, g *G) (*G, error) {
+	if t.Breakpoints().HasInternalBreakpoints() {
+		return nil, errGoroutineNotRunning
+	}
+	pc, err := firstUserFramePC(g)
+	if err != nil {
+		return nil, err
+	}
+	if pc == 0 {
+		return nil, fmt.Errorf("goroutine %d is not running and is not executing user code", g.ID)
+	}
+
+	fncallLog("goroutine %d not running, waiting for it to reach %#x", g.ID, pc)
+	if _, err := allowDuplicateBreakpoint(t.SetBreakpoint(pc, NextBreakpoint, sameGoroutineCondition(g))); err != nil {
+		return nil, err
+	}
+	if err := t.Continue(); err != nil {
+		t.ClearInternalBreakpoints()
+		return nil, err
+	}
+	if t.StopReason != StopNextFinished {
+		t.ClearInternalBreakpoints()
+		return nil, fmt.Errorf("target stopped (%s) before goroutine %d was scheduled, the function was not called", t.StopReason, g.ID)
+	}
+	g2 := t.SelectedGoroutine()
+	if g2 == nil || g2.ID != g.ID || g2.Thread == nil {
+		return nil, fmt.Errorf("target stopped before goroutine %d was scheduled, the function was not called", g.ID)
+	}
+	return g2, nil
+}
+
+// firstUserFramePC returns the PC of the first frame of the stack of g
+// that isn't part of the runtime, 0 if there isn't one.
+func firstUserFramePC(g *G) (uint64, error) {
+	frames, err := g.Stacktrace(maxGoroutineUserCurrentDepth, 0)
+	if err != nil {
+		return 0, err
+	}
+	for _, frame := range frames {
+		if frame.Call.Fn != nil && !strings.HasPrefix(frame.Call.Fn.Name, "runtime.") {
+			return frame.Current.PC, nil
+		}
+	}
+	return 0, nil
+}
+
+// canHostCall returns false if a function call can not be injected in
+// goroutine g, because it is stopped inside the runtime, or because it is
+// not running and it isn't executing user code.
+func canHostCall(g *G) bool {
+	if g.Status != Grunning || g.Thread == nil {
+		pc, err := firstUserFramePC(g)
+		return err != nil || pc != 0
+	}
+	if g.SystemStack {
+		return false
+	}
+	loc, err := g.Thread.Location()
+	return err != nil || loc.Fn == nil || !strings.HasPrefix(loc.Fn.Name, "runtime.")
+}
+
 func finishEvalExpressionWithCalls(t *Target, g *G, contReq continueRequest, ok bool) error {
+	if t.fncallForG[g.ID].callCtx.cancelled {
+		fncallLog("cancelled function call completed on %d in thread=%d", g.ID, g.Thread.ThreadID())
+		close(t.fncallForG[g.ID].continueCompleted)
+		delete(t.fncallForG, g.ID)
+		return nil
+	}
 	fncallLog("stashing return values for %d in thread=%d", g.ID, g.Thread.ThreadID())
 	g.Thread.Common().CallReturn = true
 	var err error
@@ -249,9 +388,18 @@ func evalFunctionCall(scope *EvalScope, node *ast.CallExpr) (*Variable, error) {
 		// it was a builtin call
 		return r, err
 	}
+	r, err = scope.evalTimeMethodCall(node)
+	if r != nil || err != nil {
+		// it was a method of time.Time or time.Duration evaluated without
+		// injecting a function call
+		return r, err
+	}
 	if scope.callCtx == nil {
 		return nil, errFuncCallNotAllowed
 	}
+	if scope.callCtx.cancelled {
+		return nil, errFuncCallCancelled
+	}
 	thread := scope.g.Thread
 	stacklo := scope.g.stack.lo
 	if thread == nil {
@@ -273,7 +421,7 @@ func evalFunctionCall(scope *EvalScope, node *ast.CallExpr) (*Variable, error) {
 	p := scope.callCtx.p
 	bi := scope.BinInfo
 	if !p.SupportsFunctionCalls() {
-		return nil, errFuncCallUnsupportedBackend
+		return nil, funcCallUnsupportedError(bi)
 	}
 
 	dbgcallfn, dbgcallversion := debugCallFunction(bi)
@@ -293,7 +441,7 @@ func evalFunctionCall(scope *EvalScope, node *ast.CallExpr) (*Variable, error) {
 	if regs.SP()-256 <= stacklo {
 		return nil, errNotEnoughStack
 	}
-	protocolReg, ok := debugCallProtocolReg(dbgcallversion)
+	protocolReg, ok := debugCallProtocolReg(bi.Arch.Name, dbgcallversion)
 	if !ok {
 		return nil, errFuncCallUnsupported
 	}
@@ -311,12 +459,46 @@ func evalFunctionCall(scope *EvalScope, node *ast.CallExpr) (*Variable, error) {
 		return nil, err
 	}
 
-	if err := callOP(bi, thread, regs, dbgcallfn.Entry); err != nil {
-		return nil, err
-	}
-	// write the desired argument frame size at SP-(2*pointer_size) (the extra pointer is the saved PC)
-	if err := writePointer(bi, scope.Mem, regs.SP()-3*uint64(bi.Arch.PtrSize()), uint64(fncall.argFrameSize)); err != nil {
-		return nil, err
+	switch bi.Arch.Name {
+	case "amd64":
+		if err := callOP(bi, thread, regs, dbgcallfn.Entry); err != nil {
+			return nil, err
+		}
+		// write the desired argument frame size at SP-(2*pointer_size) (the extra pointer is the saved PC)
+		if err := writePointer(bi, scope.Mem, regs.SP()-3*uint64(bi.Arch.PtrSize()), uint64(fncall.argFrameSize)); err != nil {
+			return nil, err
+		}
+	case "loong64":
+		// debugCallV2 on loong64 needs a special call sequence, callOP can not
+		// be used: the old value of LR is pushed on the stack (debugCallV2 pops
+		// it before returning), LR is set to the current PC and the desired
+		// argument frame size is written right below the saved LR.
+		sp := regs.SP() - uint64(bi.Arch.PtrSize())
+		if err := setSP(thread, sp); err != nil {
+			return nil, err
+		}
+		if err := writePointer(bi, scope.Mem, sp, bi.Arch.RegistersToDwarfRegisters(0, regs).Uint64Val(regnum.LOONG64_LR)); err != nil {
+			return nil, err
+		}
+		if err := setLR(thread, regs.PC()); err != nil {
+			return nil, err
+		}
+		if err := writePointer(bi, scope.Mem, sp-uint64(bi.Arch.PtrSize()), uint64(fncall.argFrameSize)); err != nil {
+			return nil, err
+		}
+		// the registers restored at the end of the protocol must include the
+		// new value of LR, which debugCallV2 uses to return to the current PC.
+		regs, err = thread.Registers()
+		if err != nil {
+			return nil, err
+		}
+		fncall.savedRegs, err = regs.Copy()
+		if err != nil {
+			return nil, err
+		}
+		if err := setPC(thread, dbgcallfn.Entry); err != nil {
+			return nil, err
+		}
 	}
 
 	fncallLog("function call initiated %v frame size %d goroutine %d (thread %d)", fncall.fn, fncall.argFrameSize, scope.g.ID, thread.ThreadID())
@@ -327,6 +509,7 @@ func evalFunctionCall(scope *EvalScope, node *ast.CallExpr) (*Variable, error) {
 	spoff := int64(scope.Regs.Uint64Val(scope.Regs.SPRegNum)) - int64(scope.g.stack.hi)
 	bpoff := int64(scope.Regs.Uint64Val(scope.Regs.BPRegNum)) - int64(scope.g.stack.hi)
 	fboff := scope.Regs.FrameBase - int64(scope.g.stack.hi)
+	oldstack := scope.g.stack
 
 	for {
 		scope.callCtx.injectionThread = nil
@@ -344,6 +527,11 @@ func evalFunctionCall(scope *EvalScope, node *ast.CallExpr) (*Variable, error) {
 			scope.callCtx.injectionThread = g.Thread
 		}
 
+		if scope.g.stack != oldstack {
+			scope.callCtx.relocateStack(oldstack, scope.g.stack)
+			oldstack = scope.g.stack
+		}
+
 		// adjust the value of registers inside scope
 		pcreg, bpreg, spreg := scope.Regs.Reg(scope.Regs.PCRegNum), scope.Regs.Reg(scope.Regs.BPRegNum), scope.Regs.Reg(scope.Regs.SPRegNum)
 		scope.Regs.ClearRegisters()
@@ -405,12 +593,11 @@ func fncallLog(fmtstr string, args ...interface{}) {
 func writePointer(bi *BinaryInfo, mem MemoryReadWriter, addr, val uint64) error {
 	ptrbuf := make([]byte, bi.Arch.PtrSize())
 
-	// TODO: use target architecture endianness instead of LittleEndian
 	switch len(ptrbuf) {
 	case 4:
-		binary.LittleEndian.PutUint32(ptrbuf, uint32(val))
+		bi.Arch.ByteOrder().PutUint32(ptrbuf, uint32(val))
 	case 8:
-		binary.LittleEndian.PutUint64(ptrbuf, val)
+		bi.Arch.ByteOrder().PutUint64(ptrbuf, val)
 	default:
 		panic(fmt.Errorf("unsupported pointer size %d", len(ptrbuf)))
 	}
@@ -420,9 +607,16 @@ func writePointer(bi *BinaryInfo, mem MemoryReadWriter, addr, val uint64) error
 
 // callOP simulates a call instruction on the given thread:
 // * pushes the current value of PC on the stack (adjusting SP)
+// * or, on architectures with a link register, copies PC into it instead
 // * changes the value of PC to callAddr
 // Note: regs are NOT updated!
 func callOP(bi *BinaryInfo, thread Thread, regs Registers, callAddr uint64) error {
+	if bi.Arch.usesLR {
+		if err := setLR(thread, regs.PC()); err != nil {
+			return err
+		}
+		return setPC(thread, callAddr)
+	}
 	sp := regs.SP()
 	// push PC on the stack
 	sp -= uint64(bi.Arch.PtrSize())
@@ -560,9 +754,6 @@ func funcCallCopyOneArg(scope *EvalScope, fncall *functionCallState, actualArg *
 		}
 	}
 
-	//TODO(aarzilli): autmoatic wrapping in interfaces for cases not handled
-	// by convertToEface.
-
 	var formalArgVar *Variable
 	if formalArg.dwarfEntry != nil {
 		var err error
@@ -573,6 +764,12 @@ func funcCallCopyOneArg(scope *EvalScope, fncall *functionCallState, actualArg *
 	} else {
 		formalArgVar = newVariable(formalArg.name, uint64(formalArg.off+int64(formalScope.Regs.CFA)), formalArg.typ, scope.BinInfo, scope.Mem)
 	}
+	if needsBoxing(formalArgVar, actualArg) {
+		if err := funcCallBoxArg(scope, formalArgVar, actualArg); err != nil {
+			return fmt.Errorf("cannot use %s as argument %s in function %s: %v", actualArg.Name, formalArg.name, fncall.fn.Name, err)
+		}
+		return nil
+	}
 	if err := scope.setValue(formalArgVar, actualArg, actualArg.Name); err != nil {
 		return err
 	}
@@ -580,6 +777,193 @@ func funcCallCopyOneArg(scope *EvalScope, fncall *functionCallState, actualArg *
 	return nil
 }
 
+// needsBoxing returns true if actualArg is a value of concrete type that
+// must be converted to an interface to be assigned to formalArgVar.
+func needsBoxing(formalArgVar, actualArg *Variable) bool {
+	if _, isiface := formalArgVar.RealType.(*godwarf.InterfaceType); !isiface {
+		return false
+	}
+	if actualArg == nilVariable {
+		return false
+	}
+	if actualArg.RealType == nil {
+		// untyped constant
+		return actualArg.Value != nil
+	}
+	_, isiface := actualArg.RealType.(*godwarf.InterfaceType)
+	return !isiface
+}
+
+// funcCallBoxArg converts actualArg to the interface type of formalArgVar
+// and writes the result to formalArgVar.
+// Values that are not pointer shaped are copied to newly allocated memory,
+// untyped constants are given their default type.
+// Conversions to a non-empty interface are only possible if the itab for
+// the pair of types already exists in the target process.
+func funcCallBoxArg(scope *EvalScope, formalArgVar, actualArg *Variable) error {
+	bi := scope.BinInfo
+	typ := actualArg.DwarfType
+	if typ == nil {
+		var err error
+		typ, err = constantDefaultType(bi, actualArg)
+		if err != nil {
+			return err
+		}
+	}
+	typeAddr, typeKind, found, err := dwarfToRuntimeType(bi, scope.Mem, typ)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf("could not find runtime type of %s", typ)
+	}
+
+	var data uint64
+	if typeKind&kindDirectIface != 0 {
+		// pointer shaped values are stored directly in the data word
+		switch {
+		case actualArg.Addr != 0:
+			data, err = readUintRaw(actualArg.mem, actualArg.Addr, int64(bi.Arch.PtrSize()), bi.Arch.ByteOrder())
+			if err != nil {
+				return err
+			}
+		case actualArg.Kind == reflect.Ptr && len(actualArg.Children) == 1:
+			data = actualArg.Children[0].Addr
+		default:
+			return fmt.Errorf("can not convert unaddressable value of type %s to %s", typ, formalArgVar.DwarfType)
+		}
+	} else {
+		data, err = funcCallMalloc(scope, typ.Size(), typeAddr)
+		if err != nil {
+			return err
+		}
+		if err := scope.setValue(newVariable("", data, typ, bi, scope.Mem), actualArg, actualArg.Name); err != nil {
+			return err
+		}
+	}
+
+	tab := typeAddr
+	if formalArgVar.RealType.String() != "interface {}" {
+		tab, err = findItab(scope, formalArgVar.DwarfType, typeAddr)
+		if err != nil {
+			return err
+		}
+		if tab == 0 {
+			return fmt.Errorf("%s does not implement %s or the conversion is never performed by the program", typ, formalArgVar.DwarfType)
+		}
+	}
+
+	ityp := resolveTypedef(&formalArgVar.RealType.(*godwarf.InterfaceType).TypedefType).(*godwarf.StructType)
+	for _, f := range ityp.Field {
+		fv, err := formalArgVar.toField(f)
+		if err != nil {
+			return err
+		}
+		switch f.Name {
+		case "tab", "_type":
+			err = fv.writeUint(tab, fv.RealType.Size())
+		case "data":
+			err = fv.writeUint(data, fv.RealType.Size())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// constantDefaultType returns the default type of the untyped constant v.
+func constantDefaultType(bi *BinaryInfo, v *Variable) (godwarf.Type, error) {
+	var name string
+	switch v.Value.Kind() {
+	case constant.Bool:
+		name = "bool"
+	case constant.String:
+		name = "string"
+	case constant.Int:
+		name = "int"
+	case constant.Float:
+		name = "float64"
+	case constant.Complex:
+		name = "complex128"
+	default:
+		return nil, fmt.Errorf("can not convert %s constant to an interface", v.Value)
+	}
+	return bi.findType(name)
+}
+
+// findItab searches runtime.itabTable for the itab of the interface type
+// ityp and the concrete type at typeAddr, returns 0 if it does not exist.
+func findItab(scope *EvalScope, ityp godwarf.Type, typeAddr uint64) (uint64, error) {
+	bi := scope.BinInfo
+	interAddr, _, found, err := dwarfToRuntimeType(bi, scope.Mem, ityp)
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("could not find runtime type of %s", ityp)
+	}
+
+	itabTable, err := scope.findGlobal("runtime", "itabTable")
+	if err != nil {
+		return 0, err
+	}
+	itabTable = itabTable.maybeDereference()
+	sizev := itabTable.loadFieldNamed("size")
+	entries, err := itabTable.structMember("entries")
+	if err != nil {
+		return 0, err
+	}
+	if sizev == nil || sizev.Unreadable != nil {
+		return 0, errors.New("could not read runtime.itabTable")
+	}
+	size, _ := constant.Uint64Val(sizev.Value)
+
+	itabType, err := bi.findType("runtime.itab")
+	if err != nil {
+		return 0, err
+	}
+	var interOff, typeOff int64 = -1, -1
+	for _, f := range resolveTypedef(itabType).(*godwarf.StructType).Field {
+		switch f.Name {
+		case "inter":
+			interOff = f.ByteOffset
+		case "_type":
+			typeOff = f.ByteOffset
+		}
+	}
+	if interOff < 0 || typeOff < 0 {
+		return 0, errors.New("unsupported runtime.itab type")
+	}
+
+	ptrSize := int64(bi.Arch.PtrSize())
+	mem := cacheMemory(scope.Mem, entries.Addr, int(size)*int(ptrSize))
+	for i := uint64(0); i < size; i++ {
+		tab, err := readUintRaw(mem, entries.Addr+i*uint64(ptrSize), ptrSize, bi.Arch.ByteOrder())
+		if err != nil {
+			return 0, err
+		}
+		if tab == 0 {
+			continue
+		}
+		inter, err := readUintRaw(scope.Mem, tab+uint64(interOff), ptrSize, bi.Arch.ByteOrder())
+		if err != nil {
+			return 0, err
+		}
+		if inter != interAddr {
+			continue
+		}
+		_type, err := readUintRaw(scope.Mem, tab+uint64(typeOff), ptrSize, bi.Arch.ByteOrder())
+		if err != nil {
+			return 0, err
+		}
+		if _type == typeAddr {
+			return tab, nil
+		}
+	}
+	return 0, nil
+}
+
 func funcCallArgs(fn *Function, bi *BinaryInfo, includeRet bool) (argFrameSize int64, formalArgs []funcCallArg, err error) {
 	dwarfTree, err := fn.cu.image.getDwarfTree(fn.offset)
 	if err != nil {
@@ -589,14 +973,15 @@ func funcCallArgs(fn *Function, bi *BinaryInfo, includeRet bool) (argFrameSize i
 	producer := bi.Producer()
 	trustArgOrder := producer != "" && goversion.ProducerAfterOrEqual(bi.Producer(), 1, 12)
 
-	if bi.regabi && fn.cu.optimized && fn.Name != "runtime.mallocgc" {
+	if bi.regabi && fn.cu.optimized && !isRegabiRuntimeFunc(fn.Name) {
 		// Debug info for function arguments on optimized functions is currently
 		// too incomplete to attempt injecting calls to arbitrary optimized
 		// functions.
 		// Prior to regabi we could do this because the ABI was simple enough to
 		// manually encode it in Delve.
-		// Runtime.mallocgc is an exception, we specifically patch it's DIE to be
-		// correct for call injection purposes.
+		// Runtime.mallocgc and runtime.newobject are an exception, we
+		// specifically patch their DIEs to be correct for call injection
+		// purposes.
 		return 0, nil, fmt.Errorf("can not call optimized function %s when regabi is in use", fn.Name)
 	}
 
@@ -661,7 +1046,7 @@ func funcCallArgOldABI(fn *Function, bi *BinaryInfo, entry reader.Variable, argn
 		err = fmt.Errorf("could not get argument location of %s: %v", argname, err)
 	} else {
 		var pieces []op.Piece
-		off, pieces, err = op.ExecuteStackProgram(op.DwarfRegisters{CFA: CFA, FrameBase: CFA}, locprog, bi.Arch.PtrSize())
+		off, pieces, err = op.ExecuteStackProgram(op.DwarfRegisters{CFA: CFA, FrameBase: CFA, ByteOrder: bi.Arch.ByteOrder()}, locprog, bi.Arch.PtrSize())
 		if err != nil {
 			err = fmt.Errorf("unsupported location expression for argument %s: %v", argname, err)
 		}
@@ -777,6 +1162,25 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 
 	regval := bi.Arch.RegistersToDwarfRegisters(0, regs).Uint64Val(protocolReg)
 
+	if !bi.Arch.BreakInstrMovesPC() {
+		// The runtime expects execution to resume after the breakpoint
+		// instruction it used to stop, which on this architecture did not
+		// advance PC.
+		bpsize := bi.Arch.BreakpointSize()
+		bp := make([]byte, bpsize)
+		if _, err := p.Memory().ReadMemory(bp, regs.PC()); err == nil && bytes.Equal(bp, bi.Arch.BreakpointInstruction()) {
+			if err := setPC(thread, regs.PC()+uint64(bpsize)); err != nil {
+				fncall.err = err
+				return true
+			}
+			regs, err = thread.Registers()
+			if err != nil {
+				fncall.err = err
+				return true
+			}
+		}
+	}
+
 	if logflags.FnCall() {
 		loc, _ := thread.Location()
 		var pc uint64
@@ -803,6 +1207,13 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 
 	case debugCallRegCompleteCall:
 		p.fncallForG[callScope.g.ID].startThreadID = 0
+		if callScope.callCtx.cancelled {
+			// do not call the function, the runtime will proceed to restore the
+			// registers.
+			fncall.err = errFuncCallCancelled
+			fncall.lateCallFailure = true
+			break
+		}
 		// evaluate arguments of the target function, copy them into its argument frame and call the function
 		if fncall.fn == nil || fncall.receiver != nil || fncall.closureAddr != 0 {
 			// if we couldn't figure out which function we are calling before
@@ -833,6 +1244,11 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 		}
 		cfa := regs.SP()
 		oldpc := regs.PC()
+		var oldlr uint64
+		if bi.Arch.usesLR {
+			dregs := bi.Arch.RegistersToDwarfRegisters(0, regs)
+			oldlr = dregs.Uint64Val(dregs.LRRegNum)
+		}
 		callOP(bi, thread, regs, fncall.fn.Entry)
 		formalScope, err := GoroutineScope(callScope.target, thread)
 		if formalScope != nil && formalScope.Regs.CFA != int64(cfa) {
@@ -847,6 +1263,9 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 			// rolling back the call, note: this works because we called regs.Copy() above
 			setSP(thread, cfa)
 			setPC(thread, oldpc)
+			if bi.Arch.usesLR {
+				setLR(thread, oldlr)
+			}
 			fncall.err = err
 			fncall.lateCallFailure = true
 			break
@@ -871,8 +1290,21 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 		return true
 
 	case debugCallRegReadReturn:
+		if bi.Arch.usesLR {
+			// the runtime saved the value of LR at the top of the stack before
+			// calling the function and expects us to restore it
+			oldlr, err := readUintRaw(thread.ProcessMemory(), regs.SP(), int64(bi.Arch.PtrSize()), bi.Arch.ByteOrder())
+			if err == nil {
+				err = setLR(thread, oldlr)
+			}
+			if err != nil {
+				fncall.err = fmt.Errorf("could not restore LR: %v", err)
+				break
+			}
+		}
+
 		// read return arguments from stack
-		if fncall.panicvar != nil || fncall.lateCallFailure {
+		if fncall.panicvar != nil || fncall.lateCallFailure || callScope.callCtx.cancelled {
 			break
 		}
 		retScope, err := ThreadScope(p, thread)
@@ -882,7 +1314,11 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 		}
 
 		// pretend we are still inside the function we called
-		fakeFunctionEntryScope(retScope, fncall.fn, int64(regs.SP()), regs.SP()-uint64(bi.Arch.PtrSize()))
+		entrySP := regs.SP()
+		if !bi.Arch.usesLR {
+			entrySP -= uint64(bi.Arch.PtrSize())
+		}
+		fakeFunctionEntryScope(retScope, fncall.fn, int64(regs.SP()), entrySP)
 		retScope.trustArgOrder = !bi.regabi
 
 		fncall.retvars, err = retScope.Locals()
@@ -908,6 +1344,9 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 
 	case debugCallRegReadPanic:
 		// read panic value from stack
+		if callScope.callCtx.cancelled {
+			break
+		}
 		fncall.panicvar, err = readTopstackVariable(p, thread, regs, "interface {}", callScope.callCtx.retLoadCfg)
 		if err != nil {
 			fncall.err = fmt.Errorf("could not get panic: %v", err)
@@ -934,7 +1373,12 @@ func readTopstackVariable(t *Target, thread Thread, regs Registers, typename str
 	if err != nil {
 		return nil, err
 	}
-	v := newVariable("", regs.SP(), typ, scope.BinInfo, scope.Mem)
+	addr := regs.SP()
+	if bi.Arch.usesLR {
+		// the first word of the stack is reserved for the saved link register
+		addr += uint64(bi.Arch.PtrSize())
+	}
+	v := newVariable("", addr, typ, scope.BinInfo, scope.Mem)
 	v.loadValue(loadCfg)
 	if v.Unreadable != nil {
 		return nil, v.Unreadable
@@ -976,6 +1420,48 @@ func allocString(scope *EvalScope, v *Variable) error {
 	if scope.callCtx == nil {
 		return errFuncCallNotAllowedStrAlloc
 	}
+	var err error
+	v.Base, err = funcCallMalloc(scope, v.Len, 0)
+	if err != nil {
+		return err
+	}
+	_, err = scope.Mem.WriteMemory(v.Base, []byte(constant.StringVal(v.Value)))
+	return err
+}
+
+// funcCallMalloc allocates size bytes in the target process by calling
+// runtime.mallocgc. If typeAddr is not zero it is used as the address of
+// the runtime._type of the allocated object, so that the garbage collector
+// will scan it, and the memory is zeroed.
+func funcCallMalloc(scope *EvalScope, size int64, typeAddr uint64) (uint64, error) {
+	var typeArg, needzeroArg ast.Expr = &ast.Ident{Name: "nil"}, &ast.Ident{Name: "false"}
+	if typeAddr != 0 {
+		typeArg = runtimeTypeExpr(typeAddr)
+		needzeroArg = &ast.Ident{Name: "true"}
+	}
+	return funcCallAlloc(scope, "mallocgc", &ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(int(size))}, typeArg, needzeroArg)
+}
+
+// funcCallNewobject allocates a zeroed object, whose runtime._type is at
+// typeAddr, in the target process by calling runtime.newobject.
+func funcCallNewobject(scope *EvalScope, typeAddr uint64) (uint64, error) {
+	return funcCallAlloc(scope, "newobject", runtimeTypeExpr(typeAddr))
+}
+
+// runtimeTypeExpr returns the expression (*runtime._type)(typeAddr).
+func runtimeTypeExpr(typeAddr uint64) ast.Expr {
+	return &ast.CallExpr{
+		Fun: &ast.ParenExpr{X: &ast.StarExpr{X: &ast.SelectorExpr{
+			X:   &ast.Ident{Name: "runtime"},
+			Sel: &ast.Ident{Name: "_type"},
+		}}},
+		Args: []ast.Expr{&ast.BasicLit{Kind: token.INT, Value: strconv.FormatUint(typeAddr, 10)}},
+	}
+}
+
+// funcCallAlloc calls the allocation function runtime.<fnname> with args
+// and returns the address of the allocated memory.
+func funcCallAlloc(scope *EvalScope, fnname string, args ...ast.Expr) (uint64, error) {
 	savedLoadCfg := scope.callCtx.retLoadCfg
 	scope.callCtx.retLoadCfg = loadFullValue
 	defer func() {
@@ -984,29 +1470,23 @@ func allocString(scope *EvalScope, v *Variable) error {
 	mallocv, err := evalFunctionCall(scope, &ast.CallExpr{
 		Fun: &ast.SelectorExpr{
 			X:   &ast.Ident{Name: "runtime"},
-			Sel: &ast.Ident{Name: "mallocgc"},
-		},
-		Args: []ast.Expr{
-			&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(int(v.Len))},
-			&ast.Ident{Name: "nil"},
-			&ast.Ident{Name: "false"},
+			Sel: &ast.Ident{Name: fnname},
 		},
+		Args: args,
 	})
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if mallocv.Unreadable != nil {
-		return mallocv.Unreadable
+		return 0, mallocv.Unreadable
 	}
 	if mallocv.DwarfType.String() != "*void" {
-		return fmt.Errorf("unexpected return type for mallocgc call: %v", mallocv.DwarfType.String())
+		return 0, fmt.Errorf("unexpected return type for %s call: %v", fnname, mallocv.DwarfType.String())
 	}
 	if len(mallocv.Children) != 1 {
-		return errors.New("internal error, could not interpret return value of mallocgc call")
+		return 0, fmt.Errorf("internal error, could not interpret return value of %s call", fnname)
 	}
-	v.Base = mallocv.Children[0].Addr
-	_, err = scope.Mem.WriteMemory(v.Base, []byte(constant.StringVal(v.Value)))
-	return err
+	return mallocv.Children[0].Addr, nil
 }
 
 func isCallInjectionStop(t *Target, thread Thread, loc *Location) bool {
@@ -1029,11 +1509,12 @@ func isCallInjectionStop(t *Target, thread Thread, loc *Location) bool {
 
 // callInjectionProtocol is the function called from Continue to progress
 // the injection protocol for all threads.
-// Returns true if a call injection terminated
-func callInjectionProtocol(t *Target, threads []Thread) (done bool, err error) {
+// Returns true if a call injection terminated, the IDs of the threads that
+// progressed a cancelled call injection are added to cancelled.
+func callInjectionProtocol(t *Target, threads []Thread) (done bool, cancelled map[int]bool, err error) {
 	if len(t.fncallForG) == 0 {
 		// we aren't injecting any calls, no need to check the threads.
-		return false, nil
+		return false, nil, nil
 	}
 	for _, thread := range threads {
 		loc, err := thread.Location()
@@ -1046,21 +1527,60 @@ func callInjectionProtocol(t *Target, threads []Thread) (done bool, err error) {
 
 		g, callinj, err := findCallInjectionStateForThread(t, thread)
 		if err != nil {
-			return false, err
+			return false, cancelled, err
 		}
 
 		fncallLog("step for injection on goroutine %d (current) thread=%d (location %s)", g.ID, thread.ThreadID(), loc.Fn.Name)
+		isCancelled := callinj.callCtx.cancelled
+		if isCancelled {
+			if cancelled == nil {
+				cancelled = make(map[int]bool)
+			}
+			cancelled[thread.ThreadID()] = true
+		}
 		callinj.continueCompleted <- g
 		contReq, ok := <-callinj.continueRequest
 		if !contReq.cont {
 			err := finishEvalExpressionWithCalls(t, g, contReq, ok)
 			if err != nil {
-				return done, err
+				return done, cancelled, err
+			}
+			if !isCancelled {
+				done = true
 			}
-			done = true
 		}
 	}
-	return done, nil
+	return done, cancelled, nil
+}
+
+// CancelCall abandons the evaluation of the function call injected on
+// goroutine goid, or of all function calls in progress if goid is
+// negative. This is meant to be used when the called function blocks
+// indefinitely.
+// The target is not modified immediately: the called function can not be
+// interrupted safely, when it returns its return values (or panic) are
+// discarded, no other function call of the same expression is made and
+// the registers of the goroutine are restored without stopping the
+// target.
+func (t *Target) CancelCall(goid int) error {
+	found := false
+	for id, callinj := range t.fncallForG {
+		if callinj == nil || callinj.continueCompleted == nil || (goid >= 0 && id != goid) {
+			continue
+		}
+		if !callinj.callCtx.cancelled {
+			fncallLog("cancelling function call on goroutine %d", id)
+			callinj.callCtx.cancelled = true
+		}
+		found = true
+	}
+	if !found {
+		if goid >= 0 {
+			return fmt.Errorf("no function call in progress on goroutine %d", goid)
+		}
+		return errors.New("no function call in progress")
+	}
+	return nil
 }
 
 func findCallInjectionStateForThread(t *Target, thread Thread) (*G, *callInjection, error) {
@@ -1112,20 +1632,65 @@ func debugCallFunction(bi *BinaryInfo) (*Function, int) {
 }
 
 // debugCallProtocolReg returns the register ID (as defined in pkg/dwarf/regnum)
-// of the register used in the debug call protocol, given the debug call version.
+// of the register used in the debug call protocol, given the architecture
+// and the debug call version.
 // Also returns a bool indicating whether the version is supported.
-func debugCallProtocolReg(version int) (uint64, bool) {
-	// TODO(aarzilli): make this generic when call injection is supported on other architectures.
-	var protocolReg uint64
-	switch version {
-	case 1:
-		protocolReg = regnum.AMD64_Rax
-	case 2:
-		protocolReg = regnum.AMD64_R12
+func debugCallProtocolReg(archName string, version int) (uint64, bool) {
+	switch archName {
+	case "amd64":
+		switch version {
+		case 1:
+			return regnum.AMD64_Rax, true
+		case 2:
+			return regnum.AMD64_R12, true
+		}
+	case "loong64":
+		if version == 2 {
+			return regnum.LOONG64_R0 + 19, true
+		}
 	default:
-		return 0, false
+		// The runtime only implements the debug call protocol
+		// (runtime.debugCallV2) on amd64, arm64, loong64 and ppc64le, in
+		// particular there is no debugCall function on 386 and riscv64 that
+		// Delve could inject a call into. The call sequences used on arm64
+		// and ppc64le are not implemented.
+	}
+	return 0, false
+}
+
+// CheckFunctionCalls returns an error if the executable described by bi can
+// not be the target of function calls, regardless of the backend used to
+// debug it.
+func CheckFunctionCalls(bi *BinaryInfo) error {
+	if _, ok := debugCallProtocolReg(bi.Arch.Name, maxDebugCallVersion); !ok {
+		return fmt.Errorf("function calls are not supported on %s", bi.Arch.Name)
+	}
+	dbgcallfn, dbgcallversion := debugCallFunction(bi)
+	if dbgcallfn == nil {
+		return errFuncCallUnsupported
+	}
+	if _, ok := debugCallProtocolReg(bi.Arch.Name, dbgcallversion); !ok {
+		return errFuncCallUnsupported
+	}
+	return nil
+}
+
+// funcCallUnsupportedError returns the error reported when function calls
+// are requested but not supported on the target.
+func funcCallUnsupportedError(bi *BinaryInfo) error {
+	if _, ok := debugCallProtocolReg(bi.Arch.Name, maxDebugCallVersion); !ok {
+		return fmt.Errorf("function calls are not supported on %s", bi.Arch.Name)
+	}
+	return errFuncCallUnsupportedBackend
+}
+
+func isRegabiRuntimeFunc(name string) bool {
+	for _, fnname := range regabiRuntimeFuncs {
+		if name == fnname {
+			return true
+		}
 	}
-	return protocolReg, true
+	return false
 }
 
 type fakeEntry map[dwarf.Attr]interface{}
@@ -1134,7 +1699,15 @@ func (e fakeEntry) Val(attr dwarf.Attr) interface{} {
 	return e[attr]
 }
 
-func regabiMallocgcWorkaround(bi *BinaryInfo) ([]*godwarf.Tree, error) {
+// regabiRuntimeFuncs are the optimized runtime functions that can be
+// called when regabi is in use, their DIEs are replaced with the ones
+// returned by regabiRuntimeFuncWorkaround.
+var regabiRuntimeFuncs = []string{"runtime.mallocgc", "runtime.newobject"}
+
+// regabiRuntimeFuncWorkaround returns the formal parameters of the runtime
+// function fnname, one of regabiRuntimeFuncs, with the locations used by
+// the register ABI.
+func regabiRuntimeFuncWorkaround(bi *BinaryInfo, fnname string) ([]*godwarf.Tree, error) {
 	var err1 error
 
 	t := func(name string) godwarf.Type {
@@ -1166,11 +1739,27 @@ func regabiMallocgcWorkaround(bi *BinaryInfo) ([]*godwarf.Tree, error) {
 		}
 	}
 
-	r := []*godwarf.Tree{
-		m("size", t("uintptr"), regnum.AMD64_Rax, false),
-		m("typ", t("*runtime._type"), regnum.AMD64_Rbx, false),
-		m("needzero", t("bool"), regnum.AMD64_Rcx, false),
-		m("~r1", t("unsafe.Pointer"), regnum.AMD64_Rax, true),
+	argRegs := bi.Arch.intArgRegs
+	if len(argRegs) < 3 {
+		return nil, fmt.Errorf("no workaround for %s on %s", fnname, bi.Arch.Name)
+	}
+
+	var r []*godwarf.Tree
+	switch fnname {
+	case "runtime.mallocgc":
+		r = []*godwarf.Tree{
+			m("size", t("uintptr"), int(argRegs[0]), false),
+			m("typ", t("*runtime._type"), int(argRegs[1]), false),
+			m("needzero", t("bool"), int(argRegs[2]), false),
+			m("~r1", t("unsafe.Pointer"), int(argRegs[0]), true),
+		}
+	case "runtime.newobject":
+		r = []*godwarf.Tree{
+			m("typ", t("*runtime._type"), int(argRegs[0]), false),
+			m("~r1", t("unsafe.Pointer"), int(argRegs[0]), true),
+		}
+	default:
+		return nil, fmt.Errorf("no workaround for %s", fnname)
 	}
 
 	return r, err1
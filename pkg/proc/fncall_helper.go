@@ -0,0 +1,515 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/astutil"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/goversion"
+)
+
+// The call helper is a goroutine created by the debugger to host function
+// calls when the goroutine selected for an evaluation can not, because no
+// goroutine is selected or because it is stopped inside the runtime, see
+// (*Target).SetCallHelper.
+//
+// The helper is created by a goroutine (the creator) that is stopped at
+// a breakpoint at the entry of a function while it doesn't hold any lock, by
+// making it call runtime.newproc directly, without the debugCall
+// protocol, which refuses calls from the runtime. The helper starts from
+// main.main and is stopped at its first instruction, a safe point for the
+// debugCall protocol, before it executes it.
+// While the helper is not scheduled the creator, and the helper while the
+// creator does not return from runtime.newproc, are kept away from their
+// code by making them call runtime.usleep. Because the creator holds
+// its P the helper is scheduled on a different thread, this requires
+// GOMAXPROCS > 1.
+//
+// The helper is reused by the evaluations of the same stop, it is
+// terminated, by making it return from main.main, before the target is
+// resumed or detached. While a function call executes on the helper the
+// creator is parked, calling runtime.usleep repeatedly and returning to its
+// breakpoint, so that it neither runs its code nor reports the hit again.
+
+const (
+	// callHelperSleep is the time, in microseconds, that goroutines kept
+	// away from their code sleep for.
+	callHelperSleep = 1000
+	// callHelperMaxWait is the maximum number of times the creator sleeps
+	// waiting for the call helper to be scheduled.
+	callHelperMaxWait = 100
+	// callHelperStackSpace is the stack space that must be available to the
+	// creator of the call helper, it must be enough to call runtime.newproc
+	// without growing the stack.
+	callHelperStackSpace = 1024
+	// callHelperFrameSize is the space reserved on the stack of the creator
+	// below its stack pointer, the argument of runtime.newproc is stored at
+	// callHelperFuncvalOffset, above the spill area of the call.
+	callHelperFrameSize     = 128
+	callHelperFuncvalOffset = 64
+)
+
+var errCallHelperUnsupported = errors.New("call helper goroutine not supported by this version of Go")
+
+type callHelper struct {
+	enabled bool
+	// goid is the ID of the helper goroutine, 0 if there isn't one.
+	goid int
+	// creator is the thread that created the helper goroutine, creatorRegs
+	// and creatorBp its registers and the breakpoint it was stopped at,
+	// restored when it is unparked.
+	creator     Thread
+	creatorRegs Registers
+	creatorBp   BreakpointState
+	parked      bool
+}
+
+// SetCallHelper enables or disables the call helper goroutine: if enabled,
+// function calls that can not be injected in the goroutine selected for
+// the evaluation, because no goroutine is selected or it is stopped inside
+// the runtime, are executed on a goroutine created by the debugger. The
+// helper can only be created while a goroutine is stopped at a breakpoint
+// at the entry of a function, for example at the unrecovered-panic
+// breakpoint.
+func (t *Target) SetCallHelper(enabled bool) {
+	t.callHelper.enabled = enabled
+}
+
+// callHelperEntry returns the entry point of the start function of the
+// call helper.
+func callHelperEntry(bi *BinaryInfo) (uint64, error) {
+	fn := bi.LookupFunc["main.main"]
+	if fn == nil {
+		return 0, errors.New("could not find main.main")
+	}
+	return fn.Entry, nil
+}
+
+// callHelperGoroutine returns the call helper goroutine, creating it if
+// there isn't one.
+func (t *Target) callHelperGoroutine() (*G, error) {
+	bi := t.BinInfo()
+	entry, err := callHelperEntry(bi)
+	if err != nil {
+		return nil, err
+	}
+	if t.callHelper.goid == 0 {
+		return t.newCallHelper(entry)
+	}
+	g, err := FindGoroutine(t, t.callHelper.goid)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil || g.Thread == nil {
+		return nil, fmt.Errorf("call helper goroutine %d is not running", t.callHelper.goid)
+	}
+	regs, err := g.Thread.Registers()
+	if err != nil {
+		return nil, err
+	}
+	if regs.PC() != entry {
+		return nil, fmt.Errorf("call helper goroutine %d is not available", g.ID)
+	}
+	if bp := t.Breakpoints().M[t.callHelper.creatorRegs.PC()]; bp == nil {
+		return nil, errors.New("call helper goroutine not available: the breakpoint its creator is stopped at was cleared")
+	}
+	fncallLog("reusing call helper goroutine %d", g.ID)
+	return g, nil
+}
+
+// newCallHelper creates the call helper goroutine, starting from entry.
+func (t *Target) newCallHelper(entry uint64) (*G, error) {
+	bi := t.BinInfo()
+	if !bi.regabi || len(bi.Arch.intArgRegs) == 0 || !goversion.ProducerAfterOrEqual(bi.Producer(), 1, 18) {
+		return nil, errCallHelperUnsupported
+	}
+	newproc, usleep := lookupGoFunc(bi, "runtime.newproc"), lookupGoFunc(bi, "runtime.usleep")
+	if newproc == nil || usleep == nil {
+		return nil, errCallHelperUnsupported
+	}
+	if t.Breakpoints().HasInternalBreakpoints() {
+		return nil, errors.New("can not create the call helper goroutine while nexting")
+	}
+	creator := t.callHelperCreator()
+	if creator == nil {
+		return nil, errors.New("could not create the call helper goroutine: no goroutine is stopped at the entry of a function without holding runtime locks")
+	}
+
+	th := creator.Thread
+	regs, err := th.Registers()
+	if err != nil {
+		return nil, err
+	}
+	creatorRegs, err := regs.Copy()
+	if err != nil {
+		return nil, err
+	}
+	creatorBp := *th.Breakpoint()
+	// funcval for the helper's start function
+	fv := (creatorRegs.SP()-callHelperFrameSize)&^0xf + callHelperFuncvalOffset
+	if err := writePointer(bi, th.ProcessMemory(), fv, entry); err != nil {
+		return nil, err
+	}
+	fncallLog("creating call helper goroutine on goroutine %d", creator.ID)
+	if err := callHelperCall(bi, th, creatorRegs, newproc, fv, entry); err != nil {
+		th.RestoreRegisters(creatorRegs)
+		return nil, err
+	}
+
+	startpc := astutil.Eql(astutil.Sel(astutil.PkgVar("runtime", "curg"), "startpc"), astutil.Int(int64(entry)))
+	cond := astutil.Or(sameGoroutineCondition(creator), startpc)
+
+	var helper *G
+	var helperRegs Registers
+	creatorDone := false
+	for wait := 0; ; {
+		if _, err := allowDuplicateBreakpoint(t.SetBreakpoint(entry, NextBreakpoint, cond)); err != nil {
+			return nil, err
+		}
+		err := t.Continue()
+		t.ClearInternalBreakpoints()
+		if err != nil {
+			return nil, err
+		}
+
+		creatorAtEntry := false
+		var helperThread Thread
+		for _, th2 := range t.ThreadList() {
+			regs, err := th2.Registers()
+			if err != nil || regs.PC() != entry {
+				continue
+			}
+			g, _ := GetG(th2)
+			switch {
+			case g == nil:
+			case g.ID == creator.ID:
+				creatorAtEntry = !creatorDone
+			case g.StartPC == entry && (helper == nil || g.ID == helper.ID):
+				helperThread = th2
+				if helper == nil {
+					helper = g
+					if helperRegs, err = regs.Copy(); err != nil {
+						return nil, err
+					}
+					fncallLog("call helper goroutine %d scheduled", g.ID)
+				}
+			}
+		}
+
+		if helperThread != nil {
+			if err := helperThread.RestoreRegisters(helperRegs); err != nil {
+				return nil, err
+			}
+		}
+		if creatorAtEntry && (helperThread != nil || (helper == nil && wait >= callHelperMaxWait)) {
+			if err := th.RestoreRegisters(creatorRegs); err != nil {
+				return nil, err
+			}
+			*th.Breakpoint() = creatorBp
+			creatorDone = true
+		}
+		switch {
+		case creatorDone && helperThread != nil:
+			t.callHelper.goid = helper.ID
+			t.callHelper.creator = th
+			t.callHelper.creatorRegs = creatorRegs
+			t.callHelper.creatorBp = creatorBp
+			t.clearGoroutineCaches()
+			return FindGoroutine(t, helper.ID)
+		case creatorDone && helper == nil:
+			return nil, t.abandonCallHelper(entry)
+		}
+
+		if creatorAtEntry {
+			wait++
+			if err := callHelperCall(bi, th, creatorRegs, usleep, callHelperSleep, entry); err != nil {
+				return nil, err
+			}
+		}
+		if helperThread != nil {
+			if err := callHelperCall(bi, helperThread, helperRegs, usleep, callHelperSleep, entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// lookupGoFunc returns the function called name, skipping the ABI0 wrapper
+// that has the same name, which LookupFunc could return.
+func lookupGoFunc(bi *BinaryInfo, name string) *Function {
+	for i := range bi.Functions {
+		fn := &bi.Functions[i]
+		if fn.Name != name || fn.Entry == 0 {
+			continue
+		}
+		if file, _, _ := bi.PCToLine(fn.Entry); file != "<autogenerated>" {
+			return fn
+		}
+	}
+	return nil
+}
+
+// callHelperCreator returns a goroutine that can create the call helper,
+// see newCallHelper, or nil if there isn't one. The goroutine must be
+// running, not on the system stack, stopped at the entry of a function,
+// either its first instruction or the first after its prologue, which is
+// not part of the machinery of runtime throws, it must have a P, not hold
+// any lock, have enough stack space and no pending preemption request.
+func (t *Target) callHelperCreator() *G {
+	bi := t.BinInfo()
+	mtyp, err := bi.findType("runtime.m")
+	if err != nil {
+		return nil
+	}
+	for _, th := range t.ThreadList() {
+		if bp := th.Breakpoint(); bp.Breakpoint == nil || !bp.Active || bp.Internal {
+			continue
+		}
+		g, _ := GetG(th)
+		if g == nil || g.SystemStack || g.Status != Grunning || g.variable == nil {
+			continue
+		}
+		regs, err := th.Registers()
+		if err != nil {
+			continue
+		}
+		pc, sp := regs.PC(), regs.SP()
+		fn := bi.PCToFunc(pc)
+		if fn == nil || fn.Name == "runtime.throw" || fn.Name == "runtime.fatalthrow" || fn.Name == "runtime.fatal" {
+			continue
+		}
+		if pc != fn.Entry {
+			if pc2, _ := FirstPCAfterPrologue(t, fn, false); pc2 != pc {
+				continue
+			}
+		}
+
+		gr := runtimeStructReader{v: g.variable}
+		stackguard := uint64(gr.int("stackguard0"))
+		maddr := gr.ptr("m")
+		if gr.err != nil || maddr == 0 || stackguard >= g.stack.hi || sp < stackguard+callHelperStackSpace {
+			continue
+		}
+		mr := runtimeStructReader{v: newVariable("", maddr, mtyp, bi, g.variable.mem)}
+		locks, mallocing, dying := mr.int("locks"), mr.int("mallocing"), mr.int("dying")
+		paddr := mr.ptr("p")
+		preemptoff := mr.field("preemptoff")
+		if mr.err != nil || locks != 0 || mallocing != 0 || dying != 0 || paddr == 0 {
+			continue
+		}
+		if preemptoff.loadValue(loadSingleValue); preemptoff.Unreadable != nil || preemptoff.Len != 0 {
+			continue
+		}
+		return g
+	}
+	return nil
+}
+
+// callHelperCall makes thread, whose registers before the call are regs,
+// call fn with the integer argument arg, returning to retaddr. The argument
+// is passed on the stack if fn is written in assembly, which uses ABI0.
+func callHelperCall(bi *BinaryInfo, thread Thread, regs Registers, fn *Function, arg, retaddr uint64) error {
+	mem := thread.ProcessMemory()
+	ptrSize := uint64(bi.Arch.PtrSize())
+	sp := (regs.SP() - callHelperFrameSize) &^ 0xf
+	if bi.Arch.usesLR {
+		if err := setLR(thread, retaddr); err != nil {
+			return err
+		}
+	} else {
+		sp -= ptrSize
+		if err := writePointer(bi, mem, sp, retaddr); err != nil {
+			return err
+		}
+	}
+	if err := setSP(thread, sp); err != nil {
+		return err
+	}
+	if file, _, _ := bi.PCToLine(fn.Entry); strings.HasSuffix(file, ".s") {
+		if err := writePointer(bi, mem, sp+ptrSize, arg); err != nil {
+			return err
+		}
+	} else if err := thread.SetReg(bi.Arch.intArgRegs[0], op.DwarfRegisterFromUint64(arg)); err != nil {
+		return err
+	}
+	return setPC(thread, fn.Entry)
+}
+
+// parkCallHelperCreator parks the creator of the call helper goroutine, if
+// a function call executes on the helper, before the target is resumed.
+func (t *Target) parkCallHelperCreator() error {
+	goid := t.callHelper.goid
+	if goid == 0 || t.fncallForG[goid] == nil || t.callHelper.parked {
+		return nil
+	}
+	t.callHelper.parked = true
+	return t.reparkCallHelperCreator()
+}
+
+// reparkCallHelperCreator makes the creator of the call helper goroutine
+// call runtime.usleep, returning to the breakpoint it was stopped at.
+func (t *Target) reparkCallHelperCreator() error {
+	bi := t.BinInfo()
+	usleep := lookupGoFunc(bi, "runtime.usleep")
+	if usleep == nil {
+		return errCallHelperUnsupported
+	}
+	th, regs := t.callHelper.creator, t.callHelper.creatorRegs
+	*th.Breakpoint() = BreakpointState{}
+	return callHelperCall(bi, th, regs, usleep, callHelperSleep, regs.PC())
+}
+
+// callHelperCreatorStopped is called after the target stops, if the parked
+// creator of the call helper goroutine returned to its breakpoint it is
+// parked again and true is returned, the stop must not be reported to the
+// user.
+func (t *Target) callHelperCreatorStopped() (bool, error) {
+	if !t.callHelper.parked {
+		return false, nil
+	}
+	th := t.callHelper.creator
+	regs, err := th.Registers()
+	if err != nil || regs.PC() != t.callHelper.creatorRegs.PC() {
+		return false, err
+	}
+	return true, t.reparkCallHelperCreator()
+}
+
+// switchToCallHelper switches to the thread of the call helper goroutine,
+// used when a call injection completes while the parked creator also
+// stopped.
+func (t *Target) switchToCallHelper() error {
+	g, err := FindGoroutine(t, t.callHelper.goid)
+	if err != nil || g == nil || g.Thread == nil {
+		return err
+	}
+	return t.SwitchThread(g.Thread.ThreadID())
+}
+
+// unparkCallHelperCreator restores the creator of the call helper
+// goroutine to the breakpoint it was stopped at, when the target stops.
+func (t *Target) unparkCallHelperCreator() {
+	if !t.callHelper.parked {
+		return
+	}
+	t.callHelper.parked = false
+	if valid, _ := t.Valid(); !valid {
+		return
+	}
+	th := t.callHelper.creator
+	if err := th.RestoreRegisters(t.callHelper.creatorRegs); err != nil {
+		fncallLog("could not unpark the creator of the call helper goroutine: %v", err)
+		return
+	}
+	*th.Breakpoint() = t.callHelper.creatorBp
+	t.gcache.Clear()
+	th.Common().g = nil
+	if t.selectedGoroutine != nil && t.selectedGoroutine.Thread != nil && t.selectedGoroutine.Thread.ThreadID() == th.ThreadID() {
+		t.selectedGoroutine, _ = GetG(th)
+	}
+}
+
+// abandonCallHelper is called when the call helper, starting from entry,
+// was created but not scheduled, it terminates it and returns an error.
+func (t *Target) abandonCallHelper(entry uint64) error {
+	t.clearGoroutineCaches()
+	gs, _, err := GoroutinesInfo(t, 0, 0)
+	if err != nil {
+		return err
+	}
+	for _, g := range gs {
+		if g.StartPC == entry && g.Thread == nil && g.Status == Grunnable {
+			if err := t.terminateCallHelper(g, entry); err != nil {
+				return err
+			}
+		}
+	}
+	return errors.New("could not create the call helper goroutine: it was not scheduled, GOMAXPROCS must be greater than 1")
+}
+
+// retireCallHelper terminates the call helper goroutine, if there is one
+// and it isn't executing a function call.
+func (t *Target) retireCallHelper() error {
+	goid := t.callHelper.goid
+	if goid == 0 || t.fncallForG[goid] != nil {
+		return nil
+	}
+	t.callHelper.goid = 0
+	t.callHelper.creator, t.callHelper.creatorRegs = nil, nil
+	entry, err := callHelperEntry(t.BinInfo())
+	if err != nil {
+		return err
+	}
+	g, err := FindGoroutine(t, goid)
+	if err != nil || g == nil {
+		return err
+	}
+	fncallLog("terminating call helper goroutine %d", goid)
+	return t.terminateCallHelper(g, entry)
+}
+
+// terminateCallHelper makes the call helper goroutine g, which is at the
+// entry of its start function, return from it, which terminates it.
+func (t *Target) terminateCallHelper(g *G, entry uint64) error {
+	bi := t.BinInfo()
+	mem := t.Memory()
+	ptrSize := int64(bi.Arch.PtrSize())
+	if g.Thread != nil {
+		regs, err := g.Thread.Registers()
+		if err != nil {
+			return err
+		}
+		if regs.PC() != entry {
+			return fmt.Errorf("call helper goroutine %d is not at the entry of its start function", g.ID)
+		}
+		if bi.Arch.usesLR {
+			dregs := bi.Arch.RegistersToDwarfRegisters(0, regs)
+			return setPC(g.Thread, dregs.Uint64Val(dregs.LRRegNum))
+		}
+		ret, err := readUintRaw(mem, regs.SP(), ptrSize, bi.Arch.ByteOrder())
+		if err != nil {
+			return err
+		}
+		if err := setSP(g.Thread, regs.SP()+uint64(ptrSize)); err != nil {
+			return err
+		}
+		return setPC(g.Thread, ret)
+	}
+
+	// The goroutine was never scheduled, change the state saved by
+	// runtime.newproc.
+	gr := runtimeStructReader{v: g.variable}
+	sched := gr.field("sched")
+	if gr.err != nil {
+		return gr.err
+	}
+	sr := runtimeStructReader{v: sched}
+	pc, sp, lr := sr.field("pc"), uint64(sr.int("sp")), uint64(sr.int("lr"))
+	spv := sr.field("sp")
+	if sr.err != nil {
+		return sr.err
+	}
+	if bi.Arch.usesLR {
+		return writePointer(bi, mem, pc.Addr, lr)
+	}
+	ret, err := readUintRaw(mem, sp, ptrSize, bi.Arch.ByteOrder())
+	if err != nil {
+		return err
+	}
+	if err := writePointer(bi, mem, spv.Addr, sp+uint64(ptrSize)); err != nil {
+		return err
+	}
+	return writePointer(bi, mem, pc.Addr, ret)
+}
+
+// clearGoroutineCaches clears the cached goroutines, after their registers
+// were changed.
+func (t *Target) clearGoroutineCaches() {
+	t.gcache.Clear()
+	for _, thread := range t.ThreadList() {
+		thread.Common().g = nil
+	}
+	t.selectedGoroutine, _ = GetG(t.CurrentThread())
+}
@@ -0,0 +1,107 @@
+package proc
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/go-delve/delve/pkg/dwarf/frame"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+)
+
+// FrameUnwindInfo describes the call frame information (CFI) used to
+// unwind a stack frame, that is to compute the canonical frame address
+// (CFA) of the frame and the registers of its caller.
+type FrameUnwindInfo struct {
+	PC uint64
+	// FDEStart and FDEEnd are the address range of the frame description
+	// entry covering PC. Both are zero if no FDE covers PC, in which case
+	// the rules are an architecture specific approximation.
+	FDEStart, FDEEnd uint64
+	// CFA is the rule used to compute the canonical frame address.
+	CFA string
+	// CFAValue is the value of the canonical frame address.
+	CFAValue int64
+	// RetAddrReg is the name of the register containing the return address.
+	RetAddrReg string
+	// Regs are the rules used to compute the registers of the calling
+	// frame, sorted by DWARF register number.
+	Regs []RegisterRule
+}
+
+// RegisterRule is the rule used to compute the value of a register in the
+// calling frame.
+type RegisterRule struct {
+	Name string
+	Rule string
+}
+
+// FrameUnwindInfo returns the call frame information used to unwind the
+// frame of scope.
+func (scope *EvalScope) FrameUnwindInfo() *FrameUnwindInfo {
+	bi := scope.BinInfo
+	r := &FrameUnwindInfo{PC: scope.PC, CFAValue: scope.Regs.CFA}
+
+	var framectx *frame.FrameContext
+	fde, err := bi.frameEntries.FDEForPC(scope.PC)
+	if err == nil {
+		r.FDEStart, r.FDEEnd = fde.Begin(), fde.End()
+		framectx = bi.Arch.fixFrameUnwindContext(fde.EstablishFrame(scope.PC), scope.PC, bi)
+	} else {
+		framectx = bi.Arch.fixFrameUnwindContext(nil, scope.PC, bi)
+	}
+
+	regname := func(regnum uint64) string {
+		name, _, _ := bi.Arch.DwarfRegisterToString(int(regnum), nil)
+		if name == "" {
+			return fmt.Sprintf("r%d", regnum)
+		}
+		return name
+	}
+
+	r.CFA = formatFrameRule(framectx.CFA, regname)
+	r.RetAddrReg = regname(framectx.RetAddrReg)
+
+	regnums := make([]uint64, 0, len(framectx.Regs))
+	for regnum := range framectx.Regs {
+		regnums = append(regnums, regnum)
+	}
+	sort.Slice(regnums, func(i, j int) bool { return regnums[i] < regnums[j] })
+	for _, regnum := range regnums {
+		r.Regs = append(r.Regs, RegisterRule{Name: regname(regnum), Rule: formatFrameRule(framectx.Regs[regnum], regname)})
+	}
+	return r
+}
+
+// formatFrameRule returns a description of rule, using regname to convert
+// DWARF register numbers to names.
+func formatFrameRule(rule frame.DWRule, regname func(uint64) string) string {
+	expr := func() string {
+		var buf bytes.Buffer
+		op.PrettyPrint(&buf, rule.Expression)
+		return buf.String()
+	}
+	switch rule.Rule {
+	case frame.RuleUndefined:
+		return "undefined"
+	case frame.RuleSameVal:
+		return "same value"
+	case frame.RuleOffset:
+		return fmt.Sprintf("[CFA%+d]", rule.Offset)
+	case frame.RuleValOffset:
+		return fmt.Sprintf("CFA%+d", rule.Offset)
+	case frame.RuleRegister:
+		return regname(rule.Reg)
+	case frame.RuleExpression:
+		return fmt.Sprintf("[%s]", expr())
+	case frame.RuleValExpression:
+		return expr()
+	case frame.RuleArchitectural:
+		return "architectural"
+	case frame.RuleCFA:
+		return fmt.Sprintf("%s%+d", regname(rule.Reg), rule.Offset)
+	case frame.RuleFramePointer:
+		return fmt.Sprintf("[%s] if %s <= CFA, otherwise same value", regname(rule.Reg), regname(rule.Reg))
+	}
+	return fmt.Sprintf("unknown rule %d", rule.Rule)
+}
@@ -72,6 +72,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -150,6 +151,8 @@ type gdbProcess struct {
 
 	breakpoints proc.BreakpointMap
 
+	passSignals []string // names of the signals that the stub should deliver to the target without stopping
+
 	gcmdok         bool   // true if the stub supports g and G commands
 	threadStopInfo bool   // true if the stub supports qThreadStopInfo
 	tracedir       string // if attached to rr the path to the trace directory
@@ -158,6 +161,8 @@ type gdbProcess struct {
 
 	breakpointKind int // breakpoint kind to pass to 'z' and 'Z' when creating software breakpoints
 
+	memoryBreakpoints bool // breakpoints are written to memory because the stub does not support 'Z'
+
 	process  *os.Process
 	waitChan chan *os.ProcessState
 
@@ -210,6 +215,7 @@ type gdbRegister struct {
 // gdbRegname records names of important CPU registers
 type gdbRegnames struct {
 	PC, SP, BP, CX, FsBase string
+	G                      string // register holding the address of the current G, if the architecture has one
 }
 
 // newProcess creates a new Process instance.
@@ -229,6 +235,7 @@ func newProcess(process *os.Process) *gdbProcess {
 		bi:             proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH),
 		regnames:       new(gdbRegnames),
 		breakpoints:    proc.NewBreakpointMap(),
+		passSignals:    defaultPassSignals,
 		gcmdok:         true,
 		threadStopInfo: true,
 		process:        process,
@@ -241,6 +248,8 @@ func newProcess(process *os.Process) *gdbProcess {
 		p.breakpointKind = 1
 	case "arm64":
 		p.breakpointKind = 4
+	case "s390x":
+		p.breakpointKind = 2
 	}
 
 	p.regnames.PC = registerName(p.bi.Arch, p.bi.Arch.PCRegNum)
@@ -251,6 +260,11 @@ func newProcess(process *os.Process) *gdbProcess {
 	case "arm64":
 		p.regnames.BP = "fp"
 		p.regnames.CX = "x0"
+		p.regnames.G = "x28"
+	case "s390x":
+		p.regnames.PC = "pswa"
+		p.regnames.CX = "r2"
+		p.regnames.G = "r13"
 	case "amd64":
 		p.regnames.CX = "rcx"
 		p.regnames.FsBase = "fs_base"
@@ -337,12 +351,18 @@ func (p *gdbProcess) Connect(conn net.Conn, path string, pid int, debugInfoDirs
 		p.gcmdok = false
 	}
 
+	p.conn.asyncPreemptSignal = p.stubSignals()["SIGURG"]
+	if err := p.conn.passSignals(p.passSignalNumbers()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
 	tgt, err := p.initialize(path, debugInfoDirs, stopReason)
 	if err != nil {
 		return nil, err
 	}
 
-	if p.bi.Arch.Name != "arm64" {
+	if p.regnames.G == "" {
 		// None of the stubs we support returns the value of fs_base or gs_base
 		// along with the registers, therefore we have to resort to executing a MOV
 		// instruction on the inferior to find out where the G struct of a given
@@ -602,6 +622,25 @@ func LLDBAttach(pid int, path string, debugInfoDirs []string) (*proc.Target, err
 	return tgt, err
 }
 
+// StubConnect connects to a stub that is already attached to the target
+// process, for example an instance of 'lldb-server gdbserver --attach'
+// running on a different machine.
+// The stub is only used to control the process and access its memory and
+// registers, the debug info is read from path, which must be a copy of the
+// executable of the target on the local machine.
+// The stub is not killed on Detach.
+func StubConnect(addr, path string, debugInfoDirs []string) (*proc.Target, error) {
+	if path == "" {
+		return nil, errors.New("the path of a local copy of the executable is required")
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	p := newProcess(nil)
+	return p.Connect(conn, path, 0, debugInfoDirs, proc.StopAttached)
+}
+
 // EntryPoint will return the process entry point address, useful for
 // debugging PIEs.
 func (p *gdbProcess) EntryPoint() (uint64, error) {
@@ -624,7 +663,7 @@ func (p *gdbProcess) EntryPoint() (uint64, error) {
 		// If we can't read the auxiliary vector it just means it's not supported
 		// by the OS or by the stub. If we are debugging a PIE and the entry point
 		// is needed proc.LoadBinaryInfo will complain about it.
-		entryPoint = linutil.EntryPointFromAuxv(auxv, p.BinInfo().Arch.PtrSize())
+		entryPoint = linutil.EntryPointFromAuxv(auxv, p.BinInfo().Arch.PtrSize(), p.BinInfo().Arch.ByteOrder())
 	}
 
 	return entryPoint, nil
@@ -720,6 +759,12 @@ func (p *gdbProcess) Recorded() (bool, string) {
 	return p.tracedir != "", p.tracedir
 }
 
+// RecentPackets returns the most recent packets exchanged with the stub,
+// see proc.PacketLogger.
+func (p *gdbProcess) RecentPackets() []string {
+	return p.conn.packets.recent()
+}
+
 // Pid returns the process ID.
 func (p *gdbProcess) Pid() int {
 	return int(p.conn.pid)
@@ -778,6 +823,84 @@ const (
 	debugServerTargetExcBreakpoint     = 0x96
 )
 
+// defaultPassSignals are the signals that the stub delivers to the target
+// without stopping it: SIGURG is used by the Go runtime for asynchronous
+// preemption and SIGPROF by the CPU profiler, both are received frequently
+// and stopping for them slows down the target considerably, especially
+// with debugserver and rr.
+var defaultPassSignals = []string{"SIGURG", "SIGPROF"}
+
+var (
+	// gdbSignals are the signal numbers defined by GDB, used by gdbserver,
+	// qemu, rr and debugserver (for the signals listed here the numbers used
+	// by macOS are the same).
+	gdbSignals = map[string]uint8{
+		"SIGHUP": 1, "SIGINT": 2, "SIGQUIT": 3, "SIGPIPE": 13, "SIGALRM": 14, "SIGTERM": 15,
+		"SIGURG": 16, "SIGCHLD": 20, "SIGIO": 23, "SIGVTALRM": 26, "SIGPROF": 27, "SIGWINCH": 28,
+		"SIGUSR1": 30, "SIGUSR2": 31,
+	}
+	// linuxSignals are the signal numbers used by lldb-server on linux,
+	// which uses the numbering of the host.
+	linuxSignals = map[string]uint8{
+		"SIGHUP": 1, "SIGINT": 2, "SIGQUIT": 3, "SIGPIPE": 13, "SIGALRM": 14, "SIGTERM": 15,
+		"SIGURG": 23, "SIGCHLD": 17, "SIGIO": 29, "SIGVTALRM": 26, "SIGPROF": 27, "SIGWINCH": 28,
+		"SIGUSR1": 10, "SIGUSR2": 12,
+	}
+)
+
+// AsyncPreemptSignals returns the number of asynchronous preemption
+// signals that were delivered to the target, or discarded, without
+// stopping.
+func (p *gdbProcess) AsyncPreemptSignals() uint64 {
+	return p.conn.asyncPreempt.AsyncPreemptSignals()
+}
+
+// stubSignals returns the signal numbering used by the stub: lldb-server
+// reports its host operating system through qHostInfo and uses its
+// numbering, every other stub uses the numbering defined by GDB.
+func (p *gdbProcess) stubSignals() map[string]uint8 {
+	if p.conn.hostOS == "linux" {
+		return linuxSignals
+	}
+	return gdbSignals
+}
+
+// SetPassSignals sets the signals that the stub delivers to the target
+// without stopping it, replacing the default list (SIGURG and SIGPROF).
+// Signals are specified by name, for example "SIGURG" or "URG".
+func (p *gdbProcess) SetPassSignals(names []string) error {
+	if p.exited {
+		return proc.ErrProcessExited{Pid: p.conn.pid}
+	}
+	passSignals := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.ToUpper(strings.TrimSpace(name))
+		if !strings.HasPrefix(name, "SIG") {
+			name = "SIG" + name
+		}
+		if _, ok := gdbSignals[name]; !ok {
+			return fmt.Errorf("unknown signal %q", name)
+		}
+		passSignals = append(passSignals, name)
+	}
+	p.passSignals = passSignals
+	return p.conn.passSignals(p.passSignalNumbers())
+}
+
+// passSignalNumbers returns the numbers, as understood by the stub, of the
+// signals in p.passSignals.
+func (p *gdbProcess) passSignalNumbers() []uint8 {
+	signals := p.stubSignals()
+	r := make([]uint8, 0, len(p.passSignals))
+	for _, name := range p.passSignals {
+		if sig, ok := signals[name]; ok {
+			r = append(r, sig)
+		}
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i] < r[j] })
+	return r
+}
+
 // ContinueOnce will continue execution of the process until
 // a breakpoint is hit or signal is received.
 func (p *gdbProcess) ContinueOnce() (proc.Thread, proc.StopReason, error) {
@@ -826,6 +949,12 @@ continueLoop:
 		p.updateThreadList(&tu)
 
 		trapthread = p.findThreadByStrID(threadID)
+		if trapthread == nil && threadID == "" && len(p.threads) == 1 {
+			// Stop packets of bare stubs do not specify the thread.
+			for _, th := range p.threads {
+				trapthread = th
+			}
+		}
 		if trapthread != nil && !p.threadStopInfo {
 			// For stubs that do not support qThreadStopInfo we manually set the
 			// reason the thread returned by resume() stopped.
@@ -940,6 +1069,9 @@ func (p *gdbProcess) handleThreadSignals(trapthread *gdbThread) (trapthreadOut *
 
 		default:
 			// any other signal is always propagated to inferior
+			if th.sig != 0 && th.sig == p.conn.asyncPreemptSignal {
+				p.conn.asyncPreempt.Hide()
+			}
 		}
 
 		if isStopSignal {
@@ -1215,11 +1347,43 @@ func (p *gdbProcess) WriteBreakpoint(bp *proc.Breakpoint) error {
 	if bp.WatchType != 0 {
 		return errors.New("hardware breakpoints not supported")
 	}
-	return p.conn.setBreakpoint(bp.Addr, p.breakpointKind)
+	if !p.memoryBreakpoints {
+		err := p.conn.setBreakpoint(bp.Addr, p.breakpointKind)
+		if !isProtocolErrorUnsupported(err) {
+			return err
+		}
+		// Some bare stubs (for example qemu-user on some architectures and
+		// embedded probes) do not support software breakpoints, write the
+		// breakpoint instruction to memory ourselves.
+		p.memoryBreakpoints = true
+	}
+	bp.OriginalData = make([]byte, p.bi.Arch.BreakpointSize())
+	if err := p.conn.readMemory(bp.OriginalData, bp.Addr); err != nil {
+		return err
+	}
+	return p.writeBreakpoint(bp)
 }
 
 func (p *gdbProcess) EraseBreakpoint(bp *proc.Breakpoint) error {
-	return p.conn.clearBreakpoint(bp.Addr, p.breakpointKind)
+	return p.eraseBreakpoint(bp)
+}
+
+// writeBreakpoint inserts breakpoint bp in the target.
+func (p *gdbProcess) writeBreakpoint(bp *proc.Breakpoint) error {
+	if !p.memoryBreakpoints {
+		return p.conn.setBreakpoint(bp.Addr, p.breakpointKind)
+	}
+	_, err := p.conn.writeMemory(bp.Addr, p.bi.Arch.BreakpointInstruction())
+	return err
+}
+
+// eraseBreakpoint removes breakpoint bp from the target.
+func (p *gdbProcess) eraseBreakpoint(bp *proc.Breakpoint) error {
+	if !p.memoryBreakpoints {
+		return p.conn.clearBreakpoint(bp.Addr, p.breakpointKind)
+	}
+	_, err := p.conn.writeMemory(bp.Addr, bp.OriginalData)
+	return err
 }
 
 type threadUpdater struct {
@@ -1294,6 +1458,18 @@ func (p *gdbProcess) updateThreadList(tu *threadUpdater) error {
 		first := true
 		for {
 			threads, err := p.conn.queryThreads(first)
+			if err != nil && first && isProtocolErrorUnsupported(err) {
+				// Bare stubs may not support listing threads, they only
+				// have one.
+				threads, err = p.singleThread()
+				if err != nil {
+					return err
+				}
+				if err := tu.Add(threads); err != nil {
+					return err
+				}
+				break
+			}
 			if err != nil {
 				return err
 			}
@@ -1329,6 +1505,20 @@ func (p *gdbProcess) updateThreadList(tu *threadUpdater) error {
 	return nil
 }
 
+// singleThread returns the current thread of stubs that do not support
+// qfThreadInfo, with qC or, if that is not supported either, by assuming
+// that the ID of the only thread is 1.
+func (p *gdbProcess) singleThread() ([]string, error) {
+	th, err := p.conn.currentThread()
+	if err != nil {
+		if !isProtocolErrorUnsupported(err) {
+			return nil, err
+		}
+		th = "1"
+	}
+	return []string{th}, nil
+}
+
 // clearThreadRegisters clears the memoized thread register state.
 func (p *gdbProcess) clearThreadRegisters() {
 	for _, thread := range p.threads {
@@ -1439,12 +1629,12 @@ func (t *gdbThread) Common() *proc.CommonThread {
 // StepInstruction will step exactly 1 CPU instruction.
 func (t *gdbThread) StepInstruction() error {
 	pc := t.regs.PC()
-	if _, atbp := t.p.breakpoints.M[pc]; atbp {
-		err := t.p.conn.clearBreakpoint(pc, t.p.breakpointKind)
+	if bp, atbp := t.p.breakpoints.M[pc]; atbp {
+		err := t.p.eraseBreakpoint(bp)
 		if err != nil {
 			return err
 		}
-		defer t.p.conn.setBreakpoint(pc, t.p.breakpointKind)
+		defer t.p.writeBreakpoint(bp)
 	}
 	// Reset thread registers so the next call to
 	// Thread.Registers will not be cached.
@@ -1505,6 +1695,9 @@ func (p *gdbProcess) MemoryMap() ([]proc.MemoryMapEntry, error) {
 	for addr != ^uint64(0) {
 		mri, err := p.conn.memoryRegionInfo(addr)
 		if err != nil {
+			if addr == 0 && isProtocolErrorUnsupported(err) {
+				return p.conn.readMemoryMap()
+			}
 			return nil, err
 		}
 		if addr+mri.size <= addr {
@@ -1578,17 +1771,17 @@ func (t *gdbThread) reloadRegisters() error {
 	if t.p.bi.GOOS == "linux" {
 		if reg, hasFsBase := t.regs.regs[t.p.regnames.FsBase]; hasFsBase {
 			t.regs.gaddr = 0
-			t.regs.tls = binary.LittleEndian.Uint64(reg.value)
+			t.regs.tls = t.p.bi.Arch.ByteOrder().Uint64(reg.value)
 			t.regs.hasgaddr = false
 			return nil
 		}
 	}
 
-	if t.p.bi.Arch.Name == "arm64" {
-		// no need to play around with the GInstr on ARM64 because
+	if t.p.regnames.G != "" {
+		// no need to play around with the GInstr on ARM64 and S390X because
 		// the G addr is stored in a register
 
-		t.regs.gaddr = t.regs.byName("x28")
+		t.regs.gaddr = t.regs.byName(t.p.regnames.G)
 		t.regs.hasgaddr = true
 		t.regs.tls = 0
 	} else {
@@ -1664,13 +1857,13 @@ func (t *gdbThread) reloadGAtPC() error {
 	// around by clearing and re-setting the breakpoint in a specific sequence
 	// with the memory writes.
 	// Additionally all breakpoints in [pc, pc+len(movinstr)] need to be removed
-	for addr := range t.p.breakpoints.M {
+	for addr, bp := range t.p.breakpoints.M {
 		if addr >= pc && addr <= pc+uint64(len(movinstr)) {
-			err := t.p.conn.clearBreakpoint(addr, t.p.breakpointKind)
+			err := t.p.eraseBreakpoint(bp)
 			if err != nil {
 				return err
 			}
-			defer t.p.conn.setBreakpoint(addr, t.p.breakpointKind)
+			defer t.p.writeBreakpoint(bp)
 		}
 	}
 
@@ -1787,7 +1980,13 @@ func (t *gdbThread) SetCurrentBreakpoint(adjustPC bool) error {
 		return err
 	}
 	pc := regs.PC()
-	if bp, ok := t.p.FindBreakpoint(pc); ok {
+	bp, ok := t.p.FindBreakpoint(pc)
+	if !ok && t.p.memoryBreakpoints && t.sig == breakpointSignal && t.BinInfo().Arch.BreakInstrMovesPC() {
+		// The stub does not know about breakpoints written to memory and
+		// does not move the PC back to the breakpoint address.
+		bp, ok = t.p.FindBreakpoint(pc - uint64(t.BinInfo().Arch.BreakpointSize()))
+	}
+	if ok {
 		if t.regs.PC() != bp.Addr {
 			if err := t.setPC(bp.Addr); err != nil {
 				return err
@@ -1799,27 +1998,27 @@ func (t *gdbThread) SetCurrentBreakpoint(adjustPC bool) error {
 }
 
 func (regs *gdbRegisters) PC() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regs.regnames.PC].value)
+	return regs.arch.ByteOrder().Uint64(regs.regs[regs.regnames.PC].value)
 }
 
 func (regs *gdbRegisters) setPC(value uint64) {
-	binary.LittleEndian.PutUint64(regs.regs[regs.regnames.PC].value, value)
+	regs.arch.ByteOrder().PutUint64(regs.regs[regs.regnames.PC].value, value)
 }
 
 func (regs *gdbRegisters) SP() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regs.regnames.SP].value)
+	return regs.arch.ByteOrder().Uint64(regs.regs[regs.regnames.SP].value)
 }
 
 func (regs *gdbRegisters) BP() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regs.regnames.BP].value)
+	return regs.arch.ByteOrder().Uint64(regs.regs[regs.regnames.BP].value)
 }
 
 func (regs *gdbRegisters) CX() uint64 {
-	return binary.LittleEndian.Uint64(regs.regs[regs.regnames.CX].value)
+	return regs.arch.ByteOrder().Uint64(regs.regs[regs.regnames.CX].value)
 }
 
 func (regs *gdbRegisters) setCX(value uint64) {
-	binary.LittleEndian.PutUint64(regs.regs[regs.regnames.CX].value, value)
+	regs.arch.ByteOrder().PutUint64(regs.regs[regs.regnames.CX].value, value)
 }
 
 func (regs *gdbRegisters) TLS() uint64 {
@@ -1835,7 +2034,7 @@ func (regs *gdbRegisters) byName(name string) uint64 {
 	if !ok {
 		return 0
 	}
-	return binary.LittleEndian.Uint64(reg.value)
+	return regs.arch.ByteOrder().Uint64(reg.value)
 }
 
 func (r *gdbRegisters) FloatLoadError() error {
@@ -1856,6 +2055,9 @@ func (t *gdbThread) setPC(pc uint64) error {
 // SetReg will change the value of a list of registers
 func (t *gdbThread) SetReg(regNum uint64, reg *op.DwarfRegister) error {
 	regName := registerName(t.p.bi.Arch, regNum)
+	if regNum == t.p.bi.Arch.PCRegNum {
+		regName = t.p.regnames.PC
+	}
 	_, _ = t.Registers() // Registers must be loaded first
 	gdbreg, ok := t.regs.regs[regName]
 	if !ok && strings.HasPrefix(regName, "xmm") {
@@ -1869,6 +2071,15 @@ func (t *gdbThread) SetReg(regNum uint64, reg *op.DwarfRegister) error {
 	if !ok {
 		return fmt.Errorf("could not set register %s: not found", regName)
 	}
+	if t.p.bi.Arch.ByteOrder() == binary.BigEndian && reg.Bytes == nil && len(gdbreg.value) <= 8 {
+		// the stub expects register values in the byte order of the target.
+		v := reg.Uint64Val
+		for i := len(gdbreg.value) - 1; i >= 0; i-- {
+			gdbreg.value[i] = byte(v)
+			v >>= 8
+		}
+		return t.p.conn.writeRegister(t.strID, gdbreg.regnum, gdbreg.value)
+	}
 	reg.FillBytes()
 	if len(reg.Bytes) != len(gdbreg.value) {
 		return fmt.Errorf("could not set register %s: wrong size, expected %d got %d", regName, len(gdbreg.value), len(reg.Bytes))
@@ -1889,11 +2100,11 @@ func (regs *gdbRegisters) Slice(floatingPoint bool) ([]proc.Register, error) {
 		case reginfo.Name == "mxcsr":
 			r = proc.AppendBytesRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
 		case reginfo.Bitsize == 16:
-			r = proc.AppendBytesRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
+			r = regs.appendIntRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
 		case reginfo.Bitsize == 32:
-			r = proc.AppendBytesRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
+			r = regs.appendIntRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
 		case reginfo.Bitsize == 64:
-			r = proc.AppendBytesRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
+			r = regs.appendIntRegister(r, reginfo.Name, regs.regs[reginfo.Name].value)
 		case reginfo.Bitsize == 80:
 			if !floatingPoint {
 				continue
@@ -1938,6 +2149,19 @@ func (regs *gdbRegisters) Slice(floatingPoint bool) ([]proc.Register, error) {
 	return r, nil
 }
 
+// appendIntRegister appends an integer register, whose value is encoded in
+// the byte order of the target, to r.
+func (regs *gdbRegisters) appendIntRegister(r []proc.Register, name string, value []byte) []proc.Register {
+	if regs.arch.ByteOrder() == binary.BigEndian {
+		var v uint64
+		for _, b := range value {
+			v = v<<8 | uint64(b)
+		}
+		return proc.AppendUint64Register(r, name, v)
+	}
+	return proc.AppendBytesRegister(r, name, value)
+}
+
 func (regs *gdbRegisters) Copy() (proc.Registers, error) {
 	savedRegs := &gdbRegisters{}
 	savedRegs.init(regs.regsInfo, regs.arch, regs.regnames)
@@ -39,14 +39,21 @@ type gdbConn struct {
 
 	pid int // cache process id
 
-	ack                   bool // when ack is true acknowledgment packets are enabled
-	multiprocess          bool // multiprocess extensions are active
-	maxTransmitAttempts   int  // maximum number of transmit or receive attempts when bad checksums are read
-	threadSuffixSupported bool // thread suffix supported by stub
-	isDebugserver         bool // true if the stub is debugserver
-	xcmdok                bool // x command can be used to transfer memory
+	ack                   bool   // when ack is true acknowledgment packets are enabled
+	multiprocess          bool   // multiprocess extensions are active
+	maxTransmitAttempts   int    // maximum number of transmit or receive attempts when bad checksums are read
+	threadSuffixSupported bool   // thread suffix supported by stub
+	isDebugserver         bool   // true if the stub is debugserver
+	hostOS                string // operating system reported by qHostInfo, empty if the stub does not support it
+	xcmdok                bool   // x command can be used to transfer memory
+	vcontok               bool   // vCont command can be used to resume threads
+
+	asyncPreemptSignal uint8                    // number used by the stub for the asynchronous preemption signal
+	asyncPreempt       proc.AsyncPreemptCounter // asynchronous preemption signals delivered or discarded without stopping
 
 	log *logrus.Entry
+
+	packets packetLog // recent packets, see RecentPackets
 }
 
 var ErrTooManyAttempts = errors.New("too many transmit attempts")
@@ -136,6 +143,12 @@ func (conn *gdbConn) handshake(regnames *gdbRegnames) error {
 		if _, err := conn.qSupported(false); err != nil {
 			return err
 		}
+
+		// lldb-server numbers signals like the operating system it runs on,
+		// instead of using the numbering defined by GDB, ask which one it is.
+		if info, err := conn.queryHostInfo(); err == nil {
+			conn.hostOS = info["ostype"]
+		}
 	}
 
 	// Attempt to figure out the name of the processor register.
@@ -182,9 +195,33 @@ func (conn *gdbConn) handshake(regnames *gdbRegnames) error {
 		conn.xcmdok = true
 	}
 
+	conn.vcontok = conn.supportsVCont()
+
 	return nil
 }
 
+// passSignals asks the stub to deliver the specified signals directly to
+// the target process, without stopping it. The list replaces the one sent
+// previously. Stubs that do not support the QPassSignals packet will keep
+// reporting them, those signals are then propagated to the target by
+// ContinueOnce.
+func (conn *gdbConn) passSignals(sigs []uint8) error {
+	conn.outbuf.Reset()
+	fmt.Fprint(&conn.outbuf, "$QPassSignals:")
+	for i, sig := range sigs {
+		if i > 0 {
+			conn.outbuf.WriteByte(';')
+		}
+		fmt.Fprintf(&conn.outbuf, "%02x", sig)
+	}
+	_, err := conn.exec(conn.outbuf.Bytes(), "init/passSignals")
+	if isProtocolErrorUnsupported(err) {
+		conn.log.Debugf("QPassSignals not supported by stub")
+		return nil
+	}
+	return err
+}
+
 // qSupported interprets qSupported responses.
 func (conn *gdbConn) qSupported(multiprocess bool) (features map[string]bool, err error) {
 	q := qSupportedSimple
@@ -369,6 +406,58 @@ func (conn *gdbConn) readAnnex(annex string) ([]gdbRegisterInfo, error) {
 	return tgt.Registers, nil
 }
 
+// gdbMemoryMap is used to parse the memory map returned by
+// qXfer:memory-map:read, described by:
+//  https://sourceware.org/gdb/onlinedocs/gdb/Memory-Map-Format.html
+type gdbMemoryMap struct {
+	Regions []gdbMemoryRegion `xml:"memory"`
+}
+
+type gdbMemoryRegion struct {
+	Type   string `xml:"type,attr"`
+	Start  string `xml:"start,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// readMemoryMap reads the memory map of the target with
+// qXfer:memory-map:read, this is supported by stubs that do not support
+// qMemoryRegionInfo, like the ones of qemu-system and embedded probes.
+func (conn *gdbConn) readMemoryMap() ([]proc.MemoryMapEntry, error) {
+	buf, err := conn.qXfer("memory-map", "", false)
+	if err != nil {
+		return nil, err
+	}
+	return parseMemoryMap(buf)
+}
+
+func parseMemoryMap(buf []byte) ([]proc.MemoryMapEntry, error) {
+	var mm gdbMemoryMap
+	if err := xml.Unmarshal(buf, &mm); err != nil {
+		return nil, fmt.Errorf("malformed memory map: %v", err)
+	}
+	r := make([]proc.MemoryMapEntry, 0, len(mm.Regions))
+	for _, region := range mm.Regions {
+		start, err := strconv.ParseUint(region.Start, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed memory map (start): %v", err)
+		}
+		length, err := strconv.ParseUint(region.Length, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed memory map (length): %v", err)
+		}
+		// The memory map does not describe permissions, only RAM is
+		// writable (flash needs to be erased first).
+		r = append(r, proc.MemoryMapEntry{
+			Addr:  start,
+			Size:  length,
+			Read:  true,
+			Write: region.Type == "ram",
+			Exec:  true,
+		})
+	}
+	return r, nil
+}
+
 func (conn *gdbConn) readExecFile() (string, error) {
 	outbuf, err := conn.qXfer("exec-file", "", true)
 	if err != nil {
@@ -538,7 +627,8 @@ func (conn *gdbConn) writeRegister(threadID string, regnum int, data []byte) err
 // otherwise the 'C' action will be used and the value of sig will be passed
 // to it.
 func (conn *gdbConn) resume(threads map[int]*gdbThread, tu *threadUpdater) (string, uint8, error) {
-	if conn.direction == proc.Forward {
+	switch {
+	case conn.direction == proc.Forward && conn.vcontok:
 		conn.outbuf.Reset()
 		fmt.Fprintf(&conn.outbuf, "$vCont")
 		for _, th := range threads {
@@ -547,7 +637,23 @@ func (conn *gdbConn) resume(threads map[int]*gdbThread, tu *threadUpdater) (stri
 			}
 		}
 		fmt.Fprintf(&conn.outbuf, ";c")
-	} else {
+	case conn.direction == proc.Forward:
+		// Without vCont only one signal can be delivered, to the current
+		// thread of the stub.
+		var sig uint8
+		for _, th := range threads {
+			if th.sig != 0 {
+				sig = th.sig
+				break
+			}
+		}
+		conn.outbuf.Reset()
+		if sig == 0 {
+			fmt.Fprint(&conn.outbuf, "$c")
+		} else {
+			fmt.Fprintf(&conn.outbuf, "$C%02x", sig)
+		}
+	default:
 		if err := conn.selectThread('c', "p-1.-1", "resume"); err != nil {
 			return "", 0, err
 		}
@@ -587,12 +693,22 @@ func (conn *gdbConn) step(threadID string, tu *threadUpdater, ignoreFaultSignal
 		_, _, err := conn.waitForvContStop("singlestep", threadID, tu)
 		return err
 	}
+	if !conn.vcontok && !conn.threadSuffixSupported {
+		if err := conn.selectThread('c', threadID, "step"); err != nil {
+			return err
+		}
+	}
 	var sig uint8 = 0
 	for {
 		conn.outbuf.Reset()
-		if sig == 0 {
+		switch {
+		case !conn.vcontok && sig == 0:
+			fmt.Fprint(&conn.outbuf, "$s")
+		case !conn.vcontok:
+			fmt.Fprintf(&conn.outbuf, "$S%02x", sig)
+		case sig == 0:
 			fmt.Fprintf(&conn.outbuf, "$vCont;s:%s", threadID)
-		} else {
+		default:
 			fmt.Fprintf(&conn.outbuf, "$vCont;S%02x:%s", sig, threadID)
 		}
 		if err := conn.send(conn.outbuf.Bytes()); err != nil {
@@ -620,6 +736,12 @@ func (conn *gdbConn) step(threadID string, tu *threadUpdater, ignoreFaultSignal
 		case debugServerTargetExcBadAccess, debugServerTargetExcBadInstruction, debugServerTargetExcArithmetic, debugServerTargetExcEmulation, debugServerTargetExcSoftware, debugServerTargetExcBreakpoint:
 			return nil
 		}
+		if sig != 0 && sig == conn.asyncPreemptSignal {
+			// Delivering it would make us step into the signal handler,
+			// discard it instead, the runtime will send it again.
+			conn.asyncPreempt.Hide()
+			sig = 0
+		}
 		// any other signal is propagated to the inferior
 	}
 }
@@ -668,7 +790,8 @@ type stopPacket struct {
 // executes 'vCont' (continue/step) command
 func (conn *gdbConn) parseStopPacket(resp []byte, threadID string, tu *threadUpdater) (repeat bool, sp stopPacket, err error) {
 	switch resp[0] {
-	case 'T':
+	case 'T', 'S':
+		// 'S' is sent by bare stubs, it does not specify the thread.
 		if len(resp) < 3 {
 			return false, stopPacket{}, fmt.Errorf("malformed response for vCont %s", string(resp))
 		}
@@ -756,6 +879,24 @@ func (conn *gdbConn) sendCtrlC() error {
 	return err
 }
 
+// queryHostInfo executes a qHostInfo and returns the key/value pairs of
+// the response.
+func (conn *gdbConn) queryHostInfo() (map[string]string, error) {
+	resp, err := conn.exec([]byte("$qHostInfo"), "init/hostInfo")
+	if err != nil {
+		return nil, err
+	}
+	hi := make(map[string]string)
+	for _, keyval := range strings.Split(string(resp), ";") {
+		colon := strings.Index(keyval, ":")
+		if colon < 0 {
+			continue
+		}
+		hi[keyval[:colon]] = keyval[colon+1:]
+	}
+	return hi, nil
+}
+
 // queryProcessInfo executes a qProcessInfoPID (if pid != 0) or a qProcessInfo (if pid == 0)
 func (conn *gdbConn) queryProcessInfo(pid int) (map[string]string, error) {
 	conn.outbuf.Reset()
@@ -854,6 +995,27 @@ func (conn *gdbConn) queryThreads(first bool) (threads []string, err error) {
 	return threads, nil
 }
 
+// supportsVCont executes a 'vCont?' command, bare stubs (for example the
+// ones of some embedded probes) do not support vCont and only support the
+// 'c' and 's' commands.
+func (conn *gdbConn) supportsVCont() bool {
+	resp, err := conn.exec([]byte("$vCont?"), "init")
+	return err == nil && bytes.HasPrefix(resp, []byte("vCont"))
+}
+
+// currentThread executes a 'qC' command, it is used to find the only
+// thread of stubs that do not support qfThreadInfo.
+func (conn *gdbConn) currentThread() (string, error) {
+	resp, err := conn.exec([]byte("$qC"), "current thread")
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(resp, []byte("QC")) {
+		return "", fmt.Errorf("malformed qC response %q", string(resp))
+	}
+	return string(resp[2:]), nil
+}
+
 func (conn *gdbConn) selectThread(kind byte, threadID string, context string) error {
 	if conn.threadSuffixSupported {
 		panic("selectThread when thread suffix is supported")
@@ -1158,6 +1320,7 @@ func (conn *gdbConn) send(cmd []byte) error {
 				conn.log.Debugf("<- %s", string(cmd))
 			}
 		}
+		conn.packets.add(true, cmd, nil)
 		_, err := conn.conn.Write(cmd)
 		if err != nil {
 			return err
@@ -1192,6 +1355,7 @@ func (conn *gdbConn) recv(cmd []byte, context string, binary bool) (resp []byte,
 		if err != nil {
 			return nil, err
 		}
+		conn.packets.add(false, resp, cmd)
 		if logflags.GdbWire() {
 			out := resp
 			partial := false
@@ -0,0 +1,251 @@
+package gdbserial
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// fakeStub answers the packets it receives on conn with the responses in
+// resps, in order, and sends the packets it received, without checksum,
+// to the returned channel.
+func fakeStub(conn net.Conn, resps ...string) <-chan string {
+	packets := make(chan string, len(resps))
+	go func() {
+		defer close(packets)
+		defer conn.Close()
+		rdr := bufio.NewReader(conn)
+		for _, resp := range resps {
+			packet, err := rdr.ReadString('#')
+			if err != nil {
+				return
+			}
+			if _, err := io.ReadFull(rdr, make([]byte, 2)); err != nil {
+				return
+			}
+			packets <- packet[:len(packet)-1]
+			resp = "$" + resp + "#"
+			fmt.Fprintf(conn, "%s%02x", resp, checksum([]byte(resp)))
+		}
+	}()
+	return packets
+}
+
+// newFakeStubProcess returns a process connected to a fakeStub, the
+// caller must close p.conn.conn.
+func newFakeStubProcess(resps ...string) (*gdbProcess, <-chan string) {
+	client, server := net.Pipe()
+	p := newProcess(nil)
+	p.conn.conn = client
+	p.conn.rdr = bufio.NewReader(client)
+	return p, fakeStub(server, resps...)
+}
+
+func TestPassSignalsPacket(t *testing.T) {
+	for _, tc := range []struct {
+		hostOS string
+		names  []string
+		packet string
+	}{
+		// gdbserver, qemu, rr and debugserver
+		{"", nil, "$QPassSignals:10;1b"},
+		{"macosx", nil, "$QPassSignals:10;1b"},
+		{"", []string{"SIGUSR1", "urg", "CHLD"}, "$QPassSignals:10;14;1e"},
+		{"", []string{}, "$QPassSignals:"},
+		// lldb-server on linux
+		{"linux", nil, "$QPassSignals:17;1b"},
+		{"linux", []string{"SIGUSR1", "urg", "CHLD"}, "$QPassSignals:0a;11;17"},
+	} {
+		p, packets := newFakeStubProcess("OK")
+		p.conn.hostOS = tc.hostOS
+		var err error
+		if tc.names == nil {
+			err = p.conn.passSignals(p.passSignalNumbers())
+		} else {
+			err = p.SetPassSignals(tc.names)
+		}
+		if err != nil {
+			t.Fatalf("%q %v: %v", tc.hostOS, tc.names, err)
+		}
+		if packet := <-packets; packet != tc.packet {
+			t.Errorf("%q %v: got %q expected %q", tc.hostOS, tc.names, packet, tc.packet)
+		}
+		p.conn.conn.Close()
+	}
+}
+
+func TestSetPassSignalsUnknown(t *testing.T) {
+	p, _ := newFakeStubProcess()
+	defer p.conn.conn.Close()
+	if err := p.SetPassSignals([]string{"SIGURG", "SIGFOO"}); err == nil {
+		t.Fatal("expected error for unknown signal")
+	}
+}
+
+func TestQueryHostInfo(t *testing.T) {
+	p, packets := newFakeStubProcess("cputype:16777223;cpusubtype:3;ostype:linux;vendor:unknown;endian:little;ptrsize:8;")
+	defer p.conn.conn.Close()
+	info, err := p.conn.queryHostInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if packet := <-packets; packet != "$qHostInfo" {
+		t.Errorf("got packet %q", packet)
+	}
+	if info["ostype"] != "linux" || info["ptrsize"] != "8" {
+		t.Errorf("wrong host info %v", info)
+	}
+}
+
+const testMemoryMap = `<?xml version="1.0"?>
+<!DOCTYPE memory-map PUBLIC "+//IDN gnu.org//DTD GDB Memory Map V1.0//EN" "http://sourceware.org/gdb/gdb-memory-map.dtd">
+<memory-map>
+  <memory type="flash" start="0x8000000" length="0x100000">
+    <property name="blocksize">0x20000</property>
+  </memory>
+  <memory type="ram" start="0x20000000" length="0x20000"/>
+  <memory type="rom" start="0x1fff0000" length="0x7800"/>
+</memory-map>`
+
+func TestParseMemoryMap(t *testing.T) {
+	mm, err := parseMemoryMap([]byte(testMemoryMap))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []proc.MemoryMapEntry{
+		{Addr: 0x8000000, Size: 0x100000, Read: true, Exec: true},
+		{Addr: 0x20000000, Size: 0x20000, Read: true, Write: true, Exec: true},
+		{Addr: 0x1fff0000, Size: 0x7800, Read: true, Exec: true},
+	}
+	if !reflect.DeepEqual(mm, expected) {
+		t.Errorf("got %#v expected %#v", mm, expected)
+	}
+
+	for _, buf := range []string{
+		`<memory-map><memory type="ram" start="zero" length="0x10"/></memory-map>`,
+		`<memory-map><memory type="ram" start="0x10" length=""/></memory-map>`,
+		`<memory-map><memory`,
+	} {
+		if _, err := parseMemoryMap([]byte(buf)); err == nil {
+			t.Errorf("no error parsing %q", buf)
+		}
+	}
+}
+
+func TestMemoryMapFallback(t *testing.T) {
+	// Stubs that do not support qMemoryRegionInfo are asked for the memory
+	// map with qXfer:memory-map:read.
+	p, packets := newFakeStubProcess("", "l"+testMemoryMap)
+	defer p.conn.conn.Close()
+	mm, err := p.MemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"$qMemoryRegionInfo:0", "$qXfer:memory-map:read::0,fff"} {
+		if packet := <-packets; packet != expected {
+			t.Errorf("got packet %q expected %q", packet, expected)
+		}
+	}
+	if len(mm) != 3 || !mm[1].Write || mm[1].Addr != 0x20000000 {
+		t.Errorf("wrong memory map %#v", mm)
+	}
+}
+
+func TestNoVCont(t *testing.T) {
+	// Stubs that do not support vCont are resumed with c/C and stepped with
+	// s/S on the thread selected with Hc.
+	p, packets := newFakeStubProcess("", "S05", "OK", "S05")
+	defer p.conn.conn.Close()
+	if p.conn.supportsVCont() {
+		t.Fatal("vCont supported after an empty response to vCont?")
+	}
+	threads := map[int]*gdbThread{42: {ID: 42, strID: "2a", sig: 0xb, p: p}}
+	threadID, sig, err := p.conn.resume(threads, nil)
+	if err != nil {
+		t.Fatalf("resume: %v", err)
+	}
+	if threadID != "" || sig != 5 {
+		t.Errorf("resume: got %q %d", threadID, sig)
+	}
+	if err := p.conn.step("2a", nil, false); err != nil {
+		t.Fatalf("step: %v", err)
+	}
+	for _, expected := range []string{"$vCont?", "$C0b", "$Hc2a", "$s"} {
+		if packet := <-packets; packet != expected {
+			t.Errorf("got packet %q expected %q", packet, expected)
+		}
+	}
+
+	p, _ = newFakeStubProcess("vCont;c;C;s;S")
+	defer p.conn.conn.Close()
+	if !p.conn.supportsVCont() {
+		t.Error("vCont not supported after a vCont? response")
+	}
+}
+
+func TestNoThreadList(t *testing.T) {
+	// Stubs that do not support qfThreadInfo have a single thread, found
+	// with qC or assumed to be 1.
+	for _, tc := range []struct {
+		qC string
+		id int
+	}{
+		{"QC2a", 42},
+		{"", 1},
+	} {
+		p, packets := newFakeStubProcess("", tc.qC)
+		p.threadStopInfo = false
+		if err := p.updateThreadList(&threadUpdater{p: p}); err != nil {
+			t.Fatalf("%q: %v", tc.qC, err)
+		}
+		for _, expected := range []string{"$qfThreadInfo", "$qC"} {
+			if packet := <-packets; packet != expected {
+				t.Errorf("%q: got packet %q expected %q", tc.qC, packet, expected)
+			}
+		}
+		if len(p.threads) != 1 || p.threads[tc.id] == nil || p.currentThread != p.threads[tc.id] {
+			t.Errorf("%q: wrong threads %v", tc.qC, p.threads)
+		}
+		p.conn.conn.Close()
+	}
+}
+
+func TestMemoryBreakpoints(t *testing.T) {
+	// Stubs that do not support Z0 get breakpoints written to memory.
+	p, packets := newFakeStubProcess("", "55", "OK", "55", "OK", "OK", "OK")
+	defer p.conn.conn.Close()
+	p.bi = proc.NewBinaryInfo("linux", "amd64")
+	p.breakpointKind = 1
+	p.conn.packetSize = 256
+
+	bp1 := &proc.Breakpoint{Addr: 0x401000}
+	bp2 := &proc.Breakpoint{Addr: 0x402000}
+	for _, bp := range []*proc.Breakpoint{bp1, bp2} {
+		if err := p.WriteBreakpoint(bp); err != nil {
+			t.Fatalf("WriteBreakpoint(%#x): %v", bp.Addr, err)
+		}
+		if !bytes.Equal(bp.OriginalData, []byte{0x55}) {
+			t.Errorf("WriteBreakpoint(%#x): wrong original data %x", bp.Addr, bp.OriginalData)
+		}
+	}
+	for _, bp := range []*proc.Breakpoint{bp1, bp2} {
+		if err := p.EraseBreakpoint(bp); err != nil {
+			t.Fatalf("EraseBreakpoint(%#x): %v", bp.Addr, err)
+		}
+	}
+	for _, expected := range []string{
+		"$Z0,401000,1", "$m401000,1", "$M401000,1:cc",
+		"$m402000,1", "$M402000,1:cc",
+		"$M401000,1:55", "$M402000,1:55",
+	} {
+		if packet := <-packets; packet != expected {
+			t.Errorf("got packet %q expected %q", packet, expected)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package gdbserial
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxRecentPackets is the number of packets kept by packetLog.
+const maxRecentPackets = 256
+
+// packetLog keeps the most recent packets exchanged with the stub, they
+// are included in diagnostic reports even when the gdbwire log is not
+// enabled.
+// The contents of memory, which could contain sensitive data of the
+// target, is not recorded.
+type packetLog struct {
+	mu      sync.Mutex
+	packets []string
+	next    int
+}
+
+// add records a packet, sent by us if out is true, cmd is the command
+// that packet is a response to.
+func (l *packetLog) add(out bool, packet, cmd []byte) {
+	var s string
+	if out {
+		s = "<- " + redactPacket(packet, nil)
+	} else {
+		s = "-> " + redactPacket(packet, cmd)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.packets) < maxRecentPackets {
+		l.packets = append(l.packets, s)
+		return
+	}
+	l.packets[l.next] = s
+	l.next = (l.next + 1) % maxRecentPackets
+}
+
+// recent returns the recorded packets, oldest first.
+func (l *packetLog) recent() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	r := make([]string, 0, len(l.packets))
+	r = append(r, l.packets[l.next:]...)
+	r = append(r, l.packets[:l.next]...)
+	return r
+}
+
+// redactPacket returns a printable version of packet, truncated to
+// gdbWireMaxLen, without the contents of memory. If cmd is not nil packet
+// is the response to cmd.
+func redactPacket(packet, cmd []byte) string {
+	if cmd != nil {
+		if len(cmd) > 1 && (cmd[1] == 'm' || cmd[1] == 'x') && len(packet) > 1 && packet[1] != 'E' {
+			return fmt.Sprintf("$<%d bytes of memory redacted>", len(packet)-2)
+		}
+	} else if len(packet) > 1 && (packet[1] == 'M' || packet[1] == 'X') {
+		if colon := strings.IndexByte(string(packet), ':'); colon >= 0 {
+			return fmt.Sprintf("%s:<%d bytes of memory redacted>", packet[:colon], len(packet)-colon-4)
+		}
+	}
+	if len(packet) > gdbWireMaxLen {
+		return fmt.Sprintf("%q...", packet[:gdbWireMaxLen])
+	}
+	return fmt.Sprintf("%q", packet)
+}
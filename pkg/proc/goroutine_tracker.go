@@ -0,0 +1,117 @@
+package proc
+
+import (
+	"fmt"
+	"sort"
+)
+
+// goroutineTracker remembers the goroutines listed at the previous stops
+// of the target so that the goroutines created and terminated since the
+// previous stop can be marked.
+type goroutineTracker struct {
+	// prev contains the goroutines alive the last time the goroutines were
+	// listed at a previous stop, cur the goroutines listed since the target
+	// stopped.
+	prev, cur map[int]bool
+	// prevComplete and curComplete are set if all the goroutines of the
+	// corresponding stop were listed.
+	prevComplete, curComplete bool
+}
+
+// seen records that goroutine id is alive at the current stop.
+func (gt *goroutineTracker) seen(id int) {
+	if gt.cur == nil {
+		gt.cur = make(map[int]bool)
+	}
+	gt.cur[id] = true
+}
+
+// complete records that all goroutines of the current stop have been seen.
+func (gt *goroutineTracker) complete() {
+	gt.curComplete = true
+}
+
+// resumed is called when the target is resumed. If the goroutines were
+// listed during the stop that just ended it becomes the previous stop,
+// otherwise the previous stop is kept so that new and terminated
+// goroutines are always reported relative to the last listing the user
+// saw.
+func (gt *goroutineTracker) resumed() {
+	if len(gt.cur) == 0 {
+		return
+	}
+	gt.prev, gt.prevComplete = gt.cur, gt.curComplete
+	gt.cur, gt.curComplete = nil, false
+}
+
+// IsNewGoroutine returns true if goroutine gid did not exist the last
+// time all goroutines were listed at a previous stop.
+func (t *Target) IsNewGoroutine(gid int) bool {
+	gt := &t.gtracker
+	return gt.prevComplete && !gt.prev[gid]
+}
+
+// TerminatedGoroutines returns the IDs of the goroutines that existed the
+// last time all goroutines were listed at a previous stop and that
+// terminated since. Returns nil unless all goroutines of both stops have
+// been listed.
+func (t *Target) TerminatedGoroutines() []int {
+	gt := &t.gtracker
+	if !gt.prevComplete || !gt.curComplete {
+		return nil
+	}
+	var r []int
+	for gid := range gt.prev {
+		if !gt.cur[gid] {
+			r = append(r, gid)
+		}
+	}
+	sort.Ints(r)
+	return r
+}
+
+// GoroutineName returns the name assigned to goroutine gid with
+// SetGoroutineName or the empty string.
+func (t *Target) GoroutineName(gid int) string {
+	a, _ := t.GoroutineAnnotation(gid)
+	return a.Name
+}
+
+// SetGoroutineName names goroutine gid, named goroutines are pinned at
+// the start of goroutine listings, see SortGoroutines. An empty name
+// removes the name of the goroutine. Names must be unique.
+// Goroutine IDs are never reused so the names are kept after the
+// goroutines terminate.
+func (t *Target) SetGoroutineName(gid int, name string) error {
+	a, _ := t.GoroutineAnnotation(gid)
+	if name != "" {
+		if err := t.annotations.checkGoroutineName(gid, name); err != nil {
+			return err
+		}
+		g, err := FindGoroutine(t, gid)
+		if err != nil {
+			return err
+		}
+		if g == nil {
+			return fmt.Errorf("unknown goroutine %d", gid)
+		}
+	}
+	a.GoroutineID = gid
+	a.Name = name
+	return t.SetAnnotation(a)
+}
+
+// SortGoroutines sorts gs in the order they should be presented to the
+// user: named goroutines first, then all other goroutines, ordered by ID.
+// Goroutine IDs are never reused and increase monotonically, this order
+// is therefore stable across stops.
+func (t *Target) SortGoroutines(gs []*G) {
+	sort.SliceStable(gs, func(i, j int) bool {
+		namedi := t.GoroutineName(gs[i].ID) != ""
+		namedj := t.GoroutineName(gs[j].ID) != ""
+		if namedi != namedj {
+			return namedi
+		}
+		return gs[i].ID < gs[j].ID
+	})
+}
@@ -18,6 +18,7 @@ func I386Arch(goos string) *Arch {
 	return &Arch{
 		Name:                             "386",
 		ptrSize:                          4,
+		byteOrder:                        binary.LittleEndian,
 		maxInstructionLength:             15,
 		breakpointInstruction:            i386BreakInstruction,
 		altBreakpointInstruction:         []byte{0xcd, 0x03},
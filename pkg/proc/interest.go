@@ -0,0 +1,139 @@
+package proc
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"reflect"
+)
+
+// Clients that display the local variables at every stop, like IDEs that
+// expand them automatically, usually only look at a few fields of a few
+// variables. Loading all locals with the configuration used to display them
+// can be expensive, LocalVariablesInterest loads the variables shallowly
+// and only follows the selector paths the client declared an interest in.
+
+// shallowLoadConfig returns the configuration used to load the variables
+// outside of the interest paths: structs are loaded one level deep and
+// pointers are not followed.
+func shallowLoadConfig(cfg LoadConfig) LoadConfig {
+	return LoadConfig{
+		FollowPointers:     false,
+		MaxVariableRecurse: 0,
+		MaxStringLen:       cfg.MaxStringLen,
+		MaxArrayValues:     0,
+		MaxStructFields:    -1,
+	}
+}
+
+// parseInterestPath parses path, a chain of selectors on a variable like
+// "req.URL.Path", into the name of the variable and the names of the
+// fields.
+func parseInterestPath(path string) ([]string, error) {
+	expr, err := parser.ParseExpr(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid interest path %q: %v", path, err)
+	}
+	var r []string
+	for {
+		switch node := expr.(type) {
+		case *ast.SelectorExpr:
+			r = append(r, node.Sel.Name)
+			expr = node.X
+			continue
+		case *ast.ParenExpr:
+			expr = node.X
+			continue
+		case *ast.Ident:
+			r = append(r, node.Name)
+			for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+				r[i], r[j] = r[j], r[i]
+			}
+			return r, nil
+		}
+		return nil, fmt.Errorf("invalid interest path %q: not a variable followed by field selectors", path)
+	}
+}
+
+// LocalVariablesInterest returns the local variables of scope, like
+// LocalVariables, but only the values reached by the selector paths in
+// paths (for example "req.URL.Path") are loaded using cfg, the other
+// variables are loaded shallowly: pointers are not followed and the
+// fields of nested structs, the elements of arrays, slices and maps are
+// not loaded.
+func (scope *EvalScope) LocalVariablesInterest(cfg LoadConfig, paths []string) ([]*Variable, error) {
+	parsed := make([][]string, len(paths))
+	for i := range paths {
+		var err error
+		parsed[i], err = parseInterestPath(paths[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	vars, err := scope.Locals()
+	if err != nil {
+		return nil, err
+	}
+	vars = filterVariables(vars, func(v *Variable) bool {
+		return (v.Flags & (VariableArgument | VariableReturnArgument)) == 0
+	})
+	cfg.MaxMapBuckets = maxMapBucketsFactor * cfg.MaxArrayValues
+	shallow := shallowLoadConfig(cfg)
+	for _, v := range vars {
+		v.loadValue(shallow)
+		if v.Flags&VariableShadowed != 0 {
+			continue
+		}
+		for _, path := range parsed {
+			if path[0] == v.Name {
+				v.loadInterestPath(path[1:], shallow, cfg)
+			}
+		}
+	}
+	return vars, nil
+}
+
+// loadInterestPath loads the value reached by following the fields in
+// path from v using cfg. The variables traversed to reach it are loaded
+// with shallow if they were not loaded already.
+func (v *Variable) loadInterestPath(path []string, shallow, cfg LoadConfig) {
+	if v.Unreadable != nil {
+		return
+	}
+	if len(path) == 0 {
+		v.reload(cfg)
+		return
+	}
+	switch v.Kind {
+	case reflect.Ptr, reflect.Interface:
+		if len(v.Children) != 1 {
+			v.reload(shallow)
+		}
+		if len(v.Children) != 1 {
+			return
+		}
+		child := &v.Children[0]
+		if child.OnlyAddr || !child.loaded {
+			child.OnlyAddr = false
+			child.reload(shallow)
+		}
+		child.loadInterestPath(path, shallow, cfg)
+	case reflect.Struct:
+		if len(v.Children) == 0 {
+			v.reload(shallow)
+		}
+		for i := range v.Children {
+			if v.Children[i].Name == path[0] {
+				v.Children[i].loadInterestPath(path[1:], shallow, cfg)
+				return
+			}
+		}
+	}
+}
+
+// reload discards the loaded value of v and loads it again using cfg.
+func (v *Variable) reload(cfg LoadConfig) {
+	v.loaded = false
+	v.Children = nil
+	v.loadValueInternal(0, cfg)
+}
@@ -49,6 +49,44 @@ type ProcessInternal interface {
 	MemoryMap() ([]MemoryMapEntry, error)
 }
 
+// PacketLogger is implemented by the processes that communicate with a
+// debugging stub, like gdbserver, debugserver or rr.
+type PacketLogger interface {
+	// RecentPackets returns the most recent packets exchanged with the stub,
+	// oldest first, with the contents of memory redacted.
+	RecentPackets() []string
+}
+
+// SignalPasser is implemented by the processes whose debugging stub can
+// deliver signals to the target without stopping it.
+type SignalPasser interface {
+	// SetPassSignals sets the names of the signals that are delivered to
+	// the target without stopping it.
+	SetPassSignals(names []string) error
+}
+
+// ForkFollower is implemented by the processes that can follow the child
+// processes they create.
+type ForkFollower interface {
+	// FollowFork enables or disables following child processes. While it is
+	// enabled the child created by a fork, and every followed process that
+	// calls exec, is debugged by a new target, stopped, which is passed to
+	// newTarget together with the pid of its parent. The StopReason of the
+	// target is StopForked or StopExeced, after an exec the target that was
+	// debugging the process exits.
+	// The child of a vfork shares the memory of its parent and is only
+	// debugged once it calls exec. If match is not nil the children for
+	// which it returns false are not debugged either: they are followed
+	// until they call exec, then debugged if match returns true and
+	// detached otherwise.
+	// newTarget is called during ContinueOnce and AttachChild.
+	FollowFork(enabled bool, match func(pid int) bool, newTarget func(t *Target, parentPid int)) error
+	// AttachChild attaches to process pid, a descendant of the process
+	// created before forks were followed, its target is passed to the
+	// newTarget function of FollowFork with StopReason StopAttached.
+	AttachChild(pid, parentPid int) error
+}
+
 // RecordingManipulation is an interface for manipulating process recordings.
 type RecordingManipulation interface {
 	// Recorded returns true if the current process is a recording and the path
@@ -17,15 +17,15 @@ const (
 // Supplement, section 3.4.3.
 // System V Application Binary Interface, Intel386 Architecture Processor
 // Supplement (fourth edition), section 3-28.
-func EntryPointFromAuxv(auxv []byte, ptrSize int) uint64 {
+func EntryPointFromAuxv(auxv []byte, ptrSize int, order binary.ByteOrder) uint64 {
 	rd := bytes.NewBuffer(auxv)
 
 	for {
-		tag, err := readUintRaw(rd, binary.LittleEndian, ptrSize)
+		tag, err := readUintRaw(rd, order, ptrSize)
 		if err != nil {
 			return 0
 		}
-		val, err := readUintRaw(rd, binary.LittleEndian, ptrSize)
+		val, err := readUintRaw(rd, order, ptrSize)
 		if err != nil {
 			return 0
 		}
@@ -56,10 +56,10 @@ func dynamicSearchDebug(p proc.Process) (uint64, error) {
 
 	for {
 		var tag, val uint64
-		if tag, err = readUintRaw(rd, binary.LittleEndian, p.BinInfo().Arch.PtrSize()); err != nil {
+		if tag, err = readUintRaw(rd, p.BinInfo().Arch.ByteOrder(), p.BinInfo().Arch.PtrSize()); err != nil {
 			return 0, err
 		}
-		if val, err = readUintRaw(rd, binary.LittleEndian, p.BinInfo().Arch.PtrSize()); err != nil {
+		if val, err = readUintRaw(rd, p.BinInfo().Arch.ByteOrder(), p.BinInfo().Arch.PtrSize()); err != nil {
 			return 0, err
 		}
 		switch tag {
@@ -77,7 +77,7 @@ func readPtr(p proc.Process, addr uint64) (uint64, error) {
 	if err != nil {
 		return 0, err
 	}
-	return readUintRaw(bytes.NewReader(ptrbuf), binary.LittleEndian, p.BinInfo().Arch.PtrSize())
+	return readUintRaw(bytes.NewReader(ptrbuf), p.BinInfo().Arch.ByteOrder(), p.BinInfo().Arch.PtrSize())
 }
 
 type linkMap struct {
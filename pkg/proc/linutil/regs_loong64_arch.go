@@ -0,0 +1,123 @@
+package linutil
+
+import (
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// LOONG64Registers implements the proc.Registers interface for the native/linux
+// backend and core/linux backends, on LOONG64.
+type LOONG64Registers struct {
+	Regs     *LOONG64PtraceRegs // general-purpose registers
+	iscgo    bool
+	Fpregs   []proc.Register // formatted floating point registers
+	Fpregset []byte          // holding all floating point register values
+
+	loadFpRegs func(*LOONG64Registers) error
+}
+
+func NewLOONG64Registers(regs *LOONG64PtraceRegs, iscgo bool, loadFpRegs func(*LOONG64Registers) error) *LOONG64Registers {
+	return &LOONG64Registers{Regs: regs, iscgo: iscgo, loadFpRegs: loadFpRegs}
+}
+
+// LOONG64PtraceRegs is the struct used by the linux kernel to return the
+// general purpose registers for LOONG64 CPUs (struct user_pt_regs).
+type LOONG64PtraceRegs struct {
+	Regs     [32]uint64 // R0 through R31
+	OrigA0   uint64
+	Era      uint64 // exception return address, the PC of the stopped thread
+	Badv     uint64
+	Reserved [10]uint64
+}
+
+// Slice returns the registers as a list of (name, value) pairs.
+func (r *LOONG64Registers) Slice(floatingPoint bool) ([]proc.Register, error) {
+	out := make([]proc.Register, 0, len(r.Regs.Regs)+1+len(r.Fpregs))
+	for i := range r.Regs.Regs {
+		out = proc.AppendUint64Register(out, fmt.Sprintf("R%d", i), r.Regs.Regs[i])
+	}
+	out = proc.AppendUint64Register(out, "PC", r.Regs.Era)
+	var floatLoadError error
+	if floatingPoint {
+		if r.loadFpRegs != nil {
+			floatLoadError = r.loadFpRegs(r)
+			r.loadFpRegs = nil
+		}
+		out = append(out, r.Fpregs...)
+	}
+	return out, floatLoadError
+}
+
+// PC returns the value of the ERA register.
+func (r *LOONG64Registers) PC() uint64 {
+	return r.Regs.Era
+}
+
+// SP returns the value of R3.
+func (r *LOONG64Registers) SP() uint64 {
+	return r.Regs.Regs[3]
+}
+
+// BP returns the value of R22, which holds the current g in Go code.
+func (r *LOONG64Registers) BP() uint64 {
+	return r.Regs.Regs[22]
+}
+
+// TLS returns the address of the thread local storage memory segment,
+// which is stored in the thread pointer register R2.
+func (r *LOONG64Registers) TLS() uint64 {
+	if !r.iscgo {
+		return 0
+	}
+	return r.Regs.Regs[2]
+}
+
+// GAddr returns the address of the G variable if it is known, 0 and false
+// otherwise.
+func (r *LOONG64Registers) GAddr() (uint64, bool) {
+	return r.Regs.Regs[22], !r.iscgo
+}
+
+// Copy returns a copy of these registers that is guaranteed not to change.
+func (r *LOONG64Registers) Copy() (proc.Registers, error) {
+	if r.loadFpRegs != nil {
+		err := r.loadFpRegs(r)
+		r.loadFpRegs = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+	var rr LOONG64Registers
+	rr.iscgo = r.iscgo
+	rr.Regs = &LOONG64PtraceRegs{}
+	*(rr.Regs) = *(r.Regs)
+	if r.Fpregs != nil {
+		rr.Fpregs = make([]proc.Register, len(r.Fpregs))
+		copy(rr.Fpregs, r.Fpregs)
+	}
+	if r.Fpregset != nil {
+		rr.Fpregset = make([]byte, len(r.Fpregset))
+		copy(rr.Fpregset, r.Fpregset)
+	}
+	return &rr, nil
+}
+
+// LOONG64PtraceFpRegs is the struct used by the linux kernel to return the
+// floating point registers for LOONG64 CPUs (struct user_fp_state).
+type LOONG64PtraceFpRegs struct {
+	F    [32]uint64
+	Fcc  uint64
+	Fcsr uint32
+}
+
+// Decode returns the floating point registers as a list of (name, value)
+// pairs.
+func (fpregs *LOONG64PtraceFpRegs) Decode() (regs []proc.Register) {
+	for i, v := range fpregs.F {
+		regs = proc.AppendUint64Register(regs, fmt.Sprintf("F%d", i), v)
+	}
+	regs = proc.AppendUint64Register(regs, "FCC", fpregs.Fcc)
+	regs = proc.AppendUint64Register(regs, "FCSR", uint64(fpregs.Fcsr))
+	return
+}
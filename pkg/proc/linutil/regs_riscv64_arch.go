@@ -0,0 +1,122 @@
+package linutil
+
+import (
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// RISCV64Registers implements the proc.Registers interface for the
+// core/linux backend, on RISCV64.
+type RISCV64Registers struct {
+	Regs   *RISCV64PtraceRegs // general-purpose registers
+	iscgo  bool
+	Fpregs []proc.Register // formatted floating point registers
+
+	loadFpRegs func(*RISCV64Registers) error
+}
+
+func NewRISCV64Registers(regs *RISCV64PtraceRegs, iscgo bool, loadFpRegs func(*RISCV64Registers) error) *RISCV64Registers {
+	return &RISCV64Registers{Regs: regs, iscgo: iscgo, loadFpRegs: loadFpRegs}
+}
+
+// RISCV64PtraceRegs is the struct used by the linux kernel to return the
+// general purpose registers for RISCV64 CPUs (struct user_regs_struct),
+// the slot of the hardwired zero register X0 is used for the PC.
+type RISCV64PtraceRegs struct {
+	Pc   uint64
+	Regs [31]uint64 // X1 through X31
+}
+
+// X returns the value of register Xn.
+func (r *RISCV64PtraceRegs) X(n int) uint64 {
+	if n == 0 {
+		return 0
+	}
+	return r.Regs[n-1]
+}
+
+// Slice returns the registers as a list of (name, value) pairs.
+func (r *RISCV64Registers) Slice(floatingPoint bool) ([]proc.Register, error) {
+	out := make([]proc.Register, 0, len(r.Regs.Regs)+1+len(r.Fpregs))
+	for i := range r.Regs.Regs {
+		out = proc.AppendUint64Register(out, fmt.Sprintf("X%d", i+1), r.Regs.Regs[i])
+	}
+	out = proc.AppendUint64Register(out, "PC", r.Regs.Pc)
+	var floatLoadError error
+	if floatingPoint {
+		if r.loadFpRegs != nil {
+			floatLoadError = r.loadFpRegs(r)
+			r.loadFpRegs = nil
+		}
+		out = append(out, r.Fpregs...)
+	}
+	return out, floatLoadError
+}
+
+// PC returns the value of the PC register.
+func (r *RISCV64Registers) PC() uint64 {
+	return r.Regs.Pc
+}
+
+// SP returns the value of X2.
+func (r *RISCV64Registers) SP() uint64 {
+	return r.Regs.X(2)
+}
+
+// BP returns the value of X8, which is not used as a frame pointer by Go.
+func (r *RISCV64Registers) BP() uint64 {
+	return r.Regs.X(8)
+}
+
+// TLS returns the address of the thread local storage memory segment,
+// which is stored in the thread pointer register X4.
+func (r *RISCV64Registers) TLS() uint64 {
+	if !r.iscgo {
+		return 0
+	}
+	return r.Regs.X(4)
+}
+
+// GAddr returns the address of the G variable if it is known, 0 and false
+// otherwise.
+func (r *RISCV64Registers) GAddr() (uint64, bool) {
+	return r.Regs.X(27), !r.iscgo
+}
+
+// Copy returns a copy of these registers that is guaranteed not to change.
+func (r *RISCV64Registers) Copy() (proc.Registers, error) {
+	if r.loadFpRegs != nil {
+		err := r.loadFpRegs(r)
+		r.loadFpRegs = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+	var rr RISCV64Registers
+	rr.iscgo = r.iscgo
+	rr.Regs = &RISCV64PtraceRegs{}
+	*(rr.Regs) = *(r.Regs)
+	if r.Fpregs != nil {
+		rr.Fpregs = make([]proc.Register, len(r.Fpregs))
+		copy(rr.Fpregs, r.Fpregs)
+	}
+	return &rr, nil
+}
+
+// RISCV64PtraceFpRegs is the struct used by the linux kernel to return the
+// floating point registers for RISCV64 CPUs (struct __riscv_d_ext_state).
+type RISCV64PtraceFpRegs struct {
+	F    [32]uint64
+	Fcsr uint32
+}
+
+// Decode returns the floating point registers as a list of (name, value)
+// pairs.
+func (fpregs *RISCV64PtraceFpRegs) Decode() (regs []proc.Register) {
+	for i, v := range fpregs.F {
+		regs = proc.AppendUint64Register(regs, fmt.Sprintf("F%d", i), v)
+	}
+	regs = proc.AppendUint64Register(regs, "FCSR", uint64(fpregs.Fcsr))
+	return
+}
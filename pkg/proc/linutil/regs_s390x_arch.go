@@ -0,0 +1,127 @@
+package linutil
+
+import (
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// S390XRegisters implements the proc.Registers interface for the
+// core/linux and gdbserial backends, on S390X.
+type S390XRegisters struct {
+	Regs   *S390XPtraceRegs // general-purpose registers
+	iscgo  bool
+	Fpregs []proc.Register // formatted floating point registers
+
+	loadFpRegs func(*S390XRegisters) error
+}
+
+func NewS390XRegisters(regs *S390XPtraceRegs, iscgo bool, loadFpRegs func(*S390XRegisters) error) *S390XRegisters {
+	return &S390XRegisters{Regs: regs, iscgo: iscgo, loadFpRegs: loadFpRegs}
+}
+
+// S390XPtraceRegs is the struct used by the linux kernel to return the
+// general purpose registers for S390X CPUs.
+// copy from sys/unix/ztypes_linux_s390x.go
+type S390XPtraceRegs struct {
+	Psw      S390XPtracePsw
+	Gprs     [16]uint64
+	Acrs     [16]uint32
+	OrigGpr2 uint64
+}
+
+// S390XPtracePsw is the program status word.
+type S390XPtracePsw struct {
+	Mask uint64
+	Addr uint64
+}
+
+// Slice returns the registers as a list of (name, value) pairs.
+func (r *S390XRegisters) Slice(floatingPoint bool) ([]proc.Register, error) {
+	out := make([]proc.Register, 0, 2+len(r.Regs.Gprs)+len(r.Regs.Acrs)+len(r.Fpregs))
+	for i, v := range r.Regs.Gprs {
+		out = proc.AppendUint64Register(out, fmt.Sprintf("R%d", i), v)
+	}
+	out = proc.AppendUint64Register(out, "PSWM", r.Regs.Psw.Mask)
+	out = proc.AppendUint64Register(out, "PC", r.Regs.Psw.Addr)
+	for i, v := range r.Regs.Acrs {
+		out = proc.AppendUint64Register(out, fmt.Sprintf("A%d", i), uint64(v))
+	}
+	var floatLoadError error
+	if floatingPoint {
+		if r.loadFpRegs != nil {
+			floatLoadError = r.loadFpRegs(r)
+			r.loadFpRegs = nil
+		}
+		out = append(out, r.Fpregs...)
+	}
+	return out, floatLoadError
+}
+
+// PC returns the value of the PSW address.
+func (r *S390XRegisters) PC() uint64 {
+	return r.Regs.Psw.Addr
+}
+
+// SP returns the value of R15.
+func (r *S390XRegisters) SP() uint64 {
+	return r.Regs.Gprs[15]
+}
+
+// BP returns the value of R11, which is not used as a frame pointer by Go.
+func (r *S390XRegisters) BP() uint64 {
+	return r.Regs.Gprs[11]
+}
+
+// TLS returns the address of the thread local storage memory segment, the
+// thread pointer is stored in access registers A0 and A1.
+func (r *S390XRegisters) TLS() uint64 {
+	if !r.iscgo {
+		return 0
+	}
+	return uint64(r.Regs.Acrs[0])<<32 | uint64(r.Regs.Acrs[1])
+}
+
+// GAddr returns the address of the G variable if it is known, 0 and false
+// otherwise.
+func (r *S390XRegisters) GAddr() (uint64, bool) {
+	return r.Regs.Gprs[13], !r.iscgo
+}
+
+// Copy returns a copy of these registers that is guaranteed not to change.
+func (r *S390XRegisters) Copy() (proc.Registers, error) {
+	if r.loadFpRegs != nil {
+		err := r.loadFpRegs(r)
+		r.loadFpRegs = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+	var rr S390XRegisters
+	rr.iscgo = r.iscgo
+	rr.Regs = &S390XPtraceRegs{}
+	*(rr.Regs) = *(r.Regs)
+	if r.Fpregs != nil {
+		rr.Fpregs = make([]proc.Register, len(r.Fpregs))
+		copy(rr.Fpregs, r.Fpregs)
+	}
+	return &rr, nil
+}
+
+// S390XPtraceFpRegs is the struct used by the linux kernel to return the
+// floating point registers for S390X CPUs.
+type S390XPtraceFpRegs struct {
+	Fpc  uint32
+	_    uint32
+	Fprs [16]uint64
+}
+
+// Decode returns the floating point registers as a list of (name, value)
+// pairs.
+func (fpregs *S390XPtraceFpRegs) Decode() (regs []proc.Register) {
+	regs = proc.AppendUint64Register(regs, "FPC", uint64(fpregs.Fpc))
+	for i, v := range fpregs.Fprs {
+		regs = proc.AppendUint64Register(regs, fmt.Sprintf("F%d", i), v)
+	}
+	return
+}
@@ -0,0 +1,152 @@
+package proc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/frame"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+)
+
+// loong64BreakInstruction is the BREAK 0 instruction.
+var loong64BreakInstruction = []byte{0x00, 0x00, 0x2a, 0x00}
+
+// LOONG64Arch returns an initialized LOONG64
+// struct.
+func LOONG64Arch(goos string) *Arch {
+	return &Arch{
+		Name:                             "loong64",
+		ptrSize:                          8,
+		byteOrder:                        binary.LittleEndian,
+		maxInstructionLength:             4,
+		breakpointInstruction:            loong64BreakInstruction,
+		breakInstrMovesPC:                false,
+		derefTLS:                         false,
+		prologues:                        nil,
+		fixFrameUnwindContext:            loong64FixFrameUnwindContext,
+		switchStack:                      loong64SwitchStack,
+		regSize:                          loong64RegSize,
+		RegistersToDwarfRegisters:        loong64RegistersToDwarfRegisters,
+		addrAndStackRegsToDwarfRegisters: loong64AddrAndStackRegsToDwarfRegisters,
+		DwarfRegisterToString:            loong64DwarfRegisterToString,
+		inhibitStepInto:                  func(*BinaryInfo, uint64) bool { return false },
+		asmDecode:                        loong64AsmDecode,
+		usesLR:                           true,
+		PCRegNum:                         regnum.LOONG64_PC,
+		SPRegNum:                         regnum.LOONG64_SP,
+		BPRegNum:                         regnum.LOONG64_BP,
+		ContextRegNum:                    regnum.LOONG64_R0 + 29,
+		intArgRegs:                       loong64IntArgRegs(),
+		asmRegisters:                     map[int]asmRegister{},
+		RegisterNameToDwarf:              nameToDwarfFunc(regnum.LOONG64NameToDwarf),
+	}
+}
+
+// loong64IntArgRegs returns R4 through R19, the registers used to pass
+// integer arguments by ABIInternal on loong64.
+func loong64IntArgRegs() []uint64 {
+	r := make([]uint64, 16)
+	for i := range r {
+		r[i] = regnum.LOONG64_R0 + 4 + uint64(i)
+	}
+	return r
+}
+
+func loong64FixFrameUnwindContext(fctxt *frame.FrameContext, pc uint64, bi *BinaryInfo) *frame.FrameContext {
+	if fctxt == nil {
+		// The Go toolchain does not use a frame pointer on loong64, when there
+		// is no frame descriptor entry the best we can do is assume that we are
+		// stopped at the entry point of a function:
+		// - cfa is sp
+		// - the return address is in the link register
+		return &frame.FrameContext{
+			RetAddrReg: regnum.LOONG64_PC,
+			Regs: map[uint64]frame.DWRule{
+				regnum.LOONG64_PC: frame.DWRule{
+					Rule: frame.RuleRegister,
+					Reg:  regnum.LOONG64_LR,
+				},
+				regnum.LOONG64_SP: frame.DWRule{
+					Rule:   frame.RuleValOffset,
+					Offset: 0,
+				},
+			},
+			CFA: frame.DWRule{
+				Rule:   frame.RuleCFA,
+				Reg:    regnum.LOONG64_SP,
+				Offset: 0,
+			},
+		}
+	}
+
+	if fctxt.Regs[regnum.LOONG64_LR].Rule == frame.RuleUndefined {
+		fctxt.Regs[regnum.LOONG64_LR] = frame.DWRule{
+			Rule:   frame.RuleFramePointer,
+			Reg:    regnum.LOONG64_LR,
+			Offset: 0,
+		}
+	}
+
+	return fctxt
+}
+
+func loong64SwitchStack(it *stackIterator, _ *op.DwarfRegisters) bool {
+	if it.frame.Current.Fn == nil {
+		return false
+	}
+	switch it.frame.Current.Fn.Name {
+	case "runtime.asmcgocall", "runtime.cgocallback_gofunc", "runtime.cgocallback":
+		// cgo stacktraces are not supported on loong64.
+		return false
+	case "runtime.goexit", "runtime.rt0_go", "runtime.mcall":
+		// Look for "top of stack" functions.
+		it.atend = true
+		return true
+	default:
+		if it.systemstack && it.top && it.g != nil && strings.HasPrefix(it.frame.Current.Fn.Name, "runtime.") && it.frame.Current.Fn.Name != "runtime.fatalthrow" {
+			// The runtime switches to the system stack in multiple places, since we
+			// are only interested in printing the system stack for cgo calls we
+			// switch directly to the goroutine stack if we detect that the
+			// function at the top of the stack is a runtime function.
+			it.switchToGoroutineStack()
+			return true
+		}
+		return false
+	}
+}
+
+func loong64RegSize(regnum uint64) int {
+	return 8 // general purpose and floating point registers
+}
+
+func loong64RegistersToDwarfRegisters(staticBase uint64, regs Registers) *op.DwarfRegisters {
+	dregs := initDwarfRegistersFromSlice(int(regnum.LOONG64MaxRegNum()), regs, regnum.LOONG64NameToDwarf)
+	dr := op.NewDwarfRegisters(staticBase, dregs, binary.LittleEndian, regnum.LOONG64_PC, regnum.LOONG64_SP, regnum.LOONG64_BP, regnum.LOONG64_LR)
+	dr.SetLoadMoreCallback(loadMoreDwarfRegistersFromSliceFunc(dr, regs, regnum.LOONG64NameToDwarf))
+	return dr
+}
+
+func loong64AddrAndStackRegsToDwarfRegisters(staticBase, pc, sp, bp, lr uint64) op.DwarfRegisters {
+	dregs := make([]*op.DwarfRegister, regnum.LOONG64_PC+1)
+	dregs[regnum.LOONG64_PC] = op.DwarfRegisterFromUint64(pc)
+	dregs[regnum.LOONG64_SP] = op.DwarfRegisterFromUint64(sp)
+	dregs[regnum.LOONG64_BP] = op.DwarfRegisterFromUint64(bp)
+	dregs[regnum.LOONG64_LR] = op.DwarfRegisterFromUint64(lr)
+
+	return *op.NewDwarfRegisters(staticBase, dregs, binary.LittleEndian, regnum.LOONG64_PC, regnum.LOONG64_SP, regnum.LOONG64_BP, regnum.LOONG64_LR)
+}
+
+func loong64DwarfRegisterToString(i int, reg *op.DwarfRegister) (name string, floatingPoint bool, repr string) {
+	name = regnum.LOONG64ToName(uint64(i))
+
+	if reg == nil {
+		return name, false, ""
+	}
+
+	if name[0] == 'F' {
+		return name, true, fmt.Sprintf("%#016x", reg.Uint64Val)
+	}
+	return name, false, fmt.Sprintf("%#016x", reg.Uint64Val)
+}
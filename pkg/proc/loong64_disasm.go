@@ -0,0 +1,89 @@
+package proc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+)
+
+// There is no loong64 disassembler available, loong64AsmDecode only
+// determines the length of instructions, which is always 4 bytes, and
+// recognizes the instructions emitted by the Go toolchain to call
+// functions and return from them.
+
+const (
+	loong64OpBL   = 0x15 // BL offs26: call PC+offs26<<2, return address in R1
+	loong64OpJIRL = 0x13 // JIRL rd, rj, offs16: call rj+offs16<<2, return address in rd
+
+	loong64InstBREAK     = 0x002a0000 // BREAK code, the code is in the low 15 bits
+	loong64InstBREAKMask = 0xffff8000
+	loong64InstRET       = 0x4c000020 // JIRL R0, R1, 0
+)
+
+func loong64AsmDecode(asmInst *AsmInstruction, mem []byte, regs *op.DwarfRegisters, memrw MemoryReadWriter, bi *BinaryInfo) error {
+	if len(mem) < 4 {
+		asmInst.Size = len(mem)
+		asmInst.Bytes = mem
+		asmInst.Inst = (*loong64ArchInst)(nil)
+		return fmt.Errorf("instruction truncated")
+	}
+
+	asmInst.Size = 4
+	asmInst.Bytes = mem[:4]
+	inst := loong64ArchInst(asmInst.Bytes)
+	asmInst.Inst = &inst
+	asmInst.Kind = OtherInstruction
+
+	word := binary.LittleEndian.Uint32(mem)
+	rd := word & 0x1f
+	switch {
+	case word&loong64InstBREAKMask == loong64InstBREAK:
+		asmInst.Kind = HardBreakInstruction
+	case word == loong64InstRET:
+		asmInst.Kind = RetInstruction
+	case word>>26 == loong64OpBL:
+		asmInst.Kind = CallInstruction
+		// offs[15:0] is in bits 25:10, offs[25:16] in bits 9:0
+		offs := (word&0x3ff)<<16 | (word>>10)&0xffff
+		off := int64(int32(offs<<6)>>6) << 2
+		asmInst.DestLoc = loong64Location(bi, uint64(int64(asmInst.Loc.PC)+off))
+	case word>>26 == loong64OpJIRL && rd == regnum.LOONG64_LR:
+		asmInst.Kind = CallInstruction
+		if asmInst.AtPC && regs != nil {
+			rj := uint64((word >> 5) & 0x1f)
+			off := int64(int16(word>>10)) << 2
+			asmInst.DestLoc = loong64Location(bi, uint64(int64(regs.Uint64Val(regnum.LOONG64_R0+rj))+off))
+		}
+	}
+
+	return nil
+}
+
+func loong64Location(bi *BinaryInfo, pc uint64) *Location {
+	file, line, fn := bi.PCToLine(pc)
+	if fn == nil {
+		return &Location{PC: pc}
+	}
+	return &Location{PC: pc, File: file, Line: line, Fn: fn}
+}
+
+type loong64ArchInst []byte
+
+func (inst *loong64ArchInst) Text(flavour AssemblyFlavour, pc uint64, symLookup func(uint64) (string, uint64)) string {
+	if inst == nil {
+		return "?"
+	}
+	var buf strings.Builder
+	buf.WriteString("?")
+	for _, b := range *inst {
+		fmt.Fprintf(&buf, " %02x", b)
+	}
+	return buf.String()
+}
+
+func (inst *loong64ArchInst) OpcodeEquals(op uint64) bool {
+	return false
+}
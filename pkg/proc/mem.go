@@ -108,6 +108,11 @@ func newCompositeMemory(mem MemoryReadWriter, arch *Arch, regs op.DwarfRegisters
 				}
 				return nil, fmt.Errorf("could not read %d bytes from register %d (size: %d)", piece.Size, piece.Val, len(reg))
 			}
+			if arch.ByteOrder() == binary.BigEndian && len(reg) <= 8 {
+				// the least significant bytes of a register are the last ones on
+				// big endian architectures.
+				reg = reg[len(reg)-piece.Size:]
+			}
 			cmem.data = append(cmem.data, reg[:piece.Size]...)
 		case op.AddrPiece:
 			buf := make([]byte, piece.Size)
@@ -119,7 +124,10 @@ func newCompositeMemory(mem MemoryReadWriter, arch *Arch, regs op.DwarfRegisters
 				sz = piece.Size
 			}
 			buf := make([]byte, sz)
-			binary.LittleEndian.PutUint64(buf, piece.Val)
+			arch.ByteOrder().PutUint64(buf, piece.Val)
+			if arch.ByteOrder() == binary.BigEndian && piece.Size <= 8 {
+				buf = buf[8-piece.Size:]
+			}
 			cmem.data = append(cmem.data, buf[:piece.Size]...)
 		default:
 			panic("unsupported piece kind")
@@ -157,7 +165,7 @@ func (mem *compositeMemory) WriteMemory(addr uint64, data []byte) (int, error) {
 
 			switch piece.Kind {
 			case op.RegPiece:
-				err := mem.regs.ChangeFunc(piece.Val, op.DwarfRegisterFromBytes(pieceMem))
+				err := mem.regs.ChangeFunc(piece.Val, dwarfRegisterFromMemory(mem.arch, pieceMem))
 				if err != nil {
 					return donesz, err
 				}
@@ -180,6 +188,19 @@ func (mem *compositeMemory) WriteMemory(addr uint64, data []byte) (int, error) {
 	return len(data), nil
 }
 
+// dwarfRegisterFromMemory returns a register containing the value stored
+// in buf using the byte order of arch.
+func dwarfRegisterFromMemory(arch *Arch, buf []byte) *op.DwarfRegister {
+	if arch.ByteOrder() == binary.BigEndian && len(buf) <= 8 {
+		var v uint64
+		for _, b := range buf {
+			v = v<<8 | uint64(b)
+		}
+		return op.DwarfRegisterFromUint64(v)
+	}
+	return op.DwarfRegisterFromBytes(buf)
+}
+
 // DereferenceMemory returns a MemoryReadWriter that can read and write the
 // memory pointed to by pointers in this memory.
 // Normally mem and mem.Dereference are the same object, they are different
@@ -0,0 +1,56 @@
+//+build darwin,macnative
+
+package native
+
+// #include "proc_darwin.h"
+import "C"
+import (
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/elfwriter"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+func (p *nativeProcess) MemoryMap() ([]proc.MemoryMapEntry, error) {
+	r := []proc.MemoryMapEntry{}
+
+	var addr C.mach_vm_address_t
+	for {
+		var (
+			size   C.mach_vm_size_t
+			prot   C.vm_prot_t
+			shared C.int
+		)
+		kret := C.memory_region(p.os.task, &addr, &size, &prot, &shared)
+		if kret == C.KERN_INVALID_ADDRESS {
+			// there are no regions past addr
+			break
+		}
+		if kret != C.KERN_SUCCESS {
+			return nil, fmt.Errorf("could not read memory map at %#x (error %d)", uint64(addr), kret)
+		}
+
+		// Read-only shared regions are mostly the dyld shared cache, which
+		// is several gigabytes large and identical in every process.
+		if shared == 0 || prot&C.VM_PROT_WRITE != 0 {
+			r = append(r, proc.MemoryMapEntry{
+				Addr:  uint64(addr),
+				Size:  uint64(size),
+				Read:  prot&C.VM_PROT_READ != 0,
+				Write: prot&C.VM_PROT_WRITE != 0,
+				Exec:  prot&C.VM_PROT_EXECUTE != 0,
+			})
+		}
+
+		if addr+C.mach_vm_address_t(size) <= addr {
+			break
+		}
+		addr += C.mach_vm_address_t(size)
+	}
+
+	return r, nil
+}
+
+func (p *nativeProcess) DumpProcessNotes(notes []elfwriter.Note, threadDone func()) (threadsDone bool, notesout []elfwriter.Note, err error) {
+	return false, notes, nil
+}
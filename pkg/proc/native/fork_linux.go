@@ -0,0 +1,312 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"syscall"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/go-delve/delve/pkg/logflags"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// processGroup is a group of processes followed across forks. They are
+// all traced by the same ptrace thread and waiting for the events of one
+// of them also returns the events of the others, which are kept until the
+// process they belong to waits for them.
+type processGroup struct {
+	// procs are the processes of the group debugged by a target.
+	procs []*nativeProcess
+	// pending are the children that are followed but not debugged until
+	// they call exec: the children created by vfork, which share the
+	// memory of their parent, and the children not matched by match. Maps
+	// the pid of the child to the pid of its parent.
+	pending map[int]int
+	// events are the events received by a process of the group that
+	// belong to another process, or to a thread that isn't known yet.
+	events []waitEvent
+	// match, if not nil, selects the children that are debugged.
+	match func(pid int) bool
+	// newTarget is called with the target of every new process, nil if
+	// forks are not followed anymore.
+	newTarget func(t *proc.Target, parentPid int)
+}
+
+// waitEvent is the status returned by wait for thread tid.
+type waitEvent struct {
+	tid    int
+	status *sys.WaitStatus
+}
+
+// FollowFork enables or disables following the children created by the
+// process with fork and vfork, see proc.ForkFollower.
+func (dbp *nativeProcess) FollowFork(enabled bool, match func(pid int) bool, newTarget func(t *proc.Target, parentPid int)) error {
+	if dbp.exited {
+		return proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	g := dbp.os.group
+	if g == nil {
+		if !enabled {
+			return nil
+		}
+		g = &processGroup{procs: []*nativeProcess{dbp}, pending: make(map[int]int)}
+		dbp.os.group = g
+		dbp.ptraceRefs = new(int)
+		*dbp.ptraceRefs = 1
+	}
+	g.match, g.newTarget = nil, nil
+	if enabled {
+		g.match, g.newTarget = match, newTarget
+	}
+	for _, p := range g.procs {
+		if p.exited {
+			continue
+		}
+		for _, th := range p.threads {
+			var err error
+			p.execPtraceFunc(func() { err = syscall.PtraceSetOptions(th.ID, p.ptraceOptions()) })
+			if err != nil && err != syscall.ESRCH {
+				return fmt.Errorf("could not set options of thread %d: %v", th.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ptraceOptions returns the ptrace options used for the threads of dbp.
+func (dbp *nativeProcess) ptraceOptions() int {
+	opts := syscall.PTRACE_O_TRACECLONE
+	if g := dbp.os.group; g != nil && g.newTarget != nil {
+		opts |= syscall.PTRACE_O_TRACEFORK | syscall.PTRACE_O_TRACEVFORK | syscall.PTRACE_O_TRACEEXEC
+	}
+	return opts
+}
+
+// takeEvent returns the first event kept for dbp, if any.
+func (g *processGroup) takeEvent(dbp *nativeProcess, pid int) (int, *sys.WaitStatus, bool) {
+	if g == nil || pid != -1 {
+		return 0, nil, false
+	}
+	for i, ev := range g.events {
+		if _, ok := dbp.threads[ev.tid]; ok || ev.tid == dbp.pid {
+			g.events = append(g.events[:i], g.events[i+1:]...)
+			return ev.tid, ev.status, true
+		}
+	}
+	return 0, nil, false
+}
+
+// dropEvents discards the events kept for thread tid.
+func (g *processGroup) dropEvents(tid int) bool {
+	if g == nil {
+		return false
+	}
+	found := false
+	events := g.events[:0]
+	for _, ev := range g.events {
+		if ev.tid == tid {
+			found = true
+			continue
+		}
+		events = append(events, ev)
+	}
+	g.events = events
+	return found
+}
+
+// dispatch handles an event received by dbp for thread wpid if it does not
+// belong to dbp, returns true if it did.
+func (g *processGroup) dispatch(dbp *nativeProcess, wpid int, status *sys.WaitStatus) (bool, error) {
+	if g == nil {
+		return false, nil
+	}
+	if _, ok := dbp.threads[wpid]; ok || wpid == dbp.pid {
+		return false, nil
+	}
+	if ppid, ok := g.pending[wpid]; ok {
+		return true, g.pendingEvent(dbp, wpid, ppid, status)
+	}
+	if status.Exited() || status.Signaled() {
+		for _, p := range g.procs {
+			if _, ok := p.threads[wpid]; ok && !p.exited {
+				g.events = append(g.events, waitEvent{wpid, status})
+				break
+			}
+		}
+		// otherwise a thread that disappeared when its process called exec
+		return true, nil
+	}
+	g.events = append(g.events, waitEvent{wpid, status})
+	return true, nil
+}
+
+// pendingEvent handles an event of a pending child, see
+// processGroup.pending.
+func (g *processGroup) pendingEvent(dbp *nativeProcess, pid, ppid int, status *sys.WaitStatus) error {
+	switch {
+	case status.Exited() || status.Signaled():
+		delete(g.pending, pid)
+		return nil
+	case status.StopSignal() == sys.SIGTRAP && status.TrapCause() == sys.PTRACE_EVENT_EXEC:
+		delete(g.pending, pid)
+		if g.match != nil && !g.match(pid) {
+			var err error
+			dbp.execPtraceFunc(func() { err = ptraceDetach(pid, 0) })
+			return err
+		}
+		return g.addProcess(dbp, pid, ppid, proc.StopExeced)
+	case status.StopSignal() == sys.SIGTRAP && (status.TrapCause() == sys.PTRACE_EVENT_FORK || status.TrapCause() == sys.PTRACE_EVENT_VFORK || status.TrapCause() == sys.PTRACE_EVENT_CLONE):
+		// its children are followed, like it, once they call exec
+		var child uint
+		var err error
+		dbp.execPtraceFunc(func() { child, err = sys.PtraceGetEventMsg(pid) })
+		if err == nil {
+			g.pending[int(child)] = ppid
+			if g.dropEvents(int(child)) {
+				dbp.execPtraceFunc(func() { err = ptraceCont(int(child), 0) })
+			}
+		}
+	}
+	sig := 0
+	if status.StopSignal() != sys.SIGTRAP && status.StopSignal() != sys.SIGSTOP {
+		sig = int(status.StopSignal())
+	}
+	var err error
+	dbp.execPtraceFunc(func() { err = ptraceCont(pid, sig) })
+	if err == sys.ESRCH {
+		err = nil
+	}
+	return err
+}
+
+// followChild follows the child with the given pid created by dbp.
+func (dbp *nativeProcess) followChild(pid int, vfork bool) error {
+	g := dbp.os.group
+	// wait for the initial stop of the child
+	if !g.dropEvents(pid) {
+		if _, status, err := dbp.waitFast(pid); err != nil {
+			return err
+		} else if status.Exited() || status.Signaled() {
+			return nil
+		}
+	}
+	if vfork || (g.match != nil && !g.match(pid)) {
+		if !vfork {
+			if err := dbp.removeBreakpoints(pid); err != nil {
+				return err
+			}
+		}
+		g.pending[pid] = dbp.pid
+		var err error
+		dbp.execPtraceFunc(func() { err = ptraceCont(pid, 0) })
+		if err == sys.ESRCH {
+			err = nil
+		}
+		return err
+	}
+	return g.addProcess(dbp, pid, dbp.pid, proc.StopForked)
+}
+
+// AttachChild attaches to process pid, see proc.ForkFollower.
+func (dbp *nativeProcess) AttachChild(pid, parentPid int) error {
+	if dbp.exited {
+		return proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	g := dbp.os.group
+	if g == nil || g.newTarget == nil {
+		return errors.New("forks are not followed")
+	}
+	var err error
+	dbp.execPtraceFunc(func() { err = ptraceAttach(pid) })
+	if err != nil {
+		return err
+	}
+	if _, _, err := dbp.waitFast(pid); err != nil {
+		return err
+	}
+	return g.addProcess(dbp, pid, parentPid, proc.StopAttached)
+}
+
+// removeBreakpoints removes the breakpoints of dbp from the memory of
+// process pid, a copy of dbp created by fork.
+func (dbp *nativeProcess) removeBreakpoints(pid int) error {
+	for _, bp := range dbp.breakpoints.M {
+		if bp.WatchType != 0 || bp.OriginalData == nil {
+			continue
+		}
+		var err error
+		dbp.execPtraceFunc(func() { _, err = sys.PtracePokeData(pid, uintptr(bp.Addr), bp.OriginalData) })
+		if err != nil {
+			return fmt.Errorf("could not remove breakpoint at %#x: %v", bp.Addr, err)
+		}
+	}
+	return nil
+}
+
+// execed is called when dbp replaces its executable: the process is
+// debugged by a new target and dbp exits.
+func (dbp *nativeProcess) execed() error {
+	if err := dbp.os.group.addProcess(dbp, dbp.pid, dbp.pid, proc.StopExeced); err != nil {
+		return err
+	}
+	dbp.threads = make(map[int]*nativeThread)
+	dbp.postExit()
+	return proc.ErrProcessExited{Pid: dbp.pid}
+}
+
+// addProcess creates a target for process pid, stopped for the given
+// reason, and passes it to g.newTarget. If the process can not be debugged
+// it is detached. A process created by fork is a copy of its parent, dbp,
+// including the breakpoints of dbp, which are removed.
+func (g *processGroup) addProcess(dbp *nativeProcess, pid, ppid int, reason proc.StopReason) error {
+	if g.newTarget == nil {
+		var err error
+		dbp.execPtraceFunc(func() { err = ptraceDetach(pid, 0) })
+		return err
+	}
+	child := &nativeProcess{
+		pid:            pid,
+		threads:        make(map[int]*nativeThread),
+		breakpoints:    proc.NewBreakpointMap(),
+		firstStart:     true,
+		os:             &osProcessDetails{group: g},
+		ptraceChan:     dbp.ptraceChan,
+		ptraceDoneChan: dbp.ptraceDoneChan,
+		ptraceRefs:     dbp.ptraceRefs,
+		bi:             proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH),
+		childProcess:   dbp.childProcess,
+	}
+	*child.ptraceRefs++
+	g.procs = append(g.procs, child)
+
+	tgt, err := child.initializeChild(dbp, reason)
+	if err != nil {
+		logflags.DebuggerLogger().Errorf("could not debug process %d created by process %d: %v", pid, ppid, err)
+		child.execPtraceFunc(func() { err = ptraceDetach(pid, 0) })
+		child.detached = true
+		child.postExit()
+		return nil
+	}
+	g.newTarget(tgt, ppid)
+	return nil
+}
+
+// initializeChild initializes a process created by parent, see addProcess.
+func (dbp *nativeProcess) initializeChild(parent *nativeProcess, reason proc.StopReason) (*proc.Target, error) {
+	if _, err := dbp.addThread(dbp.pid, false); err != nil {
+		return nil, err
+	}
+	if reason == proc.StopForked {
+		if err := parent.removeBreakpoints(dbp.pid); err != nil {
+			return nil, err
+		}
+	}
+	tgt, err := dbp.initialize(findExecutable("", dbp.pid), parent.debugInfoDirs)
+	if err != nil {
+		return nil, err
+	}
+	tgt.StopReason = reason
+	return tgt, nil
+}
@@ -30,6 +30,11 @@ type nativeProcess struct {
 	resumeChan     chan<- struct{}
 	ptraceChan     chan func()
 	ptraceDoneChan chan interface{}
+	// ptraceRefs, if not nil, counts the processes sharing ptraceChan with
+	// this one, which are the processes created by a process followed
+	// across forks, see FollowFork. The ptrace thread is stopped when the
+	// last one of them exits.
+	ptraceRefs *int
 	childProcess   bool       // this process was launched, not attached to
 	stopMu         sync.Mutex // protects manualStopRequested
 	// manualStopRequested is set if all the threads in the process were
@@ -37,12 +42,19 @@ type nativeProcess struct {
 	// why a thread is found to have stopped.
 	manualStopRequested bool
 
+	// asyncPreempt counts the asynchronous preemption signals delivered to
+	// the target without stopping.
+	asyncPreempt proc.AsyncPreemptCounter
+
 	// Controlling terminal file descriptor for
 	// this process.
 	ctty *os.File
 
 	iscgo bool
 
+	// debugInfoDirs are the directories searched for split debug info.
+	debugInfoDirs []string
+
 	exited, detached bool
 }
 
@@ -145,6 +157,12 @@ func (dbp *nativeProcess) Valid() (bool, error) {
 	return true, nil
 }
 
+// AsyncPreemptSignals returns the number of asynchronous preemption
+// signals that were delivered to the target without stopping.
+func (dbp *nativeProcess) AsyncPreemptSignals() uint64 {
+	return dbp.asyncPreempt.AsyncPreemptSignals()
+}
+
 // ResumeNotify specifies a channel that will be closed the next time
 // ContinueOnce finishes resuming the target.
 func (dbp *nativeProcess) ResumeNotify(ch chan<- struct{}) {
@@ -296,6 +314,7 @@ func (dbp *nativeProcess) initialize(path string, debugInfoDirs []string) (*proc
 	if err := initialize(dbp); err != nil {
 		return nil, err
 	}
+	dbp.debugInfoDirs = debugInfoDirs
 	if err := dbp.updateThreadList(); err != nil {
 		return nil, err
 	}
@@ -306,9 +325,9 @@ func (dbp *nativeProcess) initialize(path string, debugInfoDirs []string) (*proc
 	tgt, err := proc.NewTarget(dbp, dbp.memthread, proc.NewTargetConfig{
 		Path:                path,
 		DebugInfoDirs:       debugInfoDirs,
-		DisableAsyncPreempt: runtime.GOOS == "windows" || runtime.GOOS == "freebsd",
+		DisableAsyncPreempt: runtime.GOOS == "windows" || runtime.GOOS == "freebsd" || runtime.GOOS == "openbsd",
 		StopReason:          stopReason,
-		CanDump:             runtime.GOOS == "linux"})
+		CanDump:             runtime.GOOS == "linux" || runtime.GOOS == "darwin" || runtime.GOOS == "windows"})
 	if err != nil {
 		return nil, err
 	}
@@ -337,8 +356,13 @@ func (dbp *nativeProcess) execPtraceFunc(fn func()) {
 
 func (dbp *nativeProcess) postExit() {
 	dbp.exited = true
-	close(dbp.ptraceChan)
-	close(dbp.ptraceDoneChan)
+	if dbp.ptraceRefs != nil {
+		*dbp.ptraceRefs--
+	}
+	if dbp.ptraceRefs == nil || *dbp.ptraceRefs == 0 {
+		close(dbp.ptraceChan)
+		close(dbp.ptraceDoneChan)
+	}
 	dbp.bi.Close()
 	if dbp.ctty != nil {
 		dbp.ctty.Close()
@@ -134,7 +134,7 @@ thread_count(task_t task) {
 }
 
 mach_port_t
-mach_port_wait(mach_port_t port_set, task_t *task, int nonblocking) {
+mach_port_wait(mach_port_t port_set, task_t *task, int nonblocking, integer_t *exc_code) {
 	kern_return_t kret;
 	thread_act_t thread;
 	NDR_record_t *ndr;
@@ -176,9 +176,10 @@ mach_port_wait(mach_port_t port_set, task_t *task, int nonblocking) {
 			if (data[2] == EXC_SOFT_SIGNAL) {
 				if (data[3] != SIGTRAP) {
 					if (thread_resume(thread) != KERN_SUCCESS) return 0;
-					return mach_port_wait(port_set, task, nonblocking);
+					return mach_port_wait(port_set, task, nonblocking, exc_code);
 				}
 			}
+			if (exc_code != NULL) *exc_code = data[2];
 			return thread;
 		}
 
@@ -231,3 +232,17 @@ task_is_valid(task_t task) {
 	mach_msg_type_number_t count = TASK_BASIC_INFO_COUNT;
 	return task_info(task, TASK_BASIC_INFO, (task_info_t)&info, &count) == KERN_SUCCESS;
 }
+
+kern_return_t
+memory_region(task_t task, mach_vm_address_t *addr, mach_vm_size_t *size, vm_prot_t *prot, int *shared) {
+	vm_region_basic_info_data_64_t info;
+	mach_msg_type_number_t count = VM_REGION_BASIC_INFO_COUNT_64;
+	mach_port_t object_name;
+	kern_return_t kret;
+
+	kret = mach_vm_region(task, addr, size, VM_REGION_BASIC_INFO_64, (vm_region_info_t)&info, &count, &object_name);
+	if (kret != KERN_SUCCESS) return kret;
+	*prot = info.protection;
+	*shared = info.shared;
+	return KERN_SUCCESS;
+}
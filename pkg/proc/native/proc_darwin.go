@@ -183,7 +183,7 @@ func (dbp *nativeProcess) kill() (err error) {
 	}
 	for {
 		var task C.task_t
-		port := C.mach_port_wait(dbp.os.portSet, &task, C.int(0))
+		port := C.mach_port_wait(dbp.os.portSet, &task, C.int(0), nil)
 		if port == dbp.os.notificationPort {
 			break
 		}
@@ -277,6 +277,14 @@ func (dbp *nativeProcess) addThread(port int, attach bool) (*nativeThread, error
 	if dbp.memthread == nil {
 		dbp.memthread = thread
 	}
+	for _, bp := range dbp.Breakpoints().M {
+		if bp.WatchType != 0 {
+			err := thread.writeHardwareBreakpoint(bp.Addr, bp.WatchType, bp.HWBreakIndex)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 	return thread, nil
 }
 
@@ -290,7 +298,8 @@ func findExecutable(path string, pid int) string {
 func (dbp *nativeProcess) trapWait(pid int) (*nativeThread, error) {
 	for {
 		task := dbp.os.task
-		port := C.mach_port_wait(dbp.os.portSet, &task, C.int(0))
+		var excCode C.integer_t
+		port := C.mach_port_wait(dbp.os.portSet, &task, C.int(0), &excCode)
 
 		switch port {
 		case dbp.os.notificationPort:
@@ -361,6 +370,7 @@ func (dbp *nativeProcess) trapWait(pid int) (*nativeThread, error) {
 			}
 			continue
 		}
+		th.os.excCode = excCode
 		return th, nil
 	}
 }
@@ -370,10 +380,14 @@ func (dbp *nativeProcess) waitForStop() ([]int, error) {
 	count := 0
 	for {
 		var task C.task_t
-		port := C.mach_port_wait(dbp.os.portSet, &task, C.int(1))
+		var excCode C.integer_t
+		port := C.mach_port_wait(dbp.os.portSet, &task, C.int(1), &excCode)
 		if port != 0 && port != dbp.os.notificationPort && port != C.MACH_RCV_INTERRUPTED {
 			count = 0
 			ports = append(ports, int(port))
+			if th, ok := dbp.threads[int(port)]; ok {
+				th.os.excCode = excCode
+			}
 		} else {
 			n := C.num_running_threads(dbp.os.task)
 			if n == 0 {
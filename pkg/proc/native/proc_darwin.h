@@ -38,7 +38,7 @@ int
 thread_count(task_t task);
 
 mach_port_t
-mach_port_wait(mach_port_t, task_t*, int);
+mach_port_wait(mach_port_t, task_t*, int, integer_t*);
 
 kern_return_t
 mach_send_reply(mach_msg_header_t);
@@ -54,3 +54,6 @@ get_task_for_pid(int pid);
 
 int
 task_is_valid(task_t task);
+
+kern_return_t
+memory_region(task_t task, mach_vm_address_t *addr, mach_vm_size_t *size, vm_prot_t *prot, int *shared);
@@ -183,6 +183,14 @@ func (dbp *nativeProcess) addThread(tid int, attach bool) (*nativeThread, error)
 	if dbp.memthread == nil {
 		dbp.memthread = dbp.threads[tid]
 	}
+	for _, bp := range dbp.Breakpoints().M {
+		if bp.WatchType != 0 {
+			err := dbp.threads[tid].writeHardwareBreakpoint(bp.Addr, bp.WatchType, bp.HWBreakIndex)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	return dbp.threads[tid], nil
 }
@@ -284,6 +292,9 @@ func (dbp *nativeProcess) trapWaitInternal(pid int, halt bool) (*nativeThread, e
 		}
 
 		// TODO(dp) alert user about unexpected signals here.
+		if status.StopSignal() == sys.SIGURG {
+			dbp.asyncPreempt.Hide()
+		}
 		if err := th.resumeWithSig(int(status.StopSignal())); err != nil {
 			if err == sys.ESRCH {
 				return nil, proc.ErrProcessExited{Pid: dbp.pid}
@@ -45,6 +45,9 @@ const (
 // process details.
 type osProcessDetails struct {
 	comm string
+	// group is the group of processes followed across forks this process
+	// belongs to, nil if forks are not followed, see FollowFork.
+	group *processGroup
 }
 
 // Launch creates and begins debugging a new process. First entry in
@@ -194,7 +197,13 @@ func (dbp *nativeProcess) kill() error {
 	if !dbp.threads[dbp.pid].Stopped() {
 		return errors.New("process must be stopped in order to kill it")
 	}
-	if err := sys.Kill(-dbp.pid, sys.SIGKILL); err != nil {
+	// kill the process group of the process, unless the process was forked
+	// by another process in it
+	killpid := -dbp.pid
+	if pgid, _ := sys.Getpgid(dbp.pid); pgid != dbp.pid {
+		killpid = dbp.pid
+	}
+	if err := sys.Kill(killpid, sys.SIGKILL); err != nil {
 		return errors.New("could not deliver signal " + err.Error())
 	}
 	// wait for other threads first or the thread group leader (dbp.pid) will never exit.
@@ -246,12 +255,12 @@ func (dbp *nativeProcess) addThread(tid int, attach bool) (*nativeThread, error)
 		}
 	}
 
-	dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE) })
+	dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, dbp.ptraceOptions()) })
 	if err == syscall.ESRCH {
 		if _, _, err = dbp.waitFast(tid); err != nil {
 			return nil, fmt.Errorf("error while waiting after adding thread: %d %s", tid, err)
 		}
-		dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE) })
+		dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, dbp.ptraceOptions()) })
 		if err == syscall.ESRCH {
 			return nil, err
 		}
@@ -316,20 +325,31 @@ const (
 func (dbp *nativeProcess) trapWaitInternal(pid int, options trapWaitOptions) (*nativeThread, error) {
 	halt := options&trapWaitHalt != 0
 	for {
-		wopt := 0
-		if options&trapWaitNohang != 0 {
-			wopt = sys.WNOHANG
-		}
-		wpid, status, err := dbp.wait(pid, wopt)
-		if err != nil {
-			return nil, fmt.Errorf("wait err %s %d", err, pid)
-		}
-		if wpid == 0 {
+		wpid, status, ok := dbp.os.group.takeEvent(dbp, pid)
+		if !ok {
+			wopt := 0
 			if options&trapWaitNohang != 0 {
-				return nil, nil
+				wopt = sys.WNOHANG
+			}
+			var err error
+			wpid, status, err = dbp.wait(pid, wopt)
+			if err != nil {
+				return nil, fmt.Errorf("wait err %s %d", err, pid)
+			}
+			if wpid == 0 {
+				if options&trapWaitNohang != 0 {
+					return nil, nil
+				}
+				continue
+			}
+		}
+		if handled, err := dbp.os.group.dispatch(dbp, wpid, status); handled || err != nil {
+			if err != nil {
+				return nil, err
 			}
 			continue
 		}
+		var err error
 		th, ok := dbp.threads[wpid]
 		if ok {
 			th.Status = (*waitStatus)(status)
@@ -364,6 +384,9 @@ func (dbp *nativeProcess) trapWaitInternal(pid int, options trapWaitOptions) (*n
 				}
 				return nil, fmt.Errorf("could not get event message: %s", err)
 			}
+			// the initial stop of the new thread, if it was already received, is
+			// ignored
+			dbp.os.group.dropEvents(int(cloned))
 			th, err = dbp.addThread(int(cloned), false)
 			if err != nil {
 				if err == sys.ESRCH {
@@ -397,6 +420,34 @@ func (dbp *nativeProcess) trapWaitInternal(pid int, options trapWaitOptions) (*n
 			// Sometimes we get an unknown thread, ignore it?
 			continue
 		}
+		if status.StopSignal() == sys.SIGTRAP && (status.TrapCause() == sys.PTRACE_EVENT_FORK || status.TrapCause() == sys.PTRACE_EVENT_VFORK) {
+			// A followed process has created a new process, see FollowFork.
+			var child uint
+			dbp.execPtraceFunc(func() { child, err = sys.PtraceGetEventMsg(wpid) })
+			if err == nil {
+				err = dbp.followChild(int(child), status.TrapCause() == sys.PTRACE_EVENT_VFORK)
+			}
+			if err != nil {
+				if err == sys.ESRCH {
+					// the thread or its child died while we were adding the child
+					continue
+				}
+				return nil, err
+			}
+			if halt {
+				th.os.running = false
+				return nil, nil
+			}
+			if err = th.Continue(); err != nil && err != sys.ESRCH {
+				return nil, fmt.Errorf("could not continue existing thread %d %s", wpid, err)
+			}
+			continue
+		}
+		if status.StopSignal() == sys.SIGTRAP && status.TrapCause() == sys.PTRACE_EVENT_EXEC {
+			// A followed process replaced its executable, it will be debugged by
+			// a new target.
+			return nil, dbp.execed()
+		}
 		if (halt && status.StopSignal() == sys.SIGSTOP) || (status.StopSignal() == sys.SIGTRAP) {
 			th.os.running = false
 			if status.StopSignal() == sys.SIGTRAP {
@@ -406,6 +457,9 @@ func (dbp *nativeProcess) trapWaitInternal(pid int, options trapWaitOptions) (*n
 		}
 
 		// TODO(dp) alert user about unexpected signals here.
+		if status.StopSignal() == sys.SIGURG {
+			dbp.asyncPreempt.Hide()
+		}
 		if halt && !th.os.running {
 			// We are trying to stop the process, queue this signal to be delivered
 			// to the thread when we resume.
@@ -686,7 +740,7 @@ func (dbp *nativeProcess) EntryPoint() (uint64, error) {
 		return 0, fmt.Errorf("could not read auxiliary vector: %v", err)
 	}
 
-	return linutil.EntryPointFromAuxv(auxvbuf, dbp.bi.Arch.PtrSize()), nil
+	return linutil.EntryPointFromAuxv(auxvbuf, dbp.bi.Arch.PtrSize(), dbp.bi.Arch.ByteOrder()), nil
 }
 
 func killProcess(pid int) error {
@@ -0,0 +1,91 @@
+#include <sys/param.h>
+#include <sys/types.h>
+#include <sys/sysctl.h>
+
+#include <errno.h>
+#include <limits.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "proc_openbsd.h"
+
+static int get_kinfo_proc(int pid, struct kinfo_proc *kp) {
+	int mib[6] = { CTL_KERN, KERN_PROC, KERN_PROC_PID, pid, sizeof(struct kinfo_proc), 1 };
+	size_t len = sizeof(struct kinfo_proc);
+
+	if (sysctl(mib, 6, kp, &len, NULL, 0) == -1)
+		return (-1);
+	if (len == 0) {
+		errno = ESRCH;
+		return (-1);
+	}
+	return (0);
+}
+
+/*
+ * Returns the absolute pathname of the process's executable, if one was found.
+ * OpenBSD does not keep track of the path of executables, it is derived
+ * from the first argument of the process and its working directory.
+ * Must be freed by the caller. Sets errno on failure.
+ */
+char * find_executable(int pid) {
+	int mib[4] = { CTL_KERN, KERN_PROC_ARGS, pid, KERN_PROC_ARGV };
+	int cwdmib[3] = { CTL_KERN, KERN_PROC_CWD, pid };
+	char **argv = NULL;
+	char *pathname = NULL;
+	char cwd[PATH_MAX];
+	size_t len = 0;
+
+	if (sysctl(mib, 4, NULL, &len, NULL, 0) == -1)
+		return (NULL);
+	argv = malloc(len);
+	if (argv == NULL)
+		return (NULL);
+	if (sysctl(mib, 4, argv, &len, NULL, 0) == -1 || argv[0] == NULL)
+		goto out;
+
+	pathname = malloc(PATH_MAX);
+	if (pathname == NULL)
+		goto out;
+	if (argv[0][0] == '/' || strchr(argv[0], '/') == NULL) {
+		strlcpy(pathname, argv[0], PATH_MAX);
+		goto out;
+	}
+	len = sizeof(cwd);
+	if (sysctl(cwdmib, 3, cwd, &len, NULL, 0) == -1) {
+		strlcpy(pathname, argv[0], PATH_MAX);
+		goto out;
+	}
+	strlcpy(pathname, cwd, PATH_MAX);
+	strlcat(pathname, "/", PATH_MAX);
+	strlcat(pathname, argv[0], PATH_MAX);
+
+out:
+	free(argv);
+	return (pathname);
+}
+
+/*
+ * Returns the comm value of the process, which is usually the basename of its
+ * executable. Must be freed by the caller.  Sets errno on failure.
+ */
+char * find_command_name(int pid) {
+	char *command_name = NULL;
+	struct kinfo_proc kinfo;
+
+	if (get_kinfo_proc(pid, &kinfo) == 0) {
+		command_name = malloc(KI_MAXCOMLEN + 1);
+		if (command_name != NULL)
+			strlcpy(command_name, kinfo.p_comm, KI_MAXCOMLEN + 1);
+	}
+
+	return (command_name);
+}
+
+int find_status(int pid) {
+	struct kinfo_proc kinfo;
+
+	if (get_kinfo_proc(pid, &kinfo) != 0)
+		return ('?');
+	return (kinfo.p_stat);
+}
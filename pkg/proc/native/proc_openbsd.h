@@ -0,0 +1,5 @@
+#include <sys/types.h>
+
+char * find_command_name(int pid);
+char * find_executable(int pid);
+int find_status(int pid);
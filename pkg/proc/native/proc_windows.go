@@ -212,6 +212,14 @@ func (dbp *nativeProcess) addThread(hThread syscall.Handle, threadID int, attach
 	if dbp.memthread == nil {
 		dbp.memthread = dbp.threads[threadID]
 	}
+	for _, bp := range dbp.Breakpoints().M {
+		if bp.WatchType != 0 {
+			err := thread.writeHardwareBreakpoint(bp.Addr, bp.WatchType, bp.HWBreakIndex)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
 	if suspendNewThreads && !dbgUiRemoteBreakIn {
 		_, err := _SuspendThread(thread.os.hThread)
 		if err != nil {
@@ -336,6 +344,12 @@ func (dbp *nativeProcess) waitForDebugEvent(flags waitForDebugEventFlags) (threa
 					continueStatus = _DBG_CONTINUE
 				}
 			case _EXCEPTION_SINGLE_STEP:
+				if thread, found := dbp.threads[tid]; found {
+					thread.os.dataAddr = 0
+					if exception.ExceptionRecord.NumberParameters >= 2 {
+						thread.os.dataAddr = uint64(exception.ExceptionRecord.ExceptionInformation[1])
+					}
+				}
 				dbp.os.breakThread = tid
 				return tid, 0, nil
 			case _MS_VC_EXCEPTION:
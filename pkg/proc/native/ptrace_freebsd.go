@@ -68,6 +68,32 @@ func ptraceGetRegset(id int) (regset amd64util.AMD64Xstate, err error) {
 	return
 }
 
+// Requests to read and write the debug registers, see ptrace(2)
+const (
+	_PT_GETDBREGS = 37
+	_PT_SETDBREGS = 38
+)
+
+// ptraceGetDebugRegs reads the debug registers (struct dbreg) of a thread.
+// id must be an LWPID
+func ptraceGetDebugRegs(id int, debugregs *[16]uint64) error {
+	_, _, err := syscall.Syscall6(syscall.SYS_PTRACE, _PT_GETDBREGS, uintptr(id), uintptr(unsafe.Pointer(debugregs)), 0, 0, 0)
+	if err == syscall.Errno(0) {
+		return nil
+	}
+	return err
+}
+
+// ptraceSetDebugRegs writes the debug registers (struct dbreg) of a thread.
+// id must be an LWPID
+func ptraceSetDebugRegs(id int, debugregs *[16]uint64) error {
+	_, _, err := syscall.Syscall6(syscall.SYS_PTRACE, _PT_SETDBREGS, uintptr(id), uintptr(unsafe.Pointer(debugregs)), 0, 0, 0)
+	if err == syscall.Errno(0) {
+		return nil
+	}
+	return err
+}
+
 // id may be a PID or an LWPID
 func ptraceReadData(id int, addr uintptr, data []byte) (n int, err error) {
 	return sys.PtraceIO(sys.PIOD_READ_D, id, addr, data, len(data))
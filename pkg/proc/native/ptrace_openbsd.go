@@ -0,0 +1,133 @@
+package native
+
+// #include <sys/types.h>
+// #include <machine/reg.h>
+//
+// #include <stdlib.h>
+// #include "ptrace_openbsd_amd64.h"
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc/amd64util"
+	"github.com/go-delve/delve/pkg/proc/obsdutil"
+)
+
+// Operations of PT_IO, see ptrace(2)
+const (
+	_PIOD_READ_D  = 1
+	_PIOD_WRITE_D = 2
+)
+
+// ptraceAttach executes the PT_ATTACH ptrace call.
+// pid must be a PID, not a thread ID
+func ptraceAttach(pid int) error {
+	_, err := C.ptrace_attach(C.int(pid))
+	return err
+}
+
+// ptraceDetach executes the PT_DETACH ptrace call.
+func ptraceDetach(pid int) error {
+	_, err := C.ptrace_detach(C.int(pid))
+	return err
+}
+
+// ptraceCont executes the PT_CONTINUE ptrace call, resuming all threads
+// of the process.
+// id may be a PID or a thread ID
+func ptraceCont(id, sig int) error {
+	_, err := C.ptrace_cont(C.int(id), C.int(sig))
+	return err
+}
+
+// ptraceSingleStep executes the PT_STEP ptrace call.
+// tid must be a thread ID
+func ptraceSingleStep(tid int) error {
+	_, err := C.ptrace_single_step(C.int(tid))
+	return err
+}
+
+// Get a list of the thread ids of a process
+func ptraceGetLwpList(pid int) ([]int32, error) {
+	tids := make([]int32, 64)
+	for {
+		n, err := C.ptrace_get_lwp_list(C.int(pid), (*C.int)(unsafe.Pointer(&tids[0])), C.size_t(len(tids)))
+		if n < 0 {
+			return nil, err
+		}
+		if int(n) < len(tids) {
+			return tids[:n], nil
+		}
+		tids = make([]int32, 2*len(tids))
+	}
+}
+
+// Get the thread that caused the last stop of the process.
+func ptraceGetReportTid(pid int) (int, error) {
+	tid, err := C.ptrace_get_report_tid(C.int(pid))
+	if tid < 0 {
+		return 0, err
+	}
+	return int(tid), nil
+}
+
+func ptraceGetRegs(tid int, regs *obsdutil.AMD64PtraceRegs) error {
+	r, err := C.ptrace_get_regs(C.int(tid), (*C.struct_reg)(unsafe.Pointer(regs)))
+	if r < 0 {
+		return err
+	}
+	return nil
+}
+
+func ptraceSetRegs(tid int, regs *obsdutil.AMD64PtraceRegs) error {
+	r, err := C.ptrace_set_regs(C.int(tid), (*C.struct_reg)(unsafe.Pointer(regs)))
+	if r < 0 {
+		return err
+	}
+	return nil
+}
+
+// ptraceGetFpRegs reads the floating point registers of a thread, the
+// kernel returns them in the format of the FXSAVE instruction.
+func ptraceGetFpRegs(tid int) (regset amd64util.AMD64Xstate, err error) {
+	r, err := C.ptrace_get_fpregs(C.int(tid), (*C.struct_fpreg)(unsafe.Pointer(&regset.AMD64PtraceFpRegs)))
+	if r >= 0 {
+		err = nil
+	}
+	return regset, err
+}
+
+func ptraceSetFpRegs(tid int, regs *amd64util.AMD64PtraceFpRegs) error {
+	r, err := C.ptrace_set_fpregs(C.int(tid), (*C.struct_fpreg)(unsafe.Pointer(regs)))
+	if r < 0 {
+		return err
+	}
+	return nil
+}
+
+// pid must be a PID, memory is shared by all threads
+func ptraceReadData(pid int, addr uintptr, data []byte) (int, error) {
+	return ptraceIO(pid, _PIOD_READ_D, addr, data)
+}
+
+// pid must be a PID, memory is shared by all threads
+func ptraceWriteData(pid int, addr uintptr, data []byte) (int, error) {
+	return ptraceIO(pid, _PIOD_WRITE_D, addr, data)
+}
+
+func ptraceIO(pid, op int, addr uintptr, data []byte) (int, error) {
+	var n C.size_t
+	r, err := C.ptrace_io(C.int(pid), C.int(op), C.uintptr_t(addr), unsafe.Pointer(&data[0]), C.size_t(len(data)), &n)
+	if r < 0 {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+// ptraceGetEntryPoint reads the entry point of the process from its
+// auxiliary vector.
+func ptraceGetEntryPoint(pid int) (uint64, error) {
+	ep, err := C.ptrace_get_entry_point(C.int(pid))
+	return uint64(ep), err
+}
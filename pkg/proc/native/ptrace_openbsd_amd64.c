@@ -0,0 +1,120 @@
+#include <sys/types.h>
+#include <sys/ptrace.h>
+#include <sys/exec_elf.h>
+#include <machine/reg.h>
+
+#include <errno.h>
+#include <stdlib.h>
+#include <string.h>
+
+#include "ptrace_openbsd_amd64.h"
+
+/*
+ * Requests that resume the target use the address (caddr_t)1 to continue
+ * from where the target stopped.
+ */
+#define CURRENT_PC ((caddr_t)1)
+
+int ptrace_attach(int pid) {
+	return ptrace(PT_ATTACH, (pid_t)pid, 0, 0);
+}
+
+int ptrace_detach(int pid) {
+	return ptrace(PT_DETACH, (pid_t)pid, CURRENT_PC, 0);
+}
+
+/* Resumes all threads of the process, id may be a PID or a thread ID. */
+int ptrace_cont(int id, int sig) {
+	return ptrace(PT_CONTINUE, (pid_t)id, CURRENT_PC, sig);
+}
+
+int ptrace_single_step(int tid) {
+	return ptrace(PT_STEP, (pid_t)tid, CURRENT_PC, 0);
+}
+
+/*
+ * Fetches the list of threads of a given process into tids. Returns the
+ * number of thread IDs filled in, or -1 and sets errno on failure.
+ */
+int ptrace_get_lwp_list(int pid, int *tids, size_t len) {
+	struct ptrace_thread_state pts;
+	size_t n = 0;
+
+	memset(&pts, 0, sizeof(pts));
+	if (ptrace(PT_GET_THREAD_FIRST, (pid_t)pid, (caddr_t)&pts, sizeof(pts)) == -1)
+		return (-1);
+	while (pts.pts_tid != -1 && n < len) {
+		tids[n++] = pts.pts_tid;
+		if (ptrace(PT_GET_THREAD_NEXT, (pid_t)pid, (caddr_t)&pts, sizeof(pts)) == -1)
+			return (-1);
+	}
+	return (n);
+}
+
+/*
+ * Returns the ID of the thread that caused the last stop of the process,
+ * or -1 and sets errno on failure.
+ */
+int ptrace_get_report_tid(int pid) {
+	ptrace_state_t pe;
+
+	if (ptrace(PT_GET_PROCESS_STATE, (pid_t)pid, (caddr_t)&pe, sizeof(pe)) == -1)
+		return (-1);
+	return (pe.pe_tid);
+}
+
+/*
+ * Reads or writes len bytes of the target memory at addr, the number of
+ * bytes transferred is returned in n.
+ */
+int ptrace_io(int pid, int op, uintptr_t addr, void *buf, size_t len, size_t *n) {
+	struct ptrace_io_desc piod;
+
+	piod.piod_op = op;
+	piod.piod_offs = (void *)addr;
+	piod.piod_addr = buf;
+	piod.piod_len = len;
+	if (ptrace(PT_IO, (pid_t)pid, (caddr_t)&piod, 0) == -1)
+		return (-1);
+	*n = piod.piod_len;
+	return (0);
+}
+
+int ptrace_get_regs(int tid, struct reg *regs) {
+	return ptrace(PT_GETREGS, (pid_t)tid, (caddr_t)regs, 0);
+}
+
+int ptrace_set_regs(int tid, struct reg *regs) {
+	return ptrace(PT_SETREGS, (pid_t)tid, (caddr_t)regs, 0);
+}
+
+int ptrace_get_fpregs(int tid, struct fpreg *regs) {
+	return ptrace(PT_GETFPREGS, (pid_t)tid, (caddr_t)regs, 0);
+}
+
+int ptrace_set_fpregs(int tid, struct fpreg *regs) {
+	return ptrace(PT_SETFPREGS, (pid_t)tid, (caddr_t)regs, 0);
+}
+
+/*
+ * Returns the entry point of the process, read from its auxiliary vector.
+ * Sets errno on failure.
+ */
+uintptr_t ptrace_get_entry_point(int pid) {
+	Aux64Info auxv[64];
+	size_t n = 0;
+	size_t i;
+
+	if (ptrace_io(pid, PIOD_READ_AUXV, 0, auxv, sizeof(auxv), &n) == -1)
+		return (0);
+	for (i = 0; i < n / sizeof(auxv[0]); i++) {
+		if (auxv[i].au_id == AUX_entry) {
+			errno = 0;
+			return ((uintptr_t)auxv[i].au_v);
+		}
+		if (auxv[i].au_id == AUX_null)
+			break;
+	}
+	errno = EINVAL;
+	return (0);
+}
@@ -0,0 +1,18 @@
+#include <stddef.h>
+#include <stdint.h>
+
+struct reg;
+struct fpreg;
+
+int ptrace_attach(int pid);
+int ptrace_detach(int pid);
+int ptrace_cont(int id, int sig);
+int ptrace_single_step(int tid);
+int ptrace_get_lwp_list(int pid, int *tids, size_t len);
+int ptrace_get_report_tid(int pid);
+int ptrace_io(int pid, int op, uintptr_t addr, void *buf, size_t len, size_t *n);
+int ptrace_get_regs(int tid, struct reg *regs);
+int ptrace_set_regs(int tid, struct reg *regs);
+int ptrace_get_fpregs(int tid, struct fpreg *regs);
+int ptrace_set_fpregs(int tid, struct fpreg *regs);
+uintptr_t ptrace_get_entry_point(int pid);
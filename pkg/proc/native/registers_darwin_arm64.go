@@ -0,0 +1,122 @@
+//+build darwin,macnative
+
+package native
+
+// #include "threads_darwin.h"
+import "C"
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/linutil"
+)
+
+// _ARM64_NEON_VREGS_SIZE is the size of the V0-V31 registers in
+// arm_neon_state64_t.
+const _ARM64_NEON_VREGS_SIZE = 32 * 16
+
+func getThreadState(thread *nativeThread, regs *linutil.ARM64PtraceRegs) error {
+	var state C.arm_thread_state64_t
+	kret := C.get_registers(C.mach_port_name_t(thread.os.threadAct), &state)
+	if kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not get registers")
+	}
+	for i := range state.__x {
+		regs.Regs[i] = uint64(state.__x[i])
+	}
+	regs.Regs[29] = uint64(state.__fp)
+	regs.Regs[30] = uint64(state.__lr)
+	regs.Sp = uint64(state.__sp)
+	regs.Pc = uint64(state.__pc)
+	regs.Pstate = uint64(state.__cpsr)
+	return nil
+}
+
+func setThreadState(thread *nativeThread, regs *linutil.ARM64PtraceRegs) error {
+	var state C.arm_thread_state64_t
+	kret := C.get_registers(C.mach_port_name_t(thread.os.threadAct), &state)
+	if kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not get registers")
+	}
+	for i := range state.__x {
+		state.__x[i] = C.__uint64_t(regs.Regs[i])
+	}
+	state.__fp = C.__uint64_t(regs.Regs[29])
+	state.__lr = C.__uint64_t(regs.Regs[30])
+	state.__sp = C.__uint64_t(regs.Sp)
+	state.__pc = C.__uint64_t(regs.Pc)
+	state.__cpsr = C.__uint32_t(regs.Pstate)
+	kret = C.set_registers(C.mach_port_name_t(thread.os.threadAct), &state)
+	if kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not set registers")
+	}
+	return nil
+}
+
+func (thread *nativeThread) fpRegisters() ([]proc.Register, []byte, error) {
+	var state C.arm_neon_state64_t
+	kret := C.get_fpu_registers(C.mach_port_name_t(thread.os.threadAct), &state)
+	if kret != C.KERN_SUCCESS {
+		return nil, nil, fmt.Errorf("could not get floating point registers")
+	}
+	arm_fpregs := linutil.ARM64PtraceFpRegs{
+		Vregs: C.GoBytes(unsafe.Pointer(&state.__v[0]), _ARM64_NEON_VREGS_SIZE),
+		Fpsr:  uint32(state.__fpsr),
+		Fpcr:  uint32(state.__fpcr),
+	}
+	return arm_fpregs.Decode(), arm_fpregs.Vregs, nil
+}
+
+// setPC sets PC to the value specified by 'pc'.
+func (thread *nativeThread) setPC(pc uint64) error {
+	kret := C.set_pc(thread.os.threadAct, C.uint64_t(pc))
+	if kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not set pc")
+	}
+	return nil
+}
+
+// SetReg changes the value of the specified register.
+func (thread *nativeThread) SetReg(regNum uint64, reg *op.DwarfRegister) error {
+	var regs linutil.ARM64PtraceRegs
+	if err := getThreadState(thread, &regs); err != nil {
+		return err
+	}
+
+	switch {
+	case regNum <= regnum.ARM64_LR:
+		regs.Regs[regNum-regnum.ARM64_X0] = reg.Uint64Val
+	case regNum == regnum.ARM64_SP:
+		regs.Sp = reg.Uint64Val
+	case regNum == regnum.ARM64_PC:
+		regs.Pc = reg.Uint64Val
+	default:
+		return fmt.Errorf("changing register %d not implemented", regNum)
+	}
+
+	return setThreadState(thread, &regs)
+}
+
+func registers(thread *nativeThread) (proc.Registers, error) {
+	var regs linutil.ARM64PtraceRegs
+	if err := getThreadState(thread, &regs); err != nil {
+		return nil, err
+	}
+	var identity C.thread_identifier_info_data_t
+	kret := C.get_identity(C.mach_port_name_t(thread.os.threadAct), &identity)
+	if kret != C.KERN_SUCCESS {
+		return nil, fmt.Errorf("could not get thread identity informations")
+	}
+	// thread_identifier_info::thread_handle is the base of the
+	// thread-specific data area, which on arm64 is the value of the
+	// TPIDRRO_EL0 register used by cgo to store the TLS base.
+	r := linutil.NewARM64Registers(&regs, thread.dbp.iscgo, uint64(identity.thread_handle), func(r *linutil.ARM64Registers) error {
+		var floatLoadError error
+		r.Fpregs, r.Fpregset, floatLoadError = thread.fpRegisters()
+		return floatLoadError
+	})
+	return r, nil
+}
@@ -0,0 +1,89 @@
+package native
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/winutil"
+)
+
+// SetPC sets the PC register to the value specified by `pc`.
+func (thread *nativeThread) setPC(pc uint64) error {
+	context := winutil.NewARM64CONTEXT()
+	context.ContextFlags = _CONTEXT_ALL
+
+	err := _GetThreadContext(thread.os.hThread, context)
+	if err != nil {
+		return err
+	}
+
+	context.Pc = pc
+
+	return _SetThreadContext(thread.os.hThread, context)
+}
+
+// SetReg changes the value of the specified register.
+func (thread *nativeThread) SetReg(regNum uint64, reg *op.DwarfRegister) error {
+	context := winutil.NewARM64CONTEXT()
+	context.ContextFlags = _CONTEXT_ALL
+
+	err := _GetThreadContext(thread.os.hThread, context)
+	if err != nil {
+		return err
+	}
+
+	var p *uint64
+
+	switch {
+	case regNum >= regnum.ARM64_X0 && regNum <= regnum.ARM64_LR:
+		p = &context.Regs[regNum-regnum.ARM64_X0]
+	case regNum == regnum.ARM64_SP:
+		p = &context.Sp
+	case regNum == regnum.ARM64_PC:
+		p = &context.Pc
+	}
+
+	if p != nil {
+		if reg.Bytes != nil && len(reg.Bytes) != 8 {
+			return fmt.Errorf("wrong number of bytes for register %s (%d)", regnum.ARM64ToName(regNum), len(reg.Bytes))
+		}
+		*p = reg.Uint64Val
+	} else {
+		if regNum < regnum.ARM64_V0 || regNum > regnum.ARM64_V0+31 {
+			return fmt.Errorf("can not set register %s", regnum.ARM64ToName(regNum))
+		}
+		reg.FillBytes()
+		if len(reg.Bytes) > 16 {
+			return fmt.Errorf("too many bytes when setting register %s", regnum.ARM64ToName(regNum))
+		}
+		var buf [16]byte
+		copy(buf[:], reg.Bytes)
+		v := &context.V[regNum-regnum.ARM64_V0]
+		v.Low = binary.LittleEndian.Uint64(buf[:8])
+		v.High = int64(binary.LittleEndian.Uint64(buf[8:]))
+	}
+
+	return _SetThreadContext(thread.os.hThread, context)
+}
+
+func registers(thread *nativeThread) (proc.Registers, error) {
+	context := winutil.NewARM64CONTEXT()
+
+	context.ContextFlags = _CONTEXT_ALL
+	err := _GetThreadContext(thread.os.hThread, context)
+	if err != nil {
+		return nil, err
+	}
+
+	var threadInfo _THREAD_BASIC_INFORMATION
+	status := _NtQueryInformationThread(thread.os.hThread, _ThreadBasicInformation, uintptr(unsafe.Pointer(&threadInfo)), uint32(unsafe.Sizeof(threadInfo)), nil)
+	if !_NT_SUCCESS(status) {
+		return nil, fmt.Errorf("NtQueryInformationThread failed: it returns 0x%x", status)
+	}
+
+	return winutil.NewARM64Registers(context, uint64(threadInfo.TebBaseAddress), thread.dbp.iscgo), nil
+}
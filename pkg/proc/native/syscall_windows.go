@@ -2,11 +2,7 @@
 
 package native
 
-import (
-	"syscall"
-
-	"github.com/go-delve/delve/pkg/proc/winutil"
-)
+import "syscall"
 
 type _NTSTATUS int32
 
@@ -124,11 +120,6 @@ func _NT_SUCCESS(x _NTSTATUS) bool {
 	return x >= 0
 }
 
-// zsyscall_windows.go, an autogenerated file, wants to refer to the context
-// structure as _CONTEXT, but we need to have it in pkg/proc/winutil.CONTEXT
-// because it's also used on non-windows operating systems.
-type _CONTEXT = winutil.CONTEXT
-
 //sys	_NtQueryInformationThread(threadHandle syscall.Handle, infoclass int32, info uintptr, infolen uint32, retlen *uint32) (status _NTSTATUS) = ntdll.NtQueryInformationThread
 //sys	_GetThreadContext(thread syscall.Handle, context *_CONTEXT) (err error) = kernel32.GetThreadContext
 //sys	_SetThreadContext(thread syscall.Handle, context *_CONTEXT) (err error) = kernel32.SetThreadContext
@@ -1,5 +1,7 @@
 package native
 
+import "github.com/go-delve/delve/pkg/proc/winutil"
+
 const (
 	_CONTEXT_AMD64               = 0x100000
 	_CONTEXT_CONTROL             = (_CONTEXT_AMD64 | 0x1)
@@ -22,3 +24,14 @@ type _DEBUG_EVENT struct {
 	_              uint32 // to align Union properly
 	U              [160]byte
 }
+
+// zsyscall_windows.go, an autogenerated file, wants to refer to the context
+// structure as _CONTEXT, but we need to have it in pkg/proc/winutil.CONTEXT
+// because it's also used on non-windows operating systems.
+type _CONTEXT = winutil.CONTEXT
+
+// newContext allocates a _CONTEXT structure with the alignment required by
+// _GetThreadContext.
+func newContext() *_CONTEXT {
+	return winutil.NewCONTEXT()
+}
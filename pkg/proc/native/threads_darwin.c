@@ -44,64 +44,12 @@ read_memory(task_t task, mach_vm_address_t addr, void *d, mach_msg_type_number_t
 	return count;
 }
 
-kern_return_t
-get_registers(mach_port_name_t task, x86_thread_state64_t *state) {
-	kern_return_t kret;
-	mach_msg_type_number_t stateCount = x86_THREAD_STATE64_COUNT;
-	// TODO(dp) - possible memory leak - vm_deallocate state
-	return thread_get_state(task, x86_THREAD_STATE64, (thread_state_t)state, &stateCount);
-}
-
-kern_return_t
-get_fpu_registers(mach_port_name_t task, x86_float_state64_t *state) {
-	kern_return_t kret;
-	mach_msg_type_number_t stateCount = x86_FLOAT_STATE64_COUNT;
-	return thread_get_state(task, x86_FLOAT_STATE64, (thread_state_t)state, &stateCount);
-}
-
 kern_return_t
 get_identity(mach_port_name_t task, thread_identifier_info_data_t *idinfo) {
 	mach_msg_type_number_t idinfoCount = THREAD_IDENTIFIER_INFO_COUNT;
 	return thread_info(task, THREAD_IDENTIFIER_INFO, (thread_info_t)idinfo, &idinfoCount);
 }
 
-kern_return_t
-set_registers(mach_port_name_t task, x86_thread_state64_t *state) {
-	mach_msg_type_number_t stateCount = x86_THREAD_STATE64_COUNT;
-	return thread_set_state(task, x86_THREAD_STATE64, (thread_state_t)state, stateCount);
-}
-
-kern_return_t
-set_pc(thread_act_t task, uint64_t pc) {
-	kern_return_t kret;
-	x86_thread_state64_t state;
-	mach_msg_type_number_t stateCount = x86_THREAD_STATE64_COUNT;
-
-	kret = thread_get_state(task, x86_THREAD_STATE64, (thread_state_t)&state, &stateCount);
-	if (kret != KERN_SUCCESS) return kret;
-	state.__rip = pc;
-
-	return thread_set_state(task, x86_THREAD_STATE64, (thread_state_t)&state, stateCount);
-}
-
-kern_return_t
-single_step(thread_act_t thread) {
-	kern_return_t kret;
-	x86_thread_state64_t regs;
-	mach_msg_type_number_t count = x86_THREAD_STATE64_COUNT;
-
-	kret = thread_get_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, &count);
-	if (kret != KERN_SUCCESS) return kret;
-
-	// Set trap bit in rflags
-	regs.__rflags |= 0x100UL;
-
-	kret = thread_set_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, count);
-	if (kret != KERN_SUCCESS) return kret;
-
-	return resume_thread(thread);
-}
-
 kern_return_t
 resume_thread(thread_act_t thread) {
 	kern_return_t kret;
@@ -118,21 +66,6 @@ resume_thread(thread_act_t thread) {
 	return KERN_SUCCESS;
 }
 
-kern_return_t
-clear_trap_flag(thread_act_t thread) {
-	kern_return_t kret;
-	x86_thread_state64_t regs;
-	mach_msg_type_number_t count = x86_THREAD_STATE64_COUNT;
-
-	kret = thread_get_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, &count);
-	if (kret != KERN_SUCCESS) return kret;
-
-	// Clear trap bit in rflags
-	regs.__rflags ^= 0x100UL;
-
-	return thread_set_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, count);
-}
-
 int
 thread_blocked(thread_act_t thread) {
 	kern_return_t kret;
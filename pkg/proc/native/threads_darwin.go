@@ -6,7 +6,6 @@ package native
 // #include "proc_darwin.h"
 import "C"
 import (
-	"errors"
 	"fmt"
 	"unsafe"
 
@@ -22,8 +21,8 @@ type waitStatus sys.WaitStatus
 // operating system / kernel.
 type osSpecificDetails struct {
 	threadAct C.thread_act_t
-	registers C.x86_thread_state64_t
 	exists    bool
+	excCode   C.integer_t // code of the last mach exception received for this thread
 }
 
 // ErrContinueThread is the error returned when a thread could not
@@ -50,6 +49,7 @@ func (t *nativeThread) stop() (err error) {
 }
 
 func (t *nativeThread) singleStep() error {
+	t.os.excCode = 0
 	kret := C.single_step(t.os.threadAct)
 	if kret != C.KERN_SUCCESS {
 		return fmt.Errorf("could not single step")
@@ -73,6 +73,7 @@ func (t *nativeThread) singleStep() error {
 
 func (t *nativeThread) resume() error {
 	// TODO(dp) set flag for ptrace stops
+	t.os.excCode = 0
 	var err error
 	t.dbp.execPtraceFunc(func() { err = ptraceCont(t.dbp.pid, 0) })
 	if err == nil {
@@ -128,19 +129,3 @@ func (t *nativeThread) ReadMemory(buf []byte, addr uint64) (int, error) {
 	}
 	return len(buf), nil
 }
-
-func (t *nativeThread) restoreRegisters(sr proc.Registers) error {
-	return errors.New("not implemented")
-}
-
-func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
-	return proc.ErrHWBreakUnsupported
-}
-
-func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
-	return proc.ErrHWBreakUnsupported
-}
-
-func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
-	return nil, nil
-}
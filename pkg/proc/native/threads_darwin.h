@@ -13,10 +13,16 @@ int
 read_memory(task_t, mach_vm_address_t, void *, mach_msg_type_number_t);
 
 kern_return_t
-get_registers(mach_port_name_t, x86_thread_state64_t*);
+resume_thread(thread_act_t);
 
 kern_return_t
-get_fpu_registers(mach_port_name_t, x86_float_state64_t *);
+get_identity(mach_port_name_t, thread_identifier_info_data_t *);
+
+int
+thread_blocked(thread_act_t thread);
+
+int
+num_running_threads(task_t task);
 
 kern_return_t
 set_pc(thread_act_t, uint64_t);
@@ -27,17 +33,44 @@ single_step(thread_act_t);
 kern_return_t
 clear_trap_flag(thread_act_t);
 
+#if defined(__x86_64__)
+
 kern_return_t
-resume_thread(thread_act_t);
+get_registers(mach_port_name_t, x86_thread_state64_t*);
+
+kern_return_t
+get_fpu_registers(mach_port_name_t, x86_float_state64_t *);
 
 kern_return_t
 set_registers(mach_port_name_t, x86_thread_state64_t*);
 
 kern_return_t
-get_identity(mach_port_name_t, thread_identifier_info_data_t *);
+get_debug_state(thread_act_t, x86_debug_state64_t *);
 
-int
-thread_blocked(thread_act_t thread);
+kern_return_t
+set_debug_state(thread_act_t, x86_debug_state64_t *);
 
-int
-num_running_threads(task_t task);
+#elif defined(__arm64__)
+
+kern_return_t
+get_registers(mach_port_name_t, arm_thread_state64_t*);
+
+kern_return_t
+get_fpu_registers(mach_port_name_t, arm_neon_state64_t *);
+
+kern_return_t
+set_registers(mach_port_name_t, arm_thread_state64_t*);
+
+kern_return_t
+set_fpu_registers(mach_port_name_t, arm_neon_state64_t *);
+
+kern_return_t
+get_exception_state(thread_act_t, arm_exception_state64_t *);
+
+kern_return_t
+get_debug_state(thread_act_t, arm_debug_state64_t *);
+
+kern_return_t
+set_debug_state(thread_act_t, arm_debug_state64_t *);
+
+#endif
@@ -0,0 +1,81 @@
+//+build darwin,macnative
+
+#include "threads_darwin.h"
+
+kern_return_t
+get_registers(mach_port_name_t task, x86_thread_state64_t *state) {
+	kern_return_t kret;
+	mach_msg_type_number_t stateCount = x86_THREAD_STATE64_COUNT;
+	// TODO(dp) - possible memory leak - vm_deallocate state
+	return thread_get_state(task, x86_THREAD_STATE64, (thread_state_t)state, &stateCount);
+}
+
+kern_return_t
+get_fpu_registers(mach_port_name_t task, x86_float_state64_t *state) {
+	kern_return_t kret;
+	mach_msg_type_number_t stateCount = x86_FLOAT_STATE64_COUNT;
+	return thread_get_state(task, x86_FLOAT_STATE64, (thread_state_t)state, &stateCount);
+}
+
+kern_return_t
+set_registers(mach_port_name_t task, x86_thread_state64_t *state) {
+	mach_msg_type_number_t stateCount = x86_THREAD_STATE64_COUNT;
+	return thread_set_state(task, x86_THREAD_STATE64, (thread_state_t)state, stateCount);
+}
+
+kern_return_t
+set_pc(thread_act_t task, uint64_t pc) {
+	kern_return_t kret;
+	x86_thread_state64_t state;
+	mach_msg_type_number_t stateCount = x86_THREAD_STATE64_COUNT;
+
+	kret = thread_get_state(task, x86_THREAD_STATE64, (thread_state_t)&state, &stateCount);
+	if (kret != KERN_SUCCESS) return kret;
+	state.__rip = pc;
+
+	return thread_set_state(task, x86_THREAD_STATE64, (thread_state_t)&state, stateCount);
+}
+
+kern_return_t
+single_step(thread_act_t thread) {
+	kern_return_t kret;
+	x86_thread_state64_t regs;
+	mach_msg_type_number_t count = x86_THREAD_STATE64_COUNT;
+
+	kret = thread_get_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, &count);
+	if (kret != KERN_SUCCESS) return kret;
+
+	// Set trap bit in rflags
+	regs.__rflags |= 0x100UL;
+
+	kret = thread_set_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, count);
+	if (kret != KERN_SUCCESS) return kret;
+
+	return resume_thread(thread);
+}
+
+kern_return_t
+clear_trap_flag(thread_act_t thread) {
+	kern_return_t kret;
+	x86_thread_state64_t regs;
+	mach_msg_type_number_t count = x86_THREAD_STATE64_COUNT;
+
+	kret = thread_get_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, &count);
+	if (kret != KERN_SUCCESS) return kret;
+
+	// Clear trap bit in rflags
+	regs.__rflags ^= 0x100UL;
+
+	return thread_set_state(thread, x86_THREAD_STATE64, (thread_state_t)&regs, count);
+}
+
+kern_return_t
+get_debug_state(thread_act_t thread, x86_debug_state64_t *state) {
+	mach_msg_type_number_t stateCount = x86_DEBUG_STATE64_COUNT;
+	return thread_get_state(thread, x86_DEBUG_STATE64, (thread_state_t)state, &stateCount);
+}
+
+kern_return_t
+set_debug_state(thread_act_t thread, x86_debug_state64_t *state) {
+	return thread_set_state(thread, x86_DEBUG_STATE64, (thread_state_t)state, x86_DEBUG_STATE64_COUNT);
+}
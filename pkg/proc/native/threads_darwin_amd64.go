@@ -0,0 +1,77 @@
+//+build darwin,macnative
+
+package native
+
+// #include "threads_darwin.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/amd64util"
+)
+
+func (t *nativeThread) restoreRegisters(sr proc.Registers) error {
+	return errors.New("not implemented")
+}
+
+func (t *nativeThread) withDebugRegisters(f func(*amd64util.DebugRegisters) error) error {
+	var state C.x86_debug_state64_t
+	if kret := C.get_debug_state(t.os.threadAct, &state); kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not get debug registers")
+	}
+
+	drs := amd64util.NewDebugRegisters(
+		(*uint64)(unsafe.Pointer(&state.__dr0)),
+		(*uint64)(unsafe.Pointer(&state.__dr1)),
+		(*uint64)(unsafe.Pointer(&state.__dr2)),
+		(*uint64)(unsafe.Pointer(&state.__dr3)),
+		(*uint64)(unsafe.Pointer(&state.__dr6)),
+		(*uint64)(unsafe.Pointer(&state.__dr7)))
+
+	if err := f(drs); err != nil {
+		return err
+	}
+
+	if drs.Dirty {
+		if kret := C.set_debug_state(t.os.threadAct, &state); kret != C.KERN_SUCCESS {
+			return fmt.Errorf("could not set debug registers")
+		}
+	}
+	return nil
+}
+
+func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	return t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		return drs.SetBreakpoint(idx, addr, wtype.Read(), wtype.Write(), wtype.Size())
+	})
+}
+
+func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	return t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		drs.ClearBreakpoint(idx)
+		return nil
+	})
+}
+
+func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
+	var retbp *proc.Breakpoint
+	err := t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		ok, idx := drs.GetActiveBreakpoint()
+		if ok {
+			for _, bp := range t.dbp.Breakpoints().M {
+				if bp.WatchType != 0 && bp.HWBreakIndex == idx {
+					retbp = bp
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retbp, nil
+}
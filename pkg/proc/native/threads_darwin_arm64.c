@@ -0,0 +1,88 @@
+//+build darwin,macnative
+
+#include "threads_darwin.h"
+
+kern_return_t
+get_registers(mach_port_name_t task, arm_thread_state64_t *state) {
+	mach_msg_type_number_t stateCount = ARM_THREAD_STATE64_COUNT;
+	return thread_get_state(task, ARM_THREAD_STATE64, (thread_state_t)state, &stateCount);
+}
+
+kern_return_t
+get_fpu_registers(mach_port_name_t task, arm_neon_state64_t *state) {
+	mach_msg_type_number_t stateCount = ARM_NEON_STATE64_COUNT;
+	return thread_get_state(task, ARM_NEON_STATE64, (thread_state_t)state, &stateCount);
+}
+
+kern_return_t
+set_registers(mach_port_name_t task, arm_thread_state64_t *state) {
+	mach_msg_type_number_t stateCount = ARM_THREAD_STATE64_COUNT;
+	return thread_set_state(task, ARM_THREAD_STATE64, (thread_state_t)state, stateCount);
+}
+
+kern_return_t
+set_fpu_registers(mach_port_name_t task, arm_neon_state64_t *state) {
+	return thread_set_state(task, ARM_NEON_STATE64, (thread_state_t)state, ARM_NEON_STATE64_COUNT);
+}
+
+kern_return_t
+set_pc(thread_act_t task, uint64_t pc) {
+	kern_return_t kret;
+	arm_thread_state64_t state;
+	mach_msg_type_number_t stateCount = ARM_THREAD_STATE64_COUNT;
+
+	kret = thread_get_state(task, ARM_THREAD_STATE64, (thread_state_t)&state, &stateCount);
+	if (kret != KERN_SUCCESS) return kret;
+	state.__pc = pc;
+
+	return thread_set_state(task, ARM_THREAD_STATE64, (thread_state_t)&state, stateCount);
+}
+
+kern_return_t
+get_exception_state(thread_act_t thread, arm_exception_state64_t *state) {
+	mach_msg_type_number_t stateCount = ARM_EXCEPTION_STATE64_COUNT;
+	return thread_get_state(thread, ARM_EXCEPTION_STATE64, (thread_state_t)state, &stateCount);
+}
+
+kern_return_t
+get_debug_state(thread_act_t thread, arm_debug_state64_t *state) {
+	mach_msg_type_number_t stateCount = ARM_DEBUG_STATE64_COUNT;
+	return thread_get_state(thread, ARM_DEBUG_STATE64, (thread_state_t)state, &stateCount);
+}
+
+kern_return_t
+set_debug_state(thread_act_t thread, arm_debug_state64_t *state) {
+	return thread_set_state(thread, ARM_DEBUG_STATE64, (thread_state_t)state, ARM_DEBUG_STATE64_COUNT);
+}
+
+kern_return_t
+single_step(thread_act_t thread) {
+	kern_return_t kret;
+	arm_debug_state64_t state;
+
+	kret = get_debug_state(thread, &state);
+	if (kret != KERN_SUCCESS) return kret;
+
+	// Set the software step bit of MDSCR_EL1, the kernel enables the
+	// software step exception when it returns to the thread.
+	state.__mdscr_el1 |= 0x1ULL;
+
+	kret = set_debug_state(thread, &state);
+	if (kret != KERN_SUCCESS) return kret;
+
+	return resume_thread(thread);
+}
+
+kern_return_t
+clear_trap_flag(thread_act_t thread) {
+	kern_return_t kret;
+	arm_debug_state64_t state;
+
+	kret = get_debug_state(thread, &state);
+	if (kret != KERN_SUCCESS) return kret;
+
+	// Clear the software step bit of MDSCR_EL1
+	state.__mdscr_el1 &= ~0x1ULL;
+
+	return set_debug_state(thread, &state);
+}
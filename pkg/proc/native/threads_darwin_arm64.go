@@ -0,0 +1,129 @@
+//+build darwin,macnative
+
+package native
+
+// #include "threads_darwin.h"
+import "C"
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/linutil"
+)
+
+// darwinARM64MaxWatchpoints is the number of watchpoint registers
+// implemented by Apple silicon, arm_debug_state64_t has room for 16.
+const darwinARM64MaxWatchpoints = 4
+
+func (t *nativeThread) restoreRegisters(savedRegs proc.Registers) error {
+	sr := savedRegs.(*linutil.ARM64Registers)
+	if err := setThreadState(t, sr.Regs); err != nil {
+		return err
+	}
+	if sr.Fpregset == nil {
+		return nil
+	}
+	var state C.arm_neon_state64_t
+	kret := C.get_fpu_registers(C.mach_port_name_t(t.os.threadAct), &state)
+	if kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not get floating point registers")
+	}
+	copy((*[_ARM64_NEON_VREGS_SIZE]byte)(unsafe.Pointer(&state.__v[0]))[:], sr.Fpregset)
+	kret = C.set_fpu_registers(C.mach_port_name_t(t.os.threadAct), &state)
+	if kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not set floating point registers")
+	}
+	return nil
+}
+
+// withDebugRegisters reads the debug state of the thread, calls f and
+// writes it back if f returns true.
+func (t *nativeThread) withDebugRegisters(f func(state *C.arm_debug_state64_t) (bool, error)) error {
+	var state C.arm_debug_state64_t
+	if kret := C.get_debug_state(t.os.threadAct, &state); kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not get debug registers")
+	}
+	dirty, err := f(&state)
+	if err != nil || !dirty {
+		return err
+	}
+	if kret := C.set_debug_state(t.os.threadAct, &state); kret != C.KERN_SUCCESS {
+		return fmt.Errorf("could not set debug registers")
+	}
+	return nil
+}
+
+func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	if int(idx) >= darwinARM64MaxWatchpoints {
+		return errors.New("hardware breakpoints exhausted")
+	}
+	wvr, wcr, err := arm64Watchpoint(addr, wtype)
+	if err != nil {
+		return err
+	}
+	return t.withDebugRegisters(func(state *C.arm_debug_state64_t) (bool, error) {
+		if uint32(state.__wcr[idx])&wcrEnable != 0 {
+			if uint64(state.__wvr[idx]) != wvr || uint32(state.__wcr[idx]) != wcr {
+				return false, fmt.Errorf("hardware breakpoint %d already in use (address %#x)", idx, uint64(state.__wvr[idx]))
+			}
+			// hardware breakpoint already set
+			return false, nil
+		}
+		state.__wvr[idx] = C.__uint64_t(wvr)
+		state.__wcr[idx] = C.__uint64_t(wcr)
+		return true, nil
+	})
+}
+
+func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	if int(idx) >= darwinARM64MaxWatchpoints {
+		return nil
+	}
+	return t.withDebugRegisters(func(state *C.arm_debug_state64_t) (bool, error) {
+		if uint32(state.__wcr[idx])&wcrEnable == 0 {
+			return false, nil
+		}
+		state.__wcr[idx] = 0
+		state.__wvr[idx] = 0
+		return true, nil
+	})
+}
+
+// findHardwareBreakpoint returns the watchpoint that stopped the thread.
+// The debug registers do not record which watchpoint was triggered and the
+// subcode of the exception only contains the low 32 bits of the accessed
+// address, instead we read the accessed address from the exception state of
+// the thread and match it against the enabled watchpoints.
+func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
+	if t.os.excCode != C.EXC_ARM_DA_DEBUG {
+		return nil, nil
+	}
+	t.os.excCode = 0
+	var excState C.arm_exception_state64_t
+	if kret := C.get_exception_state(t.os.threadAct, &excState); kret != C.KERN_SUCCESS {
+		return nil, fmt.Errorf("could not get exception state")
+	}
+	dataAddr := uint64(excState.__far)
+
+	var retbp *proc.Breakpoint
+	err := t.withDebugRegisters(func(state *C.arm_debug_state64_t) (bool, error) {
+		for idx := 0; idx < darwinARM64MaxWatchpoints; idx++ {
+			if !arm64WatchpointMatches(uint64(state.__wvr[idx]), uint32(state.__wcr[idx]), dataAddr) {
+				continue
+			}
+			for _, bp := range t.dbp.Breakpoints().M {
+				if bp.WatchType != 0 && bp.HWBreakIndex == uint8(idx) {
+					retbp = bp
+					break
+				}
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retbp, nil
+}
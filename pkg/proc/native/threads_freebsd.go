@@ -11,6 +11,7 @@ import (
 	sys "golang.org/x/sys/unix"
 
 	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/amd64util"
 )
 
 type waitStatus sys.WaitStatus
@@ -121,14 +122,58 @@ func (t *nativeThread) ReadMemory(data []byte, addr uint64) (n int, err error) {
 	return n, err
 }
 
+func (t *nativeThread) withDebugRegisters(f func(*amd64util.DebugRegisters) error) error {
+	var err error
+	t.dbp.execPtraceFunc(func() {
+		var debugregs [16]uint64
+		err = ptraceGetDebugRegs(t.ID, &debugregs)
+		if err != nil {
+			return
+		}
+
+		drs := amd64util.NewDebugRegisters(&debugregs[0], &debugregs[1], &debugregs[2], &debugregs[3], &debugregs[6], &debugregs[7])
+
+		err = f(drs)
+
+		if err == nil && drs.Dirty {
+			err = ptraceSetDebugRegs(t.ID, &debugregs)
+		}
+	})
+	if err == sys.ESRCH {
+		err = nil
+	}
+	return err
+}
+
 func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
-	return proc.ErrHWBreakUnsupported
+	return t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		return drs.SetBreakpoint(idx, addr, wtype.Read(), wtype.Write(), wtype.Size())
+	})
 }
 
 func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
-	return proc.ErrHWBreakUnsupported
+	return t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		drs.ClearBreakpoint(idx)
+		return nil
+	})
 }
 
 func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
-	return nil, nil
+	var retbp *proc.Breakpoint
+	err := t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		ok, idx := drs.GetActiveBreakpoint()
+		if ok {
+			for _, bp := range t.dbp.Breakpoints().M {
+				if bp.WatchType != 0 && bp.HWBreakIndex == idx {
+					retbp = bp
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retbp, nil
 }
@@ -68,6 +68,11 @@ func (t *nativeThread) singleStep() (err error) {
 		if wpid == t.ID && status.StopSignal() == sys.SIGTRAP {
 			return nil
 		}
+		if wpid == t.ID && status.StopSignal() == sys.SIGURG {
+			// Asynchronous preemption signals received while single stepping
+			// are discarded, the runtime will send them again.
+			t.dbp.asyncPreempt.Hide()
+		}
 	}
 }
 
@@ -0,0 +1,96 @@
+package native
+
+import (
+	sys "golang.org/x/sys/unix"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/obsdutil"
+)
+
+type waitStatus sys.WaitStatus
+
+// osSpecificDetails hold OpenBSD specific process details.
+type osSpecificDetails struct{}
+
+func (t *nativeThread) Stopped() bool {
+	state := status(t.dbp.pid)
+	return state == statusStopped
+}
+
+func (t *nativeThread) resume() error {
+	return t.resumeWithSig(0)
+}
+
+func (t *nativeThread) resumeWithSig(sig int) (err error) {
+	t.dbp.execPtraceFunc(func() { err = ptraceCont(t.ID, sig) })
+	return
+}
+
+func (t *nativeThread) singleStep() (err error) {
+	t.dbp.execPtraceFunc(func() { err = ptraceSingleStep(t.ID) })
+	if err != nil {
+		return err
+	}
+	for {
+		th, err := t.dbp.trapWait(t.dbp.pid)
+		if err != nil {
+			return err
+		}
+		if th.ID == t.ID {
+			break
+		}
+		t.dbp.execPtraceFunc(func() { err = ptraceCont(th.ID, 0) })
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *nativeThread) restoreRegisters(savedRegs proc.Registers) error {
+	sr := savedRegs.(*obsdutil.AMD64Registers)
+
+	var restoreRegistersErr error
+	t.dbp.execPtraceFunc(func() {
+		restoreRegistersErr = ptraceSetRegs(t.ID, sr.Regs)
+		if restoreRegistersErr != nil || sr.Fpregset == nil {
+			return
+		}
+		restoreRegistersErr = ptraceSetFpRegs(t.ID, &sr.Fpregset.AMD64PtraceFpRegs)
+	})
+	return restoreRegistersErr
+}
+
+func (t *nativeThread) WriteMemory(addr uint64, data []byte) (written int, err error) {
+	if t.dbp.exited {
+		return 0, proc.ErrProcessExited{Pid: t.dbp.pid}
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	t.dbp.execPtraceFunc(func() { written, err = ptraceWriteData(t.dbp.pid, uintptr(addr), data) })
+	return written, err
+}
+
+func (t *nativeThread) ReadMemory(data []byte, addr uint64) (n int, err error) {
+	if t.dbp.exited {
+		return 0, proc.ErrProcessExited{Pid: t.dbp.pid}
+	}
+	if len(data) == 0 {
+		return 0, nil
+	}
+	t.dbp.execPtraceFunc(func() { n, err = ptraceReadData(t.dbp.pid, uintptr(addr), data) })
+	return n, err
+}
+
+func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	return proc.ErrHWBreakUnsupported
+}
+
+func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	return proc.ErrHWBreakUnsupported
+}
+
+func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
+	return nil, nil
+}
@@ -7,7 +7,6 @@ import (
 	sys "golang.org/x/sys/windows"
 
 	"github.com/go-delve/delve/pkg/proc"
-	"github.com/go-delve/delve/pkg/proc/winutil"
 )
 
 // waitStatus is a synonym for the platform-specific WaitStatus
@@ -17,11 +16,12 @@ type waitStatus sys.WaitStatus
 // operating system / kernel.
 type osSpecificDetails struct {
 	hThread            syscall.Handle
-	dbgUiRemoteBreakIn bool // whether thread is an auxiliary DbgUiRemoteBreakIn thread created by Windows
+	dbgUiRemoteBreakIn bool   // whether thread is an auxiliary DbgUiRemoteBreakIn thread created by Windows
+	dataAddr           uint64 // data address reported by the last single step exception, set by hardware watchpoints
 }
 
 func (t *nativeThread) singleStep() error {
-	context := winutil.NewCONTEXT()
+	context := newContext()
 	context.ContextFlags = _CONTEXT_ALL
 
 	// Set the processor TRAP flag
@@ -30,7 +30,7 @@ func (t *nativeThread) singleStep() error {
 		return err
 	}
 
-	context.EFlags |= 0x100
+	setTrapFlag(context, true)
 
 	err = _SetThreadContext(t.os.hThread, context)
 	if err != nil {
@@ -99,7 +99,7 @@ func (t *nativeThread) singleStep() error {
 		return err
 	}
 
-	context.EFlags &= ^uint32(0x100)
+	setTrapFlag(context, false)
 
 	return _SetThreadContext(t.os.hThread, context)
 }
@@ -151,19 +151,3 @@ func (t *nativeThread) ReadMemory(buf []byte, addr uint64) (int, error) {
 	}
 	return int(count), err
 }
-
-func (t *nativeThread) restoreRegisters(savedRegs proc.Registers) error {
-	return _SetThreadContext(t.os.hThread, savedRegs.(*winutil.AMD64Registers).Context)
-}
-
-func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
-	return proc.ErrHWBreakUnsupported
-}
-
-func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
-	return proc.ErrHWBreakUnsupported
-}
-
-func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
-	return nil, nil
-}
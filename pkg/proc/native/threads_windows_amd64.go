@@ -0,0 +1,78 @@
+package native
+
+import (
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/amd64util"
+	"github.com/go-delve/delve/pkg/proc/winutil"
+)
+
+// setTrapFlag sets or clears the TF bit of EFLAGS, which makes the
+// processor stop after executing one instruction.
+func setTrapFlag(context *_CONTEXT, trap bool) {
+	if trap {
+		context.EFlags |= 0x100
+	} else {
+		context.EFlags &= ^uint32(0x100)
+	}
+}
+
+func (t *nativeThread) restoreRegisters(savedRegs proc.Registers) error {
+	return _SetThreadContext(t.os.hThread, savedRegs.(*winutil.AMD64Registers).Context)
+}
+
+// withDebugRegisters reads the debug registers of the thread, calls f and
+// writes them back if f changed them.
+func (t *nativeThread) withDebugRegisters(f func(*amd64util.DebugRegisters) error) error {
+	context := newContext()
+	context.ContextFlags = _CONTEXT_DEBUG_REGISTERS
+	err := _GetThreadContext(t.os.hThread, context)
+	if err != nil {
+		return err
+	}
+
+	drs := amd64util.NewDebugRegisters(&context.Dr0, &context.Dr1, &context.Dr2, &context.Dr3, &context.Dr6, &context.Dr7)
+
+	err = f(drs)
+	if err != nil {
+		return err
+	}
+
+	if drs.Dirty {
+		context.ContextFlags = _CONTEXT_DEBUG_REGISTERS
+		return _SetThreadContext(t.os.hThread, context)
+	}
+	return nil
+}
+
+func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	return t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		return drs.SetBreakpoint(idx, addr, wtype.Read(), wtype.Write(), wtype.Size())
+	})
+}
+
+func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	return t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		drs.ClearBreakpoint(idx)
+		return nil
+	})
+}
+
+func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
+	var retbp *proc.Breakpoint
+	err := t.withDebugRegisters(func(drs *amd64util.DebugRegisters) error {
+		ok, idx := drs.GetActiveBreakpoint()
+		if ok {
+			for _, bp := range t.dbp.Breakpoints().M {
+				if bp.WatchType != 0 && bp.HWBreakIndex == idx {
+					retbp = bp
+					break
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retbp, nil
+}
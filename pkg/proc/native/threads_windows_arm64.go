@@ -0,0 +1,107 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/proc/winutil"
+)
+
+// setTrapFlag sets or clears the SS bit of PSTATE, which makes the
+// processor stop after executing one instruction.
+func setTrapFlag(context *_CONTEXT, trap bool) {
+	if trap {
+		context.Cpsr |= 0x200000
+	} else {
+		context.Cpsr &= ^uint32(0x200000)
+	}
+}
+
+func (t *nativeThread) restoreRegisters(savedRegs proc.Registers) error {
+	return _SetThreadContext(t.os.hThread, savedRegs.(*winutil.ARM64Registers).Context)
+}
+
+// withDebugRegisters reads the debug registers of the thread, calls f and
+// writes them back if f returns true.
+func (t *nativeThread) withDebugRegisters(f func(context *_CONTEXT) (bool, error)) error {
+	context := newContext()
+	context.ContextFlags = _CONTEXT_DEBUG_REGISTERS
+	err := _GetThreadContext(t.os.hThread, context)
+	if err != nil {
+		return err
+	}
+	dirty, err := f(context)
+	if err != nil || !dirty {
+		return err
+	}
+	context.ContextFlags = _CONTEXT_DEBUG_REGISTERS
+	return _SetThreadContext(t.os.hThread, context)
+}
+
+func (t *nativeThread) writeHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	if int(idx) >= winutil.ARM64_MAX_WATCHPOINTS {
+		return errors.New("hardware breakpoints exhausted")
+	}
+	wvr, wcr, err := arm64Watchpoint(addr, wtype)
+	if err != nil {
+		return err
+	}
+	return t.withDebugRegisters(func(context *_CONTEXT) (bool, error) {
+		if context.Wcr[idx]&wcrEnable != 0 {
+			if context.Wvr[idx] != wvr || context.Wcr[idx] != wcr {
+				return false, fmt.Errorf("hardware breakpoint %d already in use (address %#x)", idx, context.Wvr[idx])
+			}
+			// hardware breakpoint already set
+			return false, nil
+		}
+		context.Wvr[idx] = addr &^ 7
+		context.Wcr[idx] = wcr
+		return true, nil
+	})
+}
+
+func (t *nativeThread) clearHardwareBreakpoint(addr uint64, wtype proc.WatchType, idx uint8) error {
+	if int(idx) >= winutil.ARM64_MAX_WATCHPOINTS {
+		return nil
+	}
+	return t.withDebugRegisters(func(context *_CONTEXT) (bool, error) {
+		if context.Wcr[idx]&wcrEnable == 0 {
+			return false, nil
+		}
+		context.Wcr[idx] = 0
+		context.Wvr[idx] = 0
+		return true, nil
+	})
+}
+
+// findHardwareBreakpoint returns the watchpoint that stopped the thread.
+// Unlike the amd64 debug registers, the arm64 ones do not record which
+// watchpoint was triggered, instead Windows reports the accessed address
+// with the exception, which we match against the enabled watchpoints.
+func (t *nativeThread) findHardwareBreakpoint() (*proc.Breakpoint, error) {
+	dataAddr := t.os.dataAddr
+	t.os.dataAddr = 0
+	if dataAddr == 0 {
+		return nil, nil
+	}
+	var retbp *proc.Breakpoint
+	err := t.withDebugRegisters(func(context *_CONTEXT) (bool, error) {
+		for idx := range context.Wcr {
+			if !arm64WatchpointMatches(context.Wvr[idx], context.Wcr[idx], dataAddr) {
+				continue
+			}
+			for _, bp := range t.dbp.Breakpoints().M {
+				if bp.WatchType != 0 && bp.HWBreakIndex == uint8(idx) {
+					retbp = bp
+					break
+				}
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return retbp, nil
+}
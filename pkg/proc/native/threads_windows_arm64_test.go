@@ -0,0 +1,100 @@
+package native
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/go-delve/delve/pkg/proc"
+	protest "github.com/go-delve/delve/pkg/proc/test"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(protest.RunTestsWithFixtures(m))
+}
+
+func TestHardwareBreakpointARM64(t *testing.T) {
+	fixture := protest.BuildFixture("databpeasy", 0)
+	p, err := Launch([]string{fixture.Path}, ".", 0, []string{}, "", [3]string{})
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	defer p.Detach(true)
+
+	th := p.CurrentThread().(*nativeThread)
+	debugRegisters := func(idx int) (uint64, uint32) {
+		var wvr uint64
+		var wcr uint32
+		err := th.withDebugRegisters(func(context *_CONTEXT) (bool, error) {
+			wvr, wcr = context.Wvr[idx], context.Wcr[idx]
+			return false, nil
+		})
+		if err != nil {
+			t.Fatalf("withDebugRegisters: %v", err)
+		}
+		return wvr, wcr
+	}
+
+	const addr = 0x10004
+	wtype := proc.WatchWrite | proc.WatchType(4<<4) // 4 byte write watchpoint
+
+	if err := th.writeHardwareBreakpoint(addr, wtype, 0); err != nil {
+		t.Fatalf("writeHardwareBreakpoint: %v", err)
+	}
+	wvr, wcr := debugRegisters(0)
+	if wvr != 0x10000 || wcr != wcrEnable|wcrEL0|wcrStore|0xf0<<wcrBASShift {
+		t.Fatalf("wrong debug registers after writeHardwareBreakpoint: wvr=%#x wcr=%#x", wvr, wcr)
+	}
+
+	// Writing the same watchpoint again is a no-op, a different one fails.
+	if err := th.writeHardwareBreakpoint(addr, wtype, 0); err != nil {
+		t.Errorf("writeHardwareBreakpoint of the same watchpoint: %v", err)
+	}
+	if err := th.writeHardwareBreakpoint(0x20000, wtype, 0); err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Errorf("writeHardwareBreakpoint of a used index: %v", err)
+	}
+	if err := th.writeHardwareBreakpoint(0x20000, wtype, 2); err == nil || !strings.Contains(err.Error(), "exhausted") {
+		t.Errorf("writeHardwareBreakpoint past the last watchpoint register: %v", err)
+	}
+	if err := th.writeHardwareBreakpoint(0x20001, wtype, 1); err == nil {
+		t.Errorf("writeHardwareBreakpoint of an unaligned address did not fail")
+	}
+
+	bp := &proc.Breakpoint{Addr: addr, WatchType: wtype, HWBreakIndex: 0}
+	th.dbp.Breakpoints().M[addr] = bp
+	defer delete(th.dbp.Breakpoints().M, addr)
+
+	for _, tc := range []struct {
+		dataAddr uint64
+		match    bool
+	}{
+		{0, false},       // not stopped by a watchpoint
+		{0x10000, false}, // same doubleword, byte not watched
+		{0x10004, true},
+		{0x10007, true},
+		{0x10008, false},
+	} {
+		th.os.dataAddr = tc.dataAddr
+		found, err := th.findHardwareBreakpoint()
+		if err != nil {
+			t.Fatalf("findHardwareBreakpoint(%#x): %v", tc.dataAddr, err)
+		}
+		if (found == bp) != tc.match {
+			t.Errorf("findHardwareBreakpoint(%#x): got %v, match expected: %v", tc.dataAddr, found, tc.match)
+		}
+		if th.os.dataAddr != 0 {
+			t.Errorf("findHardwareBreakpoint(%#x) did not reset the data address", tc.dataAddr)
+		}
+	}
+
+	if err := th.clearHardwareBreakpoint(addr, wtype, 0); err != nil {
+		t.Fatalf("clearHardwareBreakpoint: %v", err)
+	}
+	if wvr, wcr := debugRegisters(0); wvr != 0 || wcr != 0 {
+		t.Errorf("wrong debug registers after clearHardwareBreakpoint: wvr=%#x wcr=%#x", wvr, wcr)
+	}
+	th.os.dataAddr = addr
+	if found, _ := th.findHardwareBreakpoint(); found != nil {
+		t.Errorf("findHardwareBreakpoint found %v after clearHardwareBreakpoint", found)
+	}
+}
@@ -0,0 +1,49 @@
+//+build windows darwin,macnative
+
+package native
+
+import (
+	"fmt"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// Fields of the watchpoint control registers (DBGWCR<n>_EL1).
+const (
+	wcrEnable   = 0x1
+	wcrEL0      = 0x2 << 1 // privileged access control: match accesses from EL0
+	wcrLoad     = 0x1 << 3
+	wcrStore    = 0x2 << 3
+	wcrBASShift = 5 // byte address select, one bit for each byte of the doubleword
+)
+
+// arm64Watchpoint returns the values of the watchpoint value and control
+// registers (DBGWVR<n>_EL1 and DBGWCR<n>_EL1) for a watchpoint of type
+// wtype at addr.
+func arm64Watchpoint(addr uint64, wtype proc.WatchType) (wvr uint64, wcr uint32, err error) {
+	sz := wtype.Size()
+	if sz != 1 && sz != 2 && sz != 4 && sz != 8 {
+		return 0, 0, fmt.Errorf("data breakpoint of size %d not supported", sz)
+	}
+	if addr%uint64(sz) != 0 {
+		return 0, 0, fmt.Errorf("data breakpoint at unaligned address %#x not supported", addr)
+	}
+	wcr = uint32(wcrEnable | wcrEL0)
+	if wtype.Read() {
+		wcr |= wcrLoad
+	}
+	if wtype.Write() {
+		wcr |= wcrStore
+	}
+	wcr |= ((1<<uint(sz) - 1) << (addr & 7)) << wcrBASShift
+	return addr &^ 7, wcr, nil
+}
+
+// arm64WatchpointMatches returns true if the watchpoint described by wvr
+// and wcr is enabled and covers dataAddr.
+func arm64WatchpointMatches(wvr uint64, wcr uint32, dataAddr uint64) bool {
+	if wcr&wcrEnable == 0 || wvr != dataAddr&^7 {
+		return false
+	}
+	return (wcr>>wcrBASShift)&(1<<(dataAddr&7)) != 0
+}
@@ -1,7 +1,17 @@
 package proc
 
 import (
+	"debug/elf"
+	"errors"
+	"fmt"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 	"unsafe"
@@ -132,3 +142,460 @@ func TestRegabiFlagSentinel(t *testing.T) {
 		t.Errorf("regabi flag not set")
 	}
 }
+
+// elfMemory is a MemoryReadWriter that reads the initial contents of
+// memory from the PT_LOAD segments of an executable file.
+type elfMemory struct {
+	progs []*elf.Prog
+}
+
+func (mem *elfMemory) ReadMemory(buf []byte, addr uint64) (int, error) {
+	for _, prog := range mem.progs {
+		if prog.Type == elf.PT_LOAD && addr >= prog.Vaddr && addr+uint64(len(buf)) <= prog.Vaddr+prog.Filesz {
+			return prog.ReadAt(buf, int64(addr-prog.Vaddr))
+		}
+	}
+	return 0, fmt.Errorf("could not read %#x", addr)
+}
+
+func (mem *elfMemory) WriteMemory(uint64, []byte) (int, error) {
+	panic("not supported")
+}
+
+func TestBigEndianVariables(t *testing.T) {
+	// Cross compiles a fixture for linux/s390x and reads its global
+	// variables from the executable file to check that values are decoded
+	// using the byte order of the target.
+	testCrossArchVariables(t, "s390x")
+}
+
+func TestCrossArchVariables(t *testing.T) {
+	// Reading executables built for a different architecture must not
+	// depend on the architecture Delve is running on.
+	for _, goarch := range []string{"arm64", "riscv64", "loong64"} {
+		t.Run(goarch, func(t *testing.T) {
+			testCrossArchVariables(t, goarch)
+		})
+	}
+}
+
+func testCrossArchVariables(t *testing.T, goarch string) {
+	if testing.Short() {
+		t.Skip("cross compiling fixtures is slow")
+	}
+	dir, err := ioutil.TempDir("", "bigendian")
+	assertNoError(err, t, "TempDir")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "bigendian")
+	cmd := exec.Command("go", "build", "-gcflags=all=-N -l", "-o", path, filepath.Join(protest.FindFixturesDir(), "bigendian.go"))
+	cmd.Env = append(os.Environ(), "GOOS=linux", "GOARCH="+goarch, "CGO_ENABLED=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("could not build fixture: %v\n%s", err, out)
+	}
+
+	bi := NewBinaryInfo("linux", goarch)
+	assertNoError(bi.LoadBinaryInfo(path, 0, nil), t, "LoadBinaryInfo")
+	exe, err := elf.Open(path)
+	assertNoError(err, t, "elf.Open")
+	defer exe.Close()
+	scope := globalScope(bi, bi.Images[0], &elfMemory{exe.Progs})
+
+	for _, tc := range []struct {
+		expr, value string
+	}{
+		{"main.gi8", "-2"},
+		{"main.gi16", "-258"},
+		{"main.gi32", "-16909060"},
+		{"main.gi64", "72623859790382856"},
+		{"main.gu16", "48879"},
+		{"main.gu32", "3735928559"},
+		{"main.gu64", "18364758544493064720"},
+		{"main.gf32", "1.5"},
+		{"main.gf64", "-2.25"},
+		{"main.gstr", `"a big endian string"`},
+		{"main.garr[1]", "-2"},
+		{"len(main.gsl)", "3"},
+		{"main.gsl[2]", "30"},
+		{"main.gs.A", "-7"},
+		{"main.gs.B", "16909060"},
+		{"*main.gs.C", "72623859790382856"},
+		{"main.gptr.B", "16909060"},
+	} {
+		v, err := scope.EvalExpression(tc.expr, LoadConfig{true, 1, 64, 64, -1, 0})
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if v.Unreadable != nil || v.Value == nil {
+			t.Errorf("%s: unreadable: %v", tc.expr, v.Unreadable)
+			continue
+		}
+		if out := v.Value.String(); out != tc.value {
+			t.Errorf("%s: got %s expected %s", tc.expr, out, tc.value)
+		}
+	}
+}
+
+func TestFuncCallUnsupportedArch(t *testing.T) {
+	for _, arch := range []*Arch{I386Arch("linux"), ARM64Arch("linux"), S390XArch("linux")} {
+		if _, ok := debugCallProtocolReg(arch.Name, maxDebugCallVersion); ok {
+			t.Errorf("%s: unexpected protocol register", arch.Name)
+		}
+		err := funcCallUnsupportedError(&BinaryInfo{Arch: arch})
+		if err == nil || err == errFuncCallUnsupportedBackend {
+			t.Errorf("%s: wrong error %v", arch.Name, err)
+		}
+	}
+	if err := funcCallUnsupportedError(&BinaryInfo{Arch: AMD64Arch("linux")}); err != errFuncCallUnsupportedBackend {
+		t.Errorf("amd64: wrong error %v", err)
+	}
+}
+
+func TestRelocateStack(t *testing.T) {
+	oldstack := stack{lo: 0xc000100000, hi: 0xc000102000}
+	newstack := stack{lo: 0xc000200000, hi: 0xc000204000}
+
+	local := &Variable{Addr: 0xc000101f00, Base: 0xc000101e00}
+	local.Children = []Variable{{Addr: 0xc000101f08}, {Addr: 0x4a0000}}
+	field := &local.Children[0]
+	ret := &Variable{Addr: 0xc000101000, Flags: VariableFakeAddress}
+	global := &Variable{Addr: 0x5a0000, closureAddr: 0xc000101100}
+
+	callCtx := &callContext{vars: []*Variable{local, field, ret, global}}
+	callCtx.relocateStack(oldstack, newstack)
+
+	for _, tc := range []struct {
+		name      string
+		got, want uint64
+	}{
+		{"local.Addr", local.Addr, 0xc000203f00},
+		{"local.Base", local.Base, 0xc000203e00},
+		{"field.Addr", field.Addr, 0xc000203f08},
+		{"heap child", local.Children[1].Addr, 0x4a0000},
+		{"fake address", ret.Addr, 0xc000101000},
+		{"global.Addr", global.Addr, 0x5a0000},
+		{"global.closureAddr", global.closureAddr, 0xc000203100},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %#x expected %#x", tc.name, tc.got, tc.want)
+		}
+	}
+}
+
+func TestCallerinBuiltinArgs(t *testing.T) {
+	scope := &EvalScope{BinInfo: NewBinaryInfo("linux", "amd64")}
+	for _, tc := range []struct {
+		expr, err string
+	}{
+		{`callerin("main.main")`, "wrong number of arguments to callerin: 1"},
+		{`callerin(1, 2)`, "invalid argument 1 (type int) to callerin"},
+		{`callerin("main.main", "2")`, "invalid argument \"2\" (type string) to callerin"},
+		{`callerin("main.main", 0)`, "invalid argument 0 to callerin: depth must be positive"},
+	} {
+		expr, err := parser.ParseExpr(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = scope.evalAST(expr)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%s: got error %v, expected %q", tc.expr, err, tc.err)
+		}
+	}
+}
+
+func TestPseudoBuiltins(t *testing.T) {
+	scope := &EvalScope{BinInfo: NewBinaryInfo("linux", "amd64")}
+	for _, tc := range []struct {
+		expr, err string
+	}{
+		{`now(1)`, "wrong number of arguments to now: 1"},
+		{`hitcount()`, "hitcount can only be used at a breakpoint"},
+		{`goid(1)`, "wrong number of arguments to goid: 1"},
+		{`label()`, "wrong number of arguments to label: 0"},
+		{`label(1)`, "invalid argument 1 (type int) to label"},
+	} {
+		expr, err := parser.ParseExpr(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = scope.evalAST(expr)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%s: got error %v, expected %q", tc.expr, err, tc.err)
+		}
+	}
+
+	for _, tc := range []struct {
+		expr, want string
+	}{
+		{`goid()`, "0"},
+		{`label("name")`, `""`},
+		{`now() > "2021-01-01T00:00:00Z"`, "true"},
+	} {
+		expr, err := parser.ParseExpr(tc.expr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, err := scope.evalAST(expr)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", tc.expr, err)
+			continue
+		}
+		if v.Value.ExactString() != tc.want {
+			t.Errorf("%s: got %s, expected %s", tc.expr, v.Value.ExactString(), tc.want)
+		}
+	}
+}
+
+func TestCustomBuiltins(t *testing.T) {
+	tgt := &Target{}
+	double := CustomBuiltin{Fn: func(scope *EvalScope, args []*Variable) (*Variable, error) {
+		if len(args) != 1 || args[0].Value == nil || args[0].Value.Kind() != constant.Int {
+			return nil, errors.New("wrong argument")
+		}
+		return NewConstant(constant.BinaryOp(args[0].Value, token.MUL, constant.MakeInt64(2)), scope.Mem), nil
+	}}
+	if err := tgt.RegisterBuiltin("double", double); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"double", "len", "hitcount", "int", "nil", "not-an-identifier", ""} {
+		if err := tgt.RegisterBuiltin(name, double); err == nil {
+			t.Errorf("RegisterBuiltin(%q): expected error", name)
+		}
+	}
+
+	scope := &EvalScope{BinInfo: NewBinaryInfo("linux", "amd64"), target: tgt}
+	eval := func(s string) (*Variable, error) {
+		expr, err := parser.ParseExpr(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return scope.evalAST(expr)
+	}
+	v, err := eval("double(21) + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.Value.ExactString() != "43" {
+		t.Errorf("double(21) + 1: got %s, expected 43", v.Value.ExactString())
+	}
+	if _, err := eval(`double("x")`); err == nil || err.Error() != "double: wrong argument" {
+		t.Errorf(`double("x"): got error %v`, err)
+	}
+
+	if err := tgt.UnregisterBuiltin("double"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tgt.UnregisterBuiltin("double"); err == nil {
+		t.Errorf("UnregisterBuiltin: expected error for a builtin that is not registered")
+	}
+	if _, err := eval("double(21)"); err == nil {
+		t.Errorf("double(21): expected error after UnregisterBuiltin")
+	}
+}
+
+func TestParseInterestPath(t *testing.T) {
+	for _, tc := range []struct {
+		path string
+		want []string
+	}{
+		{"req", []string{"req"}},
+		{"req.URL.Path", []string{"req", "URL", "Path"}},
+		{"(conn).state", []string{"conn", "state"}},
+		{"a[0].b", nil},
+		{"f().b", nil},
+		{"a.", nil},
+	} {
+		got, err := parseInterestPath(tc.path)
+		if tc.want == nil {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", tc.path, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: got %q %v, expected %q", tc.path, got, err, tc.want)
+		}
+	}
+}
+
+func TestGoroutineTracker(t *testing.T) {
+	tgt := &Target{}
+	stop := func(gids ...int) {
+		tgt.gtracker.resumed()
+		for _, gid := range gids {
+			tgt.gtracker.seen(gid)
+		}
+		tgt.gtracker.complete()
+	}
+
+	stop(1, 2, 3)
+	if tgt.IsNewGoroutine(3) {
+		t.Errorf("goroutine 3 marked as new at the first stop")
+	}
+	if terminated := tgt.TerminatedGoroutines(); terminated != nil {
+		t.Errorf("terminated goroutines at the first stop: %v", terminated)
+	}
+
+	stop(1, 3, 4)
+	if tgt.IsNewGoroutine(3) || !tgt.IsNewGoroutine(4) {
+		t.Errorf("wrong new goroutines: 3:%v 4:%v", tgt.IsNewGoroutine(3), tgt.IsNewGoroutine(4))
+	}
+	if terminated := tgt.TerminatedGoroutines(); !reflect.DeepEqual(terminated, []int{2}) {
+		t.Errorf("terminated goroutines: got %v expected [2]", terminated)
+	}
+
+	// A stop where the goroutines are not listed does not change the
+	// previous stop.
+	tgt.gtracker.resumed()
+	stop(1, 4)
+	if !reflect.DeepEqual(tgt.TerminatedGoroutines(), []int{3}) || tgt.IsNewGoroutine(4) {
+		t.Errorf("wrong state after a stop without listing: terminated %v, 4 new %v", tgt.TerminatedGoroutines(), tgt.IsNewGoroutine(4))
+	}
+
+	tgt.SetAnnotation(Annotation{GoroutineID: 7, Name: "worker"})
+	gs := []*G{{ID: 5}, {ID: 1}, {ID: 7}, {ID: 3}}
+	tgt.SortGoroutines(gs)
+	gids := make([]int, len(gs))
+	for i := range gs {
+		gids[i] = gs[i].ID
+	}
+	if !reflect.DeepEqual(gids, []int{7, 1, 3, 5}) {
+		t.Errorf("wrong order: %v", gids)
+	}
+	if err := tgt.SetGoroutineName(8, "worker"); err == nil {
+		t.Errorf("duplicate goroutine name accepted")
+	}
+	if err := tgt.SetGoroutineName(7, ""); err != nil || tgt.GoroutineName(7) != "" {
+		t.Errorf("could not remove name: %v %q", err, tgt.GoroutineName(7))
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	tgt := &Target{}
+	for _, a := range []Annotation{
+		{GoroutineID: 3, Name: "leader", Note: "elected at startup"},
+		{Addr: 0x2000, Size: 0x10, Name: "rxbuf"},
+		{Addr: 0x1000, Note: "suspicious"},
+		{Addr: 0x2008, Size: 4, Note: "header"},
+	} {
+		if err := tgt.SetAnnotation(a); err != nil {
+			t.Fatalf("SetAnnotation(%v): %v", a, err)
+		}
+	}
+	if err := tgt.SetAnnotation(Annotation{GoroutineID: 4, Name: "leader"}); err == nil {
+		t.Errorf("duplicate goroutine name accepted")
+	}
+	if err := tgt.SetAnnotation(Annotation{Note: "nowhere"}); err == nil {
+		t.Errorf("annotation without goroutine or address accepted")
+	}
+
+	for _, tc := range []struct {
+		addr uint64
+		want string
+	}{
+		{0x1000, "suspicious"},
+		{0x1001, ""},
+		{0x2004, "rxbuf"},
+		{0x200a, "header"},
+		{0x200c, "rxbuf"},
+		{0x2010, ""},
+	} {
+		a, ok := tgt.AddressAnnotation(tc.addr)
+		got := a.Name + a.Note
+		if ok != (tc.want != "") || got != tc.want {
+			t.Errorf("AddressAnnotation(%#x): got %q %v, expected %q", tc.addr, got, ok, tc.want)
+		}
+	}
+
+	tgt.SetAnnotation(Annotation{Addr: 0x2008})
+	if a, _ := tgt.AddressAnnotation(0x200a); a.Name != "rxbuf" {
+		t.Errorf("annotation not removed: %v", a)
+	}
+	if tgt.GoroutineName(3) != "leader" {
+		t.Errorf("wrong goroutine name %q", tgt.GoroutineName(3))
+	}
+	anns := tgt.Annotations()
+	if len(anns) != 3 || anns[0].GoroutineID != 3 || anns[1].Addr != 0x1000 || anns[2].Addr != 0x2000 {
+		t.Errorf("wrong annotations: %v", anns)
+	}
+}
+
+func TestGroupGoroutinesByStack(t *testing.T) {
+	stack := func(pcs ...uint64) []Stackframe {
+		r := make([]Stackframe, len(pcs))
+		for i := range pcs {
+			r[i].Current.PC = pcs[i]
+		}
+		return r
+	}
+	gs := []*G{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}, {ID: 6}}
+	stacks := [][]Stackframe{
+		stack(0x10, 0x20),
+		stack(0x30, 0x40),
+		stack(0x10, 0x20),
+		stack(0x10),
+		stack(0x30, 0x40),
+		stack(0x10, 0x20),
+	}
+	groups := groupGoroutinesByStack(gs, stacks)
+	var got [][]int
+	for _, group := range groups {
+		var gids []int
+		for _, g := range group.Goroutines {
+			gids = append(gids, g.ID)
+		}
+		got = append(got, gids)
+	}
+	if tgt := [][]int{{1, 3, 6}, {2, 5}, {4}}; !reflect.DeepEqual(got, tgt) {
+		t.Errorf("wrong groups: got %v expected %v", got, tgt)
+	}
+	if len(groups[2].Stack) != 1 || groups[2].Stack[0].Current.PC != 0x10 {
+		t.Errorf("wrong stack for the last group: %v", groups[2].Stack)
+	}
+}
+
+func TestLastTestLogEntry(t *testing.T) {
+	for _, tc := range []struct {
+		output, want string
+	}{
+		{"", ""},
+		{"    main_test.go:10: expected 1, got 2\n", "main_test.go:10: expected 1, got 2"},
+		{"    main_test.go:9: checking\n    main_test.go:10: expected 1\n        got 2\n", "main_test.go:10: expected 1\ngot 2"},
+		// Go 1.13 and earlier
+		{"\tmain_test.go:9: checking\n\tmain_test.go:10: failed\n", "main_test.go:10: failed"},
+		// test2json markers
+		{"\x16    main_test.go:10: \x0ffailed\x0e\n", "main_test.go:10: failed"},
+		// truncated output
+		{"        got 2\n", ""},
+	} {
+		if got := lastTestLogEntry([]byte(tc.output)); got != tc.want {
+			t.Errorf("lastTestLogEntry(%q) = %q, want %q", tc.output, got, tc.want)
+		}
+	}
+}
+
+func TestPerfMapSymbolizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "perf-1.map")
+	err := ioutil.WriteFile(path, []byte("7f0000001000 20 py::main:/tmp/main.py\n"+
+		"0x7f0000002000 0x10 [jit] Foo.Bar()\n"+
+		"garbage\n"+
+		"7f0000001000 8 py::reused:/tmp/main.py\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &PerfMapSymbolizer{path: path}
+	for _, tc := range []struct {
+		pc   uint64
+		want string
+	}{
+		{0x7f0000000fff, ""},
+		{0x7f0000001004, "py::reused:/tmp/main.py"},
+		{0x7f000000101f, ""},
+		{0x7f0000002000, "[jit] Foo.Bar()"},
+		{0x7f0000002010, ""},
+	} {
+		if got, _, _ := s.Symbolize(tc.pc); got != tc.want {
+			t.Errorf("Symbolize(%#x) = %q, want %q", tc.pc, got, tc.want)
+		}
+	}
+}
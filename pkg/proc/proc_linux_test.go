@@ -1,11 +1,14 @@
 package proc_test
 
 import (
+	"debug/elf"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
 
+	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/native"
 	protest "github.com/go-delve/delve/pkg/proc/test"
 )
@@ -37,3 +40,107 @@ func stripAndCopyDebugInfo(f protest.Fixture, t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestStacktraceMissingFDE(t *testing.T) {
+	// When the call frame information of a function is missing its caller
+	// is found by following the frame pointer and marked as approximate.
+	skipUnlessOn(t, "frame pointer unwinding only tested on amd64", "amd64")
+	fixture := protest.BuildFixture("stacktraceprog", 0)
+	path := removeFDE(t, fixture.Path, "main.func2")
+
+	p, err := native.Launch([]string{path}, "", 0, []string{}, "", [3]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Detach(true)
+
+	// Without the FDE the end of the prologue of main.func2 is unknown, stop
+	// inside its body, after the frame pointer has been saved.
+	setFileBreakpoint(p, t, fixture.Source, 12)
+	assertNoError(p.Continue(), t, "Continue()")
+	frames, err := proc.ThreadStacktrace(p.CurrentThread(), 20)
+	assertNoError(err, t, "ThreadStacktrace")
+
+	expected := []struct {
+		name        string
+		approximate bool
+	}{
+		{"main.func2", false},
+		{"main.main", true},
+		{"runtime.main", false},
+		{"runtime.goexit", false},
+	}
+	if len(frames) != len(expected) {
+		t.Fatalf("wrong number of frames %d, expected %d", len(frames), len(expected))
+	}
+	for i, frame := range frames {
+		name := "?"
+		if frame.Call.Fn != nil {
+			name = frame.Call.Fn.Name
+		}
+		if name != expected[i].name || frame.Approximate != expected[i].approximate || frame.Err != nil {
+			t.Errorf("frame %d: got %s approximate=%v err=%v, expected %s approximate=%v", i, name, frame.Approximate, frame.Err, expected[i].name, expected[i].approximate)
+		}
+	}
+}
+
+// removeFDE writes a copy of the executable at path without the
+// .debug_frame FDE of function fn and returns its path.
+func removeFDE(t *testing.T, path, fn string) string {
+	f, err := elf.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	syms, err := f.Symbols()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entry uint64
+	for _, sym := range syms {
+		if sym.Name == fn {
+			entry = sym.Value
+		}
+	}
+	if entry == 0 {
+		t.Fatalf("could not find %s", fn)
+	}
+	data, err := f.Section(".debug_frame").Data()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Entries of .debug_frame: 32bit length, 32bit CIE id or pointer and,
+	// for FDEs, the initial location of the covered range.
+	var out []byte
+	found := false
+	for len(data) > 0 {
+		n := 4 + int(f.ByteOrder.Uint32(data))
+		isFDE := f.ByteOrder.Uint32(data[4:]) != 0xffffffff
+		if isFDE && f.ByteOrder.Uint64(data[8:]) == entry {
+			found = true
+		} else {
+			out = append(out, data[:n]...)
+		}
+		data = data[n:]
+	}
+	if !found {
+		t.Fatalf("could not find the FDE of %s", fn)
+	}
+
+	dir := t.TempDir()
+	debugFrame := filepath.Join(dir, "debug_frame")
+	if err := ioutil.WriteFile(debugFrame, out, 0600); err != nil {
+		t.Fatal(err)
+	}
+	outPath := filepath.Join(dir, filepath.Base(path))
+	for _, args := range [][]string{
+		{"--decompress-debug-sections", path, outPath},
+		{"--update-section", ".debug_frame=" + debugFrame, outPath},
+	} {
+		if out, err := exec.Command("objcopy", args...).CombinedOutput(); err != nil {
+			t.Fatalf("objcopy: %v\n%s", err, out)
+		}
+	}
+	return outPath
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"go/ast"
 	"go/constant"
+	"go/parser"
 	"go/token"
 	"io/ioutil"
 	"math/rand"
@@ -1423,6 +1424,50 @@ func TestRecursiveStructure(t *testing.T) {
 	})
 }
 
+func TestLocalVariablesInterest(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testvariables2", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope()")
+		vars, err := scope.LocalVariablesInterest(normalLoadConfig, []string{"c1.pb.a"})
+		assertNoError(err, t, "LocalVariablesInterest()")
+
+		var c1, w4 *proc.Variable
+		for _, v := range vars {
+			switch v.Name {
+			case "c1":
+				c1 = v
+			case "w4":
+				w4 = v
+			}
+		}
+		if c1 == nil || w4 == nil {
+			t.Fatalf("c1 or w4 not found")
+		}
+
+		// c1.pb.a is loaded
+		if len(c1.Children) != 2 || len(c1.Children[0].Children) != 1 {
+			t.Fatalf("c1.pb not loaded: %#v", c1.Children)
+		}
+		b := c1.Children[0].Children[0]
+		if b.OnlyAddr || len(b.Children) != 1 || len(b.Children[0].Children) != 2 {
+			t.Fatalf("c1.pb.a not loaded: %#v", b)
+		}
+		if a := b.Children[0].Children[0]; a.Name != "A" || a.Value.String() != "1" {
+			t.Errorf("wrong value of c1.pb.a.A: %s = %v", a.Name, a.Value)
+		}
+		// c1.sa is not loaded
+		if sa := c1.Children[1]; sa.Len != 3 || len(sa.Children) != 0 {
+			t.Errorf("c1.sa should not be loaded: len %d, %d children", sa.Len, len(sa.Children))
+		}
+		// w4 is not dereferenced
+		if len(w4.Children) != 1 || !w4.Children[0].OnlyAddr {
+			t.Errorf("w4 should not be dereferenced: %#v", w4.Children)
+		}
+	})
+}
+
 func TestIssue316(t *testing.T) {
 	// A pointer loop that includes one interface should not send dlv into an infinite loop
 	protest.AllowRecording(t)
@@ -1477,6 +1522,41 @@ func TestBreakpointCounts(t *testing.T) {
 	})
 }
 
+func TestSerializeBreakpointHits(t *testing.T) {
+	// Every hit of a breakpoint with SerializeHits set is reported by its
+	// own stop, none of them is lost.
+	skipOn(t, "broken", "freebsd")
+	protest.AllowRecording(t)
+	withTestProcess("bpcountstest", t, func(p *proc.Target, fixture protest.Fixture) {
+		bp := setFileBreakpoint(p, t, fixture.Source, 12)
+		bp.SerializeHits = true
+
+		stops := 0
+		for {
+			if err := p.Continue(); err != nil {
+				if _, exited := err.(proc.ErrProcessExited); exited {
+					break
+				}
+				assertNoError(err, t, "Continue()")
+			}
+			active := 0
+			for _, th := range p.ThreadList() {
+				if bpstate := th.Breakpoint(); bpstate.Active && bpstate.Breakpoint == bp {
+					active++
+				}
+			}
+			if active != 1 {
+				t.Fatalf("%d threads reported at the breakpoint", active)
+			}
+			stops++
+		}
+
+		if bp.TotalHitCount != 200 || stops != 200 {
+			t.Fatalf("Wrong number of stops (%d) or TotalHitCount (%d)", stops, bp.TotalHitCount)
+		}
+	})
+}
+
 func BenchmarkArray(b *testing.B) {
 	// each bencharr struct is 128 bytes, bencharr is 64 elements long
 	b.SetBytes(int64(64 * 128))
@@ -1673,6 +1753,88 @@ func TestCondBreakpoint(t *testing.T) {
 	})
 }
 
+func TestCondBreakpointCallerin(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextprog", t, func(p *proc.Target, fixture protest.Fixture) {
+		bp := setFunctionBreakpoint(p, t, "fmt.Println")
+
+		for _, tc := range []struct {
+			cond   string
+			caller string
+			line   int
+		}{
+			{`callerin("main.main", 2)`, "main.testnext", 27},
+			{`callerin("main.helloworld", 1)`, "main.helloworld", 14},
+			{`callerin("main.main", 1)`, "main.main", 42},
+		} {
+			cond, err := parser.ParseExpr(tc.cond)
+			assertNoError(err, t, "ParseExpr")
+			bp.Cond = cond
+
+			assertNoError(p.Continue(), t, "Continue()")
+			frames, err := proc.ThreadStacktrace(p.CurrentThread(), 1)
+			assertNoError(err, t, "ThreadStacktrace")
+			if len(frames) < 2 || frames[1].Call.Fn == nil || frames[1].Call.Fn.Name != tc.caller || frames[1].Call.Line != tc.line {
+				t.Fatalf("%s: stopped at wrong call of fmt.Println %v", tc.cond, frames)
+			}
+		}
+	})
+}
+
+func TestTimeExpressions(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("timeexpr", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+		for _, tc := range []struct {
+			expr string
+			want string
+		}{
+			{`elapsed > 500*time.Millisecond`, "true"},
+			{`elapsed > "1s"`, "false"},
+			{`elapsed == time.Duration("750ms")`, "true"},
+			{`elapsed.Seconds()`, "0.75"},
+			{`elapsed.Milliseconds()`, "750"},
+			{`start.Before(deadline)`, "true"},
+			{`deadline.After(start)`, "true"},
+			{`deadline.Sub(start) == 2*time.Second`, "true"},
+			{`deadline.Before("2021-06-01")`, "false"},
+			{`start < deadline`, "true"},
+			{`start == "2021-06-01T12:00:00Z"`, "true"},
+			{`deadline >= "2021-06-01T12:00:02Z"`, "true"},
+			{`start.Unix()`, "1622548800"},
+			{`now.After(start)`, "true"},
+		} {
+			v := evalVariable(p, t, tc.expr)
+			if v.Value == nil || v.Value.String() != tc.want {
+				t.Errorf("%s: got %v, expected %s", tc.expr, v.Value, tc.want)
+			}
+		}
+		for _, expr := range []string{`elapsed > "soon"`, `start < "yesterday"`} {
+			if _, err := evalVariableOrError(p, expr); err == nil {
+				t.Errorf("%s: expected error", expr)
+			}
+		}
+	})
+}
+
+func TestCondBreakpointHitcount(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("increment", t, func(p *proc.Target, fixture protest.Fixture) {
+		bp := setFunctionBreakpoint(p, t, "main.Increment")
+		cond, err := parser.ParseExpr("hitcount() == 2 && goid() == 1")
+		assertNoError(err, t, "ParseExpr")
+		bp.Cond = cond
+
+		assertNoError(p.Continue(), t, "Continue()")
+		if y, _ := constant.Int64Val(evalVariable(p, t, "y").Value); y != 1 {
+			t.Fatalf("stopped at the wrong call of main.Increment, y = %d", y)
+		}
+		if n, _ := constant.Int64Val(evalVariable(p, t, "hitcount()").Value); n != 2 {
+			t.Fatalf("wrong hitcount() %d", n)
+		}
+	})
+}
+
 func TestCondBreakpointError(t *testing.T) {
 	skipOn(t, "broken", "freebsd")
 	protest.AllowRecording(t)
@@ -2862,6 +3024,90 @@ func TestAttachDetach(t *testing.T) {
 	cmd.Process.Kill()
 }
 
+func TestStubConnect(t *testing.T) {
+	// Connects to an instance of lldb-server already attached to the target,
+	// like 'dlv stub' does.
+	if runtime.GOOS != "linux" || testBackend == "rr" {
+		t.Skip("N/A")
+	}
+	lldbServer, err := exec.LookPath("lldb-server")
+	if err != nil {
+		t.Skip("lldb-server not found")
+	}
+	bs, _ := ioutil.ReadFile("/proc/sys/kernel/yama/ptrace_scope")
+	if bs != nil && strings.TrimSpace(string(bs)) != "0" {
+		t.Skipf("can not attach with lldb-server, ptrace_scope: %s", bs)
+	}
+	var buildFlags protest.BuildFlags
+	if buildMode == "pie" {
+		buildFlags |= protest.BuildModePIE
+	}
+	fixture := protest.BuildFixture("testnextnethttp", buildFlags)
+	cmd := exec.Command(fixture.Path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	assertNoError(cmd.Start(), t, "starting fixture")
+	defer cmd.Process.Kill()
+
+	// wait for testnextnethttp to start listening
+	t0 := time.Now()
+	for {
+		conn, err := net.Dial("tcp", "127.0.0.1:9191")
+		if err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+		if time.Since(t0) > 10*time.Second {
+			t.Fatal("fixture did not start")
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(err, t, "Listen")
+	addr := listener.Addr().String()
+	listener.Close()
+	stub := exec.Command(lldbServer, "gdbserver", "--attach", strconv.Itoa(cmd.Process.Pid), addr)
+	stub.Stdout = os.Stdout
+	stub.Stderr = os.Stderr
+	assertNoError(stub.Start(), t, "starting lldb-server")
+	defer stub.Process.Kill()
+
+	var p *proc.Target
+	t0 = time.Now()
+	for {
+		p, err = gdbserial.StubConnect(addr, fixture.Path, []string{})
+		if err == nil {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+		if time.Since(t0) > 10*time.Second {
+			t.Fatalf("could not connect to lldb-server: %v", err)
+		}
+	}
+
+	if p.Pid() != cmd.Process.Pid {
+		t.Errorf("wrong pid %d, expected %d", p.Pid(), cmd.Process.Pid)
+	}
+	go func() {
+		time.Sleep(1 * time.Second)
+		http.Get("http://127.0.0.1:9191")
+	}()
+
+	assertNoError(p.Continue(), t, "Continue")
+	assertLineNumber(p, t, 11, "Did not continue to correct location,")
+
+	assertNoError(p.Detach(false), t, "Detach")
+
+	resp, err := http.Get("http://127.0.0.1:9191/nobp")
+	assertNoError(err, t, "Page request after detach")
+	bs, err = ioutil.ReadAll(resp.Body)
+	assertNoError(err, t, "Reading /nobp page")
+	if out := string(bs); !strings.Contains(out, "hello, world!") {
+		t.Fatalf("/nobp page does not contain \"hello, world!\": %q", out)
+	}
+}
+
 func TestVarSum(t *testing.T) {
 	protest.AllowRecording(t)
 	withTestProcess("testvariables2", t, func(p *proc.Target, fixture protest.Fixture) {
@@ -4185,6 +4431,24 @@ func TestReadDeferArgs(t *testing.T) {
 	})
 }
 
+func TestNewBuiltinShadowed(t *testing.T) {
+	// A function of the target called new must be called instead of the
+	// new builtin.
+	protest.MustSupportFunctionCalls(t, testBackend)
+	withTestProcess("newshadow", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, fixture.Source, 12)
+		assertNoError(p.Continue(), t, "Continue()")
+		assertNoError(proc.EvalExpressionWithCalls(p, p.SelectedGoroutine(), "new(n)", normalLoadConfig, true), t, "EvalExpressionWithCalls")
+		retvals := p.CurrentThread().Common().ReturnValues(normalLoadConfig)
+		if len(retvals) != 1 {
+			t.Fatalf("wrong number of return values %d", len(retvals))
+		}
+		if n, _ := constant.Int64Val(retvals[0].Value); n != 42 {
+			t.Errorf("wrong return value %d (expected 42)", n)
+		}
+	})
+}
+
 func TestIssue1374(t *testing.T) {
 	// Continue did not work when stopped at a breakpoint immediately after calling CallFunction.
 	protest.MustSupportFunctionCalls(t, testBackend)
@@ -4518,6 +4782,75 @@ func TestCgoStacktrace2(t *testing.T) {
 	})
 }
 
+func TestCgoStacktraceWithoutDebugInfo(t *testing.T) {
+	// The frames of C functions in shared objects without debug symbols (the
+	// C library here) should be unwound through and symbolized using the
+	// symbol table of the shared object.
+	skipUnlessOn(t, "linux only", "linux")
+	skipOn(t, "broken", "386")
+	skipOn(t, "broken", "arm64")
+	protest.MustHaveCgo(t)
+	withTestProcess("cgoqsort", t, func(p *proc.Target, fixture protest.Fixture) {
+		p.Continue()
+		frames, err := proc.ThreadStacktrace(p.CurrentThread(), 100)
+		assertNoError(err, t, "Stacktrace()")
+		logStacktrace(t, p, frames)
+		m := stacktraceCheck(t, []string{"C.cmp", "C.sortints", "main.main"}, frames)
+		if m == nil {
+			t.Fatal("see previous loglines")
+		}
+		found := false
+		for _, frame := range frames[m[0]+1 : m[1]] {
+			name := frame.Current.Symbol
+			if frame.Current.Fn != nil {
+				// the C library has debug symbols on this system
+				name = frame.Current.Fn.Name
+			}
+			t.Logf("C library frame %#x %q", frame.Current.PC, name)
+			if strings.Contains(name, "qsort") {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatal("qsort frame not found between C.cmp and C.sortints")
+		}
+	})
+}
+
+func TestCgoCallerScopeWithoutCFI(t *testing.T) {
+	// When a goroutine is executing C code that can not be unwound the Go
+	// frames that called it should still be returned, starting from the
+	// registers saved by runtime.asmcgocall, and their variables should be
+	// accessible.
+	skipUnlessOn(t, "linux/amd64 only", "linux", "amd64")
+	protest.MustHaveCgo(t)
+	withTestProcess("cgonocfi", t, func(p *proc.Target, fixture protest.Fixture) {
+		p.Continue()
+		g, err := proc.GetG(p.CurrentThread())
+		assertNoError(err, t, "GetG")
+		frames, err := g.Stacktrace(50, 0)
+		assertNoError(err, t, "Stacktrace")
+		logStacktrace(t, p, frames)
+		frame := -1
+		for i := range frames {
+			if frames[i].Current.Fn != nil && frames[i].Current.Fn.Name == "main.gofunc" {
+				frame = i
+				break
+			}
+		}
+		if frame < 0 {
+			t.Fatal("main.gofunc not found in the stacktrace")
+		}
+		scope, err := proc.ConvertEvalScope(p, g.ID, frame, 0)
+		assertNoError(err, t, "ConvertEvalScope")
+		v, err := scope.EvalVariable("localvar", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable")
+		if n, _ := constant.Int64Val(v.Value); n != 42 {
+			t.Errorf("wrong value of localvar: %s", v.Value)
+		}
+	})
+}
+
 func TestIssue1656(t *testing.T) {
 	skipUnlessOn(t, "amd64 only", "amd64")
 	withTestProcess("issue1656/", t, func(p *proc.Target, fixture protest.Fixture) {
@@ -4554,6 +4887,38 @@ func TestBreakpointConfusionOnResume(t *testing.T) {
 	})
 }
 
+func TestAssemblyBreakpointsAndArgs(t *testing.T) {
+	// Breakpoints on lines of assembly files without instructions resolve
+	// to the next instruction and the arguments of assembly functions are
+	// read from the argument frame.
+	skipUnlessOn(t, "amd64 only", "amd64")
+	withTestProcess("issue1656/", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFileBreakpoint(p, t, filepath.ToSlash(filepath.Join(fixture.BuildDir, "main.s")), 7) // notzero:
+		assertNoError(p.Continue(), t, "Continue()")
+		assertLineNumber(p, t, 8, "wrong line number after continue")
+
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope()")
+		args, err := scope.FunctionArguments(normalLoadConfig)
+		assertNoError(err, t, "FunctionArguments()")
+		found := false
+		for _, arg := range args {
+			if arg.Name == "+0(FP)" {
+				found = true
+				if n, _ := constant.Int64Val(arg.Value); n != 1 {
+					t.Errorf("wrong value of +0(FP): %v (expected 1)", arg.Value)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("argument +0(FP) not found in %v", args)
+		}
+
+		assertNoError(p.Step(), t, "Step()")
+		assertLineNumber(p, t, 9, "wrong line number after step")
+	})
+}
+
 func TestIssue1736(t *testing.T) {
 	withTestProcess("testvariables2", t, func(p *proc.Target, fixture protest.Fixture) {
 		assertNoError(p.Continue(), t, "Continue()")
@@ -5005,7 +5370,7 @@ func TestIssue2319(t *testing.T) {
 }
 
 func TestDump(t *testing.T) {
-	if runtime.GOOS == "freebsd" || (runtime.GOOS == "darwin" && testBackend == "native") {
+	if runtime.GOOS == "freebsd" {
 		t.Skip("not supported")
 	}
 
@@ -5153,7 +5518,7 @@ func TestDump(t *testing.T) {
 		defer os.Remove(corePath)
 		testDump(p, c)
 
-		if runtime.GOOS == "linux" && runtime.GOARCH == "amd64" {
+		if runtime.GOARCH == "amd64" || runtime.GOOS == "darwin" {
 			// No reason to do this test on other goos/goarch because they use the
 			// platform-independent format anyway.
 			t.Logf("testing platform-independent dump")
@@ -5254,11 +5619,9 @@ func TestVariablesWithExternalLinking(t *testing.T) {
 }
 
 func TestWatchpointsBasic(t *testing.T) {
-	skipOn(t, "not implemented", "windows")
-	skipOn(t, "not implemented", "freebsd")
-	skipOn(t, "not implemented", "darwin")
+	skipOn(t, "not implemented", "darwin", "lldb")
 	skipOn(t, "not implemented", "386")
-	skipOn(t, "not implemented", "arm64")
+	skipOn(t, "not implemented", "linux", "arm64")
 	skipOn(t, "not implemented", "rr")
 
 	withTestProcess("databpeasy", t, func(p *proc.Target, fixture protest.Fixture) {
@@ -5300,11 +5663,9 @@ func TestWatchpointsBasic(t *testing.T) {
 }
 
 func TestWatchpointCounts(t *testing.T) {
-	skipOn(t, "not implemented", "windows")
-	skipOn(t, "not implemented", "freebsd")
-	skipOn(t, "not implemented", "darwin")
+	skipOn(t, "not implemented", "darwin", "lldb")
 	skipOn(t, "not implemented", "386")
-	skipOn(t, "not implemented", "arm64")
+	skipOn(t, "not implemented", "linux", "arm64")
 	skipOn(t, "not implemented", "rr")
 
 	protest.AllowRecording(t)
@@ -5416,3 +5777,415 @@ func TestDwrapStartLocation(t *testing.T) {
 		}
 	})
 }
+
+func TestSchedState(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("goroutinestackprog", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.stacktraceme")
+		assertNoError(p.Continue(), t, "Continue()")
+
+		st, err := proc.GetSchedState(p)
+		assertNoError(err, t, "GetSchedState")
+		if len(st.Ps) == 0 || len(st.Ms) == 0 {
+			t.Fatalf("no Ps or Ms found: %#v", st)
+		}
+
+		selg := p.SelectedGoroutine()
+		found := false
+		for _, m := range st.Ms {
+			t.Logf("%#v", m)
+			if m.CurG == selg.ID {
+				found = true
+				if m.PID < 0 {
+					t.Errorf("M %d running goroutine %d does not own a P", m.ID, selg.ID)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("could not find the M running goroutine %d", selg.ID)
+		}
+		for _, pp := range st.Ps {
+			t.Logf("%#v", pp)
+			if pp.Status == proc.Pdead {
+				t.Errorf("P %d is dead", pp.ID)
+			}
+		}
+	})
+}
+
+func TestContentionProfile(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("mutexcontention", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+
+		prof, err := proc.GetContentionProfile(p, proc.MutexProfile)
+		assertNoError(err, t, "GetContentionProfile")
+		if prof.Rate != 1 {
+			t.Errorf("wrong mutex profile rate %d", prof.Rate)
+		}
+		found := false
+		for _, rec := range prof.Records {
+			for _, pc := range rec.Stack {
+				_, _, fn := p.BinInfo().PCToLine(pc)
+				if fn != nil && fn.Name == "main.contend" {
+					found = true
+				}
+			}
+		}
+		if !found {
+			t.Errorf("main.contend not found in the mutex profile")
+		}
+
+		prof, err = proc.GetContentionProfile(p, proc.BlockProfile)
+		assertNoError(err, t, "GetContentionProfile (block)")
+		if prof.Rate != 0 || len(prof.Records) != 0 {
+			t.Errorf("block profile should be disabled: %#v", prof)
+		}
+	})
+}
+
+func TestValueProvenance(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("only supported on amd64")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("provenance", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.sink")
+		assertNoError(p.Continue(), t, "Continue()")
+
+		scope, err := proc.ConvertEvalScope(p, -1, 1, 0)
+		assertNoError(err, t, "ConvertEvalScope")
+
+		for _, tc := range []struct {
+			name   string
+			kind   proc.ProvenanceKind
+			source string
+		}{
+			{"x", proc.ProvenanceCallResult, "result of call to main.compute"},
+			{"y", proc.ProvenanceConstant, "constant 7"},
+		} {
+			r, err := scope.ValueProvenance(tc.name, normalLoadConfig)
+			assertNoError(err, t, "ValueProvenance("+tc.name+")")
+			if r.Writer == nil {
+				t.Errorf("%s: could not find writer", tc.name)
+				continue
+			}
+			t.Logf("%s: %#x %s:%d %v %q", tc.name, r.Writer.Loc.PC, r.Writer.Loc.File, r.Writer.Loc.Line, r.Kind, r.Source)
+			if r.Kind != tc.kind || r.Source != tc.source {
+				t.Errorf("%s: expected %v %q, got %v %q", tc.name, tc.kind, tc.source, r.Kind, r.Source)
+			}
+		}
+	})
+}
+
+func TestPackageVariableReferences(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("only supported on amd64")
+	}
+	protest.AllowRecording(t)
+	withTestProcess("databpeasy", t, func(p *proc.Target, fixture protest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.main")
+		assertNoError(p.Continue(), t, "Continue()")
+
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+		v, err := scope.EvalVariable("globalvar1", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable")
+
+		refs, err := proc.PackageVariableReferences(p, v)
+		assertNoError(err, t, "PackageVariableReferences")
+		var lines []int
+		for _, ref := range refs {
+			t.Logf("%#x %s:%d %v", ref.Inst.Loc.PC, ref.Inst.Loc.File, ref.Inst.Loc.Line, ref.Kind)
+			if ref.Kind == proc.VariableStore {
+				lines = append(lines, ref.Inst.Loc.Line)
+			}
+		}
+		sort.Ints(lines)
+		if !reflect.DeepEqual(lines, []int{16, 22, 32}) {
+			t.Errorf("wrong lines of the writes to globalvar1: %v", lines)
+		}
+
+		v, err = scope.EvalVariable("done", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable")
+		if _, err := proc.PackageVariableReferences(p, v); err == nil {
+			t.Error("PackageVariableReferences on a local variable: expected error")
+		}
+	})
+}
+
+func TestInlinedStacktraceNoInline(t *testing.T) {
+	if ver, _ := goversion.Parse(runtime.Version()); ver.Major >= 0 && !ver.AfterOrEqual(goversion.GoVersion{Major: 1, Minor: 10, Rev: -1}) {
+		// Versions of go before 1.10 do not have DWARF information for inlined calls
+		t.Skip("inlining not supported")
+	}
+	withTestProcessArgs("testinline", t, ".", []string{}, protest.EnableInlining, func(p *proc.Target, fixture protest.Fixture) {
+		pcs, err := p.BinInfo().LineToPC(fixture.Source, 7)
+		assertNoError(err, t, "LineToPC")
+		for _, pc := range pcs {
+			_, err := p.SetBreakpoint(pc, proc.UserBreakpoint, nil)
+			assertNoError(err, t, fmt.Sprintf("SetBreakpoint(%#x)", pc))
+		}
+		assertNoError(p.Continue(), t, "Continue")
+
+		g := p.SelectedGoroutine()
+		frames, err := g.Stacktrace(20, proc.StacktraceNoInline)
+		assertNoError(err, t, "Stacktrace")
+		if err := checkFrame(frames[0], "main.main", fixture.Source, 18, false); err != nil {
+			t.Fatalf("Wrong frame 0: %v", err)
+		}
+		for i := range frames {
+			if frames[i].Inlined {
+				t.Errorf("frame %d is inlined", i)
+			}
+		}
+
+		// the locals of the frame containing the inlined call must not
+		// include the variables of the inlined call
+		scope, err := proc.ConvertEvalScope(p, -1, 1, 0)
+		assertNoError(err, t, "ConvertEvalScope")
+		vars, err := scope.LocalVariables(normalLoadConfig)
+		assertNoError(err, t, "LocalVariables")
+		for _, v := range vars {
+			if v.Name == "z" {
+				t.Errorf("variable z of main.inlineThis found in main.main")
+			}
+		}
+	})
+}
+
+func TestDeferChain(t *testing.T) {
+	if !goversion.VersionAfterOrEqual(runtime.Version(), 1, 23) {
+		// The offsets of the variables captured by closures are only described
+		// in DWARF since Go 1.23.
+		t.Skip("closure variables not supported")
+	}
+	withTestProcess("deferchain", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+
+		defers := p.SelectedGoroutine().DeferChain()
+		tgts := []struct {
+			fn   string
+			args map[string]string
+		}{
+			{"main.work.func1", map[string]string{"count": "4"}},
+			{"main.(*closer).close", map[string]string{"c": "*main.closer {name: \"file\"}"}},
+			{"main.report", map[string]string{"n": "42", "msg": "\"cleanup\""}},
+		}
+		if len(defers) != len(tgts) {
+			t.Fatalf("wrong number of deferred calls %d (expected %d)", len(defers), len(tgts))
+		}
+		for i, tgt := range tgts {
+			_, _, fn := defers[i].DeferredFunc(p)
+			if fn == nil || fn.Name != tgt.fn {
+				t.Errorf("deferred call %d: wrong function %v (expected %s)", i, fn, tgt.fn)
+				continue
+			}
+			args, err := defers[i].Arguments(p, p.CurrentThread(), normalLoadConfig)
+			assertNoError(err, t, fmt.Sprintf("Arguments of deferred call %d", i))
+			if len(args) != len(tgt.args) {
+				t.Errorf("deferred call %d: wrong number of arguments %d (expected %d)", i, len(args), len(tgt.args))
+			}
+			for _, arg := range args {
+				if s := api.ConvertVar(arg).SinglelineString(); s != tgt.args[arg.Name] {
+					t.Errorf("deferred call %d: argument %s = %s (expected %s)", i, arg.Name, s, tgt.args[arg.Name])
+				}
+			}
+		}
+	})
+}
+
+func TestCallFunctionParkedGoroutine(t *testing.T) {
+	// Calling a function on a goroutine that is not running should resume the
+	// target until the goroutine is scheduled again.
+	protest.MustSupportFunctionCalls(t, testBackend)
+	withTestProcess("fncallparked", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+
+		gs, _, err := proc.GoroutinesInfo(p, 0, 0)
+		assertNoError(err, t, "GoroutinesInfo")
+		var g *proc.G
+		for _, g2 := range gs {
+			if loc := g2.UserCurrent(); loc.Fn != nil && loc.Fn.Name == "main.waiter" {
+				g = g2
+				break
+			}
+		}
+		if g == nil {
+			t.Fatal("could not find goroutine running main.waiter")
+		}
+		if g.Thread != nil {
+			t.Skip("goroutine running main.waiter has a thread")
+		}
+
+		assertNoError(proc.EvalExpressionWithCalls(p, g, "double(x)", normalLoadConfig, true), t, "EvalExpressionWithCalls")
+		if selg := p.SelectedGoroutine(); selg == nil || selg.ID != g.ID {
+			t.Fatalf("wrong selected goroutine %v (expected %d)", selg, g.ID)
+		}
+		retvals := p.CurrentThread().Common().ReturnValues(normalLoadConfig)
+		if len(retvals) != 1 {
+			t.Fatalf("wrong number of return values %d", len(retvals))
+		}
+		if n, _ := constant.Int64Val(retvals[0].Value); n != 10 {
+			t.Errorf("wrong return value %d (expected 10)", n)
+		}
+	})
+}
+
+func TestCallFunctionHelper(t *testing.T) {
+	// At the unrecovered-panic breakpoint the selected goroutine is stopped
+	// inside the runtime, functions can only be called on the call helper
+	// goroutine, which is reused by the following calls and never executes
+	// main.main.
+	protest.MustSupportFunctionCalls(t, testBackend)
+	withTestProcess("callhelper", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+		if bp := p.CurrentThread().Breakpoint().Breakpoint; bp == nil || bp.Name != proc.UnrecoveredPanic {
+			t.Fatalf("not stopped at the unrecovered-panic breakpoint")
+		}
+		p.SetCallHelper(true)
+
+		helper := 0
+		for _, tc := range []struct {
+			expr string
+			ret  int64
+		}{{"square(3)", 9}, {"square(4)", 16}} {
+			assertNoError(proc.EvalExpressionWithCalls(p, p.SelectedGoroutine(), tc.expr, normalLoadConfig, true), t, tc.expr)
+			retvals := p.CurrentThread().Common().ReturnValues(normalLoadConfig)
+			if len(retvals) != 1 {
+				t.Fatalf("%s: wrong number of return values %d", tc.expr, len(retvals))
+			}
+			if n, _ := constant.Int64Val(retvals[0].Value); n != tc.ret {
+				t.Errorf("%s: wrong return value %d (expected %d)", tc.expr, n, tc.ret)
+			}
+			g := p.SelectedGoroutine()
+			if g == nil || (helper != 0 && g.ID != helper) {
+				t.Fatalf("%s: call not executed on the call helper goroutine %d (%v)", tc.expr, helper, g)
+			}
+			helper = g.ID
+		}
+
+		err := p.Continue()
+		if pe, ok := err.(proc.ErrProcessExited); !ok || pe.Status != 2 {
+			t.Fatalf("expected the target to exit because of the panic, got %v", err)
+		}
+	})
+}
+
+func TestAsyncPreemptSignalsHidden(t *testing.T) {
+	// Asynchronous preemption signals should never cause Continue to stop
+	// anywhere other than the breakpoint.
+	// The fixture can not terminate if asynchronous preemption is disabled.
+	skipOn(t, "asynchronous preemption disabled", "windows")
+	skipOn(t, "asynchronous preemption disabled", "freebsd")
+	skipOn(t, "asynchronous preemption disabled", "darwin")
+	protest.AllowRecording(t)
+	withTestProcess("asyncpreempt", t, func(p *proc.Target, fixture protest.Fixture) {
+		bp := setFunctionBreakpoint(p, t, "main.done")
+		assertNoError(p.Continue(), t, "Continue()")
+		if p.StopReason != proc.StopBreakpoint {
+			t.Fatalf("wrong stop reason %v", p.StopReason)
+		}
+		if bpstate := p.CurrentThread().Breakpoint(); bpstate.Breakpoint == nil || bpstate.LogicalID != bp.LogicalID {
+			t.Fatalf("not stopped at breakpoint main.done")
+		}
+		n, ok := p.AsyncPreemptSignals()
+		t.Logf("asynchronous preemption signals hidden: %d", n)
+		if testBackend == "native" && runtime.GOOS == "linux" && (!ok || n == 0) {
+			t.Errorf("expected asynchronous preemption signals to be counted (%d %v)", n, ok)
+		}
+	})
+}
+
+func TestCallFunctionCancel(t *testing.T) {
+	// A function call that blocks can be cancelled after stopping the
+	// target, once it returns the target should continue normally.
+	protest.MustSupportFunctionCalls(t, testBackend)
+	withTestProcess("fncallblock", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+		bp := setFunctionBreakpoint(p, t, "main.done")
+
+		go func() {
+			time.Sleep(time.Second)
+			p.RequestManualStop()
+		}()
+		assertNoError(proc.EvalExpressionWithCalls(p, p.SelectedGoroutine(), "block()", normalLoadConfig, true), t, "EvalExpressionWithCalls")
+		if p.StopReason != proc.StopManual {
+			t.Fatalf("wrong stop reason %v", p.StopReason)
+		}
+		assertNoError(p.CancelCall(-1), t, "CancelCall")
+		if err := p.CancelCall(-1); err != nil {
+			t.Errorf("CancelCall on an already cancelled call: %v", err)
+		}
+
+		assertNoError(setVariable(p, "main.release", "true"), t, "SetVariable()")
+		assertNoError(p.Continue(), t, "Continue()")
+		if bpstate := p.CurrentThread().Breakpoint(); bpstate.Breakpoint == nil || bpstate.LogicalID != bp.LogicalID {
+			t.Fatalf("not stopped at breakpoint main.done (%v)", p.StopReason)
+		}
+		for _, th := range p.ThreadList() {
+			if th.Common().CallReturn {
+				t.Errorf("thread %d has the return values of a cancelled call", th.ThreadID())
+			}
+		}
+		if err := p.CancelCall(-1); err == nil {
+			t.Errorf("CancelCall succeeded without function calls in progress")
+		}
+	})
+}
+
+func TestStrictSafetyProfile(t *testing.T) {
+	withTestProcess("testvariables", t, func(p *proc.Target, fixture protest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+		p.SetSafetyProfile(proc.SafetyStrict)
+
+		a2 := evalVariable(p, t, "a2")
+		if _, ok := setVariable(p, "a2", "8").(proc.ErrSafetyProfile); !ok {
+			t.Errorf("SetVariable allowed in strict mode")
+		}
+		if _, err := p.WriteMemory(a2.Addr, []byte{1}); err == nil {
+			t.Errorf("WriteMemory allowed in strict mode")
+		}
+		if p.SupportsFunctionCalls() {
+			err := proc.EvalExpressionWithCalls(p, p.SelectedGoroutine(), "fmt.Sprint(1)", normalLoadConfig, true)
+			if _, ok := err.(proc.ErrSafetyProfile); !ok {
+				t.Errorf("function call allowed in strict mode: %v", err)
+			}
+		}
+		if v := evalVariable(p, t, "a2"); constant.Compare(v.Value, token.NEQ, constant.MakeInt64(6)) {
+			t.Errorf("a2 changed to %v", v.Value)
+		}
+		if len(p.MemoryWrites()) != 0 {
+			t.Errorf("memory writes recorded in strict mode: %#v", p.MemoryWrites())
+		}
+	})
+}
+
+func TestPseudoSource(t *testing.T) {
+	protest.AllowRecording(t)
+	withTestProcess("testnextprog", t, func(p *proc.Target, fixture protest.Fixture) {
+		fn := p.BinInfo().LookupFunc["main.testnext"]
+		lines, err := proc.PseudoSource(p.Memory(), p.Breakpoints(), p.BinInfo(), fn)
+		assertNoError(err, t, "PseudoSource")
+		if len(lines) == 0 || lines[0].Label != "L0" || lines[0].PC != fn.Entry {
+			t.Fatalf("listing does not start with a label at the entry point: %v", lines)
+		}
+		found := map[string]bool{}
+		for _, l := range lines {
+			t.Logf("%s %s:%d %s", l.Label, filepath.Base(l.File), l.Line, l.Text)
+			if l.Label == "" && l.PC >= l.End {
+				t.Errorf("empty line %#v", l)
+			}
+			for _, s := range []string{"call main.helloworld", "call main.sleepytime", "return", "if "} {
+				if strings.Contains(l.Text, s) {
+					found[s] = true
+				}
+			}
+		}
+		for _, s := range []string{"call main.helloworld", "call main.sleepytime", "return", "if "} {
+			if !found[s] {
+				t.Errorf("%q not found in listing", s)
+			}
+		}
+	})
+}
@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"debug/elf"
 	"testing"
 )
 
@@ -96,3 +97,29 @@ func TestConvertInt(t *testing.T) {
 		}
 	}
 }
+
+func TestSymbolForPC(t *testing.T) {
+	bi := &BinaryInfo{SymNames: map[uint64]*elf.Symbol{
+		0x1000: {Name: "read", Value: 0x100, Size: 0x20},
+		0x1040: {Name: "write", Value: 0x140, Size: 0},
+		0x2000: {Name: "close", Value: 0x1000, Size: 0x10},
+	}, symAddrs: []uint64{0x1000, 0x1040, 0x2000}}
+	for _, tc := range []struct {
+		pc    uint64
+		name  string
+		entry uint64
+	}{
+		{0xfff, "", 0},
+		{0x1000, "read", 0x1000},
+		{0x101f, "read", 0x1000},
+		{0x1020, "", 0},
+		{0x1050, "write", 0x1040},
+		{0x2005, "close", 0x2000},
+		{0x2010, "", 0},
+	} {
+		name, entry := bi.symbolForPC(tc.pc)
+		if name != tc.name || entry != tc.entry {
+			t.Errorf("symbolForPC(%#x) = %q %#x, expected %q %#x", tc.pc, name, entry, tc.name, tc.entry)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package proc
+
+import (
+	"debug/dwarf"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/reader"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// ProvenanceKind describes where the value of a variable came from.
+type ProvenanceKind uint8
+
+const (
+	// ProvenanceUnknown means that the instruction that wrote the variable
+	// could not be found.
+	ProvenanceUnknown ProvenanceKind = iota
+	// ProvenanceConstant means that the value is a constant encoded in the
+	// instruction.
+	ProvenanceConstant
+	// ProvenanceParameter means that the value was copied from a parameter of
+	// the function.
+	ProvenanceParameter
+	// ProvenanceVariable means that the value was copied from another
+	// variable.
+	ProvenanceVariable
+	// ProvenanceCallResult means that the value was returned by a function
+	// call.
+	ProvenanceCallResult
+	// ProvenanceRegister means that the value was copied from a register not
+	// associated with any variable.
+	ProvenanceRegister
+	// ProvenanceMemory means that the value was loaded from memory not
+	// associated with any local variable.
+	ProvenanceMemory
+	// ProvenanceComputed means that the value was computed by an arithmetic
+	// or logic instruction.
+	ProvenanceComputed
+)
+
+// ValueProvenance describes where the current value of a variable came
+// from.
+type ValueProvenance struct {
+	Variable *Variable
+	// Storage lists where the variable is stored at the current PC, either
+	// register names or memory addresses.
+	Storage []string
+	// Writer is the last instruction, executed before the current PC, that
+	// wrote the storage of the variable. It is nil if no such instruction
+	// could be found.
+	Writer *AsmInstruction
+	// InStatement is true if Writer belongs to the current statement.
+	InStatement bool
+	Kind        ProvenanceKind
+	// Source is a description of the operand the value was read from.
+	Source string
+}
+
+// maxProvenanceDepth is the maximum number of copies, through registers or
+// compiler generated temporaries, followed by ValueProvenance.
+const maxProvenanceDepth = 4
+
+// valueStorage is the set of registers and memory ranges used to store a
+// variable.
+type valueStorage struct {
+	regs map[uint64]bool
+	mem  [][2]uint64
+}
+
+func (st *valueStorage) overlaps(addr, sz uint64) bool {
+	for _, r := range st.mem {
+		if addr < r[1] && r[0] < addr+sz {
+			return true
+		}
+	}
+	return false
+}
+
+// ValueProvenance analyzes the instructions of the current function,
+// executed before the current PC, and the DWARF location of the variable
+// called name to find where its current value came from. The variable is
+// loaded using cfg.
+// Control flow is not followed: the instructions are scanned backwards in
+// address order starting from the current statement, therefore the result
+// is only a hint, especially in optimized code.
+// Only amd64 is supported.
+func (scope *EvalScope) ValueProvenance(name string, cfg LoadConfig) (*ValueProvenance, error) {
+	if scope.Fn == nil {
+		return nil, errors.New("unable to find function context")
+	}
+	if scope.BinInfo.Arch.Name != "amd64" {
+		return nil, fmt.Errorf("value provenance is not supported on %s", scope.BinInfo.Arch.Name)
+	}
+	vars, err := scope.Locals()
+	if err != nil {
+		return nil, err
+	}
+	var v *Variable
+	for _, x := range vars {
+		if x.Name == name && x.Flags&VariableShadowed == 0 {
+			v = x
+		}
+	}
+	if v == nil {
+		return nil, fmt.Errorf("could not find variable %s", name)
+	}
+
+	r := &ValueProvenance{Variable: v}
+	v.loadValue(cfg)
+	st := scope.variableStorage(v)
+	for reg := range st.regs {
+		r.Storage = append(r.Storage, regnum.AMD64ToName(reg))
+	}
+	sort.Strings(r.Storage)
+	for _, m := range st.mem {
+		r.Storage = append(r.Storage, fmt.Sprintf("%#x", m[0]))
+	}
+
+	breakpoints := NewBreakpointMap()
+	if scope.target != nil {
+		breakpoints = *scope.target.Breakpoints()
+	}
+	text, err := disassemble(scope.Mem, nil, &breakpoints, scope.BinInfo, scope.Fn.Entry, scope.PC, false)
+	if err != nil {
+		return nil, err
+	}
+
+	stmtStart := len(text)
+	for stmtStart > 0 && text[stmtStart-1].Loc.Line == scope.Line {
+		stmtStart--
+	}
+
+	i := scope.findWriter(text, len(text), st)
+	if i < 0 {
+		return r, nil
+	}
+	r.Writer = &text[i]
+	r.InStatement = i >= stmtStart
+	r.Kind, r.Source = scope.describeWrite(text, i, vars, 0)
+	return r, nil
+}
+
+// variableStorage returns the registers and memory used to store v at the
+// current PC.
+func (scope *EvalScope) variableStorage(v *Variable) *valueStorage {
+	st := &valueStorage{regs: map[uint64]bool{}}
+	if v.LocationExpr != nil && v.Flags&VariableEscaped == 0 {
+		_, pieces, _ := op.ExecuteStackProgram(scope.Regs, v.LocationExpr.instr, scope.BinInfo.Arch.PtrSize())
+		for _, piece := range pieces {
+			switch piece.Kind {
+			case op.RegPiece:
+				st.regs[piece.Val] = true
+			case op.AddrPiece:
+				st.mem = append(st.mem, [2]uint64{piece.Val, piece.Val + uint64(piece.Size)})
+			}
+		}
+	}
+	if len(st.regs) == 0 && len(st.mem) == 0 && v.Addr != 0 && v.Flags&VariableFakeAddress == 0 {
+		st.mem = append(st.mem, [2]uint64{v.Addr, v.Addr + uint64(v.RealType.Size())})
+	}
+	return st
+}
+
+// findWriter returns the index of the last instruction in text[:end] that
+// writes to st, or -1.
+func (scope *EvalScope) findWriter(text []AsmInstruction, end int, st *valueStorage) int {
+	for i := end - 1; i >= 0; i-- {
+		inst, ok := text[i].Inst.(*x86Inst)
+		if !ok || inst == nil {
+			continue
+		}
+		if text[i].IsCall() {
+			for reg := range st.regs {
+				if amd64ABIRegister(reg) {
+					return i
+				}
+			}
+			continue
+		}
+		if !x86WritesFirstArg(inst.Op) {
+			continue
+		}
+		switch arg := inst.Args[0].(type) {
+		case x86asm.Reg:
+			if reg, ok := amd64DwarfRegister(arg); ok && st.regs[reg] {
+				return i
+			}
+		case x86asm.Mem:
+			if addr, ok := scope.x86MemAddr(arg); ok && st.overlaps(addr, uint64(inst.MemBytes)) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// describeWrite describes the source of the value written by text[i].
+func (scope *EvalScope) describeWrite(text []AsmInstruction, i int, vars []*Variable, depth int) (ProvenanceKind, string) {
+	if text[i].IsCall() {
+		if text[i].DestLoc != nil && text[i].DestLoc.Fn != nil {
+			return ProvenanceCallResult, fmt.Sprintf("result of call to %s", text[i].DestLoc.Fn.Name)
+		}
+		return ProvenanceCallResult, "result of function call"
+	}
+	inst := text[i].Inst.(*x86Inst)
+	switch inst.Op {
+	case x86asm.XOR, x86asm.XORPS, x86asm.XORPD, x86asm.PXOR:
+		if inst.Args[0] == inst.Args[1] {
+			return ProvenanceConstant, "constant 0"
+		}
+	case x86asm.LEA:
+		if mem, ok := inst.Args[1].(x86asm.Mem); ok {
+			if addr, ok := scope.x86MemAddr(mem); ok {
+				if kind, name := memVariable(vars, addr); kind != ProvenanceUnknown {
+					return kind, "address of " + name
+				}
+			}
+		}
+		return ProvenanceComputed, fmt.Sprintf("address computed as %s", inst.Args[1])
+	}
+	if !x86IsMove(inst.Op) {
+		if inst.Args[1] == nil {
+			return ProvenanceComputed, fmt.Sprintf("computed by %s", inst.Op)
+		}
+		_, src := scope.describeOperand(text, i, inst.Args[1], vars, depth)
+		return ProvenanceComputed, fmt.Sprintf("computed by %s from its previous value and %s", inst.Op, src)
+	}
+	return scope.describeOperand(text, i, inst.Args[1], vars, depth)
+}
+
+// describeOperand describes operand arg of text[i].
+func (scope *EvalScope) describeOperand(text []AsmInstruction, i int, arg x86asm.Arg, vars []*Variable, depth int) (ProvenanceKind, string) {
+	switch arg := arg.(type) {
+	case x86asm.Imm:
+		return ProvenanceConstant, fmt.Sprintf("constant %d", int64(arg))
+	case x86asm.Reg:
+		reg, ok := amd64DwarfRegister(arg)
+		if !ok {
+			return ProvenanceRegister, "register " + arg.String()
+		}
+		if kind, name := scope.registerVariable(text[i].Loc.PC, reg); kind != ProvenanceUnknown {
+			return kind, name
+		}
+		if depth < maxProvenanceDepth {
+			if j := scope.findWriter(text, i, &valueStorage{regs: map[uint64]bool{reg: true}}); j >= 0 {
+				return scope.describeWrite(text, j, vars, depth+1)
+			}
+			if amd64ABIRegister(reg) {
+				return ProvenanceParameter, fmt.Sprintf("register %s at function entry", regnum.AMD64ToName(reg))
+			}
+		}
+		return ProvenanceRegister, "register " + regnum.AMD64ToName(reg)
+	case x86asm.Mem:
+		if arg.Base == x86asm.RIP {
+			addr := text[i].Loc.PC + uint64(text[i].Size) + uint64(arg.Disp)
+			if name, base := scope.BinInfo.symLookup(addr); name != "" {
+				if addr != base {
+					return ProvenanceMemory, fmt.Sprintf("global %s+%d", name, addr-base)
+				}
+				return ProvenanceMemory, "global " + name
+			}
+			return ProvenanceMemory, fmt.Sprintf("memory at %#x", addr)
+		}
+		if addr, ok := scope.x86MemAddr(arg); ok {
+			if kind, name := memVariable(vars, addr); kind != ProvenanceUnknown {
+				return kind, name
+			}
+			if depth < maxProvenanceDepth {
+				// probably a temporary created by the compiler
+				inst := text[i].Inst.(*x86Inst)
+				if j := scope.findWriter(text, i, &valueStorage{mem: [][2]uint64{{addr, addr + uint64(inst.MemBytes)}}}); j >= 0 {
+					return scope.describeWrite(text, j, vars, depth+1)
+				}
+			}
+			return ProvenanceMemory, fmt.Sprintf("memory at %#x", addr)
+		}
+		return ProvenanceMemory, "memory at " + arg.String()
+	}
+	return ProvenanceUnknown, fmt.Sprintf("%v", arg)
+}
+
+// registerVariable returns the name of the variable or parameter stored in
+// register reg at pc.
+func (scope *EvalScope) registerVariable(pc uint64, reg uint64) (ProvenanceKind, string) {
+	dwarfTree, err := scope.image().getDwarfTree(scope.Fn.offset)
+	if err != nil {
+		return ProvenanceUnknown, ""
+	}
+	for _, entry := range reader.Variables(dwarfTree, pc, math.MaxInt32, 0) {
+		instr, _, err := scope.BinInfo.locationExpr(entry.Tree, dwarf.AttrLocation, pc)
+		if err != nil {
+			continue
+		}
+		_, pieces, _ := op.ExecuteStackProgram(scope.Regs, instr, scope.BinInfo.Arch.PtrSize())
+		if len(pieces) != 1 || pieces[0].Kind != op.RegPiece || pieces[0].Val != reg {
+			continue
+		}
+		name, _ := entry.Val(dwarf.AttrName).(string)
+		if entry.Tag == dwarf.TagFormalParameter {
+			return ProvenanceParameter, "parameter " + name
+		}
+		return ProvenanceVariable, "variable " + name
+	}
+	return ProvenanceUnknown, ""
+}
+
+// memVariable returns the name of the variable in vars containing addr.
+func memVariable(vars []*Variable, addr uint64) (ProvenanceKind, string) {
+	for _, v := range vars {
+		if v.Addr == 0 || v.Flags&VariableFakeAddress != 0 || v.RealType == nil {
+			continue
+		}
+		if addr < v.Addr || addr >= v.Addr+uint64(v.RealType.Size()) {
+			continue
+		}
+		name := v.Name
+		if addr != v.Addr {
+			name = fmt.Sprintf("%s+%d", name, addr-v.Addr)
+		}
+		if v.Flags&VariableArgument != 0 {
+			return ProvenanceParameter, "parameter " + name
+		}
+		return ProvenanceVariable, "variable " + name
+	}
+	return ProvenanceUnknown, ""
+}
+
+// x86MemAddr computes the address of a memory operand relative to the
+// stack pointer or the frame pointer, which are constant during the
+// execution of a Go function after its prologue.
+func (scope *EvalScope) x86MemAddr(mem x86asm.Mem) (uint64, bool) {
+	if mem.Index != 0 || mem.Segment != 0 {
+		return 0, false
+	}
+	switch mem.Base {
+	case x86asm.RSP:
+		return uint64(int64(scope.Regs.SP()) + mem.Disp), true
+	case x86asm.RBP:
+		return uint64(int64(scope.Regs.BP()) + mem.Disp), true
+	}
+	return 0, false
+}
+
+// amd64DwarfRegister returns the DWARF register number of reg.
+func amd64DwarfRegister(reg x86asm.Reg) (uint64, bool) {
+	if reg >= x86asm.X0 && reg <= x86asm.X15 {
+		return regnum.AMD64_XMM0 + uint64(reg-x86asm.X0), true
+	}
+	r, ok := amd64AsmRegisters[int(reg)]
+	return r.dwarfNum, ok
+}
+
+// amd64ABIRegister returns true if reg is used to pass arguments and
+// results by the Go internal ABI, and is therefore clobbered by calls.
+func amd64ABIRegister(reg uint64) bool {
+	switch reg {
+	case regnum.AMD64_Rax, regnum.AMD64_Rbx, regnum.AMD64_Rcx, regnum.AMD64_Rdi, regnum.AMD64_Rsi, regnum.AMD64_R8, regnum.AMD64_R9, regnum.AMD64_R10, regnum.AMD64_R11:
+		return true
+	}
+	return reg >= regnum.AMD64_XMM0 && reg <= regnum.AMD64_XMM0+14
+}
+
+// x86WritesFirstArg returns true if instructions with opcode op write
+// their first (destination) operand.
+func x86WritesFirstArg(op x86asm.Op) bool {
+	switch op {
+	case x86asm.CMP, x86asm.TEST, x86asm.BT, x86asm.UCOMISS, x86asm.UCOMISD, x86asm.COMISS, x86asm.COMISD, x86asm.NOP, x86asm.PUSH, x86asm.PREFETCHT0, x86asm.PREFETCHNTA:
+		return false
+	}
+	return true
+}
+
+// x86IsMove returns true if instructions with opcode op copy their second
+// operand into their first operand.
+func x86IsMove(op x86asm.Op) bool {
+	switch op {
+	case x86asm.MOV, x86asm.MOVZX, x86asm.MOVSX, x86asm.MOVSXD, x86asm.MOVSD_XMM, x86asm.MOVSS, x86asm.MOVUPS, x86asm.MOVAPS, x86asm.MOVQ, x86asm.MOVD, x86asm.MOVDQU, x86asm.CVTSI2SD, x86asm.CVTSI2SS, x86asm.CVTTSD2SI, x86asm.CVTSS2SD, x86asm.CVTSD2SS:
+		return true
+	}
+	return false
+}
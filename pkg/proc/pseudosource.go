@@ -0,0 +1,326 @@
+package proc
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/arch/arm64/arm64asm"
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// PseudoSourceLine is a line of the pseudo-source listing of a function,
+// see PseudoSource.
+type PseudoSourceLine struct {
+	// Label is the name of the basic block starting at PC, if it is set the
+	// line only marks the start of the block and End is equal to PC.
+	Label string
+	// PC and End delimit the instructions rendered by this line.
+	PC, End uint64
+	// File and Line are the position, according to the debug symbols, of the
+	// instructions rendered by this line.
+	File string
+	Line int
+	// Text is the rendering of the instructions: the calls, jumps and
+	// returns they contain or, if they contain none, their number.
+	Text string
+}
+
+// pseudoBranch describes the control flow of an instruction.
+type pseudoBranch struct {
+	kind   AsmInstructionKind
+	cond   bool   // conditional jump
+	target uint64 // destination of the jump, if known
+	op     string // condition of a conditional jump, for example "==" or "NE"
+	lhs    string // operands of the comparison that sets the condition of a conditional jump, if known
+	rhs    string
+}
+
+// PseudoSource renders the instructions of fn as a structured listing that
+// can be read in place of the source code of fn, when it is not available.
+// Instructions are split in basic blocks, each block is split in lines
+// grouping the instructions that belong to the same source line, so that
+// stepping through fn moves from one line of the listing to another. Calls
+// are shown with the name of the function they call, conditional jumps with
+// the comparison that precedes them, when it can be decoded.
+func PseudoSource(mem MemoryReadWriter, breakpoints *BreakpointMap, bi *BinaryInfo, fn *Function) ([]PseudoSourceLine, error) {
+	if fn == nil || fn.Entry >= fn.End {
+		return nil, fmt.Errorf("function has no instructions")
+	}
+	insts, err := disassemble(mem, nil, breakpoints, bi, fn.Entry, fn.End, false)
+	if err != nil {
+		return nil, err
+	}
+
+	branches := make([]pseudoBranch, len(insts))
+	blockStart := map[uint64]bool{fn.Entry: true}
+	for i := range insts {
+		var prev *AsmInstruction
+		if i > 0 {
+			prev = &insts[i-1]
+		}
+		branches[i] = decodePseudoBranch(&insts[i], prev)
+		br := &branches[i]
+		if br.target >= fn.Entry && br.target < fn.End && (br.kind == JmpInstruction || br.cond) {
+			blockStart[br.target] = true
+		}
+		if br.kind == JmpInstruction || br.kind == RetInstruction || br.cond {
+			blockStart[insts[i].Loc.PC+uint64(insts[i].Size)] = true
+		}
+	}
+	labels := make(map[uint64]string)
+	for i := range insts {
+		if blockStart[insts[i].Loc.PC] {
+			labels[insts[i].Loc.PC] = fmt.Sprintf("L%d", len(labels))
+		}
+	}
+
+	var r []PseudoSourceLine
+	var cur *PseudoSourceLine
+	var parts []string
+	n := 0
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		if len(parts) > 0 {
+			cur.Text = strings.Join(parts, "; ")
+		} else if n == 1 {
+			cur.Text = "(1 instruction)"
+		} else {
+			cur.Text = fmt.Sprintf("(%d instructions)", n)
+		}
+		r = append(r, *cur)
+		cur, parts, n = nil, nil, 0
+	}
+
+	for i := range insts {
+		inst := &insts[i]
+		if label, ok := labels[inst.Loc.PC]; ok {
+			flush()
+			r = append(r, PseudoSourceLine{Label: label, PC: inst.Loc.PC, End: inst.Loc.PC, File: inst.Loc.File, Line: inst.Loc.Line})
+		}
+		if cur != nil && (cur.File != inst.Loc.File || cur.Line != inst.Loc.Line) {
+			flush()
+		}
+		if cur == nil {
+			cur = &PseudoSourceLine{PC: inst.Loc.PC, File: inst.Loc.File, Line: inst.Loc.Line}
+		}
+		cur.End = inst.Loc.PC + uint64(inst.Size)
+		n++
+		if text := pseudoBranchText(inst, &branches[i], labels, bi); text != "" {
+			parts = append(parts, text)
+		}
+	}
+	flush()
+	return r, nil
+}
+
+// pseudoBranchText renders the control flow of inst, it returns the empty
+// string for instructions that do not change it.
+func pseudoBranchText(inst *AsmInstruction, br *pseudoBranch, labels map[uint64]string, bi *BinaryInfo) string {
+	dest := func() string {
+		if label, ok := labels[br.target]; ok {
+			return label
+		}
+		if inst.DestLoc != nil && inst.DestLoc.Fn != nil {
+			return inst.DestLoc.Fn.Name
+		}
+		if br.target != 0 {
+			if name, _ := bi.symLookup(br.target); name != "" {
+				return name
+			}
+			return fmt.Sprintf("%#x", br.target)
+		}
+		return "(" + inst.Text(GoFlavour, bi) + ")"
+	}
+	switch {
+	case br.cond:
+		cond := br.op
+		if br.lhs != "" {
+			cond = fmt.Sprintf("%s %s %s", br.lhs, br.op, br.rhs)
+		}
+		return fmt.Sprintf("if %s goto %s", cond, dest())
+	case br.kind == CallInstruction:
+		return "call " + dest()
+	case br.kind == JmpInstruction:
+		if _, ok := labels[br.target]; ok {
+			return "goto " + dest()
+		}
+		return "tail call " + dest()
+	case br.kind == RetInstruction:
+		return "return"
+	case br.kind == HardBreakInstruction:
+		return "breakpoint"
+	}
+	return ""
+}
+
+// decodePseudoBranch decodes the control flow of inst, prev is the
+// instruction preceding it.
+func decodePseudoBranch(inst, prev *AsmInstruction) pseudoBranch {
+	br := pseudoBranch{kind: inst.Kind}
+	if inst.DestLoc != nil {
+		br.target = inst.DestLoc.PC
+	}
+	switch in := inst.Inst.(type) {
+	case *x86Inst:
+		if in == nil {
+			break
+		}
+		op, ok := x86CondOps[in.Op]
+		if !ok {
+			break
+		}
+		br.cond = true
+		br.op = op
+		if imm, ok := in.Args[0].(x86asm.Imm); ok {
+			br.target = uint64(imm)
+		}
+		if prev != nil {
+			br.lhs, br.op, br.rhs = x86Comparison(prev, in.Op, br.op)
+		}
+	case *arm64ArchInst:
+		if in == nil {
+			break
+		}
+		switch in.Op {
+		case arm64asm.B:
+			cond, ok := in.Args[0].(arm64asm.Cond)
+			if !ok {
+				break
+			}
+			br.kind = OtherInstruction
+			br.cond = true
+			br.op = cond.String()
+			if rel, ok := in.Args[1].(arm64asm.PCRel); ok {
+				br.target = uint64(int64(inst.Loc.PC) + int64(rel))
+			}
+			if prev != nil {
+				br.lhs, br.op, br.rhs = arm64Comparison(prev, br.op)
+			}
+		case arm64asm.CBZ, arm64asm.CBNZ:
+			br.cond = true
+			br.lhs, br.op, br.rhs = in.Args[0].String(), "==", "0"
+			if in.Op == arm64asm.CBNZ {
+				br.op = "!="
+			}
+			if rel, ok := in.Args[1].(arm64asm.PCRel); ok {
+				br.target = uint64(int64(inst.Loc.PC) + int64(rel))
+			}
+		case arm64asm.TBZ, arm64asm.TBNZ:
+			br.cond = true
+			br.lhs, br.op, br.rhs = fmt.Sprintf("%s & (1<<%s)", in.Args[0], in.Args[1]), "==", "0"
+			if in.Op == arm64asm.TBNZ {
+				br.op = "!="
+			}
+			if rel, ok := in.Args[2].(arm64asm.PCRel); ok {
+				br.target = uint64(int64(inst.Loc.PC) + int64(rel))
+			}
+		}
+	}
+	return br
+}
+
+// x86CondOps maps the conditional jumps of x86 to the comparison operator
+// they test, after a CMP instruction.
+var x86CondOps = map[x86asm.Op]string{
+	x86asm.JE:  "==",
+	x86asm.JNE: "!=",
+	x86asm.JL:  "<",
+	x86asm.JB:  "<",
+	x86asm.JLE: "<=",
+	x86asm.JBE: "<=",
+	x86asm.JG:  ">",
+	x86asm.JA:  ">",
+	x86asm.JGE: ">=",
+	x86asm.JAE: ">=",
+
+	x86asm.JO:    "overflow",
+	x86asm.JNO:   "not overflow",
+	x86asm.JS:    "negative",
+	x86asm.JNS:   "not negative",
+	x86asm.JP:    "parity",
+	x86asm.JNP:   "not parity",
+	x86asm.JCXZ:  "CX == 0",
+	x86asm.JECXZ: "ECX == 0",
+	x86asm.JRCXZ: "RCX == 0",
+}
+
+// x86Comparison returns the comparison tested by a conditional jump with
+// opcode jcc, if it is preceded by a CMP or TEST instruction.
+func x86Comparison(prev *AsmInstruction, jcc x86asm.Op, op string) (lhs, newop, rhs string) {
+	in, _ := prev.Inst.(*x86Inst)
+	if in == nil || in.Args[0] == nil || in.Args[1] == nil {
+		return "", op, ""
+	}
+	switch jcc {
+	case x86asm.JE, x86asm.JNE, x86asm.JL, x86asm.JB, x86asm.JLE, x86asm.JBE, x86asm.JG, x86asm.JA, x86asm.JGE, x86asm.JAE:
+	default:
+		return "", op, ""
+	}
+	lhs, rhs = x86ArgString(in.Args[0]), x86ArgString(in.Args[1])
+	switch in.Op {
+	case x86asm.CMP:
+		return lhs, op, rhs
+	case x86asm.TEST:
+		if jcc != x86asm.JE && jcc != x86asm.JNE {
+			break
+		}
+		if lhs == rhs {
+			return lhs, op, "0"
+		}
+		return lhs + " & " + rhs, op, "0"
+	}
+	return "", op, ""
+}
+
+func x86ArgString(arg x86asm.Arg) string {
+	switch arg := arg.(type) {
+	case x86asm.Mem:
+		var parts []string
+		if arg.Base != 0 {
+			parts = append(parts, arg.Base.String())
+		}
+		if arg.Index != 0 {
+			parts = append(parts, fmt.Sprintf("%s*%d", arg.Index, arg.Scale))
+		}
+		if arg.Disp != 0 || len(parts) == 0 {
+			parts = append(parts, fmt.Sprintf("%#x", arg.Disp))
+		}
+		s := "[" + strings.Join(parts, "+") + "]"
+		if arg.Segment != 0 {
+			s = arg.Segment.String() + ":" + s
+		}
+		return s
+	case x86asm.Imm:
+		return fmt.Sprintf("%#x", int64(arg))
+	default:
+		return arg.String()
+	}
+}
+
+// arm64CondOps maps the condition codes of arm64 to the comparison operator
+// they test, after a CMP instruction.
+var arm64CondOps = map[string]string{
+	"EQ": "==",
+	"NE": "!=",
+	"LT": "<",
+	"LO": "<",
+	"LE": "<=",
+	"LS": "<=",
+	"GT": ">",
+	"HI": ">",
+	"GE": ">=",
+	"HS": ">=",
+}
+
+// arm64Comparison returns the comparison tested by the condition code cond,
+// if the instruction preceding the conditional branch is a CMP instruction.
+func arm64Comparison(prev *AsmInstruction, cond string) (lhs, op, rhs string) {
+	in, _ := prev.Inst.(*arm64ArchInst)
+	op, ok := arm64CondOps[cond]
+	if in == nil || in.Op != arm64asm.CMP || !ok || in.Args[0] == nil || in.Args[1] == nil {
+		return "", cond, ""
+	}
+	return in.Args[0].String(), op, in.Args[1].String()
+}
@@ -0,0 +1,141 @@
+package proc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/frame"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+)
+
+// riscv64BreakInstruction is the EBREAK instruction.
+var riscv64BreakInstruction = []byte{0x73, 0x00, 0x10, 0x00}
+
+// RISCV64Arch returns an initialized RISCV64
+// struct.
+func RISCV64Arch(goos string) *Arch {
+	return &Arch{
+		Name:                             "riscv64",
+		ptrSize:                          8,
+		byteOrder:                        binary.LittleEndian,
+		maxInstructionLength:             4,
+		breakpointInstruction:            riscv64BreakInstruction,
+		breakInstrMovesPC:                false,
+		derefTLS:                         false,
+		prologues:                        nil,
+		fixFrameUnwindContext:            riscv64FixFrameUnwindContext,
+		switchStack:                      riscv64SwitchStack,
+		regSize:                          riscv64RegSize,
+		RegistersToDwarfRegisters:        riscv64RegistersToDwarfRegisters,
+		addrAndStackRegsToDwarfRegisters: riscv64AddrAndStackRegsToDwarfRegisters,
+		DwarfRegisterToString:            riscv64DwarfRegisterToString,
+		inhibitStepInto:                  func(*BinaryInfo, uint64) bool { return false },
+		asmDecode:                        riscv64AsmDecode,
+		usesLR:                           true,
+		PCRegNum:                         regnum.RISCV64_PC,
+		SPRegNum:                         regnum.RISCV64_SP,
+		BPRegNum:                         regnum.RISCV64_BP,
+		ContextRegNum:                    regnum.RISCV64_X0 + 26,
+		asmRegisters:                     map[int]asmRegister{},
+		RegisterNameToDwarf:              nameToDwarfFunc(regnum.RISCV64NameToDwarf),
+	}
+}
+
+func riscv64FixFrameUnwindContext(fctxt *frame.FrameContext, pc uint64, bi *BinaryInfo) *frame.FrameContext {
+	if fctxt == nil {
+		// The Go toolchain does not use a frame pointer on riscv64, when there
+		// is no frame descriptor entry the best we can do is assume that we are
+		// stopped at the entry point of a function:
+		// - cfa is sp
+		// - the return address is in the link register
+		return &frame.FrameContext{
+			RetAddrReg: regnum.RISCV64_PC,
+			Regs: map[uint64]frame.DWRule{
+				regnum.RISCV64_PC: frame.DWRule{
+					Rule: frame.RuleRegister,
+					Reg:  regnum.RISCV64_LR,
+				},
+				regnum.RISCV64_SP: frame.DWRule{
+					Rule:   frame.RuleValOffset,
+					Offset: 0,
+				},
+			},
+			CFA: frame.DWRule{
+				Rule:   frame.RuleCFA,
+				Reg:    regnum.RISCV64_SP,
+				Offset: 0,
+			},
+		}
+	}
+
+	if fctxt.Regs[regnum.RISCV64_LR].Rule == frame.RuleUndefined {
+		fctxt.Regs[regnum.RISCV64_LR] = frame.DWRule{
+			Rule:   frame.RuleFramePointer,
+			Reg:    regnum.RISCV64_LR,
+			Offset: 0,
+		}
+	}
+
+	return fctxt
+}
+
+func riscv64SwitchStack(it *stackIterator, _ *op.DwarfRegisters) bool {
+	if it.frame.Current.Fn == nil {
+		return false
+	}
+	switch it.frame.Current.Fn.Name {
+	case "runtime.asmcgocall", "runtime.cgocallback_gofunc", "runtime.cgocallback":
+		// cgo stacktraces are not supported on riscv64.
+		return false
+	case "runtime.goexit", "runtime.rt0_go", "runtime.mcall":
+		// Look for "top of stack" functions.
+		it.atend = true
+		return true
+	default:
+		if it.systemstack && it.top && it.g != nil && strings.HasPrefix(it.frame.Current.Fn.Name, "runtime.") && it.frame.Current.Fn.Name != "runtime.fatalthrow" {
+			// The runtime switches to the system stack in multiple places, since we
+			// are only interested in printing the system stack for cgo calls we
+			// switch directly to the goroutine stack if we detect that the
+			// function at the top of the stack is a runtime function.
+			it.switchToGoroutineStack()
+			return true
+		}
+		return false
+	}
+}
+
+func riscv64RegSize(regnum uint64) int {
+	return 8 // general purpose and floating point registers
+}
+
+func riscv64RegistersToDwarfRegisters(staticBase uint64, regs Registers) *op.DwarfRegisters {
+	dregs := initDwarfRegistersFromSlice(int(regnum.RISCV64MaxRegNum()), regs, regnum.RISCV64NameToDwarf)
+	dr := op.NewDwarfRegisters(staticBase, dregs, binary.LittleEndian, regnum.RISCV64_PC, regnum.RISCV64_SP, regnum.RISCV64_BP, regnum.RISCV64_LR)
+	dr.SetLoadMoreCallback(loadMoreDwarfRegistersFromSliceFunc(dr, regs, regnum.RISCV64NameToDwarf))
+	return dr
+}
+
+func riscv64AddrAndStackRegsToDwarfRegisters(staticBase, pc, sp, bp, lr uint64) op.DwarfRegisters {
+	dregs := make([]*op.DwarfRegister, regnum.RISCV64_PC+1)
+	dregs[regnum.RISCV64_PC] = op.DwarfRegisterFromUint64(pc)
+	dregs[regnum.RISCV64_SP] = op.DwarfRegisterFromUint64(sp)
+	dregs[regnum.RISCV64_BP] = op.DwarfRegisterFromUint64(bp)
+	dregs[regnum.RISCV64_LR] = op.DwarfRegisterFromUint64(lr)
+
+	return *op.NewDwarfRegisters(staticBase, dregs, binary.LittleEndian, regnum.RISCV64_PC, regnum.RISCV64_SP, regnum.RISCV64_BP, regnum.RISCV64_LR)
+}
+
+func riscv64DwarfRegisterToString(i int, reg *op.DwarfRegister) (name string, floatingPoint bool, repr string) {
+	name = regnum.RISCV64ToName(uint64(i))
+
+	if reg == nil {
+		return name, false, ""
+	}
+
+	if name[0] == 'F' {
+		return name, true, fmt.Sprintf("%#016x", reg.Uint64Val)
+	}
+	return name, false, fmt.Sprintf("%#016x", reg.Uint64Val)
+}
@@ -0,0 +1,95 @@
+package proc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+)
+
+// There is no riscv64 disassembler available, riscv64AsmDecode only
+// determines the length of instructions, which is 2 bytes for compressed
+// instructions and 4 bytes otherwise, and recognizes the instructions
+// emitted by the Go toolchain to call functions and return from them.
+
+const (
+	riscv64OpJAL  = 0x6f // JAL rd, offset: call PC+offset, return address in rd
+	riscv64OpJALR = 0x67 // JALR rd, offset(rs1): call rs1+offset, return address in rd
+
+	riscv64InstEBREAK = 0x00100073
+	riscv64InstRET    = 0x00008067 // JALR X0, 0(X1)
+)
+
+func riscv64AsmDecode(asmInst *AsmInstruction, mem []byte, regs *op.DwarfRegisters, memrw MemoryReadWriter, bi *BinaryInfo) error {
+	size := 4
+	if len(mem) > 0 && mem[0]&0x3 != 0x3 {
+		size = 2
+	}
+	if size > len(mem) {
+		asmInst.Size = len(mem)
+		asmInst.Bytes = mem
+		asmInst.Inst = (*riscv64ArchInst)(nil)
+		return fmt.Errorf("instruction truncated")
+	}
+
+	asmInst.Size = size
+	asmInst.Bytes = mem[:size]
+	inst := riscv64ArchInst(asmInst.Bytes)
+	asmInst.Inst = &inst
+	asmInst.Kind = OtherInstruction
+
+	if size != 4 {
+		return nil
+	}
+
+	word := binary.LittleEndian.Uint32(mem)
+	rd := (word >> 7) & 0x1f
+	switch {
+	case word == riscv64InstEBREAK:
+		asmInst.Kind = HardBreakInstruction
+	case word == riscv64InstRET:
+		asmInst.Kind = RetInstruction
+	case word&0x7f == riscv64OpJAL && rd == regnum.RISCV64_LR:
+		asmInst.Kind = CallInstruction
+		// imm[20|10:1|11|19:12]
+		off := int64(int32(word&0x80000000)>>11) | int64((word>>20)&0x7fe) | int64((word>>9)&0x800) | int64(word&0xff000)
+		asmInst.DestLoc = riscv64Location(bi, uint64(int64(asmInst.Loc.PC)+off))
+	case word&0x7f == riscv64OpJALR && rd == regnum.RISCV64_LR:
+		asmInst.Kind = CallInstruction
+		if asmInst.AtPC && regs != nil {
+			rs1 := uint64((word >> 15) & 0x1f)
+			off := int64(int32(word) >> 20)
+			asmInst.DestLoc = riscv64Location(bi, uint64(int64(regs.Uint64Val(regnum.RISCV64_X0+rs1))+off))
+		}
+	}
+
+	return nil
+}
+
+func riscv64Location(bi *BinaryInfo, pc uint64) *Location {
+	file, line, fn := bi.PCToLine(pc)
+	if fn == nil {
+		return &Location{PC: pc}
+	}
+	return &Location{PC: pc, File: file, Line: line, Fn: fn}
+}
+
+type riscv64ArchInst []byte
+
+func (inst *riscv64ArchInst) Text(flavour AssemblyFlavour, pc uint64, symLookup func(uint64) (string, uint64)) string {
+	if inst == nil {
+		return "?"
+	}
+	var buf strings.Builder
+	buf.WriteString("?")
+	for _, b := range *inst {
+		fmt.Fprintf(&buf, " %02x", b)
+	}
+	return buf.String()
+}
+
+func (inst *riscv64ArchInst) OpcodeEquals(op uint64) bool {
+	return false
+}
@@ -0,0 +1,146 @@
+package proc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/frame"
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+)
+
+// s390xBreakInstruction is the illegal instruction 0x0001, which the
+// kernel reports as a SIGTRAP with the PSW address pointing after it.
+var s390xBreakInstruction = []byte{0x00, 0x01}
+
+// S390XArch returns an initialized S390X
+// struct.
+func S390XArch(goos string) *Arch {
+	return &Arch{
+		Name:                             "s390x",
+		ptrSize:                          8,
+		byteOrder:                        binary.BigEndian,
+		maxInstructionLength:             6,
+		breakpointInstruction:            s390xBreakInstruction,
+		breakInstrMovesPC:                true,
+		derefTLS:                         false,
+		prologues:                        nil,
+		fixFrameUnwindContext:            s390xFixFrameUnwindContext,
+		switchStack:                      s390xSwitchStack,
+		regSize:                          s390xRegSize,
+		RegistersToDwarfRegisters:        s390xRegistersToDwarfRegisters,
+		addrAndStackRegsToDwarfRegisters: s390xAddrAndStackRegsToDwarfRegisters,
+		DwarfRegisterToString:            s390xDwarfRegisterToString,
+		inhibitStepInto:                  func(*BinaryInfo, uint64) bool { return false },
+		asmDecode:                        s390xAsmDecode,
+		usesLR:                           true,
+		PCRegNum:                         regnum.S390X_PC,
+		SPRegNum:                         regnum.S390X_SP,
+		BPRegNum:                         regnum.S390X_BP,
+		ContextRegNum:                    regnum.S390X_R0 + 12,
+		asmRegisters:                     map[int]asmRegister{},
+		RegisterNameToDwarf:              nameToDwarfFunc(regnum.S390XNameToDwarf),
+	}
+}
+
+func s390xFixFrameUnwindContext(fctxt *frame.FrameContext, pc uint64, bi *BinaryInfo) *frame.FrameContext {
+	if fctxt == nil {
+		// The Go toolchain does not use a frame pointer on s390x, when there is
+		// no frame descriptor entry the best we can do is assume that we are
+		// stopped at the entry point of a function:
+		// - cfa is sp
+		// - the return address is in the link register
+		return &frame.FrameContext{
+			RetAddrReg: regnum.S390X_PC,
+			Regs: map[uint64]frame.DWRule{
+				regnum.S390X_PC: frame.DWRule{
+					Rule: frame.RuleRegister,
+					Reg:  regnum.S390X_LR,
+				},
+				regnum.S390X_SP: frame.DWRule{
+					Rule:   frame.RuleValOffset,
+					Offset: 0,
+				},
+			},
+			CFA: frame.DWRule{
+				Rule:   frame.RuleCFA,
+				Reg:    regnum.S390X_SP,
+				Offset: 0,
+			},
+		}
+	}
+
+	if fctxt.Regs[regnum.S390X_LR].Rule == frame.RuleUndefined {
+		fctxt.Regs[regnum.S390X_LR] = frame.DWRule{
+			Rule:   frame.RuleFramePointer,
+			Reg:    regnum.S390X_LR,
+			Offset: 0,
+		}
+	}
+
+	return fctxt
+}
+
+func s390xSwitchStack(it *stackIterator, _ *op.DwarfRegisters) bool {
+	if it.frame.Current.Fn == nil {
+		return false
+	}
+	switch it.frame.Current.Fn.Name {
+	case "runtime.asmcgocall", "runtime.cgocallback_gofunc", "runtime.cgocallback":
+		// cgo stacktraces are not supported on s390x.
+		return false
+	case "runtime.goexit", "runtime.rt0_go", "runtime.mcall":
+		// Look for "top of stack" functions.
+		it.atend = true
+		return true
+	default:
+		if it.systemstack && it.top && it.g != nil && strings.HasPrefix(it.frame.Current.Fn.Name, "runtime.") && it.frame.Current.Fn.Name != "runtime.fatalthrow" {
+			// The runtime switches to the system stack in multiple places, since we
+			// are only interested in printing the system stack for cgo calls we
+			// switch directly to the goroutine stack if we detect that the
+			// function at the top of the stack is a runtime function.
+			it.switchToGoroutineStack()
+			return true
+		}
+		return false
+	}
+}
+
+func s390xRegSize(regnum uint64) int {
+	// access registers
+	if regnum >= 48 && regnum <= 63 {
+		return 4
+	}
+	return 8 // general, floating point and PSW registers
+}
+
+func s390xRegistersToDwarfRegisters(staticBase uint64, regs Registers) *op.DwarfRegisters {
+	dregs := initDwarfRegistersFromSlice(int(regnum.S390XMaxRegNum()), regs, regnum.S390XNameToDwarf)
+	dr := op.NewDwarfRegisters(staticBase, dregs, binary.BigEndian, regnum.S390X_PC, regnum.S390X_SP, regnum.S390X_BP, regnum.S390X_LR)
+	dr.SetLoadMoreCallback(loadMoreDwarfRegistersFromSliceFunc(dr, regs, regnum.S390XNameToDwarf))
+	return dr
+}
+
+func s390xAddrAndStackRegsToDwarfRegisters(staticBase, pc, sp, bp, lr uint64) op.DwarfRegisters {
+	dregs := make([]*op.DwarfRegister, regnum.S390X_PC+1)
+	dregs[regnum.S390X_PC] = op.DwarfRegisterFromUint64(pc)
+	dregs[regnum.S390X_SP] = op.DwarfRegisterFromUint64(sp)
+	dregs[regnum.S390X_BP] = op.DwarfRegisterFromUint64(bp)
+	dregs[regnum.S390X_LR] = op.DwarfRegisterFromUint64(lr)
+
+	return *op.NewDwarfRegisters(staticBase, dregs, binary.BigEndian, regnum.S390X_PC, regnum.S390X_SP, regnum.S390X_BP, regnum.S390X_LR)
+}
+
+func s390xDwarfRegisterToString(i int, reg *op.DwarfRegister) (name string, floatingPoint bool, repr string) {
+	name = regnum.S390XToName(uint64(i))
+
+	if reg == nil {
+		return name, false, ""
+	}
+
+	if name[0] == 'F' {
+		return name, true, fmt.Sprintf("%#016x", reg.Uint64Val)
+	}
+	return name, false, fmt.Sprintf("%#016x", reg.Uint64Val)
+}
@@ -0,0 +1,87 @@
+package proc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/op"
+	"github.com/go-delve/delve/pkg/dwarf/regnum"
+)
+
+// There is no s390x disassembler available, s390xAsmDecode only determines
+// the length of instructions, which is encoded in the two most significant
+// bits of their first byte, and recognizes the instructions emitted by the
+// Go toolchain to call functions and return from them.
+
+const (
+	s390xOpBASR  = 0x0d // BASR R1, R2: call the function at R2, return address in R1
+	s390xOpBCR   = 0x07 // BCR M1, R2: branch to R2 if M1 matches the condition code
+	s390xOpBRASL = 0xc0 // BRASL R1, RI2: call a function relative to PC, also used by other RIL instructions
+)
+
+func s390xAsmDecode(asmInst *AsmInstruction, mem []byte, regs *op.DwarfRegisters, memrw MemoryReadWriter, bi *BinaryInfo) error {
+	size := 2
+	switch mem[0] >> 6 {
+	case 1, 2:
+		size = 4
+	case 3:
+		size = 6
+	}
+	if size > len(mem) {
+		asmInst.Size = len(mem)
+		asmInst.Bytes = mem
+		asmInst.Inst = (*s390xArchInst)(nil)
+		return fmt.Errorf("instruction truncated")
+	}
+
+	asmInst.Size = size
+	asmInst.Bytes = mem[:size]
+	inst := s390xArchInst(asmInst.Bytes)
+	asmInst.Inst = &inst
+	asmInst.Kind = OtherInstruction
+
+	switch {
+	case size == 2 && mem[0] == 0x00 && mem[1] == 0x01:
+		asmInst.Kind = HardBreakInstruction
+	case size == 2 && mem[0] == s390xOpBASR:
+		asmInst.Kind = CallInstruction
+		if asmInst.AtPC && regs != nil {
+			asmInst.DestLoc = s390xLocation(bi, regs.Uint64Val(regnum.S390X_R0+uint64(mem[1]&0xf)))
+		}
+	case size == 2 && mem[0] == s390xOpBCR && mem[1] == 0xf0|regnum.S390X_LR:
+		asmInst.Kind = RetInstruction
+	case size == 6 && mem[0] == s390xOpBRASL && mem[1]&0xf == 0x5:
+		asmInst.Kind = CallInstruction
+		off := int64(int32(binary.BigEndian.Uint32(mem[2:]))) * 2
+		asmInst.DestLoc = s390xLocation(bi, uint64(int64(asmInst.Loc.PC)+off))
+	}
+
+	return nil
+}
+
+func s390xLocation(bi *BinaryInfo, pc uint64) *Location {
+	file, line, fn := bi.PCToLine(pc)
+	if fn == nil {
+		return &Location{PC: pc}
+	}
+	return &Location{PC: pc, File: file, Line: line, Fn: fn}
+}
+
+type s390xArchInst []byte
+
+func (inst *s390xArchInst) Text(flavour AssemblyFlavour, pc uint64, symLookup func(uint64) (string, uint64)) string {
+	if inst == nil {
+		return "?"
+	}
+	var buf strings.Builder
+	buf.WriteString("?")
+	for _, b := range *inst {
+		fmt.Fprintf(&buf, " %02x", b)
+	}
+	return buf.String()
+}
+
+func (inst *s390xArchInst) OpcodeEquals(op uint64) bool {
+	return false
+}
@@ -0,0 +1,49 @@
+package proc
+
+import "fmt"
+
+// SafetyProfile restricts the operations that can be performed on a
+// target, see Target.SetSafetyProfile.
+type SafetyProfile uint8
+
+const (
+	// SafetyDefault allows every operation.
+	SafetyDefault SafetyProfile = iota
+	// SafetyStrict only allows inspecting the target: function calls,
+	// writes to its memory and registers and changes to the way it handles
+	// signals (disabling asynchronous preemption) are refused with
+	// ErrSafetyProfile. Breakpoints and stepping are still allowed.
+	SafetyStrict
+)
+
+// ErrSafetyProfile is returned when an operation is refused because of the
+// safety profile of the target.
+type ErrSafetyProfile struct {
+	Profile   SafetyProfile
+	Operation string
+}
+
+func (err ErrSafetyProfile) Error() string {
+	return fmt.Sprintf("%s refused: the target is in strict mode, only read-only inspection is allowed", err.Operation)
+}
+
+// SetSafetyProfile sets the safety profile of the target, it is meant to be
+// called once, right after attaching to the target, so that every client of
+// the debugger is bound by it.
+func (t *Target) SetSafetyProfile(profile SafetyProfile) {
+	t.safety = profile
+}
+
+// SafetyProfile returns the safety profile of the target.
+func (t *Target) SafetyProfile() SafetyProfile {
+	return t.safety
+}
+
+// checkSafety returns an ErrSafetyProfile if operation, which changes the
+// state of the target, is not allowed by its safety profile.
+func (t *Target) checkSafety(operation string) error {
+	if t == nil || t.safety != SafetyStrict {
+		return nil
+	}
+	return ErrSafetyProfile{Profile: t.safety, Operation: operation}
+}
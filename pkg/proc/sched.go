@@ -0,0 +1,331 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// P status, from: src/runtime/runtime2.go
+const (
+	Pidle    uint64 = iota // 0
+	Prunning               // 1 owned by an M and running user code or the scheduler
+	Psyscall               // 2 not running user code, M is in a syscall
+	Pgcstop                // 3 halted for STW
+	Pdead                  // 4 no longer used (GOMAXPROCS shrank)
+)
+
+// SchedP describes a P (runtime.p) of the target.
+type SchedP struct {
+	ID     int
+	Status uint64
+	// MID is the ID of the M that owns this P, or -1 if the P is not
+	// associated with an M.
+	MID int
+	// Runnext is the ID of the goroutine that will run next on this P, 0 if
+	// there is none.
+	Runnext int
+	// Runq contains the IDs of the goroutines in the local run queue of this
+	// P, in scheduling order.
+	Runq        []int
+	Schedtick   uint64
+	Syscalltick uint64
+}
+
+// SchedM describes a M (runtime.m) of the target, i.e. an OS thread
+// managed by the Go runtime.
+type SchedM struct {
+	ID int
+	// ThreadID is the ID of the OS thread, as reported by runtime.m.procid.
+	ThreadID int
+	// PID is the ID of the P currently owned by this M, or -1.
+	PID int
+	// OldPID is the ID of the P that was owned by this M before it entered a
+	// system call, or -1.
+	OldPID int
+	// CurG is the ID of the goroutine running on this M, 0 if none.
+	CurG int
+	// LockedG is the ID of the goroutine locked to this M with
+	// runtime.LockOSThread, 0 if none.
+	LockedG  int
+	Spinning bool // the M is out of work and is actively looking for work
+	Blocked  bool // the M is blocked on a note
+	// InSyscall is true if CurG is executing a system call.
+	InSyscall bool
+}
+
+// SchedState is a snapshot of the state of the Go scheduler, decoded from
+// runtime.allp, runtime.allm and runtime.sched.
+type SchedState struct {
+	Ps []SchedP
+	Ms []SchedM
+	// GlobalRunq contains the IDs of the goroutines in the global run queue.
+	GlobalRunq []int
+	NMIdle     int // number of idle Ms waiting for work
+	NMSpinning int // number of spinning Ms
+	NPIdle     int // number of idle Ps
+}
+
+// maxSchedListLen bounds the length of the linked lists (allm and the
+// global run queue) followed by GetSchedState, to avoid looping forever on
+// corrupted memory.
+const maxSchedListLen = 1 << 16
+
+// GetSchedState reads the state of the Go scheduler of the target.
+func GetSchedState(t *Target) (*SchedState, error) {
+	if _, err := t.Valid(); err != nil {
+		return nil, err
+	}
+	bi := t.BinInfo()
+	mem := t.Memory()
+	scope := globalScope(bi, bi.Images[0], mem)
+
+	gtyp, err := bi.findType("runtime.g")
+	if err != nil {
+		return nil, err
+	}
+	goids := map[uint64]int{0: 0}
+	goid := func(gaddr uint64) int {
+		if id, ok := goids[gaddr]; ok {
+			return id
+		}
+		v, err := newVariable("", gaddr, gtyp, bi, mem).structMember("goid")
+		id := 0
+		if err == nil {
+			n, _ := v.asInt()
+			id = int(n)
+		}
+		goids[gaddr] = id
+		return id
+	}
+
+	r := &SchedState{}
+
+	// Ps
+	allp, err := scope.findGlobal("runtime", "allp")
+	if err != nil {
+		return nil, err
+	}
+	allpTyp, ok := resolveTypedef(allp.RealType).(*godwarf.SliceType)
+	if !ok {
+		return nil, fmt.Errorf("unexpected type for runtime.allp: %s", allp.RealType)
+	}
+	allp.loadSliceInfo(allpTyp)
+	if allp.Unreadable != nil {
+		return nil, allp.Unreadable
+	}
+	ptyp, err := bi.findType("runtime.p")
+	if err != nil {
+		return nil, err
+	}
+	pids := map[uint64]int{0: -1}
+	mOfP := make([]uint64, 0, allp.Len)
+	for i := int64(0); i < allp.Len; i++ {
+		paddr, err := readUintRaw(mem, allp.Base+uint64(i*allp.stride), int64(bi.Arch.PtrSize()), bi.Arch.ByteOrder())
+		if err != nil {
+			return nil, err
+		}
+		if paddr == 0 {
+			continue
+		}
+		fr := runtimeStructReader{v: newVariable("", paddr, ptyp, bi, mem)}
+		var sp SchedP
+		sp.ID = int(fr.int("id"))
+		sp.Status = uint64(fr.int("status"))
+		maddr := fr.ptr("m")
+		sp.Runnext = goid(fr.ptr("runnext"))
+		sp.Schedtick = uint64(fr.int("schedtick"))
+		sp.Syscalltick = uint64(fr.int("syscalltick"))
+		head, tail := uint32(fr.int("runqhead")), uint32(fr.int("runqtail"))
+		runq := fr.field("runq")
+		if fr.err != nil {
+			return nil, fmt.Errorf("could not read P %d: %v", i, fr.err)
+		}
+		runqTyp, ok := resolveTypedef(runq.RealType).(*godwarf.ArrayType)
+		if !ok || runqTyp.Count <= 0 {
+			return nil, fmt.Errorf("unexpected type for runtime.p.runq: %s", runq.RealType)
+		}
+		for j := head; j != tail && len(sp.Runq) < int(runqTyp.Count); j++ {
+			gaddr, err := readUintRaw(mem, runq.Addr+uint64(int64(j%uint32(runqTyp.Count))*runqTyp.Type.Size()), runqTyp.Type.Size(), bi.Arch.ByteOrder())
+			if err != nil {
+				return nil, err
+			}
+			sp.Runq = append(sp.Runq, goid(gaddr))
+		}
+		pids[paddr] = sp.ID
+		r.Ps = append(r.Ps, sp)
+		mOfP = append(mOfP, maddr)
+	}
+
+	// Ms
+	allm, err := scope.findGlobal("runtime", "allm")
+	if err != nil {
+		return nil, err
+	}
+	mtyp, err := bi.findType("runtime.m")
+	if err != nil {
+		return nil, err
+	}
+	mids := map[uint64]int{0: -1}
+	maddr, err := readUintRaw(mem, allm.Addr, int64(bi.Arch.PtrSize()), bi.Arch.ByteOrder())
+	if err != nil {
+		return nil, err
+	}
+	for maddr != 0 {
+		if len(r.Ms) >= maxSchedListLen {
+			return nil, errors.New("runtime.allm is too long")
+		}
+		fr := runtimeStructReader{v: newVariable("", maddr, mtyp, bi, mem)}
+		var sm SchedM
+		sm.ID = int(fr.int("id"))
+		sm.ThreadID = int(fr.int("procid"))
+		sm.PID = pidOf(pids, fr.ptr("p"))
+		sm.OldPID = pidOf(pids, fr.ptr("oldp"))
+		curg := fr.ptr("curg")
+		sm.CurG = goid(curg)
+		sm.LockedG = goid(fr.ptr("lockedg"))
+		sm.Spinning = fr.bool("spinning")
+		sm.Blocked = fr.bool("blocked")
+		next := fr.ptr("alllink")
+		if fr.err != nil {
+			return nil, fmt.Errorf("could not read M at %#x: %v", maddr, fr.err)
+		}
+		if curg != 0 {
+			gfr := runtimeStructReader{v: newVariable("", curg, gtyp, bi, mem)}
+			status := uint64(gfr.int("atomicstatus"))
+			sm.InSyscall = gfr.err == nil && status&^0x1000 == Gsyscall // clears _Gscan bit
+		}
+		mids[maddr] = sm.ID
+		r.Ms = append(r.Ms, sm)
+		maddr = next
+	}
+
+	for i := range r.Ps {
+		r.Ps[i].MID = -1
+		if id, ok := mids[mOfP[i]]; ok {
+			r.Ps[i].MID = id
+		}
+	}
+
+	// Global run queue and idle counters
+	sched, err := scope.findGlobal("runtime", "sched")
+	if err != nil {
+		return nil, err
+	}
+	fr := runtimeStructReader{v: sched}
+	r.NMIdle = int(fr.int("nmidle"))
+	r.NMSpinning = int(fr.int("nmspinning"))
+	r.NPIdle = int(fr.int("npidle"))
+	runqsize := fr.int("runqsize")
+	runq := fr.field("runq")
+	if fr.err != nil {
+		return nil, fmt.Errorf("could not read runtime.sched: %v", fr.err)
+	}
+	rqfr := runtimeStructReader{v: runq}
+	gaddr := rqfr.ptr("head")
+	if rqfr.err != nil {
+		return nil, fmt.Errorf("could not read runtime.sched.runq: %v", rqfr.err)
+	}
+	for gaddr != 0 && int64(len(r.GlobalRunq)) < runqsize && len(r.GlobalRunq) < maxSchedListLen {
+		r.GlobalRunq = append(r.GlobalRunq, goid(gaddr))
+		gfr := runtimeStructReader{v: newVariable("", gaddr, gtyp, bi, mem)}
+		gaddr = gfr.ptr("schedlink")
+		if gfr.err != nil {
+			return nil, gfr.err
+		}
+	}
+
+	return r, nil
+}
+
+func pidOf(pids map[uint64]int, paddr uint64) int {
+	if id, ok := pids[paddr]; ok {
+		return id
+	}
+	return -1
+}
+
+// runtimeStructReader reads fields of runtime structs, remembering the first
+// error encountered.
+type runtimeStructReader struct {
+	v   *Variable
+	err error
+}
+
+func (fr *runtimeStructReader) field(name string) *Variable {
+	if fr.err != nil {
+		return nil
+	}
+	fv, err := fr.v.structMember(name)
+	if err != nil {
+		fr.err = err
+		return nil
+	}
+	// Starting with Go 1.19 some fields use the types of runtime/internal/atomic.
+	if tn := fv.DwarfType.Common().Name; strings.HasPrefix(tn, "runtime/internal/atomic.") || strings.HasPrefix(tn, "internal/runtime/atomic.") {
+		if inner, err := fv.structMember("value"); err == nil {
+			fv = inner
+		}
+	}
+	return fv
+}
+
+func (fr *runtimeStructReader) int(name string) int64 {
+	fv := fr.field(name)
+	if fv == nil {
+		return 0
+	}
+	fv.loadValue(loadSingleValue)
+	if fv.Unreadable != nil {
+		fr.err = fv.Unreadable
+		return 0
+	}
+	if fv.Value == nil || fv.Value.Kind() != constant.Int {
+		fr.err = fmt.Errorf("field %s is not an integer", name)
+		return 0
+	}
+	if n, exact := constant.Int64Val(fv.Value); exact {
+		return n
+	}
+	n, _ := constant.Uint64Val(fv.Value)
+	return int64(n)
+}
+
+func (fr *runtimeStructReader) bool(name string) bool {
+	fv := fr.field(name)
+	if fv == nil {
+		return false
+	}
+	fv.loadValue(loadSingleValue)
+	if fv.Unreadable != nil {
+		fr.err = fv.Unreadable
+		return false
+	}
+	switch {
+	case fv.Value != nil && fv.Value.Kind() == constant.Bool:
+		return constant.BoolVal(fv.Value)
+	case fv.Value != nil && fv.Value.Kind() == constant.Int:
+		// atomic.Bool stores its value as an uint8
+		return constant.Sign(fv.Value) != 0
+	}
+	fr.err = fmt.Errorf("field %s is not a boolean", name)
+	return false
+}
+
+// ptr returns the value of a field containing a pointer, either as a Go
+// pointer or as a uintptr (for example runtime.guintptr).
+func (fr *runtimeStructReader) ptr(name string) uint64 {
+	fv := fr.field(name)
+	if fv == nil {
+		return 0
+	}
+	n, err := readUintRaw(fv.mem, fv.Addr, fv.RealType.Size(), fv.byteOrder())
+	if err != nil {
+		fr.err = err
+		return 0
+	}
+	return n
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"go/constant"
+	"reflect"
+	"sort"
 
 	"github.com/go-delve/delve/pkg/dwarf/frame"
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
 	"github.com/go-delve/delve/pkg/dwarf/op"
 	"github.com/go-delve/delve/pkg/dwarf/reader"
 )
@@ -48,6 +51,18 @@ type Stackframe struct {
 	Inlined bool
 	// Bottom is true if this is the bottom of the stack
 	Bottom bool
+	// Approximate is true if this frame was found by following the frame
+	// pointer of the frame below it, because its call frame information was
+	// missing or unusable. The location of approximate frames, and of the
+	// frames above them, could be wrong.
+	Approximate bool
+	// Truncated is true if this is the last frame of a stacktrace that
+	// stopped at the requested depth before reaching the bottom of the
+	// stack.
+	Truncated bool
+	// Remaining is an estimate of the number of frames below a Truncated
+	// frame, computed from the size of the stack left to unwind.
+	Remaining int
 
 	// lastpc is a memory address guaranteed to belong to the last instruction
 	// executed in this stack frame.
@@ -90,9 +105,19 @@ func (frame *Stackframe) FramePointerOffset() int64 {
 	return int64(frame.Regs.BP()) - int64(frame.stackHi)
 }
 
+// StacktraceAll can be passed as the depth of ThreadStacktrace, or used as
+// the Stacktrace field of a breakpoint, to retrieve the whole stack, up to
+// maxStacktraceAllDepth frames.
+const StacktraceAll = -1
+
+const maxStacktraceAllDepth = 1024
+
 // ThreadStacktrace returns the stack trace for thread.
 // Note the locations in the array are return addresses not call addresses.
 func ThreadStacktrace(thread Thread, depth int) ([]Stackframe, error) {
+	if depth == StacktraceAll {
+		depth = maxStacktraceAllDepth
+	}
 	g, _ := GetG(thread)
 	if g == nil {
 		regs, err := thread.Registers()
@@ -144,6 +169,12 @@ const (
 	// StacktraceG requests a stacktrace starting with the register
 	// values saved in the runtime.g structure.
 	StacktraceG
+
+	// StacktraceNoInline requests a stacktrace where inlined calls are not
+	// reported as separate frames, each physical frame is reported once with
+	// the location of the call site in the function containing the inlined
+	// calls.
+	StacktraceNoInline
 )
 
 // Stacktrace returns the stack trace for a goroutine.
@@ -193,6 +224,9 @@ type stackIterator struct {
 	g0_sched_sp_loaded bool   // g0_sched_sp was loaded from g0
 
 	opts StacktraceOptions
+
+	approximate       bool // the current frame was unwound using the frame pointer
+	callerApproximate bool // the caller of the current frame was unwound using the frame pointer
 }
 
 func newStackIterator(bi *BinaryInfo, mem MemoryReadWriter, regs op.DwarfRegisters, stackhi uint64, g *G, opts StacktraceOptions) *stackIterator {
@@ -211,9 +245,12 @@ func (it *stackIterator) Next() bool {
 
 	callFrameRegs, ret, retaddr := it.advanceRegs()
 	it.frame = it.newStackframe(ret, retaddr)
+	it.frame.Approximate = it.approximate
+	it.approximate = it.callerApproximate
 
 	if it.opts&StacktraceSimple == 0 {
 		if it.bi.Arch.switchStack(it, &callFrameRegs) {
+			it.approximate = false
 			return true
 		}
 	}
@@ -232,10 +269,11 @@ func (it *stackIterator) Next() bool {
 func (it *stackIterator) switchToGoroutineStack() {
 	it.systemstack = false
 	it.top = false
+	it.approximate = false
 	it.pc = it.g.PC
 	it.regs.Reg(it.regs.SPRegNum).Uint64Val = it.g.SP
 	it.regs.AddReg(it.regs.BPRegNum, op.DwarfRegisterFromUint64(it.g.BP))
-	if it.bi.Arch.Name == "arm64" {
+	if it.bi.Arch.usesLR {
 		it.regs.Reg(it.regs.LRRegNum).Uint64Val = it.g.LR
 	}
 }
@@ -275,6 +313,25 @@ func (it *stackIterator) newStackframe(ret, retaddr uint64) Stackframe {
 		it.regs.FrameBase = it.frameBase(fn)
 	}
 	r := Stackframe{Current: Location{PC: it.pc, File: f, Line: l, Fn: fn}, Regs: it.regs, Ret: ret, addrret: retaddr, stackHi: it.stackhi, SystemStack: it.systemstack, lastpc: it.pc}
+	if fn == nil {
+		// Frames of C functions called through cgo can still be symbolized
+		// using the symbol table of the image containing them.
+		symPC := it.pc
+		if !it.top {
+			symPC--
+		}
+		r.Current.Symbol, _ = it.bi.symbolForPC(symPC)
+		if r.Current.Symbol == "" {
+			// Code generated at run time by another runtime embedded in the
+			// process can only be resolved by the symbolizers of the target.
+			var file string
+			var line int
+			r.Current.Symbol, file, line = it.bi.symbolize(symPC)
+			if file != "" {
+				r.Current.File, r.Current.Line = file, line
+			}
+		}
+	}
 	r.Call = r.Current
 	if !it.top && r.Current.Fn != nil && it.pc != r.Current.Fn.Entry {
 		// if the return address is the entry point of the function that
@@ -303,9 +360,25 @@ func (it *stackIterator) stacktrace(depth int) ([]Stackframe, error) {
 		it.top = true
 	}
 	frames := make([]Stackframe, 0, depth+1)
-	for it.Next() {
+	cgoFallback := false
+	for {
+		if !it.Next() {
+			if !cgoFallback && it.switchToCgoCaller(frames) {
+				cgoFallback = true
+				continue
+			}
+			break
+		}
+		n := len(frames)
 		frames = it.appendInlineCalls(frames, it.Frame())
+		if it.opts&StacktraceNoInline != 0 {
+			frames = append(frames[:n], frames[len(frames)-1])
+		}
 		if len(frames) >= depth+1 {
+			if last := &frames[len(frames)-1]; !last.Bottom {
+				last.Truncated = true
+				last.Remaining = estimateRemainingFrames(frames)
+			}
 			break
 		}
 	}
@@ -318,6 +391,52 @@ func (it *stackIterator) stacktrace(depth int) ([]Stackframe, error) {
 	return frames, nil
 }
 
+// switchToCgoCaller is called when the iterator stops, either because of
+// an error or because it reached the end of the stack, without finding
+// any frame of the goroutine stack. If the goroutine is executing C code
+// called through cgo, and the C frames could not be unwound back to
+// runtime.asmcgocall (for example because the C code has no call frame
+// information), it switches the iterator to the goroutine stack, starting
+// from the registers saved in g.sched by runtime.asmcgocall, so that the
+// Go frames that called the C code are still returned.
+func (it *stackIterator) switchToCgoCaller(frames []Stackframe) bool {
+	if it.g == nil || it.g.Thread == nil || !it.g.SystemStack || it.g.Status&^0x1000 != Gsyscall {
+		return false
+	}
+	if it.opts&(StacktraceG|StacktraceSimple) != 0 || it.g.PC == 0 {
+		return false
+	}
+	for i := range frames {
+		if !frames[i].SystemStack {
+			return false
+		}
+	}
+	it.err = nil
+	it.atend = false
+	it.switchToGoroutineStack()
+	it.top = true
+	return true
+}
+
+// estimateRemainingFrames estimates the number of frames below the last
+// frame of frames, assuming that they have the same average size as the
+// frames in frames.
+func estimateRemainingFrames(frames []Stackframe) int {
+	first, last := &frames[0], &frames[len(frames)-1]
+	if first.SystemStack || last.SystemStack || last.stackHi == 0 {
+		return 1
+	}
+	used := last.Regs.CFA - int64(first.Regs.SP())
+	left := int64(last.stackHi) - last.Regs.CFA
+	if used <= 0 || left <= 0 {
+		return 1
+	}
+	if n := int(left * int64(len(frames)) / used); n > 1 {
+		return n
+	}
+	return 1
+}
+
 func (it *stackIterator) appendInlineCalls(frames []Stackframe, frame Stackframe) []Stackframe {
 	if frame.Call.Fn == nil {
 		return append(frames, frame)
@@ -354,10 +473,10 @@ func (it *stackIterator) appendInlineCalls(frames []Stackframe, frame Stackframe
 		frames = append(frames, Stackframe{
 			Current: frame.Current,
 			Call: Location{
-				frame.Call.PC,
-				frame.Call.File,
-				frame.Call.Line,
-				inlfn,
+				PC:   frame.Call.PC,
+				File: frame.Call.File,
+				Line: frame.Call.Line,
+				Fn:   inlfn,
 			},
 			Regs:        frame.Regs,
 			stackHi:     frame.stackHi,
@@ -388,16 +507,50 @@ func (it *stackIterator) appendInlineCalls(frames []Stackframe, frame Stackframe
 func (it *stackIterator) advanceRegs() (callFrameRegs op.DwarfRegisters, ret uint64, retaddr uint64) {
 	fde, err := it.bi.frameEntries.FDEForPC(it.pc)
 	var framectx *frame.FrameContext
-	if _, nofde := err.(*frame.ErrNoFDEForPC); nofde {
+	nofde := err != nil
+	if nofde {
 		framectx = it.bi.Arch.fixFrameUnwindContext(nil, it.pc, it.bi)
 	} else {
 		framectx = it.bi.Arch.fixFrameUnwindContext(fde.EstablishFrame(it.pc), it.pc, it.bi)
 	}
+	it.callerApproximate = nofde
+
+	callFrameRegs, ret, retaddr, err = it.executeFrameContext(framectx)
+
+	if !nofde && (err != nil || !it.validRetAddr(ret)) && it.canUseFramePointer() {
+		// The call frame information for it.pc is corrupt (for example it
+		// was hand-written for some assembly function and is wrong), try
+		// following the frame pointer instead of giving up.
+		cfa := it.regs.CFA
+		fpRegs, fpRet, fpRetaddr, fpErr := it.executeFrameContext(it.bi.Arch.fixFrameUnwindContext(nil, it.pc, it.bi))
+		if fpErr == nil && it.validRetAddr(fpRet) && fpRegs.SP() > it.regs.SP() {
+			callFrameRegs, ret, retaddr, err = fpRegs, fpRet, fpRetaddr, nil
+			it.callerApproximate = true
+		} else {
+			it.regs.CFA = cfa
+		}
+	}
+
+	if err != nil {
+		it.err = err
+	}
 
-	cfareg, err := it.executeFrameRegRule(0, framectx.CFA, 0)
+	if it.bi.Arch.usesLR {
+		if ret == 0 && it.regs.Reg(it.regs.LRRegNum) != nil {
+			ret = it.regs.Reg(it.regs.LRRegNum).Uint64Val
+		}
+	}
+
+	return callFrameRegs, ret, retaddr
+}
+
+// executeFrameContext executes the rules of framectx using the registers
+// of the current frame and returns the registers of the calling frame.
+// The CFA of the current frame, it.regs.CFA, is updated.
+func (it *stackIterator) executeFrameContext(framectx *frame.FrameContext) (callFrameRegs op.DwarfRegisters, ret uint64, retaddr uint64, err error) {
+	cfareg, _ := it.executeFrameRegRule(0, framectx.CFA, 0)
 	if cfareg == nil {
-		it.err = fmt.Errorf("CFA becomes undefined at PC %#x", it.pc)
-		return op.DwarfRegisters{}, 0, 0
+		return op.DwarfRegisters{}, 0, 0, fmt.Errorf("CFA becomes undefined at PC %#x", it.pc)
 	}
 	it.regs.CFA = int64(cfareg.Uint64Val)
 
@@ -415,14 +568,14 @@ func (it *stackIterator) advanceRegs() (callFrameRegs op.DwarfRegisters, ret uin
 	callFrameRegs.AddReg(callFrameRegs.SPRegNum, cfareg)
 
 	for i, regRule := range framectx.Regs {
-		reg, err := it.executeFrameRegRule(i, regRule, it.regs.CFA)
+		reg, rerr := it.executeFrameRegRule(i, regRule, it.regs.CFA)
 		callFrameRegs.AddReg(i, reg)
 		if i == framectx.RetAddrReg {
 			if reg == nil {
-				if err == nil {
-					err = fmt.Errorf("Undefined return address at %#x", it.pc)
+				if rerr == nil {
+					rerr = fmt.Errorf("Undefined return address at %#x", it.pc)
 				}
-				it.err = err
+				err = rerr
 			} else {
 				ret = reg.Uint64Val
 			}
@@ -430,13 +583,34 @@ func (it *stackIterator) advanceRegs() (callFrameRegs op.DwarfRegisters, ret uin
 		}
 	}
 
-	if it.bi.Arch.Name == "arm64" {
-		if ret == 0 && it.regs.Reg(it.regs.LRRegNum) != nil {
-			ret = it.regs.Reg(it.regs.LRRegNum).Uint64Val
-		}
+	return callFrameRegs, ret, retaddr, err
+}
+
+// canUseFramePointer returns true if frame pointer unwinding can be used
+// as a fallback for the current frame.
+func (it *stackIterator) canUseFramePointer() bool {
+	switch it.bi.Arch.Name {
+	case "amd64", "arm64":
+		// frame pointers are always enabled by the Go toolchain on these
+		// architectures.
+	default:
+		return false
 	}
+	return it.regs.Reg(it.regs.BPRegNum) != nil && it.regs.BP() != 0
+}
 
-	return callFrameRegs, ret, retaddr
+// validRetAddr returns true if ret is the address of some known code: a
+// function with debug informations or a symbol of one of the images.
+func (it *stackIterator) validRetAddr(ret uint64) bool {
+	if ret == 0 {
+		// the return address of the outermost frame is zero
+		return true
+	}
+	if it.bi.PCToFunc(ret) != nil {
+		return true
+	}
+	name, _ := it.bi.symbolForPC(ret - 1)
+	return name != ""
 }
 
 func (it *stackIterator) executeFrameRegRule(regnum uint64, rule frame.DWRule, cfa int64) (*op.DwarfRegister, error) {
@@ -495,7 +669,7 @@ func (it *stackIterator) readRegisterAt(regnum uint64, addr uint64) (*op.DwarfRe
 	if err != nil {
 		return nil, err
 	}
-	return op.DwarfRegisterFromBytes(buf), nil
+	return dwarfRegisterFromMemory(it.bi.Arch, buf), nil
 }
 
 func (it *stackIterator) loadG0SchedSP() {
@@ -525,6 +699,10 @@ type Defer struct {
 	link    *Defer // Next deferred function
 	argSz   int64
 
+	// closureAddr is the address of the funcval of the deferred function, on
+	// Go 1.17 and later the arguments of the deferred call are captured by it.
+	closureAddr uint64
+
 	variable   *Variable
 	Unreadable error
 }
@@ -580,17 +758,32 @@ func (d *Defer) load() {
 		return
 	}
 
-	fnvar := d.variable.fieldVariable("fn").maybeDereference()
-	if fnvar.Addr != 0 {
-		fnvar = fnvar.loadFieldNamed("fn")
-		if fnvar.Unreadable == nil {
-			d.DwrapPC, _ = constant.Uint64Val(fnvar.Value)
+	fnvar := d.variable.fieldVariable("fn")
+	if fnvar.Kind == reflect.Func {
+		// Go 1.18 and later: the fn field is a func() and its value is a
+		// pointer to the funcval.
+		d.closureAddr = fnvar.funcvalAddr()
+		if d.closureAddr != 0 {
+			d.DwrapPC, _ = readUintRaw(fnvar.mem, d.closureAddr, int64(fnvar.bi.Arch.PtrSize()), fnvar.byteOrder())
+		}
+	} else {
+		fnvar = fnvar.maybeDereference()
+		d.closureAddr = fnvar.Addr
+		if fnvar.Addr != 0 {
+			fnvar = fnvar.loadFieldNamed("fn")
+			if fnvar.Unreadable == nil {
+				d.DwrapPC, _ = constant.Uint64Val(fnvar.Value)
+			}
 		}
 	}
 
 	d.DeferPC, _ = constant.Uint64Val(d.variable.fieldVariable("pc").Value)
 	d.SP, _ = constant.Uint64Val(d.variable.fieldVariable("sp").Value)
-	d.argSz, _ = constant.Int64Val(d.variable.fieldVariable("siz").Value)
+	if sizvar := d.variable.fieldVariable("siz"); sizvar != nil {
+		// the siz field was removed in Go 1.17, when deferred calls stopped
+		// having arguments
+		d.argSz, _ = constant.Int64Val(sizvar.Value)
+	}
 
 	linkvar := d.variable.fieldVariable("link").maybeDereference()
 	if linkvar.Addr != 0 {
@@ -677,3 +870,128 @@ func (d *Defer) DeferredFunc(p *Target) (file string, line int, fn *Function) {
 	file, line = fn.cu.lineInfo.PCToLine(fn.Entry, fn.Entry)
 	return file, line, fn
 }
+
+// maxDeferChainLen is the maximum number of deferred calls returned by
+// (*G).DeferChain, it protects against corrupted lists containing cycles.
+const maxDeferChainLen = 1000
+
+// DeferChain returns all the deferred calls of the goroutine, starting with
+// the one that will be executed first. Unlike the Defers field of
+// Stackframe this is not limited to the frames of the stacktrace that was
+// read.
+// If a deferred call is unreadable it is the last element returned.
+func (g *G) DeferChain() []*Defer {
+	r := []*Defer{}
+	for d := g.Defer(); d != nil && len(r) < maxDeferChainLen; d = d.Next() {
+		r = append(r, d)
+		if d.Unreadable != nil {
+			break
+		}
+	}
+	return r
+}
+
+// Arguments returns the arguments that will be passed to the deferred call.
+// Before Go 1.17 the arguments are saved immediately after the defer
+// header and are read as the arguments of the deferred function.
+// On Go 1.17 and later deferred calls do not have arguments, the arguments
+// are instead captured by a closure, which either wraps the deferred
+// function or is the deferred function itself: the variables captured by
+// this closure are returned.
+// The variables captured by a defer wrapper are named after the arguments
+// of the wrapped function when they can be matched, otherwise they are
+// named argN, where N is their position in the closure.
+func (d *Defer) Arguments(t *Target, thread Thread, cfg LoadConfig) ([]*Variable, error) {
+	if d.Unreadable != nil {
+		return nil, d.Unreadable
+	}
+	if d.argSz > 0 {
+		scope, err := d.EvalScope(t, thread)
+		if err != nil {
+			return nil, err
+		}
+		return scope.FunctionArguments(cfg)
+	}
+
+	if d.closureAddr == 0 {
+		return nil, nil
+	}
+	bi := t.BinInfo()
+	fn := bi.PCToFunc(d.DwrapPC)
+	if fn == nil {
+		return nil, fmt.Errorf("could not find function at %#x", d.DwrapPC)
+	}
+	image := fn.cu.image
+	tree, err := image.getDwarfTree(fn.offset)
+	if err != nil {
+		return nil, err
+	}
+
+	type capturedVar struct {
+		v   *Variable
+		off int64
+	}
+	captured := []capturedVar{}
+	for _, entry := range tree.Children {
+		if entry.Tag != dwarf.TagVariable {
+			continue
+		}
+		off, ok := entry.Val(godwarf.AttrGoClosureOffset).(int64)
+		if !ok {
+			continue
+		}
+		name, typ, err := readVarEntry(entry, image)
+		if err != nil {
+			continue
+		}
+		v := newVariable(name, d.closureAddr+uint64(off), typ, bi, d.variable.mem)
+		if len(name) > 1 && name[0] == '&' {
+			v = v.maybeDereference()
+			v.Name = name[1:]
+			v.Flags |= VariableEscaped
+		}
+		captured = append(captured, capturedVar{v, off})
+	}
+	sort.Slice(captured, func(i, j int) bool { return captured[i].off < captured[j].off })
+
+	vars := make([]*Variable, len(captured))
+	for i := range captured {
+		vars[i] = captured[i].v
+	}
+
+	if wrapped := t.dwrapUnwrap(fn); wrapped != fn {
+		nameWrapperArguments(wrapped, vars)
+	}
+
+	cfg.MaxMapBuckets = maxMapBucketsFactor * cfg.MaxArrayValues
+	loadValues(vars, cfg)
+	return vars, nil
+}
+
+// nameWrapperArguments renames the variables captured by a defer wrapper,
+// which are compiler generated temporaries, after the arguments of the
+// wrapped function fn. This is only possible when the wrapper captured all
+// arguments of fn, arguments that are constants are not captured.
+func nameWrapperArguments(fn *Function, vars []*Variable) {
+	var params []string
+	if tree, err := fn.cu.image.getDwarfTree(fn.offset); err == nil {
+		for _, entry := range tree.Children {
+			if entry.Tag != dwarf.TagFormalParameter {
+				continue
+			}
+			if isret, _ := entry.Val(dwarf.AttrVarParam).(bool); isret {
+				continue
+			}
+			name, _ := entry.Val(dwarf.AttrName).(string)
+			params = append(params, name)
+		}
+	}
+	for i := range vars {
+		if len(params) == len(vars) && params[i] != "" {
+			vars[i].Name = params[i]
+		} else {
+			vars[i].Name = fmt.Sprintf("arg%d", i)
+		}
+		vars[i].Flags |= VariableArgument
+	}
+}
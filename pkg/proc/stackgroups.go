@@ -0,0 +1,64 @@
+package proc
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// GoroutineStackGroup is a group of goroutines with identical stacks.
+type GoroutineStackGroup struct {
+	// Stack is the stacktrace shared by all goroutines of the group.
+	Stack []Stackframe
+	// Goroutines are the goroutines of the group, the first one is the
+	// representative of the group.
+	Goroutines []*G
+}
+
+// GroupGoroutinesByStack divides gs into groups of goroutines that have
+// identical stacks, considering only the topmost depth frames, similarly
+// to how goroutines are aggregated in the tracebacks of crashed programs.
+// Groups are sorted by decreasing number of goroutines, groups of the
+// same size are sorted by the ID of their representative. Goroutines
+// keep, in each group, the order they have in gs.
+// Goroutines whose stack can not be read are grouped together.
+func GroupGoroutinesByStack(gs []*G, depth int) []GoroutineStackGroup {
+	stacks := make([][]Stackframe, len(gs))
+	for i, g := range gs {
+		stacks[i], _ = g.Stacktrace(depth, 0)
+	}
+	return groupGoroutinesByStack(gs, stacks)
+}
+
+func groupGoroutinesByStack(gs []*G, stacks [][]Stackframe) []GoroutineStackGroup {
+	groups := []GoroutineStackGroup{}
+	idx := map[string]int{}
+	for i, g := range gs {
+		key := stackKey(stacks[i])
+		j, ok := idx[key]
+		if !ok {
+			j = len(groups)
+			idx[key] = j
+			groups = append(groups, GoroutineStackGroup{Stack: stacks[i]})
+		}
+		groups[j].Goroutines = append(groups[j].Goroutines, g)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Goroutines) != len(groups[j].Goroutines) {
+			return len(groups[i].Goroutines) > len(groups[j].Goroutines)
+		}
+		return groups[i].Goroutines[0].ID < groups[j].Goroutines[0].ID
+	})
+	return groups
+}
+
+// stackKey returns a string that is the same for two stacks if and only
+// if their frames have the same PCs.
+func stackKey(frames []Stackframe) string {
+	var buf strings.Builder
+	for i := range frames {
+		buf.WriteString(strconv.FormatUint(frames[i].Current.PC, 16))
+		buf.WriteByte(' ')
+	}
+	return buf.String()
+}
@@ -0,0 +1,121 @@
+package proc
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Symbolizer resolves the addresses of code that does not belong to any
+// image of the target, typically code generated at run time by another
+// runtime embedded in the process through cgo (the JIT of the JVM or of
+// .NET, the trampolines of CPython...). Frames of this code have neither
+// debug symbols nor entries in a symbol table and would otherwise be shown
+// as anonymous frames.
+type Symbolizer interface {
+	// Symbolize returns the name of the function containing pc and, if
+	// known, its source position. An empty name means that pc is unknown to
+	// the symbolizer.
+	Symbolize(pc uint64) (name, file string, line int)
+}
+
+// AddSymbolizer adds s to the symbolizers of t. Symbolizers are tried, in
+// the order they were added, on stack frames that could not be resolved
+// using the debug information and the symbol table of the target.
+func (t *Target) AddSymbolizer(s Symbolizer) {
+	t.BinInfo().symbolizers = append(t.BinInfo().symbolizers, s)
+}
+
+// symbolize returns the location of pc as resolved by the first symbolizer
+// that knows it.
+func (bi *BinaryInfo) symbolize(pc uint64) (name, file string, line int) {
+	for _, s := range bi.symbolizers {
+		name, file, line = s.Symbolize(pc)
+		if name != "" {
+			return name, file, line
+		}
+	}
+	return "", "", 0
+}
+
+// PerfMapSymbolizer is a Symbolizer that reads the perf map file of a
+// process, /tmp/perf-<pid>.map, where runtimes that generate code write the
+// address, size and name of each generated function. CPython (3.12 and
+// later, with -X perf), .NET (with DOTNET_PerfMapEnabled=1) and the JVM
+// (with perf-map-agent) can write this file.
+// The file is read again whenever it changes.
+type PerfMapSymbolizer struct {
+	path    string
+	modTime time.Time
+	size    int64
+	entries []perfMapEntry
+}
+
+type perfMapEntry struct {
+	start, size uint64
+	name        string
+}
+
+// NewPerfMapSymbolizer returns a PerfMapSymbolizer for the process pid.
+func NewPerfMapSymbolizer(pid int) *PerfMapSymbolizer {
+	return &PerfMapSymbolizer{path: fmt.Sprintf("/tmp/perf-%d.map", pid)}
+}
+
+// Symbolize implements Symbolizer.
+func (s *PerfMapSymbolizer) Symbolize(pc uint64) (name, file string, line int) {
+	s.load()
+	// entries is sorted by start address, when a range of addresses was
+	// reused by the runtime the entry written last is the one that follows
+	// the others.
+	i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].start > pc }) - 1
+	if i < 0 || pc >= s.entries[i].start+s.entries[i].size {
+		return "", "", 0
+	}
+	return s.entries[i].name, "", 0
+}
+
+// load reads the perf map file if it changed since the last time it was read.
+func (s *PerfMapSymbolizer) load() {
+	fi, err := os.Stat(s.path)
+	if err != nil {
+		s.entries = nil
+		return
+	}
+	if fi.ModTime().Equal(s.modTime) && fi.Size() == s.size {
+		return
+	}
+	fh, err := os.Open(s.path)
+	if err != nil {
+		return
+	}
+	defer fh.Close()
+	s.entries = parsePerfMap(bufio.NewScanner(fh))
+	s.modTime, s.size = fi.ModTime(), fi.Size()
+}
+
+// parsePerfMap parses a perf map, each line of which has the format:
+//
+//	START SIZE NAME
+//
+// where START and SIZE are hexadecimal numbers. Malformed lines are ignored.
+func parsePerfMap(scan *bufio.Scanner) []perfMapEntry {
+	var entries []perfMapEntry
+	for scan.Scan() {
+		fields := strings.SplitN(strings.TrimSpace(scan.Text()), " ", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		start, err1 := strconv.ParseUint(strings.TrimPrefix(fields[0], "0x"), 16, 64)
+		size, err2 := strconv.ParseUint(strings.TrimPrefix(fields[1], "0x"), 16, 64)
+		if err1 != nil || err2 != nil || size == 0 {
+			continue
+		}
+		entries = append(entries, perfMapEntry{start: start, size: size, name: fields[2]})
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].start < entries[j].start })
+	return entries
+}
@@ -55,6 +55,9 @@ type Target struct {
 
 	// fncallForG stores a mapping of current active function calls.
 	fncallForG map[int]*callInjection
+	// callHelper is the goroutine created to host function calls, see
+	// SetCallHelper.
+	callHelper callHelper
 
 	asyncPreemptChanged bool  // runtime/debug.asyncpreemptoff was changed
 	asyncPreemptOff     int64 // cached value of runtime/debug.asyncpreemptoff
@@ -65,6 +68,20 @@ type Target struct {
 	gcache goroutineCache
 	iscgo  *bool
 
+	// gtracker remembers the goroutines listed at the previous stops.
+	gtracker goroutineTracker
+	// annotations are the names and notes assigned by the user to
+	// goroutines and addresses.
+	annotations annotations
+
+	// writeLog records the writes made by the debugger to the memory of
+	// the target.
+	writeLog writeLog
+
+	// safety restricts the operations allowed on the target, see
+	// SetSafetyProfile.
+	safety SafetyProfile
+
 	// exitStatus is the exit status of the process we are debugging.
 	// Saved here to relay to any future commands.
 	exitStatus int
@@ -74,6 +91,12 @@ type Target struct {
 	// can be given a unique address.
 	fakeMemoryRegistry    []*compositeMemory
 	fakeMemoryRegistryMap map[string]*compositeMemory
+	// customBuiltins are the functions registered with RegisterBuiltin.
+	customBuiltins map[string]CustomBuiltin
+
+	// pendingHits are the IDs of the threads that hit a breakpoint with
+	// SerializeHits set at the last stop, whose hit was not reported yet.
+	pendingHits []int
 }
 
 // ErrProcessExited indicates that the process has exited and contains both
@@ -115,6 +138,10 @@ func (sr StopReason) String() string {
 		return "call returned"
 	case StopWatchpoint:
 		return "watchpoint"
+	case StopForked:
+		return "forked"
+	case StopExeced:
+		return "exec"
 	default:
 		return ""
 	}
@@ -131,6 +158,8 @@ const (
 	StopNextFinished                   // The next/step/stepout command terminated
 	StopCallReturned                   // An injected call completed
 	StopWatchpoint                     // The target process hit one or more watchpoints
+	StopForked                         // The target process was just created by a fork of a followed process
+	StopExeced                         // The target process just replaced its executable with exec
 )
 
 // NewTargetConfig contains the configuration for a new Target object,
@@ -187,6 +216,7 @@ func NewTarget(p Process, currentThread Thread, cfg NewTargetConfig) (*Target, e
 
 	t.createUnrecoveredPanicBreakpoint()
 	t.createFatalThrowBreakpoint()
+	t.createTestFailureBreakpoint()
 
 	t.gcache.init(p.BinInfo())
 	t.fakeMemoryRegistryMap = make(map[string]*compositeMemory)
@@ -232,13 +262,15 @@ func (t *Target) Valid() (bool, error) {
 
 // SupportsFunctionCalls returns whether or not the backend supports
 // calling functions during a debug session.
-// Currently only non-recorded processes running on AMD64 support
-// function calls.
+// Currently only non-recorded processes running on architectures where
+// the runtime implements the debug call protocol (see
+// debugCallProtocolReg) support function calls.
 func (t *Target) SupportsFunctionCalls() bool {
 	if ok, _ := t.Process.Recorded(); ok {
 		return false
 	}
-	return t.Process.BinInfo().Arch.Name == "amd64"
+	_, ok := debugCallProtocolReg(t.Process.BinInfo().Arch.Name, maxDebugCallVersion)
+	return ok
 }
 
 // ClearCaches clears internal caches that should not survive a restart.
@@ -246,6 +278,7 @@ func (t *Target) SupportsFunctionCalls() bool {
 func (t *Target) ClearCaches() {
 	t.clearFakeMemory()
 	t.gcache.Clear()
+	t.gtracker.resumed()
 	for _, thread := range t.ThreadList() {
 		thread.Common().g = nil
 	}
@@ -309,6 +342,9 @@ func (p *Target) SwitchThread(tid int) error {
 // If kill is true then the process will be killed when we detach.
 func (t *Target) Detach(kill bool) error {
 	if !kill {
+		if err := t.retireCallHelper(); err != nil {
+			return err
+		}
 		if t.asyncPreemptChanged {
 			setAsyncPreemptOff(t, t.asyncPreemptOff)
 		}
@@ -329,6 +365,9 @@ func (t *Target) Detach(kill bool) error {
 // writing the value 'v' to runtime.debug.asyncpreemptoff.
 // A value of '1' means off, a value of '0' means on.
 func setAsyncPreemptOff(p *Target, v int64) {
+	if p.checkSafety("disabling asynchronous preemption") != nil {
+		return
+	}
 	if producer := p.BinInfo().Producer(); producer == "" || !goversion.ProducerAfterOrEqual(producer, 1, 14) {
 		return
 	}
@@ -384,6 +423,18 @@ func (t *Target) createFatalThrowBreakpoint() {
 	}
 }
 
+// createTestFailureBreakpoint creates a breakpoint at testing.(*common).Fail,
+// if the target is a test binary.
+func (t *Target) createTestFailureBreakpoint() {
+	failpcs, err := FindFunctionLocation(t.Process, testFailFunction, 0)
+	if err == nil {
+		bp, err := t.SetBreakpointWithID(testFailureID, failpcs[0])
+		if err == nil {
+			bp.Name = TestFailure
+		}
+	}
+}
+
 // CurrentThread returns the currently selected thread which will be used
 // for next/step/stepout and for reading variables, unless a goroutine is
 // selected.
@@ -471,7 +522,7 @@ func (t *Target) dwrapUnwrap(fn *Function) *Function {
 	if fn == nil {
 		return nil
 	}
-	if !strings.Contains(fn.Name, "·dwrap·") {
+	if !strings.Contains(fn.Name, "·dwrap·") && !strings.Contains(fn.Name, ".deferwrap") {
 		return fn
 	}
 	if unwrap := t.BinInfo().dwrapUnwrapCache[fn.Entry]; unwrap != nil {
@@ -50,6 +50,22 @@ func (dbp *Target) Continue() error {
 	if _, err := dbp.Valid(); err != nil {
 		return err
 	}
+	if dbp.GetDirection() == Forward && !dbp.Breakpoints().HasInternalBreakpoints() {
+		if th := dbp.nextPendingHit(); th != nil {
+			// Report the next hit held by a breakpoint with SerializeHits set
+			// without resuming the target.
+			dbp.StopReason = StopBreakpoint
+			return dbp.SwitchThread(th.ThreadID())
+		}
+	}
+	dbp.pendingHits = nil
+	if err := dbp.retireCallHelper(); err != nil {
+		return err
+	}
+	if err := dbp.parkCallHelperCreator(); err != nil {
+		return err
+	}
+	defer dbp.unparkCallHelperCreator()
 	for _, thread := range dbp.ThreadList() {
 		thread.Common().CallReturn = false
 		thread.Common().returnValues = nil
@@ -95,7 +111,12 @@ func (dbp *Target) Continue() error {
 
 		threads := dbp.ThreadList()
 
-		callInjectionDone, callErr := callInjectionProtocol(dbp, threads)
+		creatorStopped, err := dbp.callHelperCreatorStopped()
+		if err != nil {
+			return err
+		}
+
+		callInjectionDone, callInjectionCancelled, callErr := callInjectionProtocol(dbp, threads)
 		// callErr check delayed until after pickCurrentThread, which must always
 		// happen, otherwise the debugger could be left in an inconsistent
 		// state.
@@ -111,6 +132,24 @@ func (dbp *Target) Continue() error {
 		curthread := dbp.CurrentThread()
 		curbp := curthread.Breakpoint()
 
+		if callInjectionCancelled[curthread.ThreadID()] && curbp.Breakpoint == nil && !callInjectionDone {
+			// the thread stopped only to progress a cancelled call injection,
+			// which must not be reported to the user.
+			continue
+		}
+		if creatorStopped && curthread.ThreadID() == dbp.callHelper.creator.ThreadID() {
+			// the parked creator of the call helper goroutine returned to its
+			// breakpoint, see parkCallHelperCreator.
+			if !callInjectionDone {
+				continue
+			}
+			if err := dbp.switchToCallHelper(); err != nil {
+				return err
+			}
+			curthread = dbp.CurrentThread()
+			curbp = curthread.Breakpoint()
+		}
+
 		switch {
 		case curbp.Breakpoint == nil:
 			// runtime.Breakpoint, manual stop or debugCallV1-related stop
@@ -208,6 +247,7 @@ func (dbp *Target) Continue() error {
 			if curbp.Breakpoint.WatchType != 0 {
 				dbp.StopReason = StopWatchpoint
 			}
+			dbp.holdSerializedHits(curthread, threads)
 			return conditionErrors(threads)
 		default:
 			// not a manual stop, not on runtime.Breakpoint, not on a breakpoint, just repeat
@@ -221,6 +261,54 @@ func (dbp *Target) Continue() error {
 	}
 }
 
+// holdSerializedHits removes, from the stop reported for curthread, the
+// hits of the breakpoints with SerializeHits set by the other threads,
+// they are reported by the following calls to Continue.
+func (dbp *Target) holdSerializedHits(curthread Thread, threads []Thread) {
+	for _, th := range threads {
+		if th.ThreadID() == curthread.ThreadID() {
+			continue
+		}
+		if bp := th.Breakpoint(); bp.Active && !bp.Internal && bp.SerializeHits {
+			bp.Active = false
+			dbp.pendingHits = append(dbp.pendingHits, th.ThreadID())
+		}
+	}
+}
+
+// nextPendingHit returns the next thread whose hit was held by
+// holdSerializedHits and is still stopped at the breakpoint, the hit is
+// made active again.
+func (dbp *Target) nextPendingHit() Thread {
+	for len(dbp.pendingHits) > 0 {
+		id := dbp.pendingHits[0]
+		dbp.pendingHits = dbp.pendingHits[1:]
+		th, ok := dbp.FindThread(id)
+		if !ok {
+			continue
+		}
+		bp := th.Breakpoint()
+		if bp.Breakpoint == nil {
+			continue
+		}
+		regs, err := th.Registers()
+		if err != nil || dbp.Breakpoints().M[regs.PC()] != bp.Breakpoint {
+			// the breakpoint was cleared
+			continue
+		}
+		bp.Active = true
+		return th
+	}
+	return nil
+}
+
+// PendingHits returns the number of hits of breakpoints with SerializeHits
+// set that were held at the last stop and will be reported by the
+// following calls to Continue.
+func (dbp *Target) PendingHits() int {
+	return len(dbp.pendingHits)
+}
+
 func conditionErrors(threads []Thread) error {
 	var condErr error
 	for _, th := range threads {
@@ -1040,12 +1128,13 @@ func onNextGoroutine(thread Thread, breakpoints *BreakpointMap) (bool, error) {
 	// function or by returning from the function:
 	//   runtime.curg.goid == X && (runtime.frameoff == Y || runtime.frameoff == Z)
 	// Here we are only interested in testing the runtime.curg.goid clause.
-	w := onNextGoroutineWalker{thread: thread}
+	w := onNextGoroutineWalker{bp: bp, thread: thread}
 	ast.Walk(&w, bp.internalCond)
 	return w.ret, w.err
 }
 
 type onNextGoroutineWalker struct {
+	bp     *Breakpoint
 	thread Thread
 	ret    bool
 	err    error
@@ -1053,7 +1142,7 @@ type onNextGoroutineWalker struct {
 
 func (w *onNextGoroutineWalker) Visit(n ast.Node) ast.Visitor {
 	if binx, isbin := n.(*ast.BinaryExpr); isbin && binx.Op == token.EQL && exprToString(binx.X) == "runtime.curg.goid" {
-		w.ret, w.err = evalBreakpointCondition(w.thread, n.(ast.Expr))
+		w.ret, w.err = evalBreakpointCondition(w.bp, w.thread, n.(ast.Expr))
 		return nil
 	}
 	return w
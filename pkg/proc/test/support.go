@@ -324,6 +324,9 @@ func MustSupportFunctionCalls(t *testing.T, testBackend string) {
 	if runtime.GOARCH == "arm64" || runtime.GOARCH == "386" {
 		t.Skip(fmt.Errorf("%s does not support FunctionCall for now", runtime.GOARCH))
 	}
+	if runtime.GOARCH == "loong64" && !goversion.VersionAfterOrEqual(runtime.Version(), 1, 23) {
+		t.Skip("this version of Go does not support function calls on loong64")
+	}
 }
 
 // DefaultTestBackend changes the value of testBackend to be the default
@@ -0,0 +1,169 @@
+package proc
+
+import (
+	"bytes"
+	"fmt"
+	"go/constant"
+	"strings"
+)
+
+// testFailFunction is the function that marks a test as failed, it is
+// called by t.Fail, t.Error, t.Fatal and their variants.
+const testFailFunction = "testing.(*common).Fail"
+
+// maxTestFailureOutput is the maximum number of bytes of the output of a
+// test read to find the message of a failure.
+const maxTestFailureOutput = 4096
+
+// TestFailureInfo describes the failure of a test.
+type TestFailureInfo struct {
+	// Test is the full name of the test that failed, subtests are named
+	// like "TestParent/subtest".
+	Test string
+	// Message is the message logged by the call to t.Error or t.Fatal
+	// (or their variants) that failed the test, prefixed by its location.
+	// It is empty if the test was failed by calling t.Fail or t.FailNow
+	// directly or if the output of the test is not buffered, which is the
+	// case when the test binary runs with -test.v.
+	Message string
+}
+
+// isParentTestFailure returns true if thread is stopped at
+// testing.(*common).Fail called by testing.(*common).Fail: when a subtest
+// fails its parent tests are also failed, only the failure of the subtest
+// is reported.
+func isParentTestFailure(thread Thread) bool {
+	frames, err := ThreadStacktrace(thread, 1)
+	if err != nil || len(frames) < 2 {
+		return false
+	}
+	fn := frames[1].Current.Fn
+	return fn != nil && fn.Name == testFailFunction
+}
+
+// GetTestFailure returns the test failed by the goroutine running on
+// thread, which must be stopped at the TestFailure breakpoint.
+func GetTestFailure(t *Target, thread Thread) (*TestFailureInfo, error) {
+	scope, err := ThreadScope(t, thread)
+	if err != nil {
+		return nil, err
+	}
+	if scope.Fn == nil || scope.Fn.Name != testFailFunction {
+		return nil, fmt.Errorf("thread %d is not stopped at %s", thread.ThreadID(), testFailFunction)
+	}
+	name, err := scope.EvalExpression("c.name", loadFullValue)
+	if err != nil {
+		return nil, err
+	}
+	if name.Unreadable != nil {
+		return nil, name.Unreadable
+	}
+	r := &TestFailureInfo{Test: constant.StringVal(name.Value)}
+	if isTestLogFailure(thread) {
+		// The message of the failure is the last entry of the output of
+		// the test.
+		output, err := testOutputTail(scope)
+		if err != nil {
+			return nil, err
+		}
+		r.Message = lastTestLogEntry(output)
+	}
+	return r, nil
+}
+
+// isTestLogFailure returns true if testing.(*common).Fail, at the top of
+// the stack of thread, was called by t.Error, t.Fatal or their variants,
+// which log the message of the failure before failing the test.
+func isTestLogFailure(thread Thread) bool {
+	frames, err := ThreadStacktrace(thread, 2)
+	if err != nil || len(frames) < 2 {
+		return false
+	}
+	for _, frame := range frames[1:] {
+		if frame.Current.Fn == nil {
+			return false
+		}
+		name := frame.Current.Fn.Name
+		if !strings.HasPrefix(name, "testing.") {
+			return false
+		}
+		switch name[strings.LastIndex(name, ".")+1:] {
+		case "Error", "Errorf", "Fatal", "Fatalf":
+			return true
+		case "FailNow":
+			// t.Fatal calls t.FailNow
+			continue
+		}
+		return false
+	}
+	return false
+}
+
+// testOutputTail reads the last maxTestFailureOutput bytes of the output
+// buffered by the test of scope, the receiver of testing.(*common).Fail.
+func testOutputTail(scope *EvalScope) ([]byte, error) {
+	n, err := scope.EvalExpression("len(c.output)", loadSingleValue)
+	if err != nil {
+		return nil, err
+	}
+	start, _ := constant.Int64Val(n.Value)
+	if start -= maxTestFailureOutput; start < 0 {
+		start = 0
+	}
+	cfg := loadFullValue
+	cfg.MaxArrayValues = maxTestFailureOutput
+	v, err := scope.EvalExpression(fmt.Sprintf("c.output[%d:]", start), cfg)
+	if err != nil {
+		return nil, err
+	}
+	if v.Unreadable != nil {
+		return nil, v.Unreadable
+	}
+	buf := make([]byte, 0, len(v.Children))
+	for i := range v.Children {
+		b, _ := constant.Int64Val(v.Children[i].Value)
+		buf = append(buf, byte(b))
+	}
+	return buf, nil
+}
+
+// lastTestLogEntry returns the last entry logged in output, the output
+// buffered by a test. Each entry starts with a line indented by four
+// spaces (a tab in older versions of Go), its other lines are indented
+// further. The indentation of the lines is removed.
+func lastTestLogEntry(output []byte) string {
+	// Remove the markers used by test2json.
+	output = bytes.Map(func(r rune) rune {
+		if r < ' ' && r != '\n' && r != '\t' {
+			return -1
+		}
+		return r
+	}, output)
+	lines := strings.Split(strings.TrimRight(string(output), "\n"), "\n")
+	start := -1
+	for i, line := range lines {
+		if isTestLogEntryStart(line) {
+			start = i
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	lines = lines[start:]
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isTestLogEntryStart(line string) bool {
+	switch {
+	case strings.HasPrefix(line, "    "):
+		line = line[4:]
+	case strings.HasPrefix(line, "\t"):
+		line = line[1:]
+	default:
+		return false
+	}
+	return line != "" && line[0] != ' ' && line[0] != '\t'
+}
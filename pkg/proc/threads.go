@@ -47,6 +47,10 @@ type Location struct {
 	File string
 	Line int
 	Fn   *Function
+	// Symbol is the name of the ELF symbol containing PC, it is only set
+	// when Fn is nil and PC belongs to a function without debug symbols (for
+	// example a C function in a shared library).
+	Symbol string
 }
 
 // CommonThread contains fields used by this package, common to all
@@ -98,3 +102,11 @@ func setSP(thread Thread, newSP uint64) error {
 func setClosureReg(thread Thread, newClosureReg uint64) error {
 	return thread.SetReg(thread.BinInfo().Arch.ContextRegNum, op.DwarfRegisterFromUint64(newClosureReg))
 }
+
+func setLR(thread Thread, newLR uint64) error {
+	regs, err := thread.Registers()
+	if err != nil {
+		return err
+	}
+	return thread.SetReg(thread.BinInfo().Arch.RegistersToDwarfRegisters(0, regs).LRRegNum, op.DwarfRegisterFromUint64(newLR))
+}
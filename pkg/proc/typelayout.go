@@ -0,0 +1,101 @@
+package proc
+
+import (
+	"go/parser"
+	"reflect"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+)
+
+// TypeLayout describes how the values of a type are laid out in memory.
+type TypeLayout struct {
+	Name  string
+	Kind  reflect.Kind
+	Size  int64
+	Align int64
+	// Elem is the type of the elements of arrays, slices and channels, the
+	// type pointed to by pointers and the type of the values of maps.
+	Elem string
+	// Key is the type of the keys of maps.
+	Key string
+	// Len is the length of arrays.
+	Len int64
+	// Fields are the fields of structs, and of the structs used by the Go
+	// runtime to represent strings, slices and interfaces.
+	Fields []FieldLayout
+}
+
+// FieldLayout describes a field of a struct.
+type FieldLayout struct {
+	Name     string
+	Type     string
+	Offset   int64
+	Size     int64
+	Embedded bool
+}
+
+// TypeLayout returns the layout of the type with the given name, which is
+// either a type expression, like the ones used in type conversions, or
+// one of the names returned by Types.
+func (bi *BinaryInfo) TypeLayout(name string) (*TypeLayout, error) {
+	var typ godwarf.Type
+	expr, err := parser.ParseExpr(name)
+	if err == nil {
+		typ, err = bi.findTypeExpr(expr)
+	}
+	if err != nil {
+		typ, err = bi.findType(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	r := &TypeLayout{Name: typeName(typ), Size: typ.Size(), Align: typ.Align()}
+	rtyp := resolveTypedef(typ)
+	r.Kind = rtyp.Common().ReflectKind
+	var st *godwarf.StructType
+	switch t := rtyp.(type) {
+	case *godwarf.StructType:
+		st = t
+	case *godwarf.StringType:
+		r.Kind = reflect.String
+		st = &t.StructType
+	case *godwarf.SliceType:
+		r.Kind = reflect.Slice
+		r.Elem = typeName(t.ElemType)
+		st = &t.StructType
+	case *godwarf.InterfaceType:
+		st, _ = resolveTypedef(&t.TypedefType).(*godwarf.StructType)
+	case *godwarf.ArrayType:
+		r.Elem = typeName(t.Type)
+		r.Len = t.Count
+	case *godwarf.PtrType:
+		r.Elem = typeName(t.Type)
+	case *godwarf.ChanType:
+		r.Elem = typeName(t.ElemType)
+	case *godwarf.MapType:
+		r.Key = typeName(t.KeyType)
+		r.Elem = typeName(t.ElemType)
+	}
+	if st != nil {
+		r.Fields = make([]FieldLayout, len(st.Field))
+		for i, f := range st.Field {
+			r.Fields[i] = FieldLayout{
+				Name:     f.Name,
+				Type:     typeName(f.Type),
+				Offset:   f.ByteOffset,
+				Size:     f.Type.Size(),
+				Embedded: f.Embedded,
+			}
+		}
+	}
+	return r, nil
+}
+
+// typeName returns the name of typ, or its description for unnamed types.
+func typeName(typ godwarf.Type) string {
+	if name := typ.Common().Name; name != "" {
+		return name
+	}
+	return typ.String()
+}
@@ -349,9 +349,11 @@ func GoroutinesInfo(dbp *Target, start, count int) ([]*G, int, error) {
 		}
 		if g.Status != Gdead {
 			allg = append(allg, g)
+			dbp.gtracker.seen(g.ID)
 		}
 		dbp.gcache.addGoroutine(g)
 	}
+	dbp.gtracker.complete()
 	if start == 0 {
 		dbp.gcache.allGCache = allg
 	}
@@ -433,7 +435,7 @@ func getGVariable(thread Thread) (*Variable, error) {
 	gaddr, hasgaddr := regs.GAddr()
 	if !hasgaddr {
 		var err error
-		gaddr, err = readUintRaw(thread.ProcessMemory(), regs.TLS()+thread.BinInfo().GStructOffset(), int64(thread.BinInfo().Arch.PtrSize()))
+		gaddr, err = readUintRaw(thread.ProcessMemory(), regs.TLS()+thread.BinInfo().GStructOffset(), int64(thread.BinInfo().Arch.PtrSize()), thread.BinInfo().Arch.ByteOrder())
 		if err != nil {
 			return nil, err
 		}
@@ -582,7 +584,7 @@ func (err *IsNilErr) Error() string {
 }
 
 func globalScope(bi *BinaryInfo, image *Image, mem MemoryReadWriter) *EvalScope {
-	return &EvalScope{Location: Location{}, Regs: op.DwarfRegisters{StaticBase: image.StaticBase}, Mem: mem, g: nil, BinInfo: bi, frameOffset: 0}
+	return &EvalScope{Location: Location{}, Regs: op.DwarfRegisters{StaticBase: image.StaticBase, ByteOrder: bi.Arch.ByteOrder()}, Mem: mem, g: nil, BinInfo: bi, frameOffset: 0}
 }
 
 func newVariableFromThread(t Thread, name string, addr uint64, dwarfType godwarf.Type) *Variable {
@@ -643,7 +645,7 @@ func newVariable(name string, addr uint64, dwarfType godwarf.Type, bi *BinaryInf
 				v.Kind = reflect.String
 			}
 			if v.Addr != 0 {
-				v.Base, v.Unreadable = readUintRaw(v.mem, v.Addr, int64(v.bi.Arch.PtrSize()))
+				v.Base, v.Unreadable = readUintRaw(v.mem, v.Addr, int64(v.bi.Arch.PtrSize()), v.byteOrder())
 			}
 		}
 	case *godwarf.ChanType:
@@ -827,7 +829,7 @@ func (v *Variable) parseG() (*G, error) {
 
 	if deref {
 		var err error
-		gaddr, err = readUintRaw(mem, gaddr, int64(v.bi.Arch.PtrSize()))
+		gaddr, err = readUintRaw(mem, gaddr, int64(v.bi.Arch.PtrSize()), v.byteOrder())
 		if err != nil {
 			return nil, fmt.Errorf("error derefing *G %s", err)
 		}
@@ -1181,7 +1183,7 @@ func (v *Variable) maybeDereference() *Variable {
 			// fake pointer variable constructed by casting an integer to a pointer type
 			return &v.Children[0]
 		}
-		ptrval, err := readUintRaw(v.mem, v.Addr, t.ByteSize)
+		ptrval, err := readUintRaw(v.mem, v.Addr, t.ByteSize, v.byteOrder())
 		r := v.newVariable("", ptrval, t.Type, DereferenceMemory(v.mem))
 		if err != nil {
 			r.Unreadable = err
@@ -1300,14 +1302,14 @@ func (v *Variable) loadValueInternal(recurseLevel int, cfg LoadConfig) {
 		v.readComplex(v.RealType.(*godwarf.ComplexType).ByteSize)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
 		var val int64
-		val, v.Unreadable = readIntRaw(v.mem, v.Addr, v.RealType.(*godwarf.IntType).ByteSize)
+		val, v.Unreadable = readIntRaw(v.mem, v.Addr, v.RealType.(*godwarf.IntType).ByteSize, v.byteOrder())
 		v.Value = constant.MakeInt64(val)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
 		if v.Flags&VariableCPURegister != 0 {
 			v.Value = constant.MakeUint64(v.reg.Uint64Val)
 		} else {
 			var val uint64
-			val, v.Unreadable = readUintRaw(v.mem, v.Addr, v.RealType.(*godwarf.UintType).ByteSize)
+			val, v.Unreadable = readUintRaw(v.mem, v.Addr, v.RealType.(*godwarf.UintType).ByteSize, v.byteOrder())
 			v.Value = constant.MakeUint64(val)
 		}
 	case reflect.Bool:
@@ -1372,7 +1374,7 @@ func readStringInfo(mem MemoryReadWriter, arch *Arch, addr uint64) (uint64, int6
 	mem = cacheMemory(mem, addr, arch.PtrSize()*2)
 
 	// read len
-	strlen, err := readIntRaw(mem, addr+uint64(arch.PtrSize()), int64(arch.PtrSize()))
+	strlen, err := readIntRaw(mem, addr+uint64(arch.PtrSize()), int64(arch.PtrSize()), arch.ByteOrder())
 	if err != nil {
 		return 0, 0, fmt.Errorf("could not read string len %s", err)
 	}
@@ -1381,7 +1383,7 @@ func readStringInfo(mem MemoryReadWriter, arch *Arch, addr uint64) (uint64, int6
 	}
 
 	// read addr
-	addr, err = readUintRaw(mem, addr, int64(arch.PtrSize()))
+	addr, err = readUintRaw(mem, addr, int64(arch.PtrSize()), arch.ByteOrder())
 	if err != nil {
 		return 0, 0, fmt.Errorf("could not read string pointer %s", err)
 	}
@@ -1467,7 +1469,7 @@ func (v *Variable) loadSliceInfo(t *godwarf.SliceType) {
 		switch f.Name {
 		case sliceArrayFieldName:
 			var base uint64
-			base, err = readUintRaw(v.mem, uint64(int64(v.Addr)+f.ByteOffset), f.Type.Size())
+			base, err = readUintRaw(v.mem, uint64(int64(v.Addr)+f.ByteOffset), f.Type.Size(), v.byteOrder())
 			if err == nil {
 				v.Base = base
 				// Dereference array type to get value type
@@ -1556,6 +1558,65 @@ func (v *Variable) loadChanInfo() {
 	}
 }
 
+// chanBuffer returns an array with up to count of the values buffered in
+// channel v, in the order they will be received, starting with the
+// start-th value.
+func (v *Variable) chanBuffer(start, count int, cfg LoadConfig) (*Variable, error) {
+	chanType, ok := v.RealType.(*godwarf.ChanType)
+	if !ok {
+		return nil, errors.New("bad channel type")
+	}
+	sv := v.clone()
+	sv.RealType = resolveTypedef(&(chanType.TypedefType))
+	sv = sv.maybeDereference()
+	if sv.Unreadable != nil {
+		return nil, sv.Unreadable
+	}
+	if sv.Addr == 0 {
+		return nil, errors.New("nil channel")
+	}
+	var fields [4]uint64
+	for i, name := range []string{"qcount", "dataqsiz", "recvx", "elemsize"} {
+		fv, err := sv.structMember(name)
+		if err != nil {
+			return nil, err
+		}
+		fields[i], err = readUintRaw(fv.mem, fv.Addr, fv.RealType.Size(), v.byteOrder())
+		if err != nil {
+			return nil, err
+		}
+	}
+	qcount, dataqsiz, recvx, elemsize := fields[0], fields[1], fields[2], fields[3]
+	bufv, err := sv.structMember("buf")
+	if err != nil {
+		return nil, err
+	}
+	buf, err := readUintRaw(bufv.mem, bufv.Addr, int64(v.bi.Arch.PtrSize()), v.byteOrder())
+	if err != nil {
+		return nil, err
+	}
+
+	if start < 0 || uint64(start) > qcount {
+		return nil, fmt.Errorf("index out of bounds")
+	}
+	n := qcount - uint64(start)
+	if uint64(count) < n {
+		n = uint64(count)
+	}
+	mem := DereferenceMemory(v.mem)
+	r := v.newVariable("", 0, fakeArrayType(n, chanType.ElemType), mem)
+	r.Len = int64(n)
+	r.loaded = true
+	r.Children = make([]Variable, n)
+	for i := range r.Children {
+		idx := (recvx + uint64(start) + uint64(i)) % dataqsiz
+		elem := r.newVariable("", buf+idx*elemsize, chanType.ElemType, mem)
+		elem.loadValue(cfg)
+		r.Children[i] = *elem
+	}
+	return r, nil
+}
+
 func (v *Variable) loadArrayValues(recurseLevel int, cfg LoadConfig) {
 	if v.Unreadable != nil {
 		return
@@ -1628,7 +1689,7 @@ func (v *Variable) writeComplex(real, imag float64, size int64) error {
 	return imagaddr.writeFloatRaw(imag, int64(size/2))
 }
 
-func readIntRaw(mem MemoryReadWriter, addr uint64, size int64) (int64, error) {
+func readIntRaw(mem MemoryReadWriter, addr uint64, size int64, order binary.ByteOrder) (int64, error) {
 	var n int64
 
 	val := make([]byte, int(size))
@@ -1641,11 +1702,11 @@ func readIntRaw(mem MemoryReadWriter, addr uint64, size int64) (int64, error) {
 	case 1:
 		n = int64(int8(val[0]))
 	case 2:
-		n = int64(int16(binary.LittleEndian.Uint16(val)))
+		n = int64(int16(order.Uint16(val)))
 	case 4:
-		n = int64(int32(binary.LittleEndian.Uint32(val)))
+		n = int64(int32(order.Uint32(val)))
 	case 8:
-		n = int64(binary.LittleEndian.Uint64(val))
+		n = int64(order.Uint64(val))
 	}
 
 	return n, nil
@@ -1654,22 +1715,24 @@ func readIntRaw(mem MemoryReadWriter, addr uint64, size int64) (int64, error) {
 func (v *Variable) writeUint(value uint64, size int64) error {
 	val := make([]byte, size)
 
+	order := v.byteOrder()
+
 	switch size {
 	case 1:
 		val[0] = byte(value)
 	case 2:
-		binary.LittleEndian.PutUint16(val, uint16(value))
+		order.PutUint16(val, uint16(value))
 	case 4:
-		binary.LittleEndian.PutUint32(val, uint32(value))
+		order.PutUint32(val, uint32(value))
 	case 8:
-		binary.LittleEndian.PutUint64(val, uint64(value))
+		order.PutUint64(val, uint64(value))
 	}
 
 	_, err := v.mem.WriteMemory(v.Addr, val)
 	return err
 }
 
-func readUintRaw(mem MemoryReadWriter, addr uint64, size int64) (uint64, error) {
+func readUintRaw(mem MemoryReadWriter, addr uint64, size int64, order binary.ByteOrder) (uint64, error) {
 	var n uint64
 
 	val := make([]byte, int(size))
@@ -1682,16 +1745,25 @@ func readUintRaw(mem MemoryReadWriter, addr uint64, size int64) (uint64, error)
 	case 1:
 		n = uint64(val[0])
 	case 2:
-		n = uint64(binary.LittleEndian.Uint16(val))
+		n = uint64(order.Uint16(val))
 	case 4:
-		n = uint64(binary.LittleEndian.Uint32(val))
+		n = uint64(order.Uint32(val))
 	case 8:
-		n = uint64(binary.LittleEndian.Uint64(val))
+		n = uint64(order.Uint64(val))
 	}
 
 	return n, nil
 }
 
+// byteOrder returns the byte order of the target architecture, values
+// that are not associated with a target use little endian.
+func (v *Variable) byteOrder() binary.ByteOrder {
+	if v.bi == nil || v.bi.Arch == nil {
+		return binary.LittleEndian
+	}
+	return v.bi.Arch.ByteOrder()
+}
+
 func (v *Variable) readFloatRaw(size int64) (float64, error) {
 	val := make([]byte, int(size))
 	_, err := v.mem.ReadMemory(val, v.Addr)
@@ -1703,11 +1775,11 @@ func (v *Variable) readFloatRaw(size int64) (float64, error) {
 	switch size {
 	case 4:
 		n := float32(0)
-		binary.Read(buf, binary.LittleEndian, &n)
+		binary.Read(buf, v.byteOrder(), &n)
 		return float64(n), nil
 	case 8:
 		n := float64(0)
-		binary.Read(buf, binary.LittleEndian, &n)
+		binary.Read(buf, v.byteOrder(), &n)
 		return n, nil
 	}
 
@@ -1720,10 +1792,10 @@ func (v *Variable) writeFloatRaw(f float64, size int64) error {
 	switch size {
 	case 4:
 		n := float32(f)
-		binary.Write(buf, binary.LittleEndian, n)
+		binary.Write(buf, v.byteOrder(), n)
 	case 8:
 		n := float64(f)
-		binary.Write(buf, binary.LittleEndian, n)
+		binary.Write(buf, v.byteOrder(), n)
 	}
 
 	_, err := v.mem.WriteMemory(v.Addr, buf.Bytes())
@@ -1805,7 +1877,7 @@ func (v *Variable) readFunctionPtr() {
 		return
 	}
 
-	val, err := readUintRaw(v.mem, v.closureAddr, int64(v.bi.Arch.PtrSize()))
+	val, err := readUintRaw(v.mem, v.closureAddr, int64(v.bi.Arch.PtrSize()), v.byteOrder())
 	if err != nil {
 		v.Unreadable = err
 		return
@@ -1823,7 +1895,7 @@ func (v *Variable) readFunctionPtr() {
 
 // funcvalAddr reads the address of the funcval contained in a function variable.
 func (v *Variable) funcvalAddr() uint64 {
-	val, err := readUintRaw(v.mem, v.Addr, int64(v.bi.Arch.PtrSize()))
+	val, err := readUintRaw(v.mem, v.Addr, int64(v.bi.Arch.PtrSize()), v.byteOrder())
 	if err != nil {
 		v.Unreadable = err
 		return 0
@@ -0,0 +1,147 @@
+package proc
+
+import (
+	"debug/dwarf"
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/arch/x86/x86asm"
+)
+
+// VariableReferenceKind describes how an instruction references a package
+// variable.
+type VariableReferenceKind uint8
+
+const (
+	// VariableStore means that the instruction writes to the memory of the
+	// variable.
+	VariableStore VariableReferenceKind = iota
+	// VariableAddressTaken means that the instruction computes the address
+	// of the variable, which can then be written through the resulting
+	// pointer. This is how the compiler writes pointers to package
+	// variables when the write barrier is enabled.
+	VariableAddressTaken
+)
+
+// VariableReference is an instruction of the program that references a
+// package variable.
+type VariableReference struct {
+	Kind VariableReferenceKind
+	Inst AsmInstruction
+}
+
+// PackageVariableReferences returns the instructions of the program that
+// write to the memory of v, which must be a package variable or a part of
+// one, or that compute its address.
+// DWARF does not record cross-references, the instructions are found by
+// disassembling every function of the program and looking for PC-relative
+// memory operands pointing into v: writes made through pointers stored in
+// other variables are not found.
+// Only amd64 is supported.
+func PackageVariableReferences(t *Target, v *Variable) ([]VariableReference, error) {
+	bi := t.BinInfo()
+	if bi.Arch.Name != "amd64" {
+		return nil, fmt.Errorf("variable references are not supported on %s", bi.Arch.Name)
+	}
+	if v.Addr == 0 || v.Flags&VariableFakeAddress != 0 || v.RealType == nil {
+		return nil, errors.New("expression does not refer to memory")
+	}
+	if !bi.inPackageVar(v.Addr) {
+		return nil, fmt.Errorf("%s is not a package variable", v.Name)
+	}
+	start, end := v.Addr, v.Addr+uint64(v.RealType.Size())
+	if end == start {
+		end++
+	}
+
+	mem := t.Memory()
+	breakpoints := t.Breakpoints()
+	var r []VariableReference
+	for i := range bi.Functions {
+		fn := &bi.Functions[i]
+		if fn.Entry == 0 || fn.End <= fn.Entry {
+			continue
+		}
+		buf := make([]byte, fn.End-fn.Entry)
+		if _, err := mem.ReadMemory(buf, fn.Entry); err != nil {
+			continue
+		}
+		for _, bp := range breakpoints.M {
+			if bp.Addr >= fn.Entry && bp.Addr < fn.End {
+				copy(buf[bp.Addr-fn.Entry:], bp.OriginalData)
+			}
+		}
+
+		for pc := fn.Entry; pc < fn.End; {
+			inst, err := x86asm.Decode(buf[pc-fn.Entry:], 64)
+			if err != nil {
+				pc++
+				continue
+			}
+			kind, ok := x86VariableReference(&inst, pc, start, end)
+			if ok {
+				text, err := disassemble(mem, nil, breakpoints, bi, pc, pc+uint64(inst.Len), true)
+				if err == nil && len(text) > 0 {
+					r = append(r, VariableReference{Kind: kind, Inst: text[0]})
+				}
+			}
+			pc += uint64(inst.Len)
+		}
+	}
+	return r, nil
+}
+
+// inPackageVar returns true if addr belongs to the memory of a package
+// variable.
+func (bi *BinaryInfo) inPackageVar(addr uint64) bool {
+	i := sort.Search(len(bi.packageVars), func(i int) bool {
+		return bi.packageVars[i].addr > addr
+	}) - 1
+	if i < 0 || bi.packageVars[i].addr == 0 {
+		return false
+	}
+	pv := bi.packageVars[i]
+	reader := pv.cu.image.dwarfReader
+	reader.Seek(pv.offset)
+	entry, err := reader.Next()
+	if err != nil || entry == nil {
+		return false
+	}
+	off, ok := entry.Val(dwarf.AttrType).(dwarf.Offset)
+	if !ok {
+		return false
+	}
+	typ, err := pv.cu.image.Type(off)
+	if err != nil {
+		return false
+	}
+	return addr == pv.addr || addr < pv.addr+uint64(typ.Size())
+}
+
+// x86VariableReference returns how inst, at address pc, references the
+// memory between start and end.
+func x86VariableReference(inst *x86asm.Inst, pc, start, end uint64) (VariableReferenceKind, bool) {
+	refers := func(arg x86asm.Arg, size int) bool {
+		mem, ok := arg.(x86asm.Mem)
+		if !ok || mem.Base != x86asm.RIP || mem.Index != 0 {
+			return false
+		}
+		addr := uint64(int64(pc) + int64(inst.Len) + mem.Disp)
+		if size <= 0 {
+			size = 1
+		}
+		return addr < end && start < addr+uint64(size)
+	}
+	switch {
+	case inst.Op == x86asm.LEA:
+		if refers(inst.Args[1], 1) {
+			return VariableAddressTaken, true
+		}
+	case x86WritesFirstArg(inst.Op):
+		if refers(inst.Args[0], inst.MemBytes) {
+			return VariableStore, true
+		}
+	}
+	return 0, false
+}
@@ -172,6 +172,12 @@ func (r *AMD64Registers) Copy() (proc.Registers, error) {
 	return &rr, nil
 }
 
+// ContextBytes returns the CONTEXT structure these registers were read
+// from, as a byte slice.
+func (r *AMD64Registers) ContextBytes() []byte {
+	return (*[unsafe.Sizeof(CONTEXT{})]byte)(unsafe.Pointer(r.Context))[:]
+}
+
 // M128A tracks the _M128A windows struct.
 type M128A struct {
 	Low  uint64
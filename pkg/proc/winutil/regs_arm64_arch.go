@@ -0,0 +1,111 @@
+package winutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+const (
+	ARM64_MAX_BREAKPOINTS = 8
+	ARM64_MAX_WATCHPOINTS = 2
+)
+
+// ARM64Registers represents CPU registers on an ARM64 processor.
+type ARM64Registers struct {
+	iscgo   bool
+	tls     uint64
+	Context *ARM64CONTEXT
+}
+
+// NewARM64Registers creates a new ARM64Registers struct from an
+// ARM64CONTEXT struct and the TEB base address of the thread.
+func NewARM64Registers(context *ARM64CONTEXT, TebBaseAddress uint64, iscgo bool) *ARM64Registers {
+	return &ARM64Registers{iscgo: iscgo, tls: TebBaseAddress, Context: context}
+}
+
+// Slice returns the registers as a list of (name, value) pairs.
+func (r *ARM64Registers) Slice(floatingPoint bool) ([]proc.Register, error) {
+	out := make([]proc.Register, 0, len(r.Context.Regs)+3+len(r.Context.V)+2)
+	for i, v := range r.Context.Regs {
+		out = proc.AppendUint64Register(out, fmt.Sprintf("X%d", i), v)
+	}
+	out = proc.AppendUint64Register(out, "SP", r.Context.Sp)
+	out = proc.AppendUint64Register(out, "PC", r.Context.Pc)
+	out = proc.AppendUint64Register(out, "PSTATE", uint64(r.Context.Cpsr))
+	if floatingPoint {
+		for i := range r.Context.V {
+			var buf bytes.Buffer
+			binary.Write(&buf, binary.LittleEndian, r.Context.V[i].Low)
+			binary.Write(&buf, binary.LittleEndian, r.Context.V[i].High)
+			out = proc.AppendBytesRegister(out, fmt.Sprintf("V%d", i), buf.Bytes())
+		}
+		out = proc.AppendUint64Register(out, "Fpcr", uint64(r.Context.Fpcr))
+		out = proc.AppendUint64Register(out, "Fpsr", uint64(r.Context.Fpsr))
+	}
+	return out, nil
+}
+
+// PC returns the current program counter
+// i.e. the PC CPU register.
+func (r *ARM64Registers) PC() uint64 {
+	return r.Context.Pc
+}
+
+// SP returns the stack pointer location,
+// i.e. the SP register.
+func (r *ARM64Registers) SP() uint64 {
+	return r.Context.Sp
+}
+
+func (r *ARM64Registers) BP() uint64 {
+	return r.Context.Regs[29]
+}
+
+// TLS returns the value of the register
+// that contains the location of the thread
+// local storage segment.
+func (r *ARM64Registers) TLS() uint64 {
+	return r.tls
+}
+
+// GAddr returns the address of the G variable if it is known, 0 and false
+// otherwise.
+func (r *ARM64Registers) GAddr() (uint64, bool) {
+	return r.Context.Regs[28], !r.iscgo
+}
+
+// Copy returns a copy of these registers that is guaranteed not to change.
+func (r *ARM64Registers) Copy() (proc.Registers, error) {
+	var rr ARM64Registers
+	rr = *r
+	rr.Context = NewARM64CONTEXT()
+	*(rr.Context) = *(r.Context)
+	return &rr, nil
+}
+
+// ARM64CONTEXT tracks the _ARM64_NT_CONTEXT of windows.
+type ARM64CONTEXT struct {
+	ContextFlags uint32
+	Cpsr         uint32
+	Regs         [31]uint64
+	Sp           uint64
+	Pc           uint64
+	V            [32]M128A
+	Fpcr         uint32
+	Fpsr         uint32
+	Bcr          [ARM64_MAX_BREAKPOINTS]uint32
+	Bvr          [ARM64_MAX_BREAKPOINTS]uint64
+	Wcr          [ARM64_MAX_WATCHPOINTS]uint32
+	Wvr          [ARM64_MAX_WATCHPOINTS]uint64
+}
+
+// NewARM64CONTEXT allocates Windows ARM64CONTEXT structure aligned to 16 bytes.
+func NewARM64CONTEXT() *ARM64CONTEXT {
+	var c *ARM64CONTEXT
+	buf := make([]byte, unsafe.Sizeof(*c)+15)
+	return (*ARM64CONTEXT)(unsafe.Pointer((uintptr(unsafe.Pointer(&buf[15]))) &^ 15))
+}
@@ -0,0 +1,122 @@
+package winutil
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestARM64CONTEXTLayout(t *testing.T) {
+	// Offsets and size of the fields of _ARM64_NT_CONTEXT, as defined in winnt.h.
+	var c ARM64CONTEXT
+	offsets := []struct {
+		name string
+		got  uintptr
+		want uintptr
+	}{
+		{"Cpsr", unsafe.Offsetof(c.Cpsr), 0x4},
+		{"Regs", unsafe.Offsetof(c.Regs), 0x8},
+		{"Sp", unsafe.Offsetof(c.Sp), 0x100},
+		{"Pc", unsafe.Offsetof(c.Pc), 0x108},
+		{"V", unsafe.Offsetof(c.V), 0x110},
+		{"Fpcr", unsafe.Offsetof(c.Fpcr), 0x310},
+		{"Fpsr", unsafe.Offsetof(c.Fpsr), 0x314},
+		{"Bcr", unsafe.Offsetof(c.Bcr), 0x318},
+		{"Bvr", unsafe.Offsetof(c.Bvr), 0x338},
+		{"Wcr", unsafe.Offsetof(c.Wcr), 0x378},
+		{"Wvr", unsafe.Offsetof(c.Wvr), 0x380},
+		{"size", unsafe.Sizeof(c), 0x390},
+	}
+	for _, tc := range offsets {
+		if tc.got != tc.want {
+			t.Errorf("%s: got %#x expected %#x", tc.name, tc.got, tc.want)
+		}
+	}
+
+	for i := 0; i < 10; i++ {
+		if p := uintptr(unsafe.Pointer(NewARM64CONTEXT())); p%16 != 0 {
+			t.Fatalf("NewARM64CONTEXT returned unaligned context %#x", p)
+		}
+	}
+}
+
+func TestARM64RegistersRoundTrip(t *testing.T) {
+	context := NewARM64CONTEXT()
+	for i := range context.Regs {
+		context.Regs[i] = 0x1000 + uint64(i)
+	}
+	context.Sp = 0xc000040f80
+	context.Pc = 0x4a1b2c
+	context.Cpsr = 0x60000000
+	context.V[1] = M128A{Low: 0x0706050403020100, High: 0x0f0e0d0c0b0a0908}
+	context.Fpcr = 0x3000000
+	context.Fpsr = 0x10
+
+	regs := NewARM64Registers(context, 0x7ff000, false)
+
+	if regs.PC() != context.Pc {
+		t.Errorf("PC: got %#x expected %#x", regs.PC(), context.Pc)
+	}
+	if regs.SP() != context.Sp {
+		t.Errorf("SP: got %#x expected %#x", regs.SP(), context.Sp)
+	}
+	if regs.BP() != context.Regs[29] {
+		t.Errorf("BP: got %#x expected %#x", regs.BP(), context.Regs[29])
+	}
+	if regs.TLS() != 0x7ff000 {
+		t.Errorf("TLS: got %#x expected %#x", regs.TLS(), 0x7ff000)
+	}
+	if g, ok := regs.GAddr(); g != context.Regs[28] || !ok {
+		t.Errorf("GAddr: got %#x %v expected %#x true", g, ok, context.Regs[28])
+	}
+	if _, ok := NewARM64Registers(context, 0, true).GAddr(); ok {
+		t.Errorf("GAddr of a cgo thread should not be known")
+	}
+
+	slice, err := regs.Slice(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := map[string]uint64{}
+	bytes := map[string][]byte{}
+	for _, reg := range slice {
+		if reg.Reg.Bytes != nil {
+			bytes[reg.Name] = reg.Reg.Bytes
+		} else {
+			values[reg.Name] = reg.Reg.Uint64Val
+		}
+	}
+	for name, want := range map[string]uint64{"X0": 0x1000, "X30": 0x101e, "SP": context.Sp, "PC": context.Pc, "PSTATE": 0x60000000, "Fpcr": 0x3000000, "Fpsr": 0x10} {
+		if got, ok := values[name]; !ok || got != want {
+			t.Errorf("%s: got %#x (%v) expected %#x", name, got, ok, want)
+		}
+	}
+	v1 := bytes["V1"]
+	if len(v1) != 16 {
+		t.Fatalf("V1: got %d bytes expected 16", len(v1))
+	}
+	for i := range v1 {
+		if v1[i] != byte(i) {
+			t.Errorf("V1: got %x", v1)
+			break
+		}
+	}
+	if len(slice) != len(context.Regs)+3+len(context.V)+2 {
+		t.Errorf("wrong number of registers %d", len(slice))
+	}
+	if slice, _ := regs.Slice(false); len(slice) != len(context.Regs)+3 {
+		t.Errorf("wrong number of registers without floating point %d", len(slice))
+	}
+
+	cpy, err := regs.Copy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	context.Pc = 0
+	context.Regs[29] = 0
+	if cpy.PC() != 0x4a1b2c || cpy.BP() != 0x101d {
+		t.Errorf("copy of the registers changed with the original context: %#x %#x", cpy.PC(), cpy.BP())
+	}
+	if c := cpy.(*ARM64Registers).Context; uintptr(unsafe.Pointer(c))%16 != 0 {
+		t.Errorf("copy of the context is not aligned")
+	}
+}
@@ -0,0 +1,127 @@
+package proc
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// MemoryWrite is a write to the memory of the target made by the
+// debugger, on behalf of a client, see Target.MemoryWrites.
+type MemoryWrite struct {
+	ID   int
+	Addr uint64
+	// Old is the content of the memory before the write, nil if it could
+	// not be read.
+	Old []byte
+	New []byte
+	// Client and Origin describe who requested the write and the
+	// operation that caused it, as set by Target.SetWriteOrigin.
+	Client string
+	Origin string
+	// Undone is true if the write was reverted by UndoMemoryWrite.
+	Undone bool
+}
+
+// writeLog is the list of the writes made to the memory of the target.
+type writeLog struct {
+	writes []MemoryWrite
+	client string
+	origin string
+}
+
+// SetWriteOrigin sets the client and the description of the operation
+// recorded with the following writes to the memory of the target. It
+// should be reset, with empty strings, once the operation is done.
+func (t *Target) SetWriteOrigin(client, origin string) {
+	t.writeLog.client = client
+	t.writeLog.origin = origin
+}
+
+// MemoryWrites returns the writes to the memory of the target made by
+// the debugger through evaluation scopes (setting variables, calling
+// functions) and through Target.WriteMemory.
+func (t *Target) MemoryWrites() []MemoryWrite {
+	return t.writeLog.writes
+}
+
+// WriteMemory writes data to the memory of the target at addr, recording
+// the write.
+func (t *Target) WriteMemory(addr uint64, data []byte) (int, error) {
+	return t.auditMemory(t.Memory()).WriteMemory(addr, data)
+}
+
+// UndoMemoryWrite restores the memory overwritten by the write with the
+// given ID. It fails if the memory was changed since the write, by the
+// target or by another write, because restoring it could then corrupt
+// the state of the target.
+func (t *Target) UndoMemoryWrite(id int) error {
+	if id <= 0 || id > len(t.writeLog.writes) {
+		return fmt.Errorf("unknown write %d", id)
+	}
+	w := &t.writeLog.writes[id-1]
+	if w.Undone {
+		return fmt.Errorf("write %d was already undone", id)
+	}
+	if w.Old == nil {
+		return fmt.Errorf("the memory overwritten by write %d is unknown", id)
+	}
+	cur := make([]byte, len(w.New))
+	if _, err := t.Memory().ReadMemory(cur, w.Addr); err != nil {
+		return err
+	}
+	if !bytes.Equal(cur, w.New) {
+		return fmt.Errorf("memory at %#x changed since write %d", w.Addr, id)
+	}
+	origin := t.writeLog.origin
+	t.writeLog.origin = fmt.Sprintf("undo write %d", id)
+	defer func() { t.writeLog.origin = origin }()
+	if _, err := t.WriteMemory(w.Addr, w.Old); err != nil {
+		return err
+	}
+	w.Undone = true
+	return nil
+}
+
+// auditMemory returns mem, recording its writes in the write log of t.
+func (t *Target) auditMemory(mem MemoryReadWriter) MemoryReadWriter {
+	if t == nil {
+		return mem
+	}
+	if _, ok := mem.(*auditedMemory); ok {
+		return mem
+	}
+	return &auditedMemory{MemoryReadWriter: mem, t: t}
+}
+
+// auditedMemory records the writes made through it in the write log of a
+// target.
+type auditedMemory struct {
+	MemoryReadWriter
+	t *Target
+}
+
+func (mem *auditedMemory) WriteMemory(addr uint64, data []byte) (int, error) {
+	if err := mem.t.checkSafety("memory write"); err != nil {
+		return 0, err
+	}
+	old := make([]byte, len(data))
+	if _, err := mem.MemoryReadWriter.ReadMemory(old, addr); err != nil {
+		old = nil
+	}
+	n, err := mem.MemoryReadWriter.WriteMemory(addr, data)
+	if n > 0 {
+		l := &mem.t.writeLog
+		w := MemoryWrite{
+			ID:     len(l.writes) + 1,
+			Addr:   addr,
+			New:    append([]byte(nil), data[:n]...),
+			Client: l.client,
+			Origin: l.origin,
+		}
+		if old != nil {
+			w.Old = old[:n]
+		}
+		l.writes = append(l.writes, w)
+	}
+	return n, err
+}
@@ -113,7 +113,7 @@ func resolveCallArgX86(inst *x86asm.Inst, instAddr uint64, currentGoroutine bool
 			return nil
 		}
 		addr := uint64(int64(base) + int64(index*uint64(arg.Scale)) + arg.Disp)
-		pc, err = readUintRaw(mem, addr, int64(inst.MemBytes))
+		pc, err = readUintRaw(mem, addr, int64(inst.MemBytes), bininfo.Arch.ByteOrder())
 		if err != nil {
 			return nil
 		}
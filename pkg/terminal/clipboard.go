@@ -0,0 +1,117 @@
+package terminal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// copyLoadConfig is used to load the values copied to the clipboard, the
+// limits are much higher than the ones used by print because the value is
+// not displayed.
+var copyLoadConfig = api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 10, MaxStringLen: 1 << 20, MaxArrayValues: 1 << 16, MaxStructFields: -1}
+
+// clipboardCommand returns the command line of the program used to write
+// to the system clipboard, or nil if the OSC 52 escape sequence should be
+// used instead.
+// OSC 52 is always used in SSH sessions, where the system clipboard of the
+// remote machine is not the one the user wants to paste from.
+func clipboardCommand(goos string, getenv func(string) string, lookPath func(string) (string, error)) []string {
+	if getenv("SSH_TTY") != "" || getenv("SSH_CONNECTION") != "" {
+		return nil
+	}
+	var candidates [][]string
+	switch goos {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		if getenv("WAYLAND_DISPLAY") != "" {
+			candidates = append(candidates, []string{"wl-copy"})
+		}
+		if getenv("DISPLAY") != "" {
+			candidates = append(candidates, []string{"xclip", "-selection", "clipboard"}, []string{"xsel", "--clipboard", "--input"})
+		}
+	}
+	for _, argv := range candidates {
+		if _, err := lookPath(argv[0]); err == nil {
+			return argv
+		}
+	}
+	return nil
+}
+
+// osc52 returns the escape sequence that asks the terminal emulator to
+// write s to the clipboard.
+func osc52(s string) string {
+	return "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(s)) + "\a"
+}
+
+// copyToClipboard writes s to the system clipboard, returns a description
+// of the mechanism used.
+func copyToClipboard(stdout io.Writer, s string) (string, error) {
+	argv := clipboardCommand(runtime.GOOS, os.Getenv, exec.LookPath)
+	if argv == nil {
+		if _, err := io.WriteString(stdout, osc52(s)); err != nil {
+			return "", err
+		}
+		return "terminal (OSC 52)", nil
+	}
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin = strings.NewReader(s)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s: %v", argv[0], err)
+	}
+	return argv[0], nil
+}
+
+func copyCommand(t *Term, ctx callContext, args string) error {
+	args = strings.TrimSpace(args)
+	if args == "" {
+		return errors.New("not enough arguments")
+	}
+
+	var s string
+	if args == "-loc" {
+		frames, err := t.client.Stacktrace(ctx.Scope.GoroutineID, ctx.Scope.Frame, 0, nil)
+		if err != nil {
+			return err
+		}
+		if ctx.Scope.Frame >= len(frames) {
+			return fmt.Errorf("frame %d does not exist", ctx.Scope.Frame)
+		}
+		s = fmt.Sprintf("%s:%d", frames[ctx.Scope.Frame].File, frames[ctx.Scope.Frame].Line)
+	} else {
+		fmtstr, expr := parseFormatArg(args)
+		val, err := t.client.EvalVariable(ctx.Scope, expr, copyLoadConfig)
+		if err != nil {
+			return err
+		}
+		if val.Kind == reflect.String && fmtstr == "" && val.Unreadable == "" {
+			// strings are copied without quoting
+			s = val.Value
+			if int64(len(s)) < val.Len {
+				fmt.Fprintf(os.Stderr, "Warning: string truncated to %d bytes\n", len(s))
+			}
+		} else {
+			s = val.MultilineString("", fmtstr)
+		}
+	}
+
+	via, err := copyToClipboard(t.stdout, s)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Copied %d bytes to the clipboard using %s.\n", len(s), via)
+	return nil
+}
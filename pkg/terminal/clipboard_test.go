@@ -0,0 +1,45 @@
+package terminal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestClipboardCommand(t *testing.T) {
+	installed := map[string]bool{"pbcopy": true, "clip": true, "xsel": true}
+	lookPath := func(name string) (string, error) {
+		if installed[name] {
+			return "/usr/bin/" + name, nil
+		}
+		return "", errors.New("not found")
+	}
+
+	tests := []struct {
+		goos string
+		env  map[string]string
+		tgt  []string
+	}{
+		{"darwin", nil, []string{"pbcopy"}},
+		{"windows", nil, []string{"clip"}},
+		{"linux", nil, nil},
+		{"linux", map[string]string{"DISPLAY": ":0"}, []string{"xsel", "--clipboard", "--input"}},
+		{"linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, nil},
+		{"linux", map[string]string{"DISPLAY": ":0", "SSH_TTY": "/dev/pts/1"}, nil},
+		{"darwin", map[string]string{"SSH_CONNECTION": "10.0.0.1 22 10.0.0.2 22"}, nil},
+	}
+
+	for _, test := range tests {
+		getenv := func(name string) string { return test.env[name] }
+		argv := clipboardCommand(test.goos, getenv, lookPath)
+		if !reflect.DeepEqual(argv, test.tgt) {
+			t.Errorf("%s %v: got %q expected %q", test.goos, test.env, argv, test.tgt)
+		}
+	}
+}
+
+func TestOSC52(t *testing.T) {
+	if s := osc52("hello"); s != "\x1b]52;c;aGVsbG8=\a" {
+		t.Errorf("wrong escape sequence %q", s)
+	}
+}
@@ -5,11 +5,14 @@ package terminal
 import (
 	"bufio"
 	"bytes"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"go/parser"
 	"go/scanner"
 	"io"
+	"io/ioutil"
 	"math"
 	"os"
 	"os/exec"
@@ -125,10 +128,25 @@ See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/locspec.md for the s
 See also: "help on", "help cond" and "help clear"`},
 		{aliases: []string{"trace", "t"}, group: breakCmds, cmdFn: tracepoint, helpMsg: `Set tracepoint.
 
-	trace [name] <linespec>
+	trace [-stack <depth>] [-aggregate] [name] <linespec>
+	trace [-stack <depth>] -preset <preset>
 
 A tracepoint is a breakpoint that does not stop the execution of the program, instead when the tracepoint is hit a notification is displayed. See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/locspec.md for the syntax of linespec.
 
+With -stack the notification also contains the stack of the goroutine that hit the tracepoint, up to depth frames. If depth is 'all' the whole stack is recorded.
+
+With -aggregate no notification is displayed, instead the stacks that reach the tracepoint are recorded and, when the program exits, the distinct call paths are printed with the number of times each of them was taken, the most frequent first. Stacks are recorded up to the depth specified by -stack, 50 frames by default.
+
+With -preset a set of tracepoints on well known functions is installed, their notifications decode the arguments of each call. The following presets are available:
+
+	http	requests served by net/http servers and sent by net/http clients, with their method, URL, status and latency
+	grpc	calls handled by gRPC servers and made by gRPC clients, with their method, error and latency
+	sql	statements executed with database/sql, with their arguments, error and latency
+
+More presets can be defined by starlark scripts, see $GOPATH/src/github.com/go-delve/delve/Documentation/cli/starlark.md.
+
+The latency of a call is measured by the debugger and includes the overhead of tracing.
+
 See also: "help on", "help cond" and "help clear"`},
 		{aliases: []string{"watch"}, group: breakCmds, cmdFn: watchpoint, helpMsg: `Set watchpoint.
 	
@@ -190,12 +208,29 @@ Optional [count] argument allows you to skip multiple lines.
 		{aliases: []string{"call"}, group: runCmds, cmdFn: c.call, helpMsg: `Resumes process, injecting a function call (EXPERIMENTAL!!!)
 	
 	call [-unsafe] <function call expression>
+	call -cancel
 	
+If the called function does not return, for example because it is
+blocked, the process can be stopped with ctrl-C. After that 'call -cancel'
+abandons the function calls in progress (prefix it with 'goroutine <n>' to
+only abandon the one on goroutine n): their results are discarded and the
+registers of the goroutine are restored, without stopping, when the called
+function eventually returns. The called function can not be interrupted.
+Panics in the called function are always recovered and reported as the
+~panic return value.
+
 Current limitations:
 - only pointers to stack-allocated objects can be passed as argument.
-- only some automatic type conversions are supported.
-- functions can only be called on running goroutines that are not
-  executing the runtime.
+- only some automatic type conversions are supported. Values passed as
+  interface arguments are copied to newly allocated memory, a value can
+  only be converted to a non-empty interface if the program already
+  performs the same conversion somewhere.
+- functions can only be called on goroutines that are not executing the
+  runtime. If the goroutine is not running (for example because it is
+  blocked on a channel operation) the process is resumed until the
+  goroutine is scheduled and returns to its first function that is not
+  part of the runtime, where the function is then called. If the process
+  stops for a different reason first the function is not called.
 - the current goroutine needs to have at least 256 bytes of free space on
   the stack.
 - functions can only be called when the goroutine is stopped at a safe
@@ -204,9 +239,31 @@ Current limitations:
 - only supported on linux's native backend.
 `},
 		{aliases: []string{"threads"}, group: goroutineCmds, cmdFn: threads, helpMsg: "Print out info for every traced thread."},
+		{aliases: []string{"sched"}, group: goroutineCmds, cmdFn: sched, helpMsg: `Print out the state of the Go scheduler.
+
+	sched
+
+Shows every P (processor) with its status, the M (OS thread) that owns it and the goroutines in its local run queue, every M with the P and goroutine it is bound to, and the global run queue.
+Ms executing a system call, spinning looking for work or locked to a goroutine by runtime.LockOSThread are marked as such.`},
+		{aliases: []string{"contention"}, group: goroutineCmds, cmdFn: contention, helpMsg: `Print out the most contended call sites.
+
+	contention [-block] [-n <count>]
+
+Reads the mutex profile collected by the runtime of the target program and prints the call sites where goroutines waited the longest on a contended mutex. With -block the block profile, describing where goroutines blocked on synchronization primitives, is used instead.
+
+	-block		use the block profile instead of the mutex profile
+	-n <count>	number of call sites to print (default: 10)
+
+The target program only collects these profiles after calling runtime.SetMutexProfileFraction or runtime.SetBlockProfileRate.`},
 		{aliases: []string{"thread", "tr"}, group: goroutineCmds, cmdFn: thread, helpMsg: `Switch to the specified thread.
 
 	thread <id>`},
+		{aliases: []string{"target"}, group: runCmds, cmdFn: target, helpMsg: `Manages the processes being debugged.
+
+	target list
+	target switch <pid>
+
+When started with --follow-fork the debugger also debugs the processes created by the target with fork and vfork, and the processes that replace their executable with exec. When attaching with --children it also debugs the matching child processes. Each process is a separate target, stopped and resumed independently: 'target list' lists them, marking the current target, the one the other commands act on, and 'target switch' makes the target of the given process current.`},
 		{aliases: []string{"clear"}, group: breakCmds, cmdFn: clear, helpMsg: `Deletes breakpoint.
 
 	clear <breakpoint name or id>`},
@@ -218,9 +275,14 @@ If called with the linespec argument it will delete all the breakpoints matching
 		{aliases: []string{"toggle"}, group: breakCmds, cmdFn: toggle, helpMsg: `Toggles on or off a breakpoint.
 
 toggle <breakpoint name or id>`},
+		{aliases: []string{"hits"}, group: breakCmds, cmdFn: hitsCmd, helpMsg: `Sets how simultaneous hits of a breakpoint are reported.
+
+	hits <breakpoint name or id> report|serialize
+
+When several goroutines hit breakpoints at the same time all the hits are reported when the program stops, this is the 'report' policy. With the 'serialize' policy the hits of the breakpoint by goroutines other than the one the program stopped for are held and each of them is reported by one of the following continue commands, without resuming the program. Held hits are dropped by the other commands that resume the program.`},
 		{aliases: []string{"goroutines", "grs"}, group: goroutineCmds, cmdFn: goroutines, helpMsg: `List program goroutines.
 
-	goroutines [-u|-r|-g|-s] [-t [depth]] [-l] [-with loc expr] [-without loc expr] [-group argument]
+	goroutines [-u|-r|-g|-s] [-t [depth]] [-l] [-with loc expr] [-without loc expr] [-grep regexp] [-group argument]
 
 Print out info for every goroutine. The flag controls what information is shown along with each goroutine:
 
@@ -266,16 +328,55 @@ To only display user (or runtime) goroutines, use:
 	goroutines -with user
 	goroutines -without user
 
+To only display goroutines that have a frame in their stacktrace matching a regular expression, use:
+
+	goroutines -grep regexp
+	goroutines -grepargs regexp
+
+Each frame is formatted as 'filename:lineno in function' before being matched. With -grepargs the arguments of each frame, formatted as 'name=value', are also matched against the regular expression. The search is executed by the server, only the goroutines that match are transferred.
+
 GROUPING
 
 	goroutines -group (userloc|curloc|goloc|startloc|running|user)
 
 Groups goroutines by the given location, running status or user classification, up to 5 goroutines per group will be displayed as well as the total number of goroutines in the group.
 
+	goroutines -group stack
+
+Groups goroutines that have identical stacktraces, the biggest groups are displayed first. When the target crashes, with an unrecovered panic or a fatal error, the goroutines are automatically displayed grouped by stack.
+
 	goroutines -group label key
 
 Groups goroutines by the value of the label with the specified key.
+
+ORDERING
+
+Goroutines are listed in the same order at every stop: named goroutines (see goroutine-name) first, then all other goroutines sorted by ID. Goroutines created since the previous stop at which the goroutines were listed are marked as (new), the goroutines that terminated since then are listed at the end.
 `},
+		{aliases: []string{"goroutine-name", "grname"}, group: goroutineCmds, cmdFn: goroutineName, helpMsg: `Names a goroutine.
+
+	goroutine-name <id> <name>
+	goroutine-name <id>
+
+Named goroutines are pinned at the start of the list printed by the goroutines command and their name is displayed next to their ID. Called without a name it removes the name of the goroutine.`},
+		{aliases: []string{"note"}, cmdFn: noteCommand, helpMsg: `Assigns a note to a goroutine, a breakpoint or a range of addresses.
+
+	note
+	note -g <goroutine id> [<text>]
+	note -b <breakpoint name or id> [<text>]
+	note -a <address> [-size <n>] [-name <name>] [<text>]
+
+Called without arguments it lists all notes and named ranges of addresses. When the text is omitted the note is removed.
+
+Notes are displayed by the goroutines, breakpoints and disassemble commands and are saved in project files, see the project command. Ranges of addresses can also be named, the default size of a range is 1 byte.`},
+		{aliases: []string{"goroutine-profile", "grprof"}, group: goroutineCmds, cmdFn: goroutineProfile, helpMsg: `Writes the stacktraces of all goroutines to a file.
+
+	goroutine-profile [-folded] [-depth <depth>] <output file>
+
+	-folded		writes the folded stacks text format used by flamegraph tools instead of pprof's format
+	-depth <depth>	maximum number of frames of each stacktrace (default: 50)
+
+Goroutines with identical stacktraces and labels are aggregated. By default the profile is written in the profile.proto format and can be opened with 'go tool pprof <output file>', for example to visualize it as a flamegraph or to compare it with a goroutine profile collected in production.`},
 		{aliases: []string{"goroutine", "gr"}, group: goroutineCmds, allowedPrefixes: onPrefix, cmdFn: c.goroutine, helpMsg: `Shows or changes current goroutine
 
 	goroutine
@@ -289,18 +390,60 @@ Called with more arguments it will execute a command on the specified goroutine.
 		{aliases: []string{"print", "p"}, group: dataCmds, allowedPrefixes: onPrefix | deferredPrefix, cmdFn: printVar, helpMsg: `Evaluate an expression.
 
 	[goroutine <n>] [frame <m>] print [%format] <expression>
+	[goroutine <n>] [frame <m>] print -i <expression>
 
 See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/expr.md for a description of supported expressions.
 
-The optional format argument is a format specifier, like the ones used by the fmt package. For example "print %x v" will print v as an hexadecimal number.`},
+The optional format argument is a format specifier, like the ones used by the fmt package. For example "print %x v" will print v as an hexadecimal number.
+
+The -i flag opens an interactive explorer where the value is displayed as a tree: use the arrow keys to move, expand and collapse its children (loaded when they are first expanded), enter to toggle and q to quit.`},
+		{aliases: []string{"copy"}, group: dataCmds, cmdFn: copyCommand, helpMsg: `Copies a value or the current location to the clipboard.
+
+	[goroutine <n>] [frame <m>] copy [%format] <expression>
+	[goroutine <n>] [frame <m>] copy -loc
+
+The first form evaluates the expression, loading its value fully, and copies it to the clipboard formatted like the print command does. Strings are copied without quotes.
+The second form copies the file:line of the current frame.
+
+The clipboard is written using pbcopy on macOS, clip on Windows and wl-copy, xclip or xsel on other systems. When none of them are available, or in an SSH session, the OSC 52 escape sequence is used, which requires support from the terminal emulator.`},
+		{aliases: []string{"savevar"}, group: dataCmds, cmdFn: saveVar, helpMsg: `Saves the content of a string, array or slice to a file.
+
+	[goroutine <n>] [frame <m>] savevar <file> <expression>
+
+Writes the content of the elements of the value of the expression to the file, without loading the value in the client: the file is written by the server, on the machine running the server, which can save very large values this way. For strings and byte slices the file contains the bytes of the value, for other arrays and slices it contains the raw memory of their elements.`},
 		{aliases: []string{"whatis"}, group: dataCmds, cmdFn: whatisCommand, helpMsg: `Prints type of an expression.
 
 	whatis <expression>`},
+		{aliases: []string{"explain"}, group: dataCmds, cmdFn: explainCommand, helpMsg: `Explains where the current value of a local variable came from.
+
+	[goroutine <n>] [frame <m>] explain <variable>
+
+Analyzes the instructions of the current function executed before the current PC, together with the DWARF location of the variable, and reports which instruction last wrote the variable and where the value was read from: a constant, a parameter, another variable, the result of a function call or memory.
+Control flow is not taken into account, the result is only a hint, especially in optimized code. Only supported on amd64.`},
+		{aliases: []string{"writers"}, group: dataCmds, cmdFn: writersCommand, helpMsg: `Reports the code that writes to a package variable.
+
+	[goroutine <n>] [frame <m>] writers [-static] [-window <duration>] <variable>
+
+First lists the instructions of the program that write to the variable, or compute its address, found by disassembling every function of the program (only supported on amd64). Writes made through pointers stored elsewhere are not found this way.
+
+Then sets a write watchpoint on the variable that records the stacks of the writers without stopping the target, continues the target until it stops, or for at most <duration> (for example 10s) if -window is specified, clears the watchpoint and prints the stacks observed writing to the variable, the most frequent first.
+
+With -static the target is not resumed.`},
 		{aliases: []string{"set"}, group: dataCmds, cmdFn: setVar, helpMsg: `Changes the value of a variable.
 
 	[goroutine <n>] [frame <m>] set <variable> = <value>
 
 See $GOPATH/src/github.com/go-delve/delve/Documentation/cli/expr.md for a description of supported expressions. Only numerical variables and pointers can be changed.`},
+		{aliases: []string{"writes"}, group: dataCmds, cmdFn: memoryWrites, helpMsg: `Print the writes made by the debugger to the memory of the target.
+
+	writes
+
+Lists the writes made by setting variables, calling functions and writing memory, with the client that requested them and the content of the memory before and after the write.`},
+		{aliases: []string{"undo-write"}, group: dataCmds, cmdFn: undoMemoryWrite, helpMsg: `Restores the memory overwritten by a write.
+
+	undo-write <id>
+
+The id is the one printed by the 'writes' command. The write can only be undone if the memory still contains what was written.`},
 		{aliases: []string{"sources"}, cmdFn: sources, helpMsg: `Print list of source files.
 
 	sources [<regex>]
@@ -316,11 +459,23 @@ If regex is specified only the functions matching it will be returned.`},
 	types [<regex>]
 
 If regex is specified only the types matching it will be returned.`},
+		{aliases: []string{"layout"}, cmdFn: layoutCommand, helpMsg: `Print the memory layout of a type.
+
+	layout <type>
+
+Prints the size and alignment of the type and the offset, size and type of each of its fields.`},
+		{aliases: []string{"symbols"}, cmdFn: symbolsCommand, helpMsg: `Print list of functions and package variables with their address.
+
+	symbols [-f|-v] [<regex>]
+
+The -f and -v flags restrict the list to functions or to package variables. If regex is specified only the symbols matching it will be returned.`},
 		{aliases: []string{"args"}, allowedPrefixes: onPrefix | deferredPrefix, group: dataCmds, cmdFn: args, helpMsg: `Print function arguments.
 
 	[goroutine <n>] [frame <m>] args [-v] [<regex>]
 
-If regex is specified only function arguments with a name matching it will be returned. If -v is specified more information about each function argument will be shown.`},
+If regex is specified only function arguments with a name matching it will be returned. If -v is specified more information about each function argument will be shown.
+
+Assembly functions have no debug information for their arguments, for them args shows where the arguments would be according to the Go calling conventions: the registers used to pass integer arguments, if the program uses the register based calling convention, and the first words of the argument frame, named after their offset from the FP pseudo-register (for example +8(FP)).`},
 		{aliases: []string{"locals"}, allowedPrefixes: onPrefix | deferredPrefix, group: dataCmds, cmdFn: locals, helpMsg: `Print local variables.
 
 	[goroutine <n>] [frame <m>] locals [-v] [<regex>]
@@ -357,11 +512,12 @@ For example:
 	list 40`},
 		{aliases: []string{"stack", "bt"}, allowedPrefixes: onPrefix, group: stackCmds, cmdFn: stackCommand, helpMsg: `Print stack trace.
 
-	[goroutine <n>] [frame <m>] stack [<depth>] [-full] [-offsets] [-defer] [-a <n>] [-adepth <depth>] [-mode <mode>]
+	[goroutine <n>] [frame <m>] stack [<depth>] [-full] [-offsets] [-defer] [-noinline] [-a <n>] [-adepth <depth>] [-mode <mode>]
 
 	-full		every stackframe is decorated with the value of its local variables and arguments.
 	-offsets	prints frame offset of each frame.
 	-defer		prints deferred function call stack for each frame.
+	-noinline	does not print inlined calls, only physical frames are printed (frame numbers are not changed).
 	-a <n>		prints stacktrace of n ancestors of the selected goroutine (target process must have tracebackancestors enabled)
 	-adepth <depth>	configures depth of ancestor stacktrace
 	-mode <mode>	specifies the stacktrace mode, possible values are:
@@ -369,18 +525,29 @@ For example:
 			simple	- disables automatic switch between cgo and go
 			fromg	- starts from the registers stored in the runtime.g struct
 `},
+		{aliases: []string{"defers"}, group: stackCmds, cmdFn: defersCommand, helpMsg: `Print the defer chain of a goroutine.
+
+	[goroutine <n>] defers
+
+Prints all the deferred calls of the goroutine that have not been executed yet, in the order they will be executed, with the values of the arguments that will be passed to them.
+On Go 1.17 and later the arguments shown are the variables captured by the deferred closure, arguments that are constants are not saved by the defer statement and are not shown.`},
 		{aliases: []string{"frame"},
 			group: stackCmds,
 			cmdFn: func(t *Term, ctx callContext, arg string) error {
+				if arg == "-debug" || strings.HasPrefix(arg, "-debug ") {
+					return c.frameDebugCommand(t, ctx, strings.TrimSpace(arg[len("-debug"):]))
+				}
 				return c.frameCommand(t, ctx, arg, frameSet)
 			},
 			helpMsg: `Set the current frame, or execute command on a different frame.
 
 	frame <m>
 	frame <m> <command>
+	frame -debug [<m>]
 
 The first form sets frame used by subsequent commands such as "print" or "set".
-The second form runs the command on the given frame.`},
+The second form runs the command on the given frame.
+The third form prints the call frame information used to unwind the current frame, or frame <m>: the rule used to compute its canonical frame address (CFA) and the rules used to compute the registers of the calling frame, followed by the arguments and local variables of the frame with the DWARF location expression used to read each of them. The current frame is not changed.`},
 		{aliases: []string{"up"},
 			group: stackCmds,
 			cmdFn: func(t *Term, ctx callContext, arg string) error {
@@ -427,7 +594,11 @@ If no argument is specified the function being executed in the selected stack fr
 
 	on <breakpoint name or id> <command>.
 
-Supported commands: print, stack and goroutine)`},
+Supported commands: print, stack and goroutine)
+
+The depth of the stack command can be 'all' to record the whole stack:
+
+	on <breakpoint name or id> stack all`},
 		{aliases: []string{"condition", "cond"}, group: breakCmds, cmdFn: conditionCmd, helpMsg: `Set breakpoint condition.
 
 	condition <breakpoint name or id> <boolean expression>.
@@ -435,6 +606,16 @@ Supported commands: print, stack and goroutine)`},
 
 Specifies that the breakpoint, tracepoint or watchpoint should break only if the boolean expression is true.
 
+The callerin builtin can be used to stop only when the breakpoint is reached through a given function, for example:
+
+	condition bp callerin("main.handleRequest", 3)
+
+stops only if main.handleRequest is one of the first 3 callers of the current function, at most 3 frames are unwound.
+
+The now(), hitcount(), goid() and label("key") builtins return the current time, the number of times the breakpoint was reached, the current goroutine ID and the value of one of its pprof labels, for example:
+
+	condition bp hitcount() > 100 && label("tenant") == "acme"
+
 With the -hitcount option a condition on the breakpoint hit count can be set, the following operators are supported
 
 	condition -hitcount bp > n
@@ -503,11 +684,34 @@ The '-a' option adds an expression to the list of expression printed every time
 
 If display is called without arguments it will print the value of all expression in the list.`},
 
+		{aliases: []string{"project"}, cmdFn: projectCommand, helpMsg: `Saves or restores the configuration of the debugging session.
+
+	project save <file>
+	project load <file>
+
+The project file contains the breakpoints, tracepoints and watchpoints, the expressions added with the display command, the path substitution rules and the load limits (max-string-len, max-array-values and max-variable-recurse) as well as the parameters used to start the target (packages, arguments, build flags, working directory and backend).
+Loading a project file restores everything except the launch parameters, use 'dlv --project <file>' to start a new debugging session from a project file. Watchpoints are restored in the scope of the current goroutine and frame.`},
 		{aliases: []string{"dump"}, cmdFn: dump, helpMsg: `Creates a core dump from the current process state
 
 	dump <output file>
 
-The core dump is always written in ELF, even on systems (windows, macOS) where this is not customary. For environments other than linux/amd64 threads and registers are dumped in a format that only Delve can read back.`},
+The core dump is written in the native format of the target system: ELF on linux, a Mach-O core file on macOS and a minidump on windows/amd64. Other systems use ELF. For environments other than linux/amd64 and windows/amd64 threads and registers are dumped in a format that only Delve can read back.`},
+		{aliases: []string{"download"}, cmdFn: download, helpMsg: `Downloads a file created by the debugger, like a core dump.
+
+	download
+	download <remote file> [<local file>]
+
+Without arguments lists the files created by the debugger that can be downloaded. When connected to a headless instance of Delve on a different machine this copies the file created by the dump command to the local machine, by default in the current directory. Interrupted downloads are resumed and the checksum of the file is verified.`},
+		{aliases: []string{"diagnostics"}, cmdFn: diagnostics, helpMsg: `Writes a report of Delve's internal state at the current stop, to attach to bug reports.
+
+	diagnostics [<output file>]
+
+The report is written in JSON, to standard output if no file is specified. It contains the raw registers of the current thread, the call frame information (see frame -debug) of the top frames of the current goroutine, the expressions recently evaluated and, for the gdbserial backend, the packets recently exchanged with the debugging stub. The contents of memory are omitted from the packets and the home directory and name of the user are removed from paths, review the report anyway before making it public.`},
+		{aliases: []string{"summary"}, cmdFn: summaryCommand, helpMsg: `Prints a one-page report of the current stop, to paste into bug reports.
+
+	summary [<output file>]
+
+The report contains the reason the target stopped, the top frames of the current goroutine, the number of goroutines in each state, the breakpoints hit at the last few stops, the display expressions whose value changed at the last stop and the warnings about suspicious runtime states (see the check-bug-patterns configuration option). It is written to standard output if no file is specified.`},
 	}
 
 	addrecorded := client == nil
@@ -716,6 +920,127 @@ func threads(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func sched(t *Term, ctx callContext, args string) error {
+	st, err := t.client.SchedState()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Ps: %d (idle: %d) Ms: %d (idle: %d, spinning: %d)\n", len(st.Ps), st.NPIdle, len(st.Ms), st.NMIdle, st.NMSpinning)
+	fmt.Printf("Global run queue: %s\n", formatGoroutineIDs(st.GlobalRunq))
+	for _, p := range st.Ps {
+		fmt.Printf("P %d %s", p.ID, schedPStatusString(p.Status))
+		if p.MID >= 0 {
+			fmt.Printf(" M %d", p.MID)
+		}
+		if p.Runnext != 0 {
+			fmt.Printf(" runnext %d", p.Runnext)
+		}
+		fmt.Printf(" runq: %s schedtick %d syscalltick %d\n", formatGoroutineIDs(p.Runq), p.Schedtick, p.Syscalltick)
+	}
+	for _, m := range st.Ms {
+		fmt.Printf("M %d thread %d", m.ID, m.ThreadID)
+		if m.PID >= 0 {
+			fmt.Printf(" P %d", m.PID)
+		} else if m.OldPID >= 0 {
+			fmt.Printf(" oldP %d", m.OldPID)
+		}
+		if m.CurG != 0 {
+			fmt.Printf(" curg %d", m.CurG)
+		}
+		if m.LockedG != 0 {
+			fmt.Printf(" lockedg %d", m.LockedG)
+		}
+		if m.InSyscall {
+			fmt.Printf(" (syscall)")
+		}
+		if m.Spinning {
+			fmt.Printf(" (spinning)")
+		}
+		if m.Blocked {
+			fmt.Printf(" (blocked)")
+		}
+		fmt.Println()
+	}
+	return nil
+}
+
+func schedPStatusString(status uint64) string {
+	switch status {
+	case api.SchedPIdle:
+		return "idle"
+	case api.SchedPRunning:
+		return "running"
+	case api.SchedPSyscall:
+		return "syscall"
+	case api.SchedPGCStop:
+		return "gcstop"
+	case api.SchedPDead:
+		return "dead"
+	default:
+		return fmt.Sprintf("status(%d)", status)
+	}
+}
+
+func formatGoroutineIDs(ids []int) string {
+	if len(ids) == 0 {
+		return "[]"
+	}
+	var buf strings.Builder
+	buf.WriteByte('[')
+	for i, id := range ids {
+		if i != 0 {
+			buf.WriteByte(' ')
+		}
+		fmt.Fprintf(&buf, "%d", id)
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+func contention(t *Term, ctx callContext, argstr string) error {
+	kind := api.MutexContentionProfile
+	max := 10
+	args := strings.Fields(argstr)
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-block":
+			kind = api.BlockContentionProfile
+		case "-n":
+			i++
+			if i >= len(args) {
+				return errors.New("expected number after -n")
+			}
+			n, err := strconv.Atoi(args[i])
+			if err != nil {
+				return fmt.Errorf("expected number after -n: %v", err)
+			}
+			max = n
+		default:
+			return fmt.Errorf("wrong argument: '%s'", args[i])
+		}
+	}
+	prof, err := t.client.ContentionProfile(kind, max)
+	if err != nil {
+		return err
+	}
+	name, enable := "mutex", "runtime.SetMutexProfileFraction"
+	if kind == api.BlockContentionProfile {
+		name, enable = "block", "runtime.SetBlockProfileRate"
+	}
+	if prof.Rate == 0 && prof.Total == 0 {
+		fmt.Printf("The %s profile is disabled, the target program must call %s to enable it.\n", name, enable)
+		return nil
+	}
+	fmt.Printf("Showing %d of %d call sites of the %s profile (rate %d)\n", len(prof.Records), prof.Total, name, prof.Rate)
+	for i, rec := range prof.Records {
+		fmt.Printf("%d. %d cycles, %d events\n", i+1, rec.Cycles, rec.Count)
+		for _, loc := range rec.Stack {
+			fmt.Printf("\t%#x in %s\n\t\tat %s:%d\n", loc.PC, loc.Function.Name(), t.formatPath(loc.File), loc.Line)
+		}
+	}
+	return nil
+}
+
 func thread(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("you must specify a thread")
@@ -745,11 +1070,67 @@ func thread(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func target(t *Term, ctx callContext, args string) error {
+	v := strings.Fields(args)
+	if len(v) == 0 {
+		v = []string{"list"}
+	}
+	targets, err := t.client.ListTargets()
+	if err != nil {
+		return err
+	}
+	switch v[0] {
+	case "list":
+		for _, tgt := range targets {
+			prefix := "  "
+			if tgt.Current {
+				prefix = "* "
+			}
+			fmt.Printf("%sProcess %d %s", prefix, tgt.Pid, tgt.Path)
+			if tgt.ParentPid != 0 {
+				fmt.Printf(" (child of %d)", tgt.ParentPid)
+			}
+			fmt.Printf(" [%s]\n", tgt.StopReason)
+		}
+		return nil
+	case "switch":
+		if len(v) != 2 {
+			return errors.New("you must specify a process id")
+		}
+		pid, err := strconv.Atoi(v[1])
+		if err != nil {
+			return err
+		}
+		for _, tgt := range targets {
+			if tgt.Pid != pid {
+				continue
+			}
+			if tgt.CurrentThread == nil {
+				return fmt.Errorf("process %d has no current thread", pid)
+			}
+			if _, err := t.client.SwitchThread(tgt.CurrentThread.ID); err != nil {
+				return err
+			}
+			fmt.Printf("Switched to process %d\n", pid)
+			return nil
+		}
+		return fmt.Errorf("could not find process %d", pid)
+	default:
+		return fmt.Errorf("unknown subcommand %q", v[0])
+	}
+}
+
 type byGoroutineID []*api.Goroutine
 
-func (a byGoroutineID) Len() int           { return len(a) }
-func (a byGoroutineID) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
-func (a byGoroutineID) Less(i, j int) bool { return a[i].ID < a[j].ID }
+func (a byGoroutineID) Len() int      { return len(a) }
+func (a byGoroutineID) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a byGoroutineID) Less(i, j int) bool {
+	// named goroutines are pinned at the start of the list
+	if (a[i].Name != "") != (a[j].Name != "") {
+		return a[i].Name != ""
+	}
+	return a[i].ID < a[j].ID
+}
 
 // The number of goroutines we're going to request on each RPC call
 const goroutineBatchSize = 10000
@@ -767,7 +1148,14 @@ func printGoroutines(t *Term, indent string, gs []*api.Goroutine, fgl formatGoro
 		if state.SelectedGoroutine != nil && g.ID == state.SelectedGoroutine.ID {
 			prefix = indent + "* "
 		}
-		fmt.Printf("%sGoroutine %s\n", prefix, t.formatGoroutine(g, fgl))
+		var isNew string
+		if g.New {
+			isNew = " (new)"
+		}
+		fmt.Printf("%sGoroutine %s%s\n", prefix, t.formatGoroutine(g, fgl), isNew)
+		if g.Note != "" {
+			fmt.Printf("%s\tnote: %s\n", indent, g.Note)
+		}
 		if flags&printGoroutinesLabels != 0 {
 			writeGoroutineLabels(os.Stdout, g, indent+"\t")
 		}
@@ -838,6 +1226,17 @@ func goroutines(t *Term, ctx callContext, argstr string) error {
 			filter.Negated = true
 			filters = append(filters, *filter)
 
+		case "-grep", "-grepargs":
+			if i+1 >= len(args) || args[i+1] == "" {
+				return fmt.Errorf("%s must be followed by a regular expression", arg)
+			}
+			filter := api.ListGoroutinesFilter{Kind: api.GoroutineStack, Arg: args[i+1]}
+			if arg == "-grepargs" {
+				filter.Kind = api.GoroutineStackArgs
+			}
+			filters = append(filters, filter)
+			i++
+
 		case "-group":
 			var err error
 			group.GroupBy, err = readGoroutinesFilterKind(args, i+1)
@@ -908,10 +1307,130 @@ func goroutines(t *Term, ctx callContext, argstr string) error {
 	}
 	if gslen > 0 {
 		fmt.Printf("[%d goroutines]\n", gslen)
+		terminated, err := t.client.ListTerminatedGoroutines()
+		if err != nil {
+			return err
+		}
+		if len(terminated) > 0 {
+			fmt.Printf("Terminated since the previous stop:")
+			for _, g := range terminated {
+				fmt.Printf(" %s", formatGoroutineIDAndName(g.ID, g.Name))
+			}
+			fmt.Printf("\n")
+		}
+	}
+	return nil
+}
+
+func formatGoroutineIDAndName(id int, name string) string {
+	if name != "" {
+		return fmt.Sprintf("%d (%s)", id, name)
+	}
+	return strconv.Itoa(id)
+}
+
+func noteCommand(t *Term, ctx callContext, argstr string) error {
+	args := strings.Fields(argstr)
+	if len(args) == 0 {
+		return printNotes(t)
+	}
+	if len(args) < 2 {
+		return errors.New("not enough arguments")
+	}
+	switch args[0] {
+	case "-g":
+		gid, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid goroutine id %q", args[1])
+		}
+		a := api.Annotation{GoroutineID: gid}
+		anns, err := t.client.ListAnnotations()
+		if err != nil {
+			return err
+		}
+		for _, old := range anns {
+			if old.GoroutineID == gid {
+				a.Name = old.Name
+			}
+		}
+		a.Note = strings.Join(args[2:], " ")
+		return t.client.SetAnnotation(a)
+	case "-b":
+		bp, err := getBreakpointByIDOrName(t, args[1])
+		if err != nil {
+			return err
+		}
+		bp.Note = strings.Join(args[2:], " ")
+		return t.client.AmendBreakpoint(bp)
+	case "-a":
+		addr, err := strconv.ParseUint(args[1], 0, 64)
+		if err != nil {
+			return fmt.Errorf("invalid address %q", args[1])
+		}
+		a := api.Annotation{Addr: addr}
+		args = args[2:]
+		for len(args) >= 2 && (args[0] == "-size" || args[0] == "-name") {
+			if args[0] == "-size" {
+				a.Size, err = strconv.ParseUint(args[1], 0, 64)
+				if err != nil {
+					return fmt.Errorf("invalid size %q", args[1])
+				}
+			} else {
+				a.Name = args[1]
+			}
+			args = args[2:]
+		}
+		a.Note = strings.Join(args, " ")
+		return t.client.SetAnnotation(a)
+	default:
+		return fmt.Errorf("wrong argument: '%s'", args[0])
+	}
+}
+
+func printNotes(t *Term) error {
+	anns, err := t.client.ListAnnotations()
+	if err != nil {
+		return err
+	}
+	for _, a := range anns {
+		if a.GoroutineID != 0 {
+			if a.Note == "" {
+				continue
+			}
+			fmt.Printf("Goroutine %s: %s\n", formatGoroutineIDAndName(a.GoroutineID, a.Name), a.Note)
+			continue
+		}
+		fmt.Printf("%#x-%#x %s\n", a.Addr, a.Addr+a.Size, formatAnnotation(a))
+	}
+	bps, err := t.client.ListBreakpoints()
+	if err != nil {
+		return err
+	}
+	sort.Sort(byID(bps))
+	for _, bp := range bps {
+		if bp.Note != "" {
+			fmt.Printf("%s: %s\n", formatBreakpointName(bp, true), bp.Note)
+		}
 	}
 	return nil
 }
 
+func goroutineName(t *Term, ctx callContext, argstr string) error {
+	args := strings.SplitN(strings.TrimSpace(argstr), " ", 2)
+	if args[0] == "" {
+		return errors.New("not enough arguments")
+	}
+	gid, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid goroutine id %q", args[0])
+	}
+	var name string
+	if len(args) > 1 {
+		name = strings.TrimSpace(args[1])
+	}
+	return t.client.SetGoroutineName(gid, name)
+}
+
 func readGoroutinesFilterKind(args []string, i int) (api.GoroutineField, error) {
 	if i >= len(args) {
 		return api.GoroutineFieldNone, fmt.Errorf("%s must be followed by an argument", args[i-1])
@@ -932,6 +1451,8 @@ func readGoroutinesFilterKind(args []string, i int) (api.GoroutineField, error)
 		return api.GoroutineRunning, nil
 	case "user":
 		return api.GoroutineUser, nil
+	case "stack":
+		return api.GoroutineStack, nil
 	default:
 		return api.GoroutineFieldNone, fmt.Errorf("unrecognized argument to %s %s", args[i-1], args[i])
 	}
@@ -1064,6 +1585,66 @@ func (c *Commands) frameCommand(t *Term, ctx callContext, argstr string, directi
 	return nil
 }
 
+// frameDebugCommand implements "frame -debug".
+func (c *Commands) frameDebugCommand(t *Term, ctx callContext, argstr string) error {
+	if argstr != "" {
+		frame, err := strconv.Atoi(argstr)
+		if err != nil {
+			return err
+		}
+		if frame < 0 {
+			return fmt.Errorf("Invalid frame %d", frame)
+		}
+		ctx.Scope.Frame = frame
+	}
+	info, err := t.client.FrameUnwindInfo(ctx.Scope)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Frame %d (PC: %#x)\n", ctx.Scope.Frame, info.PC)
+	if info.FDEStart == 0 && info.FDEEnd == 0 {
+		fmt.Printf("FDE: none, using architecture specific rules\n")
+	} else {
+		fmt.Printf("FDE: %#x-%#x\n", info.FDEStart, info.FDEEnd)
+	}
+	fmt.Printf("CFA: %s = %#x\n", info.CFA, info.CFAValue)
+	fmt.Printf("Return address: %s\n", info.RetAddrReg)
+	maxlen := 0
+	for _, rule := range info.Regs {
+		if len(rule.Name) > maxlen {
+			maxlen = len(rule.Name)
+		}
+	}
+	for _, rule := range info.Regs {
+		fmt.Printf("\t%-*s %s\n", maxlen, rule.Name, rule.Rule)
+	}
+
+	for _, kind := range []string{"Arguments", "Locals"} {
+		var vars []api.Variable
+		if kind == "Arguments" {
+			vars, err = t.client.ListFunctionArgs(ctx.Scope, ShortLoadConfig)
+		} else {
+			vars, err = t.client.ListLocalVariables(ctx.Scope, ShortLoadConfig)
+		}
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s:\n", kind)
+		if len(vars) == 0 {
+			fmt.Printf("\t(none)\n")
+		}
+		for _, v := range vars {
+			fmt.Printf("\t%s = %s\n", v.Name, v.SinglelineString())
+			location := v.LocationExpr
+			if location == "" {
+				location = "(none)"
+			}
+			fmt.Printf("\t\tlocation: %s\n", location)
+		}
+	}
+	return nil
+}
+
 func (c *Commands) deferredCommand(t *Term, ctx callContext, argstr string) error {
 	ctx.Prefix = deferredPrefix
 
@@ -1138,19 +1719,13 @@ func (t *Term) formatGoroutine(g *api.Goroutine, fgl formatGoroutineLoc) string
 	}
 
 	buf := new(strings.Builder)
-	fmt.Fprintf(buf, "%d - %s: %s", g.ID, locname, t.formatLocation(loc))
+	fmt.Fprintf(buf, "%s - %s: %s", formatGoroutineIDAndName(g.ID, g.Name), locname, t.formatLocation(loc))
 	if g.ThreadID != 0 {
 		fmt.Fprintf(buf, " (thread %d)", g.ThreadID)
 	}
 
 	if (g.Status == api.GoroutineWaiting || g.Status == api.GoroutineSyscall) && g.WaitReason != 0 {
-		var wr string
-		if g.WaitReason > 0 && g.WaitReason < int64(len(waitReasonStrings)) {
-			wr = waitReasonStrings[g.WaitReason]
-		} else {
-			wr = fmt.Sprintf("unknown wait reason %d", g.WaitReason)
-		}
-		fmt.Fprintf(buf, " [%s", wr)
+		fmt.Fprintf(buf, " [%s", api.WaitReasonString(g.WaitReason))
 		if g.WaitSince > 0 {
 			fmt.Fprintf(buf, " %s", time.Since(time.Unix(0, g.WaitSince)).String())
 		}
@@ -1160,36 +1735,6 @@ func (t *Term) formatGoroutine(g *api.Goroutine, fgl formatGoroutineLoc) string
 	return buf.String()
 }
 
-var waitReasonStrings = [...]string{
-	"",
-	"GC assist marking",
-	"IO wait",
-	"chan receive (nil chan)",
-	"chan send (nil chan)",
-	"dumping heap",
-	"garbage collection",
-	"garbage collection scan",
-	"panicwait",
-	"select",
-	"select (no cases)",
-	"GC assist wait",
-	"GC sweep wait",
-	"GC scavenge wait",
-	"chan receive",
-	"chan send",
-	"finalizer wait",
-	"force gc (idle)",
-	"semacquire",
-	"sleep",
-	"sync.Cond.Wait",
-	"timer goroutine (idle)",
-	"trace reader (blocked)",
-	"wait for GC cycle",
-	"GC worker (idle)",
-	"preempted",
-	"debug call",
-}
-
 func writeGoroutineLong(t *Term, w io.Writer, g *api.Goroutine, prefix string) {
 	fmt.Fprintf(w, "%sGoroutine %d:\n%s\tRuntime: %s\n%s\tUser: %s\n%s\tGo: %s\n%s\tStart: %s\n",
 		prefix, g.ID,
@@ -1420,6 +1965,9 @@ func (c *Commands) cont(t *Term, ctx callContext, args string) error {
 	for state = range stateChan {
 		if state.Err != nil {
 			printcontextNoState(t)
+			if state.Exited {
+				printAggregatedStacks(t)
+			}
 			return state.Err
 		}
 		printcontext(t, state)
@@ -1428,6 +1976,38 @@ func (c *Commands) cont(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+// printAggregatedStacks prints the stacks recorded by tracepoints created
+// with 'trace -aggregate'.
+func printAggregatedStacks(t *Term) {
+	bps, err := t.client.ListBreakpoints()
+	if err != nil {
+		return
+	}
+	for _, bp := range bps {
+		if !bp.AggregateStacks {
+			continue
+		}
+		stacks, err := t.client.GetBreakpointStacks(bp.ID)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not read stacks of %s: %v\n", formatBreakpointName(bp, false), err)
+			continue
+		}
+		fmt.Printf("%s at %s reached %d times by %d stacks:\n", formatBreakpointName(bp, true), t.formatBreakpointLocation(bp), bp.TotalHitCount, len(stacks))
+		printStackRecords(t, stacks, "calls")
+	}
+}
+
+// printStackRecords prints the stacks recorded by a breakpoint with
+// AggregateStacks set, what is the unit of the counts.
+func printStackRecords(t *Term, stacks []api.StackRecord, what string) {
+	for i, rec := range stacks {
+		fmt.Printf("%d. %d %s\n", i+1, rec.Count, what)
+		for _, loc := range rec.Stack {
+			fmt.Printf("\t%#x in %s\n\t\tat %s:%d\n", loc.PC, loc.Function.Name(), t.formatPath(loc.File), loc.Line)
+		}
+	}
+}
+
 func continueUntilCompleteNext(t *Term, state *api.DebuggerState, op string, shouldPrintFile bool) error {
 	defer t.onStop()
 	if !state.NextInProgress {
@@ -1591,6 +2171,9 @@ func (c *Commands) call(t *Term, ctx callContext, args string) error {
 	if err := scopePrefixSwitch(t, ctx); err != nil {
 		return err
 	}
+	if strings.TrimSpace(args) == "-cancel" {
+		return t.client.CancelCall(ctx.Scope.GoroutineID)
+	}
 	const unsafePrefix = "-unsafe "
 	unsafe := false
 	if strings.HasPrefix(args, unsafePrefix) {
@@ -1604,9 +2187,23 @@ func (c *Commands) call(t *Term, ctx callContext, args string) error {
 		return err
 	}
 	printcontext(t, state)
+	if !callReturned(state) {
+		fmt.Println("The function call has not returned yet. If it is blocked use 'call -cancel' to abandon it.")
+	}
 	return continueUntilCompleteNext(t, state, "call", true)
 }
 
+// callReturned returns true if one of the threads in state has the return
+// values of an injected function call.
+func callReturned(state *api.DebuggerState) bool {
+	for _, th := range state.Threads {
+		if th.CallReturn {
+			return true
+		}
+	}
+	return false
+}
+
 func clear(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("not enough arguments")
@@ -1684,6 +2281,26 @@ func toggle(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func hitsCmd(t *Term, ctx callContext, argstr string) error {
+	args := split2PartsBySpace(argstr)
+	if len(args) < 2 {
+		return errors.New("not enough arguments")
+	}
+	bp, err := getBreakpointByIDOrName(t, args[0])
+	if err != nil {
+		return err
+	}
+	switch args[1] {
+	case "report":
+		bp.SerializeHits = false
+	case "serialize":
+		bp.SerializeHits = true
+	default:
+		return fmt.Errorf("unknown policy %q, expected report or serialize", args[1])
+	}
+	return t.client.AmendBreakpoint(bp)
+}
+
 // byID sorts breakpoints by ID.
 type byID []*api.Breakpoint
 
@@ -1707,9 +2324,20 @@ func breakpoints(t *Term, ctx callContext, args string) error {
 		if bp.HitCond != "" {
 			attrs = append(attrs, fmt.Sprintf("\tcond -hitcount %s", bp.HitCond))
 		}
-		if bp.Stacktrace > 0 {
+		if bp.Stacktrace == api.StacktraceAll {
+			attrs = append(attrs, "\tstack all")
+		} else if bp.Stacktrace > 0 {
 			attrs = append(attrs, fmt.Sprintf("\tstack %d", bp.Stacktrace))
 		}
+		if bp.AggregateStacks {
+			attrs = append(attrs, "\taggregate")
+		}
+		if bp.SerializeHits {
+			attrs = append(attrs, "\thits serialize")
+		}
+		if bp.Note != "" {
+			attrs = append(attrs, fmt.Sprintf("\tnote %s", bp.Note))
+		}
 		if bp.Goroutine {
 			attrs = append(attrs, "\tgoroutine")
 		}
@@ -1738,9 +2366,18 @@ func breakpoints(t *Term, ctx callContext, args string) error {
 }
 
 func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) ([]*api.Breakpoint, error) {
-	args := split2PartsBySpace(argstr)
-
 	requestedBp := &api.Breakpoint{}
+	if tracepoint {
+		var err error
+		var opts tracepointOptions
+		opts, argstr, err = parseTracepointOptions(argstr)
+		if err != nil {
+			return nil, err
+		}
+		requestedBp.Stacktrace, requestedBp.AggregateStacks = opts.depth, opts.aggregate
+	}
+
+	args := split2PartsBySpace(argstr)
 	spec := ""
 	switch len(args) {
 	case 1:
@@ -1798,7 +2435,7 @@ func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) ([]
 	case *locspec.RegexLocationSpec:
 		shouldSetReturnBreakpoints = true
 	}
-	if tracepoint && shouldSetReturnBreakpoints && locs[0].Function != nil {
+	if tracepoint && shouldSetReturnBreakpoints && !requestedBp.AggregateStacks && locs[0].Function != nil {
 		for i := range locs {
 			if locs[i].Function == nil {
 				continue
@@ -1811,6 +2448,7 @@ func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) ([]
 				_, err = t.client.CreateBreakpoint(&api.Breakpoint{
 					Addr:        addrs[j],
 					TraceReturn: true,
+					Stacktrace:  requestedBp.Stacktrace,
 					Line:        -1,
 					LoadArgs:    &ShortLoadConfig,
 				})
@@ -1823,13 +2461,67 @@ func setBreakpoint(t *Term, ctx callContext, tracepoint bool, argstr string) ([]
 	return created, nil
 }
 
+// tracepointOptions are the options of the trace command.
+type tracepointOptions struct {
+	depth     int    // number of stack frames to record
+	aggregate bool   // whether stacks should be aggregated
+	preset    string // name of the preset to install, see tracePresets
+}
+
+// parseTracepointOptions parses the -stack, -aggregate and -preset options
+// at the start of the arguments of the trace command, it returns the
+// options and the remaining arguments.
+func parseTracepointOptions(argstr string) (opts tracepointOptions, rest string, err error) {
+	for {
+		args := split2PartsBySpace(argstr)
+		switch args[0] {
+		case "-aggregate":
+			opts.aggregate = true
+		case "-stack":
+			if len(args) < 2 || args[1] == "" {
+				return tracepointOptions{}, "", errors.New("expected depth after -stack")
+			}
+			args = split2PartsBySpace(args[1])
+			opts.depth = api.StacktraceAll
+			if args[0] != "all" {
+				opts.depth, err = strconv.Atoi(args[0])
+				if err != nil || opts.depth <= 0 {
+					return tracepointOptions{}, "", fmt.Errorf("expected positive number or 'all' after -stack: %q", args[0])
+				}
+			}
+		case "-preset", "--preset":
+			if len(args) < 2 || args[1] == "" {
+				return tracepointOptions{}, "", errors.New("expected preset name after -preset")
+			}
+			args = split2PartsBySpace(args[1])
+			opts.preset = args[0]
+		default:
+			return opts, argstr, nil
+		}
+		if len(args) < 2 {
+			return opts, "", nil
+		}
+		argstr = args[1]
+	}
+}
+
 func breakpoint(t *Term, ctx callContext, args string) error {
 	_, err := setBreakpoint(t, ctx, false, args)
 	return err
 }
 
 func tracepoint(t *Term, ctx callContext, args string) error {
-	_, err := setBreakpoint(t, ctx, true, args)
+	opts, rest, err := parseTracepointOptions(args)
+	if err != nil {
+		return err
+	}
+	if opts.preset != "" {
+		if rest != "" {
+			return errors.New("a location can not be specified with -preset")
+		}
+		return setPresetTracepoints(t, ctx, opts)
+	}
+	_, err = setBreakpoint(t, ctx, true, args)
 	return err
 }
 
@@ -2026,6 +2718,12 @@ func printVar(t *Term, ctx callContext, args string) error {
 		ctx.Breakpoint.Variables = append(ctx.Breakpoint.Variables, args)
 		return nil
 	}
+	if strings.HasPrefix(args, "-i ") {
+		if ctx.Prefix == deferredPrefix {
+			return errors.New("interactive mode can not be used with deferred")
+		}
+		return exploreVariable(t, ctx, strings.TrimSpace(args[len("-i "):]))
+	}
 	fmtstr, args := parseFormatArg(args)
 	val, err := t.client.EvalVariable(ctx.Scope, args, t.loadConfig())
 	if err != nil {
@@ -2036,6 +2734,19 @@ func printVar(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func saveVar(t *Term, ctx callContext, args string) error {
+	v := split2PartsBySpace(args)
+	if len(v) != 2 {
+		return errors.New("wrong number of arguments: savevar <file> <expression>")
+	}
+	n, err := t.client.SaveVariable(ctx.Scope, v[1], v[0])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.stdout, "Wrote %d bytes to %s\n", n, v[0])
+	return nil
+}
+
 func whatisCommand(t *Term, ctx callContext, args string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("not enough arguments")
@@ -2063,6 +2774,135 @@ func whatisCommand(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func explainCommand(t *Term, ctx callContext, args string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("not enough arguments")
+	}
+	p, err := t.client.ValueProvenance(ctx.Scope, args, ShortLoadConfig, t.disassembleFlavour())
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s = %s\n", p.Variable.Name, p.Variable.SinglelineString())
+	if len(p.Storage) > 0 {
+		fmt.Printf("stored in: %s\n", strings.Join(p.Storage, ", "))
+	}
+	if p.Writer == nil {
+		fmt.Printf("could not find the instruction that wrote %s\n", p.Variable.Name)
+		return nil
+	}
+	stmt := "earlier statement"
+	if p.InStatement {
+		stmt = "current statement"
+	}
+	fmt.Printf("written at %s:%d (%s) by:\n", t.formatPath(p.Writer.Loc.File), p.Writer.Loc.Line, stmt)
+	fmt.Printf("\t%#x\t%s\n", p.Writer.Loc.PC, p.Writer.Text)
+	fmt.Printf("value: %s\n", p.Source)
+	return nil
+}
+
+// parseWritersOptions parses the -static and -window options at the start
+// of the arguments of the writers command, it returns the options and the
+// remaining arguments.
+func parseWritersOptions(argstr string) (static bool, window time.Duration, rest string, err error) {
+	for {
+		args := split2PartsBySpace(argstr)
+		switch args[0] {
+		case "-static":
+			static = true
+		case "-window":
+			if len(args) < 2 || args[1] == "" {
+				return false, 0, "", errors.New("expected duration after -window")
+			}
+			args = split2PartsBySpace(args[1])
+			window, err = time.ParseDuration(args[0])
+			if err != nil || window <= 0 {
+				return false, 0, "", fmt.Errorf("expected positive duration after -window: %q", args[0])
+			}
+		default:
+			return static, window, argstr, nil
+		}
+		if len(args) < 2 {
+			return static, window, "", nil
+		}
+		argstr = args[1]
+	}
+}
+
+func writersCommand(t *Term, ctx callContext, argstr string) error {
+	static, window, argstr, err := parseWritersOptions(argstr)
+	if err != nil {
+		return err
+	}
+	expr := strings.TrimSpace(argstr)
+	if expr == "" {
+		return errors.New("not enough arguments")
+	}
+
+	refs, err := t.client.ListVariableReferences(ctx.Scope, expr, t.disassembleFlavour())
+	if err != nil {
+		if static {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "could not find the instructions writing to %s: %v\n", expr, err)
+	} else {
+		fmt.Printf("Instructions writing to %s:\n", expr)
+		if len(refs) == 0 {
+			fmt.Println("\t(none)")
+		}
+		for _, ref := range refs {
+			note := ""
+			if ref.Kind == api.VariableAddressTaken {
+				note = " (address taken)"
+			}
+			fmt.Printf("\t%#x in %s at %s:%d\n\t\t%s%s\n", ref.Inst.Loc.PC, ref.Inst.Loc.Function.Name(), t.formatPath(ref.Inst.Loc.File), ref.Inst.Loc.Line, ref.Inst.Text, note)
+		}
+	}
+	if static {
+		return nil
+	}
+
+	bp, err := t.client.CreateWatchpoint(ctx.Scope, expr, api.WatchWrite)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if _, err := t.client.ClearBreakpoint(bp.ID); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to clear watchpoint %d: %v\n", bp.ID, err)
+		}
+	}()
+	bp.AggregateStacks = true
+	if err := t.client.AmendBreakpoint(bp); err != nil {
+		return err
+	}
+
+	if window > 0 {
+		timer := time.AfterFunc(window, func() { t.client.Halt() })
+		defer timer.Stop()
+	}
+	defer t.onStop()
+	var state *api.DebuggerState
+	for state = range t.client.Continue() {
+		if state.Err != nil {
+			fmt.Fprintln(os.Stderr, state.Err)
+			break
+		}
+	}
+
+	stacks, err := t.client.GetBreakpointStacks(bp.ID)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Writes to %s observed while the target was running:\n", expr)
+	if len(stacks) == 0 {
+		fmt.Println("\t(none)")
+	}
+	printStackRecords(t, stacks, "writes")
+	if state != nil && state.Err == nil && !state.Exited {
+		printcontext(t, state)
+	}
+	return nil
+}
+
 func setVar(t *Term, ctx callContext, args string) error {
 	// HACK: in go '=' is not an operator, we detect the error and try to recover from it by splitting the input string
 	_, err := parser.ParseExpr(args)
@@ -2080,6 +2920,43 @@ func setVar(t *Term, ctx callContext, args string) error {
 	return t.client.SetVariable(ctx.Scope, lexpr, rexpr)
 }
 
+func memoryWrites(t *Term, ctx callContext, args string) error {
+	writes, err := t.client.ListMemoryWrites()
+	if err != nil {
+		return err
+	}
+	if len(writes) == 0 {
+		fmt.Fprintln(t.stdout, "No memory writes.")
+		return nil
+	}
+	for _, w := range writes {
+		old := "?"
+		if w.Old != nil {
+			old = hex.EncodeToString(w.Old)
+		}
+		fmt.Fprintf(t.stdout, "Write %d at %#x: %s -> %s", w.ID, w.Addr, old, hex.EncodeToString(w.New))
+		if w.Origin != "" {
+			fmt.Fprintf(t.stdout, " (%s)", w.Origin)
+		}
+		if w.Client != "" {
+			fmt.Fprintf(t.stdout, " by %s", w.Client)
+		}
+		if w.Undone {
+			fmt.Fprint(t.stdout, " [undone]")
+		}
+		fmt.Fprintln(t.stdout)
+	}
+	return nil
+}
+
+func undoMemoryWrite(t *Term, ctx callContext, args string) error {
+	id, err := strconv.Atoi(strings.TrimSpace(args))
+	if err != nil {
+		return fmt.Errorf("invalid write id %q", args)
+	}
+	return t.client.UndoMemoryWrite(id)
+}
+
 func printFilteredVariables(varType string, vars []api.Variable, filter string, cfg api.LoadConfig) error {
 	reg, err := regexp.Compile(filter)
 	if err != nil {
@@ -2129,6 +3006,70 @@ func types(t *Term, ctx callContext, args string) error {
 	return printSortedStrings(t.client.ListTypes(args))
 }
 
+func layoutCommand(t *Term, ctx callContext, args string) error {
+	if args == "" {
+		return errors.New("not enough arguments")
+	}
+	l, err := t.client.DescribeType(args)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(t.stdout, "%s: size %d, align %d\n", l.Name, l.Size, l.Align)
+	switch {
+	case l.Key != "":
+		fmt.Fprintf(t.stdout, "key %s, elem %s\n", l.Key, l.Elem)
+	case l.Kind == reflect.Array:
+		fmt.Fprintf(t.stdout, "len %d, elem %s\n", l.Len, l.Elem)
+	case l.Elem != "":
+		fmt.Fprintf(t.stdout, "elem %s\n", l.Elem)
+	}
+	if len(l.Fields) == 0 {
+		return nil
+	}
+	w := tabwriter.NewWriter(t.stdout, 0, 8, 1, ' ', 0)
+	fmt.Fprintln(w, "offset\tsize\tfield\ttype")
+	for _, f := range l.Fields {
+		name := f.Name
+		if f.Embedded {
+			name += " (embedded)"
+		}
+		fmt.Fprintf(w, "%d\t%d\t%s\t%s\n", f.Offset, f.Size, name, f.Type)
+	}
+	return w.Flush()
+}
+
+func symbolsCommand(t *Term, ctx callContext, args string) error {
+	kind := ""
+	v := split2PartsBySpace(args)
+	switch v[0] {
+	case "-f":
+		kind = api.SymbolFunction
+	case "-v":
+		kind = api.SymbolVariable
+	}
+	if kind != "" {
+		args = ""
+		if len(v) == 2 {
+			args = v[1]
+		}
+	}
+	syms, err := t.client.ListSymbols(args, kind)
+	if err != nil {
+		return err
+	}
+	sort.Slice(syms, func(i, j int) bool { return syms[i].Addr < syms[j].Addr })
+	w := tabwriter.NewWriter(t.stdout, 0, 8, 1, ' ', 0)
+	for _, sym := range syms {
+		switch sym.Kind {
+		case api.SymbolFunction:
+			fmt.Fprintf(w, "%#x\t%d\tfunc %s\t%s:%d\n", sym.Addr, sym.Size, sym.Name, sym.File, sym.Line)
+		default:
+			fmt.Fprintf(w, "%#x\t%d\tvar %s\t%s\n", sym.Addr, sym.Size, sym.Name, sym.Type)
+		}
+	}
+	return w.Flush()
+}
+
 func parseVarArguments(args string, t *Term) (filter string, cfg api.LoadConfig) {
 	if v := split2PartsBySpace(args); len(v) >= 1 && v[0] == "-v" {
 		if len(v) == 2 {
@@ -2200,7 +3141,27 @@ func regs(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func defersCommand(t *Term, ctx callContext, args string) error {
+	if args != "" {
+		return errors.New("too many arguments")
+	}
+	defers, err := t.client.ListDefers(ctx.Scope.GoroutineID, &ShortLoadConfig)
+	if err != nil {
+		return err
+	}
+	if len(defers) == 0 {
+		fmt.Println("No deferred calls.")
+		return nil
+	}
+	api.PrintDefers(t.formatPath, os.Stdout, defers)
+	return nil
+}
+
 func stackCommand(t *Term, ctx callContext, args string) error {
+	if ctx.Prefix == onPrefix && args == "all" {
+		ctx.Breakpoint.Stacktrace = api.StacktraceAll
+		return nil
+	}
 	sa, err := parseStackArgs(args)
 	if err != nil {
 		return err
@@ -2217,7 +3178,14 @@ func stackCommand(t *Term, ctx callContext, args string) error {
 	if err != nil {
 		return err
 	}
-	printStack(t, os.Stdout, stack, "", sa.offsets)
+	include := func(api.Stackframe) bool { return true }
+	if sa.noinline {
+		// Inlined frames are filtered here, instead of using
+		// api.StacktraceNoInline, so that the printed frame numbers can still
+		// be used with the frame command.
+		include = func(frame api.Stackframe) bool { return !frame.Inlined }
+	}
+	api.PrintStack(t.formatPath, os.Stdout, stack, "", sa.offsets, include)
 	if sa.ancestors > 0 {
 		ancestors, err := t.client.Ancestors(ctx.Scope.GoroutineID, sa.ancestors, sa.ancestorDepth)
 		if err != nil {
@@ -2235,11 +3203,48 @@ func stackCommand(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func goroutineProfile(t *Term, ctx callContext, argstr string) error {
+	depth := 50
+	format := api.GoroutineProfileProto
+	var dest string
+	args := strings.Fields(argstr)
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-folded":
+			format = api.GoroutineProfileFolded
+		case "-depth":
+			i++
+			if i >= len(args) {
+				return errors.New("expected number after -depth")
+			}
+			n, err := strconv.Atoi(args[i])
+			if err != nil {
+				return fmt.Errorf("expected number after -depth: %v", err)
+			}
+			depth = n
+		default:
+			if dest != "" {
+				return errors.New("too many arguments")
+			}
+			dest = args[i]
+		}
+	}
+	if dest == "" {
+		return errors.New("not enough arguments")
+	}
+	data, err := t.client.GoroutineProfile(depth, format)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(dest, data, 0644)
+}
+
 type stackArgs struct {
-	depth   int
-	full    bool
-	offsets bool
-	opts    api.StacktraceOptions
+	depth    int
+	full     bool
+	offsets  bool
+	noinline bool
+	opts     api.StacktraceOptions
 
 	ancestors     int
 	ancestorDepth int
@@ -2271,6 +3276,8 @@ func parseStackArgs(argstr string) (stackArgs, error) {
 				r.offsets = true
 			case "-defer":
 				r.opts |= api.StacktraceReadDefers
+			case "-noinline":
+				r.noinline = true
 			case "-mode":
 				i++
 				if i >= len(args) {
@@ -2400,6 +3407,20 @@ func (c *Commands) sourceCommand(t *Term, ctx callContext, args string) error {
 
 var disasmUsageError = errors.New("wrong number of arguments: disassemble [-a <start> <end>] [-l <locspec>]")
 
+// disassembleFlavour returns the assembly syntax selected by the
+// disassemble-flavor configuration option.
+func (t *Term) disassembleFlavour() api.AssemblyFlavour {
+	if t.conf != nil && t.conf.DisassembleFlavor != nil {
+		switch *t.conf.DisassembleFlavor {
+		case "go":
+			return api.GoFlavour
+		case "gnu":
+			return api.GNUFlavour
+		}
+	}
+	return api.IntelFlavour
+}
+
 func disassCommand(t *Term, ctx callContext, args string) error {
 	var cmd, rest string
 
@@ -2412,17 +3433,7 @@ func disassCommand(t *Term, ctx callContext, args string) error {
 		rest = argv[1]
 	}
 
-	flavor := api.IntelFlavour
-	if t.conf != nil && t.conf.DisassembleFlavor != nil {
-		switch *t.conf.DisassembleFlavor {
-		case "go":
-			flavor = api.GoFlavour
-		case "gnu":
-			flavor = api.GNUFlavour
-		default:
-			flavor = api.IntelFlavour
-		}
-	}
+	flavor := t.disassembleFlavour()
 
 	var disasm api.AsmInstructions
 	var disasmErr error
@@ -2465,7 +3476,12 @@ func disassCommand(t *Term, ctx callContext, args string) error {
 		return disasmErr
 	}
 
-	disasmPrint(disasm, os.Stdout)
+	annotations, err := t.client.ListAnnotations()
+	if err != nil {
+		return err
+	}
+
+	disasmPrint(disasm, annotations, os.Stdout)
 
 	return nil
 }
@@ -2535,6 +3551,46 @@ func printcontext(t *Term, state *api.DebuggerState) {
 	if state.When != "" {
 		fmt.Println(state.When)
 	}
+
+	if state.PendingHits > 0 {
+		fmt.Printf("%d more breakpoint hits will be reported by the next continue commands\n", state.PendingHits)
+	}
+
+	if th.Breakpoint != nil && (th.Breakpoint.Name == api.UnrecoveredPanicBreakpointName || th.Breakpoint.Name == api.FatalThrowBreakpointName) {
+		printGoroutineStackGroups(t, state)
+	}
+}
+
+const maxCrashGoroutineGroups = 10
+
+// printGoroutineStackGroups prints the goroutines of the target grouped
+// by identical stacks, so that when a target with thousands of goroutines
+// crashes what they were doing can be seen at a glance.
+func printGoroutineStackGroups(t *Term, state *api.DebuggerState) {
+	group := &api.GoroutineGroupingOptions{GroupBy: api.GoroutineStack, MaxGroupMembers: maxGroupMembers, MaxGroups: maxCrashGoroutineGroups}
+	gs, groups, _, tooManyGroups, err := t.client.ListGoroutinesWithFilter(0, 0, nil, group)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Could not list goroutines: %v\n", err)
+		return
+	}
+	fmt.Println("\nGoroutines grouped by stack:")
+	for i := range groups {
+		ids := make([]string, groups[i].Count)
+		for j, g := range gs[groups[i].Offset:][:groups[i].Count] {
+			ids[j] = strconv.Itoa(g.ID)
+		}
+		more := ""
+		if groups[i].Total > groups[i].Count {
+			more = " ..."
+		}
+		fmt.Printf("%d goroutines [%s%s]:\n", groups[i].Total, strings.Join(ids, " "), more)
+		for _, frame := range strings.Split(groups[i].Name, "\n") {
+			fmt.Printf("\t%s\n", frame)
+		}
+	}
+	if tooManyGroups {
+		fmt.Printf("Too many groups, use 'goroutines -group stack' to see all of them\n")
+	}
 }
 
 func printcontextLocation(t *Term, loc api.Location) {
@@ -2592,6 +3648,9 @@ func printcontextThread(t *Term, th *api.Thread) {
 	}
 
 	if th.Breakpoint.Tracepoint || th.Breakpoint.TraceReturn {
+		if t.printPresetTracepoint(th) {
+			return
+		}
 		printTracepoint(t, th, bpname, fn, args, hasReturnValue)
 		return
 	}
@@ -2650,6 +3709,14 @@ func printBreakpointInfo(t *Term, th *api.Thread, tracepointOnNewline bool) {
 		writeGoroutineLong(t, os.Stdout, bpi.Goroutine, "\t")
 	}
 
+	if bpi.TestFailure != nil {
+		fmt.Printf("\tTest %s failed", bpi.TestFailure.Test)
+		if bpi.TestFailure.Message != "" {
+			fmt.Printf(": %s", strings.Replace(bpi.TestFailure.Message, "\n", "\n\t\t", -1))
+		}
+		fmt.Println()
+	}
+
 	for _, v := range bpi.Variables {
 		tracepointnl()
 		fmt.Printf("\t%s: %s\n", v.Name, v.MultilineString("\t", ""))
@@ -2714,6 +3781,9 @@ func printfile(t *Term, filename string, line int, showArrow bool) error {
 
 	file, err := os.Open(t.substitutePath(filename))
 	if err != nil {
+		if t.conf.PseudoSource && printPseudoSource(t, filename, line, showArrow) == nil {
+			return nil
+		}
 		return err
 	}
 	defer file.Close()
@@ -2960,6 +4030,60 @@ func dump(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func diagnostics(t *Term, ctx callContext, args string) error {
+	if len(strings.Fields(args)) > 1 {
+		return errors.New("too many arguments")
+	}
+	r, err := t.client.Diagnostics()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "\t")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if args == "" {
+		_, err = t.stdout.Write(data)
+		return err
+	}
+	return ioutil.WriteFile(args, data, 0644)
+}
+
+func download(t *Term, ctx callContext, args string) error {
+	v := strings.Fields(args)
+	switch len(v) {
+	case 0:
+		artifacts, err := t.client.ListArtifacts()
+		if err != nil {
+			return err
+		}
+		if len(artifacts) == 0 {
+			fmt.Println("No files to download")
+		}
+		for _, artifact := range artifacts {
+			fmt.Printf("%s\t%d bytes\tsha256:%s\n", artifact.Path, artifact.Size, artifact.SHA256)
+		}
+		return nil
+	case 1, 2:
+		dest := filepath.Base(v[0])
+		if len(v) == 2 {
+			dest = v[1]
+		}
+		err := t.client.DownloadArtifact(v[0], dest, func(done, total int64) {
+			fmt.Printf("\rDownloading %d / %d...", done, total)
+		})
+		fmt.Printf("\n")
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Downloaded %s to %s\n", v[0], dest)
+		return nil
+	default:
+		return fmt.Errorf("too many arguments")
+	}
+}
+
 func formatBreakpointName(bp *api.Breakpoint, upcase bool) string {
 	thing := "breakpoint"
 	if bp.Tracepoint {
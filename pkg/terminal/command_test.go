@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strconv"
@@ -312,6 +313,147 @@ func TestTraceOnNonFunctionEntry(t *testing.T) {
 	})
 }
 
+func TestTraceStack(t *testing.T) {
+	test.AllowRecording(t)
+	withTestTerminal("issue573", t, func(term *FakeTerminal) {
+		term.MustExec("trace -stack all foobar issue573.go:19")
+		out, _ := term.Exec("continue")
+		if !strings.Contains(out, "> goroutine(1): [foobar] main.foo(99, 9801)") {
+			t.Fatalf("Wrong output for tracepoint: %s", out)
+		}
+		if !strings.Contains(out, "Stack:") || !strings.Contains(out, "in main.main") || !strings.Contains(out, "in runtime.main") {
+			t.Fatalf("Tracepoint output does not contain the whole stack:\n%s", out)
+		}
+	})
+}
+
+func TestTraceAggregate(t *testing.T) {
+	test.AllowRecording(t)
+	withTestTerminal("issue573", t, func(term *FakeTerminal) {
+		term.MustExec("trace -aggregate main.foo")
+		out, _ := term.Exec("continue")
+		if strings.Contains(out, "> goroutine(1): main.foo") {
+			t.Fatalf("aggregating tracepoint printed a notification:\n%s", out)
+		}
+		if !strings.Contains(out, "reached 1 times by 1 stacks") || !strings.Contains(out, "1. 1 calls") {
+			t.Fatalf("wrong aggregated stacks report:\n%s", out)
+		}
+		if !strings.Contains(out, "in main.foo") || !strings.Contains(out, "in main.main") {
+			t.Fatalf("aggregated stack does not contain the callers of main.foo:\n%s", out)
+		}
+	})
+}
+
+func TestParseTracepointOptions(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		opts tracepointOptions
+		rest string
+		err  bool
+	}{
+		{"main.foo", tracepointOptions{}, "main.foo", false},
+		{"foobar main.foo", tracepointOptions{}, "foobar main.foo", false},
+		{"-stack 5 main.foo", tracepointOptions{depth: 5}, "main.foo", false},
+		{"-stack all foobar main.foo", tracepointOptions{depth: api.StacktraceAll}, "foobar main.foo", false},
+		{"-aggregate main.foo", tracepointOptions{aggregate: true}, "main.foo", false},
+		{"-stack 10 -aggregate main.foo", tracepointOptions{depth: 10, aggregate: true}, "main.foo", false},
+		{"-aggregate -stack all main.foo", tracepointOptions{depth: api.StacktraceAll, aggregate: true}, "main.foo", false},
+		{"-preset http", tracepointOptions{preset: "http"}, "", false},
+		{"--preset http", tracepointOptions{preset: "http"}, "", false},
+		{"-stack 5 -preset http", tracepointOptions{depth: 5, preset: "http"}, "", false},
+		{"-stack", tracepointOptions{}, "", true},
+		{"-stack 0 main.foo", tracepointOptions{}, "", true},
+		{"-stack main.foo", tracepointOptions{}, "", true},
+		{"-preset", tracepointOptions{}, "", true},
+	} {
+		opts, rest, err := parseTracepointOptions(tc.in)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", tc.in, err)
+			continue
+		}
+		if opts != tc.opts || rest != tc.rest {
+			t.Errorf("%q: got (%+v, %q), expected (%+v, %q)", tc.in, opts, rest, tc.opts, tc.rest)
+		}
+	}
+}
+
+func TestTracePresetHTTP(t *testing.T) {
+	withTestTerminal("http_server", t, func(term *FakeTerminal) {
+		out := term.MustExec("trace -preset http")
+		if !strings.Contains(out, "http server tracepoint set at") {
+			t.Fatalf("http server tracepoint not set:\n%s", out)
+		}
+		if _, err := term.Exec("trace -preset foo"); err == nil || !strings.Contains(err.Error(), `unknown preset "foo"`) {
+			t.Fatalf("expected unknown preset error, got %v", err)
+		}
+		if _, err := term.Exec("trace -preset http main.main"); err == nil {
+			t.Fatal("expected error for a location with -preset")
+		}
+	})
+}
+
+func TestPresetValues(t *testing.T) {
+	vals := presetValues{
+		"req.Method":        {Name: "req.Method", Kind: reflect.String, Value: "GET"},
+		"req.RequestURI":    {Name: "req.RequestURI", Kind: reflect.String, Value: "/hello?x=1"},
+		"rw.(data).status":  {Name: "rw.(data).status", Kind: reflect.Int, Value: "0"},
+		"retres.StatusCode": {Name: "retres.StatusCode", Unreadable: "nil pointer dereference"},
+		"req.URL.Scheme":    {Name: "req.URL.Scheme", Kind: reflect.String, Value: "https"},
+		"req.URL.Host":      {Name: "req.URL.Host", Kind: reflect.String, Value: "example.com"},
+		"req.URL.Path":      {Name: "req.URL.Path", Kind: reflect.String, Value: "/a"},
+	}
+	server, client := &tracePresets["http"][0], &tracePresets["http"][1]
+	for _, tc := range []struct {
+		got, want string
+	}{
+		{server.call(vals), "GET /hello?x=1"},
+		{server.result(vals), "200"},
+		{client.call(vals), "GET https://example.com/a"},
+		{client.result(vals), "error ?"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %q, want %q", tc.got, tc.want)
+		}
+	}
+}
+
+func TestPresetValuesGRPCAndSQL(t *testing.T) {
+	nilErr := api.Variable{Kind: reflect.Interface, Addr: 0xc000010000, Type: "error", Children: []api.Variable{{Kind: reflect.Invalid}}}
+	someErr := api.Variable{Kind: reflect.Interface, Addr: 0xc000010000, Type: "error", Children: []api.Variable{{Kind: reflect.String, Type: "string", Value: "failed"}}}
+	vals := presetValues{
+		"stream.method": {Kind: reflect.String, Value: "/helloworld.Greeter/SayHello"},
+		"method":        {Kind: reflect.String, Value: "/helloworld.Greeter/SayHello"},
+		"cc.target":     {Kind: reflect.String, Value: "localhost:50051"},
+		"err":           nilErr,
+		"~r0":           someErr,
+		"query":         {Kind: reflect.String, Value: "SELECT name FROM users WHERE id = ?"},
+		"args":          {Kind: reflect.Slice, Type: "[]interface {}", Len: 0},
+		"~r1":           nilErr,
+	}
+	server, client := &tracePresets["grpc"][0], &tracePresets["grpc"][2]
+	query := &tracePresets["sql"][0]
+	for _, tc := range []struct {
+		got, want string
+	}{
+		{server.call(vals), "/helloworld.Greeter/SayHello"},
+		{server.result(vals), "OK"},
+		{client.call(vals), "/helloworld.Greeter/SayHello localhost:50051"},
+		{client.result(vals), "error " + someErr.SinglelineString()},
+		{query.call(vals), "SELECT name FROM users WHERE id = ?"},
+		{query.result(vals), "ok"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %q, want %q", tc.got, tc.want)
+		}
+	}
+}
+
 func TestExitStatus(t *testing.T) {
 	withTestTerminal("continuetestprog", t, func(term *FakeTerminal) {
 		term.Exec("continue")
@@ -957,6 +1099,40 @@ func TestTruncateStacktrace(t *testing.T) {
 		if !strings.Contains(out2, stacktraceTruncatedMessage) {
 			t.Fatalf("stacktrace was not truncated")
 		}
+		if !strings.Contains(out2, "more frames") {
+			t.Fatalf("number of remaining frames missing")
+		}
+	})
+}
+
+func TestGoroutineProfile(t *testing.T) {
+	withTestTerminal("stacktraceprog", t, func(term *FakeTerminal) {
+		term.MustExec("break main.stacktraceme")
+		term.MustExec("continue")
+		dir, err := ioutil.TempDir("", "goroutineprofile")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		folded := filepath.Join(dir, "folded.txt")
+		term.MustExec("goroutine-profile -folded " + folded)
+		buf, err := ioutil.ReadFile(folded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("folded stacks:\n%s", buf)
+		if !strings.Contains(string(buf), "main.main;main.func1;main.stacktraceme 1\n") {
+			t.Errorf("current goroutine stack not found in folded output")
+		}
+		proto := filepath.Join(dir, "goroutine.pb.gz")
+		term.MustExec("goroutine-profile -depth 10 " + proto)
+		buf, err = ioutil.ReadFile(proto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(buf) < 2 || buf[0] != 0x1f || buf[1] != 0x8b {
+			t.Errorf("profile is not gzip compressed")
+		}
 	})
 }
 
@@ -1192,3 +1368,121 @@ func TestHitCondBreakpoint(t *testing.T) {
 		}
 	})
 }
+
+func TestProjectSaveLoad(t *testing.T) {
+	withTestTerminal("testnextprog", t, func(term *FakeTerminal) {
+		dir, err := ioutil.TempDir("", "project")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "test.dlvproj")
+
+		term.MustExec("break main.helloworld")
+		term.MustExec("break mybp main.testnext")
+		term.MustExec("condition mybp j > 2")
+		term.MustExec("note -b mybp slow path")
+		term.MustExec("note -a 0x1000 -size 16 -name rxbuf checked by the driver")
+		term.MustExec("toggle 1")
+		term.MustExec("trace main.main")
+		term.MustExec("display -a %x j")
+		term.MustExec("config max-string-len 10")
+		term.MustExec("config substitute-path /from /to")
+		term.MustExec("project save " + path)
+
+		p, err := api.ReadProject(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Kind != api.ProjectDebug {
+			t.Errorf("wrong project kind %q", p.Kind)
+		}
+		if len(p.Displays) != 1 || p.Displays[0] != (api.ProjectDisplay{Expr: "j", Format: "%x"}) {
+			t.Errorf("wrong displays %v", p.Displays)
+		}
+		if p.MaxStringLen == nil || *p.MaxStringLen != 10 {
+			t.Errorf("wrong max-string-len %v", p.MaxStringLen)
+		}
+		if len(p.SubstitutePath) != 1 || p.SubstitutePath[0] != (api.ProjectSubstitutePathRule{From: "/from", To: "/to"}) {
+			t.Errorf("wrong substitute-path rules %v", p.SubstitutePath)
+		}
+		for _, bp := range p.Breakpoints {
+			if bp.ID != 0 || bp.Addr != 0 || len(bp.Addrs) != 0 || bp.TotalHitCount != 0 {
+				t.Errorf("breakpoint state saved: %#v", bp)
+			}
+		}
+
+		term.MustExec("clearall")
+		term.MustExec("display -d 0")
+		term.MustExec("project load " + path)
+
+		bps, err := term.client.ListBreakpoints()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var helloworld, mybp, trace *api.Breakpoint
+		for _, bp := range bps {
+			switch {
+			case bp.FunctionName == "main.helloworld":
+				helloworld = bp
+			case bp.Name == "mybp":
+				mybp = bp
+			case bp.FunctionName == "main.main" && bp.Tracepoint:
+				trace = bp
+			}
+		}
+		if helloworld == nil || !helloworld.Disabled {
+			t.Errorf("disabled breakpoint not restored: %#v", helloworld)
+		}
+		if mybp == nil || mybp.Cond != "j > 2" || mybp.Note != "slow path" {
+			t.Errorf("conditional breakpoint not restored: %#v", mybp)
+		}
+		anns, err := term.client.ListAnnotations()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(anns) != 1 || anns[0] != (api.Annotation{Addr: 0x1000, Size: 16, Name: "rxbuf", Note: "checked by the driver"}) {
+			t.Errorf("annotations not restored: %v", anns)
+		}
+		if trace == nil {
+			t.Errorf("tracepoint not restored")
+		}
+		if len(term.displays) != 1 || term.displays[0] != (displayEntry{"j", "%x"}) {
+			t.Errorf("displays not restored: %v", term.displays)
+		}
+	})
+}
+
+func TestFrameDebug(t *testing.T) {
+	withTestTerminal("testvariables2", t, func(term *FakeTerminal) {
+		term.MustExec("continue")
+		out := term.MustExec("frame -debug")
+		t.Logf("frame -debug:\n%s", out)
+		for _, tgt := range []string{"Frame 0 ", "FDE: 0x", "CFA: ", "Return address: ", "Locals:", "location: "} {
+			if !strings.Contains(out, tgt) {
+				t.Errorf("output of frame -debug does not contain %q", tgt)
+			}
+		}
+		out = term.MustExec("frame -debug 1")
+		if !strings.HasPrefix(out, "Frame 1 ") {
+			t.Errorf("wrong output of frame -debug 1:\n%s", out)
+		}
+		if _, err := term.Exec("frame -debug -1"); err == nil {
+			t.Errorf("expected error for negative frame")
+		}
+	})
+}
+
+func TestSummary(t *testing.T) {
+	withTestTerminal("testvariables2", t, func(term *FakeTerminal) {
+		term.MustExec("break main.main")
+		term.MustExec("continue")
+		out := term.MustExec("summary")
+		t.Logf("summary:\n%s", out)
+		for _, tgt := range []string{"Stop reason: breakpoint 1 at ", "Current goroutine 1", "Goroutines: ", "Recent breakpoint hits:\n\tbreakpoint 1 at "} {
+			if !strings.Contains(out, tgt) {
+				t.Errorf("output of summary does not contain %q", tgt)
+			}
+		}
+	})
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/go-delve/delve/service/api"
 )
 
-func disasmPrint(dv api.AsmInstructions, out io.Writer) {
+func disasmPrint(dv api.AsmInstructions, annotations []api.Annotation, out io.Writer) {
 	bw := bufio.NewWriter(out)
 	defer bw.Flush()
 	if len(dv) > 0 && dv[0].Loc.Function != nil {
@@ -18,7 +18,14 @@ func disasmPrint(dv api.AsmInstructions, out io.Writer) {
 	}
 	tw := tabwriter.NewWriter(bw, 1, 8, 1, '\t', 0)
 	defer tw.Flush()
+	printed := make(map[uint64]bool)
 	for _, inst := range dv {
+		for _, a := range annotations {
+			if a.GoroutineID == 0 && inst.Loc.PC >= a.Addr && inst.Loc.PC < a.Addr+a.Size && !printed[a.Addr] {
+				printed[a.Addr] = true
+				fmt.Fprintf(tw, "\t; %s\n", formatAnnotation(a))
+			}
+		}
 		atbp := ""
 		if inst.Breakpoint {
 			atbp = "*"
@@ -30,3 +37,71 @@ func disasmPrint(dv api.AsmInstructions, out io.Writer) {
 		fmt.Fprintf(tw, "%s\t%s:%d\t%#x%s\t%x\t%s\n", atpc, filepath.Base(inst.Loc.File), inst.Loc.Line, inst.Loc.PC, atbp, inst.Bytes, inst.Text)
 	}
 }
+
+func formatAnnotation(a api.Annotation) string {
+	switch {
+	case a.Name != "" && a.Note != "":
+		return fmt.Sprintf("%s: %s", a.Name, a.Note)
+	case a.Name != "":
+		return a.Name
+	default:
+		return a.Note
+	}
+}
+
+// printPseudoSource prints the pseudo-source listing of the function
+// containing filename:line, in place of its source code, around the lines
+// corresponding to filename:line. They are marked with an arrow if
+// showArrow is set.
+func printPseudoSource(t *Term, filename string, line int, showArrow bool) error {
+	locs, err := t.client.FindLocation(api.EvalScope{GoroutineID: -1}, fmt.Sprintf("%s:%d", filename, line), true, nil)
+	if err != nil {
+		return err
+	}
+	if len(locs) == 0 {
+		return fmt.Errorf("no instructions for %s:%d", filename, line)
+	}
+	fnname, lines, err := t.client.PseudoSource(locs[0].PC)
+	if err != nil {
+		return err
+	}
+
+	first, last := -1, -1
+	for i := range lines {
+		if lines[i].File == filename && lines[i].Line == line {
+			if first < 0 {
+				first = i
+			}
+			last = i
+		}
+	}
+	if first < 0 {
+		first, last = 0, 0
+	}
+	n := t.conf.GetSourceListLineCount()
+	start, end := first-n, last+n+1
+	if start < 0 {
+		start = 0
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	bw := bufio.NewWriter(t.stdout)
+	defer bw.Flush()
+	fmt.Fprintf(bw, "Source not available, pseudo-source of %s:\n", fnname)
+	tw := tabwriter.NewWriter(bw, 1, 8, 1, '\t', 0)
+	defer tw.Flush()
+	for _, l := range lines[start:end] {
+		if l.Label != "" {
+			fmt.Fprintf(tw, "\t%s:\n", l.Label)
+			continue
+		}
+		atpc := ""
+		if showArrow && l.File == filename && l.Line == line {
+			atpc = "=>"
+		}
+		fmt.Fprintf(tw, "%s\t\t%s:%d\t%s\n", atpc, filepath.Base(l.File), l.Line, l.Text)
+	}
+	return nil
+}
@@ -0,0 +1,436 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+	"github.com/mattn/go-isatty"
+)
+
+// explorerLoadConfig is used to load each level of the tree displayed by the
+// interactive variable explorer, deeper levels are loaded when they are
+// expanded.
+var explorerLoadConfig = api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+
+// explorerNode is a node of the tree displayed by the interactive variable
+// explorer.
+type explorerNode struct {
+	name string
+	v    *api.Variable
+	// expr is an expression evaluating to v, it is used to load the children
+	// of v. If expr is empty the children already loaded in v are used.
+	expr   string
+	parent *explorerNode
+	depth  int
+
+	expanded bool
+	loaded   bool
+	children []*explorerNode
+
+	// more is set on placeholder nodes standing for the elements of an
+	// array, slice or map that have not been loaded yet, expr reslices the
+	// parent starting at offset.
+	more   bool
+	offset int
+}
+
+// variableExplorer displays a variable as a tree where each node can be
+// expanded and collapsed, children are loaded lazily.
+type variableExplorer struct {
+	root   *explorerNode
+	eval   func(expr string) (*api.Variable, error)
+	cursor int // index of the selected node in the list of visible nodes
+	top    int // index of the first visible node displayed
+	err    error
+}
+
+func newVariableExplorer(expr string, v *api.Variable, eval func(string) (*api.Variable, error)) *variableExplorer {
+	root := &explorerNode{name: expr, v: v, expr: expr}
+	return &variableExplorer{root: root, eval: eval}
+}
+
+// expandable returns true if node has (or could have) children.
+func (node *explorerNode) expandable() bool {
+	if node.more {
+		return true
+	}
+	v := node.v
+	if v.Unreadable != "" {
+		return false
+	}
+	switch v.Kind {
+	case reflect.Struct, reflect.Array, reflect.Slice, reflect.Map, reflect.Chan:
+		return len(v.Children) > 0 || v.Len > 0
+	case reflect.Ptr:
+		return len(v.Children) > 0 && v.Children[0].Addr != 0
+	case reflect.Interface:
+		return len(v.Children) > 0 && v.Children[0].Kind != reflect.Invalid
+	}
+	return false
+}
+
+// load loads the children of node.
+func (x *variableExplorer) load(node *explorerNode) error {
+	v := node.v
+	if node.expr != "" {
+		var err error
+		v, err = x.eval(node.expr)
+		if err != nil {
+			return err
+		}
+		if !node.more {
+			node.v = v
+		}
+	}
+	if node.more {
+		// replace the placeholder with the newly loaded elements
+		parent := node.parent
+		children := explorerChildren(parent, v, node.offset)
+		for i := range parent.children {
+			if parent.children[i] == node {
+				parent.children = append(parent.children[:i], children...)
+				break
+			}
+		}
+		return nil
+	}
+	node.children = explorerChildren(node, v, 0)
+	node.loaded = true
+	return nil
+}
+
+// explorerChildren returns the nodes for the children of v, which are
+// displayed as children of parent. If v is the result of reslicing the
+// variable of parent offset is the index of its first element.
+func explorerChildren(parent *explorerNode, v *api.Variable, offset int) []*explorerNode {
+	r := []*explorerNode{}
+	child := func(name string, cv *api.Variable, expr string) {
+		r = append(r, &explorerNode{name: name, v: cv, expr: expr, parent: parent, depth: parent.depth + 1})
+	}
+	pexpr := parent.expr
+	subexpr := func(format string, args ...interface{}) string {
+		if pexpr == "" {
+			return ""
+		}
+		return fmt.Sprintf(format, append([]interface{}{pexpr}, args...)...)
+	}
+	switch v.Kind {
+	case reflect.Struct, reflect.Chan:
+		for i := range v.Children {
+			child(v.Children[i].Name, &v.Children[i], subexpr("(%s).%s", v.Children[i].Name))
+		}
+	case reflect.Array, reflect.Slice:
+		for i := range v.Children {
+			child(fmt.Sprintf("[%d]", offset+i), &v.Children[i], subexpr("(%s)[%d]", offset+i))
+		}
+	case reflect.Map:
+		for i := 0; i+1 < len(v.Children); i += 2 {
+			key := &v.Children[i]
+			expr := ""
+			switch key.Kind {
+			case reflect.String, reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+				expr = subexpr("(%s)[%s]", key.SinglelineString())
+			}
+			child("["+key.SinglelineString()+"]", &v.Children[i+1], expr)
+		}
+	case reflect.Ptr:
+		child("*", &v.Children[0], subexpr("*(%s)"))
+	case reflect.Interface:
+		c := &v.Children[0]
+		child("("+c.Type+")", c, subexpr("(%s).(%s)", c.Type))
+	}
+
+	n := len(v.Children)
+	if v.Kind == reflect.Map {
+		n /= 2
+	}
+	switch v.Kind {
+	case reflect.Array, reflect.Slice, reflect.Map:
+		// when v is the result of reslicing its Len is relative to offset
+		if pexpr != "" && n > 0 && int64(n) < v.Len {
+			r = append(r, &explorerNode{
+				name:   fmt.Sprintf("... %d more", v.Len-int64(n)),
+				expr:   fmt.Sprintf("(%s)[%d:]", pexpr, offset+n),
+				parent: parent,
+				depth:  parent.depth + 1,
+				more:   true,
+				offset: offset + n,
+			})
+		}
+	}
+	return r
+}
+
+// visible returns the list of nodes currently displayed.
+func (x *variableExplorer) visible() []*explorerNode {
+	r := []*explorerNode{}
+	var visit func(node *explorerNode)
+	visit = func(node *explorerNode) {
+		r = append(r, node)
+		if node.expanded {
+			for _, child := range node.children {
+				visit(child)
+			}
+		}
+	}
+	visit(x.root)
+	return r
+}
+
+func (x *variableExplorer) selected() *explorerNode {
+	nodes := x.visible()
+	if x.cursor >= len(nodes) {
+		x.cursor = len(nodes) - 1
+	}
+	return nodes[x.cursor]
+}
+
+func (x *variableExplorer) selectNode(node *explorerNode) {
+	for i, n := range x.visible() {
+		if n == node {
+			x.cursor = i
+			return
+		}
+	}
+}
+
+// expand expands the selected node, or selects its first child if it is
+// already expanded.
+func (x *variableExplorer) expand() {
+	node := x.selected()
+	if !node.expandable() {
+		return
+	}
+	if node.more {
+		// reselect the first loaded element, which replaces the placeholder
+		parent := node.parent
+		idx := -1
+		for i := range parent.children {
+			if parent.children[i] == node {
+				idx = i
+			}
+		}
+		if x.err = x.load(node); x.err == nil && idx >= 0 && idx < len(parent.children) {
+			x.selectNode(parent.children[idx])
+		}
+		return
+	}
+	if node.expanded {
+		if len(node.children) > 0 {
+			x.cursor++
+		}
+		return
+	}
+	if !node.loaded {
+		if x.err = x.load(node); x.err != nil {
+			return
+		}
+	}
+	node.expanded = true
+}
+
+// collapse collapses the selected node, or selects its parent if it is
+// already collapsed.
+func (x *variableExplorer) collapse() {
+	node := x.selected()
+	if node.expanded {
+		node.expanded = false
+		return
+	}
+	if node.parent != nil {
+		x.selectNode(node.parent)
+	}
+}
+
+func (x *variableExplorer) toggle() {
+	if x.selected().expanded {
+		x.collapse()
+	} else {
+		x.expand()
+	}
+}
+
+func (x *variableExplorer) move(delta int) {
+	x.cursor += delta
+	if n := len(x.visible()); x.cursor >= n {
+		x.cursor = n - 1
+	}
+	if x.cursor < 0 {
+		x.cursor = 0
+	}
+}
+
+// render returns the lines to display on a screen of the specified size,
+// the last line is a status line.
+func (x *variableExplorer) render(height, width int) []string {
+	if height < 2 {
+		height = 2
+	}
+	nodes := x.visible()
+	x.selected()
+	rows := height - 1
+	if x.cursor < x.top {
+		x.top = x.cursor
+	}
+	if x.cursor >= x.top+rows {
+		x.top = x.cursor - rows + 1
+	}
+
+	r := []string{}
+	for i := x.top; i < len(nodes) && i < x.top+rows; i++ {
+		node := nodes[i]
+		var b strings.Builder
+		if i == x.cursor {
+			b.WriteString("> ")
+		} else {
+			b.WriteString("  ")
+		}
+		b.WriteString(strings.Repeat("  ", node.depth))
+		switch {
+		case node.expanded:
+			b.WriteString("- ")
+		case node.expandable():
+			b.WriteString("+ ")
+		default:
+			b.WriteString("  ")
+		}
+		b.WriteString(node.name)
+		switch {
+		case node.more:
+		case node.expanded:
+			fmt.Fprintf(&b, " %s", node.v.Type)
+		default:
+			fmt.Fprintf(&b, " = %s", node.v.SinglelineString())
+		}
+		r = append(r, truncateLine(b.String(), width))
+	}
+
+	status := "up/down: move, right/left: expand/collapse, enter: toggle, q: quit"
+	if x.err != nil {
+		status = "error: " + x.err.Error()
+	}
+	r = append(r, truncateLine(status, width))
+	return r
+}
+
+func truncateLine(s string, width int) string {
+	if width <= 0 || len(s) <= width {
+		return s
+	}
+	if width <= 3 {
+		return s[:width]
+	}
+	return s[:width-3] + "..."
+}
+
+type explorerKey uint8
+
+const (
+	explorerKeyNone explorerKey = iota
+	explorerKeyUp
+	explorerKeyDown
+	explorerKeyRight
+	explorerKeyLeft
+	explorerKeyToggle
+	explorerKeyPageUp
+	explorerKeyPageDown
+	explorerKeyQuit
+)
+
+// decodeExplorerKey decodes the bytes read from the terminal, in raw mode,
+// for one key press.
+func decodeExplorerKey(b []byte) explorerKey {
+	switch string(b) {
+	case "\x1b[A", "\x1bOA", "k":
+		return explorerKeyUp
+	case "\x1b[B", "\x1bOB", "j":
+		return explorerKeyDown
+	case "\x1b[C", "\x1bOC", "l":
+		return explorerKeyRight
+	case "\x1b[D", "\x1bOD", "h":
+		return explorerKeyLeft
+	case "\r", "\n", " ":
+		return explorerKeyToggle
+	case "\x1b[5~":
+		return explorerKeyPageUp
+	case "\x1b[6~":
+		return explorerKeyPageDown
+	case "q", "\x1b", "\x03", "\x04":
+		return explorerKeyQuit
+	}
+	return explorerKeyNone
+}
+
+// handleKey executes the action associated with key, returns false if the
+// explorer should be closed.
+func (x *variableExplorer) handleKey(key explorerKey, height int) bool {
+	switch key {
+	case explorerKeyUp:
+		x.move(-1)
+	case explorerKeyDown:
+		x.move(1)
+	case explorerKeyRight:
+		x.expand()
+	case explorerKeyLeft:
+		x.collapse()
+	case explorerKeyToggle:
+		x.toggle()
+	case explorerKeyPageUp:
+		x.move(-(height - 1))
+	case explorerKeyPageDown:
+		x.move(height - 1)
+	case explorerKeyQuit:
+		return false
+	}
+	return true
+}
+
+// exploreVariable runs the interactive variable explorer for expr.
+func exploreVariable(t *Term, ctx callContext, expr string) error {
+	if !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return errors.New("interactive mode requires a terminal")
+	}
+	eval := func(expr string) (*api.Variable, error) {
+		return t.client.EvalVariable(ctx.Scope, expr, explorerLoadConfig)
+	}
+	v, err := eval(expr)
+	if err != nil {
+		return err
+	}
+	x := newVariableExplorer(expr, v, eval)
+
+	restore, err := rawTerminalMode()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// switch to the alternate screen buffer, so that the previous content of
+	// the terminal is restored when the explorer is closed
+	fmt.Print("\x1b[?1049h")
+	defer fmt.Print("\x1b[?1049l")
+
+	buf := make([]byte, 16)
+	for {
+		height, width := terminalSize()
+		var out strings.Builder
+		out.WriteString("\x1b[H\x1b[2J")
+		for _, line := range x.render(height, width) {
+			out.WriteString(line)
+			out.WriteString("\r\n")
+		}
+		fmt.Print(out.String())
+
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return err
+		}
+		if !x.handleKey(decodeExplorerKey(buf[:n]), height) {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package terminal
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+func explorerTestEval(t *testing.T) func(string) (*api.Variable, error) {
+	intv := func(name string, n int) api.Variable {
+		return api.Variable{Name: name, Type: "int", Kind: reflect.Int, Value: fmt.Sprint(n)}
+	}
+	slice := func(start, n int) *api.Variable {
+		v := &api.Variable{Type: "[]int", Kind: reflect.Slice, Addr: 0xc000010000, Base: 0x1000, Len: int64(n - start), Cap: int64(n - start)}
+		for i := start; i < n && i < start+2; i++ {
+			v.Children = append(v.Children, intv("", i))
+		}
+		return v
+	}
+	slicev := func(name string, start, n int) api.Variable {
+		v := slice(start, n)
+		v.Name = name
+		return *v
+	}
+	return func(expr string) (*api.Variable, error) {
+		t.Logf("eval %q", expr)
+		switch expr {
+		case "s":
+			return &api.Variable{Name: "s", Type: "main.S", Kind: reflect.Struct, Len: 2, Children: []api.Variable{
+				intv("A", 1),
+				slicev("B", 0, 5),
+			}}, nil
+		case "(s).A":
+			v := intv("A", 1)
+			return &v, nil
+		case "(s).B":
+			return slice(0, 5), nil
+		case "((s).B)[2:]":
+			return slice(2, 5), nil
+		case "((s).B)[4:]":
+			return slice(4, 5), nil
+		}
+		return nil, fmt.Errorf("unknown expression %q", expr)
+	}
+}
+
+func TestVariableExplorer(t *testing.T) {
+	eval := explorerTestEval(t)
+	v, _ := eval("s")
+	x := newVariableExplorer("s", v, eval)
+
+	check := func(tgt ...string) {
+		t.Helper()
+		lines := x.render(20, 80)
+		lines = lines[:len(lines)-1]
+		if len(lines) != len(tgt) {
+			t.Fatalf("expected %d lines, got:\n%s", len(tgt), strings.Join(lines, "\n"))
+		}
+		for i := range lines {
+			if strings.TrimRight(lines[i], " ") != tgt[i] {
+				t.Errorf("line %d: expected %q got %q", i, tgt[i], lines[i])
+			}
+		}
+	}
+
+	check("> + s = main.S {A: 1, B: []int len: 5, cap: 5, [0,1,...+3 more]}")
+	x.handleKey(explorerKeyRight, 20)
+	check(
+		"> - s main.S",
+		"      A = 1",
+		"    + B = []int len: 5, cap: 5, [0,1,...+3 more]")
+	x.handleKey(explorerKeyDown, 20)
+	x.handleKey(explorerKeyDown, 20)
+	x.handleKey(explorerKeyToggle, 20)
+	check(
+		"  - s main.S",
+		"      A = 1",
+		">   - B []int",
+		"        [0] = 0",
+		"        [1] = 1",
+		"      + ... 3 more")
+	x.handleKey(explorerKeyDown, 20)
+	x.handleKey(explorerKeyPageDown, 20)
+	x.handleKey(explorerKeyRight, 20)
+	check(
+		"  - s main.S",
+		"      A = 1",
+		"    - B []int",
+		"        [0] = 0",
+		"        [1] = 1",
+		">       [2] = 2",
+		"        [3] = 3",
+		"      + ... 1 more")
+	x.handleKey(explorerKeyLeft, 20)
+	x.handleKey(explorerKeyLeft, 20)
+	check(
+		"  - s main.S",
+		"      A = 1",
+		">   + B = []int len: 5, cap: 5, [0,1,...+3 more]")
+	if x.handleKey(decodeExplorerKey([]byte("q")), 20) {
+		t.Errorf("q did not quit")
+	}
+}
+
+func TestDecodeExplorerKey(t *testing.T) {
+	for in, tgt := range map[string]explorerKey{
+		"\x1b[A": explorerKeyUp,
+		"\x1b[B": explorerKeyDown,
+		"\x1b[C": explorerKeyRight,
+		"\x1bOD": explorerKeyLeft,
+		"\r":     explorerKeyToggle,
+		"\x1b":   explorerKeyQuit,
+		"x":      explorerKeyNone,
+	} {
+		if out := decodeExplorerKey([]byte(in)); out != tgt {
+			t.Errorf("decodeExplorerKey(%q) = %d, expected %d", in, out, tgt)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// +build !windows
+
+package terminal
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// rawTerminalMode disables line buffering and echo on stdin, the returned
+// function restores the previous mode.
+func rawTerminalMode() (restore func(), err error) {
+	fd := int(os.Stdin.Fd())
+	orig, err := unix.IoctlGetTermios(fd, ioctlReadTermios)
+	if err != nil {
+		return nil, err
+	}
+	raw := *orig
+	raw.Lflag &^= unix.ECHO | unix.ICANON | unix.ISIG | unix.IEXTEN
+	raw.Iflag &^= unix.ICRNL | unix.IXON
+	raw.Cc[unix.VMIN] = 1
+	raw.Cc[unix.VTIME] = 0
+	if err := unix.IoctlSetTermios(fd, ioctlWriteTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() {
+		unix.IoctlSetTermios(fd, ioctlWriteTermios, orig)
+	}, nil
+}
+
+// terminalSize returns the number of rows and columns of the terminal.
+func terminalSize() (height, width int) {
+	ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ)
+	if err != nil || ws.Row == 0 {
+		return 24, 80
+	}
+	return int(ws.Row), int(ws.Col)
+}
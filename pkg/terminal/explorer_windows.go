@@ -0,0 +1,11 @@
+package terminal
+
+import "errors"
+
+func rawTerminalMode() (restore func(), err error) {
+	return nil, errors.New("interactive mode is not supported on windows")
+}
+
+func terminalSize() (height, width int) {
+	return 24, 80
+}
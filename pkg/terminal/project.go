@@ -0,0 +1,185 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/config"
+	"github.com/go-delve/delve/service/api"
+	"github.com/go-delve/delve/service/rpc2"
+)
+
+func projectCommand(t *Term, ctx callContext, args string) error {
+	v := strings.SplitN(strings.TrimSpace(args), " ", 2)
+	if len(v) != 2 || strings.TrimSpace(v[1]) == "" {
+		return fmt.Errorf("wrong number of arguments: project [save|load] <file>")
+	}
+	path := strings.TrimSpace(v[1])
+	switch v[0] {
+	case "save":
+		p, err := t.project()
+		if err != nil {
+			return err
+		}
+		return p.Save(path)
+	case "load":
+		p, err := api.ReadProject(path)
+		if err != nil {
+			return err
+		}
+		t.loadProject(p)
+		return nil
+	default:
+		return fmt.Errorf("unknown subcommand %q: project [save|load] <file>", v[0])
+	}
+}
+
+// project returns the configuration of the current debugging session,
+// the server provides the launch parameters and the breakpoints.
+func (t *Term) project() (*api.Project, error) {
+	p, err := t.client.GetProject()
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range t.displays {
+		if d.expr != "" {
+			p.Displays = append(p.Displays, api.ProjectDisplay{Expr: d.expr, Format: d.fmtstr})
+		}
+	}
+	if t.conf != nil {
+		for _, r := range t.conf.SubstitutePath {
+			p.SubstitutePath = append(p.SubstitutePath, api.ProjectSubstitutePathRule{From: r.From, To: r.To})
+		}
+		p.MaxStringLen = t.conf.MaxStringLen
+		p.MaxArrayValues = t.conf.MaxArrayValues
+		p.MaxVariableRecurse = t.conf.MaxVariableRecurse
+	}
+	return p, nil
+}
+
+// loadProject restores the breakpoints, annotations, displayed expressions
+// and settings saved in p. The launch parameters are ignored, they are used by
+// 'dlv --project' to start the target. Breakpoints that can not be created
+// are reported and skipped.
+func (t *Term) loadProject(p *api.Project) {
+	if t.conf == nil {
+		t.conf = &config.Config{}
+	}
+	for _, r := range p.SubstitutePath {
+		found := false
+		for i := range t.conf.SubstitutePath {
+			if t.conf.SubstitutePath[i].From == r.From {
+				t.conf.SubstitutePath[i].To = r.To
+				found = true
+			}
+		}
+		if !found {
+			t.conf.SubstitutePath = append(t.conf.SubstitutePath, config.SubstitutePathRule{From: r.From, To: r.To})
+		}
+	}
+	t.substitutePathRulesCache = nil
+	if p.MaxStringLen != nil {
+		t.conf.MaxStringLen = p.MaxStringLen
+	}
+	if p.MaxArrayValues != nil {
+		t.conf.MaxArrayValues = p.MaxArrayValues
+	}
+	if p.MaxVariableRecurse != nil {
+		t.conf.MaxVariableRecurse = p.MaxVariableRecurse
+	}
+
+	for _, d := range p.Displays {
+		t.addDisplay(d.Expr, d.Format)
+	}
+
+	returnBreakpointsDone := map[string]bool{}
+	for _, bp := range p.Breakpoints {
+		var err error
+		switch {
+		case bp.TraceReturn:
+			if returnBreakpointsDone[bp.FunctionName] {
+				continue
+			}
+			returnBreakpointsDone[bp.FunctionName] = true
+			err = t.setReturnBreakpoints(bp)
+		case bp.WatchExpr != "":
+			var created *api.Breakpoint
+			created, err = t.client.CreateWatchpoint(api.EvalScope{GoroutineID: -1}, bp.WatchExpr, bp.WatchType)
+			if err == nil {
+				fmt.Printf("%s set at %s\n", formatBreakpointName(created, true), t.formatBreakpointLocation(created))
+			}
+		default:
+			err = t.restoreBreakpoint(bp)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "could not restore %s: %v\n", projectBreakpointDescr(bp), err)
+		}
+	}
+
+	for _, a := range p.Annotations {
+		if err := t.client.SetAnnotation(a); err != nil {
+			fmt.Fprintf(os.Stderr, "could not restore annotation %s: %v\n", formatAnnotation(a), err)
+		}
+	}
+}
+
+// restoreBreakpoint creates a breakpoint saved in a project file, at the
+// same file and line or function.
+func (t *Term) restoreBreakpoint(saved *api.Breakpoint) error {
+	bp := *saved
+	bp.ID = 0
+	bp.Addr = 0
+	bp.Addrs = nil
+	created, err := t.client.CreateBreakpoint(&bp)
+	if err != nil {
+		return err
+	}
+	if saved.Disabled {
+		created, err = t.client.ToggleBreakpoint(created.ID)
+		if err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%s set at %s\n", formatBreakpointName(created, true), t.formatBreakpointLocation(created))
+	return nil
+}
+
+// setReturnBreakpoints creates the breakpoints on the return instructions
+// of a traced function, like the trace command does.
+func (t *Term) setReturnBreakpoints(saved *api.Breakpoint) error {
+	client, ok := t.client.(*rpc2.RPCClient)
+	if !ok || saved.FunctionName == "" {
+		return fmt.Errorf("unknown function")
+	}
+	addrs, err := client.FunctionReturnLocations(saved.FunctionName)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		_, err := t.client.CreateBreakpoint(&api.Breakpoint{
+			Addr:        addr,
+			TraceReturn: true,
+			Stacktrace:  saved.Stacktrace,
+			Line:        -1,
+			LoadArgs:    &ShortLoadConfig,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func projectBreakpointDescr(bp *api.Breakpoint) string {
+	switch {
+	case bp.WatchExpr != "":
+		return fmt.Sprintf("watchpoint on %s", bp.WatchExpr)
+	case bp.TraceReturn:
+		return fmt.Sprintf("return tracepoints of %s", bp.FunctionName)
+	case bp.Name != "":
+		return fmt.Sprintf("breakpoint %s at %s:%d", bp.Name, bp.File, bp.Line)
+	default:
+		return fmt.Sprintf("breakpoint at %s:%d", bp.File, bp.Line)
+	}
+}
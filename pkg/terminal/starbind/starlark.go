@@ -23,6 +23,7 @@ const (
 	readFileBuiltinName          = "read_file"
 	writeFileBuiltinName         = "write_file"
 	commandPrefix                = "command_"
+	tracePresetPrefix            = "trace_preset_"
 	dlvContextName               = "dlv_context"
 	curScopeBuiltinName          = "cur_scope"
 	defaultLoadConfigBuiltinName = "default_load_config"
@@ -46,6 +47,21 @@ type Context interface {
 	CallCommand(cmdstr string) error
 	Scope() api.EvalScope
 	LoadConfig() api.LoadConfig
+	RegisterTracePreset(name string, tracepoints []TracePresetTracepoint)
+}
+
+// TracePresetTracepoint is a tracepoint of a preset of 'trace -preset'
+// defined by a script.
+type TracePresetTracepoint struct {
+	Name     string
+	Function string
+	Vars     []string
+	RetVars  []string
+	// Call and Result decode the values of Vars, when Function is
+	// entered, and of RetVars and of the return values of Function, when
+	// it returns, into the description of a call and of its result.
+	Call   func(vals map[string]api.Variable) (string, error)
+	Result func(vals map[string]api.Variable) (string, error)
 }
 
 // Env is the environment used to evaluate starlark scripts.
@@ -158,8 +174,9 @@ func (env *Env) Execute(path string, source interface{}, mainFnName string, args
 }
 
 // exportGlobals saves globals with a name starting with a capital letter
-// into the environment and creates commands from globals with a name
-// starting with "command_"
+// into the environment, creates commands from globals with a name
+// starting with "command_" and trace presets from globals with a name
+// starting with "trace_preset_".
 func (env *Env) exportGlobals(globals starlark.StringDict) error {
 	for name, val := range globals {
 		switch {
@@ -168,6 +185,11 @@ func (env *Env) exportGlobals(globals starlark.StringDict) error {
 			if err != nil {
 				return err
 			}
+		case strings.HasPrefix(name, tracePresetPrefix):
+			err := env.createTracePreset(name, val)
+			if err != nil {
+				return err
+			}
 		case name[0] >= 'A' && name[0] <= 'Z':
 			env.env[name] = val
 		}
@@ -243,6 +265,117 @@ func (env *Env) createCommand(name string, val starlark.Value) error {
 	return nil
 }
 
+// createTracePreset registers the preset of 'trace -preset' defined by
+// val, a list of dictionaries each describing one tracepoint of the
+// preset:
+//
+//	{
+//		"name": name of the tracepoint,
+//		"function": function traced,
+//		"vars": expressions evaluated when the function is entered,
+//		"ret_vars": expressions evaluated when the function returns,
+//		"call": function returning the description of a call,
+//		"result": function returning the description of its result,
+//	}
+//
+// The call and result functions are passed a dictionary mapping each
+// expression, and each return value of the traced function, to its value.
+func (env *Env) createTracePreset(name string, val starlark.Value) error {
+	name = name[len(tracePresetPrefix):]
+	list, ok := val.(*starlark.List)
+	if !ok {
+		return fmt.Errorf("trace preset %s is not a list", name)
+	}
+	tracepoints := make([]TracePresetTracepoint, list.Len())
+	for i := range tracepoints {
+		d, ok := list.Index(i).(*starlark.Dict)
+		if !ok {
+			return fmt.Errorf("tracepoint %d of trace preset %s is not a dictionary", i, name)
+		}
+		tp := &tracepoints[i]
+		var err error
+		if tp.Name, err = tracePresetString(d, "name"); err != nil {
+			return fmt.Errorf("tracepoint %d of trace preset %s: %v", i, name, err)
+		}
+		if tp.Function, err = tracePresetString(d, "function"); err != nil {
+			return fmt.Errorf("tracepoint %d of trace preset %s: %v", i, name, err)
+		}
+		if tp.Vars, err = tracePresetStrings(d, "vars"); err != nil {
+			return fmt.Errorf("tracepoint %d of trace preset %s: %v", i, name, err)
+		}
+		if tp.RetVars, err = tracePresetStrings(d, "ret_vars"); err != nil {
+			return fmt.Errorf("tracepoint %d of trace preset %s: %v", i, name, err)
+		}
+		if tp.Call, err = env.tracePresetDecoder(d, "call"); err != nil {
+			return fmt.Errorf("tracepoint %d of trace preset %s: %v", i, name, err)
+		}
+		if tp.Result, err = env.tracePresetDecoder(d, "result"); err != nil {
+			return fmt.Errorf("tracepoint %d of trace preset %s: %v", i, name, err)
+		}
+	}
+	env.ctx.RegisterTracePreset(name, tracepoints)
+	return nil
+}
+
+func tracePresetString(d *starlark.Dict, key string) (string, error) {
+	v, found, _ := d.Get(starlark.String(key))
+	if !found {
+		return "", fmt.Errorf("missing %q", key)
+	}
+	s, ok := v.(starlark.String)
+	if !ok {
+		return "", fmt.Errorf("%q is not a string", key)
+	}
+	return string(s), nil
+}
+
+func tracePresetStrings(d *starlark.Dict, key string) ([]string, error) {
+	v, found, _ := d.Get(starlark.String(key))
+	if !found {
+		return nil, nil
+	}
+	list, ok := v.(*starlark.List)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a list", key)
+	}
+	r := make([]string, list.Len())
+	for i := range r {
+		s, ok := list.Index(i).(starlark.String)
+		if !ok {
+			return nil, fmt.Errorf("element %d of %q is not a string", i, key)
+		}
+		r[i] = string(s)
+	}
+	return r, nil
+}
+
+func (env *Env) tracePresetDecoder(d *starlark.Dict, key string) (func(map[string]api.Variable) (string, error), error) {
+	v, found, _ := d.Get(starlark.String(key))
+	if !found {
+		return nil, fmt.Errorf("missing %q", key)
+	}
+	fn, ok := v.(starlark.Callable)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a function", key)
+	}
+	return func(vals map[string]api.Variable) (string, error) {
+		dict := starlark.NewDict(len(vals))
+		for expr, val := range vals {
+			if err := dict.SetKey(starlark.String(expr), env.interfaceToStarlarkValue(val)); err != nil {
+				return "", err
+			}
+		}
+		r, err := starlark.Call(env.newThread(), fn, starlark.Tuple{dict}, nil)
+		if err != nil {
+			return "", err
+		}
+		if s, ok := r.(starlark.String); ok {
+			return string(s), nil
+		}
+		return r.String(), nil
+	}, nil
+}
+
 // callMain calls the main function in globals, if one was defined.
 func (env *Env) callMain(thread *starlark.Thread, globals starlark.StringDict, mainFnName string, args []interface{}) (starlark.Value, error) {
 	if mainFnName == "" {
@@ -57,3 +57,7 @@ func (ctx starlarkContext) Scope() api.EvalScope {
 func (ctx starlarkContext) LoadConfig() api.LoadConfig {
 	return ctx.term.loadConfig()
 }
+
+func (ctx starlarkContext) RegisterTracePreset(name string, tracepoints []starbind.TracePresetTracepoint) {
+	ctx.term.registerTracePreset(name, tracepoints)
+}
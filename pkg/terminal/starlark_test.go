@@ -2,6 +2,7 @@ package terminal
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -158,3 +159,27 @@ func TestStarlarkVariable(t *testing.T) {
 		}
 	})
 }
+
+func TestStarlarkTracePreset(t *testing.T) {
+	withTestTerminal("testvariables2", t, func(term *FakeTerminal) {
+		term.MustExec("source " + findStarFile("trace_preset"))
+		out := term.MustExec("trace -preset afunc")
+		if !strings.Contains(out, "afunc tracepoint set at") {
+			t.Fatalf("afunc tracepoint not set:\n%s", out)
+		}
+		tp := &term.userTracePresets["afunc"][0]
+		vals := presetValues{
+			"x":   {Name: "x", Kind: reflect.Int, Value: "3"},
+			"~r0": {Name: "~r0", Kind: reflect.Int, Value: "6"},
+		}
+		if got := tp.call(vals); got != "afunc(3)" {
+			t.Errorf("got call %q, want %q", got, "afunc(3)")
+		}
+		if got := tp.result(vals); got != "6" {
+			t.Errorf("got result %q, want %q", got, "6")
+		}
+		if got := tp.call(presetValues{}); !strings.HasPrefix(got, "<decoder error: ") {
+			t.Errorf("expected decoder error, got %q", got)
+		}
+	})
+}
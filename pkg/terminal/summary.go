@@ -0,0 +1,249 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+const (
+	maxRecentHits      = 10
+	summaryStackDepth  = 5
+	summaryMaxWarnings = 10
+)
+
+// displayValue is the value of a display expression at the last two stops
+// where it was printed.
+type displayValue struct {
+	stop        int
+	value, prev string
+}
+
+// recentHit is a breakpoint hit recorded by recordHits.
+type recentHit struct {
+	stop        int
+	bp          api.Breakpoint
+	goroutineID int
+}
+
+// recordDisplayValue records the value of a display expression at the
+// current stop.
+func (t *Term) recordDisplayValue(e displayEntry, value string) {
+	if t.displayValues == nil {
+		t.displayValues = make(map[displayEntry]*displayValue)
+	}
+	dv := t.displayValues[e]
+	if dv == nil {
+		t.displayValues[e] = &displayValue{stop: t.stops, value: value, prev: value}
+		return
+	}
+	if dv.stop != t.stops {
+		dv.prev = dv.value
+		dv.stop = t.stops
+	}
+	dv.value = value
+}
+
+// recordHits records the user breakpoints hit by the threads of the target
+// at the current stop, keeping the last maxRecentHits of them.
+func (t *Term) recordHits() {
+	state, err := t.client.GetStateNonBlocking()
+	if err != nil || state.Running {
+		return
+	}
+	for _, th := range state.Threads {
+		if th.Breakpoint == nil || th.Breakpoint.ID < 0 || th.Breakpoint.Tracepoint {
+			continue
+		}
+		t.recentHits = append(t.recentHits, recentHit{stop: t.stops, bp: *th.Breakpoint, goroutineID: th.GoroutineID})
+	}
+	if len(t.recentHits) > maxRecentHits {
+		t.recentHits = append(t.recentHits[:0], t.recentHits[len(t.recentHits)-maxRecentHits:]...)
+	}
+}
+
+func summaryCommand(t *Term, ctx callContext, args string) error {
+	if len(strings.Fields(args)) > 1 {
+		return errors.New("too many arguments")
+	}
+	if args == "" {
+		return writeSummary(t, t.stdout)
+	}
+	fh, err := os.Create(args)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	return writeSummary(t, fh)
+}
+
+func writeSummary(t *Term, w io.Writer) error {
+	state, err := t.client.GetState()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Stop reason: %s\n", stopReason(t, state))
+	if state.Exited {
+		return nil
+	}
+
+	if g := state.SelectedGoroutine; g != nil {
+		fmt.Fprintf(w, "\nCurrent goroutine %s", formatGoroutineIDAndName(g.ID, g.Name))
+		if g.ThreadID != 0 {
+			fmt.Fprintf(w, " (thread %d)", g.ThreadID)
+		}
+		fmt.Fprintf(w, ":\n")
+		stack, err := t.client.Stacktrace(g.ID, summaryStackDepth, 0, nil)
+		if err != nil {
+			fmt.Fprintf(w, "\tcould not read stack: %v\n", err)
+		} else {
+			printStack(t, w, stack, "\t", false)
+		}
+	} else if th := state.CurrentThread; th != nil {
+		fmt.Fprintf(w, "\nCurrent thread %d at %s\n", th.ID, t.formatLocation(api.Location{PC: th.PC, File: th.File, Line: th.Line, Function: th.Function}))
+	}
+
+	if err := writeGoroutineCounts(t, w); err != nil {
+		fmt.Fprintf(w, "\nCould not list goroutines: %v\n", err)
+	}
+
+	if len(t.recentHits) > 0 {
+		fmt.Fprintf(w, "\nRecent breakpoint hits:\n")
+		for i := len(t.recentHits) - 1; i >= 0; i-- {
+			hit := &t.recentHits[i]
+			fmt.Fprintf(w, "\t%s at %s:%d", summaryBreakpointName(&hit.bp), t.formatPath(hit.bp.File), hit.bp.Line)
+			if hit.goroutineID != 0 {
+				fmt.Fprintf(w, " goroutine %d", hit.goroutineID)
+			}
+			if hit.stop == t.stops {
+				fmt.Fprintf(w, " (current stop)")
+			} else {
+				fmt.Fprintf(w, " (%d stops ago)", t.stops-hit.stop)
+			}
+			fmt.Fprintf(w, "\n")
+		}
+	}
+
+	changed := false
+	for i, e := range t.displays {
+		dv := t.displayValues[e]
+		if e.expr == "" || dv == nil || dv.stop != t.stops || dv.value == dv.prev {
+			continue
+		}
+		if !changed {
+			fmt.Fprintf(w, "\nChanged display expressions:\n")
+			changed = true
+		}
+		fmt.Fprintf(w, "\t%d: %s = %s (was %s)\n", i, e.expr, dv.value, dv.prev)
+	}
+
+	patterns, err := t.client.BugPatterns(api.EvalScope{GoroutineID: -1})
+	if err == nil && len(patterns) > 0 {
+		fmt.Fprintf(w, "\nWarnings:\n")
+		for i, p := range patterns {
+			if i >= summaryMaxWarnings {
+				fmt.Fprintf(w, "\t... and %d more\n", len(patterns)-i)
+				break
+			}
+			fmt.Fprintf(w, "\t%s\n", p.Message)
+		}
+	}
+	return nil
+}
+
+// stopReason describes why the target stopped.
+func stopReason(t *Term, state *api.DebuggerState) string {
+	if state.Exited {
+		return fmt.Sprintf("process exited with status %d", state.ExitStatus)
+	}
+	th := state.CurrentThread
+	if th == nil {
+		return "unknown, no current thread"
+	}
+	loc := t.formatLocation(api.Location{PC: th.PC, File: th.File, Line: th.Line, Function: th.Function})
+	bp := th.Breakpoint
+	switch {
+	case bp == nil && state.NextInProgress:
+		return fmt.Sprintf("interrupted during next/step at %s", loc)
+	case bp == nil:
+		return fmt.Sprintf("manual stop or step at %s", loc)
+	case bp.Name == api.UnrecoveredPanicBreakpointName:
+		return fmt.Sprintf("unrecovered panic at %s", loc)
+	case bp.Name == api.FatalThrowBreakpointName:
+		return fmt.Sprintf("fatal error at %s", loc)
+	default:
+		return fmt.Sprintf("%s at %s (hits goroutine(%d):%d total:%d)", summaryBreakpointName(bp), loc, th.GoroutineID, bp.HitCount[strconv.Itoa(th.GoroutineID)], bp.TotalHitCount)
+	}
+}
+
+func summaryBreakpointName(bp *api.Breakpoint) string {
+	id := bp.Name
+	if id == "" {
+		id = strconv.Itoa(bp.ID)
+	}
+	if bp.WatchExpr != "" {
+		return fmt.Sprintf("watchpoint %s on [%s]", id, bp.WatchExpr)
+	}
+	return "breakpoint " + id
+}
+
+// writeGoroutineCounts writes the number of goroutines of the target in
+// each state.
+func writeGoroutineCounts(t *Term, w io.Writer) error {
+	counts := make(map[string]int)
+	total := 0
+	for start := 0; start >= 0; {
+		var gs []*api.Goroutine
+		var err error
+		gs, start, err = t.client.ListGoroutines(start, goroutineBatchSize)
+		if err != nil {
+			return err
+		}
+		for _, g := range gs {
+			counts[goroutineStatusString(g)]++
+		}
+		total += len(gs)
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	fmt.Fprintf(w, "\nGoroutines: %d\n", total)
+	for _, name := range names {
+		fmt.Fprintf(w, "\t%d %s\n", counts[name], name)
+	}
+	return nil
+}
+
+func goroutineStatusString(g *api.Goroutine) string {
+	switch {
+	case g.Unreadable != "":
+		return "unreadable"
+	case g.Status == api.GoroutineSyscall:
+		return "in syscall"
+	case g.ThreadID != 0:
+		return "running"
+	case g.Status == api.GoroutineRunnable:
+		return "runnable"
+	case g.Status == api.GoroutineWaiting:
+		if g.WaitReason != 0 {
+			return "waiting (" + api.WaitReasonString(g.WaitReason) + ")"
+		}
+		return "waiting"
+	default:
+		return fmt.Sprintf("status %d", g.Status)
+	}
+}
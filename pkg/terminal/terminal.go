@@ -54,6 +54,7 @@ type Term struct {
 	cmds         *Commands
 	stdout       io.Writer
 	InitFile     string
+	Project      *api.Project // restored before executing InitFile
 	displays     []displayEntry
 	colorEscapes map[colorize.Style]string
 
@@ -63,6 +64,15 @@ type Term struct {
 
 	substitutePathRulesCache [][2]string
 
+	// presetTracepoints maps the IDs of the breakpoints set by
+	// 'trace -preset' to their tracepoint, presetCalls are the calls to
+	// those tracepoints that did not return yet.
+	presetTracepoints map[int]*presetTracepoint
+	presetCalls       map[presetCallKey]presetCall
+	// userTracePresets are the presets of 'trace -preset' defined by
+	// starlark scripts.
+	userTracePresets map[string][]presetTracepoint
+
 	// quitContinue is set to true by exitCommand to signal that the process
 	// should be resumed before quitting.
 	quitContinue bool
@@ -72,6 +82,13 @@ type Term struct {
 
 	quittingMutex sync.Mutex
 	quitting      bool
+
+	// stops counts the times the target stopped, displayValues and
+	// recentHits record what happened at the last stops for the summary
+	// command.
+	stops         int
+	displayValues map[displayEntry]*displayValue
+	recentHits    []recentHit
 }
 
 type displayEntry struct {
@@ -244,6 +261,14 @@ func (t *Term) Run() (int, error) {
 
 	fmt.Println("Type 'help' for list of commands.")
 
+	if multiClient {
+		t.resync()
+	}
+
+	if t.Project != nil {
+		t.loadProject(t.Project)
+	}
+
 	if t.InitFile != "" {
 		err := t.cmds.executeFile(t, t.InitFile)
 		if err != nil {
@@ -368,6 +393,68 @@ func yesno(line *liner.State, question string) (bool, error) {
 	}
 }
 
+// resyncOutputSize is the number of bytes of the most recent output of the
+// target printed by resync.
+const resyncOutputSize = 4096
+
+// resync prints what happened before the terminal connected to a headless
+// instance that was already running the target, for example after a
+// previous client disconnected: the output of the target kept by the
+// server (only if it was started with --capture-output), the breakpoints
+// and where the target is stopped.
+func (t *Term) resync() {
+	events, err := t.client.WaitForEvents(0, 0)
+	if err != nil {
+		return
+	}
+	resumed := false
+	var exited *api.Event
+	for i := range events {
+		switch events[i].Kind {
+		case api.EventResumed:
+			resumed = true
+			exited = nil
+		case api.EventExited:
+			exited = &events[i]
+		}
+	}
+	if !resumed {
+		// The target never ran, there is nothing to resynchronize.
+		return
+	}
+
+	if output, err := t.client.GetOutput(-resyncOutputSize, 0); err == nil && len(output.Chunks) > 0 {
+		fmt.Fprintln(t.stdout, "Recent output of the target:")
+		for _, c := range output.Chunks {
+			fmt.Fprint(t.stdout, c.Data)
+		}
+		if !strings.HasSuffix(output.Chunks[len(output.Chunks)-1].Data, "\n") {
+			fmt.Fprintln(t.stdout)
+		}
+	}
+
+	if exited != nil {
+		fmt.Fprintf(t.stdout, "Process has exited with status %d\n", exited.ExitStatus)
+		return
+	}
+
+	if bps, err := t.client.ListBreakpoints(); err == nil {
+		n := 0
+		for _, bp := range bps {
+			if bp.ID > 0 {
+				n++
+			}
+		}
+		if n > 0 {
+			fmt.Fprintf(t.stdout, "%d breakpoint(s) set, use 'breakpoints' to list them.\n", n)
+		}
+	}
+
+	if state, err := t.client.GetState(); err == nil && !state.Exited {
+		printcontext(t, state)
+	}
+}
+
 func (t *Term) handleExit() (int, error) {
 	if t.historyFile != nil {
 		if _, err := t.line.WriteHistory(t.historyFile); err != nil {
@@ -477,28 +564,75 @@ func (t *Term) addDisplay(expr, fmtstr string) {
 }
 
 func (t *Term) printDisplay(i int) {
-	expr, fmtstr := t.displays[i].expr, t.displays[i].fmtstr
+	expr := t.displays[i].expr
 	val, err := t.client.EvalVariable(api.EvalScope{GoroutineID: -1}, expr, ShortLoadConfig)
 	if err != nil {
 		if isErrProcessExited(err) {
 			return
 		}
-		fmt.Printf("%d: %s = error %v\n", i, expr, err)
+		t.printDisplayResult(i, nil, err.Error())
+		return
+	}
+	t.printDisplayResult(i, val, "")
+}
+
+func (t *Term) printDisplayResult(i int, val *api.Variable, errmsg string) {
+	if errmsg != "" {
+		fmt.Printf("%d: %s = error %s\n", i, t.displays[i].expr, errmsg)
 		return
 	}
-	fmt.Printf("%d: %s = %s\n", i, val.Name, val.SinglelineStringFormatted(fmtstr))
+	value := val.SinglelineStringFormatted(t.displays[i].fmtstr)
+	t.recordDisplayValue(t.displays[i], value)
+	fmt.Printf("%d: %s = %s\n", i, val.Name, value)
 }
 
+// printDisplays evaluates all display expressions with a single request,
+// falling back to one request per expression for servers that do not
+// support EvalBatch.
 func (t *Term) printDisplays() {
+	var idx []int
+	var exprs []api.EvalBatchExpr
 	for i := range t.displays {
 		if t.displays[i].expr != "" {
+			idx = append(idx, i)
+			exprs = append(exprs, api.EvalBatchExpr{Expr: t.displays[i].expr})
+		}
+	}
+	if len(exprs) == 0 {
+		return
+	}
+	results, err := t.client.EvalBatch(api.EvalScope{GoroutineID: -1}, exprs, ShortLoadConfig)
+	if err != nil {
+		for _, i := range idx {
 			t.printDisplay(i)
 		}
+		return
+	}
+	for j, i := range idx {
+		if strings.Contains(results[j].Err, "has exited with status") {
+			return
+		}
+		t.printDisplayResult(i, results[j].Variable, results[j].Err)
 	}
 }
 
 func (t *Term) onStop() {
+	t.stops++
+	t.recordHits()
 	t.printDisplays()
+	if t.conf != nil && t.conf.CheckBugPatterns {
+		t.printBugPatterns()
+	}
+}
+
+func (t *Term) printBugPatterns() {
+	patterns, err := t.client.BugPatterns(api.EvalScope{GoroutineID: -1})
+	if err != nil {
+		return
+	}
+	for _, p := range patterns {
+		fmt.Printf("Warning: %s\n", p.Message)
+	}
 }
 
 func (t *Term) longCommandCancel() {
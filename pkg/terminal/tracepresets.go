@@ -0,0 +1,378 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-delve/delve/pkg/terminal/starbind"
+	"github.com/go-delve/delve/service/api"
+	"github.com/go-delve/delve/service/rpc2"
+)
+
+// presetTracepoint is a tracepoint, installed by 'trace -preset', on the
+// entry and on the returns of a function. Its notifications describe each
+// call with the values of vars, evaluated when the function is entered,
+// and its result with the values of retVars and the return values of the
+// function, evaluated when it returns. Unnamed return values are named
+// ~r0, ~r1, etc.
+//
+// Besides the presets in tracePresets, presets can be defined by starlark
+// scripts, see registerTracePreset.
+type presetTracepoint struct {
+	name    string
+	fn      string
+	vars    []string
+	retVars []string
+	// call and result return the description of a call and of its result
+	// from the values of vars and retVars.
+	call   func(vals presetValues) string
+	result func(vals presetValues) string
+}
+
+// tracePresets are the presets of 'trace -preset'.
+var tracePresets = map[string][]presetTracepoint{
+	"http": {
+		{
+			name:    "http server",
+			fn:      "net/http.serverHandler.ServeHTTP",
+			vars:    []string{"req.Method", "req.RequestURI"},
+			retVars: []string{"rw.(data).status"},
+			call: func(vals presetValues) string {
+				return vals.get("req.Method") + " " + vals.get("req.RequestURI")
+			},
+			result: func(vals presetValues) string {
+				status := vals.get("rw.(data).status")
+				if status == "0" {
+					// The handler did not write a response, net/http replies
+					// with an empty 200 response.
+					status = "200"
+				}
+				return status
+			},
+		},
+		{
+			name:    "http client",
+			fn:      "net/http.(*Client).do",
+			vars:    []string{"req.Method", "req.URL.Scheme", "req.URL.Host", "req.URL.Path", "req.URL.RawQuery"},
+			retVars: []string{"retres.StatusCode", "reterr"},
+			call: func(vals presetValues) string {
+				url := vals.get("req.URL.Scheme") + "://" + vals.get("req.URL.Host") + vals.get("req.URL.Path")
+				if query := vals.get("req.URL.RawQuery"); query != "" && query != "?" {
+					url += "?" + query
+				}
+				return vals.get("req.Method") + " " + url
+			},
+			result: func(vals presetValues) string {
+				if status, ok := vals["retres.StatusCode"]; ok && status.Unreadable == "" {
+					return status.Value
+				}
+				return "error " + vals.get("reterr")
+			},
+		},
+	},
+	"grpc": {
+		{
+			name:    "grpc server",
+			fn:      "google.golang.org/grpc.(*Server).processUnaryRPC",
+			vars:    []string{"stream.method"},
+			retVars: []string{"err"},
+			call:    grpcPresetCall("stream.method", ""),
+			result:  errorPresetResult("err", "OK"),
+		},
+		{
+			name:    "grpc server stream",
+			fn:      "google.golang.org/grpc.(*Server).processStreamingRPC",
+			vars:    []string{"stream.method"},
+			retVars: []string{"err"},
+			call:    grpcPresetCall("stream.method", ""),
+			result:  errorPresetResult("err", "OK"),
+		},
+		{
+			name:   "grpc client",
+			fn:     "google.golang.org/grpc.(*ClientConn).Invoke",
+			vars:   []string{"method", "cc.target"},
+			call:   grpcPresetCall("method", "cc.target"),
+			result: errorPresetResult("~r0", "OK"),
+		},
+		{
+			name:   "grpc client stream",
+			fn:     "google.golang.org/grpc.(*ClientConn).NewStream",
+			vars:   []string{"method", "cc.target"},
+			call:   grpcPresetCall("method", "cc.target"),
+			result: errorPresetResult("~r1", "OK"),
+		},
+	},
+	"sql": {
+		{
+			name:   "sql query",
+			fn:     "database/sql.(*DB).QueryContext",
+			vars:   []string{"query", "args"},
+			call:   sqlPresetCall("query"),
+			result: errorPresetResult("~r1", "ok"),
+		},
+		{
+			name:   "sql exec",
+			fn:     "database/sql.(*DB).ExecContext",
+			vars:   []string{"query", "args"},
+			call:   sqlPresetCall("query"),
+			result: errorPresetResult("~r1", "ok"),
+		},
+		{
+			name:   "sql tx query",
+			fn:     "database/sql.(*Tx).QueryContext",
+			vars:   []string{"query", "args"},
+			call:   sqlPresetCall("query"),
+			result: errorPresetResult("~r1", "ok"),
+		},
+		{
+			name:   "sql tx exec",
+			fn:     "database/sql.(*Tx).ExecContext",
+			vars:   []string{"query", "args"},
+			call:   sqlPresetCall("query"),
+			result: errorPresetResult("~r1", "ok"),
+		},
+		{
+			name:   "sql stmt query",
+			fn:     "database/sql.(*Stmt).QueryContext",
+			vars:   []string{"s.query", "args"},
+			call:   sqlPresetCall("s.query"),
+			result: errorPresetResult("~r1", "ok"),
+		},
+		{
+			name:   "sql stmt exec",
+			fn:     "database/sql.(*Stmt).ExecContext",
+			vars:   []string{"s.query", "args"},
+			call:   sqlPresetCall("s.query"),
+			result: errorPresetResult("~r1", "ok"),
+		},
+	},
+}
+
+// grpcPresetCall describes a call to a gRPC method, the full name of the
+// method is the value of method and, for clients, the address of the
+// server the value of target.
+func grpcPresetCall(method, target string) func(vals presetValues) string {
+	return func(vals presetValues) string {
+		call := vals.get(method)
+		if target != "" {
+			call += " " + vals.get(target)
+		}
+		return call
+	}
+}
+
+// sqlPresetCall describes a call executing the SQL statement query with
+// the arguments args.
+func sqlPresetCall(query string) func(vals presetValues) string {
+	return func(vals presetValues) string {
+		call := vals.get(query)
+		if args, ok := vals["args"]; ok && args.Unreadable == "" && args.Len > 0 {
+			call += " " + vals.get("args")
+		}
+		return call
+	}
+}
+
+// errorPresetResult describes the result of a call that returns the error
+// err, ok is the description of a successful call.
+func errorPresetResult(err, ok string) func(vals presetValues) string {
+	return func(vals presetValues) string {
+		if vals.isNil(err) {
+			return ok
+		}
+		return "error " + vals.get(err)
+	}
+}
+
+// presetValues are the values of the variables of a preset tracepoint,
+// by expression.
+type presetValues map[string]api.Variable
+
+// get returns the value of expr, or "?" if it could not be read.
+func (vals presetValues) get(expr string) string {
+	v, ok := vals[expr]
+	if !ok || v.Unreadable != "" {
+		return "?"
+	}
+	if v.Kind == reflect.String || len(v.Children) == 0 {
+		return v.Value
+	}
+	return v.SinglelineString()
+}
+
+// isNil returns true if the value of expr is a nil interface.
+func (vals presetValues) isNil(expr string) bool {
+	v, ok := vals[expr]
+	if !ok || v.Unreadable != "" || v.Kind != reflect.Interface {
+		return false
+	}
+	return v.Addr == 0 || len(v.Children) == 0 || (v.Children[0].Kind == reflect.Invalid && v.Children[0].Addr == 0)
+}
+
+// presetCallKey identifies a call to a preset tracepoint that did not
+// return yet.
+type presetCallKey struct {
+	tp          *presetTracepoint
+	goroutineID int
+}
+
+// presetCall is a call to a preset tracepoint that did not return yet.
+type presetCall struct {
+	call  string
+	start time.Time
+}
+
+// setPresetTracepoints installs the tracepoints of the preset opts.preset.
+// Functions that are not part of the target are skipped.
+func setPresetTracepoints(t *Term, ctx callContext, opts tracepointOptions) error {
+	preset, ok := t.userTracePresets[opts.preset]
+	if !ok {
+		preset, ok = tracePresets[opts.preset]
+	}
+	if !ok {
+		return fmt.Errorf("unknown preset %q, available presets: %s", opts.preset, t.presetNames())
+	}
+	if opts.aggregate {
+		return errors.New("-aggregate can not be used with -preset")
+	}
+	if t.presetTracepoints == nil {
+		t.presetTracepoints = make(map[int]*presetTracepoint)
+		t.presetCalls = make(map[presetCallKey]presetCall)
+	}
+	set := 0
+	for i := range preset {
+		tp := &preset[i]
+		locs, err := t.client.FindLocation(ctx.Scope, tp.fn, true, t.substitutePathRules())
+		if err != nil || len(locs) == 0 {
+			continue
+		}
+		bp, err := t.client.CreateBreakpoint(&api.Breakpoint{
+			Addr:       locs[0].PC,
+			Addrs:      locs[0].PCs,
+			Tracepoint: true,
+			Stacktrace: opts.depth,
+			Variables:  tp.vars,
+		})
+		if err != nil {
+			return err
+		}
+		t.presetTracepoints[bp.ID] = tp
+		addrs, err := t.client.(*rpc2.RPCClient).FunctionReturnLocations(tp.fn)
+		if err != nil {
+			return err
+		}
+		for _, addr := range addrs {
+			retbp, err := t.client.CreateBreakpoint(&api.Breakpoint{
+				Addr:        addr,
+				TraceReturn: true,
+				Line:        -1,
+				Variables:   tp.retVars,
+				LoadArgs:    &ShortLoadConfig,
+			})
+			if err != nil {
+				return err
+			}
+			t.presetTracepoints[retbp.ID] = tp
+		}
+		fmt.Printf("%s tracepoint set at %s\n", tp.name, t.formatBreakpointLocation(bp))
+		set++
+	}
+	if set == 0 {
+		return fmt.Errorf("the functions traced by preset %q are not part of the target", opts.preset)
+	}
+	return nil
+}
+
+// printPresetTracepoint prints the notification of a tracepoint set by
+// 'trace -preset', it returns false if th is not stopped at one.
+func (t *Term) printPresetTracepoint(th *api.Thread) bool {
+	tp := t.presetTracepoints[th.Breakpoint.ID]
+	if tp == nil {
+		return false
+	}
+	vals := presetValues{}
+	if th.BreakpointInfo != nil {
+		for _, v := range th.BreakpointInfo.Variables {
+			vals[v.Name] = v
+		}
+	}
+	for _, v := range th.ReturnValues {
+		vals[v.Name] = v
+	}
+	key := presetCallKey{tp, th.GoroutineID}
+	if th.Breakpoint.Tracepoint {
+		call := tp.call(vals)
+		t.presetCalls[key] = presetCall{call: call, start: time.Now()}
+		fmt.Fprintf(os.Stderr, "> goroutine(%d): %s %s\n", th.GoroutineID, tp.name, call)
+	} else {
+		call, ok := t.presetCalls[key]
+		delete(t.presetCalls, key)
+		if !ok {
+			// The call started before the tracepoint was set.
+			call.call = "?"
+		}
+		fmt.Fprintf(os.Stderr, "> goroutine(%d): %s %s => %s", th.GoroutineID, tp.name, call.call, tp.result(vals))
+		if !call.start.IsZero() {
+			fmt.Fprintf(os.Stderr, " (%v)", time.Since(call.start).Round(time.Microsecond))
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+	if th.BreakpointInfo != nil && th.BreakpointInfo.Stacktrace != nil {
+		fmt.Fprintf(os.Stderr, "\tStack:\n")
+		printStack(t, os.Stderr, th.BreakpointInfo.Stacktrace, "\t\t", false)
+	}
+	return true
+}
+
+// presetNames returns the names of the presets of 'trace -preset'.
+func (t *Term) presetNames() string {
+	names := make([]string, 0, len(tracePresets)+len(t.userTracePresets))
+	for name := range tracePresets {
+		names = append(names, name)
+	}
+	for name := range t.userTracePresets {
+		if _, builtin := tracePresets[name]; !builtin {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// registerTracePreset adds the preset name, defined by a starlark script,
+// to the presets of 'trace -preset', replacing any preset with the same
+// name. Errors of its decoders are displayed in place of the description
+// they failed to produce.
+func (t *Term) registerTracePreset(name string, tracepoints []starbind.TracePresetTracepoint) {
+	preset := make([]presetTracepoint, len(tracepoints))
+	for i := range tracepoints {
+		tp := &tracepoints[i]
+		preset[i] = presetTracepoint{
+			name:    tp.Name,
+			fn:      tp.Function,
+			vars:    tp.Vars,
+			retVars: tp.RetVars,
+			call:    starlarkPresetDecoder(tp.Call),
+			result:  starlarkPresetDecoder(tp.Result),
+		}
+	}
+	if t.userTracePresets == nil {
+		t.userTracePresets = make(map[string][]presetTracepoint)
+	}
+	t.userTracePresets[name] = preset
+}
+
+func starlarkPresetDecoder(decode func(map[string]api.Variable) (string, error)) func(vals presetValues) string {
+	return func(vals presetValues) string {
+		s, err := decode(vals)
+		if err != nil {
+			return fmt.Sprintf("<decoder error: %v>", err)
+		}
+		return s
+	}
+}
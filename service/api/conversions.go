@@ -20,23 +20,27 @@ import (
 // an api.Breakpoint.
 func ConvertBreakpoint(bp *proc.Breakpoint) *Breakpoint {
 	b := &Breakpoint{
-		Name:          bp.Name,
-		ID:            bp.LogicalID,
-		FunctionName:  bp.FunctionName,
-		File:          bp.File,
-		Line:          bp.Line,
-		Addr:          bp.Addr,
-		Tracepoint:    bp.Tracepoint,
-		TraceReturn:   bp.TraceReturn,
-		Stacktrace:    bp.Stacktrace,
-		Goroutine:     bp.Goroutine,
-		Variables:     bp.Variables,
-		LoadArgs:      LoadConfigFromProc(bp.LoadArgs),
-		LoadLocals:    LoadConfigFromProc(bp.LoadLocals),
-		WatchExpr:     bp.WatchExpr,
-		WatchType:     WatchType(bp.WatchType),
-		TotalHitCount: bp.TotalHitCount,
-		Addrs:         []uint64{bp.Addr},
+		Name:            bp.Name,
+		Note:            bp.Note,
+		ID:              bp.LogicalID,
+		FunctionName:    bp.FunctionName,
+		File:            bp.File,
+		Line:            bp.Line,
+		Addr:            bp.Addr,
+		Tracepoint:      bp.Tracepoint,
+		TraceReturn:     bp.TraceReturn,
+		Stacktrace:      bp.Stacktrace,
+		AggregateStacks: bp.AggregateStacks,
+		SerializeHits:   bp.SerializeHits,
+		Goroutine:       bp.Goroutine,
+		Variables:       bp.Variables,
+		LoadArgs:        LoadConfigFromProc(bp.LoadArgs),
+		LoadLocals:      LoadConfigFromProc(bp.LoadLocals),
+		LoadLocalsPaths: bp.LoadLocalsPaths,
+		WatchExpr:       bp.WatchExpr,
+		WatchType:       WatchType(bp.WatchType),
+		TotalHitCount:   bp.TotalHitCount,
+		Addrs:           []uint64{bp.Addr},
 	}
 
 	b.HitCount = map[string]uint64{}
@@ -293,7 +297,7 @@ func ConvertGoroutine(tgt *proc.Target, g *proc.G) *Goroutine {
 	if g.Unreadable != nil {
 		return &Goroutine{Unreadable: g.Unreadable.Error()}
 	}
-	return &Goroutine{
+	r := &Goroutine{
 		ID:             g.ID,
 		CurrentLoc:     ConvertLocation(g.CurrentLoc),
 		UserCurrentLoc: ConvertLocation(g.UserCurrent()),
@@ -304,7 +308,12 @@ func ConvertGoroutine(tgt *proc.Target, g *proc.G) *Goroutine {
 		WaitReason:     g.WaitReason,
 		Labels:         g.Labels(),
 		Status:         g.Status,
+		New:            tgt.IsNewGoroutine(g.ID),
 	}
+	if a, ok := tgt.GoroutineAnnotation(g.ID); ok {
+		r.Name, r.Note = a.Name, a.Note
+	}
+	return r
 }
 
 // ConvertGoroutines converts from []*proc.G to []*api.Goroutine.
@@ -316,13 +325,42 @@ func ConvertGoroutines(tgt *proc.Target, gs []*proc.G) []*Goroutine {
 	return goroutines
 }
 
+// ConvertAnnotation converts from proc.Annotation to api.Annotation.
+func ConvertAnnotation(a proc.Annotation) Annotation {
+	return Annotation{
+		GoroutineID: a.GoroutineID,
+		Addr:        a.Addr,
+		Size:        a.Size,
+		Name:        a.Name,
+		Note:        a.Note,
+	}
+}
+
 // ConvertLocation converts from proc.Location to api.Location.
 func ConvertLocation(loc proc.Location) Location {
+	fn := ConvertFunction(loc.Fn)
+	if fn == nil && loc.Symbol != "" {
+		// function without debug symbols
+		fn = &Function{Name_: loc.Symbol, Optimized: true}
+	}
 	return Location{
 		PC:       loc.PC,
 		File:     loc.File,
 		Line:     loc.Line,
-		Function: ConvertFunction(loc.Fn),
+		Function: fn,
+	}
+}
+
+// ConvertPseudoSourceLine converts from proc.PseudoSourceLine to
+// api.PseudoSourceLine.
+func ConvertPseudoSourceLine(line proc.PseudoSourceLine) PseudoSourceLine {
+	return PseudoSourceLine{
+		Label: line.Label,
+		PC:    line.PC,
+		End:   line.End,
+		File:  line.File,
+		Line:  line.Line,
+		Text:  line.Text,
 	}
 }
 
@@ -423,6 +461,48 @@ func ConvertRegisters(in *op.DwarfRegisters, dwarfRegisterToString func(int, *op
 	return
 }
 
+// ConvertMemoryWrite converts a proc.MemoryWrite to an api.MemoryWrite.
+func ConvertMemoryWrite(w *proc.MemoryWrite) MemoryWrite {
+	return MemoryWrite{
+		ID:     w.ID,
+		Addr:   w.Addr,
+		Old:    w.Old,
+		New:    w.New,
+		Client: w.Client,
+		Origin: w.Origin,
+		Undone: w.Undone,
+	}
+}
+
+// ConvertValueChunk converts from proc.ValueChunk to api.ValueChunk.
+func ConvertValueChunk(c *proc.ValueChunk) *ValueChunk {
+	r := &ValueChunk{Len: c.Len, Offset: c.Offset, Data: c.Data}
+	if c.Elems != nil {
+		r.Elems = make([]Variable, len(c.Elems))
+		for i := range c.Elems {
+			r.Elems[i] = *ConvertVar(&c.Elems[i])
+		}
+	}
+	return r
+}
+
+// ConvertTypeLayout converts from proc.TypeLayout to api.TypeLayout.
+func ConvertTypeLayout(l *proc.TypeLayout) *TypeLayout {
+	r := &TypeLayout{
+		Name:  l.Name,
+		Kind:  l.Kind,
+		Size:  l.Size,
+		Align: l.Align,
+		Elem:  l.Elem,
+		Key:   l.Key,
+		Len:   l.Len,
+	}
+	for _, f := range l.Fields {
+		r.Fields = append(r.Fields, FieldLayout(f))
+	}
+	return r
+}
+
 func ConvertImage(image *proc.Image) Image {
 	return Image{Path: image.Path, Address: image.StaticBase}
 }
@@ -443,3 +523,64 @@ func ConvertDumpState(dumpState *proc.DumpState) *DumpState {
 	}
 	return r
 }
+
+// ConvertSchedState converts from proc.SchedState to api.SchedState.
+func ConvertSchedState(s *proc.SchedState) *SchedState {
+	r := &SchedState{
+		GlobalRunq: s.GlobalRunq,
+		NMIdle:     s.NMIdle,
+		NMSpinning: s.NMSpinning,
+		NPIdle:     s.NPIdle,
+	}
+	r.Ps = make([]SchedP, len(s.Ps))
+	for i, p := range s.Ps {
+		r.Ps[i] = SchedP{ID: p.ID, Status: p.Status, MID: p.MID, Runnext: p.Runnext, Runq: p.Runq, Schedtick: p.Schedtick, Syscalltick: p.Syscalltick}
+	}
+	r.Ms = make([]SchedM, len(s.Ms))
+	for i, m := range s.Ms {
+		r.Ms[i] = SchedM(m)
+	}
+	return r
+}
+
+// ConvertValueProvenance converts a proc.ValueProvenance to an
+// api.ValueProvenance, writerText is the text of the instruction that wrote
+// the variable.
+func ConvertValueProvenance(p *proc.ValueProvenance, writerText string) *ValueProvenance {
+	r := &ValueProvenance{
+		Variable:    *ConvertVar(p.Variable),
+		Storage:     p.Storage,
+		InStatement: p.InStatement,
+		Kind:        ValueProvenanceKind(p.Kind),
+		Source:      p.Source,
+	}
+	if p.Writer != nil {
+		writer := ConvertAsmInstruction(*p.Writer, writerText)
+		r.Writer = &writer
+	}
+	return r
+}
+
+// ConvertVariableReference converts a proc.VariableReference to an
+// api.VariableReference, text is the text of its instruction.
+func ConvertVariableReference(ref proc.VariableReference, text string) VariableReference {
+	return VariableReference{Kind: VariableReferenceKind(ref.Kind), Inst: ConvertAsmInstruction(ref.Inst, text)}
+}
+
+// ConvertFrameUnwindInfo converts a proc.FrameUnwindInfo to an
+// api.FrameUnwindInfo.
+func ConvertFrameUnwindInfo(info *proc.FrameUnwindInfo) *FrameUnwindInfo {
+	r := &FrameUnwindInfo{
+		PC:         info.PC,
+		FDEStart:   info.FDEStart,
+		FDEEnd:     info.FDEEnd,
+		CFA:        info.CFA,
+		CFAValue:   info.CFAValue,
+		RetAddrReg: info.RetAddrReg,
+		Regs:       make([]RegisterRule, len(info.Regs)),
+	}
+	for i, rule := range info.Regs {
+		r.Regs[i] = RegisterRule(rule)
+	}
+	return r
+}
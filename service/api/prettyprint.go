@@ -504,6 +504,28 @@ func digits(n int) int {
 	return int(math.Floor(math.Log10(float64(n)))) + 1
 }
 
+// PrintDefers prints a chain of deferred calls and their arguments.
+func PrintDefers(formatPath func(string) string, out io.Writer, defers []Defer) {
+	d := digits(len(defers))
+	for i := range defers {
+		deferHeader := fmt.Sprintf("%"+strconv.Itoa(d)+"d  ", i+1)
+		s := strings.Repeat(" ", len(deferHeader))
+		if defers[i].Unreadable != "" && defers[i].DeferredLoc.PC == 0 {
+			fmt.Fprintf(out, "%s(unreadable defer: %s)\n", deferHeader, defers[i].Unreadable)
+			continue
+		}
+		fmt.Fprintf(out, "%s%#016x in %s\n", deferHeader, defers[i].DeferredLoc.PC, defers[i].DeferredLoc.Function.Name())
+		fmt.Fprintf(out, "%sat %s:%d\n", s, formatPath(defers[i].DeferredLoc.File), defers[i].DeferredLoc.Line)
+		fmt.Fprintf(out, "%sdeferred by %s at %s:%d\n", s, defers[i].DeferLoc.Function.Name(), formatPath(defers[i].DeferLoc.File), defers[i].DeferLoc.Line)
+		if defers[i].Unreadable != "" {
+			fmt.Fprintf(out, "%s    (%s)\n", s, defers[i].Unreadable)
+		}
+		for j := range defers[i].Arguments {
+			fmt.Fprintf(out, "%s    %s = %s\n", s, defers[i].Arguments[j].Name, defers[i].Arguments[j].SinglelineString())
+		}
+	}
+}
+
 func PrintStack(formatPath func(string) string, out io.Writer, stack []Stackframe, ind string, offsets bool, include func(Stackframe) bool) {
 	if len(stack) == 0 {
 		return
@@ -529,7 +551,11 @@ func PrintStack(formatPath func(string) string, out io.Writer, stack []Stackfram
 			fmt.Fprintf(out, "%serror: %s\n", s, stack[i].Err)
 			continue
 		}
-		fmt.Fprintf(out, fmtstr, ind, i, stack[i].PC, stack[i].Function.Name())
+		name := stack[i].Function.Name()
+		if stack[i].Approximate {
+			name += " (approximate)"
+		}
+		fmt.Fprintf(out, fmtstr, ind, i, stack[i].PC, name)
 		fmt.Fprintf(out, "%sat %s:%d\n", s, formatPath(stack[i].File), stack[i].Line)
 
 		if offsets {
@@ -560,7 +586,11 @@ func PrintStack(formatPath func(string) string, out io.Writer, stack []Stackfram
 		}
 	}
 
-	if len(stack) > 0 && !stack[len(stack)-1].Bottom {
-		fmt.Fprintf(out, "%s"+stacktraceTruncatedMessage+"\n", ind)
+	if last := stack[len(stack)-1]; !last.Bottom {
+		fmt.Fprintf(out, "%s"+stacktraceTruncatedMessage, ind)
+		if last.Truncated {
+			fmt.Fprintf(out, " about %d more frames", last.Remaining)
+		}
+		fmt.Fprintln(out)
 	}
 }
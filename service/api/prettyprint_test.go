@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"math"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -57,3 +58,43 @@ func Test_byteArrayToUInt64(t *testing.T) {
 		})
 	}
 }
+
+func TestPrintStackApproximate(t *testing.T) {
+	stack := []Stackframe{
+		{Location: Location{PC: 0x1000, File: "main.go", Line: 10, Function: &Function{Name_: "main.f"}}},
+		{Location: Location{PC: 0x2000, File: "main.go", Line: 20, Function: &Function{Name_: "main.main"}}, Approximate: true, Bottom: true},
+	}
+	buf := new(strings.Builder)
+	PrintStack(func(s string) string { return s }, buf, stack, "", false, func(Stackframe) bool { return true })
+	lines := strings.Split(buf.String(), "\n")
+	if strings.Contains(lines[0], "(approximate)") {
+		t.Errorf("unexpected approximate frame %q", lines[0])
+	}
+	if !strings.HasSuffix(lines[2], "in main.main (approximate)") {
+		t.Errorf("expected approximate frame, got %q", lines[2])
+	}
+}
+
+func TestPrintDefers(t *testing.T) {
+	defers := []Defer{
+		{
+			DeferredLoc: Location{PC: 0x1000, File: "main.go", Line: 5, Function: &Function{Name_: "main.report"}},
+			DeferLoc:    Location{PC: 0x2000, File: "main.go", Line: 20, Function: &Function{Name_: "main.main"}},
+			Arguments: []Variable{
+				{Name: "n", Kind: reflect.Int, Type: "int", Value: "42"},
+			},
+		},
+		{Unreadable: "corrupted defer list: SP decreased"},
+	}
+	buf := new(strings.Builder)
+	PrintDefers(func(s string) string { return s }, buf, defers)
+	tgt := `1  0x0000000000001000 in main.report
+   at main.go:5
+   deferred by main.main at main.go:20
+       n = 42
+2  (unreadable defer: corrupted defer list: SP decreased)
+`
+	if buf.String() != tgt {
+		t.Errorf("wrong output:\n%s\nexpected:\n%s", buf.String(), tgt)
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Kinds of project, describing how the target is started.
+const (
+	ProjectDebug = "debug" // the packages are compiled and executed, like 'dlv debug'
+	ProjectTest  = "test"  // the test binary of the package is compiled and executed, like 'dlv test'
+	ProjectExec  = "exec"  // an existing executable is executed, like 'dlv exec'
+)
+
+// Project is the configuration of a debugging session: how the target is
+// started, its breakpoints and tracepoints, the annotations of the user and
+// the settings of the client (displayed expressions, path substitution
+// rules and load limits).
+// Projects are saved by the 'project save' command of the terminal client
+// and restored with 'dlv --project <file>'.
+type Project struct {
+	// Kind is one of ProjectDebug, ProjectTest or ProjectExec, an empty
+	// Kind means that the project can not be used to start the target
+	// (for example if the debugger was attached to a running process).
+	Kind string `json:"kind,omitempty"`
+	// Packages are the packages compiled for ProjectDebug and ProjectTest.
+	Packages []string `json:"packages,omitempty"`
+	// Program is the executable for ProjectExec.
+	Program string `json:"program,omitempty"`
+	// Args are the command line arguments of the target.
+	Args       []string `json:"args,omitempty"`
+	BuildFlags string   `json:"buildFlags,omitempty"`
+	WorkingDir string   `json:"workingDir,omitempty"`
+	Backend    string   `json:"backend,omitempty"`
+
+	// Breakpoints are the user breakpoints, tracepoints and watchpoints, the
+	// fields describing their state (addresses and hit counts) are not saved.
+	Breakpoints []*Breakpoint `json:"breakpoints,omitempty"`
+
+	// Annotations are the names and notes assigned to goroutines and
+	// addresses, the notes of breakpoints are saved with the breakpoints.
+	Annotations []Annotation `json:"annotations,omitempty"`
+
+	Displays       []ProjectDisplay            `json:"displays,omitempty"`
+	SubstitutePath []ProjectSubstitutePathRule `json:"substitutePath,omitempty"`
+
+	MaxStringLen       *int `json:"maxStringLen,omitempty"`
+	MaxArrayValues     *int `json:"maxArrayValues,omitempty"`
+	MaxVariableRecurse *int `json:"maxVariableRecurse,omitempty"`
+}
+
+// ProjectDisplay is an expression printed every time the program stops,
+// see the 'display' command.
+type ProjectDisplay struct {
+	Expr   string `json:"expr"`
+	Format string `json:"format,omitempty"`
+}
+
+// ProjectSubstitutePathRule is a rule converting paths of source files,
+// see the 'config substitute-path' command.
+type ProjectSubstitutePathRule struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ReadProject reads a project file.
+func ReadProject(path string) (*Project, error) {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var p Project
+	if err := json.Unmarshal(buf, &p); err != nil {
+		return nil, fmt.Errorf("could not parse project file %s: %v", path, err)
+	}
+	switch p.Kind {
+	case "", ProjectDebug, ProjectTest, ProjectExec:
+	default:
+		return nil, fmt.Errorf("unknown kind %q in project file %s", p.Kind, path)
+	}
+	return &p, nil
+}
+
+// Save writes p to a project file.
+func (p *Project) Save(path string) error {
+	buf, err := json.MarshalIndent(p, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(buf, '\n'), 0644)
+}
@@ -44,10 +44,22 @@ type DebuggerState struct {
 	ExitStatus int  `json:"exitStatus"`
 	// When contains a description of the current position in a recording
 	When string
+	// PendingHits is the number of simultaneous hits of breakpoints with
+	// SerializeHits set that were not reported with this stop, each of
+	// them will be reported by one of the following continue commands,
+	// without resuming the target. Other commands drop them.
+	PendingHits int `json:"pendingHits,omitempty"`
 	// Filled by RPCClient.Continue, indicates an error
 	Err error `json:"-"`
 }
 
+// Names of the breakpoints set by the debugger to stop the target when it
+// crashes.
+const (
+	UnrecoveredPanicBreakpointName = proc.UnrecoveredPanic
+	FatalThrowBreakpointName       = proc.FatalThrow
+)
+
 // Breakpoint addresses a set of locations at which process execution may be
 // suspended.
 type Breakpoint struct {
@@ -80,14 +92,27 @@ type Breakpoint struct {
 	TraceReturn bool `json:"traceReturn"`
 	// retrieve goroutine information
 	Goroutine bool `json:"goroutine"`
-	// number of stack frames to retrieve
+	// number of stack frames to retrieve, StacktraceAll retrieves the whole
+	// stack
 	Stacktrace int `json:"stacktrace"`
+	// AggregateStacks: if set the breakpoint does not stop the target,
+	// instead the stacks that reach it are counted, see StackRecord
+	AggregateStacks bool `json:"aggregateStacks,omitempty"`
+	// SerializeHits: if set the hits of this breakpoint by goroutines
+	// reaching it at the same time as another breakpoint are reported one
+	// per stop, see DebuggerState.PendingHits. Otherwise they are all
+	// reported in the same stop, in the Breakpoint field of their threads.
+	SerializeHits bool `json:"serializeHits,omitempty"`
 	// expressions to evaluate
 	Variables []string `json:"variables,omitempty"`
 	// LoadArgs requests loading function arguments when the breakpoint is hit
 	LoadArgs *LoadConfig
 	// LoadLocals requests loading function locals when the breakpoint is hit
 	LoadLocals *LoadConfig
+	// LoadLocalsPaths are the selector paths (for example "req.URL.Path")
+	// of the locals that should be loaded with LoadLocals, if not empty the
+	// other locals are loaded shallowly.
+	LoadLocalsPaths []string `json:"loadLocalsPaths,omitempty"`
 
 	// WatchExpr is the expression used to create this watchpoint
 	WatchExpr string
@@ -99,8 +124,15 @@ type Breakpoint struct {
 	TotalHitCount uint64 `json:"totalHitCount"`
 	// Disabled flag, signifying the state of the breakpoint
 	Disabled bool `json:"disabled"`
+	// Note is a note assigned to the breakpoint by the user.
+	Note string `json:"note,omitempty"`
 }
 
+// StacktraceAll is the value of Breakpoint.Stacktrace that retrieves the
+// whole stack of the goroutine hitting the breakpoint.
+// Tracks proc.StacktraceAll
+const StacktraceAll = -1
+
 // ValidBreakpointName returns an error if
 // the name to be chosen for a breakpoint is invalid.
 // The name can not be just a number, and must contain a series
@@ -179,6 +211,20 @@ type Stackframe struct {
 	Defers []Defer
 
 	Bottom bool `json:"Bottom,omitempty"` // Bottom is true if this is the bottom frame of the stack
+	// Inlined is true if this frame is an inlined call, sharing its physical
+	// stack frame with the frame following it.
+	Inlined bool `json:"Inlined,omitempty"`
+
+	// Approximate is true if this frame was found by following the frame
+	// pointer because the call frame information was missing or corrupt.
+	Approximate bool `json:"Approximate,omitempty"`
+
+	// Truncated is true if this is the last frame returned but not the
+	// bottom of the stack, because the requested depth was reached.
+	Truncated bool `json:"Truncated,omitempty"`
+	// Remaining is an estimate of the number of frames below a Truncated
+	// frame.
+	Remaining int `json:"Remaining,omitempty"`
 
 	Err string
 }
@@ -189,6 +235,11 @@ type Defer struct {
 	DeferLoc    Location // location of the defer statement
 	SP          uint64   // value of SP when the function was deferred
 	Unreadable  string
+
+	// Arguments are the arguments that will be passed to the deferred
+	// function, they are only loaded when listing the defer chain of a
+	// goroutine.
+	Arguments []Variable `json:"Arguments,omitempty"`
 }
 
 // Var will return the variable described by 'name' within
@@ -348,11 +399,77 @@ type Goroutine struct {
 	Unreadable string `json:"unreadable"`
 	// Goroutine's pprof labels
 	Labels map[string]string `json:"labels,omitempty"`
+	// Name is the name assigned to the goroutine with SetGoroutineName.
+	Name string `json:"name,omitempty"`
+	// Note is the note assigned to the goroutine with SetAnnotation.
+	Note string `json:"note,omitempty"`
+	// New is true if the goroutine did not exist the last time all
+	// goroutines were listed at a previous stop.
+	New bool `json:"new,omitempty"`
+}
+
+// Annotation is a name and a note assigned by the user to a goroutine or
+// to a range of addresses.
+type Annotation struct {
+	// GoroutineID is the ID of the annotated goroutine, if it is zero the
+	// annotation is for the Size bytes starting at Addr.
+	GoroutineID int    `json:"goroutineID,omitempty"`
+	Addr        uint64 `json:"addr,omitempty"`
+	Size        uint64 `json:"size,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Note        string `json:"note,omitempty"`
+}
+
+// TerminatedGoroutine is a goroutine that existed the last time all
+// goroutines were listed at a previous stop and terminated since.
+type TerminatedGoroutine struct {
+	ID   int    `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+// WaitReasonString returns a description of the WaitReason field of a
+// goroutine, see waitReasonStrings in $GOROOT/src/runtime/runtime2.go.
+func WaitReasonString(reason int64) string {
+	if reason > 0 && reason < int64(len(waitReasonStrings)) {
+		return waitReasonStrings[reason]
+	}
+	return fmt.Sprintf("unknown wait reason %d", reason)
+}
+
+var waitReasonStrings = [...]string{
+	"",
+	"GC assist marking",
+	"IO wait",
+	"chan receive (nil chan)",
+	"chan send (nil chan)",
+	"dumping heap",
+	"garbage collection",
+	"garbage collection scan",
+	"panicwait",
+	"select",
+	"select (no cases)",
+	"GC assist wait",
+	"GC sweep wait",
+	"GC scavenge wait",
+	"chan receive",
+	"chan send",
+	"finalizer wait",
+	"force gc (idle)",
+	"semacquire",
+	"sleep",
+	"sync.Cond.Wait",
+	"timer goroutine (idle)",
+	"trace reader (blocked)",
+	"wait for GC cycle",
+	"GC worker (idle)",
+	"preempted",
+	"debug call",
 }
 
 const (
-	GoroutineWaiting = proc.Gwaiting
-	GoroutineSyscall = proc.Gsyscall
+	GoroutineRunnable = proc.Grunnable
+	GoroutineWaiting  = proc.Gwaiting
+	GoroutineSyscall  = proc.Gsyscall
 )
 
 // DebuggerCommand is a command which changes the debugger's execution state.
@@ -385,6 +502,10 @@ type DebuggerCommand struct {
 	// violate the rules about stack objects you can disable this safety check
 	// by setting UnsafeCall to true.
 	UnsafeCall bool `json:"unsafeCall,omitempty"`
+
+	// Client identifies the client that sent the command, it is set by
+	// the server and recorded with the writes made by a Call command.
+	Client string `json:"-"`
 }
 
 // BreakpointInfo contains informations about the current breakpoint
@@ -394,6 +515,18 @@ type BreakpointInfo struct {
 	Variables  []Variable   `json:"variables,omitempty"`
 	Arguments  []Variable   `json:"arguments,omitempty"`
 	Locals     []Variable   `json:"locals,omitempty"`
+	// TestFailure is set when the target stopped because one of its tests
+	// failed.
+	TestFailure *TestFailure `json:"testFailure,omitempty"`
+}
+
+// TestFailure describes the failure of a test.
+type TestFailure struct {
+	// Test is the full name of the test, subtests are named like
+	// "TestParent/subtest".
+	Test string `json:"test"`
+	// Message is the message of the failure, when it is known.
+	Message string `json:"message,omitempty"`
 }
 
 // EvalScope is the scope a command should
@@ -404,6 +537,35 @@ type EvalScope struct {
 	DeferredCall int // when DeferredCall is n > 0 this eval scope is relative to the n-th deferred call in the current frame
 }
 
+// EvalBatchExpr is an expression evaluated by the EvalBatch API call.
+type EvalBatchExpr struct {
+	Expr string
+	// Scope, if not nil, is the scope the expression is evaluated in
+	// instead of the scope of the call.
+	Scope *EvalScope `json:",omitempty"`
+}
+
+// EvalBatchResult is the result of the evaluation of an expression by the
+// EvalBatch API call: either Variable or Err is set.
+type EvalBatchResult struct {
+	Variable *Variable
+	Err      string `json:",omitempty"`
+}
+
+// ValueChunk is a part of the elements of a string, array or slice,
+// returned by the EvalChunk API call.
+type ValueChunk struct {
+	// Len is the length of the whole value.
+	Len int64
+	// Offset is the index of the first element of the chunk.
+	Offset int64
+	// Data is the content of the elements of the chunk, it is set for
+	// strings and for arrays and slices of bytes.
+	Data []byte `json:",omitempty"`
+	// Elems are the elements of the chunk, for other arrays and slices.
+	Elems []Variable `json:",omitempty"`
+}
+
 const (
 	// Continue resumes process execution.
 	Continue = "continue"
@@ -472,6 +634,22 @@ type AsmInstruction struct {
 // AsmInstructions is a slice of single instructions.
 type AsmInstructions []AsmInstruction
 
+// PseudoSourceLine is a line of the pseudo-source listing of a function,
+// used in place of its source code when it is not available.
+type PseudoSourceLine struct {
+	// Label is the name of the basic block starting at PC, if it is set the
+	// line only marks the start of the block.
+	Label string `json:"label,omitempty"`
+	// PC and End delimit the instructions rendered by this line.
+	PC  uint64 `json:"pc"`
+	End uint64 `json:"end"`
+	// File and Line are the position of the instructions of this line.
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Text is the pseudo-code rendering of the instructions.
+	Text string `json:"text,omitempty"`
+}
+
 // GetVersionIn is the argument for GetVersion.
 type GetVersionIn struct {
 }
@@ -496,6 +674,15 @@ type SetAPIVersionIn struct {
 type SetAPIVersionOut struct {
 }
 
+// AuthenticateIn is the input for Authenticate.
+type AuthenticateIn struct {
+	Token string
+}
+
+// AuthenticateOut is the output for Authenticate.
+type AuthenticateOut struct {
+}
+
 // Register holds information on a CPU register.
 type Register struct {
 	Name        string
@@ -567,6 +754,11 @@ const (
 	// StacktraceG requests a stacktrace starting with the register
 	// values saved in the runtime.g structure.
 	StacktraceG
+
+	// StacktraceNoInline requests a stacktrace where inlined calls are not
+	// reported as separate frames. Note that the frame numbers used by
+	// EvalScope always refer to the stacktrace with inlined calls.
+	StacktraceNoInline
 )
 
 // ImportPathToDirectoryPath maps an import path to a directory path.
@@ -587,6 +779,18 @@ type DumpState struct {
 	Err string
 }
 
+// Artifact is a file created by the debugger, like a core dump, that can
+// be downloaded by clients.
+type Artifact struct {
+	// Path is the absolute path of the file on the machine running the
+	// debugger.
+	Path string
+	// Size is the size of the file in bytes.
+	Size int64
+	// SHA256 is the hex encoded SHA-256 checksum of the file.
+	SHA256 string
+}
+
 // ListGoroutinesFilter describes a filtering condition for the
 // ListGoroutines API call.
 type ListGoroutinesFilter struct {
@@ -607,6 +811,8 @@ const (
 	GoroutineLabel                     // the goroutine's label
 	GoroutineRunning                   // the goroutine is running
 	GoroutineUser                      // the goroutine is a user goroutine
+	GoroutineStack                     // any frame of the goroutine's stacktrace, when grouping the whole stacktrace
+	GoroutineStackArgs                 // any frame of the goroutine's stacktrace or its arguments
 )
 
 // GoroutineGroup represents a group of goroutines in the return value of
@@ -624,3 +830,418 @@ type GoroutineGroupingOptions struct {
 	MaxGroupMembers int
 	MaxGroups       int
 }
+
+// GoroutineProfileFormat is the encoding of the result of the
+// GoroutineProfile API call.
+type GoroutineProfileFormat uint8
+
+const (
+	// GoroutineProfileProto is the gzipped profile.proto format used by pprof.
+	GoroutineProfileProto GoroutineProfileFormat = iota
+	// GoroutineProfileFolded is the folded stacks text format used by
+	// flamegraph tools, one line per distinct stack.
+	GoroutineProfileFolded
+)
+
+// SchedState is a snapshot of the state of the Go scheduler of the target.
+type SchedState struct {
+	Ps []SchedP `json:"ps"`
+	Ms []SchedM `json:"ms"`
+	// GlobalRunq contains the IDs of the goroutines in the global run queue.
+	GlobalRunq []int `json:"globalRunq"`
+	NMIdle     int   `json:"nmIdle"`
+	NMSpinning int   `json:"nmSpinning"`
+	NPIdle     int   `json:"npIdle"`
+}
+
+// SchedP describes a P (processor) of the Go scheduler.
+type SchedP struct {
+	ID     int    `json:"id"`
+	Status uint64 `json:"status"`
+	// MID is the ID of the M owning this P, -1 if none.
+	MID int `json:"mID"`
+	// Runnext is the ID of the goroutine that will run next on this P, 0 if none.
+	Runnext int `json:"runnext"`
+	// Runq contains the IDs of the goroutines in the local run queue.
+	Runq        []int  `json:"runq"`
+	Schedtick   uint64 `json:"schedtick"`
+	Syscalltick uint64 `json:"syscalltick"`
+}
+
+// SchedM describes a M (OS thread) of the Go scheduler.
+type SchedM struct {
+	ID       int `json:"id"`
+	ThreadID int `json:"threadID"`
+	// PID is the ID of the P owned by this M, -1 if none.
+	PID int `json:"pID"`
+	// OldPID is the ID of the P owned by this M before entering a system
+	// call, -1 if none.
+	OldPID int `json:"oldPID"`
+	// CurG is the ID of the goroutine running on this M, 0 if none.
+	CurG int `json:"curG"`
+	// LockedG is the ID of the goroutine locked to this M, 0 if none.
+	LockedG   int  `json:"lockedG"`
+	Spinning  bool `json:"spinning"`
+	Blocked   bool `json:"blocked"`
+	InSyscall bool `json:"inSyscall"`
+}
+
+const (
+	SchedPIdle    = proc.Pidle
+	SchedPRunning = proc.Prunning
+	SchedPSyscall = proc.Psyscall
+	SchedPGCStop  = proc.Pgcstop
+	SchedPDead    = proc.Pdead
+)
+
+// ContentionProfileKind selects the runtime profile returned by the
+// ContentionProfile API call.
+type ContentionProfileKind uint8
+
+const (
+	MutexContentionProfile ContentionProfileKind = iota // profile of contended mutexes
+	BlockContentionProfile                              // profile of blocking events
+)
+
+// ContentionProfile is a snapshot of a contention profile collected by the
+// runtime of the target.
+type ContentionProfile struct {
+	// Rate is the sampling rate of the profile, as set in the target by
+	// runtime.SetMutexProfileFraction or runtime.SetBlockProfileRate. A value
+	// of 0 means that the profile is disabled.
+	Rate int64 `json:"rate"`
+	// Total is the number of call sites in the profile, it can be larger
+	// than len(Records) if the result was truncated.
+	Total   int                `json:"total"`
+	Records []ContentionRecord `json:"records"`
+}
+
+// ContentionRecord is a contended call site.
+type ContentionRecord struct {
+	Count  int64      `json:"count"`
+	Cycles int64      `json:"cycles"`
+	Stack  []Location `json:"stack"`
+}
+
+// StackRecord is a call path that reached a breakpoint with
+// AggregateStacks set and the number of times it did.
+type StackRecord struct {
+	Count uint64     `json:"count"`
+	Stack []Location `json:"stack"`
+}
+
+// BugPatternKind is the kind of mistake described by a BugPattern.
+type BugPatternKind uint8
+
+const (
+	BugPatternSliceAliasing  BugPatternKind = iota // two slices share the same backing array
+	BugPatternNilMapWrite                          // a nil map is about to be written
+	BugPatternLoopVarCapture                       // goroutines share the same loop variable
+)
+
+// BugPattern is a likely mistake detected by the BugPatterns API call.
+type BugPattern struct {
+	Kind    BugPatternKind `json:"kind"`
+	Message string         `json:"message"`
+}
+
+// ValueProvenanceKind describes where the value of a variable came from.
+type ValueProvenanceKind uint8
+
+const (
+	ProvenanceUnknown    ValueProvenanceKind = iota // the instruction that wrote the variable could not be found
+	ProvenanceConstant                              // constant encoded in the instruction
+	ProvenanceParameter                             // copied from a parameter of the function
+	ProvenanceVariable                              // copied from another variable
+	ProvenanceCallResult                            // returned by a function call
+	ProvenanceRegister                              // copied from a register not associated with any variable
+	ProvenanceMemory                                // loaded from memory not associated with any local variable
+	ProvenanceComputed                              // computed by an arithmetic or logic instruction
+)
+
+// ValueProvenance describes where the current value of a variable came
+// from, as determined by analyzing the instructions executed before the
+// current PC.
+type ValueProvenance struct {
+	Variable Variable
+	// Storage lists where the variable is stored at the current PC, either
+	// register names or memory addresses.
+	Storage []string
+	// Writer is the last instruction that wrote the storage of the
+	// variable, nil if it could not be found.
+	Writer *AsmInstruction
+	// InStatement is true if Writer belongs to the current statement.
+	InStatement bool
+	Kind        ValueProvenanceKind
+	// Source is a description of the operand the value was read from.
+	Source string
+}
+
+// VariableReferenceKind describes how an instruction references a package
+// variable.
+type VariableReferenceKind uint8
+
+const (
+	VariableStore        VariableReferenceKind = iota // the instruction writes to the variable
+	VariableAddressTaken                              // the instruction computes the address of the variable
+)
+
+// VariableReference is an instruction of the program that references a
+// package variable.
+type VariableReference struct {
+	Kind VariableReferenceKind `json:"kind"`
+	Inst AsmInstruction        `json:"inst"`
+}
+
+// Kinds of the events of a debugging session, see Event.
+const (
+	// EventResumed is reported when the target is resumed by a command.
+	EventResumed = "resumed"
+	// EventStopped is reported when the target stops after running.
+	EventStopped = "stopped"
+	// EventBreakpoint is reported, after EventStopped, for each thread
+	// stopped at a breakpoint.
+	EventBreakpoint = "breakpoint"
+	// EventOutput is reported when the target writes to its standard
+	// output or error, only if they are captured by the debugger.
+	EventOutput = "output"
+	// EventExited is reported when the target exits.
+	EventExited = "exited"
+	// EventImageLoaded is reported for each image (the executable and
+	// its dynamic libraries) loaded by the target, when the target stops.
+	EventImageLoaded = "imageLoaded"
+	// EventDetached is reported when the debugger detaches from the
+	// target because it stayed stopped for too long, see the --auto-detach
+	// flag of the attach command.
+	EventDetached = "detached"
+	// EventTargetAdded is reported when the debugger starts debugging a
+	// process created by fork or exec, see Target.
+	EventTargetAdded = "targetAdded"
+)
+
+// Streams of EventOutput events.
+const (
+	StdoutStream = "stdout"
+	StderrStream = "stderr"
+)
+
+// Event is an event of the debugging session.
+type Event struct {
+	// Seq is the sequence number of the event, the first event is 1 and
+	// each following event is numbered one more than the previous one.
+	Seq int64 `json:"seq"`
+	// Kind is one of the Event* constants.
+	Kind string `json:"kind"`
+
+	// ThreadID and GoroutineID are the thread and goroutine that stopped,
+	// for EventStopped and EventBreakpoint.
+	ThreadID    int `json:"threadID,omitempty"`
+	GoroutineID int `json:"goroutineID,omitempty"`
+	// BreakpointID and BreakpointName identify the breakpoint of an
+	// EventBreakpoint event.
+	BreakpointID   int    `json:"breakpointID,omitempty"`
+	BreakpointName string `json:"breakpointName,omitempty"`
+	// Stream is StdoutStream or StderrStream and Output what the target
+	// wrote to it, for EventOutput.
+	Stream string `json:"stream,omitempty"`
+	Output string `json:"output,omitempty"`
+	// ExitStatus is the exit status of the target, for EventExited.
+	ExitStatus int `json:"exitStatus,omitempty"`
+	// Pid is the pid of the target, for EventExited and EventTargetAdded.
+	Pid int `json:"pid,omitempty"`
+	// Image is the image of an EventImageLoaded event.
+	Image *Image `json:"image,omitempty"`
+}
+
+// Target is a process debugged by the debugger. Besides the process
+// launched or attached to, the debugger can follow the processes it
+// creates, see the --follow-fork flag.
+type Target struct {
+	Pid int `json:"pid"`
+	// ParentPid is the pid of the target that created this one with fork
+	// or vfork, zero for the target launched or attached to.
+	ParentPid int `json:"parentPid,omitempty"`
+	// Path is the path of the executable of the target.
+	Path string `json:"path"`
+	// Current is true for the target the other commands act on.
+	Current bool `json:"current"`
+	// StopReason is the reason why the target is stopped.
+	StopReason string `json:"stopReason"`
+	// CurrentThread is the current thread of the target.
+	CurrentThread *Thread `json:"currentThread,omitempty"`
+}
+
+// FileBreakpoints describes the lines of a source file where breakpoints
+// can be set and the breakpoints set on them, for example to draw the
+// gutter of an editor.
+type FileBreakpoints struct {
+	File string `json:"file"`
+	// Lines are the lines of File where a breakpoint can be set, in
+	// increasing order.
+	Lines []int `json:"lines"`
+	// Breakpoints are the breakpoints, enabled or disabled, set on lines of
+	// File, ordered by line.
+	Breakpoints []*Breakpoint `json:"breakpoints"`
+}
+
+// OutputChunk is a part of the output of the target.
+type OutputChunk struct {
+	// Offset is the position of the first byte of Data in the output of
+	// the target, counting the bytes written to both streams.
+	Offset int64 `json:"offset"`
+	// Stream is StdoutStream or StderrStream.
+	Stream string `json:"stream"`
+	Data   string `json:"data"`
+}
+
+// TargetOutput is the output of the target kept by the debugger.
+type TargetOutput struct {
+	Chunks []OutputChunk `json:"chunks"`
+	// Start is the offset of the oldest byte kept, the output before it was
+	// discarded.
+	Start int64 `json:"start"`
+	// End is the offset following the last byte written by the target,
+	// clients can use it as the offset of the next request to only receive
+	// new output.
+	End int64 `json:"end"`
+}
+
+// MemoryWrite is a write to the memory of the target made by the debugger.
+type MemoryWrite struct {
+	ID   int    `json:"id"`
+	Addr uint64 `json:"addr"`
+	// Old is the content of the memory before the write, nil if it could
+	// not be read.
+	Old []byte `json:"old"`
+	New []byte `json:"new"`
+	// Client identifies the client that requested the write, for the
+	// JSON-RPC API it is the address of the client.
+	Client string `json:"client,omitempty"`
+	// Origin is the operation that caused the write, like "set x = 1".
+	Origin string `json:"origin,omitempty"`
+	// Undone is true if the write was reverted by UndoMemoryWrite.
+	Undone bool `json:"undone,omitempty"`
+}
+
+// TypeLayout describes how the values of a type are laid out in memory.
+type TypeLayout struct {
+	Name  string       `json:"name"`
+	Kind  reflect.Kind `json:"kind"`
+	Size  int64        `json:"size"`
+	Align int64        `json:"align"`
+	// Elem is the type of the elements of arrays, slices and channels, the
+	// type pointed to by pointers and the type of the values of maps.
+	Elem string `json:"elem,omitempty"`
+	// Key is the type of the keys of maps.
+	Key string `json:"key,omitempty"`
+	// Len is the length of arrays.
+	Len int64 `json:"len,omitempty"`
+	// Fields are the fields of structs, and of the structs used by the Go
+	// runtime to represent strings, slices and interfaces.
+	Fields []FieldLayout `json:"fields,omitempty"`
+}
+
+// FieldLayout describes a field of a struct.
+type FieldLayout struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Offset   int64  `json:"offset"`
+	Size     int64  `json:"size"`
+	Embedded bool   `json:"embedded,omitempty"`
+}
+
+const (
+	// SymbolFunction is the kind of the symbols of functions.
+	SymbolFunction = "function"
+	// SymbolVariable is the kind of the symbols of package variables.
+	SymbolVariable = "variable"
+)
+
+// Symbol is a function or a package variable of the target.
+type Symbol struct {
+	Name string `json:"name"`
+	// Kind is SymbolFunction or SymbolVariable.
+	Kind string `json:"kind"`
+	// Addr is the entry point of functions and the address of variables.
+	Addr uint64 `json:"addr"`
+	// Size is the size of the code of functions and of the value of
+	// variables.
+	Size int64 `json:"size"`
+	// Type is the type of variables.
+	Type string `json:"type,omitempty"`
+	// File and Line are the position of the entry point of functions.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+// FrameUnwindInfo describes the call frame information used to unwind a
+// stack frame.
+type FrameUnwindInfo struct {
+	PC uint64 `json:"pc"`
+	// FDEStart and FDEEnd are the address range of the frame description
+	// entry covering PC. Both are zero if no FDE covers PC, in which case
+	// the rules are an architecture specific approximation.
+	FDEStart uint64 `json:"fdeStart"`
+	FDEEnd   uint64 `json:"fdeEnd"`
+	// CFA is the rule used to compute the canonical frame address.
+	CFA string `json:"cfa"`
+	// CFAValue is the value of the canonical frame address.
+	CFAValue int64 `json:"cfaValue"`
+	// RetAddrReg is the name of the register containing the return address.
+	RetAddrReg string `json:"retAddrReg"`
+	// Regs are the rules used to compute the registers of the calling
+	// frame.
+	Regs []RegisterRule `json:"regs"`
+}
+
+// RegisterRule is the rule used to compute the value of a register in the
+// calling frame.
+type RegisterRule struct {
+	Name string `json:"name"`
+	Rule string `json:"rule"`
+}
+
+// Diagnostics is a report of the internal state of the debugger at the
+// current stop, meant to be attached to bug reports. The home directory
+// and the name of the user are redacted from it, as well as the contents
+// of the target's memory.
+type Diagnostics struct {
+	Version GetVersionOut `json:"version"`
+	GOOS    string        `json:"goos"`
+	GOARCH  string        `json:"goarch"`
+
+	ThreadID    int `json:"threadID"`
+	GoroutineID int `json:"goroutineID"`
+	// Registers are the raw registers of the current thread.
+	Registers []Register `json:"registers"`
+	// Frames are the top frames of the selected goroutine, or of the
+	// current thread, with the call frame information used to unwind them.
+	Frames []DiagnosticsFrame `json:"frames"`
+	// Expressions are the expressions most recently evaluated.
+	Expressions []DiagnosticsExpression `json:"expressions"`
+	// Packets are the most recent packets exchanged with the debugging
+	// stub, only for the backends that use one (rr, lldb).
+	Packets []string `json:"packets,omitempty"`
+	// AsyncPreemptSignals is the number of asynchronous preemption signals
+	// that the backend delivered to the target, or discarded, without
+	// stopping. It is nil if the backend does not count them.
+	AsyncPreemptSignals *uint64 `json:"asyncPreemptSignals,omitempty"`
+	// Errors are the errors encountered collecting the report.
+	Errors []string `json:"errors,omitempty"`
+}
+
+// DiagnosticsFrame is a stack frame of a diagnostics report.
+type DiagnosticsFrame struct {
+	Location
+	Unwind *FrameUnwindInfo `json:"unwind"`
+	Err    string           `json:"err,omitempty"`
+}
+
+// DiagnosticsExpression is an expression evaluated by the debugger.
+type DiagnosticsExpression struct {
+	Expr  string    `json:"expr"`
+	Scope EvalScope `json:"scope"`
+	// Type is the type of the result, its value is not reported.
+	Type string `json:"type,omitempty"`
+	Err  string `json:"err,omitempty"`
+}
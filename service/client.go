@@ -11,6 +11,9 @@ import (
 type Client interface {
 	// Returns the pid of the process we are debugging.
 	ProcessPid() int
+	// ListTargets returns the processes being debugged, see the
+	// --follow-fork flag.
+	ListTargets() ([]*api.Target, error)
 
 	// LastModified returns the time that the process' executable was modified.
 	LastModified() time.Time
@@ -48,6 +51,8 @@ type Client interface {
 	ReverseStepOut() (*api.DebuggerState, error)
 	// Call resumes process execution while making a function call.
 	Call(goroutineID int, expr string, unsafe bool) (*api.DebuggerState, error)
+	// CancelCall abandons the function call in progress on a goroutine, or all function calls in progress if goroutineID is negative.
+	CancelCall(goroutineID int) error
 
 	// SingleStep will step a single cpu instruction.
 	StepInstruction() (*api.DebuggerState, error)
@@ -64,12 +69,17 @@ type Client interface {
 	GetBreakpoint(id int) (*api.Breakpoint, error)
 	// GetBreakpointByName gets a breakpoint by name.
 	GetBreakpointByName(name string) (*api.Breakpoint, error)
+	// GetBreakpointStacks returns the stacks recorded by a breakpoint with AggregateStacks set.
+	GetBreakpointStacks(id int) ([]api.StackRecord, error)
 	// CreateBreakpoint creates a new breakpoint.
 	CreateBreakpoint(*api.Breakpoint) (*api.Breakpoint, error)
 	// CreateWatchpoint creates a new watchpoint.
 	CreateWatchpoint(api.EvalScope, string, api.WatchType) (*api.Breakpoint, error)
 	// ListBreakpoints gets all breakpoints.
 	ListBreakpoints() ([]*api.Breakpoint, error)
+	// ListFileBreakpoints returns the lines of file where a breakpoint can
+	// be set and the breakpoints set on them.
+	ListFileBreakpoints(file string) (*api.FileBreakpoints, error)
 	// ClearBreakpoint deletes a breakpoint by ID.
 	ClearBreakpoint(id int) (*api.Breakpoint, error)
 	// ClearBreakpointByName deletes a breakpoint by name
@@ -93,9 +103,34 @@ type Client interface {
 	ListPackageVariables(filter string, cfg api.LoadConfig) ([]api.Variable, error)
 	// EvalVariable returns a variable in the context of the current thread.
 	EvalVariable(scope api.EvalScope, symbol string, cfg api.LoadConfig) (*api.Variable, error)
+	// EvalBatch evaluates each expression of exprs in its scope, or in
+	// scope if it does not specify one, with a single request.
+	EvalBatch(scope api.EvalScope, exprs []api.EvalBatchExpr, cfg api.LoadConfig) ([]api.EvalBatchResult, error)
+	// EvalChunk returns at most count elements, starting at offset, of the
+	// value of expr, a string, array or slice.
+	EvalChunk(scope api.EvalScope, expr string, offset, count int64, cfg api.LoadConfig) (*api.ValueChunk, error)
+	// SaveVariable writes the content of the value of expr, a string,
+	// array or slice, to a file on the machine running the server.
+	SaveVariable(scope api.EvalScope, expr, path string) (int64, error)
+
+	// ValueProvenance returns where the current value of a local variable came from.
+	ValueProvenance(scope api.EvalScope, name string, cfg api.LoadConfig, flavour api.AssemblyFlavour) (*api.ValueProvenance, error)
+
+	// ListVariableReferences returns the instructions that write to a package variable or compute its address.
+	ListVariableReferences(scope api.EvalScope, expr string, flavour api.AssemblyFlavour) ([]api.VariableReference, error)
+
+	// FrameUnwindInfo returns the call frame information used to unwind a stack frame.
+	FrameUnwindInfo(scope api.EvalScope) (*api.FrameUnwindInfo, error)
+	// Diagnostics returns a report of the internal state of the debugger at the current stop.
+	Diagnostics() (*api.Diagnostics, error)
 
 	// SetVariable sets the value of a variable
 	SetVariable(scope api.EvalScope, symbol, value string) error
+	// ListMemoryWrites returns the writes made by the debugger to the
+	// memory of the target.
+	ListMemoryWrites() ([]api.MemoryWrite, error)
+	// UndoMemoryWrite restores the memory overwritten by a write.
+	UndoMemoryWrite(id int) error
 
 	// ListSources lists all source files in the process matching filter.
 	ListSources(filter string) ([]string, error)
@@ -103,8 +138,17 @@ type Client interface {
 	ListFunctions(filter string) ([]string, error)
 	// ListTypes lists all types in the process matching filter.
 	ListTypes(filter string) ([]string, error)
+	// DescribeType returns the memory layout of a type.
+	DescribeType(typ string) (*api.TypeLayout, error)
+	// ListSymbols lists the functions and package variables matching
+	// filter, with their address.
+	ListSymbols(filter, kind string) ([]api.Symbol, error)
 	// ListLocals lists all local variables in scope.
 	ListLocalVariables(scope api.EvalScope, cfg api.LoadConfig) ([]api.Variable, error)
+	// ListLocalVariablesInterest lists all local variables in scope, only the
+	// values reached by the selector paths in paths are loaded with cfg, the
+	// other variables are loaded shallowly.
+	ListLocalVariablesInterest(scope api.EvalScope, cfg api.LoadConfig, paths []string) ([]api.Variable, error)
 	// ListFunctionArgs lists all arguments to the current function.
 	ListFunctionArgs(scope api.EvalScope, cfg api.LoadConfig) ([]api.Variable, error)
 	// ListThreadRegisters lists registers and their values, for the given thread.
@@ -116,6 +160,15 @@ type Client interface {
 	ListGoroutines(start, count int) ([]*api.Goroutine, int, error)
 	// ListGoroutinesWithFilter lists goroutines matching the filters
 	ListGoroutinesWithFilter(start, count int, filters []api.ListGoroutinesFilter, group *api.GoroutineGroupingOptions) ([]*api.Goroutine, []api.GoroutineGroup, int, bool, error)
+	// ListTerminatedGoroutines lists the goroutines that terminated since
+	// the previous stop.
+	ListTerminatedGoroutines() ([]api.TerminatedGoroutine, error)
+	// SetGoroutineName names a goroutine, an empty name removes its name.
+	SetGoroutineName(id int, name string) error
+	// ListAnnotations lists the names and notes of goroutines and addresses.
+	ListAnnotations() ([]api.Annotation, error)
+	// SetAnnotation adds, replaces or removes an annotation.
+	SetAnnotation(a api.Annotation) error
 
 	// Returns stacktrace
 	Stacktrace(goroutineID int, depth int, opts api.StacktraceOptions, cfg *api.LoadConfig) ([]api.Stackframe, error)
@@ -123,6 +176,21 @@ type Client interface {
 	// Returns ancestor stacktraces
 	Ancestors(goroutineID int, numAncestors int, depth int) ([]api.Ancestor, error)
 
+	// ListDefers returns the chain of deferred calls of a goroutine, with their arguments.
+	ListDefers(goroutineID int, cfg *api.LoadConfig) ([]api.Defer, error)
+
+	// GoroutineProfile returns the stacktraces of all goroutines encoded in the specified format.
+	GoroutineProfile(depth int, format api.GoroutineProfileFormat) ([]byte, error)
+
+	// SchedState returns the state of the Go scheduler.
+	SchedState() (*api.SchedState, error)
+
+	// ContentionProfile returns the most contended call sites recorded by the runtime's mutex or block profile.
+	ContentionProfile(kind api.ContentionProfileKind, max int) (*api.ContentionProfile, error)
+
+	// BugPatterns returns the likely mistakes detected in the specified stack frame.
+	BugPatterns(scope api.EvalScope) ([]api.BugPattern, error)
+
 	// Returns whether we attached to a running process or not
 	AttachedToExistingProcess() bool
 
@@ -144,6 +212,9 @@ type Client interface {
 	DisassembleRange(scope api.EvalScope, startPC, endPC uint64, flavour api.AssemblyFlavour) (api.AsmInstructions, error)
 	// Disassemble code of the function containing PC
 	DisassemblePC(scope api.EvalScope, pc uint64, flavour api.AssemblyFlavour) (api.AsmInstructions, error)
+	// PseudoSource returns the pseudo-source listing of the function
+	// containing pc, to be used in place of its source code.
+	PseudoSource(pc uint64) (string, []api.PseudoSourceLine, error)
 
 	// Recorded returns true if the target is a recording.
 	Recorded() bool
@@ -180,6 +251,41 @@ type Client interface {
 	// CoreDumpCancel cancels a core dump in progress
 	CoreDumpCancel() error
 
+	// ListArtifacts returns the files created by the server, like core
+	// dumps, that can be downloaded.
+	ListArtifacts() ([]api.Artifact, error)
+	// ReadArtifact reads at most length bytes starting at offset from the
+	// artifact path.
+	ReadArtifact(path string, offset int64, length int) ([]byte, error)
+	// DownloadArtifact copies the artifact path to the local file dest,
+	// resuming interrupted downloads and verifying the checksum.
+	DownloadArtifact(path, dest string, progress func(done, total int64)) error
+
+	// GetProject returns the launch parameters and the breakpoints of the
+	// debugging session.
+	GetProject() (*api.Project, error)
+
+	// StartCommand starts running cmd and returns as soon as the target
+	// is resumed, with a token identifying the operation.
+	StartCommand(cmd *api.DebuggerCommand) (int, error)
+	// CommandResult returns the state returned by the command identified
+	// by token, waiting at most wait for it to finish (forever if wait is
+	// negative). The second return value is false if it did not finish.
+	CommandResult(token int, wait time.Duration) (*api.DebuggerState, bool, error)
+	// CancelCommand interrupts the command identified by token and waits
+	// for it to finish.
+	CancelCommand(token int) error
+
+	// WaitForEvents returns the events of the debugging session following
+	// the event with sequence number after, waiting at most wait for one
+	// to happen.
+	WaitForEvents(after int64, wait time.Duration) ([]api.Event, error)
+
+	// GetOutput returns at most max bytes of the output of the target kept
+	// by the server, starting at offset. A negative offset is relative to
+	// the end of the output.
+	GetOutput(offset int64, max int) (*api.TargetOutput, error)
+
 	// Disconnect closes the connection to the server without sending a Detach request first.
 	// If cont is true a continue command will be sent instead.
 	Disconnect(cont bool) error
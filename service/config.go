@@ -27,6 +27,12 @@ type Config struct {
 	// Note that the server API is not reentrant and clients will have to coordinate.
 	AcceptMulti bool
 
+	// KeepAlive configures the server to keep running when its client
+	// disconnects without detaching, a new client can then connect to it.
+	// Unlike with AcceptMulti clients are served one at a time, a client
+	// connecting while another one is connected waits for it to disconnect.
+	KeepAlive bool
+
 	// APIVersion selects which version of the API to serve (default: 1).
 	APIVersion int
 
@@ -34,6 +40,12 @@ type Config struct {
 	// connections come from the same user that started the headless server
 	CheckLocalConnUser bool
 
+	// AuthToken, if not empty, is the token clients must authenticate
+	// with before the server processes any of their requests. JSON-RPC
+	// clients call RPCServer.Authenticate, DAP clients pass it as the
+	// authToken attribute of the launch or attach request.
+	AuthToken string
+
 	// DisconnectChan will be closed by the server when the client disconnects
 	DisconnectChan chan<- struct{}
 }
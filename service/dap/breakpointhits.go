@@ -0,0 +1,115 @@
+package dap
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-delve/delve/service/api"
+	"github.com/google/go-dap"
+)
+
+// When several goroutines hit a breakpoint at the same time they are all
+// stopped by the same 'stopped' event: its hitBreakpointIds lists the
+// breakpoints hit by all of them and its text the goroutines other than
+// the one the event is reported for. It is followed by a custom
+// 'breakpointHits' event listing every goroutine stopped at a breakpoint,
+// so that editors can let users navigate between them instead of
+// discovering them one continue at a time.
+
+// breakpointHitsEvent is the custom event sent after a 'stopped' event
+// when more than one goroutine is stopped at a breakpoint.
+type breakpointHitsEvent struct {
+	dap.Event
+
+	Body breakpointHitsEventBody `json:"body"`
+}
+
+func (e *breakpointHitsEvent) GetEvent() *dap.Event { return &e.Event }
+
+// breakpointHitsEventBody is the body of the 'breakpointHits' event, the
+// goroutine the 'stopped' event was reported for comes first.
+type breakpointHitsEventBody struct {
+	Hits []breakpointHit `json:"hits"`
+}
+
+// breakpointHit is a goroutine stopped at a breakpoint.
+type breakpointHit struct {
+	// ThreadId is the ID of the goroutine, used as the thread ID in
+	// the other requests and events.
+	ThreadId     int        `json:"threadId"`
+	BreakpointId int        `json:"breakpointId"`
+	Source       dap.Source `json:"source"`
+	Line         int        `json:"line"`
+}
+
+// breakpointHits returns the goroutines stopped at a user breakpoint,
+// starting with the current one.
+func (s *Server) breakpointHits(state *api.DebuggerState) []breakpointHit {
+	var hits []breakpointHit
+	for _, th := range hitThreads(state) {
+		clientPath := s.toClientPath(th.File)
+		hits = append(hits, breakpointHit{
+			ThreadId:     th.GoroutineID,
+			BreakpointId: th.Breakpoint.ID,
+			Source:       dap.Source{Name: filepath.Base(clientPath), Path: clientPath},
+			Line:         th.Line,
+		})
+	}
+	return hits
+}
+
+// hitThreads returns the threads stopped at a user breakpoint, starting
+// with the current thread.
+func hitThreads(state *api.DebuggerState) []*api.Thread {
+	var r []*api.Thread
+	isHit := func(th *api.Thread) bool {
+		return th != nil && th.Breakpoint != nil && th.Breakpoint.ID > 0
+	}
+	if isHit(state.CurrentThread) {
+		r = append(r, state.CurrentThread)
+	}
+	for _, th := range state.Threads {
+		if isHit(th) && (state.CurrentThread == nil || th.ID != state.CurrentThread.ID) {
+			r = append(r, th)
+		}
+	}
+	return r
+}
+
+// simultaneousHits returns the IDs of the breakpoints hit by the goroutines
+// stopped at a breakpoint, starting with the current one, and text
+// followed by the list of the other goroutines, if any.
+func simultaneousHits(state *api.DebuggerState, text string) ([]int, string) {
+	var ids []int
+	var others []string
+	for i, th := range hitThreads(state) {
+		if i > 0 || th != state.CurrentThread {
+			others = append(others, strconv.Itoa(th.GoroutineID))
+		}
+		found := false
+		for _, id := range ids {
+			if id == th.Breakpoint.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ids = append(ids, th.Breakpoint.ID)
+		}
+	}
+	if len(others) > 0 {
+		if text != "" {
+			text += "\n"
+		}
+		text += fmt.Sprintf("Breakpoints also hit by goroutines %s", strings.Join(others, ", "))
+	}
+	if state.PendingHits > 0 {
+		if text != "" {
+			text += "\n"
+		}
+		text += fmt.Sprintf("%d more breakpoint hits will be reported by the next continue requests", state.PendingHits)
+	}
+	return ids, text
+}
@@ -13,8 +13,10 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"runtime"
 	"testing"
 
+	"github.com/go-delve/delve/service/api"
 	"github.com/google/go-dap"
 )
 
@@ -52,12 +54,110 @@ func (c *Client) send(request dap.Message) {
 }
 
 func (c *Client) ReadMessage() (dap.Message, error) {
-	return dap.ReadProtocolMessage(c.reader)
+	return readProtocolMessage(c.reader)
 }
 
+// readProtocolMessage reads and decodes a message from reader.
+// The version of go-dap in use does not decode 'setInstructionBreakpoints'
+// and 'writeMemory' responses, progress events and the custom 'testEvent'
+// event and 'dlvDiagnostics' response, they are decoded here.
+func readProtocolMessage(reader *bufio.Reader) (dap.Message, error) {
+	data, err := dap.ReadBaseMessage(reader)
+	if err != nil {
+		return nil, err
+	}
+	var e dap.Event
+	if err := json.Unmarshal(data, &e); err == nil && e.Type == "event" {
+		var event dap.Message
+		switch e.Event {
+		case "progressStart":
+			event = &dap.ProgressStartEvent{}
+		case "progressUpdate":
+			event = &dap.ProgressUpdateEvent{}
+		case "progressEnd":
+			event = &dap.ProgressEndEvent{}
+		case "testEvent":
+			event = &TestEvent{}
+		}
+		if event != nil {
+			if err := json.Unmarshal(data, event); err != nil {
+				return nil, err
+			}
+			return event, nil
+		}
+	}
+	var r dap.Response
+	if err := json.Unmarshal(data, &r); err == nil && r.Type == "response" && r.Success {
+		var response dap.Message
+		switch r.Command {
+		case "setInstructionBreakpoints":
+			response = &dap.SetInstructionBreakpointsResponse{}
+		case "writeMemory":
+			response = &WriteMemoryResponse{}
+		case "dlvDiagnostics":
+			response = &DiagnosticsResponse{}
+		}
+		if response != nil {
+			if err := json.Unmarshal(data, response); err != nil {
+				return nil, err
+			}
+			return response, nil
+		}
+	}
+	return dap.DecodeProtocolMessage(data)
+}
+
+// WriteMemoryRequest is the 'writeMemory' request, which is not defined by
+// the version of go-dap in use.
+type WriteMemoryRequest struct {
+	dap.Request
+
+	Arguments WriteMemoryArguments `json:"arguments"`
+}
+
+// WriteMemoryArguments are the arguments of the 'writeMemory' request.
+type WriteMemoryArguments struct {
+	MemoryReference string `json:"memoryReference"`
+	Offset          int    `json:"offset,omitempty"`
+	AllowPartial    bool   `json:"allowPartial,omitempty"`
+	Data            string `json:"data"`
+}
+
+// WriteMemoryResponse is the response to the 'writeMemory' request.
+type WriteMemoryResponse struct {
+	dap.Response
+
+	Body struct {
+		Offset       int `json:"offset,omitempty"`
+		BytesWritten int `json:"bytesWritten,omitempty"`
+	} `json:"body,omitempty"`
+}
+
+// DiagnosticsResponse is the response to the custom 'dlvDiagnostics'
+// request.
+type DiagnosticsResponse struct {
+	dap.Response
+
+	Body *api.Diagnostics `json:"body,omitempty"`
+}
+
+// TestEvent is the custom event sent by the server when a test starts or
+// completes.
+type TestEvent struct {
+	dap.Event
+
+	Body struct {
+		Action  string  `json:"action"`
+		Test    string  `json:"test"`
+		Elapsed float64 `json:"elapsed,omitempty"`
+	} `json:"body"`
+}
+
+func (e *TestEvent) GetEvent() *dap.Event { return &e.Event }
+
 func (c *Client) ExpectMessage(t *testing.T) dap.Message {
 	t.Helper()
-	m, err := dap.ReadProtocolMessage(c.reader)
+	m, err := readProtocolMessage(c.reader)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -96,15 +196,31 @@ func (c *Client) ExpectInitializeResponseAndCapabilities(t *testing.T) *dap.Init
 	initResp := c.ExpectInitializeResponse(t)
 	wantCapabilities := dap.Capabilities{
 		// the values set by dap.(*Server).onInitializeRequest.
-		SupportsConfigurationDoneRequest: true,
-		SupportsConditionalBreakpoints:   true,
-		SupportsDelayedStackTraceLoading: true,
-		SupportTerminateDebuggee:         true,
-		SupportsExceptionInfoRequest:     true,
-		SupportsSetVariable:              true,
-		SupportsFunctionBreakpoints:      true,
-		SupportsEvaluateForHovers:        true,
-		SupportsClipboardContext:         true,
+		SupportsConfigurationDoneRequest:  true,
+		SupportsConditionalBreakpoints:    true,
+		SupportsHitConditionalBreakpoints: true,
+		SupportsDelayedStackTraceLoading:  true,
+		SupportTerminateDebuggee:          true,
+		SupportsExceptionInfoRequest:      true,
+		SupportsSetVariable:               true,
+		SupportsFunctionBreakpoints:       true,
+		SupportsEvaluateForHovers:         true,
+		SupportsClipboardContext:          true,
+		// watchpoints are only supported by the native backend on linux/amd64.
+		SupportsDataBreakpoints:        runtime.GOOS == "linux" && runtime.GOARCH == "amd64",
+		SupportsDisassembleRequest:     true,
+		SupportsInstructionBreakpoints: true,
+		SupportsSteppingGranularity:    true,
+		SupportsReadMemoryRequest:      true,
+		SupportsRestartRequest:         true,
+		SupportsModulesRequest:         true,
+		ExceptionBreakpointFilters: []dap.ExceptionBreakpointsFilter{
+			{Filter: "uncaughtPanic", Label: "Uncaught panics", Description: "Stop when a panic is not recovered.", Default: true},
+			{Filter: "uncaughtPanicInModule", Label: "Uncaught panics in my module", Description: "Stop when a panic raised by a function of the main module is not recovered."},
+			{Filter: "panic", Label: "All panics", Description: "Stop at every panic, including panics that are later recovered."},
+			{Filter: "fatalError", Label: "Fatal runtime errors", Description: "Stop at fatal errors of the runtime, such as concurrent map writes or deadlocks.", Default: true},
+			{Filter: "testFailure", Label: "Test failures", Description: "Stop when a test fails, where the failure is recorded.", Default: true},
+		},
 	}
 	if !reflect.DeepEqual(initResp.Body, wantCapabilities) {
 		t.Errorf("capabilities in initializeResponse: got %+v, want %v", pretty(initResp.Body), pretty(wantCapabilities))
@@ -268,8 +384,9 @@ func (c *Client) SetHitConditionalBreakpointsRequest(file string, lines []int, c
 }
 
 // SetExceptionBreakpointsRequest sends a 'setExceptionBreakpoints' request.
-func (c *Client) SetExceptionBreakpointsRequest() {
-	request := &dap.SetBreakpointsRequest{Request: *c.newRequest("setExceptionBreakpoints")}
+func (c *Client) SetExceptionBreakpointsRequest(filters []string) {
+	request := &dap.SetExceptionBreakpointsRequest{Request: *c.newRequest("setExceptionBreakpoints")}
+	request.Arguments.Filters = filters
 	c.send(request)
 }
 
@@ -293,6 +410,14 @@ func (c *Client) NextRequest(thread int) {
 	c.send(request)
 }
 
+// StepInstructionRequest sends a 'stepIn' request with instruction granularity.
+func (c *Client) StepInstructionRequest(thread int) {
+	request := &dap.StepInRequest{Request: *c.newRequest("stepIn")}
+	request.Arguments.ThreadId = thread
+	request.Arguments.Granularity = "instruction"
+	c.send(request)
+}
+
 // StepInRequest sends a 'stepIn' request.
 func (c *Client) StepInRequest(thread int) {
 	request := &dap.NextRequest{Request: *c.newRequest("stepIn")}
@@ -416,8 +541,10 @@ func (c *Client) SetExpressionRequest() {
 }
 
 // SourceRequest sends a 'source' request.
-func (c *Client) SourceRequest() {
-	c.send(&dap.SourceRequest{Request: *c.newRequest("source")})
+func (c *Client) SourceRequest(path string) {
+	request := &dap.SourceRequest{Request: *c.newRequest("source")}
+	request.Arguments.Source.Path = path
+	c.send(request)
 }
 
 // TerminateThreadsRequest sends a 'terminateThreads' request.
@@ -462,23 +589,88 @@ func (c *Client) LoadedSourcesRequest() {
 }
 
 // DataBreakpointInfoRequest sends a 'dataBreakpointInfo' request.
-func (c *Client) DataBreakpointInfoRequest() {
-	c.send(&dap.DataBreakpointInfoRequest{Request: *c.newRequest("dataBreakpointInfo")})
+func (c *Client) DataBreakpointInfoRequest(variablesReference int, name string) {
+	request := &dap.DataBreakpointInfoRequest{Request: *c.newRequest("dataBreakpointInfo")}
+	request.Arguments.VariablesReference = variablesReference
+	request.Arguments.Name = name
+	c.send(request)
 }
 
 // SetDataBreakpointsRequest sends a 'setDataBreakpoints' request.
-func (c *Client) SetDataBreakpointsRequest() {
-	c.send(&dap.SetDataBreakpointsRequest{Request: *c.newRequest("setDataBreakpoints")})
+func (c *Client) SetDataBreakpointsRequest(breakpoints []dap.DataBreakpoint) {
+	c.send(&dap.SetDataBreakpointsRequest{
+		Request: *c.newRequest("setDataBreakpoints"),
+		Arguments: dap.SetDataBreakpointsArguments{
+			Breakpoints: breakpoints,
+		},
+	})
 }
 
 // ReadMemoryRequest sends a 'readMemory' request.
-func (c *Client) ReadMemoryRequest() {
-	c.send(&dap.ReadMemoryRequest{Request: *c.newRequest("readMemory")})
+func (c *Client) ReadMemoryRequest(memoryReference string, offset, count int) {
+	request := &dap.ReadMemoryRequest{Request: *c.newRequest("readMemory")}
+	request.Arguments.MemoryReference = memoryReference
+	request.Arguments.Offset = offset
+	request.Arguments.Count = count
+	c.send(request)
+}
+
+// WriteMemoryRequest sends a 'writeMemory' request.
+func (c *Client) WriteMemoryRequest(memoryReference string, offset int, data string, allowPartial bool) {
+	request := &WriteMemoryRequest{Request: *c.newRequest("writeMemory")}
+	request.Arguments.MemoryReference = memoryReference
+	request.Arguments.Offset = offset
+	request.Arguments.Data = data
+	request.Arguments.AllowPartial = allowPartial
+	c.send(request)
+}
+
+// ExpectWriteMemoryResponse reads a protocol message from the connection
+// and fails the test if the read message is not *WriteMemoryResponse.
+func (c *Client) ExpectWriteMemoryResponse(t *testing.T) *WriteMemoryResponse {
+	t.Helper()
+	m := c.ExpectMessage(t)
+	r, ok := m.(*WriteMemoryResponse)
+	if !ok {
+		t.Fatalf("got %#v, want *WriteMemoryResponse", m)
+	}
+	return r
+}
+
+// DiagnosticsRequest sends a custom 'dlvDiagnostics' request.
+func (c *Client) DiagnosticsRequest() {
+	c.send(c.newRequest("dlvDiagnostics"))
+}
+
+// ExpectDiagnosticsResponse reads a protocol message from the connection
+// and fails the test if the read message is not *DiagnosticsResponse.
+func (c *Client) ExpectDiagnosticsResponse(t *testing.T) *DiagnosticsResponse {
+	t.Helper()
+	m := c.ExpectMessage(t)
+	r, ok := m.(*DiagnosticsResponse)
+	if !ok {
+		t.Fatalf("got %#v, want *DiagnosticsResponse", m)
+	}
+	return r
 }
 
 // DisassembleRequest sends a 'disassemble' request.
-func (c *Client) DisassembleRequest() {
-	c.send(&dap.DisassembleRequest{Request: *c.newRequest("disassemble")})
+func (c *Client) DisassembleRequest(memoryReference string, instructionOffset, instructionCount int) {
+	request := &dap.DisassembleRequest{Request: *c.newRequest("disassemble")}
+	request.Arguments.MemoryReference = memoryReference
+	request.Arguments.InstructionOffset = instructionOffset
+	request.Arguments.InstructionCount = instructionCount
+	c.send(request)
+}
+
+// SetInstructionBreakpointsRequest sends a 'setInstructionBreakpoints' request.
+func (c *Client) SetInstructionBreakpointsRequest(breakpoints []dap.InstructionBreakpoint) {
+	c.send(&dap.SetInstructionBreakpointsRequest{
+		Request: *c.newRequest("setInstructionBreakpoints"),
+		Arguments: dap.SetInstructionBreakpointsArguments{
+			Breakpoints: breakpoints,
+		},
+	})
 }
 
 // CancelRequest sends a 'cancel' request.
@@ -531,6 +723,33 @@ func (c *Client) KnownEvent() {
 	c.send(event)
 }
 
+// ExpectRunInTerminalRequest reads a protocol message from the connection
+// and fails the test if the read message is not a 'runInTerminal' reverse
+// request.
+func (c *Client) ExpectRunInTerminalRequest(t *testing.T) *dap.RunInTerminalRequest {
+	t.Helper()
+	m := c.ExpectMessage(t)
+	r, ok := m.(*dap.RunInTerminalRequest)
+	if !ok {
+		t.Fatalf("got %#v, want *dap.RunInTerminalRequest", m)
+	}
+	return r
+}
+
+// RunInTerminalResponse sends the response to a 'runInTerminal' reverse
+// request, if message is not empty the request failed.
+func (c *Client) RunInTerminalResponse(request *dap.RunInTerminalRequest, message string) {
+	response := &dap.RunInTerminalResponse{}
+	response.Type = "response"
+	response.Command = request.Command
+	response.RequestSeq = request.Seq
+	response.Seq = c.seq
+	c.seq++
+	response.Success = message == ""
+	response.Message = message
+	c.send(response)
+}
+
 func (c *Client) newRequest(command string) *dap.Request {
 	request := &dap.Request{}
 	request.Type = "request"
@@ -24,7 +24,17 @@ const (
 	UnableToHalt               = 2010
 	UnableToGetExceptionInfo   = 2011
 	UnableToSetVariable        = 2012
+	UnableToGetSource          = 2013
+	UnableToDisassemble        = 2014
+	UnableToReadMemory         = 2015
+	UnableToWriteMemory        = 2016
+	UnableToRestart            = 2017
+	UnableToStepBack           = 2018
+	UnableToReverseContinue    = 2019
+	UnableToProduceDiagnostics = 2020
 	// Add more codes as we support more requests
 	DebuggeeIsRunning = 4000
+	SessionNotAdopted = 4001
+	NotAuthenticated  = 4002
 	DisconnectError   = 5000
 )
@@ -0,0 +1,195 @@
+package dap
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+)
+
+// goroutineFieldNames maps the names used in the 'goroutineFilters' and
+// 'groupGoroutinesBy' attributes of a launch or attach request to goroutine
+// fields, they are the same names used by the goroutines command of the
+// terminal client.
+var goroutineFieldNames = map[string]api.GoroutineField{
+	"curloc":   api.GoroutineCurrentLoc,
+	"userloc":  api.GoroutineUserLoc,
+	"goloc":    api.GoroutineGoLoc,
+	"startloc": api.GoroutineStartLoc,
+	"label":    api.GoroutineLabel,
+	"running":  api.GoroutineRunning,
+	"user":     api.GoroutineUser,
+}
+
+// parseGoroutineFilters parses the 'goroutineFilters' attribute of a launch
+// or attach request, a list of {'kind': string, 'arg': string, 'negated': bool}
+// objects. Only the goroutines matching all filters are returned by the
+// threads request.
+func parseGoroutineFilters(filters interface{}) ([]api.ListGoroutinesFilter, error) {
+	typeMismatchError := fmt.Errorf("'goroutineFilters' attribute '%v' in debug configuration is not a []{'kind': string, 'arg': string, 'negated': bool}", filters)
+	filtersParsed, ok := filters.([]interface{})
+	if !ok {
+		return nil, typeMismatchError
+	}
+	r := make([]api.ListGoroutinesFilter, 0, len(filtersParsed))
+	for _, arg := range filtersParsed {
+		m, ok := arg.(map[string]interface{})
+		if !ok {
+			return nil, typeMismatchError
+		}
+		kindName, ok := m["kind"].(string)
+		if !ok {
+			return nil, typeMismatchError
+		}
+		kind, ok := goroutineFieldNames[kindName]
+		if !ok {
+			return nil, fmt.Errorf("unknown kind %q in 'goroutineFilters' attribute", kindName)
+		}
+		filter := api.ListGoroutinesFilter{Kind: kind}
+		if v, ok := m["arg"]; ok {
+			if filter.Arg, ok = v.(string); !ok {
+				return nil, typeMismatchError
+			}
+		}
+		if v, ok := m["negated"]; ok {
+			if filter.Negated, ok = v.(bool); !ok {
+				return nil, typeMismatchError
+			}
+		}
+		switch kind {
+		case api.GoroutineRunning, api.GoroutineUser:
+			if filter.Arg != "" {
+				return nil, fmt.Errorf("goroutine filter %q in 'goroutineFilters' attribute does not take an argument", kindName)
+			}
+		case api.GoroutineLabel:
+			if filter.Arg == "" {
+				return nil, fmt.Errorf("goroutine filter %q in 'goroutineFilters' attribute requires a key=value argument", kindName)
+			}
+		default:
+			if _, err := regexp.Compile(filter.Arg); err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q in 'goroutineFilters' attribute: %v", filter.Arg, err)
+			}
+		}
+		r = append(r, filter)
+	}
+	return r, nil
+}
+
+// parseGroupGoroutinesBy parses the 'groupGoroutinesBy' attribute of a
+// launch or attach request, one of the goroutine fields accepted by
+// 'goroutineFilters' or 'label:<key>' to group by the value of a label.
+func parseGroupGoroutinesBy(groupBy interface{}) (api.GoroutineGroupingOptions, error) {
+	s, ok := groupBy.(string)
+	if !ok {
+		return api.GoroutineGroupingOptions{}, fmt.Errorf("'groupGoroutinesBy' attribute '%v' in debug configuration is not a string", groupBy)
+	}
+	if s == "" {
+		return api.GoroutineGroupingOptions{}, nil
+	}
+	if strings.HasPrefix(s, "label:") {
+		key := s[len("label:"):]
+		if key == "" {
+			return api.GoroutineGroupingOptions{}, fmt.Errorf("missing label key in 'groupGoroutinesBy' attribute %q", s)
+		}
+		return api.GoroutineGroupingOptions{GroupBy: api.GoroutineLabel, GroupByKey: key}, nil
+	}
+	field, ok := goroutineFieldNames[s]
+	if !ok || field == api.GoroutineLabel {
+		return api.GoroutineGroupingOptions{}, fmt.Errorf("unknown value %q for 'groupGoroutinesBy' attribute", s)
+	}
+	return api.GoroutineGroupingOptions{GroupBy: field}, nil
+}
+
+// selectGoroutines applies the goroutine filters and the grouping specified
+// in the launch or attach request to gs. The selected goroutine is never
+// filtered out. The returned map contains the name of the group of each
+// goroutine, when grouping is enabled.
+func (s *Server) selectGoroutines(gs []*proc.G, selected *api.Goroutine) ([]*proc.G, map[int]string, error) {
+	filters := s.args.goroutineFilters
+	if s.args.hideSystemGoroutines {
+		filters = append(filters[:len(filters):len(filters)], api.ListGoroutinesFilter{Kind: api.GoroutineUser})
+	}
+	filtered, err := s.debugger.FilterGoroutines(gs, filters)
+	if err != nil {
+		return nil, nil, err
+	}
+	if selected != nil && len(filtered) != len(gs) {
+		found := false
+		for _, g := range filtered {
+			if g.ID == selected.ID {
+				found = true
+				break
+			}
+		}
+		if !found {
+			for _, g := range gs {
+				if g.ID == selected.ID {
+					filtered = append([]*proc.G{g}, filtered...)
+					break
+				}
+			}
+		}
+	}
+
+	if s.args.groupGoroutinesBy.GroupBy == api.GoroutineFieldNone || len(filtered) == 0 {
+		return filtered, nil, nil
+	}
+	opts := s.args.groupGoroutinesBy
+	opts.MaxGroupMembers = len(filtered)
+	grouped, groups, _ := s.debugger.GroupGoroutines(filtered, &opts)
+	groupNames := make(map[int]string, len(grouped))
+	for _, group := range groups {
+		for _, g := range grouped[group.Offset : group.Offset+group.Count] {
+			groupNames[g.ID] = group.Name
+		}
+	}
+	return grouped, groupNames, nil
+}
+
+// goroutineDetails returns the wait reason, the start function, the
+// labels and the note of g, formatted to be appended to the name of its
+// thread.
+func goroutineDetails(tgt *proc.Target, g *proc.G, userLoc *proc.Location) string {
+	var buf strings.Builder
+	if (g.Status == proc.Gwaiting || g.Status == proc.Gsyscall) && g.WaitReason != 0 {
+		fmt.Fprintf(&buf, " [%s]", api.WaitReasonString(g.WaitReason))
+	}
+	if startLoc := g.StartLoc(tgt); startLoc.Fn != nil && fnName(&startLoc) != fnName(userLoc) {
+		fmt.Fprintf(&buf, " (go %s)", fnName(&startLoc))
+	}
+	if labels := g.Labels(); len(labels) > 0 {
+		keys := make([]string, 0, len(labels))
+		for k := range labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString(" {")
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprintf(&buf, "%s=%s", k, labels[k])
+		}
+		buf.WriteString("}")
+	}
+	if a, _ := tgt.GoroutineAnnotation(g.ID); a.Note != "" {
+		fmt.Fprintf(&buf, " // %s", a.Note)
+	}
+	return buf.String()
+}
+
+// formatAnnotation returns the name and the note of a range of
+// addresses annotated by the user.
+func formatAnnotation(a api.Annotation) string {
+	switch {
+	case a.Name != "" && a.Note != "":
+		return fmt.Sprintf("%s: %s", a.Name, a.Note)
+	case a.Name != "":
+		return a.Name
+	default:
+		return a.Note
+	}
+}
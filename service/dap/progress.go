@@ -0,0 +1,100 @@
+package dap
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/go-dap"
+)
+
+// progressDelay is how long an operation must run before its progress is
+// reported, so that the client does not flash a progress notification for
+// operations that complete quickly.
+var progressDelay = 500 * time.Millisecond
+
+// progressReporter reports the progress of a long running operation, like
+// building and loading the target or listing all goroutines, with
+// 'progressStart', 'progressUpdate' and 'progressEnd' events. The events
+// are only sent if the client supports them and the operation takes longer
+// than progressDelay.
+type progressReporter struct {
+	s     *Server
+	id    string
+	title string
+	timer *time.Timer
+
+	mu      sync.Mutex
+	message string
+	started bool
+	ended   bool
+}
+
+// progressSeq is used to generate unique progress IDs.
+var progressSeq uint64
+
+// startProgress starts reporting the progress of an operation, the
+// returned reporter must be ended when the operation completes.
+func (s *Server) startProgress(title, message string) *progressReporter {
+	p := &progressReporter{s: s, title: title, message: message}
+	if !s.clientCapabilities.supportsProgressReporting {
+		p.ended = true
+		return p
+	}
+	p.id = fmt.Sprintf("delve-%d", atomic.AddUint64(&progressSeq, 1))
+	if progressDelay <= 0 {
+		p.sendStart()
+	} else {
+		p.timer = time.AfterFunc(progressDelay, p.sendStart)
+	}
+	return p
+}
+
+func (p *progressReporter) sendStart() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended {
+		return
+	}
+	p.started = true
+	p.s.send(&dap.ProgressStartEvent{
+		Event: *newEvent("progressStart"),
+		Body:  dap.ProgressStartEventBody{ProgressId: p.id, Title: p.title, Message: p.message},
+	})
+}
+
+// update changes the message describing the current step of the operation.
+func (p *progressReporter) update(message string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended {
+		return
+	}
+	p.message = message
+	if p.started {
+		p.s.send(&dap.ProgressUpdateEvent{
+			Event: *newEvent("progressUpdate"),
+			Body:  dap.ProgressUpdateEventBody{ProgressId: p.id, Message: message},
+		})
+	}
+}
+
+// end signals that the operation completed.
+func (p *progressReporter) end() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ended {
+		return
+	}
+	p.ended = true
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+	if p.started {
+		p.s.send(&dap.ProgressEndEvent{
+			Event: *newEvent("progressEnd"),
+			Body:  dap.ProgressEndEventBody{ProgressId: p.id},
+		})
+	}
+}
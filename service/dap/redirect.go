@@ -0,0 +1,150 @@
+package dap
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/google/go-dap"
+)
+
+// By default the target inherits the standard output and error of the
+// debugger. If the 'outputMode' launch attribute is "remote" they are
+// captured separately and sent to the client as 'output' events of
+// category "stdout" and "stderr". If the 'outputFile' launch attribute is
+// set they are written to that file instead.
+
+// outputDrainTimeout is how long the output of the target is still read
+// after the debug session ends, so that the output written right before
+// the target exited is not lost.
+var outputDrainTimeout = 100 * time.Millisecond
+
+// outputEventWriter sends what is written to it to the client as 'output'
+// events.
+type outputEventWriter struct {
+	s        *Server
+	category string
+}
+
+func (w *outputEventWriter) Write(p []byte) (int, error) {
+	w.s.send(&dap.OutputEvent{
+		Event: *newEvent("output"),
+		Body: dap.OutputEventBody{
+			Output:   string(p),
+			Category: w.category,
+		}})
+	return len(p), nil
+}
+
+// targetOutput is where the standard output and error of the target are
+// written when they are not inherited from the debugger.
+type targetOutput struct {
+	stdout, stderr io.Writer
+	// file is the file set with the 'outputFile' launch attribute.
+	file *os.File
+
+	// dir is the temporary directory containing the named pipes the target
+	// writes to, pipes are their read ends.
+	dir   string
+	pipes []*os.File
+	wg    sync.WaitGroup
+}
+
+// newTargetOutput returns where the output of the target should be
+// written according to the 'outputMode' and 'outputFile' launch
+// attributes, nil if the target inherits the output of the debugger.
+func (s *Server) newTargetOutput(args map[string]interface{}) (*targetOutput, error) {
+	mode := "local"
+	if arg, ok := args["outputMode"]; ok {
+		mode, ok = arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("'outputMode' attribute '%v' in debug configuration is not a string.", arg)
+		}
+		if mode != "local" && mode != "remote" {
+			return nil, fmt.Errorf("Unsupported 'outputMode' value %q in debug configuration.", mode)
+		}
+	}
+	var path string
+	if arg, ok := args["outputFile"]; ok {
+		path, ok = arg.(string)
+		if !ok {
+			return nil, fmt.Errorf("'outputFile' attribute '%v' in debug configuration is not a string.", arg)
+		}
+	}
+
+	switch {
+	case path != "":
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		return &targetOutput{stdout: f, stderr: f, file: f}, nil
+	case mode == "remote":
+		return &targetOutput{
+			stdout: &outputEventWriter{s: s, category: "stdout"},
+			stderr: &outputEventWriter{s: s, category: "stderr"},
+		}, nil
+	}
+	return nil, nil
+}
+
+// startPipes creates the named pipes the target should write its
+// standard output and error to and starts copying what is written to
+// them. Returns the paths of the pipes.
+func (o *targetOutput) startPipes() (paths [2]string, err error) {
+	dir, err := ioutil.TempDir("", "dlv-dap-output")
+	if err != nil {
+		return paths, err
+	}
+	o.dir = dir
+	for i, w := range []io.Writer{o.stdout, o.stderr} {
+		path := filepath.Join(dir, fmt.Sprintf("fd%d", i+1))
+		if err := mkfifo(path); err != nil {
+			return paths, err
+		}
+		// Opening the pipe for writing as well does not block until the
+		// target opens it and lets the target open it without blocking.
+		pipe, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return paths, err
+		}
+		o.pipes = append(o.pipes, pipe)
+		paths[i] = path
+		o.wg.Add(1)
+		go func(w io.Writer) {
+			defer o.wg.Done()
+			io.Copy(w, pipe)
+		}(w)
+	}
+	return paths, nil
+}
+
+// close stops copying the output of the target, after reading what is
+// left in the pipes, and closes the output file.
+func (o *targetOutput) close() {
+	if o == nil {
+		return
+	}
+	drain := true
+	for _, pipe := range o.pipes {
+		if err := pipe.SetReadDeadline(time.Now().Add(outputDrainTimeout)); err != nil {
+			drain = false
+		}
+	}
+	if drain {
+		o.wg.Wait()
+	}
+	for _, pipe := range o.pipes {
+		pipe.Close()
+	}
+	if o.dir != "" {
+		os.RemoveAll(o.dir)
+	}
+	if o.file != nil {
+		o.file.Close()
+	}
+}
@@ -0,0 +1,9 @@
+// +build !windows
+
+package dap
+
+import "syscall"
+
+func mkfifo(path string) error {
+	return syscall.Mkfifo(path, 0600)
+}
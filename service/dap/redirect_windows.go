@@ -0,0 +1,7 @@
+package dap
+
+import "errors"
+
+func mkfifo(path string) error {
+	return errors.New("capturing the output of the target is not supported on windows")
+}
@@ -0,0 +1,152 @@
+package dap
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/go-dap"
+)
+
+// If the 'console' launch attribute is "integratedTerminal" or
+// "externalTerminal" the target is run in a terminal of the client, so
+// that programs that read from their standard input or that need a real
+// terminal, like TUIs, can be debugged.
+//
+// The target is still started by the debugger: a 'runInTerminal' reverse
+// request asks the client to run a shell in its terminal that reports the
+// path of the terminal through a named pipe and then waits, without
+// reading from the terminal, for the debug session to end. The target is
+// then launched with its standard input, output and error redirected to
+// that terminal.
+
+// runInTerminalTimeout is how long the debugger waits for the shell run
+// by the client to report the path of its terminal.
+var runInTerminalTimeout = 10 * time.Second
+
+// runInTerminalScript is run by the shell in the terminal of the client,
+// $1 is the named pipe the path of the terminal is written to and $2 the
+// named pipe that is closed when the debug session ends. Signals sent
+// from the keyboard are ignored, the shell does not own the terminal once
+// the target starts.
+const runInTerminalScript = `trap '' INT QUIT TSTP; tty > "$1" && exec cat "$2" > /dev/null`
+
+// targetTerminal is a terminal of the client used by the target.
+type targetTerminal struct {
+	// tty is the path of the terminal.
+	tty string
+
+	// dir is the temporary directory containing the named pipes, wait
+	// is kept open until the debug session ends.
+	dir  string
+	wait *os.File
+}
+
+// runInTerminal asks the client to open a terminal of the given kind,
+// "integrated" or "external", for the target. It is called from
+// onLaunchRequest on the goroutine that reads requests, the response of
+// the client is therefore read here and the other requests received
+// before it are handled after the launch request.
+func (s *Server) runInTerminal(kind, title, cwd string) (t *targetTerminal, err error) {
+	if !s.clientCapabilities.supportsRunInTerminalRequest {
+		return nil, fmt.Errorf("the client does not support the 'runInTerminal' request")
+	}
+	t = &targetTerminal{}
+	defer func() {
+		if err != nil {
+			t.close()
+		}
+	}()
+	t.dir, err = ioutil.TempDir("", "dlv-dap-terminal")
+	if err != nil {
+		return nil, err
+	}
+	ttyPath, waitPath := filepath.Join(t.dir, "tty"), filepath.Join(t.dir, "wait")
+	for _, path := range []string{ttyPath, waitPath} {
+		if err := mkfifo(path); err != nil {
+			return nil, err
+		}
+	}
+	// Both pipes are opened for reading and writing so that opening them
+	// does not block, the shell only reaches the end of the wait pipe once
+	// it is closed by close.
+	ttyPipe, err := os.OpenFile(ttyPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer ttyPipe.Close()
+	t.wait, err = os.OpenFile(waitPath, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	s.send(&dap.RunInTerminalRequest{
+		Request: dap.Request{
+			ProtocolMessage: dap.ProtocolMessage{Seq: 0, Type: "request"},
+			Command:         "runInTerminal",
+		},
+		Arguments: dap.RunInTerminalRequestArguments{
+			Kind:  kind,
+			Title: title,
+			Cwd:   cwd,
+			Args:  []string{"/bin/sh", "-c", runInTerminalScript, "dlv-terminal", ttyPath, waitPath},
+		},
+	})
+	if err := s.expectRunInTerminalResponse(); err != nil {
+		return nil, err
+	}
+
+	if err := ttyPipe.SetReadDeadline(time.Now().Add(runInTerminalTimeout)); err != nil {
+		return nil, err
+	}
+	line, err := bufio.NewReader(ttyPipe).ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("could not read the path of the terminal: %v", err)
+	}
+	t.tty = strings.TrimSpace(line)
+	if !filepath.IsAbs(t.tty) {
+		return nil, fmt.Errorf("could not read the path of the terminal: %q", t.tty)
+	}
+	return t, nil
+}
+
+// expectRunInTerminalResponse reads messages from the client until the
+// response to the 'runInTerminal' request is received. The requests read
+// in the meantime are deferred.
+func (s *Server) expectRunInTerminalResponse() error {
+	for {
+		m, err := readProtocolMessage(s.reader)
+		if err != nil {
+			return err
+		}
+		switch m := m.(type) {
+		case *dap.RunInTerminalResponse:
+			return nil
+		case *dap.ErrorResponse:
+			if m.Command == "runInTerminal" {
+				if m.Body.Error.Format != "" {
+					return fmt.Errorf("the client could not run the terminal: %s", m.Body.Error.Format)
+				}
+				return fmt.Errorf("the client could not run the terminal: %s", m.Message)
+			}
+		}
+		s.deferredMessages = append(s.deferredMessages, m)
+	}
+}
+
+// close ends the shell in the terminal and removes the named pipes.
+func (t *targetTerminal) close() {
+	if t == nil {
+		return
+	}
+	if t.wait != nil {
+		t.wait.Close()
+	}
+	if t.dir != "" {
+		os.RemoveAll(t.dir)
+	}
+}
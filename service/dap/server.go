@@ -11,12 +11,15 @@ package dap
 import (
 	"bufio"
 	"bytes"
+	"crypto/subtle"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"go/constant"
 	"go/parser"
 	"io"
+	"io/ioutil"
 	"net"
 	"os"
 	"os/exec"
@@ -25,8 +28,10 @@ import (
 	"regexp"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/go-delve/delve/pkg/gobuild"
 	"github.com/go-delve/delve/pkg/goversion"
@@ -71,6 +76,14 @@ import (
 // error or responding to a (synchronous) DAP disconnect request.
 // Once stop is triggered, the goroutine exits.
 //
+// If the server is started with config.AcceptMulti, a graceful disconnect
+// request that does not ask to terminate the debuggee, or the loss of the
+// client connection, does not stop the server. Instead the client
+// connection is closed, the debug session (target process, breakpoints)
+// is kept and the run goroutine goes back to accepting a new client
+// connection. The new client can adopt the existing session with an
+// attach request in "remote" mode.
+//
 // TODO(polina): add another layer of per-client goroutines to support multiple clients
 //
 // (3) Per-request goroutine is started for each asynchronous request
@@ -111,6 +124,9 @@ type Server struct {
 	exceptionErr error
 	// clientCapabilities tracks special settings for handling debug session requests.
 	clientCapabilities dapClientCapabilites
+	// exceptionFilters tracks the exception breakpoint filters enabled with
+	// the 'setExceptionBreakpoints' request.
+	exceptionFilters map[string]bool
 
 	// mu synchronizes access to objects set on start-up (from run goroutine)
 	// and stopped on teardown (from main goroutine)
@@ -124,6 +140,30 @@ type Server struct {
 	binaryToRemove string
 	// noDebugProcess is set for the noDebug launch process.
 	noDebugProcess *exec.Cmd
+	// targetOutput is where the output of the launched target is written,
+	// if it is not inherited from the debugger.
+	targetOutput *targetOutput
+	// targetTerminal is the terminal of the client the launched target
+	// runs in, if any.
+	targetTerminal *targetTerminal
+	// sessionKept is set when the client disconnected but the debug
+	// session was kept alive for the next client to adopt.
+	sessionKept bool
+	// haltedForRestart is set when a restart request halts the target,
+	// the command that was interrupted does not report the stop.
+	haltedForRestart bool
+	// authenticated is set when the client authenticated with the token
+	// the server requires, see service.Config.AuthToken.
+	authenticated bool
+	// modulesReported is the number of images of the target that the
+	// client knows about, either from the images loaded at launch or attach
+	// or from module events.
+	modulesReported int
+
+	// deferredMessages are the messages received while a request waited
+	// for the response to a reverse request, they are handled once that
+	// request is done. Only accessed by the goroutine reading requests.
+	deferredMessages []dap.Message
 
 	// sendingMu synchronizes writing to net.Conn
 	// to ensure that messages do not get interleaved
@@ -140,11 +180,37 @@ type launchAttachArgs struct {
 	// showGlobalVariables indicates if global package variables should be loaded.
 	showGlobalVariables bool
 	// substitutePathClientToServer indicates rules for converting file paths between client and debugger.
-	// These must be directory paths.
-	substitutePathClientToServer [][2]string
+	// These are evaluated in order, the first matching rule is used.
+	substitutePathClientToServer []substitutePathRule
 	// substitutePathServerToClient indicates rules for converting file paths between debugger and client.
-	// These must be directory paths.
-	substitutePathServerToClient [][2]string
+	// These are evaluated in order, the first matching rule is used.
+	substitutePathServerToClient []substitutePathRule
+	// workspaceFolder is the directory on the client that corresponds to
+	// the root of the main module of the target. If set, a rule mapping
+	// the two is added after the rules specified with substitutePath.
+	workspaceFolder string
+	// adoptedSession is set when the client adopted a debug session
+	// that was kept alive after the previous client disconnected.
+	adoptedSession bool
+	// keepRunningOnDisconnect is set to let the target run while the
+	// debug session is kept for the next client, instead of halting it.
+	keepRunningOnDisconnect bool
+	// hideSystemGoroutines removes the goroutines started by the runtime
+	// from the threads response.
+	hideSystemGoroutines bool
+	// goroutineFilters are applied to the goroutines of the threads response.
+	goroutineFilters []api.ListGoroutinesFilter
+	// groupGoroutinesBy sorts the goroutines of the threads response by group,
+	// the name of their group is prepended to the name of their thread.
+	groupGoroutinesBy api.GoroutineGroupingOptions
+	// serializeBreakpointHits sets SerializeHits on the breakpoints set by
+	// the client: the hits of goroutines that reach them at the same time
+	// are reported by separate stopped events.
+	serializeBreakpointHits bool
+	// maxClipboardValueLen limits the length of the strings, the number
+	// of elements of the arrays, slices and maps and the length of the
+	// value returned by evaluate requests in the "clipboard" context.
+	maxClipboardValueLen int
 }
 
 // defaultArgs borrows the defaults for the arguments from the original vscode-go adapter.
@@ -152,8 +218,10 @@ var defaultArgs = launchAttachArgs{
 	stopOnEntry:                  false,
 	stackTraceDepth:              50,
 	showGlobalVariables:          false,
-	substitutePathClientToServer: [][2]string{},
-	substitutePathServerToClient: [][2]string{},
+	substitutePathClientToServer: []substitutePathRule{},
+	substitutePathServerToClient: []substitutePathRule{},
+	workspaceFolder:              "",
+	maxClipboardValueLen:         defaultMaxClipboardValueLen,
 }
 
 // dapClientCapabilites captures arguments from intitialize request that
@@ -164,6 +232,7 @@ type dapClientCapabilites struct {
 	supportsRunInTerminalRequest bool
 	supportsMemoryReferences     bool
 	supportsProgressReporting    bool
+	supportsInvalidatedEvent     bool
 }
 
 // DefaultLoadConfig controls how variables are loaded from the target's memory.
@@ -189,6 +258,12 @@ const (
 	// what is presented. A common use case of a call injection is to
 	// stringify complex data conveniently.
 	maxStringLenInCallRetVars = 1 << 10 // 1024
+	// Values copied to the clipboard are loaded in full, up to this limit
+	// unless the client specifies a different one.
+	defaultMaxClipboardValueLen = 1 << 20 // 1MB
+	// Strings that are too long to be loaded in full are presented as
+	// indexed children, each one a chunk of the string of this length.
+	stringChunkLen = maxSingleStringLen
 )
 
 // NewServer creates a new DAP Server. It takes an opened Listener
@@ -198,7 +273,7 @@ const (
 // Server.Stop() must be called to shutdown this single-user server.
 func NewServer(config *service.Config) *Server {
 	logger := logflags.DAPLogger()
-	logflags.WriteDAPListeningMessage(config.Listener.Addr().String())
+	logflags.WriteDAPListeningMessage(service.ListenAddress(config.Listener.Addr()))
 	logger.Debug("DAP server pid = ", os.Getpid())
 	return &Server{
 		config:            config,
@@ -209,6 +284,7 @@ func NewServer(config *service.Config) *Server {
 		variableHandles:   newVariablesHandlesMap(),
 		args:              defaultArgs,
 		exceptionErr:      nil,
+		exceptionFilters:  defaultExceptionFilters(),
 	}
 }
 
@@ -229,35 +305,73 @@ func (s *Server) setLaunchAttachArgs(request dap.LaunchAttachRequest) error {
 	}
 	paths, ok := request.GetArguments()["substitutePath"]
 	if ok {
-		typeMismatchError := fmt.Errorf("'substitutePath' attribute '%v' in debug configuration is not a []{'from': string, 'to': string}", paths)
-		pathsParsed, ok := paths.([]interface{})
-		if !ok {
-			return typeMismatchError
-		}
-		clientToServer := make([][2]string, 0, len(pathsParsed))
-		serverToClient := make([][2]string, 0, len(pathsParsed))
-		for _, arg := range pathsParsed {
-			pathMapping, ok := arg.(map[string]interface{})
-			if !ok {
-				return typeMismatchError
-			}
-			from, ok := pathMapping["from"].(string)
-			if !ok {
-				return typeMismatchError
-			}
-			to, ok := pathMapping["to"].(string)
-			if !ok {
-				return typeMismatchError
-			}
-			clientToServer = append(clientToServer, [2]string{from, to})
-			serverToClient = append(serverToClient, [2]string{to, from})
+		clientToServer, serverToClient, err := parseSubstitutePath(paths)
+		if err != nil {
+			return err
 		}
 		s.args.substitutePathClientToServer = clientToServer
 		s.args.substitutePathServerToClient = serverToClient
 	}
+	workspaceFolder, ok := request.GetArguments()["workspaceFolder"]
+	if ok {
+		wf, ok := workspaceFolder.(string)
+		if !ok {
+			return fmt.Errorf("'workspaceFolder' attribute '%v' in debug configuration is not a string", workspaceFolder)
+		}
+		s.args.workspaceFolder = wf
+	}
+	serializeHits, ok := request.GetArguments()["serializeBreakpointHits"].(bool)
+	if ok {
+		s.args.serializeBreakpointHits = serializeHits
+	}
+	clipboardLen, ok := request.GetArguments()["maxClipboardValueLen"].(float64)
+	if ok && clipboardLen > 0 {
+		s.args.maxClipboardValueLen = int(clipboardLen)
+	}
+	keepRunning, ok := request.GetArguments()["keepRunningOnDisconnect"].(bool)
+	if ok {
+		s.args.keepRunningOnDisconnect = keepRunning
+	}
+	hideSystem, ok := request.GetArguments()["hideSystemGoroutines"].(bool)
+	if ok {
+		s.args.hideSystemGoroutines = hideSystem
+	}
+	filters, ok := request.GetArguments()["goroutineFilters"]
+	if ok {
+		goroutineFilters, err := parseGoroutineFilters(filters)
+		if err != nil {
+			return err
+		}
+		s.args.goroutineFilters = goroutineFilters
+	}
+	groupBy, ok := request.GetArguments()["groupGoroutinesBy"]
+	if ok {
+		groupGoroutinesBy, err := parseGroupGoroutinesBy(groupBy)
+		if err != nil {
+			return err
+		}
+		s.args.groupGoroutinesBy = groupGoroutinesBy
+	}
 	return nil
 }
 
+// addModuleSubstitutePath adds a rule mapping the root of the main module
+// of the target to the workspace folder of the client, evaluated after the
+// rules specified with substitutePath.
+func (s *Server) addModuleSubstitutePath() {
+	if s.args.workspaceFolder == "" {
+		return
+	}
+	root, err := s.mainModuleRoot()
+	if err != nil {
+		s.log.Debugf("could not determine the root of the main module: %v", err)
+		return
+	}
+	s.log.Debugf("module root %s mapped to workspace folder %s", root, s.args.workspaceFolder)
+	s.args.substitutePathClientToServer = append(s.args.substitutePathClientToServer, substitutePathRule{from: s.args.workspaceFolder, to: root})
+	s.args.substitutePathServerToClient = append(s.args.substitutePathServerToClient, substitutePathRule{from: root, to: s.args.workspaceFolder})
+}
+
 // Stop stops the DAP debugger service, closes the listener and the client
 // connection. It shuts down the underlying debugger and kills the target
 // process if it was launched by it or stops the noDebug process.
@@ -320,44 +434,96 @@ func (s *Server) triggerServerStop() {
 
 // Run launches a new goroutine where it accepts a client connection
 // and starts processing requests from it. Use Stop() to close connection.
-// The server does not support multiple clients, serially or in parallel.
-// The server should be restarted for every new debug session.
+// The server does not support multiple clients in parallel.
+// Unless config.AcceptMulti is set, it does not support multiple clients
+// serially either and should be restarted for every new debug session.
 // The debugger won't be started until launch/attach request is received.
 // TODO(polina): allow new client connections for new debug sessions,
 // so the editor needs to launch delve only once?
 func (s *Server) Run() {
 	go func() {
-		conn, err := s.listener.Accept() // listener is closed in Stop()
-		if err != nil {
-			select {
-			case <-s.stopTriggered:
-			default:
-				s.log.Errorf("Error accepting client connection: %s\n", err)
-				s.triggerServerStop()
+		for {
+			conn, err := s.listener.Accept() // listener is closed in Stop()
+			if err != nil {
+				select {
+				case <-s.stopTriggered:
+				default:
+					s.log.Errorf("Error accepting client connection: %s\n", err)
+					s.triggerServerStop()
+				}
+				return
 			}
-			return
-		}
-		if s.config.CheckLocalConnUser {
-			if !sameuser.CanAccept(s.listener.Addr(), conn.RemoteAddr()) {
-				s.log.Error("Error accepting client connection: Only connections from the same user that started this instance of Delve are allowed to connect. See --only-same-user.")
-				s.triggerServerStop()
+			if s.config.CheckLocalConnUser {
+				if !sameuser.CanAccept(s.listener.Addr(), conn.RemoteAddr()) {
+					s.log.Error("Error accepting client connection: Only connections from the same user that started this instance of Delve are allowed to connect. See --only-same-user.")
+					s.triggerServerStop()
+					return
+				}
+			}
+			s.mu.Lock()
+			s.conn = conn // closed in Stop()
+			s.mu.Unlock()
+			s.serveDAPCodec()
+			if !s.isSessionKept() {
 				return
 			}
+			s.log.Debug("debug session kept, waiting for a new client connection")
+			s.resetClientState()
 		}
-		s.mu.Lock()
-		s.conn = conn // closed in Stop()
-		s.mu.Unlock()
-		s.serveDAPCodec()
 	}()
 }
 
+// authenticate returns true if request can be processed: the server does
+// not require authentication, the client already authenticated or it is
+// authenticating with request. Only the initialize request is accepted
+// before the client authenticates with the authToken attribute of a
+// launch or attach request.
+func (s *Server) authenticate(request dap.Message) bool {
+	if s.config.AuthToken == "" || s.authenticated {
+		return true
+	}
+	switch request := request.(type) {
+	case *dap.InitializeRequest:
+		return true
+	case dap.LaunchAttachRequest:
+		token, _ := request.GetArguments()["authToken"].(string)
+		if subtle.ConstantTimeCompare([]byte(token), []byte(s.config.AuthToken)) != 1 {
+			s.log.Warn("authentication failed")
+			return false
+		}
+		s.authenticated = true
+		return true
+	}
+	return false
+}
+
+func (s *Server) isSessionKept() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessionKept
+}
+
+// resetClientState discards all the state that was specific to the
+// previous client before a new client adopts the kept debug session.
+// Launch/attach arguments are kept because they describe the session.
+func (s *Server) resetClientState() {
+	s.resetHandlesForStoppedEvent()
+	s.clientCapabilities = dapClientCapabilites{}
+	s.authenticated = false
+}
+
 // serveDAPCodec reads and decodes requests from the client
 // until it encounters an error or EOF, when it sends
 // a disconnect signal and returns.
 func (s *Server) serveDAPCodec() {
 	s.reader = bufio.NewReader(s.conn)
 	for {
-		request, err := dap.ReadProtocolMessage(s.reader)
+		for len(s.deferredMessages) > 0 {
+			request := s.deferredMessages[0]
+			s.deferredMessages = s.deferredMessages[1:]
+			s.handleRequest(request)
+		}
+		request, err := readProtocolMessage(s.reader)
 		// Handle dap.DecodeProtocolMessageFieldError errors gracefully by responding with an ErrorResponse.
 		// For example:
 		// -- "Request command 'foo' is not supported" means we
@@ -370,6 +536,13 @@ func (s *Server) serveDAPCodec() {
 			select {
 			case <-s.stopTriggered:
 			default:
+				if s.isSessionKept() {
+					// The client disconnected without ending the debug session.
+					return
+				}
+				if s.keepDebugSessionOnConnectionLoss(err) {
+					return
+				}
 				if err != io.EOF {
 					if decodeErr, ok := err.(*dap.DecodeProtocolMessageFieldError); ok {
 						// Send an error response to the users if we were unable to process the message.
@@ -386,6 +559,102 @@ func (s *Server) serveDAPCodec() {
 	}
 }
 
+// readProtocolMessage reads and decodes a message from reader.
+// The version of go-dap in use does not decode 'setInstructionBreakpoints'
+// and 'writeMemory' requests, they are decoded here along with the custom
+// 'dlvDiagnostics' request.
+func readProtocolMessage(reader *bufio.Reader) (dap.Message, error) {
+	data, err := dap.ReadBaseMessage(reader)
+	if err != nil {
+		return nil, err
+	}
+	var r dap.Request
+	if err := json.Unmarshal(data, &r); err == nil && r.Type == "request" {
+		var request dap.Message
+		switch r.Command {
+		case "setInstructionBreakpoints":
+			request = &dap.SetInstructionBreakpointsRequest{}
+		case "writeMemory":
+			request = &writeMemoryRequest{}
+		case "dlvDiagnostics":
+			request = &diagnosticsRequest{}
+		}
+		if request != nil {
+			if err := json.Unmarshal(data, request); err != nil {
+				return nil, err
+			}
+			return request, nil
+		}
+	}
+	return dap.DecodeProtocolMessage(data)
+}
+
+// The types below are not defined by the version of go-dap in use.
+
+// capabilities are the capabilities of the server, sent in response to the
+// 'initialize' request.
+type capabilities struct {
+	dap.Capabilities
+	SupportsWriteMemoryRequest bool `json:"supportsWriteMemoryRequest,omitempty"`
+}
+
+// initializeResponse is the response to the 'initialize' request.
+type initializeResponse struct {
+	dap.Response
+
+	Body capabilities `json:"body,omitempty"`
+}
+
+// writeMemoryRequest writes bytes to memory at the provided location.
+type writeMemoryRequest struct {
+	dap.Request
+
+	Arguments writeMemoryArguments `json:"arguments"`
+}
+
+func (r *writeMemoryRequest) GetRequest() *dap.Request { return &r.Request }
+
+// writeMemoryArguments are the arguments of the 'writeMemory' request.
+type writeMemoryArguments struct {
+	MemoryReference string `json:"memoryReference"`
+	Offset          int    `json:"offset,omitempty"`
+	AllowPartial    bool   `json:"allowPartial,omitempty"`
+	Data            string `json:"data"`
+}
+
+// writeMemoryResponse is the response to the 'writeMemory' request.
+type writeMemoryResponse struct {
+	dap.Response
+
+	Body writeMemoryResponseBody `json:"body,omitempty"`
+}
+
+func (r *writeMemoryResponse) GetResponse() *dap.Response { return &r.Response }
+
+// writeMemoryResponseBody is the body of the 'writeMemory' response.
+type writeMemoryResponseBody struct {
+	Offset       int `json:"offset,omitempty"`
+	BytesWritten int `json:"bytesWritten,omitempty"`
+}
+
+// diagnosticsRequest is a custom request, not part of DAP, asking for a
+// report of the internal state of the debugger at the current stop, to
+// attach to bug reports. See the diagnostics command of the terminal.
+type diagnosticsRequest struct {
+	dap.Request
+}
+
+func (r *diagnosticsRequest) GetRequest() *dap.Request { return &r.Request }
+
+// diagnosticsResponse is the response to the 'dlvDiagnostics' request.
+type diagnosticsResponse struct {
+	dap.Response
+
+	Body *api.Diagnostics `json:"body,omitempty"`
+}
+
+func (r *diagnosticsResponse) GetResponse() *dap.Response { return &r.Response }
+
 // In case a handler panics, we catch the panic to avoid crashing both
 // the server and the target. We send an error response back, but
 // in case its a dup and ignored by the client, we also log the error.
@@ -407,6 +676,13 @@ func (s *Server) handleRequest(request dap.Message) {
 		return
 	}
 
+	if !s.authenticate(request) {
+		r := request.(dap.RequestMessage).GetRequest()
+		s.sendErrorResponse(*r, NotAuthenticated, fmt.Sprintf("Unable to process `%s`", r.Command),
+			"authentication required, specify the authToken attribute of the launch or attach request")
+		return
+	}
+
 	// These requests, can be handled regardless of whether the targret is running
 	switch request := request.(type) {
 	case *dap.DisconnectRequest:
@@ -424,11 +700,22 @@ func (s *Server) handleRequest(request dap.Message) {
 		return
 	case *dap.RestartRequest:
 		// Optional (capability ‘supportsRestartRequest’)
-		// TODO: implement this request in V1
 		s.onRestartRequest(request)
 		return
 	}
 
+	// A kept debug session must be adopted before the new client can use it.
+	if s.isSessionKept() {
+		switch request := request.(type) {
+		case *dap.InitializeRequest, *dap.LaunchRequest, *dap.AttachRequest:
+		default:
+			r := request.(dap.RequestMessage).GetRequest()
+			s.sendErrorResponse(*r, SessionNotAdopted, fmt.Sprintf("Unable to process `%s`", r.Command),
+				"debug session must be adopted with an attach request in 'remote' mode first")
+			return
+		}
+	}
+
 	// Most requests cannot be processed while the debuggee is running.
 	// We have a couple of options for handling these without blocking
 	// the request loop indefinitely when we are in running state.
@@ -490,6 +777,14 @@ func (s *Server) handleRequest(request dap.Message) {
 				return
 			}
 			s.onSetFunctionBreakpointsRequest(request)
+		case *dap.SetInstructionBreakpointsRequest:
+			s.log.Debug("halting execution to set breakpoints")
+			_, err := s.debugger.Command(&api.DebuggerCommand{Name: api.Halt}, nil)
+			if err != nil {
+				s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", err.Error())
+				return
+			}
+			s.onSetInstructionBreakpointsRequest(request)
 		default:
 			r := request.(dap.RequestMessage).GetRequest()
 			s.sendErrorResponse(*r, DebuggeeIsRunning, fmt.Sprintf("Unable to process `%s`", r.Command), "debuggee is running")
@@ -547,12 +842,18 @@ func (s *Server) handleRequest(request dap.Message) {
 		<-resumeRequestLoop
 	case *dap.StepBackRequest:
 		// Optional (capability ‘supportsStepBack’)
-		// TODO: implement this request in V1
-		s.onStepBackRequest(request)
+		go func() {
+			defer s.recoverPanic(request)
+			s.onStepBackRequest(request, resumeRequestLoop)
+		}()
+		<-resumeRequestLoop
 	case *dap.ReverseContinueRequest:
 		// Optional (capability ‘supportsStepBack’)
-		// TODO: implement this request in V1
-		s.onReverseContinueRequest(request)
+		go func() {
+			defer s.recoverPanic(request)
+			s.onReverseContinueRequest(request, resumeRequestLoop)
+		}()
+		<-resumeRequestLoop
 	//--- Synchronous requests ---
 	case *dap.InitializeRequest:
 		// Required
@@ -569,6 +870,9 @@ func (s *Server) handleRequest(request dap.Message) {
 	case *dap.SetFunctionBreakpointsRequest:
 		// Optional (capability ‘supportsFunctionBreakpoints’)
 		s.onSetFunctionBreakpointsRequest(request)
+	case *dap.SetInstructionBreakpointsRequest:
+		// Optional (capability ‘supportsInstructionBreakpoints’)
+		s.onSetInstructionBreakpointsRequest(request)
 	case *dap.SetExceptionBreakpointsRequest:
 		// Optional (capability ‘exceptionBreakpointFilters’)
 		s.onSetExceptionBreakpointsRequest(request)
@@ -602,11 +906,15 @@ func (s *Server) handleRequest(request dap.Message) {
 		s.onLoadedSourcesRequest(request)
 	case *dap.ReadMemoryRequest:
 		// Optional (capability ‘supportsReadMemoryRequest‘)
-		// TODO: implement this request in V1
 		s.onReadMemoryRequest(request)
+	case *writeMemoryRequest:
+		// Optional (capability ‘supportsWriteMemoryRequest‘)
+		s.onWriteMemoryRequest(request)
+	case *diagnosticsRequest:
+		// Delve specific
+		s.onDiagnosticsRequest(request)
 	case *dap.DisassembleRequest:
 		// Optional (capability ‘supportsDisassembleRequest’)
-		// TODO: implement this request in V1
 		s.onDisassembleRequest(request)
 	case *dap.CancelRequest:
 		// Optional (capability ‘supportsCancelRequest’)
@@ -615,6 +923,9 @@ func (s *Server) handleRequest(request dap.Message) {
 	case *dap.ExceptionInfoRequest:
 		// Optional (capability ‘supportsExceptionInfoRequest’)
 		s.onExceptionInfoRequest(request)
+	case *dap.SourceRequest:
+		// Required
+		s.onSourceRequest(request)
 	//--- Requests that we do not plan to support ---
 	case *dap.RestartFrameRequest:
 		// Optional (capability ’supportsRestartFrame’)
@@ -622,11 +933,6 @@ func (s *Server) handleRequest(request dap.Message) {
 	case *dap.GotoRequest:
 		// Optional (capability ‘supportsGotoTargetsRequest’)
 		s.sendUnsupportedErrorResponse(request.Request)
-	case *dap.SourceRequest:
-		// Required
-		// This does not make sense in the context of Go as
-		// the source cannot be a string eval'ed at runtime.
-		s.sendUnsupportedErrorResponse(request.Request)
 	case *dap.TerminateThreadsRequest:
 		// Optional (capability ‘supportsTerminateThreadsRequest’)
 		s.sendUnsupportedErrorResponse(request.Request)
@@ -641,17 +947,16 @@ func (s *Server) handleRequest(request dap.Message) {
 		s.sendUnsupportedErrorResponse(request.Request)
 	case *dap.DataBreakpointInfoRequest:
 		// Optional (capability ‘supportsDataBreakpoints’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onDataBreakpointInfoRequest(request)
 	case *dap.SetDataBreakpointsRequest:
 		// Optional (capability ‘supportsDataBreakpoints’)
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onSetDataBreakpointsRequest(request)
 	case *dap.BreakpointLocationsRequest:
 		// Optional (capability ‘supportsBreakpointLocationsRequest’)
 		s.sendUnsupportedErrorResponse(request.Request)
 	case *dap.ModulesRequest:
 		// Optional (capability ‘supportsModulesRequest’)
-		// TODO: does this request make sense for delve?
-		s.sendUnsupportedErrorResponse(request.Request)
+		s.onModulesRequest(request)
 	default:
 		// This is a DAP message that go-dap has a struct for, so
 		// decoding succeeded, but this function does not know how
@@ -702,6 +1007,7 @@ func (s *Server) onInitializeRequest(request *dap.InitializeRequest) {
 	response := &dap.InitializeResponse{Response: *newResponse(request.Request)}
 	response.Body.SupportsConfigurationDoneRequest = true
 	response.Body.SupportsConditionalBreakpoints = true
+	response.Body.SupportsHitConditionalBreakpoints = true
 	response.Body.SupportsDelayedStackTraceLoading = true
 	response.Body.SupportTerminateDebuggee = true
 	response.Body.SupportsFunctionBreakpoints = true
@@ -709,20 +1015,31 @@ func (s *Server) onInitializeRequest(request *dap.InitializeRequest) {
 	response.Body.SupportsSetVariable = true
 	response.Body.SupportsEvaluateForHovers = true
 	response.Body.SupportsClipboardContext = true
+	response.Body.SupportsDataBreakpoints = s.supportsDataBreakpoints()
+	response.Body.SupportsDisassembleRequest = true
+	response.Body.SupportsInstructionBreakpoints = true
+	response.Body.SupportsSteppingGranularity = true
+	response.Body.ExceptionBreakpointFilters = exceptionBreakpointFilters
 	// TODO(polina): support these requests in addition to vscode-go feature parity
 	response.Body.SupportsTerminateRequest = false
-	response.Body.SupportsRestartRequest = false
+	response.Body.SupportsRestartRequest = true
+	response.Body.SupportsModulesRequest = true
+	// Reverse execution is only possible on recordings, the capability is
+	// enabled with a 'capabilities' event once the target is started.
 	response.Body.SupportsStepBack = false
 	response.Body.SupportsSetExpression = false
 	response.Body.SupportsLoadedSourcesRequest = false
-	response.Body.SupportsReadMemoryRequest = false
-	response.Body.SupportsDisassembleRequest = false
+	response.Body.SupportsReadMemoryRequest = true
 	response.Body.SupportsCancelRequest = false
-	s.send(response)
+	s.send(&initializeResponse{
+		Response: response.Response,
+		Body:     capabilities{Capabilities: response.Body, SupportsWriteMemoryRequest: true},
+	})
 }
 
 func (s *Server) setClientCapabilities(args dap.InitializeRequestArguments) {
 	s.clientCapabilities.supportsMemoryReferences = args.SupportsMemoryReferences
+	s.clientCapabilities.supportsInvalidatedEvent = args.SupportsInvalidatedEvent
 	s.clientCapabilities.supportsProgressReporting = args.SupportsProgressReporting
 	s.clientCapabilities.supportsRunInTerminalRequest = args.SupportsRunInTerminalRequest
 	s.clientCapabilities.supportsVariablePaging = args.SupportsVariablePaging
@@ -741,6 +1058,11 @@ func cleanExeName(name string) string {
 }
 
 func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
+	if s.isSessionKept() {
+		s.sendErrorResponse(request.Request, FailedToLaunch, "Failed to launch",
+			"A debug session is already in progress, use an attach request in 'remote' mode to adopt it")
+		return
+	}
 	// Validate launch request mode
 	mode, ok := request.Arguments["mode"]
 	if !ok || mode == "" {
@@ -753,15 +1075,31 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 		return
 	}
 
+	// In replay mode the executable is read from the rr trace, the program
+	// attribute is optional.
+	var traceDirPath string
+	if mode == "replay" {
+		traceDirPath, _ = request.Arguments["traceDirPath"].(string)
+		if traceDirPath == "" {
+			s.sendErrorResponse(request.Request,
+				FailedToLaunch, "Failed to launch",
+				"The 'traceDirPath' attribute is missing in debug configuration.")
+			return
+		}
+	}
+
 	// TODO(polina): Respond with an error if debug session is in progress?
 	program, ok := request.Arguments["program"].(string)
-	if !ok || program == "" {
+	if (!ok || program == "") && mode != "replay" {
 		s.sendErrorResponse(request.Request,
 			FailedToLaunch, "Failed to launch",
 			"The program attribute is missing in debug configuration.")
 		return
 	}
 
+	progress := s.startProgress("Launching", "")
+	defer progress.end()
+
 	if mode == "debug" || mode == "test" {
 		output, ok := request.Arguments["output"].(string)
 		if !ok || output == "" {
@@ -786,7 +1124,16 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 			}
 		}
 
+		// Restart requests rebuild the binary with the same configuration.
+		s.config.Debugger.Packages = []string{program}
+		s.config.Debugger.BuildFlags = buildFlags
+		s.config.Debugger.ExecuteKind = debugger.ExecutingGeneratedFile
+		if mode == "test" {
+			s.config.Debugger.ExecuteKind = debugger.ExecutingGeneratedTest
+		}
+
 		s.log.Debugf("building binary at %s", debugbinary)
+		progress.update(fmt.Sprintf("Building %s", program))
 		var cmd string
 		var out []byte
 		switch mode {
@@ -859,23 +1206,74 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 		s.config.Debugger.WorkingDir = wdParsed
 	}
 
+	targetOut, err := s.newTargetOutput(request.Arguments)
+	if err != nil {
+		s.sendErrorResponse(request.Request, FailedToLaunch, "Failed to launch", err.Error())
+		return
+	}
+	if targetOut != nil && mode == "test" {
+		targetOut.stdout = &testEventWriter{s: s, w: targetOut.stdout}
+	}
+
+	console := "internalConsole"
+	if arg, ok := request.Arguments["console"]; ok {
+		console, ok = arg.(string)
+		if !ok {
+			targetOut.close()
+			s.sendErrorResponse(request.Request,
+				FailedToLaunch, "Failed to launch",
+				fmt.Sprintf("'console' attribute '%v' in debug configuration is not a string.", arg))
+			return
+		}
+	}
+	var term *targetTerminal
+	switch console {
+	case "internalConsole":
+	case "integratedTerminal", "externalTerminal":
+		if targetOut != nil {
+			targetOut.close()
+			s.sendErrorResponse(request.Request,
+				FailedToLaunch, "Failed to launch",
+				fmt.Sprintf("The 'outputMode' and 'outputFile' attributes can not be used with 'console' %q.", console))
+			return
+		}
+		progress.update("Waiting for the terminal")
+		kind := map[string]string{"integratedTerminal": "integrated", "externalTerminal": "external"}[console]
+		term, err = s.runInTerminal(kind, fmt.Sprintf("Delve: %s", filepath.Base(program)), s.config.Debugger.WorkingDir)
+		if err != nil {
+			s.sendErrorResponse(request.Request, FailedToLaunch, "Failed to launch", err.Error())
+			return
+		}
+	default:
+		targetOut.close()
+		s.sendErrorResponse(request.Request,
+			FailedToLaunch, "Failed to launch",
+			fmt.Sprintf("Unsupported 'console' value %q in debug configuration.", console))
+		return
+	}
+
 	s.log.Debugf("running program in %s\n", s.config.Debugger.WorkingDir)
 	if noDebug, ok := request.Arguments["noDebug"].(bool); ok && noDebug {
 		s.mu.Lock()
-		cmd, err := s.startNoDebugProcess(program, targetArgs, s.config.Debugger.WorkingDir)
+		cmd, err := s.startNoDebugProcess(program, targetArgs, s.config.Debugger.WorkingDir, targetOut, term)
 		s.mu.Unlock()
 		if err != nil {
+			targetOut.close()
+			term.close()
 			s.sendErrorResponse(request.Request, FailedToLaunch, "Failed to launch", err.Error())
 			return
 		}
 		// Skip 'initialized' event, which will prevent the client from sending
 		// debug-related requests.
+		progress.end()
 		s.send(&dap.LaunchResponse{Response: *newResponse(request.Request)})
 
 		// Then, block until the program terminates or is stopped.
 		if err := cmd.Wait(); err != nil {
 			s.log.Debugf("program exited with error: %v", err)
 		}
+		targetOut.close()
+		term.close()
 		stopped := false
 		s.mu.Lock()
 		stopped = s.noDebugProcess == nil // if it was stopped, this should be nil.
@@ -889,15 +1287,46 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 		return
 	}
 
+	if traceDirPath != "" {
+		s.config.Debugger.Backend = "rr"
+		s.config.Debugger.CoreFile = traceDirPath
+		progress.update(fmt.Sprintf("Opening trace %s", traceDirPath))
+	} else {
+		progress.update(fmt.Sprintf("Loading %s", program))
+	}
+
+	if targetOut != nil {
+		paths, err := targetOut.startPipes()
+		if err != nil {
+			targetOut.close()
+			s.sendErrorResponse(request.Request, FailedToLaunch, "Failed to launch", err.Error())
+			return
+		}
+		s.config.Debugger.Redirects[1], s.config.Debugger.Redirects[2] = paths[0], paths[1]
+	}
+	if term != nil {
+		s.config.Debugger.Redirects = [3]string{term.tty, term.tty, term.tty}
+	}
+
 	func() {
 		s.mu.Lock()
 		defer s.mu.Unlock() // Make sure to unlock in case of panic that will become internal error
 		s.debugger, err = debugger.New(&s.config.Debugger, s.config.ProcessArgs)
+		if err == nil {
+			s.targetOutput = targetOut
+			s.targetTerminal = term
+		}
 	}()
 	if err != nil {
+		targetOut.close()
+		term.close()
 		s.sendErrorResponse(request.Request, FailedToLaunch, "Failed to launch", err.Error())
 		return
 	}
+	s.modulesReported = len(s.debugger.ListImages())
+
+	s.addModuleSubstitutePath()
+	s.sendStepBackCapability()
 
 	// Notify the client that the debugger is ready to start accepting
 	// configuration requests for setting breakpoints, etc. The client
@@ -906,14 +1335,40 @@ func (s *Server) onLaunchRequest(request *dap.LaunchRequest) {
 	s.send(&dap.LaunchResponse{Response: *newResponse(request.Request)})
 }
 
+// sendStepBackCapability enables the reverse execution controls of the
+// client when the target is a recording. The 'initialize' request is
+// received before the target is started, the capability is therefore
+// advertised with a 'capabilities' event.
+func (s *Server) sendStepBackCapability() {
+	if !s.isRecording() {
+		return
+	}
+	s.send(&dap.CapabilitiesEvent{
+		Event: *newEvent("capabilities"),
+		Body:  dap.CapabilitiesEventBody{Capabilities: dap.Capabilities{SupportsStepBack: true}},
+	})
+}
+
 // startNoDebugProcess is called from onLaunchRequest (run goroutine) and
 // requires holding mu lock.
-func (s *Server) startNoDebugProcess(program string, targetArgs []string, wd string) (*exec.Cmd, error) {
+func (s *Server) startNoDebugProcess(program string, targetArgs []string, wd string, targetOut *targetOutput, term *targetTerminal) (*exec.Cmd, error) {
 	if s.noDebugProcess != nil {
 		return nil, fmt.Errorf("another launch request is in progress")
 	}
 	cmd := exec.Command(program, targetArgs...)
 	cmd.Stdout, cmd.Stderr, cmd.Stdin, cmd.Dir = os.Stdout, os.Stderr, os.Stdin, wd
+	if targetOut != nil {
+		cmd.Stdout, cmd.Stderr = targetOut.stdout, targetOut.stderr
+	}
+	if term != nil {
+		tty, err := os.OpenFile(term.tty, os.O_RDWR, 0)
+		if err != nil {
+			return nil, err
+		}
+		// The child process has its own copy of the file descriptor.
+		defer tty.Close()
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = tty, tty, tty
+	}
 	if err := cmd.Start(); err != nil {
 		return nil, err
 	}
@@ -941,7 +1396,7 @@ func (s *Server) stopNoDebugProcess() {
 // TODO(polina): support "remote" mode
 func isValidLaunchMode(launchMode interface{}) bool {
 	switch launchMode {
-	case "exec", "debug", "test":
+	case "exec", "debug", "test", "replay":
 		return true
 	}
 
@@ -952,6 +1407,10 @@ func isValidLaunchMode(launchMode interface{}) bool {
 // it disconnects the debuggee and signals that the debug adaptor
 // (in our case this TCP server) can be terminated.
 func (s *Server) onDisconnectRequest(request *dap.DisconnectRequest) {
+	if s.config.AcceptMulti && s.debugger != nil && !request.Arguments.TerminateDebuggee {
+		s.keepDebugSession(request)
+		return
+	}
 	defer s.triggerServerStop()
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -974,6 +1433,89 @@ func (s *Server) onDisconnectRequest(request *dap.DisconnectRequest) {
 	}
 }
 
+// keepDebugSession disconnects the client without ending the debug session,
+// so it can be adopted by the next client that connects to the server.
+// Unless the keepRunningOnDisconnect attribute is set the target is halted,
+// so the new client finds it in a stopped state.
+func (s *Server) keepDebugSession(request *dap.DisconnectRequest) {
+	if err := s.haltForNextClient(); err != nil {
+		s.sendErrorResponse(request.Request, DisconnectError, "Error while disconnecting", err.Error())
+		return
+	}
+	s.logToConsole("Detaching client, debug session is kept for the next client")
+	s.send(&dap.DisconnectResponse{Response: *newResponse(request.Request)})
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionKept = true
+	// Closing the connection will break out of the read loop,
+	// which will go back to accepting connections.
+	_ = s.conn.Close()
+}
+
+// keepDebugSessionOnConnectionLoss keeps the debug session for the next
+// client when the connection was lost without a disconnect request, for
+// example because the editor crashed or its window was reloaded.
+// Returns false if the session should end instead.
+func (s *Server) keepDebugSessionOnConnectionLoss(connErr error) bool {
+	s.mu.Lock()
+	hasSession := s.debugger != nil
+	s.mu.Unlock()
+	if !s.config.AcceptMulti || !hasSession {
+		return false
+	}
+	if err := s.haltForNextClient(); err != nil {
+		s.log.Errorf("could not keep the debug session after losing the client connection: %v", err)
+		return false
+	}
+	s.log.Debugf("client connection lost (%v), debug session is kept for the next client", connErr)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessionKept = true
+	_ = s.conn.Close()
+	return true
+}
+
+// haltForNextClient halts the target, before the debug session is kept for
+// the next client, unless the keepRunningOnDisconnect attribute is set.
+func (s *Server) haltForNextClient() error {
+	if s.args.keepRunningOnDisconnect || !s.debugger.IsRunning() {
+		return nil
+	}
+	// Halting will interrupt the command pending on the per-request
+	// goroutine. Its stopped event will be sent to this client, which
+	// is about to be disconnected, so it is resent on adoption.
+	_, err := s.debugger.Command(&api.DebuggerCommand{Name: api.Halt}, nil)
+	return err
+}
+
+// onAdoptSessionRequest handles an attach request in "remote" mode,
+// which lets a new client take over a debug session kept by keepDebugSession.
+func (s *Server) onAdoptSessionRequest(request *dap.AttachRequest) {
+	s.mu.Lock()
+	kept := s.sessionKept
+	s.sessionKept = false
+	s.mu.Unlock()
+	if !kept {
+		s.sendErrorResponse(request.Request,
+			FailedToAttach, "Failed to attach",
+			"No debug session to adopt: 'remote' mode requires a session kept by a previous client (see --accept-multiclient)")
+		return
+	}
+	// Launch/attach arguments that only affect presentation can be changed
+	// by the new client. The target itself is not affected.
+	if err := s.setLaunchAttachArgs(request); err != nil {
+		s.sendErrorResponse(request.Request, FailedToAttach, "Failed to attach", err.Error())
+		return
+	}
+	// The target was halted when the previous client disconnected.
+	// Do not stop on entry, report the existing stop instead.
+	s.args.stopOnEntry = false
+	s.args.adoptedSession = true
+	s.addModuleSubstitutePath()
+	s.send(&dap.InitializedEvent{Event: *newEvent("initialized")})
+	s.send(&dap.AttachResponse{Response: *newResponse(request.Request)})
+}
+
 // stopDebugSession is called from Stop (main goroutine) and
 // onDisconnectRequest (run goroutine) and requires holding mu lock.
 // Returns any detach error other than proc.ErrProcessExited.
@@ -1018,6 +1560,10 @@ func (s *Server) stopDebugSession(killProcess bool) error {
 	}
 	err = s.debugger.Detach(killProcess)
 	s.debugger = nil
+	s.targetOutput.close()
+	s.targetOutput = nil
+	s.targetTerminal.close()
+	s.targetTerminal = nil
 	if err != nil {
 		switch err.(type) {
 		case proc.ErrProcessExited:
@@ -1111,7 +1657,7 @@ func (s *Server) onSetBreakpointsRequest(request *dap.SetBreakpointsRequest) {
 		} else {
 			// Create new breakpoints.
 			got, err = s.debugger.CreateBreakpoint(
-				&api.Breakpoint{File: serverPath, Line: want.Line, Cond: want.Condition, HitCond: want.HitCondition, Name: reqString})
+				&api.Breakpoint{File: serverPath, Line: want.Line, Cond: want.Condition, HitCond: want.HitCondition, Name: reqString, SerializeHits: s.args.serializeBreakpointHits})
 			bpAdded[reqString] = struct{}{}
 		}
 
@@ -1211,7 +1757,8 @@ func (s *Server) onSetFunctionBreakpointsRequest(request *dap.SetFunctionBreakpo
 			breakpoints[i].Message = err.Error()
 			continue
 		}
-		if loc, ok := spec.(*locspec.NormalLocationSpec); !ok || loc.FuncBase == nil {
+		normalSpec, ok := spec.(*locspec.NormalLocationSpec)
+		if !ok || normalSpec.FuncBase == nil {
 			// Other locations do not make sense in the context of function breakpoints.
 			// Regex locations are likely to resolve to multiple places and offset locations
 			// are only meaningful at the time the breakpoint was created.
@@ -1226,8 +1773,14 @@ func (s *Server) onSetFunctionBreakpointsRequest(request *dap.SetFunctionBreakpo
 		// Find the location of the function name. CreateBreakpoint requires the name to include the base
 		// (e.g. main.functionName is supported but not functionName).
 		// We first find the location of the function, and then set breakpoints for that location.
+		// Paths on the client are converted to paths on the server before
+		// matching them against the sources of the target, using the same
+		// rules as source breakpoints.
+		if filepath.IsAbs(normalSpec.Base) {
+			normalSpec.Base = s.toServerPath(normalSpec.Base)
+		}
 		var locs []api.Location
-		locs, err = s.debugger.FindLocationSpec(-1, 0, 0, want.Name, spec, true, s.args.substitutePathClientToServer)
+		locs, err = s.debugger.FindLocationSpec(-1, 0, 0, want.Name, spec, true, nil)
 		if err != nil {
 			breakpoints[i].Message = err.Error()
 			continue
@@ -1243,7 +1796,7 @@ func (s *Server) onSetFunctionBreakpointsRequest(request *dap.SetFunctionBreakpo
 
 		// Set breakpoint using the PCs that were found.
 		loc := locs[0]
-		got, err := s.debugger.CreateBreakpoint(&api.Breakpoint{Addr: loc.PC, Addrs: loc.PCs, Cond: want.Condition, Name: reqString})
+		got, err := s.debugger.CreateBreakpoint(&api.Breakpoint{Addr: loc.PC, Addrs: loc.PCs, Cond: want.Condition, Name: reqString, SerializeHits: s.args.serializeBreakpointHits})
 
 		var clientPath string
 		if got != nil {
@@ -1258,6 +1811,294 @@ func (s *Server) onSetFunctionBreakpointsRequest(request *dap.SetFunctionBreakpo
 	s.send(response)
 }
 
+// dataBpPrefix is the prefix of bp.Name for every breakpoint bp set
+// by a setDataBreakpoints request.
+const dataBpPrefix = "dataBreakpoint"
+
+// supportsDataBreakpoints returns true if the backend used by this server
+// can set watchpoints. Watchpoints are only implemented by the native
+// backend on linux/amd64.
+func (s *Server) supportsDataBreakpoints() bool {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		return false
+	}
+	switch s.config.Debugger.Backend {
+	case "", "default", "native":
+		return true
+	}
+	return false
+}
+
+// onDataBreakpointInfoRequest handles 'dataBreakpointInfo' requests.
+// The data id of a variable is the expression that evaluates to it, the
+// watchpoint is set when the client sends the corresponding
+// setDataBreakpoints request.
+func (s *Server) onDataBreakpointInfoRequest(request *dap.DataBreakpointInfoRequest) {
+	response := &dap.DataBreakpointInfoResponse{Response: *newResponse(request.Request)}
+	// If a data breakpoint can not be set for the variable the response
+	// should have a null data id and a description of the reason.
+	unavailable := func(reason string) {
+		response.Body.DataId = nil
+		response.Body.Description = reason
+		s.send(response)
+	}
+
+	if !s.supportsDataBreakpoints() {
+		unavailable("data breakpoints are not supported on this platform")
+		return
+	}
+
+	// If variablesReference is not specified, name is an expression.
+	expr := request.Arguments.Name
+	if ref := request.Arguments.VariablesReference; ref != 0 {
+		v, ok := s.variableHandles.get(ref)
+		if !ok {
+			unavailable(fmt.Sprintf("unknown reference %d", ref))
+			return
+		}
+		var err error
+		expr, err = s.computeEvaluateName(v, request.Arguments.Name)
+		if err != nil {
+			unavailable(err.Error())
+			return
+		}
+	}
+
+	// Like setVariable, watchpoints are set on the variable accessible
+	// with expr from the top most frame of the current goroutine.
+	v, err := s.debugger.EvalVariableInScope(-1, 0, 0, expr, proc.LoadConfig{})
+	if err != nil {
+		unavailable(err.Error())
+		return
+	}
+	if v.Addr == 0 || v.Flags&proc.VariableFakeAddress != 0 {
+		unavailable(fmt.Sprintf("%s is not addressable", expr))
+		return
+	}
+
+	response.Body.DataId = expr
+	response.Body.Description = expr
+	response.Body.AccessTypes = []dap.DataBreakpointAccessType{"write", "read", "readWrite"}
+	response.Body.CanPersist = false
+	s.send(response)
+}
+
+// onSetDataBreakpointsRequest handles 'setDataBreakpoints' requests.
+func (s *Server) onSetDataBreakpointsRequest(request *dap.SetDataBreakpointsRequest) {
+	if s.isNoDebug() {
+		s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", "running in noDebug mode")
+		return
+	}
+
+	// Like setFunctionBreakpoints, setDataBreakpoints replaces all existing
+	// data breakpoints. Existing watchpoints are amended to maintain their
+	// state, the watched expression might no longer be in scope.
+
+	// Get all existing data breakpoints.
+	existingBps := s.getMatchingBreakpoints(dataBpPrefix)
+	bpAdded := make(map[string]struct{}, len(existingBps))
+
+	// Amend any existing breakpoints.
+	breakpoints := make([]dap.Breakpoint, len(request.Arguments.Breakpoints))
+	for i, want := range request.Arguments.Breakpoints {
+		reqString := dataBpName(want)
+		got, ok := existingBps[reqString]
+		if !ok {
+			// Skip if the breakpoint does not already exist.
+			// These will be created after deleting existing
+			// breakpoints to avoid conflicts.
+			continue
+		}
+		var err error
+		if _, ok := bpAdded[reqString]; ok {
+			err = fmt.Errorf("data breakpoint exists for %q", want.DataId)
+		} else {
+			got.Cond = want.Condition
+			got.HitCond = want.HitCondition
+			err = s.debugger.AmendBreakpoint(got)
+			bpAdded[reqString] = struct{}{}
+		}
+		updateDataBreakpointsResponse(breakpoints, i, err, got)
+	}
+
+	// Clear existing breakpoints that were not added.
+	err := s.clearBreakpoints(existingBps, bpAdded)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", err.Error())
+		return
+	}
+
+	// Create new breakpoints.
+	for i, want := range request.Arguments.Breakpoints {
+		reqString := dataBpName(want)
+		if _, ok := existingBps[reqString]; ok {
+			continue
+		}
+		if _, ok := bpAdded[reqString]; ok {
+			updateDataBreakpointsResponse(breakpoints, i, fmt.Errorf("data breakpoint exists for %q", want.DataId), nil)
+			continue
+		}
+		bpAdded[reqString] = struct{}{}
+
+		got, err := s.createDataBreakpoint(want, reqString)
+		updateDataBreakpointsResponse(breakpoints, i, err, got)
+	}
+
+	response := &dap.SetDataBreakpointsResponse{Response: *newResponse(request.Request)}
+	response.Body.Breakpoints = breakpoints
+
+	s.send(response)
+}
+
+// createDataBreakpoint sets a watchpoint for the expression returned as
+// data id by onDataBreakpointInfoRequest.
+func (s *Server) createDataBreakpoint(want dap.DataBreakpoint, name string) (*api.Breakpoint, error) {
+	if !s.supportsDataBreakpoints() {
+		return nil, errors.New("data breakpoints are not supported on this platform")
+	}
+	expr := want.DataId
+	if expr == "" {
+		return nil, errors.New("empty data id")
+	}
+	var wtype api.WatchType
+	switch want.AccessType {
+	case "read":
+		wtype = api.WatchRead
+	case "write", "":
+		wtype = api.WatchWrite
+	case "readWrite":
+		wtype = api.WatchRead | api.WatchWrite
+	default:
+		return nil, fmt.Errorf("unknown access type %q", want.AccessType)
+	}
+	got, err := s.debugger.CreateWatchpoint(-1, 0, 0, expr, wtype)
+	if err != nil {
+		return nil, err
+	}
+	got.Name = name
+	got.Cond = want.Condition
+	got.HitCond = want.HitCondition
+	if err := s.debugger.AmendBreakpoint(got); err != nil {
+		s.debugger.ClearBreakpoint(got)
+		return nil, err
+	}
+	return got, nil
+}
+
+// dataBpName returns the name of the watchpoint created for bp.
+func dataBpName(bp dap.DataBreakpoint) string {
+	accessType := bp.AccessType
+	if accessType == "" {
+		accessType = "write"
+	}
+	return fmt.Sprintf("%s AccessType=%s DataId=%s", dataBpPrefix, accessType, bp.DataId)
+}
+
+func updateDataBreakpointsResponse(breakpoints []dap.Breakpoint, i int, err error, got *api.Breakpoint) {
+	breakpoints[i].Verified = (err == nil)
+	if err != nil {
+		breakpoints[i].Message = err.Error()
+	} else {
+		breakpoints[i].Id = got.ID
+	}
+}
+
+// instructionBpPrefix is the prefix of bp.Name for every breakpoint bp set
+// by a setInstructionBreakpoints request.
+const instructionBpPrefix = "instructionBreakpoint"
+
+// onSetInstructionBreakpointsRequest handles 'setInstructionBreakpoints' requests.
+func (s *Server) onSetInstructionBreakpointsRequest(request *dap.SetInstructionBreakpointsRequest) {
+	if s.isNoDebug() {
+		s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", "running in noDebug mode")
+		return
+	}
+
+	// Like setFunctionBreakpoints, setInstructionBreakpoints replaces all
+	// existing instruction breakpoints, existing breakpoints are amended
+	// to maintain their state.
+
+	// Get all existing instruction breakpoints.
+	existingBps := s.getMatchingBreakpoints(instructionBpPrefix)
+	bpAdded := make(map[string]struct{}, len(existingBps))
+
+	breakpoints := make([]dap.Breakpoint, len(request.Arguments.Breakpoints))
+	addrs := make([]uint64, len(request.Arguments.Breakpoints))
+	reqStrings := make([]string, len(request.Arguments.Breakpoints))
+	for i, want := range request.Arguments.Breakpoints {
+		addr, err := strconv.ParseUint(want.InstructionReference, 0, 64)
+		if err != nil {
+			breakpoints[i].Message = fmt.Sprintf("invalid instruction reference %q", want.InstructionReference)
+			continue
+		}
+		addrs[i] = uint64(int64(addr) + int64(want.Offset))
+		reqStrings[i] = fmt.Sprintf("%s PC=%#x", instructionBpPrefix, addrs[i])
+	}
+
+	// Amend any existing breakpoints.
+	for i, want := range request.Arguments.Breakpoints {
+		got, ok := existingBps[reqStrings[i]]
+		if reqStrings[i] == "" || !ok {
+			// Skip if the breakpoint does not already exist.
+			// These will be created after deleting existing
+			// breakpoints to avoid conflicts.
+			continue
+		}
+		var err error
+		if _, ok := bpAdded[reqStrings[i]]; ok {
+			err = fmt.Errorf("breakpoint exists at address %#x", addrs[i])
+		} else {
+			got.Cond = want.Condition
+			got.HitCond = want.HitCondition
+			err = s.debugger.AmendBreakpoint(got)
+			bpAdded[reqStrings[i]] = struct{}{}
+		}
+		s.updateInstructionBreakpointsResponse(breakpoints, i, err, got)
+	}
+
+	// Clear existing breakpoints that were not added.
+	err := s.clearBreakpoints(existingBps, bpAdded)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", err.Error())
+		return
+	}
+
+	// Create new breakpoints.
+	for i, want := range request.Arguments.Breakpoints {
+		if reqStrings[i] == "" {
+			continue
+		}
+		if _, ok := existingBps[reqStrings[i]]; ok {
+			continue
+		}
+		var got *api.Breakpoint
+		var err error
+		if _, ok := bpAdded[reqStrings[i]]; ok {
+			err = fmt.Errorf("breakpoint exists at address %#x", addrs[i])
+		} else {
+			got, err = s.debugger.CreateBreakpoint(&api.Breakpoint{Addr: addrs[i], Cond: want.Condition, HitCond: want.HitCondition, Name: reqStrings[i], SerializeHits: s.args.serializeBreakpointHits})
+			bpAdded[reqStrings[i]] = struct{}{}
+		}
+		s.updateInstructionBreakpointsResponse(breakpoints, i, err, got)
+	}
+
+	response := &dap.SetInstructionBreakpointsResponse{Response: *newResponse(request.Request)}
+	response.Body.Breakpoints = breakpoints
+
+	s.send(response)
+}
+
+func (s *Server) updateInstructionBreakpointsResponse(breakpoints []dap.Breakpoint, i int, err error, got *api.Breakpoint) {
+	var clientPath string
+	if got != nil {
+		clientPath = s.toClientPath(got.File)
+	}
+	updateBreakpointsResponse(breakpoints, i, err, got, clientPath)
+	if err == nil {
+		breakpoints[i].InstructionReference = fmt.Sprintf("%#x", got.Addr)
+	}
+}
+
 func (s *Server) clearBreakpoints(existingBps map[string]*api.Breakpoint, bpAdded map[string]struct{}) error {
 	for req, bp := range existingBps {
 		if _, ok := bpAdded[req]; ok {
@@ -1288,10 +2129,163 @@ func (s *Server) getMatchingBreakpoints(prefix string) map[string]*api.Breakpoin
 	return matchingBps
 }
 
+// Exception breakpoint filters, see exceptionBreakpointFilters.
+const (
+	uncaughtPanicFilter         = "uncaughtPanic"
+	uncaughtPanicInModuleFilter = "uncaughtPanicInModule"
+	panicFilter                 = "panic"
+	fatalErrorFilter            = "fatalError"
+	testFailureFilter           = "testFailure"
+)
+
+// exceptionBreakpointFilters are the exception breakpoint filters
+// advertised in the 'initialize' response.
+var exceptionBreakpointFilters = []dap.ExceptionBreakpointsFilter{
+	{
+		Filter:      uncaughtPanicFilter,
+		Label:       "Uncaught panics",
+		Description: "Stop when a panic is not recovered.",
+		Default:     true,
+	},
+	{
+		Filter:      uncaughtPanicInModuleFilter,
+		Label:       "Uncaught panics in my module",
+		Description: "Stop when a panic raised by a function of the main module is not recovered.",
+	},
+	{
+		Filter:      panicFilter,
+		Label:       "All panics",
+		Description: "Stop at every panic, including panics that are later recovered.",
+	},
+	{
+		Filter:      fatalErrorFilter,
+		Label:       "Fatal runtime errors",
+		Description: "Stop at fatal errors of the runtime, such as concurrent map writes or deadlocks.",
+		Default:     true,
+	},
+	{
+		Filter:      testFailureFilter,
+		Label:       "Test failures",
+		Description: "Stop when a test fails, where the failure is recorded.",
+		Default:     true,
+	},
+}
+
+func defaultExceptionFilters() map[string]bool {
+	filters := make(map[string]bool)
+	for _, f := range exceptionBreakpointFilters {
+		if f.Default {
+			filters[f.Filter] = true
+		}
+	}
+	return filters
+}
+
+// panicBpName is the name of the breakpoint at runtime.gopanic, set when
+// the panicFilter exception filter is enabled.
+const panicBpName = "exceptionBreakpoint panic"
+
+// onSetExceptionBreakpointsRequest handles 'setExceptionBreakpoints' requests.
+// The debugger always stops at uncaught panics and fatal errors, when they
+// are excluded by the filters execution is resumed (see isFilteredException).
+// Stopping at every panic requires a breakpoint at runtime.gopanic.
 func (s *Server) onSetExceptionBreakpointsRequest(request *dap.SetExceptionBreakpointsRequest) {
-	// Unlike what DAP documentation claims, this request is always sent
-	// even though we specified no filters at initialization. Handle as no-op.
-	s.send(&dap.SetExceptionBreakpointsResponse{Response: *newResponse(request.Request)})
+	if s.isNoDebug() {
+		s.send(&dap.SetExceptionBreakpointsResponse{Response: *newResponse(request.Request)})
+		return
+	}
+
+	filters := make(map[string]bool, len(request.Arguments.Filters))
+	breakpoints := make([]dap.Breakpoint, len(request.Arguments.Filters))
+	for i, filter := range request.Arguments.Filters {
+		switch filter {
+		case uncaughtPanicFilter, uncaughtPanicInModuleFilter, panicFilter, fatalErrorFilter, testFailureFilter:
+			filters[filter] = true
+			breakpoints[i].Verified = true
+		default:
+			breakpoints[i].Message = fmt.Sprintf("unknown exception filter %q", filter)
+		}
+	}
+
+	panicBp := s.debugger.FindBreakpointByName(panicBpName)
+	switch {
+	case filters[panicFilter] && panicBp == nil:
+		if _, err := s.debugger.CreateBreakpoint(&api.Breakpoint{FunctionName: "runtime.gopanic", Name: panicBpName}); err != nil {
+			delete(filters, panicFilter)
+			for i, filter := range request.Arguments.Filters {
+				if filter == panicFilter {
+					breakpoints[i].Verified = false
+					breakpoints[i].Message = err.Error()
+				}
+			}
+		}
+	case !filters[panicFilter] && panicBp != nil:
+		if _, err := s.debugger.ClearBreakpoint(panicBp); err != nil {
+			s.sendErrorResponse(request.Request, UnableToSetBreakpoints, "Unable to set or clear breakpoints", err.Error())
+			return
+		}
+	}
+	s.exceptionFilters = filters
+
+	response := &dap.SetExceptionBreakpointsResponse{Response: *newResponse(request.Request)}
+	response.Body.Breakpoints = breakpoints
+	s.send(response)
+}
+
+// isFilteredException returns true if the program stopped at an uncaught
+// panic, a fatal error or a test failure that is excluded by the exception
+// filters.
+func (s *Server) isFilteredException(state *api.DebuggerState) bool {
+	if state == nil || state.CurrentThread == nil || state.CurrentThread.Breakpoint == nil {
+		return false
+	}
+	switch state.CurrentThread.Breakpoint.Name {
+	case proc.FatalThrow:
+		return !s.exceptionFilters[fatalErrorFilter]
+	case proc.TestFailure:
+		return !s.exceptionFilters[testFailureFilter]
+	case proc.UnrecoveredPanic:
+		if s.exceptionFilters[uncaughtPanicFilter] {
+			return false
+		}
+		return !s.exceptionFilters[uncaughtPanicInModuleFilter] || !s.panicInMainModule(stoppedGoroutineID(state))
+	}
+	return false
+}
+
+// panicInMainModule returns true if the panic of goroutine goroutineID was
+// raised by a function of the main module, that is if the first function
+// in its stack that is not part of the runtime belongs to the main module.
+func (s *Server) panicInMainModule(goroutineID int) bool {
+	frames, err := s.debugger.Stacktrace(goroutineID, s.args.stackTraceDepth, 0)
+	if err != nil {
+		s.log.Debugf("could not find the panicking function of goroutine %d: %v", goroutineID, err)
+		return true
+	}
+	_, modPath, err := s.mainModuleInfo()
+	if err != nil {
+		s.log.Debugf("could not find the main module: %v", err)
+	}
+	for _, frame := range frames {
+		if frame.Call.Fn == nil {
+			continue
+		}
+		pkg := frame.Call.Fn.PackageName()
+		if pkg == "runtime" || strings.HasPrefix(pkg, "runtime/") {
+			continue
+		}
+		return isMainModulePackage(pkg, modPath)
+	}
+	return false
+}
+
+// isMainModulePackage returns true if the package with import path pkg
+// belongs to the module with path modPath, or is the main package.
+func isMainModulePackage(pkg, modPath string) bool {
+	if pkg == "main" {
+		return true
+	}
+	return modPath != "" && (pkg == modPath || strings.HasPrefix(pkg, modPath+"/"))
 }
 
 func (s *Server) asyncCommandDone(asyncSetupDone chan struct{}) {
@@ -1311,6 +2305,20 @@ func (s *Server) asyncCommandDone(asyncSetupDone chan struct{}) {
 // so the s.debugger is guaranteed to be set.
 func (s *Server) onConfigurationDoneRequest(request *dap.ConfigurationDoneRequest, asyncSetupDone chan struct{}) {
 	defer s.asyncCommandDone(asyncSetupDone)
+	if s.args.adoptedSession {
+		// The session was adopted from a previous client, so the target
+		// is already stopped, unless it was kept running. Report that stop
+		// and let the user decide when to resume. If the target is running
+		// the command resumed by the previous client reports the next stop.
+		s.send(&dap.ConfigurationDoneResponse{Response: *newResponse(request.Request)})
+		if s.debugger.IsRunning() {
+			return
+		}
+		if state, err := s.debugger.State( /*nowait*/ true); err == nil {
+			s.sendStoppedEvent(state)
+		}
+		return
+	}
 	if s.args.stopOnEntry {
 		e := &dap.StoppedEvent{
 			Event: *newEvent("stopped"),
@@ -1357,6 +2365,9 @@ func (s *Server) onThreadsRequest(request *dap.ThreadsRequest) {
 		return
 	}
 
+	progress := s.startProgress("Loading goroutines", "")
+	defer progress.end()
+
 	gs, _, err := s.debugger.Goroutines(0, 0)
 	if err != nil {
 		switch err.(type) {
@@ -1384,8 +2395,19 @@ func (s *Server) onThreadsRequest(request *dap.ThreadsRequest) {
 			s.sendErrorResponse(request.Request, UnableToDisplayThreads, "Unable to display threads", err.Error())
 			return
 		}
+		gs, groupNames, err := s.selectGoroutines(gs, state.SelectedGoroutine)
+		if err != nil {
+			s.sendErrorResponse(request.Request, UnableToDisplayThreads, "Unable to display threads", err.Error())
+			return
+		}
+		if len(gs) == 0 {
+			threads = []dap.Thread{{Id: 1, Name: "Dummy"}}
+		} else {
+			threads = make([]dap.Thread, len(gs))
+		}
 		s.debugger.LockTarget()
 		defer s.debugger.UnlockTarget()
+		tgt := s.debugger.Target()
 
 		for i, g := range gs {
 			selected := ""
@@ -1398,8 +2420,16 @@ func (s *Server) onThreadsRequest(request *dap.ThreadsRequest) {
 			}
 			// File name and line number are communicated via `stackTrace`
 			// so no need to include them here.
+			group := ""
+			if name, ok := groupNames[g.ID]; ok {
+				group = "(" + name + ") "
+			}
 			loc := g.UserCurrent()
-			threads[i].Name = fmt.Sprintf("%s[Go %d] %s%s", selected, g.ID, fnName(&loc), thread)
+			goName := ""
+			if a, _ := tgt.GoroutineAnnotation(g.ID); a.Name != "" {
+				goName = fmt.Sprintf(" (%s)", a.Name)
+			}
+			threads[i].Name = fmt.Sprintf("%s%s[Go %d%s] %s%s%s", selected, group, g.ID, goName, fnName(&loc), thread, goroutineDetails(tgt, g, &loc))
 			threads[i].Id = g.ID
 		}
 	}
@@ -1418,29 +2448,61 @@ func (s *Server) onAttachRequest(request *dap.AttachRequest) {
 	if !ok || mode == "" {
 		mode = "local"
 	}
+	if mode == "remote" {
+		s.onAdoptSessionRequest(request)
+		return
+	}
+	if s.isSessionKept() {
+		s.sendErrorResponse(request.Request, FailedToAttach, "Failed to attach",
+			"A debug session is already in progress, use 'remote' mode to adopt it")
+		return
+	}
 	if mode == "local" {
-		pid, ok := request.Arguments["processId"].(float64)
-		if !ok || pid == 0 {
+		pid, _ := request.Arguments["processId"].(float64)
+		waitFor, err := attachWaitForArgs(request.Arguments)
+		if err != nil {
+			s.sendErrorResponse(request.Request, FailedToAttach, "Failed to attach", err.Error())
+			return
+		}
+		switch {
+		case pid != 0 && waitFor != "":
+			s.sendErrorResponse(request.Request,
+				FailedToAttach, "Failed to attach",
+				"The 'processId' and 'waitFor' attributes can not be used together")
+			return
+		case pid == 0 && waitFor == "":
 			s.sendErrorResponse(request.Request,
 				FailedToAttach, "Failed to attach",
 				"The 'processId' attribute is missing in debug configuration")
 			return
 		}
 		s.config.Debugger.AttachPid = int(pid)
-		err := s.setLaunchAttachArgs(request)
+		s.config.Debugger.AttachWaitFor = waitFor
+		if timeout, ok := request.Arguments["waitForTimeout"].(float64); ok && timeout > 0 {
+			s.config.Debugger.AttachWaitForDuration = time.Duration(timeout * float64(time.Second))
+		}
+		err = s.setLaunchAttachArgs(request)
 		if err != nil {
 			s.sendErrorResponse(request.Request, FailedToAttach, "Failed to attach", err.Error())
 			return
 		}
+		msg := fmt.Sprintf("Attaching to process %d", int(pid))
+		if waitFor != "" {
+			s.logToConsole(fmt.Sprintf("Waiting for a process matching %q to start...", waitFor))
+			msg = fmt.Sprintf("Waiting for a process matching %q", waitFor)
+		}
+		progress := s.startProgress("Attaching", msg)
 		func() {
 			s.mu.Lock()
 			defer s.mu.Unlock() // Make sure to unlock in case of panic that will become internal error
 			s.debugger, err = debugger.New(&s.config.Debugger, nil)
 		}()
+		progress.end()
 		if err != nil {
 			s.sendErrorResponse(request.Request, FailedToAttach, "Failed to attach", err.Error())
 			return
 		}
+		s.modulesReported = len(s.debugger.ListImages())
 	} else {
 		// TODO(polina): support 'remote' mode with 'host' and 'port'
 		s.sendErrorResponse(request.Request,
@@ -1448,6 +2510,8 @@ func (s *Server) onAttachRequest(request *dap.AttachRequest) {
 			fmt.Sprintf("Unsupported 'mode' value %q in debug configuration", mode))
 		return
 	}
+	s.addModuleSubstitutePath()
+
 	// Notify the client that the debugger is ready to start accepting
 	// configuration requests for setting breakpoints, etc. The client
 	// will end the configuration sequence with 'configurationDone'.
@@ -1455,18 +2519,42 @@ func (s *Server) onAttachRequest(request *dap.AttachRequest) {
 	s.send(&dap.AttachResponse{Response: *newResponse(request.Request)})
 }
 
+// attachWaitForArgs returns the 'waitFor' attribute of an attach request,
+// the name of a process to wait for and attach to when it starts.
+func attachWaitForArgs(args map[string]interface{}) (string, error) {
+	v, ok := args["waitFor"]
+	if !ok {
+		return "", nil
+	}
+	waitFor, ok := v.(string)
+	if !ok || waitFor == "" {
+		return "", fmt.Errorf("The 'waitFor' attribute '%v' in debug configuration is not a process name", v)
+	}
+	return waitFor, nil
+}
+
 // onNextRequest handles 'next' request.
 // This is a mandatory request to support.
 func (s *Server) onNextRequest(request *dap.NextRequest, asyncSetupDone chan struct{}) {
 	s.send(&dap.NextResponse{Response: *newResponse(request.Request)})
-	s.doStepCommand(api.Next, request.Arguments.ThreadId, asyncSetupDone)
+	s.doStepCommand(stepCommand(api.Next, request.Arguments.Granularity), request.Arguments.ThreadId, asyncSetupDone)
 }
 
 // onStepInRequest handles 'stepIn' request
 // This is a mandatory request to support.
 func (s *Server) onStepInRequest(request *dap.StepInRequest, asyncSetupDone chan struct{}) {
 	s.send(&dap.StepInResponse{Response: *newResponse(request.Request)})
-	s.doStepCommand(api.Step, request.Arguments.ThreadId, asyncSetupDone)
+	s.doStepCommand(stepCommand(api.Step, request.Arguments.Granularity), request.Arguments.ThreadId, asyncSetupDone)
+}
+
+// stepCommand returns the command used to step with the given granularity.
+// With 'instruction' granularity next and stepIn execute a single
+// instruction, stepOut always steps out of the current function.
+func stepCommand(command string, granularity dap.SteppingGranularity) string {
+	if granularity == "instruction" {
+		return api.StepInstruction
+	}
+	return command
 }
 
 // onStepOutRequest handles 'stepOut' request
@@ -1550,7 +2638,12 @@ type stackFrame struct {
 // to a successful threads request as part of the "request waterfall".
 func (s *Server) onStackTraceRequest(request *dap.StackTraceRequest) {
 	goroutineID := request.Arguments.ThreadId
-	frames, err := s.debugger.Stacktrace(goroutineID, s.args.stackTraceDepth, 0)
+	// Frames past stackTraceDepth are loaded when the client asks for them.
+	depth := s.args.stackTraceDepth
+	if request.Arguments.Levels > 0 && request.Arguments.StartFrame+request.Arguments.Levels > depth {
+		depth = request.Arguments.StartFrame + request.Arguments.Levels
+	}
+	frames, err := s.debugger.Stacktrace(goroutineID, depth, 0)
 	if err != nil {
 		s.sendErrorResponse(request.Request, UnableToProduceStackTrace, "Unable to produce stack trace", err.Error())
 		return
@@ -1566,7 +2659,7 @@ func (s *Server) onStackTraceRequest(request *dap.StackTraceRequest) {
 	for i, frame := range frames {
 		loc := &frame.Call
 		uniqueStackFrameID := s.stackFrameHandles.create(stackFrame{goroutineID, i})
-		stackFrames[i] = dap.StackFrame{Id: uniqueStackFrameID, Line: loc.Line, Name: fnName(loc)}
+		stackFrames[i] = dap.StackFrame{Id: uniqueStackFrameID, Line: loc.Line, Name: fnName(loc), InstructionPointerReference: fmt.Sprintf("%#x", loc.PC)}
 		if loc.File != "<autogenerated>" {
 			clientPath := s.toClientPath(loc.File)
 			stackFrames[i].Source = dap.Source{Name: filepath.Base(clientPath), Path: clientPath}
@@ -1577,6 +2670,9 @@ func (s *Server) onStackTraceRequest(request *dap.StackTraceRequest) {
 		if !isSystemGoroutine && packageName == "runtime" {
 			stackFrames[i].Source.PresentationHint = "deemphasize"
 		}
+		if frame.Approximate {
+			stackFrames[i].PresentationHint = "subtle"
+		}
 	}
 	// Since the backend doesn't support paging, we load all frames up to
 	// pre-configured depth every time and then slice them here per
@@ -1589,9 +2685,26 @@ func (s *Server) onStackTraceRequest(request *dap.StackTraceRequest) {
 	if request.Arguments.Levels > 0 {
 		stackFrames = stackFrames[:min(request.Arguments.Levels, len(stackFrames))]
 	}
+	totalFrames := len(frames)
+	if len(frames) > 0 && frames[len(frames)-1].Truncated {
+		// Report the estimated size of the whole stack, so that clients
+		// loading frames in pages can ask for the ones that were not loaded.
+		remaining := frames[len(frames)-1].Remaining
+		totalFrames += remaining
+		if request.Arguments.Levels <= 0 {
+			// The client asked for all frames, mark the truncation explicitly.
+			// The label is not a real frame, its id (0) is never a valid
+			// handle, so scopes requests for it are rejected.
+			stackFrames = append(stackFrames, dap.StackFrame{
+				Id:               0,
+				Name:             fmt.Sprintf("(truncated, about %d more frames)", remaining),
+				PresentationHint: "label",
+			})
+		}
+	}
 	response := &dap.StackTraceResponse{
 		Response: *newResponse(request.Request),
-		Body:     dap.StackTraceResponseBody{StackFrames: stackFrames, TotalFrames: len(frames)},
+		Body:     dap.StackTraceResponseBody{StackFrames: stackFrames, TotalFrames: totalFrames},
 	}
 	s.send(response)
 }
@@ -1703,13 +2816,29 @@ func (s *Server) onVariablesRequest(request *dap.VariablesRequest) {
 	// If there is a filter applied, we will need to create a new variable that includes
 	// the values actually needed to load. This cannot be done when loading the parent
 	// node, since it is unknown at that point which children will need to be loaded.
+	// The indexed children of long strings and of channels, the values in
+	// their buffer, are never loaded with the parent node, without a filter
+	// the first page of them is returned.
+	indexedV := v
 	if request.Arguments.Filter == "indexed" {
 		var err error
-		v, err = s.maybeLoadResliced(v, request.Arguments.Start, request.Arguments.Count)
+		indexedV, err = s.maybeLoadResliced(v, request.Arguments.Start, request.Arguments.Count)
 		if err != nil {
 			s.sendErrorResponse(request.Request, UnableToLookupVariable, "Unable to lookup variable", err.Error())
 			return
 		}
+	} else if request.Arguments.Filter == "" && (v.Kind == reflect.String || v.Kind == reflect.Chan) {
+		if count := getIndexedVariableCount(v.Variable); count > 0 {
+			if count > DefaultLoadConfig.MaxArrayValues {
+				count = DefaultLoadConfig.MaxArrayValues
+			}
+			var err error
+			indexedV, err = s.maybeLoadResliced(v, 0, count)
+			if err != nil {
+				s.sendErrorResponse(request.Request, UnableToLookupVariable, "Unable to lookup variable", err.Error())
+				return
+			}
+		}
 	}
 
 	var children []dap.Variable
@@ -1722,7 +2851,7 @@ func (s *Server) onVariablesRequest(request *dap.VariablesRequest) {
 		children = append(children, named...)
 	}
 	if request.Arguments.Filter == "indexed" || request.Arguments.Filter == "" {
-		indexed, err := s.childrenToDAPVariables(v)
+		indexed, err := s.childrenToDAPVariables(indexedV)
 		if err != nil {
 			s.sendErrorResponse(request.Request, UnableToLookupVariable, "Unable to lookup variable", err.Error())
 			return
@@ -1737,6 +2866,26 @@ func (s *Server) onVariablesRequest(request *dap.VariablesRequest) {
 }
 
 func (s *Server) maybeLoadResliced(v *fullyQualifiedVariable, start, count int) (*fullyQualifiedVariable, error) {
+	switch v.Kind {
+	case reflect.String:
+		// The indexed children of a string are chunks of stringChunkLen bytes.
+		indexedLoadConfig := DefaultLoadConfig
+		indexedLoadConfig.MaxStringLen = count * stringChunkLen
+		newV, err := s.debugger.LoadResliced(v.Variable, start*stringChunkLen, indexedLoadConfig)
+		if err != nil {
+			return nil, err
+		}
+		return &fullyQualifiedVariable{newV, v.fullyQualifiedNameOrExpr, false, start}, nil
+	case reflect.Chan:
+		indexedLoadConfig := DefaultLoadConfig
+		indexedLoadConfig.MaxArrayValues = count
+		newV, err := s.debugger.LoadResliced(v.Variable, start, indexedLoadConfig)
+		if err != nil {
+			return nil, err
+		}
+		// The values buffered in a channel can not be accessed by expressions.
+		return &fullyQualifiedVariable{newV, "", false, start}, nil
+	}
 	if start == 0 && count == len(v.Children) {
 		// If we have already loaded the correct children,
 		// just return the variable.
@@ -1756,10 +2905,27 @@ func getIndexedVariableCount(c *proc.Variable) int {
 	switch c.Kind {
 	case reflect.Array, reflect.Slice, reflect.Map:
 		indexedVars = int(c.Len)
+	case reflect.Chan:
+		// The values buffered in the channel.
+		for i := range c.Children {
+			if c.Children[i].Name == "qcount" {
+				qcount, _ := constant.Int64Val(c.Children[i].Value)
+				indexedVars = int(qcount)
+			}
+		}
+	case reflect.String:
+		if isTruncatedString(c) {
+			indexedVars = int((c.Len + stringChunkLen - 1) / stringChunkLen)
+		}
 	}
 	return indexedVars
 }
 
+// isTruncatedString returns true if v is a string that was not loaded in full.
+func isTruncatedString(v *proc.Variable) bool {
+	return v.Kind == reflect.String && v.Value != nil && int64(len(constant.StringVal(v.Value))) < v.Len
+}
+
 // childrenToDAPVariables returns the DAP presentation of the referenced variable's children.
 func (s *Server) childrenToDAPVariables(v *fullyQualifiedVariable) ([]dap.Variable, error) {
 	// TODO(polina): consider convertVariableToString instead of convertVariable
@@ -1841,64 +3007,100 @@ func (s *Server) childrenToDAPVariables(v *fullyQualifiedVariable) ([]dap.Variab
 				children = append(children, kvvar)
 			}
 		}
+	case reflect.String:
+		// The chunks of a string that is too long to be loaded in full,
+		// v was loaded by maybeLoadResliced.
+		str := constant.StringVal(v.Value)
+		for i := 0; i < len(str); i += stringChunkLen {
+			end := i + stringChunkLen
+			if end > len(str) {
+				end = len(str)
+			}
+			low, high := v.startIndex*stringChunkLen+i, v.startIndex*stringChunkLen+end
+			cfqname := ""
+			if v.fullyQualifiedNameOrExpr != "" {
+				cfqname = fmt.Sprintf("%s[%d:%d]", v.fullyQualifiedNameOrExpr, low, high)
+			}
+			children = append(children, dap.Variable{
+				Name:         fmt.Sprintf("[%d:%d]", low, high),
+				EvaluateName: cfqname,
+				Type:         "string",
+				Value:        strconv.Quote(str[i:end]),
+			})
+		}
+	case reflect.Chan:
+		// The values buffered in the channel are only loaded on demand by
+		// maybeLoadResliced, its fields are named children.
 	case reflect.Slice, reflect.Array:
 		children = make([]dap.Variable, len(v.Children))
 		for i := range v.Children {
 			idx := v.startIndex + i
 			cfqname := fmt.Sprintf("%s[%d]", v.fullyQualifiedNameOrExpr, idx)
+			if v.fullyQualifiedNameOrExpr == "" {
+				cfqname = ""
+			}
 			cvalue, cvarref := s.convertVariable(&v.Children[i], cfqname)
 			children[i] = dap.Variable{
 				Name:               fmt.Sprintf("[%d]", idx),
 				EvaluateName:       cfqname,
 				Type:               s.getTypeIfSupported(&v.Children[i]),
 				Value:              cvalue,
+				MemoryReference:    s.getMemoryReferenceIfSupported(&v.Children[i]),
 				VariablesReference: cvarref,
 				IndexedVariables:   getIndexedVariableCount(&v.Children[i]),
 				NamedVariables:     getNamedVariableCount(&v.Children[i]),
 			}
 		}
 	default:
-		children = make([]dap.Variable, len(v.Children))
-		for i := range v.Children {
-			c := &v.Children[i]
-			cfqname := fmt.Sprintf("%s.%s", v.fullyQualifiedNameOrExpr, c.Name)
+		children = s.fieldsToDAPVariables(v)
+	}
+	return children, nil
+}
 
-			if strings.HasPrefix(c.Name, "~") || strings.HasPrefix(c.Name, ".") {
-				cfqname = ""
-			} else if v.isScope && v.fullyQualifiedNameOrExpr == "" {
-				cfqname = c.Name
-			} else if v.fullyQualifiedNameOrExpr == "" {
-				cfqname = ""
-			} else if v.Kind == reflect.Interface {
-				cfqname = fmt.Sprintf("%s.(%s)", v.fullyQualifiedNameOrExpr, c.Name) // c is data
-			} else if v.Kind == reflect.Ptr {
-				cfqname = fmt.Sprintf("(*%v)", v.fullyQualifiedNameOrExpr) // c is the nameless pointer value
-			} else if v.Kind == reflect.Complex64 || v.Kind == reflect.Complex128 {
-				cfqname = "" // complex children are not struct fields and can't be accessed directly
-			}
-			cvalue, cvarref := s.convertVariable(c, cfqname)
-
-			// Annotate any shadowed variables to "(name)" in order
-			// to distinguish from non-shadowed variables.
-			// TODO(suzmue): should we support a special evaluateName syntax that
-			// can access shadowed variables?
-			name := c.Name
-			if c.Flags&proc.VariableShadowed == proc.VariableShadowed {
-				name = fmt.Sprintf("(%s)", name)
-			}
+// fieldsToDAPVariables returns the DAP presentation of the children of
+// v that are accessed by name, like the fields of a struct.
+func (s *Server) fieldsToDAPVariables(v *fullyQualifiedVariable) []dap.Variable {
+	children := make([]dap.Variable, len(v.Children))
+	for i := range v.Children {
+		c := &v.Children[i]
+		cfqname := fmt.Sprintf("%s.%s", v.fullyQualifiedNameOrExpr, c.Name)
+
+		if strings.HasPrefix(c.Name, "~") || strings.HasPrefix(c.Name, ".") {
+			cfqname = ""
+		} else if v.isScope && v.fullyQualifiedNameOrExpr == "" {
+			cfqname = c.Name
+		} else if v.fullyQualifiedNameOrExpr == "" {
+			cfqname = ""
+		} else if v.Kind == reflect.Interface {
+			cfqname = fmt.Sprintf("%s.(%s)", v.fullyQualifiedNameOrExpr, c.Name) // c is data
+		} else if v.Kind == reflect.Ptr {
+			cfqname = fmt.Sprintf("(*%v)", v.fullyQualifiedNameOrExpr) // c is the nameless pointer value
+		} else if v.Kind == reflect.Complex64 || v.Kind == reflect.Complex128 {
+			cfqname = "" // complex children are not struct fields and can't be accessed directly
+		}
+		cvalue, cvarref := s.convertVariable(c, cfqname)
+
+		// Annotate any shadowed variables to "(name)" in order
+		// to distinguish from non-shadowed variables.
+		// TODO(suzmue): should we support a special evaluateName syntax that
+		// can access shadowed variables?
+		name := c.Name
+		if c.Flags&proc.VariableShadowed == proc.VariableShadowed {
+			name = fmt.Sprintf("(%s)", name)
+		}
 
-			children[i] = dap.Variable{
-				Name:               name,
-				EvaluateName:       cfqname,
-				Type:               s.getTypeIfSupported(c),
-				Value:              cvalue,
-				VariablesReference: cvarref,
-				IndexedVariables:   getIndexedVariableCount(c),
-				NamedVariables:     getNamedVariableCount(c),
-			}
+		children[i] = dap.Variable{
+			Name:               name,
+			EvaluateName:       cfqname,
+			Type:               s.getTypeIfSupported(c),
+			Value:              cvalue,
+			MemoryReference:    s.getMemoryReferenceIfSupported(c),
+			VariablesReference: cvarref,
+			IndexedVariables:   getIndexedVariableCount(c),
+			NamedVariables:     getNamedVariableCount(c),
 		}
 	}
-	return children, nil
+	return children
 }
 
 func getNamedVariableCount(v *proc.Variable) int {
@@ -1907,6 +3109,10 @@ func getNamedVariableCount(v *proc.Variable) int {
 		// string value of array/slice of bytes and runes.
 		namedVars += 1
 	}
+	if v.Kind == reflect.Chan {
+		// The fields of the channel.
+		namedVars += len(v.Children)
+	}
 	return namedVars
 }
 
@@ -1936,6 +3142,9 @@ func (s *Server) metadataToDAPVariables(v *fullyQualifiedVariable) ([]dap.Variab
 			})
 		}
 	}
+	if v.Kind == reflect.Chan {
+		children = append(children, s.fieldsToDAPVariables(v)...)
+	}
 	return children, nil
 }
 
@@ -1954,6 +3163,26 @@ func (s *Server) getTypeIfSupported(v *proc.Variable) string {
 	return v.TypeString()
 }
 
+// getMemoryReferenceIfSupported returns the address of the memory that
+// pointer and slice variables point to, which clients can inspect with
+// 'readMemory' and 'writeMemory' requests.
+func (s *Server) getMemoryReferenceIfSupported(v *proc.Variable) string {
+	if !s.clientCapabilities.supportsMemoryReferences || v.Unreadable != nil {
+		return ""
+	}
+	switch v.Kind {
+	case reflect.Ptr:
+		if len(v.Children) == 1 && v.Children[0].Addr != 0 {
+			return fmt.Sprintf("%#x", v.Children[0].Addr)
+		}
+	case reflect.Slice:
+		if v.Base != 0 {
+			return fmt.Sprintf("%#x", v.Base)
+		}
+	}
+	return ""
+}
+
 // convertVariable converts proc.Variable to dap.Variable value and reference
 // while keeping track of the full qualified name or load expression.
 // Variable reference is used to keep track of the children associated with each
@@ -2089,7 +3318,11 @@ func (s *Server) convertVariableWithOpts(v *proc.Variable, qualifiedNameOrExpr s
 			variablesReference = maybeCreateVariableHandle(v)
 		}
 	case reflect.String:
-		// TODO(polina): implement auto-loading here.
+		// A string that was not loaded in full is presented in chunks, as
+		// indexed children. Its value can still be truncated.
+		if isTruncatedString(v) && opts&skipRef == 0 {
+			variablesReference = s.variableHandles.create(&fullyQualifiedVariable{v, qualifiedNameOrExpr, false /*not a scope*/, 0})
+		}
 	case reflect.Interface:
 		if v.Addr != 0 && len(v.Children) > 0 && v.Children[0].Kind != reflect.Invalid && v.Children[0].Addr != 0 {
 			if v.Children[0].OnlyAddr { // Not loaded
@@ -2187,15 +3420,21 @@ func (s *Server) onEvaluateRequest(request *dap.EvaluateRequest) {
 			}
 		}
 	} else { // {expression}
-		exprVar, err := s.debugger.EvalVariableInScope(goid, frame, 0, request.Arguments.Expression, DefaultLoadConfig)
+		ctxt := request.Arguments.Context
+		loadCfg := DefaultLoadConfig
+		if ctxt == "clipboard" {
+			// The value is copied by the user, load it in full.
+			loadCfg.MaxStringLen = s.args.maxClipboardValueLen
+			loadCfg.MaxArrayValues = s.args.maxClipboardValueLen
+		}
+		exprVar, err := s.debugger.EvalVariableInScope(goid, frame, 0, request.Arguments.Expression, loadCfg)
 		if err != nil {
 			s.sendErrorResponseWithOpts(request.Request, UnableToEvaluateExpression, "Unable to evaluate expression", err.Error(), showErrorToUser)
 			return
 		}
 
-		ctxt := request.Arguments.Context
 		switch ctxt {
-		case "repl", "variables", "hover", "clipboard":
+		case "repl", "variables", "hover":
 			if exprVar.Kind == reflect.String {
 				if strVal := constant.StringVal(exprVar.Value); exprVar.Len > int64(len(strVal)) {
 					// Reload the string value with a bigger limit.
@@ -2216,7 +3455,10 @@ func (s *Server) onEvaluateRequest(request *dap.EvaluateRequest) {
 			opts |= showFullValue
 		}
 		exprVal, exprRef := s.convertVariableWithOpts(exprVar, fmt.Sprintf("(%s)", request.Arguments.Expression), opts)
-		response.Body = dap.EvaluateResponseBody{Result: exprVal, VariablesReference: exprRef, IndexedVariables: getIndexedVariableCount(exprVar), NamedVariables: getNamedVariableCount(exprVar)}
+		if ctxt == "clipboard" && len(exprVal) > s.args.maxClipboardValueLen {
+			exprVal = exprVal[:s.args.maxClipboardValueLen] + "..."
+		}
+		response.Body = dap.EvaluateResponseBody{Result: exprVal, VariablesReference: exprRef, IndexedVariables: getIndexedVariableCount(exprVar), NamedVariables: getNamedVariableCount(exprVar), MemoryReference: s.getMemoryReferenceIfSupported(exprVar)}
 	}
 	s.send(response)
 }
@@ -2252,6 +3494,7 @@ func (s *Server) doCall(goid, frame int, expr string) (*api.DebuggerState, []*pr
 		Expr:                 expr,
 		UnsafeCall:           false,
 		GoroutineID:          goid,
+		Client:               s.clientAddr(),
 	}, nil)
 	if _, isexited := err.(proc.ErrProcessExited); isexited || err == nil && state.Exited {
 		e := &dap.TerminatedEvent{Event: *newEvent("terminated")}
@@ -2331,22 +3574,108 @@ func (s *Server) onTerminateRequest(request *dap.TerminateRequest) {
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
-// onRestartRequest sends a not-yet-implemented error response
-// Capability 'supportsRestartRequest' is not set in 'initialize' response.
+// onRestartRequest handles 'restart' request.
+// The target is killed and launched again, rebuilding the binary in debug
+// and test modes, with the configuration of the original launch request.
+// Breakpoints are recreated by the debugger, the ones that could not be
+// restored are reported to the client as unverified. The debug session
+// is not terminated, so the client does not repeat the configuration
+// sequence: execution resumes right away unless stopOnEntry is set.
+// Capability 'supportsRestartRequest' is set in 'initialize' response.
 func (s *Server) onRestartRequest(request *dap.RestartRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	if s.debugger == nil {
+		s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", "restart is not supported in noDebug mode")
+		return
+	}
+	if s.debugger.IsRunning() {
+		s.log.Debug("halting execution to restart")
+		s.mu.Lock()
+		s.haltedForRestart = true
+		s.mu.Unlock()
+		if _, err := s.debugger.Command(&api.DebuggerCommand{Name: api.Halt}, nil); err != nil {
+			s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", err.Error())
+			return
+		}
+	}
+	s.logToConsole("Restarting the target process")
+	discarded, err := s.debugger.Restart(false, "", false, nil, [3]string{}, s.config.Debugger.ExecuteKind != debugger.ExecutingExistingFile)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToRestart, "Unable to restart", err.Error())
+		if _, err := s.debugger.State( /*nowait*/ true); err != nil {
+			// The old process was killed but a new one could not be started.
+			s.send(&dap.TerminatedEvent{Event: *newEvent("terminated")})
+		}
+		return
+	}
+	s.resetHandlesForStoppedEvent()
+	s.exceptionErr = nil
+	s.send(&dap.RestartResponse{Response: *newResponse(request.Request)})
+	s.resetModules()
+	for _, d := range discarded {
+		s.logToConsole(fmt.Sprintf("Breakpoint %d could not be restored: %s", d.Breakpoint.ID, d.Reason))
+		s.send(&dap.BreakpointEvent{
+			Event: *newEvent("breakpoint"),
+			Body: dap.BreakpointEventBody{
+				Reason:     "changed",
+				Breakpoint: dap.Breakpoint{Id: d.Breakpoint.ID, Verified: false, Message: d.Reason},
+			},
+		})
+	}
+	if s.args.stopOnEntry {
+		s.send(&dap.StoppedEvent{
+			Event: *newEvent("stopped"),
+			Body:  dap.StoppedEventBody{Reason: "entry", ThreadId: 1, AllThreadsStopped: true},
+		})
+		return
+	}
+	resumeRequestLoop := make(chan struct{})
+	go func() {
+		defer s.recoverPanic(request)
+		s.doRunCommand(api.Continue, resumeRequestLoop)
+	}()
+	<-resumeRequestLoop
 }
 
-// onStepBackRequest sends a not-yet-implemented error response.
-// Capability 'supportsStepBack' is not set 'initialize' response.
-func (s *Server) onStepBackRequest(request *dap.StepBackRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// onStepBackRequest handles 'stepBack' requests.
+// This is an optional request enabled by capability 'supportsStepBack',
+// which is only advertised when the target is a recording.
+func (s *Server) onStepBackRequest(request *dap.StepBackRequest, asyncSetupDone chan struct{}) {
+	if !s.isRecording() {
+		defer s.asyncCommandDone(asyncSetupDone)
+		s.sendErrorResponse(request.Request, UnableToStepBack, "Unable to step back", "the target is not a recording")
+		return
+	}
+	s.send(&dap.StepBackResponse{Response: *newResponse(request.Request)})
+	command := api.ReverseNext
+	if request.Arguments.Granularity == "instruction" {
+		command = api.ReverseStepInstruction
+	}
+	s.doStepCommand(command, request.Arguments.ThreadId, asyncSetupDone)
 }
 
-// onReverseContinueRequest sends a not-yet-implemented error response.
-// Capability 'supportsStepBack' is not set 'initialize' response.
-func (s *Server) onReverseContinueRequest(request *dap.ReverseContinueRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+// isRecording returns true if the target is a recording that can be
+// executed backwards.
+func (s *Server) isRecording() bool {
+	if s.debugger == nil {
+		return false
+	}
+	recorded, _ := s.debugger.Recorded()
+	return recorded
+}
+
+// onReverseContinueRequest handles 'reverseContinue' requests, execution
+// is resumed backwards up to the previous breakpoint or the start of the
+// recording.
+// This is an optional request enabled by capability 'supportsStepBack',
+// which is only advertised when the target is a recording.
+func (s *Server) onReverseContinueRequest(request *dap.ReverseContinueRequest, asyncSetupDone chan struct{}) {
+	if !s.isRecording() {
+		defer s.asyncCommandDone(asyncSetupDone)
+		s.sendErrorResponse(request.Request, UnableToReverseContinue, "Unable to reverse continue", "the target is not a recording")
+		return
+	}
+	s.send(&dap.ReverseContinueResponse{Response: *newResponse(request.Request)})
+	s.doRunCommand(api.Rewind, asyncSetupDone)
 }
 
 // computeEvaluateName finds the named child, and computes its evaluate name.
@@ -2355,6 +3684,10 @@ func (s *Server) computeEvaluateName(v *fullyQualifiedVariable, cname string) (s
 	if err != nil {
 		return "", err
 	}
+	if v.Kind == reflect.Chan {
+		// The fields of a channel are named children.
+		children = s.fieldsToDAPVariables(v)
+	}
 	for _, c := range children {
 		if c.Name == cname {
 			if c.EvaluateName != "" {
@@ -2432,7 +3765,7 @@ func (s *Server) onSetVariableRequest(request *dap.SetVariableRequest) {
 			return
 		}
 	} else {
-		if err := s.debugger.SetVariableInScope(goid, frame, 0, evaluateName, arg.Value); err != nil {
+		if err := s.debugger.SetVariableInScope(goid, frame, 0, evaluateName, arg.Value, s.clientAddr()); err != nil {
 			s.sendErrorResponse(request.Request, UnableToSetVariable, "Unable to set variable", err.Error())
 			return
 		}
@@ -2473,16 +3806,266 @@ func (s *Server) onLoadedSourcesRequest(request *dap.LoadedSourcesRequest) {
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
-// onReadMemoryRequest sends a not-yet-implemented error response.
-// Capability 'supportsReadMemoryRequest' is not set 'initialize' response.
+// onModulesRequest handles 'modules' requests, listing the executable file
+// and the dynamic libraries loaded by the target.
+// This is a mandatory request to support if 'supportsModulesRequest' is set.
+func (s *Server) onModulesRequest(request *dap.ModulesRequest) {
+	images := s.debugger.ListImages()
+	start, count := request.Arguments.StartModule, request.Arguments.ModuleCount
+	if start < 0 || start > len(images) {
+		start = len(images)
+	}
+	end := len(images)
+	if count > 0 && start+count < end {
+		end = start + count
+	}
+	modules := make([]dap.Module, 0, end-start)
+	for i := start; i < end; i++ {
+		modules = append(modules, convertModule(i, images[i]))
+	}
+	s.send(&dap.ModulesResponse{
+		Response: *newResponse(request.Request),
+		Body:     dap.ModulesResponseBody{Modules: modules, TotalModules: len(images)},
+	})
+}
+
+// convertModule converts the image with the given index in the list of
+// images of the target to a DAP module, the index is used as module id.
+func convertModule(id int, image *proc.Image) dap.Module {
+	m := dap.Module{
+		Id:           id,
+		Name:         filepath.Base(image.Path),
+		Path:         image.Path,
+		SymbolStatus: "Symbols not found.",
+	}
+	if image.HasDebugInfo() {
+		m.SymbolStatus = "Symbols loaded."
+	}
+	if lowpc, highpc := image.AddrRange(); highpc != 0 {
+		m.AddressRange = fmt.Sprintf("%#x-%#x", lowpc, highpc)
+	}
+	return m
+}
+
+// sendModuleEvents sends a 'new' module event for each image, for example
+// a shared library or a plugin, loaded since the last time the target
+// stopped.
+func (s *Server) sendModuleEvents() {
+	images := s.debugger.ListImages()
+	for i := s.modulesReported; i < len(images); i++ {
+		s.send(&dap.ModuleEvent{
+			Event: *newEvent("module"),
+			Body:  dap.ModuleEventBody{Reason: "new", Module: convertModule(i, images[i])},
+		})
+	}
+	if len(images) > s.modulesReported {
+		s.modulesReported = len(images)
+	}
+}
+
+// resetModules updates the modules known by the client after the target
+// has been restarted: the new process has not loaded the libraries loaded
+// by the old one yet, the modules they were assigned to are removed.
+func (s *Server) resetModules() {
+	images := s.debugger.ListImages()
+	for i := len(images); i < s.modulesReported; i++ {
+		s.send(&dap.ModuleEvent{
+			Event: *newEvent("module"),
+			Body:  dap.ModuleEventBody{Reason: "removed", Module: dap.Module{Id: i}},
+		})
+	}
+	s.modulesReported = len(images)
+}
+
+// memoryPageSize is the granularity at which onReadMemoryRequest reads
+// memory, so that the readable bytes before an unreadable page are returned.
+const memoryPageSize = 0x1000
+
+// parseMemoryReference returns the address at offset bytes from the
+// memoryReference of a variable (see getMemoryReferenceIfSupported) or of
+// an instruction.
+func parseMemoryReference(memoryReference string, offset int) (uint64, error) {
+	addr, err := strconv.ParseUint(memoryReference, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid memory reference %q", memoryReference)
+	}
+	return uint64(int64(addr) + int64(offset)), nil
+}
+
+// onReadMemoryRequest handles 'readMemory' requests.
+// Memory is read one page at a time, the bytes after the first page that
+// can not be read are reported as unreadable.
 func (s *Server) onReadMemoryRequest(request *dap.ReadMemoryRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	args := request.Arguments
+	addr, err := parseMemoryReference(args.MemoryReference, args.Offset)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToReadMemory, "Unable to read memory", err.Error())
+		return
+	}
+	if args.Count < 0 {
+		s.sendErrorResponse(request.Request, UnableToReadMemory, "Unable to read memory", fmt.Sprintf("invalid count %d", args.Count))
+		return
+	}
+
+	var data []byte
+	for len(data) < args.Count {
+		start := addr + uint64(len(data))
+		n := memoryPageSize - int(start%memoryPageSize)
+		if rem := args.Count - len(data); n > rem {
+			n = rem
+		}
+		page, err := s.debugger.ExamineMemory(start, n)
+		if err != nil {
+			s.log.Debugf("readMemory: %v", err)
+			break
+		}
+		data = append(data, page...)
+	}
+
+	response := &dap.ReadMemoryResponse{Response: *newResponse(request.Request)}
+	response.Body = dap.ReadMemoryResponseBody{
+		Address:         fmt.Sprintf("%#x", addr),
+		UnreadableBytes: args.Count - len(data),
+		Data:            base64.StdEncoding.EncodeToString(data),
+	}
+	s.send(response)
+}
+
+// onWriteMemoryRequest handles 'writeMemory' requests.
+// Since the values of variables can change, clients that support the
+// 'invalidated' event are asked to refresh them.
+// clientAddr returns the address of the client, recorded with the writes
+// made to the memory of the target on its behalf.
+func (s *Server) clientAddr() string {
+	if s.conn == nil {
+		return ""
+	}
+	return s.conn.RemoteAddr().String()
+}
+
+func (s *Server) onWriteMemoryRequest(request *writeMemoryRequest) {
+	args := request.Arguments
+	addr, err := parseMemoryReference(args.MemoryReference, args.Offset)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", err.Error())
+		return
+	}
+	data, err := base64.StdEncoding.DecodeString(args.Data)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", fmt.Sprintf("invalid data: %v", err))
+		return
+	}
+
+	n, err := s.debugger.WriteMemory(addr, data, s.clientAddr())
+	if err != nil && (!args.AllowPartial || n == 0) {
+		s.sendErrorResponse(request.Request, UnableToWriteMemory, "Unable to write memory", err.Error())
+		return
+	}
+
+	response := &writeMemoryResponse{Response: *newResponse(request.Request)}
+	if args.AllowPartial {
+		response.Body.Offset = args.Offset
+		response.Body.BytesWritten = n
+	}
+	s.send(response)
+
+	if n > 0 && s.clientCapabilities.supportsInvalidatedEvent {
+		s.send(&dap.InvalidatedEvent{
+			Event: *newEvent("invalidated"),
+			Body:  dap.InvalidatedEventBody{Areas: []dap.InvalidatedAreas{"variables"}},
+		})
+	}
+}
+
+// onDiagnosticsRequest handles 'dlvDiagnostics' requests.
+func (s *Server) onDiagnosticsRequest(request *diagnosticsRequest) {
+	r, err := s.debugger.Diagnostics()
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToProduceDiagnostics, "Unable to produce diagnostics", err.Error())
+		return
+	}
+	s.send(&diagnosticsResponse{Response: *newResponse(request.Request), Body: r})
 }
 
-// onDisassembleRequest sends a not-yet-implemented error response.
-// Capability 'supportsDisassembleRequest' is not set 'initialize' response.
+// invalidInstruction is returned by onDisassembleRequest for addresses
+// that do not belong to any function.
+var invalidInstruction = dap.DisassembledInstruction{Instruction: "invalid instruction"}
+
+// onDisassembleRequest handles 'disassemble' requests.
+// The client requests instructionCount instructions starting instructionOffset
+// instructions away from the instruction at memoryReference+offset, which
+// can be before or after it. Instructions are disassembled one function at
+// a time, instructions outside of any function are reported as invalid
+// so that the response always contains instructionCount instructions.
 func (s *Server) onDisassembleRequest(request *dap.DisassembleRequest) {
-	s.sendNotYetImplementedErrorResponse(request.Request)
+	args := request.Arguments
+	addr, err := parseMemoryReference(args.MemoryReference, args.Offset)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToDisassemble, "Unable to disassemble", err.Error())
+		return
+	}
+
+	// Disassemble the function containing addr, then the functions before
+	// and after it until there are enough instructions.
+	insts, err := s.debugger.Disassemble(-1, addr, 0)
+	if err != nil {
+		insts = nil
+	}
+	ref := 0 // index of the instruction containing addr
+	for ref < len(insts) && insts[ref].Loc.PC+uint64(insts[ref].Size) <= addr {
+		ref++
+	}
+	for len(insts) > 0 && ref+args.InstructionOffset < 0 {
+		prev, err := s.debugger.Disassemble(-1, insts[0].Loc.PC-1, 0)
+		if err != nil || len(prev) == 0 {
+			break
+		}
+		insts = append(prev, insts...)
+		ref += len(prev)
+	}
+	for len(insts) > 0 && ref+args.InstructionOffset+args.InstructionCount > len(insts) {
+		last := insts[len(insts)-1]
+		next, err := s.debugger.Disassemble(-1, last.Loc.PC+uint64(last.Size), 0)
+		if err != nil || len(next) == 0 {
+			break
+		}
+		insts = append(insts, next...)
+	}
+
+	if args.InstructionCount < 0 {
+		args.InstructionCount = 0
+	}
+	instructions := make([]dap.DisassembledInstruction, args.InstructionCount)
+	for i := range instructions {
+		j := ref + args.InstructionOffset + i
+		if j < 0 || j >= len(insts) {
+			instructions[i] = invalidInstruction
+			instructions[i].Address = fmt.Sprintf("%#x", uint64(int64(addr)+int64(j-ref)))
+			continue
+		}
+		inst := &insts[j]
+		instructions[i] = dap.DisassembledInstruction{
+			Address:          fmt.Sprintf("%#x", inst.Loc.PC),
+			InstructionBytes: fmt.Sprintf("%x", inst.Bytes),
+			Instruction:      s.debugger.AsmInstructionText(inst, proc.GoFlavour),
+			Line:             inst.Loc.Line,
+		}
+		if inst.Loc.File != "" {
+			clientPath := s.toClientPath(inst.Loc.File)
+			instructions[i].Location = dap.Source{Name: filepath.Base(clientPath), Path: clientPath}
+		}
+		if inst.Loc.Fn != nil && inst.Loc.PC == inst.Loc.Fn.Entry {
+			instructions[i].Symbol = inst.Loc.Fn.Name
+		}
+		if a, ok := s.debugger.AddressAnnotation(inst.Loc.PC); ok && a.Addr == inst.Loc.PC {
+			// Annotations of the user are displayed like symbols.
+			instructions[i].Symbol = formatAnnotation(a)
+		}
+	}
+
+	response := &dap.DisassembleResponse{Response: *newResponse(request.Request)}
+	response.Body.Instructions = instructions
+	s.send(response)
 }
 
 // onCancelRequest sends a not-yet-implemented error response.
@@ -2491,6 +4074,29 @@ func (s *Server) onCancelRequest(request *dap.CancelRequest) {
 	s.sendNotYetImplementedErrorResponse(request.Request)
 }
 
+// onSourceRequest handles 'source' requests.
+// The content of source files that are not available on the client is
+// read from the debugger's file system, after converting the client path
+// with the substitutePath rules. Sources without a path (identified by
+// a sourceReference) do not make sense in the context of Go as the
+// source cannot be a string eval'ed at runtime.
+func (s *Server) onSourceRequest(request *dap.SourceRequest) {
+	clientPath := request.Arguments.Source.Path
+	if clientPath == "" {
+		s.sendErrorResponse(request.Request, UnableToGetSource, "Unable to get source", "source requests without a path are not supported")
+		return
+	}
+	serverPath := s.toServerPath(clientPath)
+	content, err := ioutil.ReadFile(serverPath)
+	if err != nil {
+		s.sendErrorResponse(request.Request, UnableToGetSource, "Unable to get source", err.Error())
+		return
+	}
+	response := &dap.SourceResponse{Response: *newResponse(request.Request)}
+	response.Body.Content = string(content)
+	s.send(response)
+}
+
 // onExceptionInfoRequest handles 'exceptionInfo' requests.
 // Capability 'supportsExceptionInfoRequest' is set in 'initialize' response.
 func (s *Server) onExceptionInfoRequest(request *dap.ExceptionInfoRequest) {
@@ -2511,7 +4117,7 @@ func (s *Server) onExceptionInfoRequest(request *dap.ExceptionInfoRequest) {
 		bpState = g.Thread.Breakpoint()
 	}
 	// Check if this goroutine ID is stopped at a breakpoint.
-	if bpState != nil && bpState.Breakpoint != nil && (bpState.Breakpoint.Name == proc.FatalThrow || bpState.Breakpoint.Name == proc.UnrecoveredPanic) {
+	if bpState != nil && bpState.Breakpoint != nil && (bpState.Breakpoint.Name == proc.FatalThrow || bpState.Breakpoint.Name == proc.UnrecoveredPanic || bpState.Breakpoint.Name == panicBpName || bpState.Breakpoint.Name == proc.TestFailure) {
 		switch bpState.Breakpoint.Name {
 		case proc.FatalThrow:
 			body.ExceptionId = "fatal error"
@@ -2531,6 +4137,20 @@ func (s *Server) onExceptionInfoRequest(request *dap.ExceptionInfoRequest) {
 			if err != nil {
 				body.Description = fmt.Sprintf("Error getting panic message: %s", err.Error())
 			}
+		case panicBpName:
+			body.ExceptionId = "panic"
+			body.Description, err = s.gopanicReason(goroutineID)
+			if err != nil {
+				body.Description = fmt.Sprintf("Error getting panic message: %s", err.Error())
+			}
+		case proc.TestFailure:
+			body.ExceptionId = "test failure"
+			tf, err := proc.GetTestFailure(s.debugger.Target(), g.Thread)
+			if err != nil {
+				body.Description = fmt.Sprintf("Error getting test failure: %s", err.Error())
+			} else {
+				body.Description = testFailureReason(&api.TestFailure{Test: tf.Test, Message: tf.Message})
+			}
 		}
 	} else {
 		// If this thread is not stopped on a breakpoint, then a runtime error must have occurred.
@@ -2593,6 +4213,20 @@ func (s *Server) panicReason(goroutineID int) (string, error) {
 	return s.getExprString("(*msgs).arg.(data)", goroutineID, 0)
 }
 
+// gopanicReason returns the value passed to panic by a goroutine stopped
+// at the entry of runtime.gopanic.
+func (s *Server) gopanicReason(goroutineID int) (string, error) {
+	return s.getExprString("e.(data)", goroutineID, 0)
+}
+
+// testFailureReason describes the failure of a test.
+func testFailureReason(tf *api.TestFailure) string {
+	if tf.Message == "" {
+		return fmt.Sprintf("%s failed", tf.Test)
+	}
+	return fmt.Sprintf("%s failed: %s", tf.Test, tf.Message)
+}
+
 func (s *Server) getExprString(expr string, goroutineID, frame int) (string, error) {
 	exprVar, err := s.debugger.EvalVariableInScope(goroutineID, frame, 0, expr, DefaultLoadConfig)
 	if err != nil {
@@ -2693,6 +4327,19 @@ func (s *Server) doRunCommand(command string, asyncSetupDone chan struct{}) {
 	// So we should always close it ourselves just in case.
 	defer s.asyncCommandDone(asyncSetupDone)
 	state, err := s.debugger.Command(&api.DebuggerCommand{Name: command}, asyncSetupDone)
+	for err == nil && !state.Exited && s.isFilteredException(state) {
+		s.log.Debugf("resuming execution after exception %q excluded by the exception filters", state.CurrentThread.Breakpoint.Name)
+		state, err = s.debugger.Command(&api.DebuggerCommand{Name: api.Continue}, nil)
+	}
+	s.mu.Lock()
+	haltedForRestart := s.haltedForRestart
+	s.haltedForRestart = false
+	s.mu.Unlock()
+	if haltedForRestart {
+		// The target is being restarted, the client is not
+		// interested in this stop.
+		return
+	}
 	if _, isexited := err.(proc.ErrProcessExited); isexited || err == nil && state.Exited {
 		s.send(&dap.TerminatedEvent{Event: *newEvent("terminated")})
 		return
@@ -2727,6 +4374,10 @@ func (s *Server) doRunCommand(command string, asyncSetupDone chan struct{}) {
 		default:
 			stopped.Body.Reason = "breakpoint"
 		}
+		if command == api.StepInstruction {
+			// The stop reason is not updated when stepping a single instruction.
+			stopped.Body.Reason = "step"
+		}
 		if state.CurrentThread != nil && state.CurrentThread.Breakpoint != nil {
 			switch state.CurrentThread.Breakpoint.Name {
 			case proc.FatalThrow:
@@ -2737,12 +4388,28 @@ func (s *Server) doRunCommand(command string, asyncSetupDone chan struct{}) {
 				stopped.Body.Reason = "exception"
 				stopped.Body.Description = "panic"
 				stopped.Body.Text, _ = s.panicReason(stopped.Body.ThreadId)
+			case panicBpName:
+				stopped.Body.Reason = "exception"
+				stopped.Body.Description = "panic"
+				stopped.Body.Text, _ = s.gopanicReason(stopped.Body.ThreadId)
+			case proc.TestFailure:
+				stopped.Body.Reason = "exception"
+				stopped.Body.Description = "test failure"
+				if bpi := state.CurrentThread.BreakpointInfo; bpi != nil && bpi.TestFailure != nil {
+					stopped.Body.Text = testFailureReason(bpi.TestFailure)
+				}
 			}
 			if strings.HasPrefix(state.CurrentThread.Breakpoint.Name, functionBpPrefix) {
 				stopped.Body.Reason = "function breakpoint"
 			}
-			stopped.Body.HitBreakpointIds = []int{state.CurrentThread.Breakpoint.ID}
+			if strings.HasPrefix(state.CurrentThread.Breakpoint.Name, dataBpPrefix) {
+				stopped.Body.Reason = "data breakpoint"
+			}
+			if strings.HasPrefix(state.CurrentThread.Breakpoint.Name, instructionBpPrefix) {
+				stopped.Body.Reason = "instruction breakpoint"
+			}
 		}
+		stopped.Body.HitBreakpointIds, stopped.Body.Text = simultaneousHits(state, stopped.Body.Text)
 	} else {
 		s.exceptionErr = err
 		s.log.Error("runtime error: ", err)
@@ -2759,17 +4426,24 @@ func (s *Server) doRunCommand(command string, asyncSetupDone chan struct{}) {
 		}
 	}
 
+	s.sendModuleEvents()
+
 	// NOTE: If we happen to be responding to another request with an is-running
 	// error while this one completes, it is possible that the error response
 	// will arrive after this stopped event.
 	s.send(stopped)
+	if err == nil {
+		if hits := s.breakpointHits(state); len(hits) > 1 {
+			s.send(&breakpointHitsEvent{Event: *newEvent("breakpointHits"), Body: breakpointHitsEventBody{Hits: hits}})
+		}
+	}
 }
 
 func (s *Server) toClientPath(path string) string {
 	if len(s.args.substitutePathServerToClient) == 0 {
 		return path
 	}
-	clientPath := locspec.SubstitutePath(path, s.args.substitutePathServerToClient)
+	clientPath := substitutePath(path, s.args.substitutePathServerToClient)
 	if clientPath != path {
 		s.log.Debugf("server path=%s converted to client path=%s\n", path, clientPath)
 	}
@@ -2780,7 +4454,7 @@ func (s *Server) toServerPath(path string) string {
 	if len(s.args.substitutePathClientToServer) == 0 {
 		return path
 	}
-	serverPath := locspec.SubstitutePath(path, s.args.substitutePathClientToServer)
+	serverPath := substitutePath(path, s.args.substitutePathClientToServer)
 	if serverPath != path {
 		s.log.Debugf("client path=%s converted to server path=%s\n", path, serverPath)
 	}
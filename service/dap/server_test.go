@@ -2,6 +2,7 @@ package dap
 
 import (
 	"bufio"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"runtime"
 	"strings"
@@ -21,8 +23,8 @@ import (
 	"github.com/go-delve/delve/pkg/logflags"
 	protest "github.com/go-delve/delve/pkg/proc/test"
 	"github.com/go-delve/delve/service"
+	"github.com/go-delve/delve/service/api"
 	"github.com/go-delve/delve/service/dap/daptest"
-	"github.com/go-delve/delve/service/debugger"
 	"github.com/google/go-dap"
 )
 
@@ -64,19 +66,23 @@ func runTestBuildFlags(t *testing.T, name string, test func(c *daptest.Client, f
 }
 
 func startDapServer(t *testing.T) *daptest.Client {
+	return daptest.NewClient(startDapServerWithOpts(t, service.Config{}))
+}
+
+// startDapServerWithOpts starts the DAP server, configured with the
+// options of config, and returns the address where it is listening for
+// client connections.
+func startDapServerWithOpts(t *testing.T, config service.Config) string {
 	// Start the DAP server.
 	listener, err := net.Listen("tcp", ":0")
 	if err != nil {
 		t.Fatal(err)
 	}
 	disconnectChan := make(chan struct{})
-	server := NewServer(&service.Config{
-		Listener:       listener,
-		DisconnectChan: disconnectChan,
-		Debugger: debugger.Config{
-			Backend: "default",
-		},
-	})
+	config.Listener = listener
+	config.DisconnectChan = disconnectChan
+	config.Debugger.Backend = "default"
+	server := NewServer(&config)
 	server.Run()
 	// Give server time to start listening for clients
 	time.Sleep(100 * time.Millisecond)
@@ -89,8 +95,144 @@ func startDapServer(t *testing.T) *daptest.Client {
 		server.Stop()
 	}()
 
-	client := daptest.NewClient(listener.Addr().String())
-	return client
+	return listener.Addr().String()
+}
+
+// TestKeepSessionOnDisconnect verifies that with AcceptMulti the debug session
+// survives a graceful disconnect and can be adopted by the next client.
+func TestKeepSessionOnDisconnect(t *testing.T) {
+	fixture := protest.BuildFixture("increment", protest.AllNonOptimized)
+	addr := startDapServerWithOpts(t, service.Config{AcceptMulti: true})
+
+	client := daptest.NewClient(addr)
+	client.InitializeRequest()
+	client.ExpectInitializeResponseAndCapabilities(t)
+	client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+	client.ExpectInitializedEvent(t)
+	client.ExpectLaunchResponse(t)
+	client.SetBreakpointsRequest(fixture.Source, []int{8})
+	client.ExpectSetBreakpointsResponse(t)
+	client.ConfigurationDoneRequest()
+	client.ExpectConfigurationDoneResponse(t)
+	client.ExpectStoppedEvent(t)
+	verifyStopLocation(t, client, 1, "main.Increment", 8)
+
+	// Disconnect without terminating the debuggee.
+	client.DisconnectRequest()
+	client.ExpectOutputEventRegex(t, "Detaching client, debug session is kept for the next client")
+	client.ExpectDisconnectResponse(t)
+	client.Close()
+
+	client = daptest.NewClient(addr)
+	defer client.Close()
+
+	// Requests are rejected until the session is adopted.
+	client.ThreadsRequest()
+	er := client.ExpectInvisibleErrorResponse(t)
+	if er.Body.Error.Id != SessionNotAdopted {
+		t.Errorf("\ngot %#v\nwant Id=%d", er, SessionNotAdopted)
+	}
+	client.InitializeRequest()
+	client.ExpectInitializeResponseAndCapabilities(t)
+	client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+	er = client.ExpectInvisibleErrorResponse(t)
+	if er.Body.Error.Id != FailedToLaunch {
+		t.Errorf("\ngot %#v\nwant Id=%d", er, FailedToLaunch)
+	}
+
+	client.AttachRequest(map[string]interface{}{"mode": "remote"})
+	client.ExpectInitializedEvent(t)
+	client.ExpectAttachResponse(t)
+	// The breakpoint set by the previous client is still there.
+	client.SetBreakpointsRequest(fixture.Source, []int{8})
+	bps := client.ExpectSetBreakpointsResponse(t)
+	if len(bps.Body.Breakpoints) != 1 || bps.Body.Breakpoints[0].Id != 1 {
+		t.Errorf("\ngot %#v\nwant the existing breakpoint with Id=1", bps)
+	}
+	client.ConfigurationDoneRequest()
+	client.ExpectConfigurationDoneResponse(t)
+	client.ExpectStoppedEvent(t)
+	verifyStopLocation(t, client, 1, "main.Increment", 8)
+
+	client.DisconnectRequestWithKillOption(true)
+	client.ExpectOutputEventDetachingKill(t)
+	client.ExpectDisconnectResponse(t)
+}
+
+// TestKeepSessionOnConnectionLoss verifies that with AcceptMulti the debug
+// session survives the loss of the client connection, without a disconnect
+// request, and can be adopted by the next client.
+func TestKeepSessionOnConnectionLoss(t *testing.T) {
+	fixture := protest.BuildFixture("increment", protest.AllNonOptimized)
+	addr := startDapServerWithOpts(t, service.Config{AcceptMulti: true})
+
+	client := daptest.NewClient(addr)
+	client.InitializeRequest()
+	client.ExpectInitializeResponseAndCapabilities(t)
+	client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+	client.ExpectInitializedEvent(t)
+	client.ExpectLaunchResponse(t)
+	client.SetBreakpointsRequest(fixture.Source, []int{8})
+	client.ExpectSetBreakpointsResponse(t)
+	client.ConfigurationDoneRequest()
+	client.ExpectConfigurationDoneResponse(t)
+	client.ExpectStoppedEvent(t)
+	verifyStopLocation(t, client, 1, "main.Increment", 8)
+	// The client goes away without disconnecting.
+	client.Close()
+
+	client = daptest.NewClient(addr)
+	defer client.Close()
+	client.InitializeRequest()
+	client.ExpectInitializeResponseAndCapabilities(t)
+	client.AttachRequest(map[string]interface{}{"mode": "remote"})
+	client.ExpectInitializedEvent(t)
+	client.ExpectAttachResponse(t)
+	client.ConfigurationDoneRequest()
+	client.ExpectConfigurationDoneResponse(t)
+	client.ExpectStoppedEvent(t)
+	verifyStopLocation(t, client, 1, "main.Increment", 8)
+
+	// The breakpoint set by the previous client is still there.
+	client.ContinueRequest(1)
+	client.ExpectContinueResponse(t)
+	client.ExpectStoppedEvent(t)
+	verifyStopLocation(t, client, 1, "main.Increment", 8)
+
+	client.DisconnectRequestWithKillOption(true)
+	client.ExpectOutputEventDetachingKill(t)
+	client.ExpectDisconnectResponse(t)
+}
+
+// TestAuthToken verifies that a server started with an authentication
+// token only processes the requests of clients that authenticated.
+func TestAuthToken(t *testing.T) {
+	fixture := protest.BuildFixture("increment", protest.AllNonOptimized)
+	client := daptest.NewClient(startDapServerWithOpts(t, service.Config{AuthToken: "secret"}))
+	defer client.Close()
+
+	client.ThreadsRequest()
+	er := client.ExpectInvisibleErrorResponse(t)
+	if er.Body.Error.Id != NotAuthenticated {
+		t.Errorf("\ngot %#v\nwant Id=%d", er, NotAuthenticated)
+	}
+	client.InitializeRequest()
+	client.ExpectInitializeResponseAndCapabilities(t)
+	client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "authToken": "wrong"})
+	er = client.ExpectInvisibleErrorResponse(t)
+	if er.Body.Error.Id != NotAuthenticated {
+		t.Errorf("\ngot %#v\nwant Id=%d", er, NotAuthenticated)
+	}
+
+	client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "authToken": "secret", "stopOnEntry": true})
+	client.ExpectInitializedEvent(t)
+	client.ExpectLaunchResponse(t)
+	client.ThreadsRequest()
+	client.ExpectThreadsResponse(t)
+
+	client.DisconnectRequestWithKillOption(true)
+	client.ExpectOutputEventDetachingKill(t)
+	client.ExpectDisconnectResponse(t)
 }
 
 // TestLaunchStopOnEntry emulates the message exchange that can be observed with
@@ -156,7 +298,7 @@ func TestLaunchStopOnEntry(t *testing.T) {
 		}
 
 		// 4 >> setExceptionBreakpoints, << setExceptionBreakpoints
-		client.SetExceptionBreakpointsRequest()
+		client.SetExceptionBreakpointsRequest(nil)
 		sebpResp := client.ExpectSetExceptionBreakpointsResponse(t)
 		if sebpResp.Seq != 0 || sebpResp.RequestSeq != 4 {
 			t.Errorf("\ngot %#v\nwant Seq=0, RequestSeq=4", sebpResp)
@@ -301,7 +443,7 @@ func TestAttachStopOnEntry(t *testing.T) {
 		}
 
 		// 4 >> setExceptionBreakpoints, << setExceptionBreakpoints
-		client.SetExceptionBreakpointsRequest()
+		client.SetExceptionBreakpointsRequest(nil)
 		sebpResp := client.ExpectSetExceptionBreakpointsResponse(t)
 		if sebpResp.Seq != 0 || sebpResp.RequestSeq != 4 {
 			t.Errorf("\ngot %#v\nwant Seq=0, RequestSeq=4", sebpResp)
@@ -404,7 +546,7 @@ func TestContinueOnEntry(t *testing.T) {
 		client.ExpectSetBreakpointsResponse(t)
 
 		// 4 >> setExceptionBreakpoints, << setExceptionBreakpoints
-		client.SetExceptionBreakpointsRequest()
+		client.SetExceptionBreakpointsRequest(nil)
 		client.ExpectSetExceptionBreakpointsResponse(t)
 
 		// 5 >> configurationDone, << configurationDone
@@ -476,7 +618,7 @@ func TestPreSetBreakpoint(t *testing.T) {
 			t.Errorf("got breakpoints[0] = %#v, want Verified=true, Line=8, Id=1, Path=%q", bkpt0, fixture.Source)
 		}
 
-		client.SetExceptionBreakpointsRequest()
+		client.SetExceptionBreakpointsRequest(nil)
 		client.ExpectSetExceptionBreakpointsResponse(t)
 
 		client.ConfigurationDoneRequest()
@@ -1207,15 +1349,29 @@ func TestScopesAndVariablesRequests2(t *testing.T) {
 					// reflect.Kind == Array
 					checkVarExact(t, locals, -1, "a0", "a0", "[0]int []", "[0]int", noChildren)
 					// reflect.Kind == Chan
-					ref := checkVarExact(t, locals, -1, "ch1", "ch1", "chan int 4/11", "chan int", hasChildren)
+					ref := checkVarExactIndexed(t, locals, -1, "ch1", "ch1", "chan int 4/11", "chan int", hasChildren, 4, 11)
 					if ref > 0 {
+						// The fields of the channel are followed by the values in its buffer.
 						client.VariablesRequest(ref)
 						ch1 := client.ExpectVariablesResponse(t)
-						checkChildren(t, ch1, "ch1", 11)
+						checkChildren(t, ch1, "ch1", 15)
 						checkVarExact(t, ch1, 0, "qcount", "ch1.qcount", "4", "uint", noChildren)
 						checkVarRegex(t, ch1, 10, "lock", "ch1.lock", `runtime\.mutex {.*key: 0.*}`, `runtime\.mutex`, hasChildren)
 						validateEvaluateName(t, client, ch1, 0)
 						validateEvaluateName(t, client, ch1, 10)
+						for i, want := range []string{"1", "4", "3", "2"} {
+							checkVarExact(t, ch1, 11+i, fmt.Sprintf("[%d]", i), "", want, "int", noChildren)
+						}
+
+						client.IndexedVariablesRequest(ref, 1, 2)
+						ch1 = client.ExpectVariablesResponse(t)
+						checkChildren(t, ch1, "ch1", 2)
+						checkVarExact(t, ch1, 0, "[1]", "", "4", "int", noChildren)
+						checkVarExact(t, ch1, 1, "[2]", "", "3", "int", noChildren)
+
+						client.NamedVariablesRequest(ref)
+						ch1 = client.ExpectVariablesResponse(t)
+						checkChildren(t, ch1, "ch1", 11)
 					}
 					checkVarExact(t, locals, -1, "chnil", "chnil", "chan int nil", "chan int", noChildren)
 					// reflect.Kind == Func
@@ -1494,7 +1650,14 @@ func TestVariablesLoading(t *testing.T) {
 
 					// String partially missing based on LoadConfig.MaxStringLen
 					// See also TestVariableLoadingOfLongStrings
-					checkVarExact(t, locals, -1, "longstr", "longstr", longstrLoaded64, "string", noChildren)
+					// Expect to be able to load the string in chunks.
+					ref := checkVarExactIndexed(t, locals, -1, "longstr", "longstr", longstrLoaded64, "string", hasChildren, 1, 0)
+					if ref > 0 {
+						client.VariablesRequest(ref)
+						chunks := client.ExpectVariablesResponse(t)
+						checkChildren(t, chunks, "longstr", 1)
+						checkVarExact(t, chunks, 0, "[0:137]", "longstr[0:137]", longstr, "string", noChildren)
+					}
 
 					checkArrayChildren := func(t *testing.T, longarr *dap.VariablesResponse, parentName string, start int) {
 						t.Helper()
@@ -1508,7 +1671,7 @@ func TestVariablesLoading(t *testing.T) {
 
 					// Array not fully loaded based on LoadConfig.MaxArrayValues.
 					// Expect to be able to load array by paging.
-					ref := checkVarExactIndexed(t, locals, -1, "longarr", "longarr", "[100]int [0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,...+36 more]", "[100]int", hasChildren, 100, 0)
+					ref = checkVarExactIndexed(t, locals, -1, "longarr", "longarr", "[100]int [0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,0,...+36 more]", "[100]int", hasChildren, 100, 0)
 					if ref > 0 {
 						client.VariablesRequest(ref)
 						longarr := client.ExpectVariablesResponse(t)
@@ -1997,7 +2160,56 @@ func TestLaunchRequestWithStackTraceDepth(t *testing.T) {
 				execute: func() {
 					client.StackTraceRequest(1, 0, 0)
 					stResp = client.ExpectStackTraceResponse(t)
-					checkStackFrames(t, stResp, "main.Increment", 8, 1000, 2 /*returned*/, 2 /*available*/)
+					// The stack is truncated: a label frame marks the truncation and
+					// the estimate of the frames left is added to TotalFrames.
+					if len(stResp.Body.StackFrames) != 3 || stResp.Body.TotalFrames <= 2 {
+						t.Fatalf("got %#v, want 2 frames and a label frame, TotalFrames>2", stResp.Body)
+					}
+					label := stResp.Body.StackFrames[2]
+					if label.Id != 0 || label.PresentationHint != "label" || !strings.HasPrefix(label.Name, "(truncated, about ") {
+						t.Errorf("got %#v, want truncation label", label)
+					}
+					client.ScopesRequest(label.Id)
+					erres := client.ExpectInvisibleErrorResponse(t)
+					if erres.Body.Error.Format != "Unable to list locals: unknown frame id 0" {
+						t.Errorf("\ngot %#v\nwant Format=\"Unable to list locals: unknown frame id 0\"", erres)
+					}
+
+					// Frames past stackTraceDepth are loaded when requested.
+					client.StackTraceRequest(1, 2, 2)
+					stResp = client.ExpectStackTraceResponse(t)
+					if len(stResp.Body.StackFrames) != 2 || stResp.Body.StackFrames[0].Name != "main.Increment" || stResp.Body.StackFrames[1].Name != "main.main" {
+						t.Errorf("got %#v, want main.Increment and main.main", stResp.Body.StackFrames)
+					}
+				},
+				disconnect: false,
+			}})
+	})
+}
+
+func TestLaunchRequestWithGoroutineFilters(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequestWithArgs(map[string]interface{}{
+					"mode": "exec", "program": fixture.Path, "hideSystemGoroutines": true, "groupGoroutinesBy": "user",
+				})
+			},
+			// Set breakpoints
+			fixture.Source, []int{8},
+			[]onBreakpoint{{ // Stop at line 8
+				execute: func() {
+					client.ThreadsRequest()
+					tResp := client.ExpectThreadsResponse(t)
+					if len(tResp.Body.Threads) < 1 || tResp.Body.Threads[0].Id != 1 || !strings.HasPrefix(tResp.Body.Threads[0].Name, "* (user=true) [Go 1] main.Increment") {
+						t.Errorf("\ngot  %#v\nwant Threads[0]={Id=1, Name=\"* (user=true) [Go 1] main.Increment ...\"}", tResp.Body.Threads)
+					}
+					for _, got := range tResp.Body.Threads {
+						if !strings.Contains(got.Name, "(user=true) ") {
+							t.Errorf("got %#v, want only user goroutines", got)
+						}
+					}
 				},
 				disconnect: false,
 			}})
@@ -2181,6 +2393,45 @@ func TestHitBreakpointIds(t *testing.T) {
 	})
 }
 
+func TestSimultaneousHits(t *testing.T) {
+	bp1, bp2, internal := &api.Breakpoint{ID: 1}, &api.Breakpoint{ID: 2}, &api.Breakpoint{ID: -1}
+	state := &api.DebuggerState{
+		CurrentThread: &api.Thread{ID: 10, GoroutineID: 1, Breakpoint: bp1},
+		Threads: []*api.Thread{
+			{ID: 11, GoroutineID: 5, Breakpoint: bp2, File: "/a.go", Line: 3},
+			{ID: 10, GoroutineID: 1, Breakpoint: bp1, File: "/b.go", Line: 7},
+			{ID: 12, GoroutineID: 6},
+			{ID: 13, GoroutineID: 7, Breakpoint: internal},
+			{ID: 14, GoroutineID: 8, Breakpoint: bp1, File: "/b.go", Line: 7},
+		},
+		PendingHits: 2,
+	}
+	ids, text := simultaneousHits(state, "reason")
+	if !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("got ids %v, want [1 2]", ids)
+	}
+	wantText := "reason\nBreakpoints also hit by goroutines 5, 8\n2 more breakpoint hits will be reported by the next continue requests"
+	if text != wantText {
+		t.Errorf("got text %q, want %q", text, wantText)
+	}
+
+	s := &Server{args: defaultArgs}
+	var got []int
+	for _, hit := range s.breakpointHits(state) {
+		got = append(got, hit.ThreadId, hit.BreakpointId, hit.Line)
+	}
+	if want := []int{1, 1, 0, 5, 2, 3, 8, 1, 7}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got hits %v, want %v", got, want)
+	}
+
+	// Stops at internal breakpoints, like unrecovered panics, do not
+	// report their breakpoint.
+	state = &api.DebuggerState{CurrentThread: &api.Thread{ID: 10, GoroutineID: 1, Breakpoint: internal}}
+	if ids, text := simultaneousHits(state, ""); ids != nil || text != "" {
+		t.Errorf("got %v %q for an internal breakpoint", ids, text)
+	}
+}
+
 func stringContainsCaseInsensitive(got, want string) bool {
 	return strings.Contains(strings.ToLower(got), strings.ToLower(want))
 }
@@ -2659,6 +2910,249 @@ func TestHitConditionBreakpoints(t *testing.T) {
 	})
 }
 
+// TestSetDataBreakpoints sets a data breakpoint on a global variable
+// and expects the program to stop when the variable is written.
+func TestSetDataBreakpoints(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("watchpoints are only supported on linux/amd64")
+	}
+	runTest(t, "databpeasy", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{12},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.main", 12)
+
+					client.DataBreakpointInfoRequest(0, "globalvar1")
+					info := client.ExpectDataBreakpointInfoResponse(t)
+					if info.Body.DataId != "globalvar1" {
+						t.Errorf("\ngot  %#v\nwant DataId=\"globalvar1\"", info)
+					}
+
+					client.DataBreakpointInfoRequest(0, "1 + 2")
+					info = client.ExpectDataBreakpointInfoResponse(t)
+					if info.Body.DataId != nil || info.Body.Description == "" {
+						t.Errorf("\ngot  %#v\nwant DataId=nil with a description", info)
+					}
+
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{{DataId: "globalvar1", AccessType: "write"}})
+					got := client.ExpectSetDataBreakpointsResponse(t)
+					if len(got.Body.Breakpoints) != 1 || !got.Body.Breakpoints[0].Verified {
+						t.Fatalf("\ngot  %#v\nwant one verified breakpoint", got)
+					}
+
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "data breakpoint" || len(se.Body.HitBreakpointIds) != 1 || se.Body.HitBreakpointIds[0] != got.Body.Breakpoints[0].Id {
+						t.Errorf("\ngot  %#v\nwant Reason=\"data breakpoint\" HitBreakpointIds=[%d]", se, got.Body.Breakpoints[0].Id)
+					}
+					checkStop(t, client, 1, "main.main", 17)
+
+					// Clear the data breakpoint.
+					client.SetDataBreakpointsRequest([]dap.DataBreakpoint{})
+					got = client.ExpectSetDataBreakpointsResponse(t)
+					if len(got.Body.Breakpoints) != 0 {
+						t.Errorf("\ngot  %#v\nwant no breakpoints", got)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+// TestReadWriteMemory reads and writes the memory referenced by a pointer
+// variable.
+func TestReadWriteMemory(t *testing.T) {
+	runTest(t, "databpeasy", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequestWithArgs(dap.InitializeRequestArguments{
+			AdapterID:                "go",
+			PathFormat:               "path",
+			LinesStartAt1:            true,
+			ColumnsStartAt1:          true,
+			SupportsVariableType:     true,
+			SupportsMemoryReferences: true,
+			SupportsInvalidatedEvent: true,
+		})
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+		client.SetBreakpointsRequest(fixture.Source, []int{12})
+		client.ExpectSetBreakpointsResponse(t)
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+		client.ExpectStoppedEvent(t)
+
+		client.EvaluateRequest("&globalvar1", 0 /*no frame specified*/, "repl")
+		ptr := client.ExpectEvaluateResponse(t)
+		memRef := ptr.Body.MemoryReference
+		if memRef == "" {
+			t.Fatalf("\ngot  %#v\nwant MemoryReference", ptr)
+		}
+
+		client.ReadMemoryRequest(memRef, 0, 8)
+		rm := client.ExpectReadMemoryResponse(t)
+		if rm.Body.Address != memRef || rm.Body.UnreadableBytes != 0 || rm.Body.Data != base64.StdEncoding.EncodeToString(make([]byte, 8)) {
+			t.Errorf("\ngot  %#v\nwant Address=%s Data=8 zero bytes", rm, memRef)
+		}
+
+		client.WriteMemoryRequest(memRef, 0, base64.StdEncoding.EncodeToString([]byte{42}), false)
+		client.ExpectWriteMemoryResponse(t)
+		if ie := client.ExpectInvalidatedEvent(t); len(ie.Body.Areas) != 1 || ie.Body.Areas[0] != "variables" {
+			t.Errorf("\ngot  %#v\nwant Areas=[variables]", ie)
+		}
+		client.EvaluateRequest("globalvar1", 0 /*no frame specified*/, "repl")
+		if got := client.ExpectEvaluateResponse(t); got.Body.Result != "42" {
+			t.Errorf("\ngot  %#v\nwant Result=\"42\"", got)
+		}
+
+		// Unmapped memory is reported as unreadable and can not be written.
+		client.ReadMemoryRequest("0x0", 0, 16)
+		rm = client.ExpectReadMemoryResponse(t)
+		if rm.Body.UnreadableBytes != 16 || rm.Body.Data != "" {
+			t.Errorf("\ngot  %#v\nwant UnreadableBytes=16", rm)
+		}
+		client.WriteMemoryRequest("0x0", 0, base64.StdEncoding.EncodeToString([]byte{42}), false)
+		if er := client.ExpectErrorResponse(t); er.Body.Error.Id != UnableToWriteMemory {
+			t.Errorf("\ngot  %#v\nwant Id=%d", er, UnableToWriteMemory)
+		}
+		client.ReadMemoryRequest("globalvar1", 0, 8)
+		if er := client.ExpectErrorResponse(t); er.Body.Error.Id != UnableToReadMemory {
+			t.Errorf("\ngot  %#v\nwant Id=%d", er, UnableToReadMemory)
+		}
+
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+// TestDiagnosticsRequest checks that the custom 'dlvDiagnostics' request
+// reports the registers, the frames and the expressions evaluated at the
+// current stop.
+func TestDiagnosticsRequest(t *testing.T) {
+	runTest(t, "databpeasy", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{12},
+			[]onBreakpoint{{
+				execute: func() {
+					client.EvaluateRequest("globalvar1", 0 /*no frame specified*/, "repl")
+					client.ExpectEvaluateResponse(t)
+
+					client.DiagnosticsRequest()
+					r := client.ExpectDiagnosticsResponse(t).Body
+					if r == nil || len(r.Registers) == 0 {
+						t.Fatalf("\ngot  %#v\nwant Registers", r)
+					}
+					if len(r.Frames) == 0 || r.Frames[0].Function == nil || r.Frames[0].Function.Name() != "main.main" || r.Frames[0].Unwind == nil || r.Frames[0].Unwind.CFA == "" {
+						t.Errorf("\ngot  %#v\nwant Frames[0] in main.main with unwind information", r.Frames)
+					}
+					found := false
+					for _, e := range r.Expressions {
+						if e.Expr == "globalvar1" && e.Err == "" && e.Type == "int" {
+							found = true
+						}
+					}
+					if !found {
+						t.Errorf("\ngot  %#v\nwant globalvar1 of type int", r.Expressions)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
+// TestDisassembleAndInstructionBreakpoints disassembles the instructions
+// around the current pc, steps a single instruction and stops at an
+// instruction breakpoint.
+func TestDisassembleAndInstructionBreakpoints(t *testing.T) {
+	runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			},
+			// Set breakpoints
+			fixture.Source, []int{8},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.loop", 8)
+
+					client.StackTraceRequest(1, 0, 1)
+					st := client.ExpectStackTraceResponse(t)
+					pc := st.Body.StackFrames[0].InstructionPointerReference
+					if pc == "" {
+						t.Fatalf("\ngot  %#v\nwant InstructionPointerReference", st)
+					}
+
+					client.DisassembleRequest(pc, -2, 5)
+					dr := client.ExpectDisassembleResponse(t)
+					if len(dr.Body.Instructions) != 5 {
+						t.Fatalf("\ngot  %#v\nwant 5 instructions", dr)
+					}
+					if got := dr.Body.Instructions[2]; got.Address != pc || got.Line != 8 || got.Location.Path != fixture.Source || got.Instruction == invalidInstruction.Instruction {
+						t.Errorf("\ngot  %#v\nwant Address=%s Line=8 Path=%s", got, pc, fixture.Source)
+					}
+
+					// Addresses that do not belong to any function are reported as invalid instructions.
+					client.DisassembleRequest("0x0", 0, 2)
+					dr = client.ExpectDisassembleResponse(t)
+					if len(dr.Body.Instructions) != 2 || dr.Body.Instructions[0].Instruction != invalidInstruction.Instruction || dr.Body.Instructions[1].Instruction != invalidInstruction.Instruction {
+						t.Errorf("\ngot  %#v\nwant 2 invalid instructions", dr)
+					}
+
+					client.StepInstructionRequest(1)
+					client.ExpectStepInResponse(t)
+					if se := client.ExpectStoppedEvent(t); se.Body.Reason != "step" {
+						t.Errorf("\ngot  %#v\nwant Reason=\"step\"", se)
+					}
+					client.StackTraceRequest(1, 0, 1)
+					st = client.ExpectStackTraceResponse(t)
+					if st.Body.StackFrames[0].InstructionPointerReference == pc {
+						t.Errorf("pc did not change after stepping one instruction: %s", pc)
+					}
+
+					// Replace the source breakpoint with an instruction breakpoint
+					// at the same address, hit in the next iteration of the loop.
+					client.SetBreakpointsRequest(fixture.Source, []int{})
+					client.ExpectSetBreakpointsResponse(t)
+					client.SetInstructionBreakpointsRequest([]dap.InstructionBreakpoint{{InstructionReference: pc}})
+					ib := client.ExpectSetInstructionBreakpointsResponse(t)
+					if len(ib.Body.Breakpoints) != 1 || !ib.Body.Breakpoints[0].Verified || ib.Body.Breakpoints[0].InstructionReference != pc {
+						t.Fatalf("\ngot  %#v\nwant one verified breakpoint at %s", ib, pc)
+					}
+
+					client.ContinueRequest(1)
+					client.ExpectContinueResponse(t)
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "instruction breakpoint" || len(se.Body.HitBreakpointIds) != 1 || se.Body.HitBreakpointIds[0] != ib.Body.Breakpoints[0].Id {
+						t.Errorf("\ngot  %#v\nwant Reason=\"instruction breakpoint\" HitBreakpointIds=[%d]", se, ib.Body.Breakpoints[0].Id)
+					}
+					checkStop(t, client, 1, "main.loop", 8)
+
+					client.SetInstructionBreakpointsRequest([]dap.InstructionBreakpoint{})
+					ib = client.ExpectSetInstructionBreakpointsResponse(t)
+					if len(ib.Body.Breakpoints) != 0 {
+						t.Errorf("\ngot  %#v\nwant no breakpoints", ib)
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
 // TestLaunchSubstitutePath sets a breakpoint using a path
 // that does not exist and expects the substitutePath attribute
 // in the launch configuration to take care of the mapping.
@@ -2729,27 +3223,158 @@ func substitutePathTestHelper(t *testing.T, fixture protest.Fixture, client *dap
 
 			execute: func() {
 				checkStop(t, client, 1, "main.loop", 8)
+
+				// The source is read from the path on the server.
+				client.SourceRequest(filepath.Join(nonexistentDir, "loopprog.go"))
+				got := client.ExpectSourceResponse(t)
+				want, err := ioutil.ReadFile(fixture.Source)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got.Body.Content != string(want) {
+					t.Errorf("got source %q, want %q", got.Body.Content, want)
+				}
 			},
 			disconnect: true,
 		}})
 }
 
-// execFixture runs the binary fixture.Path and hooks up stdout and stderr
-// to os.Stdout and os.Stderr.
-func execFixture(t *testing.T, fixture protest.Fixture) *exec.Cmd {
-	t.Helper()
-	// TODO(polina): do I need to sanity check testBackend and runtime.GOOS?
-	cmd := exec.Command(fixture.Path)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Start(); err != nil {
+func TestSubstitutePathRules(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test uses unix paths")
+	}
+	clientToServer, serverToClient, err := parseSubstitutePath([]interface{}{
+		map[string]interface{}{"from": "/client/src", "to": "/server/src"},
+		map[string]interface{}{"fromRegexp": `^/home/[^/]+/go/pkg/mod/(.*)$`, "to": "/go/pkg/mod/$1"},
+		map[string]interface{}{"from": "/home/me/go/pkg/mod/$1", "toRegexp": `^/go/pkg/mod/(.*)$`},
+		// Rules are evaluated in order, this one is never used from client to server.
+		map[string]interface{}{"from": "/client/src", "to": "/elsewhere"},
+	})
+	if err != nil {
 		t.Fatal(err)
 	}
-	return cmd
-}
 
-// TestWorkingDir executes to a breakpoint and tests that the specified
-// working directory is the one used to run the program.
+	for _, tc := range []struct {
+		rules      []substitutePathRule
+		path, want string
+	}{
+		{clientToServer, "/client/src/main.go", "/server/src/main.go"},
+		{clientToServer, "/client/srcmain.go", "/client/srcmain.go"},
+		{clientToServer, "/home/you/go/pkg/mod/golang.org/x/sys/unix/syscall.go", "/go/pkg/mod/golang.org/x/sys/unix/syscall.go"},
+		{clientToServer, "/other/main.go", "/other/main.go"},
+		{serverToClient, "/server/src/main.go", "/client/src/main.go"},
+		{serverToClient, "/elsewhere/main.go", "/client/src/main.go"},
+		{serverToClient, "/go/pkg/mod/golang.org/x/sys/unix/syscall.go", "/home/me/go/pkg/mod/golang.org/x/sys/unix/syscall.go"},
+	} {
+		if got := substitutePath(tc.path, tc.rules); got != tc.want {
+			t.Errorf("substitutePath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"fromRegexp": "(", "to": "/server"},
+		{"fromRegexp": "^/client", "toRegexp": "^/server"},
+		{"from": "/client", "fromRegexp": "^/client", "to": "/server"},
+	} {
+		if _, _, err := parseSubstitutePath([]interface{}{bad}); err == nil {
+			t.Errorf("parseSubstitutePath(%v): expected error", bad)
+		}
+	}
+}
+
+func TestGoroutineFilterAttributes(t *testing.T) {
+	filters, err := parseGoroutineFilters([]interface{}{
+		map[string]interface{}{"kind": "label", "arg": "tenant=acme"},
+		map[string]interface{}{"kind": "startloc", "arg": "net/http", "negated": true},
+		map[string]interface{}{"kind": "running"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []api.ListGoroutinesFilter{
+		{Kind: api.GoroutineLabel, Arg: "tenant=acme"},
+		{Kind: api.GoroutineStartLoc, Arg: "net/http", Negated: true},
+		{Kind: api.GoroutineRunning},
+	}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("parseGoroutineFilters: got %#v, want %#v", filters, want)
+	}
+
+	for _, bad := range []interface{}{
+		"user",
+		[]interface{}{map[string]interface{}{"kind": "unknown"}},
+		[]interface{}{map[string]interface{}{"kind": "curloc", "arg": "("}},
+		[]interface{}{map[string]interface{}{"kind": "label"}},
+		[]interface{}{map[string]interface{}{"kind": "user", "arg": "x"}},
+		[]interface{}{map[string]interface{}{"kind": "user", "negated": "yes"}},
+	} {
+		if _, err := parseGoroutineFilters(bad); err == nil {
+			t.Errorf("parseGoroutineFilters(%v): expected error", bad)
+		}
+	}
+
+	for _, tc := range []struct {
+		in   string
+		want api.GoroutineGroupingOptions
+	}{
+		{"", api.GoroutineGroupingOptions{}},
+		{"userloc", api.GoroutineGroupingOptions{GroupBy: api.GoroutineUserLoc}},
+		{"label:tenant", api.GoroutineGroupingOptions{GroupBy: api.GoroutineLabel, GroupByKey: "tenant"}},
+	} {
+		got, err := parseGroupGoroutinesBy(tc.in)
+		if err != nil || got != tc.want {
+			t.Errorf("parseGroupGoroutinesBy(%q): got %#v %v, want %#v", tc.in, got, err, tc.want)
+		}
+	}
+	for _, bad := range []interface{}{1, "label", "label:", "unknown"} {
+		if _, err := parseGroupGoroutinesBy(bad); err == nil {
+			t.Errorf("parseGroupGoroutinesBy(%v): expected error", bad)
+		}
+	}
+}
+
+func TestModuleRoot(t *testing.T) {
+	modinfo := "0w\xaf\f\x92t\b\x02A\xe1\xc1\a\xe6\xd6\x18\xe6path\texample.com/mod/cmd/prog\nmod\texample.com/mod\t(devel)\t\ndep\tgolang.org/x/sys\tv0.1.0\th1:abc=\n\xf92C1\x86\x18 r\x00\x82B\x10A\x16\xd8\xf2"
+	mainPath, modPath := parseModinfo(modinfo)
+	if mainPath != "example.com/mod/cmd/prog" || modPath != "example.com/mod" {
+		t.Errorf("parseModinfo: got %q %q", mainPath, modPath)
+	}
+
+	for _, tc := range []struct {
+		importPath, dir string
+		want            string
+		ok              bool
+	}{
+		{"example.com/mod", "/build/mod", "/build/mod", true},
+		{"example.com/mod/cmd/prog", "/build/mod/cmd/prog", "/build/mod", true},
+		{"example.com/mod/cmd/prog", `C:\build\mod\cmd\prog`, `C:\build\mod`, true},
+		{"example.com/mod/cmd/prog", "example.com/mod/cmd/prog", "example.com/mod", true},
+		{"example.com/modother", "/build/modother", "", false},
+		{"golang.org/x/sys/unix", "/go/pkg/mod/golang.org/x/sys@v0.1.0/unix", "", false},
+	} {
+		got, ok := moduleRoot(modPath, tc.importPath, tc.dir)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("moduleRoot(%q, %q) = %q, %v, want %q, %v", tc.importPath, tc.dir, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+// execFixture runs the binary fixture.Path and hooks up stdout and stderr
+// to os.Stdout and os.Stderr.
+func execFixture(t *testing.T, fixture protest.Fixture) *exec.Cmd {
+	t.Helper()
+	// TODO(polina): do I need to sanity check testBackend and runtime.GOOS?
+	cmd := exec.Command(fixture.Path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd
+}
+
+// TestWorkingDir executes to a breakpoint and tests that the specified
+// working directory is the one used to run the program.
 func TestWorkingDir(t *testing.T) {
 	runTest(t, "workdir", func(client *daptest.Client, fixture protest.Fixture) {
 		wd := os.TempDir()
@@ -3095,7 +3720,6 @@ func TestVariableLoadingOfLongStrings(t *testing.T) {
 						{"repl", maxSingleStringLen},
 						{"hover", maxSingleStringLen},
 						{"variables", maxSingleStringLen},
-						{"clipboard", maxSingleStringLen},
 						{"somethingelse", DefaultLoadConfig.MaxStringLen},
 					}
 					for _, tc := range tests {
@@ -3103,7 +3727,7 @@ func TestVariableLoadingOfLongStrings(t *testing.T) {
 							// Long string by itself (limits vary)
 							client.EvaluateRequest("s4097", 0, tc.context)
 							want := fmt.Sprintf(`"x+\.\.\.\+%d more"`, 4097-tc.limit)
-							checkEvalRegex(t, client.ExpectEvaluateResponse(t), want, noChildren)
+							checkEvalRegex(t, client.ExpectEvaluateResponse(t), want, hasChildren)
 
 							// Evaluated container variables return values with minimally loaded
 							// strings, which are further truncated for displaying, so we
@@ -3111,7 +3735,7 @@ func TestVariableLoadingOfLongStrings(t *testing.T) {
 							// value is returned.
 							client.EvaluateRequest("&s4097", 0, tc.context)
 							switch tc.context {
-							case "variables", "clipboard":
+							case "variables":
 								want = fmt.Sprintf(`\*"x+\.\.\.\+%d more`, 4097-DefaultLoadConfig.MaxStringLen)
 							default:
 								want = fmt.Sprintf(`\*"x{%d}\.\.\.`, maxVarValueLen-2)
@@ -3120,6 +3744,14 @@ func TestVariableLoadingOfLongStrings(t *testing.T) {
 						})
 					}
 
+					// Values copied to the clipboard are loaded in full
+					client.EvaluateRequest("s4097", 0, "clipboard")
+					checkEvalRegex(t, client.ExpectEvaluateResponse(t), `"x{4097}"`, noChildren)
+					client.EvaluateRequest("&s4097", 0, "clipboard")
+					checkEvalRegex(t, client.ExpectEvaluateResponse(t), `\*"x{4097}"`, hasChildren)
+					client.EvaluateRequest("nested", 0, "clipboard")
+					checkEvalRegex(t, client.ExpectEvaluateResponse(t), `map\[int\]string \[.*"x{4097}"`, hasChildren)
+
 					// Long strings returned from calls are subject to a different limit,
 					// same limit regardless of context
 					for _, context := range []string{"", "watch", "repl", "variables", "hover", "clipboard", "somethingelse"} {
@@ -3132,7 +3764,21 @@ func TestVariableLoadingOfLongStrings(t *testing.T) {
 					}
 
 					// Variables requests use the most conservative loading limit
-					checkVarRegex(t, locals, -1, "s513", "s513", `"x{512}\.\.\.\+1 more"`, "string", noChildren)
+					// and are loaded in full in chunks
+					ref := checkVarRegexIndexed(t, locals, -1, "s513", "s513", `"x{512}\.\.\.\+1 more"`, "string", hasChildren, 1, 0)
+					if ref > 0 {
+						client.VariablesRequest(ref)
+						chunks := client.ExpectVariablesResponse(t)
+						checkChildren(t, chunks, "s513", 1)
+						checkVarRegex(t, chunks, 0, `\[0:513\]`, `s513\[0:513\]`, `"x{513}"`, "string", noChildren)
+					}
+					ref = checkVarRegexIndexed(t, locals, -1, "s4097", "s4097", `"x{512}\.\.\.\+3585 more"`, "string", hasChildren, 2, 0)
+					if ref > 0 {
+						client.IndexedVariablesRequest(ref, 1, 1)
+						chunks := client.ExpectVariablesResponse(t)
+						checkChildren(t, chunks, "s4097", 1)
+						checkVarExact(t, chunks, 0, "[4096:4097]", "s4097[4096:4097]", `"x"`, "string", noChildren)
+					}
 					// Container variables are subject to additional stricter value truncation that drops +more part
 					checkVarRegex(t, locals, -1, "nested", "nested", `map\[int\]string \[513: \"x+\.\.\.`, "string", hasChildren)
 				},
@@ -3141,6 +3787,38 @@ func TestVariableLoadingOfLongStrings(t *testing.T) {
 	})
 }
 
+// TestClipboardValueLimit verifies that the values copied to the clipboard
+// are limited by the maxClipboardValueLen launch attribute.
+func TestClipboardValueLimit(t *testing.T) {
+	runTest(t, "longstrings", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequestWithArgs(map[string]interface{}{
+					"mode": "exec", "program": fixture.Path, "maxClipboardValueLen": 1000,
+				})
+			},
+			// Breakpoint set within the program
+			fixture.Source, []int{},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.main", -1)
+
+					client.EvaluateRequest("s4097", 0, "clipboard")
+					checkEvalRegex(t, client.ExpectEvaluateResponse(t), `"x{1000}\.\.\.\+3097 more"`, hasChildren)
+					client.EvaluateRequest("s513", 0, "clipboard")
+					checkEvalRegex(t, client.ExpectEvaluateResponse(t), `"x{513}"`, noChildren)
+					client.EvaluateRequest("nested", 0, "clipboard")
+					got := client.ExpectEvaluateResponse(t)
+					if len(got.Body.Result) != 1000+len("...") {
+						t.Errorf("got value of length %d, want %d", len(got.Body.Result), 1000+len("..."))
+					}
+				},
+				disconnect: true,
+			}})
+	})
+}
+
 func TestEvaluateCallRequest(t *testing.T) {
 	protest.MustSupportFunctionCalls(t, testBackend)
 	runTest(t, "fncall", func(client *daptest.Client, fixture protest.Fixture) {
@@ -3675,6 +4353,144 @@ func TestPanicBreakpointOnNext(t *testing.T) {
 	})
 }
 
+// TestExceptionBreakpointFilters checks that the program stops at panics
+// only when they are selected by the exception filters.
+func TestExceptionBreakpointFilters(t *testing.T) {
+	runFilterTest := func(t *testing.T, filters []string, onStop func(t *testing.T, se *dap.StoppedEvent)) {
+		t.Helper()
+		runTest(t, "panic", func(client *daptest.Client, fixture protest.Fixture) {
+			client.InitializeRequest()
+			client.ExpectInitializeResponseAndCapabilities(t)
+			client.LaunchRequest("exec", fixture.Path, !stopOnEntry)
+			client.ExpectInitializedEvent(t)
+			client.ExpectLaunchResponse(t)
+
+			client.SetExceptionBreakpointsRequest(filters)
+			resp := client.ExpectSetExceptionBreakpointsResponse(t)
+			if len(resp.Body.Breakpoints) != len(filters) {
+				t.Fatalf("\ngot  %#v\nwant len(Breakpoints)=%d", resp, len(filters))
+			}
+			for i, filter := range filters {
+				if wantVerified := filter != "foo"; resp.Body.Breakpoints[i].Verified != wantVerified {
+					t.Errorf("\ngot  Breakpoints[%d]=%#v\nwant Verified=%v", i, resp.Body.Breakpoints[i], wantVerified)
+				}
+			}
+
+			client.ConfigurationDoneRequest()
+			client.ExpectConfigurationDoneResponse(t)
+			if onStop == nil {
+				// The panic terminates the program without stopping.
+				client.ExpectTerminatedEvent(t)
+				client.DisconnectRequestWithKillOption(true)
+				client.ExpectOutputEventProcessExited(t, 2)
+				client.ExpectOutputEventDetaching(t)
+				client.ExpectDisconnectResponse(t)
+				return
+			}
+			onStop(t, client.ExpectStoppedEvent(t))
+			client.DisconnectRequestWithKillOption(true)
+			client.ExpectOutputEventDetachingKill(t)
+			client.ExpectDisconnectResponse(t)
+		})
+	}
+
+	text := "\"BOOM!\""
+	checkPanic := func(t *testing.T, se *dap.StoppedEvent) {
+		t.Helper()
+		if se.Body.Reason != "exception" || se.Body.Description != "panic" || se.Body.Text != text {
+			t.Errorf("\ngot  %#v\nwant Reason=\"exception\" Description=\"panic\" Text=%q", se, text)
+		}
+	}
+
+	t.Run("none", func(t *testing.T) {
+		runFilterTest(t, nil, nil)
+	})
+	t.Run("unknown", func(t *testing.T) {
+		runFilterTest(t, []string{"foo", "fatalError"}, nil)
+	})
+	t.Run("uncaughtPanicInModule", func(t *testing.T) {
+		// main.main raises the panic.
+		runFilterTest(t, []string{"uncaughtPanicInModule"}, checkPanic)
+	})
+	t.Run("panic", func(t *testing.T) {
+		// Stops at runtime.gopanic, before the panic is known to be uncaught.
+		runFilterTest(t, []string{"panic"}, checkPanic)
+	})
+}
+
+// TestTestFailureBreakpoint checks that the program stops when a test
+// fails, unless test failures are excluded by the exception filters.
+func TestTestFailureBreakpoint(t *testing.T) {
+	runFailureTest := func(t *testing.T, filters []string, stop bool) {
+		t.Helper()
+		runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+			client.InitializeRequest()
+			client.ExpectInitializeResponseAndCapabilities(t)
+
+			fixtures := protest.FindFixturesDir()
+			testdir, _ := filepath.Abs(filepath.Join(fixtures, "testfailure"))
+			client.LaunchRequestWithArgs(map[string]interface{}{
+				"mode": "test", "program": testdir, "output": "__mytestdir"})
+			client.ExpectInitializedEvent(t)
+			client.ExpectLaunchResponse(t)
+
+			client.SetExceptionBreakpointsRequest(filters)
+			client.ExpectSetExceptionBreakpointsResponse(t)
+			client.ConfigurationDoneRequest()
+			client.ExpectConfigurationDoneResponse(t)
+			if !stop {
+				client.ExpectTerminatedEvent(t)
+				client.DisconnectRequestWithKillOption(true)
+				client.ExpectOutputEventProcessExited(t, 1)
+				client.ExpectOutputEventDetaching(t)
+				client.ExpectDisconnectResponse(t)
+				return
+			}
+
+			text := "TestFailure/subtest failed: testfailure_test.go:11: expected 1\ngot 2"
+			se := client.ExpectStoppedEvent(t)
+			if se.Body.Reason != "exception" || se.Body.Description != "test failure" || se.Body.Text != text {
+				t.Errorf("\ngot  %#v\nwant Reason=\"exception\" Description=\"test failure\" Text=%q", se, text)
+			}
+			client.ExceptionInfoRequest(se.Body.ThreadId)
+			eInfo := client.ExpectExceptionInfoResponse(t)
+			if eInfo.Body.ExceptionId != "test failure" || eInfo.Body.Description != text {
+				t.Errorf("\ngot  %#v\nwant ExceptionId=\"test failure\" Description=%q", eInfo, text)
+			}
+
+			client.DisconnectRequestWithKillOption(true)
+			client.ExpectOutputEventDetachingKill(t)
+			client.ExpectDisconnectResponse(t)
+		})
+	}
+
+	t.Run("default", func(t *testing.T) {
+		runFailureTest(t, []string{"uncaughtPanic", "fatalError", "testFailure"}, true)
+	})
+	t.Run("excluded", func(t *testing.T) {
+		runFailureTest(t, []string{"uncaughtPanic", "fatalError"}, false)
+	})
+}
+
+func TestIsMainModulePackage(t *testing.T) {
+	for _, tc := range []struct {
+		pkg, modPath string
+		want         bool
+	}{
+		{"main", "", true},
+		{"main", "example.com/mod", true},
+		{"example.com/mod", "example.com/mod", true},
+		{"example.com/mod/pkg", "example.com/mod", true},
+		{"example.com/module/pkg", "example.com/mod", false},
+		{"fmt", "example.com/mod", false},
+		{"example.com/mod/pkg", "", false},
+	} {
+		if got := isMainModulePackage(tc.pkg, tc.modPath); got != tc.want {
+			t.Errorf("isMainModulePackage(%q, %q) = %v, want %v", tc.pkg, tc.modPath, got, tc.want)
+		}
+	}
+}
+
 func TestFatalThrowBreakpoint(t *testing.T) {
 	runTest(t, "fatalerror", func(client *daptest.Client, fixture protest.Fixture) {
 		runDebugSessionWithBPs(t, client, "launch",
@@ -3924,6 +4740,76 @@ func TestLaunchDebugRequest(t *testing.T) {
 	}
 }
 
+// TestRestartRequest verifies that a restart request rebuilds and relaunches
+// the program and that breakpoints are restored in the new process.
+func TestRestartRequest(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		wd, _ := os.Getwd()
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequestWithArgs(map[string]interface{}{
+					"mode": "debug", "program": fixture.Source, "output": filepath.Join(wd, "__restartBin")})
+			},
+			// Set breakpoints
+			fixture.Source, []int{8},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.Increment", 8)
+
+					client.RestartRequest()
+					client.ExpectOutputEventRegex(t, "Restarting the target process")
+					client.ExpectRestartResponse(t)
+					// The new process runs to the restored breakpoint.
+					se := client.ExpectStoppedEvent(t)
+					if se.Body.Reason != "breakpoint" {
+						t.Errorf("\ngot  %#v\nwant Reason=\"breakpoint\"", se)
+					}
+					checkStop(t, client, 1, "main.Increment", 8)
+				},
+				disconnect: false,
+			}})
+	})
+}
+
+func TestModulesRequest(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		runDebugSessionWithBPs(t, client, "launch",
+			// Launch
+			func() {
+				client.LaunchRequestWithArgs(map[string]interface{}{
+					"mode": "exec", "program": fixture.Path})
+			},
+			// Set breakpoints
+			fixture.Source, []int{8},
+			[]onBreakpoint{{
+				execute: func() {
+					checkStop(t, client, 1, "main.Increment", 8)
+
+					client.ModulesRequest()
+					resp := client.ExpectModulesResponse(t)
+					if len(resp.Body.Modules) == 0 || resp.Body.TotalModules != len(resp.Body.Modules) {
+						t.Fatalf("got %#v, want at least one module", resp.Body)
+					}
+					exe := resp.Body.Modules[0]
+					if id, ok := exe.Id.(float64); !ok || id != 0 {
+						t.Errorf("got Id=%#v, want 0", exe.Id)
+					}
+					if exe.Path != fixture.Path || exe.Name != filepath.Base(fixture.Path) {
+						t.Errorf("got Path=%q Name=%q, want %q", exe.Path, exe.Name, fixture.Path)
+					}
+					if exe.SymbolStatus != "Symbols loaded." {
+						t.Errorf("got SymbolStatus=%q, want \"Symbols loaded.\"", exe.SymbolStatus)
+					}
+					if !strings.HasPrefix(exe.AddressRange, "0x") || !strings.Contains(exe.AddressRange, "-0x") {
+						t.Errorf("got AddressRange=%q, want <start>-<end>", exe.AddressRange)
+					}
+				},
+				disconnect: false,
+			}})
+	})
+}
+
 // TestLaunchRequestDefaults tests defaults for launch attribute that are explicit in other tests.
 func TestLaunchRequestDefaults(t *testing.T) {
 	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
@@ -3981,6 +4867,252 @@ func TestLaunchRequestNoDebug_BadStatus(t *testing.T) {
 	})
 }
 
+func TestLaunchRequestProgress(t *testing.T) {
+	defer func(delay time.Duration) { progressDelay = delay }(progressDelay)
+	progressDelay = 0
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequestWithArgs(dap.InitializeRequestArguments{
+			AdapterID:                 "go",
+			PathFormat:                "path",
+			LinesStartAt1:             true,
+			ColumnsStartAt1:           true,
+			SupportsProgressReporting: true,
+		})
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"noDebug": true,
+			"mode":    "debug",
+			"program": fixture.Source,
+			"output":  "__mybin"})
+		start := client.ExpectProgressStartEvent(t)
+		if start.Body.Title != "Launching" || start.Body.ProgressId == "" {
+			t.Errorf("\ngot  %#v\nwant Title=\"Launching\"", start)
+		}
+		update := client.ExpectProgressUpdateEvent(t)
+		if update.Body.ProgressId != start.Body.ProgressId || update.Body.Message != fmt.Sprintf("Building %s", fixture.Source) {
+			t.Errorf("\ngot  %#v\nwant ProgressId=%q Message=\"Building %s\"", update, start.Body.ProgressId, fixture.Source)
+		}
+		end := client.ExpectProgressEndEvent(t)
+		if end.Body.ProgressId != start.Body.ProgressId {
+			t.Errorf("\ngot  %#v\nwant ProgressId=%q", end, start.Body.ProgressId)
+		}
+		client.ExpectLaunchResponse(t)
+
+		client.ExpectOutputEventProcessExited(t, 0)
+		client.ExpectTerminatedEvent(t)
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+// expectTargetOutput reads output events until the target exits and
+// returns the output of the target, by category.
+func expectTargetOutput(t *testing.T, client *daptest.Client) map[string]string {
+	t.Helper()
+	got := map[string]string{}
+	for {
+		e := client.ExpectOutputEvent(t)
+		if e.Body.Category == "console" {
+			if matched, _ := regexp.MatchString(`Process [0-9]+ has exited with status 0\n`, e.Body.Output); matched {
+				return got
+			}
+			continue
+		}
+		got[e.Body.Category] += e.Body.Output
+	}
+}
+
+func TestLaunchRequestOutputMode(t *testing.T) {
+	runTest(t, "outputtest", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"noDebug":    true,
+			"mode":       "exec",
+			"program":    fixture.Path,
+			"outputMode": "remote"})
+		client.ExpectLaunchResponse(t)
+
+		got := expectTargetOutput(t, client)
+		if want := map[string]string{"stdout": "hello stdout\n", "stderr": "hello stderr\n"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+		client.ExpectTerminatedEvent(t)
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+func TestLaunchRequestOutputFile(t *testing.T) {
+	runTest(t, "outputtest", func(client *daptest.Client, fixture protest.Fixture) {
+		dir, err := ioutil.TempDir("", "dlv-dap-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		outputFile := filepath.Join(dir, "output.txt")
+
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"noDebug":    true,
+			"mode":       "exec",
+			"program":    fixture.Path,
+			"outputMode": "remote",
+			"outputFile": outputFile})
+		client.ExpectLaunchResponse(t)
+
+		if got := expectTargetOutput(t, client); len(got) != 0 {
+			t.Errorf("output sent to the client: %q", got)
+		}
+		client.ExpectTerminatedEvent(t)
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectDisconnectResponse(t)
+
+		buf, err := ioutil.ReadFile(outputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != "hello stdout\nhello stderr\n" {
+			t.Errorf("got output file %q", buf)
+		}
+	})
+}
+
+func TestLaunchDebugRequestOutputMode(t *testing.T) {
+	runTest(t, "outputtest", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"mode":       "exec",
+			"program":    fixture.Path,
+			"outputMode": "remote"})
+		client.ExpectInitializedEvent(t)
+		client.ExpectLaunchResponse(t)
+
+		client.ConfigurationDoneRequest()
+		client.ExpectConfigurationDoneResponse(t)
+
+		// The output of the target is forwarded while it runs, the output
+		// still in the pipes when the session ends is forwarded before the
+		// disconnect response.
+		got := map[string]string{}
+	loop:
+		for {
+			m, err := client.ReadMessage()
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch m := m.(type) {
+			case *dap.TerminatedEvent:
+				client.DisconnectRequestWithKillOption(true)
+			case *dap.DisconnectResponse:
+				break loop
+			default:
+				if e := client.CheckOutputEvent(t, m); e.Body.Category != "console" {
+					got[e.Body.Category] += e.Body.Output
+				}
+			}
+		}
+		if want := map[string]string{"stdout": "hello stdout\n", "stderr": "hello stderr\n"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got output %q, want %q", got, want)
+		}
+	})
+}
+
+func TestLaunchRequestRunInTerminal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("running the target in the terminal of the client is not supported on windows")
+	}
+	runTest(t, "outputtest", func(client *daptest.Client, fixture protest.Fixture) {
+		dir, err := ioutil.TempDir("", "dlv-dap-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		// A regular file stands in for the terminal of the client.
+		tty := filepath.Join(dir, "tty")
+		if err := ioutil.WriteFile(tty, nil, 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"noDebug": true,
+			"mode":    "exec",
+			"program": fixture.Path,
+			"console": "integratedTerminal"})
+		req := client.ExpectRunInTerminalRequest(t)
+		args := req.Arguments.Args
+		if req.Arguments.Kind != "integrated" || len(args) != 6 || args[0] != "/bin/sh" {
+			t.Fatalf("\ngot %#v\nwant Kind=integrated Args=[/bin/sh -c script name ttyPipe waitPipe]", req.Arguments)
+		}
+		// Do what the shell run by the client would do: report the path of
+		// the terminal and wait for the end of the debug session.
+		if err := ioutil.WriteFile(args[4], []byte(tty+"\n"), 0); err != nil {
+			t.Fatal(err)
+		}
+		waitDone := make(chan error)
+		go func() {
+			_, err := ioutil.ReadFile(args[5])
+			waitDone <- err
+		}()
+		client.RunInTerminalResponse(req, "")
+		client.ExpectLaunchResponse(t)
+
+		client.ExpectOutputEventProcessExited(t, 0)
+		client.ExpectTerminatedEvent(t)
+		select {
+		case err := <-waitDone:
+			if err != nil {
+				t.Error(err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("the wait pipe was not closed at the end of the debug session")
+		}
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectDisconnectResponse(t)
+
+		buf, err := ioutil.ReadFile(tty)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(buf) != "hello stdout\nhello stderr\n" {
+			t.Errorf("got terminal output %q", buf)
+		}
+	})
+}
+
+func TestLaunchRequestRunInTerminalFailed(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("running the target in the terminal of the client is not supported on windows")
+	}
+	runTest(t, "outputtest", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"mode":    "exec",
+			"program": fixture.Path,
+			"console": "externalTerminal"})
+		req := client.ExpectRunInTerminalRequest(t)
+		if req.Arguments.Kind != "external" {
+			t.Errorf("\ngot %#v\nwant Kind=external", req.Arguments)
+		}
+		client.RunInTerminalResponse(req, "no terminal available")
+		er := client.ExpectInvisibleErrorResponse(t)
+		if er.Command != "launch" || er.Body.Error.Format != "Failed to launch: the client could not run the terminal: no terminal available" {
+			t.Errorf("\ngot %#v\nwant Command=launch Format=\"Failed to launch: the client could not run the terminal: no terminal available\"", er)
+		}
+	})
+}
+
 // runNoDebugDebugSession tests the session started with noDebug=true runs uninterrupted
 // even when breakpoint is set.
 func runNoDebugDebugSession(t *testing.T, client *daptest.Client, cmdRequest func(), source string, breakpoints []int, status int) {
@@ -4011,6 +5143,64 @@ func TestLaunchTestRequest(t *testing.T) {
 	})
 }
 
+func TestLaunchTestRequestTestEvents(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.InitializeRequest()
+		client.ExpectInitializeResponseAndCapabilities(t)
+
+		fixtures := protest.FindFixturesDir()
+		testdir, _ := filepath.Abs(filepath.Join(fixtures, "buildtest"))
+		client.LaunchRequestWithArgs(map[string]interface{}{
+			"noDebug": true, "mode": "test", "program": testdir, "output": "__mytestdir",
+			"args": []string{"-test.v"}, "outputMode": "remote"})
+		client.ExpectLaunchResponse(t)
+
+		var got []string
+		for {
+			m := client.ExpectMessage(t)
+			if e, ok := m.(*daptest.TestEvent); ok {
+				got = append(got, e.Body.Action+" "+e.Body.Test)
+				continue
+			}
+			e := client.CheckOutputEvent(t, m)
+			if e.Body.Category == "console" && strings.Contains(e.Body.Output, "has exited with status 0") {
+				break
+			}
+		}
+		if want := []string{"run TestCurrentDirectory", "pass TestCurrentDirectory"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got test events %q, want %q", got, want)
+		}
+		client.ExpectTerminatedEvent(t)
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
+func TestParseTestOutputLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want testEventBody
+		ok   bool
+	}{
+		{"=== RUN   TestFoo", testEventBody{Action: "run", Test: "TestFoo"}, true},
+		{"=== RUN   TestFoo/sub_test", testEventBody{Action: "run", Test: "TestFoo/sub_test"}, true},
+		{"=== PAUSE TestFoo", testEventBody{Action: "pause", Test: "TestFoo"}, true},
+		{"=== CONT  TestFoo", testEventBody{Action: "cont", Test: "TestFoo"}, true},
+		{"--- PASS: TestFoo (0.25s)", testEventBody{Action: "pass", Test: "TestFoo", Elapsed: 0.25}, true},
+		{"    --- FAIL: TestFoo/sub (1.50s)", testEventBody{Action: "fail", Test: "TestFoo/sub", Elapsed: 1.5}, true},
+		{"--- SKIP: TestFoo (0.00s)", testEventBody{Action: "skip", Test: "TestFoo"}, true},
+		{"    main_test.go:14: --- PASS: TestFoo (0.25s)", testEventBody{}, false},
+		{"PASS", testEventBody{}, false},
+		{"ok  	example.com/foo	0.010s", testEventBody{}, false},
+	}
+	for _, tc := range tests {
+		got, ok := parseTestOutputLine(tc.line)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("parseTestOutputLine(%q) = %#v, %v; want %#v, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
 // Tests that 'args' from LaunchRequest are parsed and passed to the target
 // program. The target program exits without an error on success, and
 // panics on error, causing an unexpected StoppedEvent instead of
@@ -4074,6 +5264,33 @@ func TestAttachRequest(t *testing.T) {
 	})
 }
 
+// TestAttachWaitFor attaches to a process started after the attach request.
+func TestAttachWaitFor(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("waitFor is only supported on linux")
+	}
+	runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
+		started := make(chan *exec.Cmd, 1)
+		go func() {
+			time.Sleep(500 * time.Millisecond)
+			started <- execFixture(t, fixture)
+		}()
+		client.AttachRequest(map[string]interface{}{"mode": "local", "waitFor": fixture.Path, "waitForTimeout": 10})
+		client.ExpectOutputEventRegex(t, `Waiting for a process matching ".*loopprog.*" to start...\n`)
+		client.ExpectInitializedEvent(t)
+		client.ExpectAttachResponse(t)
+		cmd := <-started
+		defer cmd.Process.Kill()
+
+		client.ThreadsRequest()
+		client.ExpectThreadsResponse(t)
+
+		client.DisconnectRequestWithKillOption(true)
+		client.ExpectOutputEventDetachingKill(t)
+		client.ExpectDisconnectResponse(t)
+	})
+}
+
 func TestPauseAndContinue(t *testing.T) {
 	runTest(t, "loopprog", func(client *daptest.Client, fixture protest.Fixture) {
 		runDebugSessionWithBPs(t, client, "launch",
@@ -4138,9 +5355,6 @@ func TestUnupportedCommandResponses(t *testing.T) {
 		client.GotoRequest()
 		expectUnsupportedCommand("goto")
 
-		client.SourceRequest()
-		expectUnsupportedCommand("source")
-
 		client.TerminateThreadsRequest()
 		expectUnsupportedCommand("terminateThreads")
 
@@ -4153,17 +5367,8 @@ func TestUnupportedCommandResponses(t *testing.T) {
 		client.CompletionsRequest()
 		expectUnsupportedCommand("completions")
 
-		client.DataBreakpointInfoRequest()
-		expectUnsupportedCommand("dataBreakpointInfo")
-
-		client.SetDataBreakpointsRequest()
-		expectUnsupportedCommand("setDataBreakpoints")
-
 		client.BreakpointLocationsRequest()
 		expectUnsupportedCommand("breakpointLocations")
-
-		client.ModulesRequest()
-		expectUnsupportedCommand("modules")
 	})
 }
 
@@ -4555,32 +5760,33 @@ func TestOptionalNotYetImplementedResponses(t *testing.T) {
 		client.TerminateRequest()
 		expectNotYetImplemented("terminate")
 
-		client.RestartRequest()
-		expectNotYetImplemented("restart")
-
-		client.StepBackRequest()
-		expectNotYetImplemented("stepBack")
-
-		client.ReverseContinueRequest()
-		expectNotYetImplemented("reverseContinue")
-
 		client.SetExpressionRequest()
 		expectNotYetImplemented("setExpression")
 
 		client.LoadedSourcesRequest()
 		expectNotYetImplemented("loadedSources")
 
-		client.ReadMemoryRequest()
-		expectNotYetImplemented("readMemory")
-
-		client.DisassembleRequest()
-		expectNotYetImplemented("disassemble")
-
 		client.CancelRequest()
 		expectNotYetImplemented("cancel")
 	})
 }
 
+func TestReverseRequestsWithoutRecording(t *testing.T) {
+	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
+		client.StepBackRequest()
+		got := client.ExpectErrorResponse(t)
+		if got.Command != "stepBack" || got.Body.Error.Id != UnableToStepBack {
+			t.Errorf("\ngot  %#v\nwant Command=stepBack Id=%d", got, UnableToStepBack)
+		}
+
+		client.ReverseContinueRequest()
+		got = client.ExpectErrorResponse(t)
+		if got.Command != "reverseContinue" || got.Body.Error.Id != UnableToReverseContinue {
+			t.Errorf("\ngot  %#v\nwant Command=reverseContinue Id=%d", got, UnableToReverseContinue)
+		}
+	})
+}
+
 func TestBadLaunchRequests(t *testing.T) {
 	runTest(t, "increment", func(client *daptest.Client, fixture protest.Fixture) {
 		seqCnt := 1
@@ -4648,6 +5854,15 @@ func TestBadLaunchRequests(t *testing.T) {
 		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
 			"Failed to launch: The program attribute is missing in debug configuration.")
 
+		// Bad "traceDirPath"
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "replay", "program": fixture.Path})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: The 'traceDirPath' attribute is missing in debug configuration.")
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "replay", "traceDirPath": 12345})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: The 'traceDirPath' attribute is missing in debug configuration.")
+
 		// Bad "args"
 		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "args": nil})
 		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
@@ -4661,6 +5876,38 @@ func TestBadLaunchRequests(t *testing.T) {
 		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
 			"Failed to launch: value '1' in 'args' attribute in debug configuration is not a string.")
 
+		// Bad "outputMode" and "outputFile"
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "outputMode": 123})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: 'outputMode' attribute '123' in debug configuration is not a string.")
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "outputMode": "console"})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: Unsupported 'outputMode' value \"console\" in debug configuration.")
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "outputFile": 123})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: 'outputFile' attribute '123' in debug configuration is not a string.")
+
+		// Bad "console"
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "console": 123})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: 'console' attribute '123' in debug configuration is not a string.")
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "console": "terminal"})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: Unsupported 'console' value \"terminal\" in debug configuration.")
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "console": "integratedTerminal", "outputMode": "remote"})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: The 'outputMode' and 'outputFile' attributes can not be used with 'console' \"integratedTerminal\".")
+
+		// The client did not send the 'initialize' request, it does not
+		// support the 'runInTerminal' request.
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "exec", "program": fixture.Path, "console": "integratedTerminal"})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: the client does not support the 'runInTerminal' request")
+
 		// Bad "buildFlags"
 		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "debug", "program": fixture.Source, "buildFlags": 123})
 		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
@@ -4681,6 +5928,14 @@ func TestBadLaunchRequests(t *testing.T) {
 		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "debug", "program": fixture.Source, "substitutePath": []interface{}{map[string]interface{}{"from": "path1", "to": 123}}})
 		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
 			"Failed to launch: 'substitutePath' attribute '[map[from:path1 to:123]]' in debug configuration is not a []{'from': string, 'to': string}")
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "debug", "program": fixture.Source, "substitutePath": []interface{}{map[string]interface{}{"fromRegexp": "(", "to": "path2"}}})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: invalid 'fromRegexp' \"(\" in 'substitutePath' attribute: error parsing regexp: missing closing ): `(`")
+
+		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "debug", "program": fixture.Source, "workspaceFolder": 123})
+		checkFailedToLaunchWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to launch: 'workspaceFolder' attribute '123' in debug configuration is not a string")
 		client.LaunchRequestWithArgs(map[string]interface{}{"mode": "debug", "program": fixture.Source, "cwd": 123})
 		checkFailedToLaunchWithMessage(client.ExpectErrorResponse(t),
 			"Failed to launch: 'cwd' attribute '123' in debug configuration is not a string.")
@@ -4765,7 +6020,7 @@ func TestBadAttachRequest(t *testing.T) {
 		// Bad "mode"
 		client.AttachRequest(map[string]interface{}{"mode": "remote"})
 		checkFailedToAttachWithMessage(client.ExpectInvisibleErrorResponse(t),
-			"Failed to attach: Unsupported 'mode' value \"remote\" in debug configuration")
+			"Failed to attach: No debug session to adopt: 'remote' mode requires a session kept by a previous client (see --accept-multiclient)")
 
 		client.AttachRequest(map[string]interface{}{"mode": "blah blah blah"})
 		checkFailedToAttachWithMessage(client.ExpectInvisibleErrorResponse(t),
@@ -4783,6 +6038,15 @@ func TestBadAttachRequest(t *testing.T) {
 		checkFailedToAttachWithMessage(client.ExpectInvisibleErrorResponse(t),
 			"Failed to attach: The 'processId' attribute is missing in debug configuration")
 
+		// Bad "waitFor"
+		client.AttachRequest(map[string]interface{}{"mode": "local", "waitFor": 123})
+		checkFailedToAttachWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to attach: The 'waitFor' attribute '123' in debug configuration is not a process name")
+
+		client.AttachRequest(map[string]interface{}{"mode": "local", "waitFor": "loopprog", "processId": 1})
+		checkFailedToAttachWithMessage(client.ExpectInvisibleErrorResponse(t),
+			"Failed to attach: The 'processId' and 'waitFor' attributes can not be used together")
+
 		// Bad "processId"
 		client.AttachRequest(map[string]interface{}{"mode": "local"})
 		checkFailedToAttachWithMessage(client.ExpectInvisibleErrorResponse(t),
@@ -0,0 +1,200 @@
+package dap
+
+import (
+	"errors"
+	"fmt"
+	"go/constant"
+	"regexp"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/locspec"
+	"github.com/go-delve/delve/pkg/proc"
+)
+
+// substitutePathRule is a rule for converting file paths between the
+// client and the debugger, in one direction.
+type substitutePathRule struct {
+	// from and to are directory paths, a path in directory from is
+	// converted to the same path in directory to.
+	from, to string
+	// re, if set, is matched against the path instead of from and the
+	// first match is replaced with to, which can refer to submatches
+	// of re (see regexp.Regexp.Expand).
+	re *regexp.Regexp
+}
+
+// apply converts path using r, returns false if r does not match path.
+func (r substitutePathRule) apply(path string) (string, bool) {
+	if r.re == nil {
+		return locspec.SubstitutePathRule(path, [2]string{r.from, r.to})
+	}
+	m := r.re.FindStringSubmatchIndex(path)
+	if m == nil {
+		return path, false
+	}
+	return path[:m[0]] + string(r.re.ExpandString(nil, r.to, path, m)) + path[m[1]:], true
+}
+
+// substitutePath converts path using the first rule that matches it.
+func substitutePath(path string, rules []substitutePathRule) string {
+	for _, r := range rules {
+		if substPath, ok := r.apply(path); ok {
+			return substPath
+		}
+	}
+	return path
+}
+
+// parseSubstitutePath parses the 'substitutePath' attribute of a launch or
+// attach request, a list of rules evaluated in order. Each rule is either:
+//   - {'from': string, 'to': string}: converts paths in directory 'from' on
+//     the client to paths in directory 'to' on the debugger and vice versa;
+//   - {'fromRegexp': string, 'to': string}: converts paths matching the
+//     regular expression 'fromRegexp' on the client to paths on the debugger
+//     by replacing the match with 'to', which can use $1 for submatches;
+//   - {'from': string, 'toRegexp': string}: converts paths matching the
+//     regular expression 'toRegexp' on the debugger to paths on the client
+//     by replacing the match with 'from'.
+func parseSubstitutePath(paths interface{}) (clientToServer, serverToClient []substitutePathRule, err error) {
+	typeMismatchError := fmt.Errorf("'substitutePath' attribute '%v' in debug configuration is not a []{'from': string, 'to': string}", paths)
+	pathsParsed, ok := paths.([]interface{})
+	if !ok {
+		return nil, nil, typeMismatchError
+	}
+	clientToServer = make([]substitutePathRule, 0, len(pathsParsed))
+	serverToClient = make([]substitutePathRule, 0, len(pathsParsed))
+	for _, arg := range pathsParsed {
+		pathMapping, ok := arg.(map[string]interface{})
+		if !ok {
+			return nil, nil, typeMismatchError
+		}
+		attr := func(name string) (string, bool, error) {
+			v, ok := pathMapping[name]
+			if !ok {
+				return "", false, nil
+			}
+			s, ok := v.(string)
+			if !ok {
+				return "", false, typeMismatchError
+			}
+			return s, true, nil
+		}
+		from, hasFrom, err := attr("from")
+		if err != nil {
+			return nil, nil, err
+		}
+		to, hasTo, err := attr("to")
+		if err != nil {
+			return nil, nil, err
+		}
+		fromRegexp, hasFromRegexp, err := attr("fromRegexp")
+		if err != nil {
+			return nil, nil, err
+		}
+		toRegexp, hasToRegexp, err := attr("toRegexp")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		switch {
+		case hasFrom && hasTo && !hasFromRegexp && !hasToRegexp:
+			clientToServer = append(clientToServer, substitutePathRule{from: from, to: to})
+			serverToClient = append(serverToClient, substitutePathRule{from: to, to: from})
+		case hasFromRegexp && hasTo && !hasFrom && !hasToRegexp:
+			re, err := regexp.Compile(fromRegexp)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid 'fromRegexp' %q in 'substitutePath' attribute: %v", fromRegexp, err)
+			}
+			clientToServer = append(clientToServer, substitutePathRule{re: re, to: to})
+		case hasFrom && hasToRegexp && !hasTo && !hasFromRegexp:
+			re, err := regexp.Compile(toRegexp)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid 'toRegexp' %q in 'substitutePath' attribute: %v", toRegexp, err)
+			}
+			serverToClient = append(serverToClient, substitutePathRule{re: re, to: from})
+		default:
+			return nil, nil, typeMismatchError
+		}
+	}
+	return clientToServer, serverToClient, nil
+}
+
+// mainModuleRoot returns the directory where the main module of the
+// target was compiled, using the module information embedded by the
+// linker in runtime.modinfo and the directories of the compiled packages.
+func (s *Server) mainModuleRoot() (string, error) {
+	mainPath, modPath, err := s.mainModuleInfo()
+	if err != nil {
+		return "", err
+	}
+	for _, pkg := range s.debugger.ListPackagesBuildInfo(false) {
+		ip := pkg.ImportPath
+		if ip == "main" {
+			ip = mainPath
+		}
+		if root, ok := moduleRoot(modPath, ip, pkg.DirectoryPath); ok {
+			return root, nil
+		}
+	}
+	return "", fmt.Errorf("could not find directory of module %s", modPath)
+}
+
+// mainModuleInfo returns the import path of the main package and the path
+// of the main module of the target, read from runtime.modinfo.
+func (s *Server) mainModuleInfo() (mainPath, modPath string, err error) {
+	v, err := s.debugger.EvalVariableInScope(-1, 0, 0, "runtime.modinfo", proc.LoadConfig{MaxStringLen: 1 << 16})
+	if err != nil {
+		return "", "", err
+	}
+	if v.Unreadable != nil {
+		return "", "", v.Unreadable
+	}
+	if v.Value == nil || v.Value.Kind() != constant.String {
+		return "", "", errors.New("no module information")
+	}
+	mainPath, modPath = parseModinfo(constant.StringVal(v.Value))
+	if modPath == "" {
+		return "", "", errors.New("no module information")
+	}
+	return mainPath, modPath, nil
+}
+
+// parseModinfo returns the import path of the main package and the path
+// of the main module from the contents of runtime.modinfo, see
+// cmd/go/internal/modload.PackageBuildInfo.
+func parseModinfo(modinfo string) (mainPath, modPath string) {
+	// The module information is wrapped in 16 bytes long sentinels.
+	if len(modinfo) >= 33 && modinfo[len(modinfo)-17] == '\n' {
+		modinfo = modinfo[16 : len(modinfo)-16]
+	}
+	for _, line := range strings.Split(modinfo, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "path":
+			mainPath = fields[1]
+		case "mod":
+			modPath = fields[1]
+		}
+	}
+	return mainPath, modPath
+}
+
+// moduleRoot returns the root directory of module modPath, given the
+// directory dir of the package with import path importPath, if the
+// package belongs to the module.
+func moduleRoot(modPath, importPath, dir string) (string, bool) {
+	if importPath == modPath {
+		return dir, true
+	}
+	if !strings.HasPrefix(importPath, modPath+"/") {
+		return "", false
+	}
+	rel := importPath[len(modPath):]
+	if !strings.HasSuffix(strings.Replace(dir, "\\", "/", -1), rel) {
+		return "", false
+	}
+	return dir[:len(dir)-len(rel)], true
+}
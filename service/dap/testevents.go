@@ -0,0 +1,84 @@
+package dap
+
+import (
+	"bytes"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-dap"
+)
+
+// When debugging a test binary whose output is captured (see
+// newTargetOutput) its standard output is parsed, like test2json does, and
+// the start and the result of each test are reported to the client with
+// custom 'testEvent' events, so that editors can update their test explorer
+// while the tests run. Tests are only reported as started when the test
+// binary is run with -test.v.
+
+// testEvent is the custom event sent when a test starts, pauses,
+// continues or completes.
+type testEvent struct {
+	dap.Event
+
+	Body testEventBody `json:"body"`
+}
+
+func (e *testEvent) GetEvent() *dap.Event { return &e.Event }
+
+// testEventBody is the body of the 'testEvent' event, its fields have the
+// same meaning as the fields of the events of test2json.
+type testEventBody struct {
+	// Action is one of "run", "pause", "cont", "pass", "fail" or "skip".
+	Action string `json:"action"`
+	// Test is the name of the test, subtests are named like
+	// "TestParent/subtest".
+	Test string `json:"test"`
+	// Elapsed is the duration of the test in seconds, only set for the
+	// "pass", "fail" and "skip" actions.
+	Elapsed float64 `json:"elapsed,omitempty"`
+}
+
+var (
+	testStartRe  = regexp.MustCompile(`^=== (RUN|PAUSE|CONT) +(\S+)`)
+	testResultRe = regexp.MustCompile(`^\s*--- (PASS|FAIL|SKIP): (\S+) \(([0-9.]+)s\)`)
+)
+
+// parseTestOutputLine returns the event described by line, a line of the
+// output of a test binary.
+func parseTestOutputLine(line string) (testEventBody, bool) {
+	if m := testStartRe.FindStringSubmatch(line); m != nil {
+		return testEventBody{Action: strings.ToLower(m[1]), Test: m[2]}, true
+	}
+	if m := testResultRe.FindStringSubmatch(line); m != nil {
+		elapsed, _ := strconv.ParseFloat(m[3], 64)
+		return testEventBody{Action: strings.ToLower(m[1]), Test: m[2], Elapsed: elapsed}, true
+	}
+	return testEventBody{}, false
+}
+
+// testEventWriter writes the output of a test binary to w and sends a
+// 'testEvent' event for each line that reports a change in the state of
+// a test.
+type testEventWriter struct {
+	s    *Server
+	w    io.Writer
+	line []byte // incomplete last line
+}
+
+func (tw *testEventWriter) Write(p []byte) (int, error) {
+	n, err := tw.w.Write(p)
+	tw.line = append(tw.line, p...)
+	for {
+		i := bytes.IndexByte(tw.line, '\n')
+		if i < 0 {
+			break
+		}
+		if body, ok := parseTestOutputLine(string(tw.line[:i])); ok {
+			tw.s.send(&testEvent{Event: *newEvent("testEvent"), Body: body})
+		}
+		tw.line = tw.line[i+1:]
+	}
+	return n, err
+}
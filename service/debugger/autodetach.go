@@ -0,0 +1,61 @@
+package debugger
+
+import (
+	"time"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// armAutoDetach starts counting the time the target stays stopped, if
+// Config.AutoDetach is set: when it exceeds AutoDetach the debugger
+// detaches from the target, resuming it.
+func (d *Debugger) armAutoDetach() {
+	if d.config.AutoDetach <= 0 || (d.config.AttachPid == 0 && d.config.StubAddr == "") {
+		return
+	}
+	d.autoDetachMu.Lock()
+	defer d.autoDetachMu.Unlock()
+	if d.autoDetachTimer != nil {
+		d.autoDetachTimer.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(d.config.AutoDetach, func() { d.autoDetach(t) })
+	d.autoDetachTimer = t
+}
+
+// disarmAutoDetach stops counting the time the target stays stopped, it
+// is called when the target is resumed.
+func (d *Debugger) disarmAutoDetach() {
+	d.autoDetachMu.Lock()
+	defer d.autoDetachMu.Unlock()
+	if d.autoDetachTimer != nil {
+		d.autoDetachTimer.Stop()
+		d.autoDetachTimer = nil
+	}
+}
+
+// autoDetach detaches from the target when the timer t, started by
+// armAutoDetach, expires.
+func (d *Debugger) autoDetach(t *time.Timer) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	d.autoDetachMu.Lock()
+	current := d.autoDetachTimer == t
+	if current {
+		d.autoDetachTimer = nil
+	}
+	d.autoDetachMu.Unlock()
+	if !current {
+		// The target was resumed while the timer expired.
+		return
+	}
+	if ok, _ := d.target.Valid(); !ok {
+		return
+	}
+	d.log.Warnf("target stopped for longer than %v, detaching", d.config.AutoDetach)
+	if err := d.detach(false); err != nil {
+		d.log.Errorf("could not detach: %v", err)
+		return
+	}
+	d.events.add(api.Event{Kind: api.EventDetached})
+}
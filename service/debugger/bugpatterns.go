@@ -0,0 +1,249 @@
+package debugger
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-delve/delve/pkg/dwarf/godwarf"
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/service/api"
+)
+
+// bugPatternsLoadConfig only loads what is needed to find the backing
+// array of slices and to know if maps are nil.
+var bugPatternsLoadConfig = proc.LoadConfig{FollowPointers: false, MaxVariableRecurse: 0, MaxStringLen: 0, MaxArrayValues: 0, MaxStructFields: 0}
+
+// BugPatterns inspects the specified stack frame looking for common
+// mistakes: slices sharing the same backing array where an append to one
+// would overwrite elements of the other, writes to nil maps on the current
+// line and goroutines started by closures of the current function that
+// share the same loop variable.
+// Each returned warning is a heuristic and could be a false positive.
+func (d *Debugger) BugPatterns(goid, frame int) ([]api.BugPattern, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	scope, err := proc.ConvertEvalScope(d.target, goid, frame, 0)
+	if err != nil {
+		return nil, err
+	}
+	args, err := scope.FunctionArguments(bugPatternsLoadConfig)
+	if err != nil {
+		return nil, err
+	}
+	locals, err := scope.LocalVariables(bugPatternsLoadConfig)
+	if err != nil {
+		return nil, err
+	}
+	vars := append(args, locals...)
+
+	r := []api.BugPattern{}
+	r = append(r, sliceAliasingPatterns(vars)...)
+
+	// The remaining checks need the source of the current function.
+	if scope.Fn == nil || scope.File == "" {
+		return r, nil
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, scope.File, nil, 0)
+	if err != nil {
+		d.log.Debugf("could not parse %s: %v", scope.File, err)
+		return r, nil
+	}
+	r = append(r, nilMapWritePatterns(fset, file, scope.Line, vars)...)
+	r = append(r, d.loopVarCapturePatterns(fset, file, scope.Fn)...)
+	return r, nil
+}
+
+// sliceAliasingPatterns finds pairs of slices where appending to the first
+// one would overwrite elements of the second one.
+func sliceAliasingPatterns(vars []*proc.Variable) []api.BugPattern {
+	type sliceRange struct {
+		v              *proc.Variable
+		lenEnd, capEnd uint64
+	}
+	slices := []sliceRange{}
+	for _, v := range vars {
+		if v.Kind != reflect.Slice || v.Unreadable != nil || v.Base == 0 || v.Flags&proc.VariableShadowed != 0 {
+			continue
+		}
+		typ, ok := v.RealType.(*godwarf.SliceType)
+		if !ok || typ.ElemType.Size() == 0 {
+			continue
+		}
+		sz := uint64(typ.ElemType.Size())
+		slices = append(slices, sliceRange{v: v, lenEnd: v.Base + uint64(v.Len)*sz, capEnd: v.Base + uint64(v.Cap)*sz})
+	}
+	r := []api.BugPattern{}
+	for i := range slices {
+		for j := range slices {
+			a, b := &slices[i], &slices[j]
+			if i == j || a.v.Len >= a.v.Cap || b.v.Len == 0 {
+				continue
+			}
+			// appending to a writes to [a.lenEnd, a.capEnd), which must not
+			// overlap the elements of b.
+			if a.lenEnd < b.lenEnd && b.v.Base < a.capEnd {
+				r = append(r, api.BugPattern{
+					Kind:    api.BugPatternSliceAliasing,
+					Message: fmt.Sprintf("%s and %s share the same backing array, appending to %s will overwrite elements of %s", a.v.Name, b.v.Name, a.v.Name, b.v.Name),
+				})
+			}
+		}
+	}
+	return r
+}
+
+// nilMapWritePatterns finds assignments to elements of nil maps on the
+// specified line.
+func nilMapWritePatterns(fset *token.FileSet, file *ast.File, line int, vars []*proc.Variable) []api.BugPattern {
+	nilMaps := map[string]bool{}
+	for _, v := range vars {
+		if v.Kind == reflect.Map && v.Unreadable == nil && v.Base == 0 && v.Flags&proc.VariableShadowed == 0 {
+			nilMaps[v.Name] = true
+		}
+	}
+	if len(nilMaps) == 0 {
+		return nil
+	}
+	r := []api.BugPattern{}
+	check := func(lhs ast.Expr) {
+		idx, ok := lhs.(*ast.IndexExpr)
+		if !ok {
+			return
+		}
+		id, ok := idx.X.(*ast.Ident)
+		if !ok || !nilMaps[id.Name] {
+			return
+		}
+		r = append(r, api.BugPattern{
+			Kind:    api.BugPatternNilMapWrite,
+			Message: fmt.Sprintf("%s is a nil map, assigning to one of its elements will panic", id.Name),
+		})
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if n == nil || fset.Position(n.Pos()).Line > line || fset.Position(n.End()).Line < line {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			if fset.Position(n.Pos()).Line == line {
+				for _, lhs := range n.Lhs {
+					check(lhs)
+				}
+			}
+		case *ast.IncDecStmt:
+			if fset.Position(n.Pos()).Line == line {
+				check(n.X)
+			}
+		}
+		return true
+	})
+	return r
+}
+
+// loopVarCapturePatterns finds goroutines, started by closures defined in
+// fn, which share the address of a variable declared by a for statement.
+// Such closures all see the value of the variable for the last iteration
+// instead of the one for the iteration that started them.
+func (d *Debugger) loopVarCapturePatterns(fset *token.FileSet, file *ast.File, fn *proc.Function) []api.BugPattern {
+	gs, _, err := proc.GoroutinesInfo(d.target, 0, 0)
+	if err != nil {
+		return nil
+	}
+
+	type capture struct {
+		fn, name string
+		addr     uint64
+		declLine int64
+	}
+	gids := map[capture][]int{}
+	prefix := fn.Name + ".func"
+	for _, g := range gs {
+		startfn := g.StartLoc(d.target).Fn
+		if startfn == nil || !strings.HasPrefix(startfn.Name, prefix) {
+			continue
+		}
+		frames, err := g.Stacktrace(goroutineStackFilterDepth, 0)
+		if err != nil {
+			continue
+		}
+		i := len(frames) - 1
+		for i >= 0 && frames[i].Current.Fn != startfn {
+			i--
+		}
+		if i < 0 {
+			continue
+		}
+		scope := proc.FrameToScope(d.target, d.target.BinInfo(), d.target.Memory(), g, frames[i:]...)
+		vars, err := scope.Locals()
+		if err != nil {
+			continue
+		}
+		for _, v := range vars {
+			if v.Flags&proc.VariableEscaped == 0 || v.Addr == 0 {
+				continue
+			}
+			c := capture{startfn.Name, v.Name, v.Addr, v.DeclLine}
+			gids[c] = append(gids[c], g.ID)
+		}
+	}
+
+	r := []api.BugPattern{}
+	for c, ids := range gids {
+		if len(ids) < 2 || !loopVarDeclaredAt(fset, file, c.name, int(c.declLine)) {
+			continue
+		}
+		idstrs := make([]string, len(ids))
+		for i := range ids {
+			idstrs[i] = strconv.Itoa(ids[i])
+		}
+		r = append(r, api.BugPattern{
+			Kind:    api.BugPatternLoopVarCapture,
+			Message: fmt.Sprintf("goroutines %s started by %s share the same loop variable %s (at %#x)", strings.Join(idstrs, ", "), c.fn, c.name, c.addr),
+		})
+	}
+	sort.Slice(r, func(i, j int) bool { return r[i].Message < r[j].Message })
+	return r
+}
+
+// loopVarDeclaredAt returns true if a for statement at the specified line
+// of file declares a variable called name. If line is 0 any line matches.
+func loopVarDeclaredAt(fset *token.FileSet, file *ast.File, name string, line int) bool {
+	found := false
+	match := func(e ast.Expr) {
+		if id, ok := e.(*ast.Ident); ok && id.Name == name && (line == 0 || fset.Position(id.Pos()).Line == line) {
+			found = true
+		}
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if found {
+			return false
+		}
+		switch n := n.(type) {
+		case *ast.ForStmt:
+			if init, ok := n.Init.(*ast.AssignStmt); ok && init.Tok == token.DEFINE {
+				for _, lhs := range init.Lhs {
+					match(lhs)
+				}
+			}
+		case *ast.RangeStmt:
+			if n.Tok == token.DEFINE {
+				if n.Key != nil {
+					match(n.Key)
+				}
+				if n.Value != nil {
+					match(n.Value)
+				}
+			}
+		}
+		return true
+	})
+	return found
+}
@@ -2,11 +2,14 @@ package debugger
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"debug/dwarf"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"go/parser"
 	"go/token"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -22,6 +25,7 @@ import (
 	"github.com/go-delve/delve/pkg/goversion"
 	"github.com/go-delve/delve/pkg/locspec"
 	"github.com/go-delve/delve/pkg/logflags"
+	"github.com/go-delve/delve/pkg/pprofwriter"
 	"github.com/go-delve/delve/pkg/proc"
 	"github.com/go-delve/delve/pkg/proc/core"
 	"github.com/go-delve/delve/pkg/proc/gdbserial"
@@ -62,6 +66,13 @@ type Debugger struct {
 
 	targetMutex sync.Mutex
 	target      *proc.Target
+	// targets are all the targets of the debugging session: the launched
+	// or attached target and the targets created following its children,
+	// see Config.FollowFork. target is one of them.
+	targets []*proc.Target
+	// parentPids maps the pid of each target created following a fork to
+	// the pid of its parent.
+	parentPids map[int]int
 
 	log *logrus.Entry
 
@@ -72,10 +83,42 @@ type Debugger struct {
 	recordMutex   sync.Mutex
 
 	dumpState proc.DumpState
+	// dumpDest is the absolute path of the last core dump, protected by
+	// dumpState.Mutex.
+	dumpDest string
 	// Debugger keeps a map of disabled breakpoints
 	// so lower layers like proc doesn't need to deal
 	// with them
 	disabledBreakpoints map[int]*api.Breakpoint
+	// artifacts are the files created by the debugger, like core dumps,
+	// that clients can download with ReadArtifact.
+	artifactsMutex sync.Mutex
+	artifacts      []string
+
+	// events are the recent events of the debugging session, see
+	// WaitForEvents.
+	events eventLog
+	// imagesReported is the number of images of the target reported by
+	// EventImageLoaded events.
+	imagesReported int
+	// output is the output of the target captured when
+	// config.CaptureOutput is set.
+	output *targetOutput
+	// outbuf keeps the recent output of the target, see TargetOutput.
+	outbuf outputBuffer
+
+	// evalq serializes the function calls requested by clients, see
+	// evalQueue.
+	evalq evalQueue
+
+	// autoDetachTimer expires when the target stays stopped for longer
+	// than config.AutoDetach, see armAutoDetach.
+	autoDetachMu    sync.Mutex
+	autoDetachTimer *time.Timer
+
+	// exprs are the expressions recently evaluated, reported by
+	// Diagnostics.
+	exprs exprLog
 }
 
 type ExecuteKind int
@@ -101,6 +144,23 @@ type Config struct {
 	// attach.
 	AttachPid int
 
+	// AttachWaitFor, if AttachPid is 0, is the name of a process the
+	// debugger should wait for and attach to, see matchProcess.
+	AttachWaitFor string
+
+	// AttachWaitForInterval is the interval between two searches of a
+	// process matching AttachWaitFor.
+	AttachWaitForInterval time.Duration
+
+	// AttachWaitForDuration is the maximum amount of time to wait for a
+	// process matching AttachWaitFor, zero means forever.
+	AttachWaitForDuration time.Duration
+
+	// StubAddr, if not empty, is the address of a stub already attached to
+	// the target process, see gdbserial.StubConnect. The first element of
+	// the process arguments is the path to a local copy of the executable.
+	StubAddr string
+
 	// CoreFile specifies the path to the core dump to open.
 	CoreFile string
 
@@ -137,6 +197,60 @@ type Config struct {
 
 	// DisableASLR disables ASLR
 	DisableASLR bool
+
+	// CaptureOutput captures the standard output and error of the launched
+	// process, unless they are redirected, reports them as output events
+	// (see WaitForEvents) and keeps the most recent output (see
+	// TargetOutput). They are still written to the standard output and
+	// error of the debugger.
+	CaptureOutput bool
+
+	// AutoDetach, if not zero, is the maximum amount of time the attached
+	// process can stay stopped: when it is exceeded the debugger detaches
+	// from it, resuming it. Only used when attaching through the JSON-RPC
+	// server, the DAP server never sets it.
+	AutoDetach time.Duration
+
+	// Strict, if set, puts the attached process in strict mode: only read
+	// only inspection is allowed, see proc.SafetyStrict. Only used when
+	// attaching.
+	Strict bool
+
+	// MaxQueuedCalls, if not zero, is the maximum number of function calls
+	// of a client that can wait for the function calls of other clients to
+	// complete, further function calls fail with ErrEvalQueueFull.
+	MaxQueuedCalls int
+
+	// CallHelper, if set, lets the function calls that can not be executed
+	// on the selected goroutine, because it is stopped inside the runtime,
+	// execute on a goroutine created by the debugger, see
+	// proc.(*Target).SetCallHelper.
+	CallHelper bool
+
+	// Symbolizers are used to resolve frames of code generated at run time by
+	// other runtimes embedded in the target, in addition to the perf map
+	// file of the target, see proc.Symbolizer.
+	Symbolizers []proc.Symbolizer
+
+	// PassSignals, if not nil, is the list of signals that are delivered to
+	// the target without stopping it, replacing the default of the backend.
+	// Only used by the backends that implement proc.SignalPasser (lldb, rr
+	// and remote stubs).
+	PassSignals []string
+
+	// FollowFork, if set, debugs the children created by the target with
+	// fork and vfork, and the processes that replace their executable with
+	// exec, as separate targets, see ListTargets. Only supported by the
+	// backends that implement proc.ForkFollower.
+	FollowFork bool
+
+	// AttachChildren, if not empty, is a regular expression: the debugger
+	// also attaches to the descendants of the attached process whose
+	// command line matches it and, like FollowFork, follows the processes
+	// created by all of them. The new processes are only debugged if their
+	// command line matches, checked at fork and again at exec. Only used
+	// when attaching.
+	AttachChildren string
 }
 
 // New creates a new Debugger. ProcessArgs specify the commandline arguments for the
@@ -149,8 +263,35 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		log:         logger,
 	}
 
+	if d.config.AttachPid == 0 && d.config.AttachWaitFor != "" {
+		pid, err := d.waitForProcess()
+		if err != nil {
+			return nil, err
+		}
+		d.config.AttachPid = pid
+	}
+
 	// Create the process by either attaching or launching.
 	switch {
+	case d.config.StubAddr != "":
+		d.log.Infof("connecting to stub %s", d.config.StubAddr)
+		path := ""
+		if len(d.processArgs) > 0 {
+			path = d.processArgs[0]
+		}
+		p, err := gdbserial.StubConnect(d.config.StubAddr, path, d.config.DebugInfoDirectories)
+		if err != nil {
+			err = go11DecodeErrorCheck(err)
+			return nil, fmt.Errorf("could not connect to stub %s: %v", d.config.StubAddr, err)
+		}
+		d.target = p
+		d.setSafetyProfile()
+		if err := d.checkGoVersion(); err != nil {
+			d.target.Detach(false)
+			return nil, err
+		}
+		d.armAutoDetach()
+
 	case d.config.AttachPid > 0:
 		d.log.Infof("attaching to pid %d", d.config.AttachPid)
 		path := ""
@@ -163,6 +304,8 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 			return nil, attachErrorMessage(d.config.AttachPid, err)
 		}
 		d.target = p
+		d.setSafetyProfile()
+		d.armAutoDetach()
 
 	case d.config.CoreFile != "":
 		var p *proc.Target
@@ -189,6 +332,7 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		d.log.Infof("launching process with args: %v", d.processArgs)
 		p, err := d.Launch(d.processArgs, d.config.WorkingDir)
 		if err != nil {
+			d.closeOutput()
 			if _, ok := err.(*proc.ErrUnsupportedArch); !ok {
 				err = go11DecodeErrorCheck(err)
 				err = fmt.Errorf("could not launch process: %s", err)
@@ -205,15 +349,231 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		}
 	}
 
+	if d.target != nil {
+		if err := d.setPassSignals(d.target); err != nil {
+			d.target.Detach(d.config.AttachPid == 0 && d.config.StubAddr == "")
+			return nil, err
+		}
+		d.addSymbolizers(d.target)
+		d.target.SetCallHelper(d.config.CallHelper)
+		d.targets = []*proc.Target{d.target}
+		if err := d.setFollowFork(d.target); err != nil {
+			d.detach(false)
+			return nil, err
+		}
+	}
 	d.disabledBreakpoints = make(map[int]*api.Breakpoint)
 
 	return d, nil
 }
 
+// addSymbolizers adds Config.Symbolizers and a symbolizer reading the perf
+// map file of the target to p.
+func (d *Debugger) addSymbolizers(p *proc.Target) {
+	for _, s := range d.config.Symbolizers {
+		p.AddSymbolizer(s)
+	}
+	if p.Pid() > 0 {
+		p.AddSymbolizer(proc.NewPerfMapSymbolizer(p.Pid()))
+	}
+}
+
+// setPassSignals applies Config.PassSignals to p.
+func (d *Debugger) setPassSignals(p *proc.Target) error {
+	if d.config.PassSignals == nil {
+		return nil
+	}
+	sp, ok := p.Process.(proc.SignalPasser)
+	if !ok {
+		return nil
+	}
+	return sp.SetPassSignals(d.config.PassSignals)
+}
+
+// setFollowFork applies Config.FollowFork and Config.AttachChildren to p.
+func (d *Debugger) setFollowFork(p *proc.Target) error {
+	d.parentPids = make(map[int]int)
+	attachChildren := d.config.AttachChildren != "" && d.config.AttachPid > 0
+	if !d.config.FollowFork && !attachChildren {
+		return nil
+	}
+	ff, ok := p.Process.(proc.ForkFollower)
+	if !ok {
+		return errors.New("following forks is not supported by this backend")
+	}
+	if !attachChildren {
+		return ff.FollowFork(true, nil, d.addTarget)
+	}
+
+	re, err := regexp.Compile(d.config.AttachChildren)
+	if err != nil {
+		return fmt.Errorf("invalid children pattern: %v", err)
+	}
+	match := func(pid int) bool {
+		return re.MatchString(strings.Join(processCmdline(pid), " "))
+	}
+	if err := ff.FollowFork(true, match, d.addTarget); err != nil {
+		return err
+	}
+	children, err := descendants(p.Pid())
+	if err != nil {
+		return err
+	}
+	pids := make([]int, 0, len(children))
+	for pid := range children {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		if !match(pid) {
+			continue
+		}
+		d.log.Infof("attaching to child process %d", pid)
+		if err := ff.AttachChild(pid, children[pid]); err != nil {
+			d.log.Errorf("could not attach to child process %d: %v", pid, err)
+		}
+	}
+	return nil
+}
+
+// addTarget is called with the target of a process created by fork or
+// exec while a target is running, or of a child process attached to, see
+// Config.FollowFork and Config.AttachChildren. The target of an exec
+// replaces the target that was debugging the process.
+func (d *Debugger) addTarget(p *proc.Target, parentPid int) {
+	d.log.Infof("following process %d, child of process %d", p.Pid(), parentPid)
+	execed := p.StopReason == proc.StopExeced
+	d.addSymbolizers(p)
+	p.SetCallHelper(d.config.CallHelper)
+	for name, b := range d.target.CustomBuiltins() {
+		p.RegisterBuiltin(name, b)
+	}
+
+	replaced := false
+	for i, t := range d.targets {
+		if execed && t.Pid() == p.Pid() {
+			d.copyTargetBreakpoints(t, p, true)
+			d.targets[i] = p
+			if d.target == t {
+				d.target = p
+			}
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		if parent := d.findTarget(parentPid); parent != nil {
+			d.copyTargetBreakpoints(parent, p, p.StopReason != proc.StopForked)
+		}
+		d.targets = append(d.targets, p)
+		d.parentPids[p.Pid()] = parentPid
+	}
+	d.events.add(api.Event{Kind: api.EventTargetAdded, Pid: p.Pid()})
+}
+
+// copyTargetBreakpoints sets the user breakpoints of from on to, by file
+// and line if byLine is set, by address otherwise. Watchpoints are not
+// copied.
+func (d *Debugger) copyTargetBreakpoints(from, to *proc.Target, byLine bool) {
+	maxID := 0
+	for _, bp := range api.ConvertBreakpoints(userBreakpoints(from)) {
+		if bp.ID > maxID {
+			maxID = bp.ID
+		}
+		if bp.WatchExpr != "" {
+			continue
+		}
+		addrs := bp.Addrs
+		if byLine {
+			if bp.File == "" {
+				continue
+			}
+			var err error
+			addrs, err = proc.FindFileLocation(to, bp.File, bp.Line)
+			if err != nil {
+				d.log.Debugf("could not set breakpoint %d on process %d: %v", bp.ID, to.Pid(), err)
+				continue
+			}
+		}
+		for _, addr := range addrs {
+			newBp, err := to.SetBreakpointWithID(bp.ID, addr)
+			if err == nil {
+				err = copyBreakpointInfo(newBp, bp)
+			}
+			if err != nil {
+				d.log.Debugf("could not set breakpoint %d on process %d: %v", bp.ID, to.Pid(), err)
+			}
+		}
+	}
+	for _, bp := range d.disabledBreakpoints {
+		if bp.ID > maxID {
+			maxID = bp.ID
+		}
+	}
+	to.SetNextBreakpointID(maxID)
+}
+
+// findTarget returns the target of the process with the given pid, nil if
+// there isn't one.
+func (d *Debugger) findTarget(pid int) *proc.Target {
+	for _, t := range d.targets {
+		if t.Pid() == pid {
+			return t
+		}
+	}
+	return nil
+}
+
+// setSafetyProfile applies Config.Strict to the attached target.
+func (d *Debugger) setSafetyProfile() {
+	if d.config.Strict {
+		d.log.Info("strict mode, only read-only inspection is allowed")
+		d.target.SetSafetyProfile(proc.SafetyStrict)
+	}
+}
+
+// waitForProcess searches the running processes for one matching
+// d.config.AttachWaitFor until it is found or AttachWaitForDuration has
+// elapsed, and returns its pid.
+func (d *Debugger) waitForProcess() (int, error) {
+	interval := d.config.AttachWaitForInterval
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	var deadline time.Time
+	if d.config.AttachWaitForDuration > 0 {
+		deadline = time.Now().Add(d.config.AttachWaitForDuration)
+	}
+	d.log.Infof("waiting for process %q", d.config.AttachWaitFor)
+	for {
+		pid, err := findProcess(d.config.AttachWaitFor)
+		if err != nil {
+			return 0, err
+		}
+		if pid > 0 {
+			return pid, nil
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return 0, fmt.Errorf("no process matching %q started within %v", d.config.AttachWaitFor, d.config.AttachWaitForDuration)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// matchProcess returns true if the process with command line cmdline
+// matches name: name is either the path or the base name of the
+// executable, or a prefix of the command line.
+func matchProcess(cmdline []string, name string) bool {
+	if len(cmdline) == 0 || name == "" {
+		return false
+	}
+	return cmdline[0] == name || filepath.Base(cmdline[0]) == name || strings.HasPrefix(strings.Join(cmdline, " "), name)
+}
+
 // canRestart returns true if the target was started with Launch and can be restarted
 func (d *Debugger) canRestart() bool {
 	switch {
-	case d.config.AttachPid > 0:
+	case d.config.AttachPid > 0 || d.config.StubAddr != "":
 		return false
 	case d.config.CoreFile != "":
 		return false
@@ -257,11 +617,20 @@ func (d *Debugger) Launch(processArgs []string, wd string) (*proc.Target, error)
 		launchFlags |= proc.LaunchDisableASLR
 	}
 
+	redirects := d.config.Redirects
+	if d.config.CaptureOutput && d.config.Backend != "rr" {
+		var err error
+		redirects, err = d.captureOutput(redirects)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	switch d.config.Backend {
 	case "native":
-		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects)
+		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, redirects)
 	case "lldb":
-		return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects))
+		return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, redirects))
 	case "rr":
 		if d.target != nil {
 			// restart should not call us if the backend is 'rr'
@@ -291,6 +660,11 @@ func (d *Debugger) Launch(processArgs []string, wd string) (*proc.Target, error)
 			}
 			d.recordingDone()
 			d.target = p
+			d.targets = []*proc.Target{p}
+			d.parentPids = make(map[int]int)
+			if err := d.setPassSignals(p); err != nil {
+				d.log.Errorf("could not set pass signals: %v", err)
+			}
 			if err := d.checkGoVersion(); err != nil {
 				d.log.Error(err)
 				err := d.target.Detach(true)
@@ -303,9 +677,9 @@ func (d *Debugger) Launch(processArgs []string, wd string) (*proc.Target, error)
 
 	case "default":
 		if runtime.GOOS == "darwin" {
-			return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects))
+			return betterGdbserialLaunchError(gdbserial.LLDBLaunch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, redirects))
 		}
-		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, d.config.Redirects)
+		return native.Launch(processArgs, wd, launchFlags, d.config.DebugInfoDirectories, d.config.TTY, redirects)
 	default:
 		return nil, fmt.Errorf("unknown backend %q", d.config.Backend)
 	}
@@ -376,6 +750,34 @@ func (d *Debugger) ProcessPid() int {
 	return d.target.Pid()
 }
 
+// ListTargets returns the targets of the debugging session that have not
+// exited, see Config.FollowFork.
+func (d *Debugger) ListTargets() []*api.Target {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	r := []*api.Target{}
+	for _, t := range d.allTargets() {
+		if ok, _ := t.Valid(); !ok {
+			continue
+		}
+		tgt := &api.Target{
+			Pid:        t.Pid(),
+			ParentPid:  d.parentPids[t.Pid()],
+			Current:    t == d.target,
+			StopReason: t.StopReason.String(),
+		}
+		if images := t.BinInfo().Images; len(images) > 0 {
+			tgt.Path = images[0].Path
+		}
+		if th := t.CurrentThread(); th != nil {
+			tgt.CurrentThread = api.ConvertThread(th)
+		}
+		r = append(r, tgt)
+	}
+	return r
+}
+
 // LastModified returns the time that the process' executable was last
 // modified.
 func (d *Debugger) LastModified() time.Time {
@@ -429,17 +831,46 @@ func (d *Debugger) Detach(kill bool) error {
 	d.log.Debug("detaching")
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
-	if ok, _ := d.target.Valid(); !ok {
+	valid := false
+	for _, t := range d.allTargets() {
+		if ok, _ := t.Valid(); ok {
+			valid = true
+		}
+	}
+	if !valid {
 		return nil
 	}
 	return d.detach(kill)
 }
 
 func (d *Debugger) detach(kill bool) error {
-	if d.config.AttachPid == 0 {
+	if d.config.AttachPid == 0 && d.config.StubAddr == "" {
 		kill = true
 	}
-	return d.target.Detach(kill)
+	// the targets created following forks are detached first, the first
+	// target is the one launched or attached to
+	targets := d.allTargets()
+	for i := len(targets) - 1; i > 0; i-- {
+		if ok, _ := targets[i].Valid(); !ok {
+			continue
+		}
+		if err := targets[i].Detach(kill); err != nil {
+			d.log.Errorf("could not detach from process %d: %v", targets[i].Pid(), err)
+		}
+	}
+	err := targets[0].Detach(kill)
+	d.closeOutput()
+	d.disarmAutoDetach()
+	return err
+}
+
+// allTargets returns all the targets of the debugging session, the
+// launched or attached target first.
+func (d *Debugger) allTargets() []*proc.Target {
+	if len(d.targets) == 0 {
+		return []*proc.Target{d.target}
+	}
+	return d.targets
 }
 
 // Restart will restart the target process, first killing
@@ -464,6 +895,9 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 		return nil, ErrCanNotRestart
 	}
 
+	// the breakpoints of the launched target are restored
+	d.target = d.allTargets()[0]
+
 	if valid, _ := d.target.Valid(); valid && !recorded {
 		// Ensure the process is in a PTRACE_STOP.
 		if err := stopProcess(d.target.Pid()); err != nil {
@@ -516,7 +950,28 @@ func (d *Debugger) Restart(rerecord bool, pos string, resetArgs bool, newArgs []
 
 	discarded := []api.DiscardedBreakpoint{}
 	breakpoints := api.ConvertBreakpoints(d.breakpoints())
+	for name, b := range d.target.CustomBuiltins() {
+		p.RegisterBuiltin(name, b)
+	}
+	for _, a := range d.target.Annotations() {
+		// goroutine IDs are not preserved by a restart
+		if a.GoroutineID == 0 {
+			p.SetAnnotation(a)
+		}
+	}
+	if err := d.setPassSignals(p); err != nil {
+		p.Detach(true)
+		return nil, err
+	}
+	if err := d.setFollowFork(p); err != nil {
+		p.Detach(true)
+		return nil, err
+	}
+	d.addSymbolizers(p)
+	p.SetCallHelper(d.config.CallHelper)
 	d.target = p
+	d.targets = []*proc.Target{p}
+	d.imagesReported = 0
 	maxID := 0
 	for _, oldBp := range breakpoints {
 		if oldBp.ID < 0 {
@@ -618,6 +1073,7 @@ func (d *Debugger) state(retLoadCfg *proc.LoadConfig) (*api.DebuggerState, error
 	}
 
 	state.NextInProgress = d.target.Breakpoints().HasInternalBreakpoints()
+	state.PendingHits = d.target.PendingHits()
 
 	if recorded, _ := d.target.Recorded(); recorded {
 		state.When, _ = d.target.When()
@@ -672,18 +1128,7 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoin
 	case requestedBp.TraceReturn:
 		addrs = []uint64{requestedBp.Addr}
 	case len(requestedBp.File) > 0:
-		fileName := requestedBp.File
-		if runtime.GOOS == "windows" {
-			// Accept fileName which is case-insensitive and slash-insensitive match
-			fileNameNormalized := strings.ToLower(filepath.ToSlash(fileName))
-			for _, symFile := range d.target.BinInfo().Sources {
-				if fileNameNormalized == strings.ToLower(filepath.ToSlash(symFile)) {
-					fileName = symFile
-					break
-				}
-			}
-		}
-		addrs, err = proc.FindFileLocation(d.target, fileName, requestedBp.Line)
+		addrs, err = proc.FindFileLocation(d.target, d.sourceFileName(requestedBp.File), requestedBp.Line)
 	case len(requestedBp.FunctionName) > 0:
 		addrs, err = proc.FindFunctionLocation(d.target, requestedBp.FunctionName, requestedBp.Line)
 	case len(requestedBp.Addrs) > 0:
@@ -806,13 +1251,17 @@ func (d *Debugger) CancelNext() error {
 
 func copyBreakpointInfo(bp *proc.Breakpoint, requested *api.Breakpoint) (err error) {
 	bp.Name = requested.Name
+	bp.Note = requested.Note
 	bp.Tracepoint = requested.Tracepoint
 	bp.TraceReturn = requested.TraceReturn
 	bp.Goroutine = requested.Goroutine
 	bp.Stacktrace = requested.Stacktrace
+	bp.AggregateStacks = requested.AggregateStacks
+	bp.SerializeHits = requested.SerializeHits
 	bp.Variables = requested.Variables
 	bp.LoadArgs = api.LoadConfigToProc(requested.LoadArgs)
 	bp.LoadLocals = api.LoadConfigToProc(requested.LoadLocals)
+	bp.LoadLocalsPaths = requested.LoadLocalsPaths
 	bp.Cond = nil
 	if requested.Cond != "" {
 		bp.Cond, err = parser.ParseExpr(requested.Cond)
@@ -837,10 +1286,11 @@ func parseHitCondition(hitCond string) (token.Token, int, error) {
 	// A hit condition can be in the following formats:
 	// - "number"
 	// - "OP number"
-	hitConditionRegex := regexp.MustCompile(`((=|>|<|%|!)+|)( |)((\d|_)+)`)
+	// where OP is one of ==, !=, >, >=, <, <= and %.
+	hitConditionRegex := regexp.MustCompile(`^((=|>|<|%|!)+|)\s*((\d|_)+)$`)
 
 	match := hitConditionRegex.FindStringSubmatch(strings.TrimSpace(hitCond))
-	if match == nil || len(match) != 6 {
+	if match == nil || len(match) != 5 {
 		return 0, 0, fmt.Errorf("unable to parse breakpoint hit condition: %q\nhit conditions should be of the form \"number\" or \"OP number\"", hitCond)
 	}
 
@@ -865,7 +1315,7 @@ func parseHitCondition(hitCond string) (token.Token, int, error) {
 		return 0, 0, fmt.Errorf("unable to parse breakpoint hit condition: %q\ninvalid operator: %q", hitCond, opStr)
 	}
 
-	numStr := match[4]
+	numStr := match[3]
 	val, parseErr := strconv.Atoi(numStr)
 	if parseErr != nil {
 		return 0, 0, fmt.Errorf("unable to parse breakpoint hit condition: %q\ninvalid number: %q", hitCond, numStr)
@@ -949,9 +1399,110 @@ func (d *Debugger) Breakpoints() []*api.Breakpoint {
 	return bps
 }
 
+// FileBreakpoints returns the lines of file where a breakpoint can be set
+// and the breakpoints, enabled or disabled, set on lines of file.
+func (d *Debugger) FileBreakpoints(file string) (*api.FileBreakpoints, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	bi := d.target.BinInfo()
+	file = d.sourceFileName(file)
+	r := &api.FileBreakpoints{File: file, Lines: bi.StmtLinesForFile(file)}
+	if len(r.Lines) == 0 {
+		return nil, fmt.Errorf("could not find file %s", file)
+	}
+
+	for _, bp := range api.ConvertBreakpoints(d.breakpoints()) {
+		if bp.File == file {
+			r.Breakpoints = append(r.Breakpoints, bp)
+		}
+	}
+	for _, bp := range d.disabledBreakpoints {
+		if bp.File == file {
+			r.Breakpoints = append(r.Breakpoints, bp)
+		}
+	}
+	sort.SliceStable(r.Breakpoints, func(i, j int) bool {
+		if r.Breakpoints[i].Line != r.Breakpoints[j].Line {
+			return r.Breakpoints[i].Line < r.Breakpoints[j].Line
+		}
+		return r.Breakpoints[i].ID < r.Breakpoints[j].ID
+	})
+	return r, nil
+}
+
+// sourceFileName returns the name of the source file of the target
+// matching fileName. On Windows the match is case-insensitive and
+// slash-insensitive.
+func (d *Debugger) sourceFileName(fileName string) string {
+	if runtime.GOOS == "windows" {
+		fileNameNormalized := strings.ToLower(filepath.ToSlash(fileName))
+		for _, symFile := range d.target.BinInfo().Sources {
+			if fileNameNormalized == strings.ToLower(filepath.ToSlash(symFile)) {
+				return symFile
+			}
+		}
+	}
+	return fileName
+}
+
+// Project returns the launch parameters and the breakpoints of the
+// debugging session. The state of the breakpoints (their addresses and hit
+// counts) is not included.
+func (d *Debugger) Project() *api.Project {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	p := &api.Project{
+		BuildFlags: d.config.BuildFlags,
+		WorkingDir: d.config.WorkingDir,
+		Backend:    d.config.Backend,
+	}
+	if len(d.processArgs) > 0 {
+		p.Args = append([]string{}, d.processArgs[1:]...)
+	}
+	switch {
+	case d.config.AttachPid > 0 || d.config.StubAddr != "" || d.config.CoreFile != "":
+		// can not be restarted from the project file
+	case d.config.ExecuteKind == ExecutingGeneratedFile:
+		p.Kind = api.ProjectDebug
+		p.Packages = d.config.Packages
+	case d.config.ExecuteKind == ExecutingGeneratedTest:
+		p.Kind = api.ProjectTest
+		p.Packages = d.config.Packages
+	case d.config.ExecuteKind == ExecutingExistingFile && len(d.processArgs) > 0:
+		p.Kind = api.ProjectExec
+		p.Program = d.processArgs[0]
+	}
+
+	bps := api.ConvertBreakpoints(d.breakpoints())
+	for _, bp := range d.disabledBreakpoints {
+		bps = append(bps, bp)
+	}
+	sort.Slice(bps, func(i, j int) bool { return bps[i].ID < bps[j].ID })
+	for _, bp := range bps {
+		bp := *bp
+		bp.ID = 0
+		bp.Addr = 0
+		bp.Addrs = nil
+		bp.HitCount = nil
+		bp.TotalHitCount = 0
+		p.Breakpoints = append(p.Breakpoints, &bp)
+	}
+	for _, a := range d.target.Annotations() {
+		p.Annotations = append(p.Annotations, api.ConvertAnnotation(a))
+	}
+	return p
+}
+
 func (d *Debugger) breakpoints() []*proc.Breakpoint {
+	return userBreakpoints(d.target)
+}
+
+// userBreakpoints returns the user breakpoints of p sorted by logical ID.
+func userBreakpoints(p *proc.Target) []*proc.Breakpoint {
 	bps := []*proc.Breakpoint{}
-	for _, bp := range d.target.Breakpoints().M {
+	for _, bp := range p.Breakpoints().M {
 		if bp.IsUser() {
 			bps = append(bps, bp)
 		}
@@ -1111,14 +1662,27 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 
 	withBreakpointInfo := true
 
+	if command.Name == api.Call {
+		release, err := d.evalq.acquire(command.Client, d.config.MaxQueuedCalls)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
 	d.setRunning(true)
 	defer d.setRunning(false)
 
-	if command.Name != api.SwitchGoroutine && command.Name != api.SwitchThread && command.Name != api.Halt {
+	cur := d.target
+	resuming := command.Name != api.SwitchGoroutine && command.Name != api.SwitchThread && command.Name != api.Halt
+	if resuming {
 		d.target.ResumeNotify(resumeNotify)
+		d.events.add(api.Event{Kind: api.EventResumed})
+		d.disarmAutoDetach()
+		defer d.armAutoDetach()
 	} else if resumeNotify != nil {
 		close(resumeNotify)
 	}
@@ -1148,7 +1712,9 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 				return nil, err
 			}
 		}
+		d.target.SetWriteOrigin(command.Client, "call "+command.Expr)
 		err = proc.EvalExpressionWithCalls(d.target, g, command.Expr, *api.LoadConfigToProc(command.ReturnInfoLoadConfig), !command.UnsafeCall)
+		d.target.SetWriteOrigin("", "")
 	case api.Rewind:
 		d.log.Debug("rewinding")
 		if err := d.target.ChangeDirection(proc.Backward); err != nil {
@@ -1205,7 +1771,7 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 		err = d.target.StepOut()
 	case api.SwitchThread:
 		d.log.Debugf("switching to thread %d", command.ThreadID)
-		err = d.target.SwitchThread(command.ThreadID)
+		err = d.switchThread(command.ThreadID)
 		withBreakpointInfo = false
 	case api.SwitchGoroutine:
 		d.log.Debugf("switching to goroutine %d", command.GoroutineID)
@@ -1220,6 +1786,11 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 		withBreakpointInfo = false
 	}
 
+	if _, ok := err.(proc.ErrProcessExited); ok && d.target != cur {
+		// the process replaced its executable and is debugged by a new
+		// target, see addTarget
+		err = nil
+	}
 	if err != nil {
 		if pe, ok := err.(proc.ErrProcessExited); ok && command.Name != api.SwitchGoroutine && command.Name != api.SwitchThread {
 			state := &api.DebuggerState{}
@@ -1227,6 +1798,9 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 			state.Exited = true
 			state.ExitStatus = pe.Status
 			state.Err = pe
+			if resuming {
+				d.events.add(api.Event{Kind: api.EventExited, ExitStatus: pe.Status, Pid: state.Pid})
+			}
 			return state, nil
 		}
 		return nil, err
@@ -1238,6 +1812,9 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 	if withBreakpointInfo {
 		err = d.collectBreakpointInformation(state)
 	}
+	if resuming {
+		d.emitStopEvents(state)
+	}
 	for _, th := range state.Threads {
 		if th.Breakpoint != nil && th.Breakpoint.TraceReturn {
 			for _, v := range th.BreakpointInfo.Arguments {
@@ -1250,6 +1827,23 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 	return state, err
 }
 
+// switchThread switches to thread tid, which can belong to another target
+// of the debugging session, see Config.FollowFork.
+func (d *Debugger) switchThread(tid int) error {
+	if _, ok := d.target.FindThread(tid); !ok {
+		for _, t := range d.targets {
+			if ok, _ := t.Valid(); !ok {
+				continue
+			}
+			if _, ok := t.FindThread(tid); ok {
+				d.target = t
+				break
+			}
+		}
+	}
+	return d.target.SwitchThread(tid)
+}
+
 func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error {
 	if state == nil {
 		return nil
@@ -1264,16 +1858,24 @@ func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error
 		bpi := &api.BreakpointInfo{}
 		state.Threads[i].BreakpointInfo = bpi
 
+		// Several threads can hit breakpoints at the same time, the information
+		// is collected from the thread that hit bp, which is not necessarily
+		// the current thread.
+		thread, found := d.target.FindThread(state.Threads[i].ID)
+		if !found {
+			return fmt.Errorf("could not find thread %d", state.Threads[i].ID)
+		}
+
 		if bp.Goroutine {
-			g, err := proc.GetG(d.target.CurrentThread())
+			g, err := proc.GetG(thread)
 			if err != nil {
 				return err
 			}
 			bpi.Goroutine = api.ConvertGoroutine(d.target, g)
 		}
 
-		if bp.Stacktrace > 0 {
-			rawlocs, err := proc.ThreadStacktrace(d.target.CurrentThread(), bp.Stacktrace)
+		if bp.Stacktrace > 0 || bp.Stacktrace == api.StacktraceAll {
+			rawlocs, err := proc.ThreadStacktrace(thread, bp.Stacktrace)
 			if err != nil {
 				return err
 			}
@@ -1283,9 +1885,14 @@ func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error
 			}
 		}
 
-		thread, found := d.target.FindThread(state.Threads[i].ID)
-		if !found {
-			return fmt.Errorf("could not find thread %d", state.Threads[i].ID)
+		if bp.Name == proc.TestFailure {
+			// Stopping at the failure is still useful if the failed test
+			// can not be determined.
+			if tf, err := proc.GetTestFailure(d.target, thread); err != nil {
+				d.log.Errorf("could not read test failure: %v", err)
+			} else {
+				bpi.TestFailure = &api.TestFailure{Test: tf.Test, Message: tf.Message}
+			}
 		}
 
 		if len(bp.Variables) == 0 && bp.LoadArgs == nil && bp.LoadLocals == nil {
@@ -1315,7 +1922,14 @@ func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error
 			}
 		}
 		if bp.LoadLocals != nil {
-			if locals, err := s.LocalVariables(*api.LoadConfigToProc(bp.LoadLocals)); err == nil {
+			var locals []*proc.Variable
+			var err error
+			if len(bp.LoadLocalsPaths) > 0 {
+				locals, err = s.LocalVariablesInterest(*api.LoadConfigToProc(bp.LoadLocals), bp.LoadLocalsPaths)
+			} else {
+				locals, err = s.LocalVariables(*api.LoadConfigToProc(bp.LoadLocals))
+			}
+			if err == nil {
 				bpi.Locals = api.ConvertVars(locals)
 			}
 		}
@@ -1387,6 +2001,69 @@ func (d *Debugger) Types(filter string) ([]string, error) {
 	return r, nil
 }
 
+// TypeLayout returns the memory layout of the type with the given name.
+func (d *Debugger) TypeLayout(name string) (*proc.TypeLayout, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	return d.target.BinInfo().TypeLayout(name)
+}
+
+// Symbols returns the functions and the package variables whose name
+// matches filter, with their address. If kind is api.SymbolFunction or
+// api.SymbolVariable only symbols of that kind are returned.
+func (d *Debugger) Symbols(filter, kind string) ([]api.Symbol, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	regex, err := regexp.Compile(filter)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter argument: %s", err.Error())
+	}
+
+	r := []api.Symbol{}
+	bi := d.target.BinInfo()
+	if kind == "" || kind == api.SymbolFunction {
+		for _, fn := range bi.Functions {
+			if fn.Entry == 0 || !regex.MatchString(fn.Name) {
+				continue
+			}
+			file, line, _ := bi.PCToLine(fn.Entry)
+			r = append(r, api.Symbol{
+				Name: fn.Name,
+				Kind: api.SymbolFunction,
+				Addr: fn.Entry,
+				Size: int64(fn.End - fn.Entry),
+				File: file,
+				Line: line,
+			})
+		}
+	}
+	if kind == "" || kind == api.SymbolVariable {
+		scope, err := proc.ThreadScope(d.target, d.target.CurrentThread())
+		if err != nil {
+			return nil, err
+		}
+		// Only the addresses of the variables are needed, not their values.
+		vars, err := scope.PackageVariables(proc.LoadConfig{})
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vars {
+			if !regex.MatchString(v.Name) {
+				continue
+			}
+			sym := api.Symbol{Name: v.Name, Kind: api.SymbolVariable, Addr: v.Addr}
+			if v.DwarfType != nil {
+				sym.Type = api.PrettyTypeName(v.DwarfType)
+				sym.Size = v.DwarfType.Size()
+			}
+			r = append(r, sym)
+		}
+	}
+	return r, nil
+}
+
 // PackageVariables returns a list of package variables for the thread,
 // optionally regexp filtered using regexp described in 'filter'.
 func (d *Debugger) PackageVariables(filter string, cfg proc.LoadConfig) ([]*proc.Variable, error) {
@@ -1460,8 +2137,10 @@ func (d *Debugger) LocalVariables(goid, frame, deferredCall int, cfg proc.LoadCo
 	return s.LocalVariables(cfg)
 }
 
-// FunctionArguments returns the arguments to the current function.
-func (d *Debugger) FunctionArguments(goid, frame, deferredCall int, cfg proc.LoadConfig) ([]*proc.Variable, error) {
+// LocalVariablesInterest returns the local variables in the given scope,
+// only the selector paths in paths are loaded with cfg, see
+// proc.EvalScope.LocalVariablesInterest.
+func (d *Debugger) LocalVariablesInterest(goid, frame, deferredCall int, cfg proc.LoadConfig, paths []string) ([]*proc.Variable, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
@@ -1469,11 +2148,75 @@ func (d *Debugger) FunctionArguments(goid, frame, deferredCall int, cfg proc.Loa
 	if err != nil {
 		return nil, err
 	}
-	return s.FunctionArguments(cfg)
+	return s.LocalVariablesInterest(cfg, paths)
 }
 
-// Function returns the current function.
-func (d *Debugger) Function(goid, frame, deferredCall int, cfg proc.LoadConfig) (*proc.Function, error) {
+// ValueProvenance returns where the current value of the variable called
+// name came from, in the specified scope.
+func (d *Debugger) ValueProvenance(goid, frame, deferredCall int, name string, cfg proc.LoadConfig) (*proc.ValueProvenance, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	return s.ValueProvenance(name, cfg)
+}
+
+// VariableReferences returns the instructions of the program that write to
+// the package variable expr, evaluated in the specified scope, or compute
+// its address. The text of the instructions is formatted using flavour.
+func (d *Debugger) VariableReferences(goid, frame, deferredCall int, expr string, flavour proc.AssemblyFlavour) ([]api.VariableReference, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.EvalVariable(expr, proc.LoadConfig{})
+	if err != nil {
+		return nil, err
+	}
+	refs, err := proc.PackageVariableReferences(d.target, v)
+	if err != nil {
+		return nil, err
+	}
+	r := make([]api.VariableReference, len(refs))
+	for i := range refs {
+		r[i] = api.ConvertVariableReference(refs[i], refs[i].Inst.Text(flavour, d.target.BinInfo()))
+	}
+	return r, nil
+}
+
+// FrameUnwindInfo returns the call frame information used to unwind the
+// specified stack frame.
+func (d *Debugger) FrameUnwindInfo(goid, frame int) (*proc.FrameUnwindInfo, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, goid, frame, 0)
+	if err != nil {
+		return nil, err
+	}
+	return s.FrameUnwindInfo(), nil
+}
+
+// FunctionArguments returns the arguments to the current function.
+func (d *Debugger) FunctionArguments(goid, frame, deferredCall int, cfg proc.LoadConfig) ([]*proc.Variable, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	return s.FunctionArguments(cfg)
+}
+
+// Function returns the current function.
+func (d *Debugger) Function(goid, frame, deferredCall int, cfg proc.LoadConfig) (*proc.Function, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
@@ -1494,7 +2237,39 @@ func (d *Debugger) EvalVariableInScope(goid, frame, deferredCall int, symbol str
 	if err != nil {
 		return nil, err
 	}
-	return s.EvalVariable(symbol, cfg)
+	v, err := s.EvalVariable(symbol, cfg)
+	d.exprs.add(api.EvalScope{GoroutineID: goid, Frame: frame, DeferredCall: deferredCall}, symbol, v, err)
+	return v, err
+}
+
+// EvalVariablesInScopes evaluates exprs[i] in the scope scopes[i] for
+// each expression and returns the result of each evaluation or its error.
+// Each distinct scope is only computed once.
+func (d *Debugger) EvalVariablesInScopes(scopes []api.EvalScope, exprs []string, cfg proc.LoadConfig) ([]*proc.Variable, []error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	type scopeResult struct {
+		scope *proc.EvalScope
+		err   error
+	}
+	converted := make(map[api.EvalScope]scopeResult)
+	vars := make([]*proc.Variable, len(exprs))
+	errs := make([]error, len(exprs))
+	for i := range exprs {
+		r, ok := converted[scopes[i]]
+		if !ok {
+			r.scope, r.err = proc.ConvertEvalScope(d.target, scopes[i].GoroutineID, scopes[i].Frame, scopes[i].DeferredCall)
+			converted[scopes[i]] = r
+		}
+		if r.err != nil {
+			errs[i] = r.err
+			continue
+		}
+		vars[i], errs[i] = r.scope.EvalVariable(exprs[i], cfg)
+		d.exprs.add(scopes[i], exprs[i], vars[i], errs[i])
+	}
+	return vars, errs
 }
 
 // LoadResliced will attempt to 'reslice' a map, array or slice so that the values
@@ -1505,12 +2280,51 @@ func (d *Debugger) LoadResliced(v *proc.Variable, start int, cfg proc.LoadConfig
 	return v.LoadResliced(start, cfg)
 }
 
+// EvalVariableChunk evaluates expr, a string, array or slice, in the
+// given scope and returns at most count of its elements starting at
+// offset.
+func (d *Debugger) EvalVariableChunk(goid, frame, deferredCall int, expr string, offset, count int64, cfg proc.LoadConfig) (*proc.ValueChunk, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, goid, frame, deferredCall)
+	if err != nil {
+		return nil, err
+	}
+	return s.EvalExpressionChunk(expr, offset, count, cfg)
+}
+
+// SaveVariable evaluates expr, a string, array or slice, in the given
+// scope and writes the content of its elements to the file at path.
+// It returns the number of bytes written.
+func (d *Debugger) SaveVariable(goid, frame, deferredCall int, expr, path string) (int64, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	s, err := proc.ConvertEvalScope(d.target, goid, frame, deferredCall)
+	if err != nil {
+		return 0, err
+	}
+	fh, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := s.WriteExpressionValue(fh, expr)
+	if cerr := fh.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
 // SetVariableInScope will set the value of the variable represented by
 // 'symbol' to the value given, in the given scope.
-func (d *Debugger) SetVariableInScope(goid, frame, deferredCall int, symbol, value string) error {
+func (d *Debugger) SetVariableInScope(goid, frame, deferredCall int, symbol, value, client string) error {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
+	d.target.SetWriteOrigin(client, fmt.Sprintf("set %s = %s", symbol, value))
+	defer d.target.SetWriteOrigin("", "")
+
 	s, err := proc.ConvertEvalScope(d.target, goid, frame, deferredCall)
 	if err != nil {
 		return err
@@ -1518,17 +2332,90 @@ func (d *Debugger) SetVariableInScope(goid, frame, deferredCall int, symbol, val
 	return s.SetVariable(symbol, value)
 }
 
-// Goroutines will return a list of goroutines in the target process.
+// Goroutines will return a list of goroutines in the target process,
+// sorted as described by proc.Target.SortGoroutines.
 func (d *Debugger) Goroutines(start, count int) ([]*proc.G, int, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
-	return proc.GoroutinesInfo(d.target, start, count)
+	gs, nextg, err := proc.GoroutinesInfo(d.target, start, count)
+	if err != nil {
+		return nil, nextg, err
+	}
+	d.target.SortGoroutines(gs)
+	return gs, nextg, nil
+}
+
+// SetGoroutineName names goroutine gid, see proc.Target.SetGoroutineName.
+func (d *Debugger) SetGoroutineName(gid int, name string) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.SetGoroutineName(gid, name)
+}
+
+// Annotations returns the names and notes assigned to goroutines and
+// addresses, see proc.Target.Annotations.
+func (d *Debugger) Annotations() []api.Annotation {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	anns := d.target.Annotations()
+	r := make([]api.Annotation, len(anns))
+	for i := range anns {
+		r[i] = api.ConvertAnnotation(anns[i])
+	}
+	return r
+}
+
+// SetAnnotation adds, replaces or removes an annotation, see
+// proc.Target.SetAnnotation.
+func (d *Debugger) SetAnnotation(a api.Annotation) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.SetAnnotation(proc.Annotation{
+		GoroutineID: a.GoroutineID,
+		Addr:        a.Addr,
+		Size:        a.Size,
+		Name:        a.Name,
+		Note:        a.Note,
+	})
+}
+
+// AddressAnnotation returns the annotation of the range of addresses
+// containing addr, see proc.Target.AddressAnnotation.
+func (d *Debugger) AddressAnnotation(addr uint64) (api.Annotation, bool) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	a, ok := d.target.AddressAnnotation(addr)
+	return api.ConvertAnnotation(a), ok
+}
+
+// TerminatedGoroutines returns the goroutines that terminated since the
+// previous stop, see proc.Target.TerminatedGoroutines.
+func (d *Debugger) TerminatedGoroutines() []api.TerminatedGoroutine {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	gids := d.target.TerminatedGoroutines()
+	r := make([]api.TerminatedGoroutine, len(gids))
+	for i, gid := range gids {
+		r[i] = api.TerminatedGoroutine{ID: gid, Name: d.target.GoroutineName(gid)}
+	}
+	return r
 }
 
 // FilterGoroutines returns the goroutines in gs that satisfy the specified filters.
-func (d *Debugger) FilterGoroutines(gs []*proc.G, filters []api.ListGoroutinesFilter) []*proc.G {
+func (d *Debugger) FilterGoroutines(gs []*proc.G, filters []api.ListGoroutinesFilter) ([]*proc.G, error) {
 	if len(filters) == 0 {
-		return gs
+		return gs, nil
+	}
+	regexps := make([]*regexp.Regexp, len(filters))
+	for i := range filters {
+		switch filters[i].Kind {
+		case api.GoroutineStack, api.GoroutineStackArgs:
+			var err error
+			regexps[i], err = regexp.Compile(filters[i].Arg)
+			if err != nil {
+				return nil, fmt.Errorf("invalid regular expression %q: %v", filters[i].Arg, err)
+			}
+		}
 	}
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
@@ -1536,7 +2423,7 @@ func (d *Debugger) FilterGoroutines(gs []*proc.G, filters []api.ListGoroutinesFi
 	for _, g := range gs {
 		ok := true
 		for i := range filters {
-			if !matchGoroutineFilter(d.target, g, &filters[i]) {
+			if !matchGoroutineFilter(d.target, g, &filters[i], regexps[i]) {
 				ok = false
 				break
 			}
@@ -1545,10 +2432,10 @@ func (d *Debugger) FilterGoroutines(gs []*proc.G, filters []api.ListGoroutinesFi
 			r = append(r, g)
 		}
 	}
-	return r
+	return r, nil
 }
 
-func matchGoroutineFilter(tgt *proc.Target, g *proc.G, filter *api.ListGoroutinesFilter) bool {
+func matchGoroutineFilter(tgt *proc.Target, g *proc.G, filter *api.ListGoroutinesFilter, re *regexp.Regexp) bool {
 	var val bool
 	switch filter.Kind {
 	default:
@@ -1574,6 +2461,10 @@ func matchGoroutineFilter(tgt *proc.Target, g *proc.G, filter *api.ListGoroutine
 		val = g.Thread != nil
 	case api.GoroutineUser:
 		val = !g.System(tgt)
+	case api.GoroutineStack:
+		val = matchGoroutineStackFilter(tgt, g, re, false)
+	case api.GoroutineStackArgs:
+		val = matchGoroutineStackFilter(tgt, g, re, true)
 	}
 	if filter.Negated {
 		val = !val
@@ -1581,6 +2472,37 @@ func matchGoroutineFilter(tgt *proc.Target, g *proc.G, filter *api.ListGoroutine
 	return val
 }
 
+const goroutineStackFilterDepth = 50
+
+var goroutineStackFilterLoadConfig = proc.LoadConfig{FollowPointers: false, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 16, MaxStructFields: -1}
+
+// matchGoroutineStackFilter returns true if any frame of the stacktrace of
+// g, formatted as 'filename:lineno in function', matches re. If args is
+// set the arguments of each frame, formatted as 'name=value', are also
+// matched against re.
+func matchGoroutineStackFilter(tgt *proc.Target, g *proc.G, re *regexp.Regexp, args bool) bool {
+	frames, _ := g.Stacktrace(goroutineStackFilterDepth, 0)
+	for i := range frames {
+		if re.MatchString(formatLoc(frames[i].Call)) {
+			return true
+		}
+		if !args || frames[i].Current.Fn == nil {
+			continue
+		}
+		scope := proc.FrameToScope(tgt, tgt.BinInfo(), tgt.Memory(), g, frames[i:]...)
+		vars, err := scope.FunctionArguments(goroutineStackFilterLoadConfig)
+		if err != nil {
+			continue
+		}
+		for _, v := range vars {
+			if re.MatchString(v.Name + "=" + api.ConvertVar(v).SinglelineString()) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func matchGoroutineLocFilter(loc proc.Location, arg string) bool {
 	return strings.Contains(formatLoc(loc), arg)
 }
@@ -1603,6 +2525,10 @@ func (d *Debugger) GroupGoroutines(gs []*proc.G, group *api.GoroutineGroupingOpt
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
+	if group.GroupBy == api.GoroutineStack {
+		return groupGoroutinesByStack(gs, group)
+	}
+
 	groupMembers := map[string][]*proc.G{}
 	totals := map[string]int{}
 
@@ -1650,6 +2576,34 @@ func (d *Debugger) GroupGoroutines(gs []*proc.G, group *api.GoroutineGroupingOpt
 	return gsout, groups, tooManyGroups
 }
 
+// groupGoroutinesByStack groups the goroutines in gs that have identical
+// stacks, the name of each group is its stacktrace with one frame,
+// formatted as 'filename:lineno in function', per line. Groups are
+// sorted by decreasing size.
+func groupGoroutinesByStack(gs []*proc.G, group *api.GoroutineGroupingOptions) ([]*proc.G, []api.GoroutineGroup, bool) {
+	stackGroups := proc.GroupGoroutinesByStack(gs, goroutineStackFilterDepth)
+	tooManyGroups := false
+	gsout := []*proc.G{}
+	groups := []api.GoroutineGroup{}
+	for _, sg := range stackGroups {
+		if group.MaxGroups > 0 && len(groups) >= group.MaxGroups {
+			tooManyGroups = true
+			break
+		}
+		frames := make([]string, len(sg.Stack))
+		for i := range sg.Stack {
+			frames[i] = formatLoc(sg.Stack[i].Call)
+		}
+		members := sg.Goroutines
+		if len(members) > group.MaxGroupMembers {
+			members = members[:group.MaxGroupMembers]
+		}
+		groups = append(groups, api.GoroutineGroup{Name: strings.Join(frames, "\n"), Offset: len(gsout), Count: len(members), Total: len(sg.Goroutines)})
+		gsout = append(gsout, members...)
+	}
+	return gsout, groups, tooManyGroups
+}
+
 // Stacktrace returns a list of Stackframes for the given goroutine. The
 // length of the returned list will be min(stack_len, depth).
 // If 'full' is true, then local vars, function args, etc will be returned as well.
@@ -1673,6 +2627,130 @@ func (d *Debugger) Stacktrace(goroutineID, depth int, opts api.StacktraceOptions
 	}
 }
 
+// GoroutineProfile returns the stacktraces of all goroutines, up to depth
+// frames each, encoded in the specified format.
+// Goroutines with identical stacks and labels are aggregated in a single
+// sample.
+func (d *Debugger) GoroutineProfile(depth int, format api.GoroutineProfileFormat) ([]byte, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return nil, err
+	}
+
+	gs, _, err := proc.GoroutinesInfo(d.target, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	samples := make([]pprofwriter.Sample, 0, len(gs))
+	for _, g := range gs {
+		frames, err := g.Stacktrace(depth, 0)
+		if err != nil {
+			d.log.Debugf("could not read stacktrace of goroutine %d: %v", g.ID, err)
+		}
+		stack := make([]pprofwriter.Frame, len(frames))
+		for i := range frames {
+			stack[i] = pprofwriter.Frame{PC: frames[i].Call.PC, File: frames[i].Call.File, Line: frames[i].Call.Line}
+			if frames[i].Call.Fn != nil {
+				stack[i].Function = frames[i].Call.Fn.Name
+			}
+		}
+		samples = append(samples, pprofwriter.Sample{Stack: stack, Value: 1, Labels: g.Labels()})
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case api.GoroutineProfileProto:
+		err = pprofwriter.WriteProto(&buf, "goroutine", "count", samples)
+	case api.GoroutineProfileFolded:
+		err = pprofwriter.WriteFolded(&buf, samples)
+	default:
+		err = fmt.Errorf("unknown profile format %d", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SchedState returns the state of the Go scheduler of the target: its
+// Ps with their run queues, its Ms and the global run queue.
+func (d *Debugger) SchedState() (*proc.SchedState, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return proc.GetSchedState(d.target)
+}
+
+// ContentionProfile returns the mutex or block profile collected by the
+// runtime of the target, limited to the max most contended call sites
+// (all of them if max is 0).
+func (d *Debugger) ContentionProfile(kind api.ContentionProfileKind, max int) (*api.ContentionProfile, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	var pkind proc.ContentionProfileKind
+	switch kind {
+	case api.MutexContentionProfile:
+		pkind = proc.MutexProfile
+	case api.BlockContentionProfile:
+		pkind = proc.BlockProfile
+	default:
+		return nil, fmt.Errorf("unknown contention profile kind %d", kind)
+	}
+
+	prof, err := proc.GetContentionProfile(d.target, pkind)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &api.ContentionProfile{Rate: prof.Rate, Total: len(prof.Records)}
+	records := prof.Records
+	if max > 0 && len(records) > max {
+		records = records[:max]
+	}
+	bi := d.target.BinInfo()
+	r.Records = make([]api.ContentionRecord, len(records))
+	for i, rec := range records {
+		r.Records[i] = api.ContentionRecord{Count: rec.Count, Cycles: rec.Cycles, Stack: make([]api.Location, len(rec.Stack))}
+		for j, pc := range rec.Stack {
+			file, line, fn := bi.PCToLine(pc)
+			r.Records[i].Stack[j] = api.ConvertLocation(proc.Location{PC: pc, File: file, Line: line, Fn: fn})
+		}
+	}
+	return r, nil
+}
+
+// BreakpointStacks returns the stacks recorded by the breakpoint with the
+// given ID, which must have AggregateStacks set, the most frequent first.
+func (d *Debugger) BreakpointStacks(id int) ([]api.StackRecord, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	bps := d.findBreakpoint(id)
+	if len(bps) == 0 {
+		return nil, fmt.Errorf("no breakpoint with id %d", id)
+	}
+	if !bps[0].AggregateStacks {
+		return nil, fmt.Errorf("breakpoint %d does not aggregate stacks", id)
+	}
+	var records []proc.StackRecord
+	for _, bp := range bps {
+		records = append(records, bp.StackRecords()...)
+	}
+	proc.SortStackRecords(records)
+
+	r := make([]api.StackRecord, len(records))
+	for i, rec := range records {
+		r[i] = api.StackRecord{Count: rec.Count, Stack: make([]api.Location, len(rec.Stack))}
+		for j := range rec.Stack {
+			r[i].Stack[j] = api.ConvertLocation(rec.Stack[j])
+		}
+	}
+	return r, nil
+}
+
 // Ancestors returns the stacktraces for the ancestors of a goroutine.
 func (d *Debugger) Ancestors(goroutineID, numAncestors, depth int) ([]api.Ancestor, error) {
 	d.targetMutex.Lock()
@@ -1735,7 +2813,11 @@ func (d *Debugger) convertStacktrace(rawlocs []proc.Stackframe, cfg *proc.LoadCo
 
 			Defers: d.convertDefers(rawlocs[i].Defers),
 
-			Bottom: rawlocs[i].Bottom,
+			Bottom:      rawlocs[i].Bottom,
+			Inlined:     rawlocs[i].Inlined,
+			Approximate: rawlocs[i].Approximate,
+			Truncated:   rawlocs[i].Truncated,
+			Remaining:   rawlocs[i].Remaining,
 		}
 		if rawlocs[i].Err != nil {
 			frame.Err = rawlocs[i].Err.Error()
@@ -1761,15 +2843,68 @@ func (d *Debugger) convertStacktrace(rawlocs []proc.Stackframe, cfg *proc.LoadCo
 	return locations, nil
 }
 
+// CancelCall abandons the function call in progress on the specified
+// goroutine, or all function calls in progress if goroutineID is negative.
+func (d *Debugger) CancelCall(goroutineID int) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return err
+	}
+
+	return d.target.CancelCall(goroutineID)
+}
+
+// Defers returns the chain of deferred calls of the specified goroutine,
+// starting with the one that will run first, with their arguments loaded
+// using cfg.
+func (d *Debugger) Defers(goroutineID int, cfg proc.LoadConfig) ([]api.Defer, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return nil, err
+	}
+
+	g, err := proc.FindGoroutine(d.target, goroutineID)
+	if err != nil {
+		return nil, err
+	}
+	if g == nil {
+		return nil, errors.New("no selected goroutine")
+	}
+
+	defers := g.DeferChain()
+	r := d.convertDefers(defers)
+	for i := range defers {
+		if defers[i].Unreadable != nil {
+			continue
+		}
+		args, err := defers[i].Arguments(d.target, d.target.CurrentThread(), cfg)
+		if err != nil {
+			r[i].Unreadable = fmt.Sprintf("could not read arguments: %v", err)
+			continue
+		}
+		r[i].Arguments = api.ConvertVars(args)
+	}
+	return r, nil
+}
+
 func (d *Debugger) convertDefers(defers []*proc.Defer) []api.Defer {
 	r := make([]api.Defer, len(defers))
 	for i := range defers {
 		ddf, ddl, ddfn := defers[i].DeferredFunc(d.target)
 		drf, drl, drfn := d.target.BinInfo().PCToLine(defers[i].DeferPC)
 
+		var ddpc uint64
+		if ddfn != nil {
+			ddpc = ddfn.Entry
+		}
+
 		r[i] = api.Defer{
 			DeferredLoc: api.ConvertLocation(proc.Location{
-				PC:   ddfn.Entry,
+				PC:   ddpc,
 				File: ddf,
 				Line: ddl,
 				Fn:   ddfn,
@@ -1892,6 +3027,24 @@ func (d *Debugger) Disassemble(goroutineID int, addr1, addr2 uint64) ([]proc.Asm
 	return proc.Disassemble(d.target.Memory(), regs, d.target.Breakpoints(), d.target.BinInfo(), addr1, addr2)
 }
 
+// PseudoSource returns the pseudo-source listing of the function
+// containing addr, see proc.PseudoSource.
+func (d *Debugger) PseudoSource(addr uint64) (string, []proc.PseudoSourceLine, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return "", nil, err
+	}
+
+	fn := d.target.BinInfo().PCToFunc(addr)
+	if fn == nil {
+		return "", nil, fmt.Errorf("address %#x does not belong to any function", addr)
+	}
+	lines, err := proc.PseudoSource(d.target.Memory(), d.target.Breakpoints(), d.target.BinInfo(), fn)
+	return fn.Name, lines, err
+}
+
 func (d *Debugger) AsmInstructionText(inst *proc.AsmInstruction, flavour proc.AssemblyFlavour) string {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
@@ -1952,6 +3105,30 @@ func (d *Debugger) ListDynamicLibraries() []*proc.Image {
 
 }
 
+// ListImages returns the executable file followed by the dynamic libraries
+// loaded by the target.
+func (d *Debugger) ListImages() []*proc.Image {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return append([]*proc.Image(nil), d.target.BinInfo().Images...)
+}
+
+// RegisterBuiltin registers a custom builtin function that can be used in
+// expressions, see proc.Target.RegisterBuiltin. Custom builtins are kept
+// when the target is restarted.
+func (d *Debugger) RegisterBuiltin(name string, b proc.CustomBuiltin) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.RegisterBuiltin(name, b)
+}
+
+// UnregisterBuiltin removes a custom builtin registered with RegisterBuiltin.
+func (d *Debugger) UnregisterBuiltin(name string) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.UnregisterBuiltin(name)
+}
+
 // ExamineMemory returns the raw memory stored at the given address.
 // The amount of data to be read is specified by length.
 // This function will return an error if it reads less than `length` bytes.
@@ -1971,6 +3148,42 @@ func (d *Debugger) ExamineMemory(address uint64, length int) ([]byte, error) {
 	return data, nil
 }
 
+// WriteMemory writes data to the memory of the target at address and
+// returns the number of bytes written.
+func (d *Debugger) WriteMemory(address uint64, data []byte, client string) (int, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	d.target.SetWriteOrigin(client, "write memory")
+	defer d.target.SetWriteOrigin("", "")
+	return d.target.WriteMemory(address, data)
+}
+
+// MemoryWrites returns the writes made by the debugger to the memory of
+// the target.
+func (d *Debugger) MemoryWrites() []api.MemoryWrite {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	writes := d.target.MemoryWrites()
+	r := make([]api.MemoryWrite, len(writes))
+	for i := range writes {
+		r[i] = api.ConvertMemoryWrite(&writes[i])
+	}
+	return r
+}
+
+// UndoMemoryWrite restores the memory overwritten by the write with the
+// given ID, if it was not changed since.
+func (d *Debugger) UndoMemoryWrite(id int, client string) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	d.target.SetWriteOrigin(client, "")
+	defer d.target.SetWriteOrigin("", "")
+	return d.target.UndoMemoryWrite(id)
+}
+
 func (d *Debugger) GetVersion(out *api.GetVersionOut) error {
 	if d.config.CoreFile != "" {
 		if d.config.Backend == "rr" {
@@ -2061,6 +3274,7 @@ func (d *Debugger) DumpStart(dest string) error {
 		d.targetMutex.Unlock()
 		return err
 	}
+	d.dumpDest = d.addArtifact(dest)
 
 	d.dumpState.Dumping = true
 	d.dumpState.AllDone = false
@@ -2101,6 +3315,115 @@ func (d *Debugger) DumpWait(wait time.Duration) *proc.DumpState {
 	return &d.dumpState
 }
 
+// MaxArtifactChunk is the maximum number of bytes returned by a call to
+// ReadArtifact.
+const MaxArtifactChunk = 4 << 20
+
+// addArtifact records that path was created by the debugger, returns
+// its absolute path.
+func (d *Debugger) addArtifact(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	d.artifactsMutex.Lock()
+	defer d.artifactsMutex.Unlock()
+	for _, artifact := range d.artifacts {
+		if artifact == path {
+			return path
+		}
+	}
+	d.artifacts = append(d.artifacts, path)
+	return path
+}
+
+// Artifacts returns the files created by the debugger that still exist,
+// with their size and SHA-256 checksum. Files that are still being written
+// are not returned.
+func (d *Debugger) Artifacts() ([]api.Artifact, error) {
+	d.artifactsMutex.Lock()
+	paths := append([]string(nil), d.artifacts...)
+	d.artifactsMutex.Unlock()
+	r := []api.Artifact{}
+	for _, path := range paths {
+		if d.artifactInProgress(path) {
+			continue
+		}
+		artifact, err := artifactInfo(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		r = append(r, artifact)
+	}
+	return r, nil
+}
+
+// artifactInProgress returns true if path is the destination of a core
+// dump in progress.
+func (d *Debugger) artifactInProgress(path string) bool {
+	d.dumpState.Mutex.Lock()
+	defer d.dumpState.Mutex.Unlock()
+	return d.dumpState.Dumping && d.dumpDest == path
+}
+
+func artifactInfo(path string) (api.Artifact, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return api.Artifact{}, err
+	}
+	defer fh.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, fh)
+	if err != nil {
+		return api.Artifact{}, err
+	}
+	return api.Artifact{Path: path, Size: size, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// ReadArtifact reads at most length bytes, and at most MaxArtifactChunk,
+// starting at offset from path, which must be a file returned by
+// Artifacts. Returns the data and the size of the file.
+func (d *Debugger) ReadArtifact(path string, offset int64, length int) ([]byte, int64, error) {
+	known := false
+	d.artifactsMutex.Lock()
+	for _, artifact := range d.artifacts {
+		if artifact == path {
+			known = true
+			break
+		}
+	}
+	d.artifactsMutex.Unlock()
+	if !known {
+		return nil, 0, fmt.Errorf("%s is not a file created by the debugger", path)
+	}
+	if d.artifactInProgress(path) {
+		return nil, 0, fmt.Errorf("%s is still being written", path)
+	}
+	if offset < 0 || length < 0 {
+		return nil, 0, errors.New("negative offset or length")
+	}
+	if length > MaxArtifactChunk {
+		length = MaxArtifactChunk
+	}
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer fh.Close()
+	fi, err := fh.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	buf := make([]byte, length)
+	n, err := fh.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	return buf[:n], fi.Size(), nil
+}
+
 // DumpCancel canels a dump in progress
 func (d *Debugger) DumpCancel() error {
 	d.dumpState.Mutex.Lock()
@@ -1,6 +1,7 @@
 package debugger
 
 import (
+	"errors"
 	"fmt"
 	sys "golang.org/x/sys/unix"
 )
@@ -12,3 +13,15 @@ func attachErrorMessage(pid int, err error) error {
 func stopProcess(pid int) error {
 	return sys.Kill(pid, sys.SIGSTOP)
 }
+
+func findProcess(name string) (int, error) {
+	return 0, errors.New("waiting for a process is not supported on FreeBSD")
+}
+
+func processCmdline(pid int) []string {
+	return nil
+}
+
+func descendants(pid int) (map[int]int, error) {
+	return nil, errors.New("attaching to child processes is not supported on FreeBSD")
+}
@@ -1,9 +1,13 @@
 package debugger
 
 import (
+	"bytes"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"syscall"
 
 	sys "golang.org/x/sys/unix"
@@ -34,3 +38,84 @@ func attachErrorMessage(pid int, err error) error {
 func stopProcess(pid int) error {
 	return sys.Kill(pid, sys.SIGSTOP)
 }
+
+// findProcess returns the pid of a process matching name, or 0 if there
+// is none. The processes are searched in order of pid.
+func findProcess(name string) (int, error) {
+	fis, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return 0, err
+	}
+	pids := make([]int, 0, len(fis))
+	for _, fi := range fis {
+		if pid, err := strconv.Atoi(fi.Name()); err == nil && fi.IsDir() && pid != os.Getpid() {
+			pids = append(pids, pid)
+		}
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		cmdline := processCmdline(pid)
+		if cmdline == nil {
+			// the process exited or is a kernel thread
+			continue
+		}
+		if matchProcess(cmdline, name) {
+			return pid, nil
+		}
+	}
+	return 0, nil
+}
+
+// processCmdline returns the command line of process pid, nil if it
+// exited or is a kernel thread.
+func processCmdline(pid int) []string {
+	buf, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil || len(buf) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(buf), "\x00"), "\x00")
+}
+
+// descendants returns the descendants of process pid, mapping the pid of
+// each one to the pid of its parent.
+func descendants(pid int) (map[int]int, error) {
+	fis, err := ioutil.ReadDir("/proc")
+	if err != nil {
+		return nil, err
+	}
+	ppids := make(map[int]int)
+	for _, fi := range fis {
+		p, err := strconv.Atoi(fi.Name())
+		if err != nil || !fi.IsDir() {
+			continue
+		}
+		stat, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/stat", p))
+		if err != nil {
+			// the process exited
+			continue
+		}
+		// the fields following the command name, which is between parenthesis,
+		// are the state and the pid of the parent
+		fields := strings.Fields(string(stat[bytes.LastIndexByte(stat, ')')+1:]))
+		if len(fields) < 2 {
+			continue
+		}
+		if ppid, err := strconv.Atoi(fields[1]); err == nil {
+			ppids[p] = ppid
+		}
+	}
+	r := make(map[int]int)
+	for found := true; found; {
+		found = false
+		for p, ppid := range ppids {
+			if _, ok := r[p]; ok {
+				continue
+			}
+			if _, ok := r[ppid]; ok || ppid == pid {
+				r[p] = ppid
+				found = true
+			}
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,27 @@
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	sys "golang.org/x/sys/unix"
+)
+
+func attachErrorMessage(pid int, err error) error {
+	return fmt.Errorf("could not attach to pid %d: %s", pid, err)
+}
+
+func stopProcess(pid int) error {
+	return sys.Kill(pid, sys.SIGSTOP)
+}
+
+func findProcess(name string) (int, error) {
+	return 0, errors.New("waiting for a process is not supported on OpenBSD")
+}
+
+func processCmdline(pid int) []string {
+	return nil
+}
+
+func descendants(pid int) (map[int]int, error) {
+	return nil, errors.New("attaching to child processes is not supported on OpenBSD")
+}
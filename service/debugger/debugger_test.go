@@ -1,13 +1,21 @@
 package debugger
 
 import (
+	"bufio"
 	"fmt"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/go-delve/delve/pkg/gobuild"
+	"github.com/go-delve/delve/pkg/proc"
 	protest "github.com/go-delve/delve/pkg/proc/test"
 	"github.com/go-delve/delve/service/api"
 )
@@ -47,6 +55,7 @@ func TestDebugger_LaunchInvalidFormat(t *testing.T) {
 		"darwin":  "linux",
 		"windows": "linux",
 		"freebsd": "windows",
+		"openbsd": "windows",
 		"linux":   "windows",
 	}
 	if runtime.GOARCH == "arm64" && runtime.GOOS == "linux" {
@@ -68,3 +77,336 @@ func TestDebugger_LaunchInvalidFormat(t *testing.T) {
 		t.Fatalf("expected error \"%s\" got \"%v\"", api.ErrNotExecutable, err)
 	}
 }
+
+func TestLoopVarDeclaredAt(t *testing.T) {
+	const src = `package main
+
+func main() {
+	for i := 0; i < 10; i++ {
+	}
+	for _, v := range []int{1, 2} {
+	}
+	x := 0
+	_ = x
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name string
+		line int
+		tgt  bool
+	}{
+		{"i", 4, true},
+		{"i", 0, true},
+		{"v", 6, true},
+		{"v", 4, false},
+		{"x", 8, false},
+		{"x", 0, false},
+	} {
+		if got := loopVarDeclaredAt(fset, file, tc.name, tc.line); got != tc.tgt {
+			t.Errorf("loopVarDeclaredAt(%q, %d) = %v, expected %v", tc.name, tc.line, got, tc.tgt)
+		}
+	}
+}
+
+func TestNilMapWritePatterns(t *testing.T) {
+	const src = `package main
+
+func main() {
+	var m map[string]int
+	m["a"] = 1
+	m["b"]++
+}
+`
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "main.go", src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := []*proc.Variable{{Name: "m", Kind: reflect.Map}}
+	for _, line := range []int{5, 6} {
+		r := nilMapWritePatterns(fset, file, line, vars)
+		if len(r) != 1 || r[0].Kind != api.BugPatternNilMapWrite {
+			t.Errorf("line %d: unexpected result %v", line, r)
+		}
+	}
+	if r := nilMapWritePatterns(fset, file, 4, vars); len(r) != 0 {
+		t.Errorf("line 4: unexpected result %v", r)
+	}
+	vars[0].Base = 0x1000
+	if r := nilMapWritePatterns(fset, file, 5, vars); len(r) != 0 {
+		t.Errorf("non-nil map: unexpected result %v", r)
+	}
+}
+
+func TestMatchProcess(t *testing.T) {
+	for _, tc := range []struct {
+		cmdline []string
+		name    string
+		want    bool
+	}{
+		{[]string{"/usr/bin/myserver", "-port", "80"}, "myserver", true},
+		{[]string{"/usr/bin/myserver", "-port", "80"}, "/usr/bin/myserver", true},
+		{[]string{"/usr/bin/myserver", "-port", "80"}, "/usr/bin/myserver -port 80", true},
+		{[]string{"/usr/bin/myserver", "-port", "80"}, "myserv", false},
+		{[]string{"/usr/bin/myserver2"}, "myserver", false},
+		{nil, "myserver", false},
+	} {
+		if got := matchProcess(tc.cmdline, tc.name); got != tc.want {
+			t.Errorf("matchProcess(%q, %q) = %v, want %v", tc.cmdline, tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestFindProcess(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("not supported")
+	}
+	cmd := exec.Command("sleep", "37.125")
+	if err := cmd.Start(); err != nil {
+		t.Skip(err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	pid, err := findProcess("sleep 37.125")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid != cmd.Process.Pid {
+		t.Errorf("findProcess: got pid %d, want %d", pid, cmd.Process.Pid)
+	}
+}
+
+// startLoopprog starts the loopprog fixture and returns a channel that
+// receives a value when it prints something.
+func startLoopprog(t *testing.T) (*exec.Cmd, <-chan struct{}) {
+	exepath := filepath.Join(t.TempDir(), "loopprog")
+	if err := gobuild.GoBuild("loopprog", []string{filepath.Join(protest.FindFixturesDir(), "loopprog.go")}, fmt.Sprintf("-o %s", exepath)); err != nil {
+		t.Fatalf("go build error %v", err)
+	}
+	cmd := exec.Command(exepath)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	output := make(chan struct{}, 1)
+	go func() {
+		scan := bufio.NewScanner(stdout)
+		for scan.Scan() {
+			select {
+			case output <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	<-output
+	return cmd, output
+}
+
+// waitOutput returns true if the fixture started by startLoopprog prints
+// something new, after any output already buffered is discarded.
+func waitOutput(output <-chan struct{}) bool {
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-output:
+	default:
+	}
+	select {
+	case <-output:
+		return true
+	case <-time.After(10 * time.Second):
+		return false
+	}
+}
+
+func TestAutoDetach(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("not supported")
+	}
+	cmd, output := startLoopprog(t)
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+
+	d, err := New(&Config{AttachPid: cmd.Process.Pid, Backend: "default", AutoDetach: 100 * time.Millisecond}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var detached bool
+	for _, ev := range d.WaitForEvents(0, 10*time.Second) {
+		if ev.Kind == api.EventDetached {
+			detached = true
+		}
+	}
+	if !detached {
+		t.Fatal("no detached event after the auto-detach deadline")
+	}
+	if ok, _ := d.target.Valid(); ok {
+		t.Error("target still valid after the detached event")
+	}
+	if !waitOutput(output) {
+		t.Error("target not resumed after the detach")
+	}
+}
+
+func TestReadArtifact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "core")
+	if err := ioutil.WriteFile(path, []byte("0123456789"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	d := &Debugger{}
+	if _, _, err := d.ReadArtifact(path, 0, 10); err == nil {
+		t.Errorf("ReadArtifact of a file not created by the debugger should fail")
+	}
+	d.addArtifact(path)
+
+	artifacts, err := d.Artifacts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := api.Artifact{Path: path, Size: 10, SHA256: "84d89877f0d4041efb6bf91a16f0248f2fd573e6af05c19f96bedb9f882f7882"}
+	if len(artifacts) != 1 || artifacts[0] != want {
+		t.Errorf("Artifacts: got %#v, want %#v", artifacts, want)
+	}
+
+	for _, tc := range []struct {
+		offset int64
+		length int
+		want   string
+	}{
+		{0, 4, "0123"},
+		{4, 100, "456789"},
+		{10, 4, ""},
+	} {
+		data, size, err := d.ReadArtifact(path, tc.offset, tc.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != tc.want || size != 10 {
+			t.Errorf("ReadArtifact(%d, %d): got %q %d, want %q 10", tc.offset, tc.length, data, size, tc.want)
+		}
+	}
+
+	os.Remove(path)
+	if artifacts, _ := d.Artifacts(); len(artifacts) != 0 {
+		t.Errorf("deleted artifact still listed: %#v", artifacts)
+	}
+}
+
+func TestParseHitCondition(t *testing.T) {
+	for _, tc := range []struct {
+		hitCond string
+		op      token.Token
+		val     int
+	}{
+		{"3", token.EQL, 3},
+		{"== 3", token.EQL, 3},
+		{">= 10", token.GEQ, 10},
+		{">=   10", token.GEQ, 10},
+		{"% 5", token.REM, 5},
+		{"%5", token.REM, 5},
+		{" != 2 ", token.NEQ, 2},
+		{"< 8", token.LSS, 8},
+		{"= 2", token.ILLEGAL, 0},
+		{"=> 2", token.ILLEGAL, 0},
+		{"abc 5", token.ILLEGAL, 0},
+		{"> 5 6", token.ILLEGAL, 0},
+		{">", token.ILLEGAL, 0},
+	} {
+		op, val, err := parseHitCondition(tc.hitCond)
+		if tc.op == token.ILLEGAL {
+			if err == nil {
+				t.Errorf("%q: expected error, got %v %d", tc.hitCond, op, val)
+			}
+			continue
+		}
+		if err != nil || op != tc.op || val != tc.val {
+			t.Errorf("%q: got %v %d %v, expected %v %d", tc.hitCond, op, val, err, tc.op, tc.val)
+		}
+	}
+}
+
+func TestOutputBuffer(t *testing.T) {
+	var b outputBuffer
+	b.add(api.StdoutStream, []byte("hello "))
+	b.add(api.StderrStream, []byte("world\n"))
+
+	out := b.read(3, 5)
+	expected := []api.OutputChunk{{Offset: 3, Stream: api.StdoutStream, Data: "lo "}, {Offset: 6, Stream: api.StderrStream, Data: "wo"}}
+	if !reflect.DeepEqual(out.Chunks, expected) || out.Start != 0 || out.End != 12 {
+		t.Errorf("read(3, 5): got %#v", out)
+	}
+	if out := b.read(-6, 0); len(out.Chunks) != 1 || out.Chunks[0].Data != "world\n" {
+		t.Errorf("read(-6, 0): got %#v", out)
+	}
+	if out := b.read(12, 0); len(out.Chunks) != 0 {
+		t.Errorf("read(12, 0): got %#v", out)
+	}
+
+	// Filling the buffer discards the oldest output.
+	b.add(api.StdoutStream, make([]byte, maxOutputBufferSize-4))
+	out = b.read(0, 4)
+	expected = []api.OutputChunk{{Offset: 8, Stream: api.StderrStream, Data: "rld\n"}}
+	if !reflect.DeepEqual(out.Chunks, expected) || out.Start != 8 || out.End != maxOutputBufferSize+8 {
+		t.Errorf("read after overflow: got %#v", out)
+	}
+}
+
+func TestEvalQueue(t *testing.T) {
+	var q evalQueue
+	release, err := q.acquire("a", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	served := make(chan string, 3)
+	enqueue := func(client string) {
+		n := len(q.waiting)
+		go func() {
+			release, err := q.acquire(client, 2)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			served <- client
+			release()
+		}()
+		for {
+			q.mu.Lock()
+			m := len(q.waiting)
+			q.mu.Unlock()
+			if m > n {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	enqueue("a")
+	enqueue("a")
+	enqueue("b")
+	if _, err := q.acquire("a", 2); err != ErrEvalQueueFull {
+		t.Errorf("expected ErrEvalQueueFull, got %v", err)
+	}
+
+	// b was served less recently than a and goes first.
+	release()
+	var order []string
+	for i := 0; i < 3; i++ {
+		order = append(order, <-served)
+	}
+	if !reflect.DeepEqual(order, []string{"b", "a", "a"}) {
+		t.Errorf("wrong order %v", order)
+	}
+}
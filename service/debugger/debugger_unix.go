@@ -7,6 +7,7 @@ import (
 	"debug/macho"
 	"os"
 	"runtime"
+	"syscall"
 
 	"github.com/go-delve/delve/service/api"
 )
@@ -30,7 +31,7 @@ func verifyBinaryFormat(exePath string) error {
 	switch runtime.GOOS {
 	case "darwin":
 		_, err = macho.NewFile(f)
-	case "linux", "freebsd":
+	case "linux", "freebsd", "openbsd":
 		_, err = elf.NewFile(f)
 	default:
 		panic("attempting to open file Delve cannot parse")
@@ -40,3 +41,7 @@ func verifyBinaryFormat(exePath string) error {
 	}
 	return nil
 }
+
+func mkfifo(path string) error {
+	return syscall.Mkfifo(path, 0600)
+}
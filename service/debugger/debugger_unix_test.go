@@ -29,6 +29,7 @@ func TestDebugger_LaunchNoExecutablePerm(t *testing.T) {
 		"darwin":  "linux",
 		"windows": "linux",
 		"freebsd": "windows",
+		"openbsd": "windows",
 		"linux":   "windows",
 	}
 	if runtime.GOARCH == "arm64" && runtime.GOOS == "linux" {
@@ -2,6 +2,7 @@ package debugger
 
 import (
 	"debug/pe"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -40,3 +41,19 @@ func verifyBinaryFormat(exePath string) error {
 	}
 	return nil
 }
+
+func findProcess(name string) (int, error) {
+	return 0, errors.New("waiting for a process is not supported on Windows")
+}
+
+func processCmdline(pid int) []string {
+	return nil
+}
+
+func descendants(pid int) (map[int]int, error) {
+	return nil, errors.New("attaching to child processes is not supported on Windows")
+}
+
+func mkfifo(path string) error {
+	return errors.New("capturing the output of the target is not supported on windows")
+}
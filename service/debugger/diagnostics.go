@@ -0,0 +1,148 @@
+package debugger
+
+import (
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/go-delve/delve/pkg/proc"
+	"github.com/go-delve/delve/pkg/version"
+	"github.com/go-delve/delve/service/api"
+)
+
+const (
+	// maxDiagnosticsExprs is the number of evaluated expressions
+	// remembered for diagnostic reports.
+	maxDiagnosticsExprs = 32
+	// maxDiagnosticsFrames is the number of stack frames included in
+	// diagnostic reports.
+	maxDiagnosticsFrames = 20
+)
+
+// exprLog remembers the expressions most recently evaluated.
+type exprLog struct {
+	mu    sync.Mutex
+	exprs []api.DiagnosticsExpression
+}
+
+// add records the evaluation of expr in scope, with result v or error err.
+func (l *exprLog) add(scope api.EvalScope, expr string, v *proc.Variable, err error) {
+	e := api.DiagnosticsExpression{Expr: expr, Scope: scope}
+	if err != nil {
+		e.Err = err.Error()
+	} else if v != nil {
+		e.Type = v.TypeString()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.exprs) >= maxDiagnosticsExprs {
+		l.exprs = append(l.exprs[:0], l.exprs[len(l.exprs)-maxDiagnosticsExprs+1:]...)
+	}
+	l.exprs = append(l.exprs, e)
+}
+
+func (l *exprLog) recent() []api.DiagnosticsExpression {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]api.DiagnosticsExpression(nil), l.exprs...)
+}
+
+// Diagnostics returns a report of the internal state of the debugger at
+// the current stop: the raw registers of the current thread, the call
+// frame information of the top frames, the expressions recently evaluated
+// and the packets recently exchanged with the debugging stub.
+// Failures to collect parts of the report are recorded in its Errors
+// field.
+func (d *Debugger) Diagnostics() (*api.Diagnostics, error) {
+	r := &api.Diagnostics{GOOS: runtime.GOOS, GOARCH: runtime.GOARCH}
+	if err := d.GetVersion(&r.Version); err != nil {
+		return nil, err
+	}
+	r.Version.DelveVersion = version.DelveVersion.String()
+
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+
+	if _, err := d.target.Valid(); err != nil {
+		return nil, err
+	}
+
+	adderr := func(err error) {
+		r.Errors = append(r.Errors, err.Error())
+	}
+
+	bi := d.target.BinInfo()
+	thread := d.target.CurrentThread()
+	r.ThreadID = thread.ThreadID()
+	if regs, err := thread.Registers(); err == nil {
+		r.Registers = api.ConvertRegisters(bi.Arch.RegistersToDwarfRegisters(0, regs), bi.Arch.DwarfRegisterToString, false)
+	} else {
+		adderr(err)
+	}
+
+	g := d.target.SelectedGoroutine()
+	var frames []proc.Stackframe
+	var err error
+	if g != nil {
+		r.GoroutineID = g.ID
+		frames, err = g.Stacktrace(maxDiagnosticsFrames, 0)
+	} else {
+		frames, err = proc.ThreadStacktrace(thread, maxDiagnosticsFrames)
+	}
+	if err != nil {
+		adderr(err)
+	}
+	for i := range frames {
+		f := api.DiagnosticsFrame{Location: api.ConvertLocation(frames[i].Call)}
+		if frames[i].Err != nil {
+			f.Err = frames[i].Err.Error()
+		}
+		scope := proc.FrameToScope(d.target, bi, d.target.Memory(), g, frames[i:]...)
+		f.Unwind = api.ConvertFrameUnwindInfo(scope.FrameUnwindInfo())
+		r.Frames = append(r.Frames, f)
+	}
+
+	r.Expressions = d.exprs.recent()
+
+	if pl, ok := d.target.Process.(proc.PacketLogger); ok {
+		r.Packets = pl.RecentPackets()
+	}
+
+	if n, ok := d.target.AsyncPreemptSignals(); ok {
+		r.AsyncPreemptSignals = &n
+	}
+
+	redactDiagnostics(r)
+	return r, nil
+}
+
+// redactDiagnostics removes the home directory and the name of the user
+// from the file paths and the packets of r.
+func redactDiagnostics(r *api.Diagnostics) {
+	var oldnew []string
+	if u, err := user.Current(); err == nil {
+		if u.HomeDir != "" && u.HomeDir != string(filepath.Separator) {
+			oldnew = append(oldnew, u.HomeDir, "~")
+		}
+		if u.Username != "" {
+			for _, sep := range []string{"/", `\`} {
+				oldnew = append(oldnew, sep+u.Username+sep, sep+"<user>"+sep)
+			}
+		}
+	}
+	if len(oldnew) == 0 {
+		return
+	}
+	redact := strings.NewReplacer(oldnew...).Replace
+	for i := range r.Frames {
+		r.Frames[i].File = redact(r.Frames[i].File)
+	}
+	for i := range r.Packets {
+		r.Packets[i] = redact(r.Packets[i])
+	}
+	for i := range r.Errors {
+		r.Errors[i] = redact(r.Errors[i])
+	}
+}
@@ -0,0 +1,89 @@
+package debugger
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrEvalQueueFull is returned by Command when a client requests a
+// function call while too many of its function calls are already waiting
+// for the evaluations of other clients to complete, see
+// Config.MaxQueuedCalls.
+var ErrEvalQueueFull = errors.New("debugger busy: too many function calls waiting for the evaluations of other clients")
+
+// evalQueue serializes the evaluations that inject function calls in the
+// target. Only one of them runs at a time and, when evaluations of
+// multiple clients are waiting, the next one is taken from the client that
+// was served least recently, so that a client sending many calls does not
+// starve the others.
+type evalQueue struct {
+	mu      sync.Mutex
+	running bool
+	waiting []*evalTicket // in arrival order
+
+	serial     uint64
+	lastServed map[string]uint64
+}
+
+type evalTicket struct {
+	client string
+	ready  chan struct{}
+}
+
+// acquire waits for the turn of client to evaluate a function call. At
+// most max evaluations of each client can be waiting, if max is not zero.
+// The returned function must be called when the evaluation is done.
+func (q *evalQueue) acquire(client string, max int) (release func(), err error) {
+	q.mu.Lock()
+	if !q.running && len(q.waiting) == 0 {
+		q.running = true
+		q.served(client)
+		q.mu.Unlock()
+		return q.release, nil
+	}
+	if max > 0 {
+		n := 0
+		for _, t := range q.waiting {
+			if t.client == client {
+				n++
+			}
+		}
+		if n >= max {
+			q.mu.Unlock()
+			return nil, ErrEvalQueueFull
+		}
+	}
+	t := &evalTicket{client: client, ready: make(chan struct{})}
+	q.waiting = append(q.waiting, t)
+	q.mu.Unlock()
+	<-t.ready
+	return q.release, nil
+}
+
+// release ends the running evaluation and hands the turn to the next one.
+func (q *evalQueue) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.waiting) == 0 {
+		q.running = false
+		return
+	}
+	next := 0
+	for i, t := range q.waiting {
+		if q.lastServed[t.client] < q.lastServed[q.waiting[next].client] {
+			next = i
+		}
+	}
+	t := q.waiting[next]
+	q.waiting = append(q.waiting[:next], q.waiting[next+1:]...)
+	q.served(t.client)
+	close(t.ready)
+}
+
+func (q *evalQueue) served(client string) {
+	if q.lastServed == nil {
+		q.lastServed = make(map[string]uint64)
+	}
+	q.serial++
+	q.lastServed[client] = q.serial
+}
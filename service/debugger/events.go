@@ -0,0 +1,129 @@
+package debugger
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// maxEvents is the number of events kept by the debugger, clients that
+// wait for events less often than that lose the oldest events.
+const maxEvents = 1000
+
+// eventLog is the list of the most recent events of the debugging
+// session, clients read them with WaitForEvents.
+type eventLog struct {
+	mu      sync.Mutex
+	events  []api.Event
+	lastSeq int64
+	// added is closed, and replaced, every time an event is added.
+	added chan struct{}
+}
+
+// add appends ev to the log, assigning it the next sequence number.
+func (l *eventLog) add(ev api.Event) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastSeq++
+	ev.Seq = l.lastSeq
+	if len(l.events) >= maxEvents {
+		l.events = append(l.events[:0], l.events[len(l.events)-maxEvents+1:]...)
+	}
+	l.events = append(l.events, ev)
+	if l.added != nil {
+		close(l.added)
+		l.added = nil
+	}
+}
+
+// after returns the events with a sequence number greater than seq and a
+// channel that is closed when a new event is added.
+func (l *eventLog) after(seq int64) ([]api.Event, <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := range l.events {
+		if l.events[i].Seq > seq {
+			return append([]api.Event(nil), l.events[i:]...), nil
+		}
+	}
+	if l.added == nil {
+		l.added = make(chan struct{})
+	}
+	return nil, l.added
+}
+
+// WaitForEvents returns the events with a sequence number greater than
+// after. If there are none it waits until one happens or timeout elapses,
+// in which case it returns an empty list.
+// Only the last maxEvents events are kept: if the sequence number of the
+// first event returned is greater than after+1 the events in between were
+// lost.
+func (d *Debugger) WaitForEvents(after int64, timeout time.Duration) []api.Event {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		events, added := d.events.after(after)
+		if events != nil {
+			return events
+		}
+		select {
+		case <-added:
+		case <-timer.C:
+			return []api.Event{}
+		}
+	}
+}
+
+// emitStopEvents records the events for the stop of the target described
+// by state.
+func (d *Debugger) emitStopEvents(state *api.DebuggerState) {
+	ev := api.Event{Kind: api.EventStopped}
+	if state.CurrentThread != nil {
+		ev.ThreadID = state.CurrentThread.ID
+	}
+	if state.SelectedGoroutine != nil {
+		ev.GoroutineID = state.SelectedGoroutine.ID
+	}
+	d.events.add(ev)
+	for _, th := range state.Threads {
+		if th.Breakpoint == nil {
+			continue
+		}
+		d.events.add(api.Event{
+			Kind:           api.EventBreakpoint,
+			ThreadID:       th.ID,
+			GoroutineID:    th.GoroutineID,
+			BreakpointID:   th.Breakpoint.ID,
+			BreakpointName: th.Breakpoint.Name,
+		})
+	}
+	d.emitImageEvents()
+}
+
+// emitImageEvents records an event for each image loaded by the target
+// since the last call.
+func (d *Debugger) emitImageEvents() {
+	images := d.target.BinInfo().Images
+	for _, image := range images[d.imagesReported:] {
+		img := api.ConvertImage(image)
+		d.events.add(api.Event{Kind: api.EventImageLoaded, Image: &img})
+	}
+	d.imagesReported = len(images)
+}
+
+// outputEventWriter records what is written to it as output events of
+// the given stream, keeps it in the output buffer of the debugger and then
+// writes it to w.
+type outputEventWriter struct {
+	d      *Debugger
+	stream string
+	w      io.Writer
+}
+
+func (w *outputEventWriter) Write(p []byte) (int, error) {
+	w.d.events.add(api.Event{Kind: api.EventOutput, Stream: w.stream, Output: string(p)})
+	w.d.outbuf.add(w.stream, p)
+	return w.w.Write(p)
+}
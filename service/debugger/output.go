@@ -0,0 +1,185 @@
+package debugger
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-delve/delve/service/api"
+)
+
+// outputDrainTimeout is how long the output of the target is still read
+// after it is detached, so that the output written right before the
+// target exited is not lost.
+var outputDrainTimeout = 100 * time.Millisecond
+
+// targetOutput is the output of the target captured when
+// Config.CaptureOutput is set: the target writes to named pipes, what is
+// read from them is reported as output events and written to the standard
+// output and error of the debugger.
+type targetOutput struct {
+	dir   string
+	pipes []*os.File
+	wg    sync.WaitGroup
+}
+
+// captureOutput starts capturing the standard output and error of the
+// next target, unless they are redirected, and returns the redirects to
+// launch it with.
+func (d *Debugger) captureOutput(redirects [3]string) (_ [3]string, err error) {
+	d.closeOutput()
+	o := &targetOutput{}
+	defer func() {
+		if err != nil {
+			o.close()
+			return
+		}
+		d.output = o
+	}()
+	for i, stream := range []string{api.StdoutStream, api.StderrStream} {
+		if redirects[i+1] != "" {
+			continue
+		}
+		if o.dir == "" {
+			o.dir, err = ioutil.TempDir("", "dlv-output")
+			if err != nil {
+				return redirects, err
+			}
+		}
+		path := filepath.Join(o.dir, fmt.Sprintf("fd%d", i+1))
+		if err := mkfifo(path); err != nil {
+			return redirects, err
+		}
+		// Opening the pipe for writing as well does not block until the
+		// target opens it and lets the target open it without blocking.
+		pipe, err := os.OpenFile(path, os.O_RDWR, 0)
+		if err != nil {
+			return redirects, err
+		}
+		o.pipes = append(o.pipes, pipe)
+		redirects[i+1] = path
+		w := &outputEventWriter{d: d, stream: stream, w: os.Stdout}
+		if stream == api.StderrStream {
+			w.w = os.Stderr
+		}
+		o.wg.Add(1)
+		go func() {
+			defer o.wg.Done()
+			io.Copy(w, pipe)
+		}()
+	}
+	return redirects, nil
+}
+
+// closeOutput stops capturing the output of the target.
+func (d *Debugger) closeOutput() {
+	d.output.close()
+	d.output = nil
+}
+
+// close stops copying the output of the target, after reading what is
+// left in the pipes.
+func (o *targetOutput) close() {
+	if o == nil {
+		return
+	}
+	drain := true
+	for _, pipe := range o.pipes {
+		if err := pipe.SetReadDeadline(time.Now().Add(outputDrainTimeout)); err != nil {
+			drain = false
+		}
+	}
+	if drain {
+		o.wg.Wait()
+	}
+	for _, pipe := range o.pipes {
+		pipe.Close()
+	}
+	if o.dir != "" {
+		os.RemoveAll(o.dir)
+	}
+}
+
+// maxOutputBufferSize is the number of bytes of the output of the target
+// kept by the debugger, see TargetOutput.
+const maxOutputBufferSize = 1 << 20
+
+// outputBuffer keeps the last maxOutputBufferSize bytes written by the
+// target to its standard output and error.
+type outputBuffer struct {
+	mu     sync.Mutex
+	chunks []api.OutputChunk
+	size   int
+	// end is the offset of the byte following the last byte written.
+	end int64
+}
+
+// add appends what the target wrote to stream to the buffer, discarding
+// the oldest output if the buffer is full.
+func (b *outputBuffer) add(stream string, p []byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.chunks = append(b.chunks, api.OutputChunk{Offset: b.end, Stream: stream, Data: string(p)})
+	b.size += len(p)
+	b.end += int64(len(p))
+	for b.size > maxOutputBufferSize {
+		excess := b.size - maxOutputBufferSize
+		c := &b.chunks[0]
+		if len(c.Data) > excess {
+			c.Data = c.Data[excess:]
+			c.Offset += int64(excess)
+			b.size -= excess
+			break
+		}
+		b.size -= len(c.Data)
+		b.chunks = b.chunks[1:]
+	}
+}
+
+// read returns the output starting at offset, at most max bytes of it if
+// max is greater than zero. A negative offset is relative to the end of
+// the output.
+func (b *outputBuffer) read(offset int64, max int) *api.TargetOutput {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	r := &api.TargetOutput{Start: b.end - int64(b.size), End: b.end}
+	if offset < 0 {
+		offset += b.end
+	}
+	if offset < r.Start {
+		offset = r.Start
+	}
+	n := 0
+	for _, c := range b.chunks {
+		if c.Offset+int64(len(c.Data)) <= offset {
+			continue
+		}
+		if c.Offset < offset {
+			c.Data = c.Data[offset-c.Offset:]
+			c.Offset = offset
+		}
+		if max > 0 && n+len(c.Data) > max {
+			c.Data = c.Data[:max-n]
+		}
+		if c.Data == "" {
+			break
+		}
+		r.Chunks = append(r.Chunks, c)
+		n += len(c.Data)
+	}
+	return r
+}
+
+// TargetOutput returns the output of the target kept by the debugger,
+// starting at offset and at most max bytes of it if max is greater than
+// zero. A negative offset is relative to the end of the output.
+// Only the output captured while Config.CaptureOutput is set is kept, and
+// only its last maxOutputBufferSize bytes: output before the Start offset
+// of the result was discarded.
+func (d *Debugger) TargetOutput(offset int64, max int) *api.TargetOutput {
+	return d.outbuf.read(offset, max)
+}
@@ -1,11 +1,15 @@
 package rpc2
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"io"
 	"log"
 	"net"
 	"net/rpc"
 	"net/rpc/jsonrpc"
+	"os"
 	"time"
 
 	"github.com/go-delve/delve/service"
@@ -42,12 +46,29 @@ func NewClientFromConn(conn net.Conn) *RPCClient {
 	return newFromRPCClient(jsonrpc.NewClient(conn))
 }
 
+// NewAuthenticatedClient creates a new RPCClient from the given
+// connection, authenticating it with token before making any other call.
+func NewAuthenticatedClient(conn net.Conn, token string) (*RPCClient, error) {
+	client := jsonrpc.NewClient(conn)
+	if err := client.Call("RPCServer.Authenticate", api.AuthenticateIn{Token: token}, &api.AuthenticateOut{}); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return newFromRPCClient(client), nil
+}
+
 func (c *RPCClient) ProcessPid() int {
 	out := new(ProcessPidOut)
 	c.call("ProcessPid", ProcessPidIn{}, out)
 	return out.Pid
 }
 
+func (c *RPCClient) ListTargets() ([]*api.Target, error) {
+	var out ListTargetsOut
+	err := c.call("ListTargets", ListTargetsIn{}, &out)
+	return out.Targets, err
+}
+
 func (c *RPCClient) LastModified() time.Time {
 	out := new(LastModifiedOut)
 	c.call("LastModified", LastModifiedIn{}, out)
@@ -176,6 +197,11 @@ func (c *RPCClient) Call(goroutineID int, expr string, unsafe bool) (*api.Debugg
 	return &out.State, err
 }
 
+func (c *RPCClient) CancelCall(goroutineID int) error {
+	var out CancelCallOut
+	return c.call("CancelCall", CancelCallIn{goroutineID}, &out)
+}
+
 func (c *RPCClient) StepInstruction() (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.StepInstruction}, &out)
@@ -220,6 +246,12 @@ func (c *RPCClient) GetBreakpoint(id int) (*api.Breakpoint, error) {
 	return &out.Breakpoint, err
 }
 
+func (c *RPCClient) GetBreakpointStacks(id int) ([]api.StackRecord, error) {
+	var out GetBreakpointStacksOut
+	err := c.call("GetBreakpointStacks", GetBreakpointStacksIn{id}, &out)
+	return out.Stacks, err
+}
+
 func (c *RPCClient) GetBreakpointByName(name string) (*api.Breakpoint, error) {
 	var out GetBreakpointOut
 	err := c.call("GetBreakpoint", GetBreakpointIn{0, name}, &out)
@@ -248,6 +280,12 @@ func (c *RPCClient) ListBreakpoints() ([]*api.Breakpoint, error) {
 	return out.Breakpoints, err
 }
 
+func (c *RPCClient) ListFileBreakpoints(file string) (*api.FileBreakpoints, error) {
+	var out ListFileBreakpointsOut
+	err := c.call("ListFileBreakpoints", ListFileBreakpointsIn{file}, &out)
+	return &out.FileBreakpoints, err
+}
+
 func (c *RPCClient) ClearBreakpoint(id int) (*api.Breakpoint, error) {
 	var out ClearBreakpointOut
 	err := c.call("ClearBreakpoint", ClearBreakpointIn{id, ""}, &out)
@@ -301,11 +339,63 @@ func (c *RPCClient) EvalVariable(scope api.EvalScope, expr string, cfg api.LoadC
 	return out.Variable, err
 }
 
+func (c *RPCClient) EvalBatch(scope api.EvalScope, exprs []api.EvalBatchExpr, cfg api.LoadConfig) ([]api.EvalBatchResult, error) {
+	var out EvalBatchOut
+	err := c.call("EvalBatch", EvalBatchIn{scope, exprs, &cfg}, &out)
+	return out.Results, err
+}
+
+func (c *RPCClient) EvalChunk(scope api.EvalScope, expr string, offset, count int64, cfg api.LoadConfig) (*api.ValueChunk, error) {
+	var out EvalChunkOut
+	err := c.call("EvalChunk", EvalChunkIn{scope, expr, offset, count, &cfg}, &out)
+	return out.Chunk, err
+}
+
+func (c *RPCClient) SaveVariable(scope api.EvalScope, expr, path string) (int64, error) {
+	var out SaveVariableOut
+	err := c.call("SaveVariable", SaveVariableIn{scope, expr, path}, &out)
+	return out.Written, err
+}
+
+func (c *RPCClient) ValueProvenance(scope api.EvalScope, name string, cfg api.LoadConfig, flavour api.AssemblyFlavour) (*api.ValueProvenance, error) {
+	var out ValueProvenanceOut
+	err := c.call("ValueProvenance", ValueProvenanceIn{scope, name, &cfg, flavour}, &out)
+	return out.Provenance, err
+}
+
+func (c *RPCClient) ListVariableReferences(scope api.EvalScope, expr string, flavour api.AssemblyFlavour) ([]api.VariableReference, error) {
+	var out ListVariableReferencesOut
+	err := c.call("ListVariableReferences", ListVariableReferencesIn{scope, expr, flavour}, &out)
+	return out.References, err
+}
+
+func (c *RPCClient) FrameUnwindInfo(scope api.EvalScope) (*api.FrameUnwindInfo, error) {
+	var out FrameUnwindInfoOut
+	err := c.call("FrameUnwindInfo", FrameUnwindInfoIn{scope}, &out)
+	return out.Info, err
+}
+
+func (c *RPCClient) Diagnostics() (*api.Diagnostics, error) {
+	var out DiagnosticsOut
+	err := c.call("Diagnostics", DiagnosticsIn{}, &out)
+	return out.Diagnostics, err
+}
+
 func (c *RPCClient) SetVariable(scope api.EvalScope, symbol, value string) error {
 	out := new(SetOut)
 	return c.call("Set", SetIn{scope, symbol, value}, out)
 }
 
+func (c *RPCClient) ListMemoryWrites() ([]api.MemoryWrite, error) {
+	var out ListMemoryWritesOut
+	err := c.call("ListMemoryWrites", ListMemoryWritesIn{}, &out)
+	return out.Writes, err
+}
+
+func (c *RPCClient) UndoMemoryWrite(id int) error {
+	return c.call("UndoMemoryWrite", UndoMemoryWriteIn{ID: id}, &UndoMemoryWriteOut{})
+}
+
 func (c *RPCClient) ListSources(filter string) ([]string, error) {
 	sources := new(ListSourcesOut)
 	err := c.call("ListSources", ListSourcesIn{filter}, sources)
@@ -324,6 +414,18 @@ func (c *RPCClient) ListTypes(filter string) ([]string, error) {
 	return types.Types, err
 }
 
+func (c *RPCClient) DescribeType(typ string) (*api.TypeLayout, error) {
+	var out DescribeTypeOut
+	err := c.call("DescribeType", DescribeTypeIn{typ}, &out)
+	return out.Layout, err
+}
+
+func (c *RPCClient) ListSymbols(filter, kind string) ([]api.Symbol, error) {
+	var out ListSymbolsOut
+	err := c.call("ListSymbols", ListSymbolsIn{filter, kind}, &out)
+	return out.Symbols, err
+}
+
 func (c *RPCClient) ListPackageVariables(filter string, cfg api.LoadConfig) ([]api.Variable, error) {
 	var out ListPackageVarsOut
 	err := c.call("ListPackageVars", ListPackageVarsIn{filter, cfg}, &out)
@@ -332,7 +434,13 @@ func (c *RPCClient) ListPackageVariables(filter string, cfg api.LoadConfig) ([]a
 
 func (c *RPCClient) ListLocalVariables(scope api.EvalScope, cfg api.LoadConfig) ([]api.Variable, error) {
 	var out ListLocalVarsOut
-	err := c.call("ListLocalVars", ListLocalVarsIn{scope, cfg}, &out)
+	err := c.call("ListLocalVars", ListLocalVarsIn{Scope: scope, Cfg: cfg}, &out)
+	return out.Variables, err
+}
+
+func (c *RPCClient) ListLocalVariablesInterest(scope api.EvalScope, cfg api.LoadConfig, paths []string) ([]api.Variable, error) {
+	var out ListLocalVarsOut
+	err := c.call("ListLocalVars", ListLocalVarsIn{Scope: scope, Cfg: cfg, InterestPaths: paths}, &out)
 	return out.Variables, err
 }
 
@@ -369,18 +477,70 @@ func (c *RPCClient) ListGoroutinesWithFilter(start, count int, filters []api.Lis
 	return out.Goroutines, out.Groups, out.Nextg, out.TooManyGroups, err
 }
 
+func (c *RPCClient) ListTerminatedGoroutines() ([]api.TerminatedGoroutine, error) {
+	var out ListTerminatedGoroutinesOut
+	err := c.call("ListTerminatedGoroutines", ListTerminatedGoroutinesIn{}, &out)
+	return out.Goroutines, err
+}
+
+func (c *RPCClient) SetGoroutineName(id int, name string) error {
+	var out SetGoroutineNameOut
+	return c.call("SetGoroutineName", SetGoroutineNameIn{ID: id, Name: name}, &out)
+}
+
+func (c *RPCClient) ListAnnotations() ([]api.Annotation, error) {
+	var out ListAnnotationsOut
+	err := c.call("ListAnnotations", ListAnnotationsIn{}, &out)
+	return out.Annotations, err
+}
+
+func (c *RPCClient) SetAnnotation(a api.Annotation) error {
+	var out SetAnnotationOut
+	return c.call("SetAnnotation", SetAnnotationIn{Annotation: a}, &out)
+}
+
 func (c *RPCClient) Stacktrace(goroutineId, depth int, opts api.StacktraceOptions, cfg *api.LoadConfig) ([]api.Stackframe, error) {
 	var out StacktraceOut
 	err := c.call("Stacktrace", StacktraceIn{goroutineId, depth, false, false, opts, cfg}, &out)
 	return out.Locations, err
 }
 
+func (c *RPCClient) ListDefers(goroutineID int, cfg *api.LoadConfig) ([]api.Defer, error) {
+	var out ListDefersOut
+	err := c.call("ListDefers", ListDefersIn{goroutineID, cfg}, &out)
+	return out.Defers, err
+}
+
 func (c *RPCClient) Ancestors(goroutineID int, numAncestors int, depth int) ([]api.Ancestor, error) {
 	var out AncestorsOut
 	err := c.call("Ancestors", AncestorsIn{goroutineID, numAncestors, depth}, &out)
 	return out.Ancestors, err
 }
 
+func (c *RPCClient) GoroutineProfile(depth int, format api.GoroutineProfileFormat) ([]byte, error) {
+	var out GoroutineProfileOut
+	err := c.call("GoroutineProfile", GoroutineProfileIn{Depth: depth, Format: format}, &out)
+	return out.Data, err
+}
+
+func (c *RPCClient) SchedState() (*api.SchedState, error) {
+	var out SchedStateOut
+	err := c.call("SchedState", SchedStateIn{}, &out)
+	return out.State, err
+}
+
+func (c *RPCClient) ContentionProfile(kind api.ContentionProfileKind, max int) (*api.ContentionProfile, error) {
+	var out ContentionProfileOut
+	err := c.call("ContentionProfile", ContentionProfileIn{Kind: kind, Max: max}, &out)
+	return out.Profile, err
+}
+
+func (c *RPCClient) BugPatterns(scope api.EvalScope) ([]api.BugPattern, error) {
+	var out BugPatternsOut
+	err := c.call("BugPatterns", BugPatternsIn{Scope: scope}, &out)
+	return out.Patterns, err
+}
+
 func (c *RPCClient) AttachedToExistingProcess() bool {
 	out := new(AttachedToExistingProcessOut)
 	c.call("AttachedToExistingProcess", AttachedToExistingProcessIn{}, out)
@@ -407,6 +567,13 @@ func (c *RPCClient) DisassemblePC(scope api.EvalScope, pc uint64, flavour api.As
 	return out.Disassemble, err
 }
 
+// PseudoSource returns the pseudo-source listing of the function containing pc.
+func (c *RPCClient) PseudoSource(pc uint64) (string, []api.PseudoSourceLine, error) {
+	var out PseudoSourceOut
+	err := c.call("PseudoSource", PseudoSourceIn{pc}, &out)
+	return out.Function, out.Lines, err
+}
+
 // Recorded returns true if the debugger target is a recording.
 func (c *RPCClient) Recorded() bool {
 	out := new(RecordedOut)
@@ -503,6 +670,138 @@ func (c *RPCClient) CoreDumpCancel() error {
 	return c.call("DumpCancel", DumpCancelIn{}, out)
 }
 
+func (c *RPCClient) ListArtifacts() ([]api.Artifact, error) {
+	var out ListArtifactsOut
+	err := c.call("ListArtifacts", ListArtifactsIn{}, &out)
+	return out.Artifacts, err
+}
+
+func (c *RPCClient) ReadArtifact(path string, offset int64, length int) ([]byte, error) {
+	var out ReadArtifactOut
+	err := c.call("ReadArtifact", ReadArtifactIn{Path: path, Offset: offset, Length: length}, &out)
+	return out.Data, err
+}
+
+// artifactChunkSize is the size of the chunks requested by DownloadArtifact.
+const artifactChunkSize = 1 << 20
+
+// DownloadArtifact copies the artifact path to the local file dest. If
+// dest already contains the beginning of the artifact, from an interrupted
+// download, the download is resumed. The checksum of dest is verified at
+// the end, if it does not match dest is truncated so that the next
+// download starts over. If progress is not nil it is called after each
+// chunk.
+func (c *RPCClient) DownloadArtifact(path, dest string, progress func(done, total int64)) error {
+	artifacts, err := c.ListArtifacts()
+	if err != nil {
+		return err
+	}
+	var artifact *api.Artifact
+	for i := range artifacts {
+		if artifacts[i].Path == path {
+			artifact = &artifacts[i]
+			break
+		}
+	}
+	if artifact == nil {
+		return fmt.Errorf("no artifact %s", path)
+	}
+
+	fh, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer fh.Close()
+	fi, err := fh.Stat()
+	if err != nil {
+		return err
+	}
+	offset := fi.Size()
+	if offset > artifact.Size {
+		if err := fh.Truncate(0); err != nil {
+			return err
+		}
+		offset = 0
+	}
+	if _, err := fh.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	for offset < artifact.Size {
+		data, err := c.ReadArtifact(path, offset, artifactChunkSize)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 {
+			return fmt.Errorf("unexpected end of %s at offset %d", path, offset)
+		}
+		if _, err := fh.Write(data); err != nil {
+			return err
+		}
+		offset += int64(len(data))
+		if progress != nil {
+			progress(offset, artifact.Size)
+		}
+	}
+
+	if _, err := fh.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, fh); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != artifact.SHA256 {
+		fh.Truncate(0)
+		return fmt.Errorf("checksum mismatch for %s: got %s, expected %s", dest, sum, artifact.SHA256)
+	}
+	return nil
+}
+
+func (c *RPCClient) GetProject() (*api.Project, error) {
+	var out GetProjectOut
+	err := c.call("GetProject", GetProjectIn{}, &out)
+	return &out.Project, err
+}
+
+func (c *RPCClient) StartCommand(cmd *api.DebuggerCommand) (int, error) {
+	if cmd.ReturnInfoLoadConfig == nil {
+		cmd.ReturnInfoLoadConfig = c.retValLoadCfg
+	}
+	var out StartCommandOut
+	err := c.call("StartCommand", StartCommandIn{Command: *cmd}, &out)
+	return out.Token, err
+}
+
+func (c *RPCClient) CommandResult(token int, wait time.Duration) (*api.DebuggerState, bool, error) {
+	w := int(wait / time.Millisecond)
+	if wait < 0 {
+		w = -1
+	}
+	var out CommandResultOut
+	err := c.call("CommandResult", CommandResultIn{Token: token, Wait: w}, &out)
+	if err != nil || !out.Done {
+		return nil, false, err
+	}
+	return &out.State, true, nil
+}
+
+func (c *RPCClient) CancelCommand(token int) error {
+	return c.call("CancelCommand", CancelCommandIn{Token: token}, &CancelCommandOut{})
+}
+
+func (c *RPCClient) WaitForEvents(after int64, wait time.Duration) ([]api.Event, error) {
+	var out WaitForEventsOut
+	err := c.call("WaitForEvents", WaitForEventsIn{After: after, Wait: int(wait / time.Millisecond)}, &out)
+	return out.Events, err
+}
+
+func (c *RPCClient) GetOutput(offset int64, max int) (*api.TargetOutput, error) {
+	var out GetOutputOut
+	err := c.call("GetOutput", GetOutputIn{Offset: offset, Max: max}, &out)
+	return &out.Output, err
+}
+
 func (c *RPCClient) call(method string, args, reply interface{}) error {
 	return c.client.Call("RPCServer."+method, args, reply)
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"sync"
 	"time"
 
 	"github.com/go-delve/delve/pkg/dwarf/op"
@@ -18,10 +19,26 @@ type RPCServer struct {
 	config *service.Config
 	// debugger is a debugger service.
 	debugger *debugger.Debugger
+
+	// operations are the commands started by StartCommand whose result
+	// was not returned yet by CommandResult, indexed by token.
+	operationsMu  sync.Mutex
+	operations    map[int]*operation
+	lastOperation int
+}
+
+// operation is a command started by StartCommand.
+type operation struct {
+	command string
+	// done is closed when the command returns, state and err are its
+	// result.
+	done  chan struct{}
+	state *api.DebuggerState
+	err   error
 }
 
 func NewServer(config *service.Config, debugger *debugger.Debugger) *RPCServer {
-	return &RPCServer{config, debugger}
+	return &RPCServer{config: config, debugger: debugger, operations: make(map[int]*operation)}
 }
 
 type ProcessPidIn struct {
@@ -37,6 +54,22 @@ func (s *RPCServer) ProcessPid(arg ProcessPidIn, out *ProcessPidOut) error {
 	return nil
 }
 
+type ListTargetsIn struct {
+}
+
+type ListTargetsOut struct {
+	Targets []*api.Target
+}
+
+// ListTargets returns the processes being debugged: the process launched
+// or attached to and, with --follow-fork, the processes it created.
+// Commands act on the current target, use Command with SwitchThread and a
+// thread of another target to change it.
+func (s *RPCServer) ListTargets(arg ListTargetsIn, out *ListTargetsOut) error {
+	out.Targets = s.debugger.ListTargets()
+	return nil
+}
+
 type LastModifiedIn struct {
 }
 
@@ -126,6 +159,7 @@ type CommandOut struct {
 
 // Command interrupts, continues and steps through the program.
 func (s *RPCServer) Command(command api.DebuggerCommand, cb service.RPCCallback) {
+	command.Client = cb.Client()
 	st, err := s.debugger.Command(&command, cb.SetupDoneChan())
 	if err != nil {
 		cb.Return(nil, err)
@@ -136,6 +170,162 @@ func (s *RPCServer) Command(command api.DebuggerCommand, cb service.RPCCallback)
 	cb.Return(out, nil)
 }
 
+type StartCommandIn struct {
+	Command api.DebuggerCommand
+}
+
+type StartCommandOut struct {
+	// Token identifies the operation in CommandResult and CancelCommand.
+	Token int
+}
+
+// StartCommand runs arg.Command, like Command, but returns as soon as the
+// target is resumed, without waiting for it to stop. The result of the
+// command is read with CommandResult and the command is interrupted with
+// CancelCommand, clients can therefore control the target with a single
+// connection.
+func (s *RPCServer) StartCommand(arg StartCommandIn, cb service.RPCCallback) {
+	close(cb.SetupDoneChan())
+	if arg.Command.Name == api.Halt {
+		cb.Return(nil, errors.New("halt can not be started as an operation, use CancelCommand"))
+		return
+	}
+	arg.Command.Client = cb.Client()
+	op := &operation{command: arg.Command.Name, done: make(chan struct{})}
+	s.operationsMu.Lock()
+	s.lastOperation++
+	token := s.lastOperation
+	s.operations[token] = op
+	s.operationsMu.Unlock()
+
+	resumed := make(chan struct{})
+	go func() {
+		defer close(op.done)
+		defer func() {
+			// The command does not run on a goroutine of the RPC server,
+			// which would recover from its panics.
+			if ierr := recover(); ierr != nil {
+				op.state, op.err = nil, fmt.Errorf("internal debugger error: %v", ierr)
+			}
+		}()
+		op.state, op.err = s.debugger.Command(&arg.Command, resumed)
+	}()
+	select {
+	case <-resumed:
+	case <-op.done:
+	}
+	cb.Return(StartCommandOut{Token: token}, nil)
+}
+
+type CommandResultIn struct {
+	Token int
+	// Wait is the maximum number of milliseconds to wait for the command
+	// to finish, zero returns immediately and a negative value waits until
+	// it finishes.
+	Wait int
+}
+
+type CommandResultOut struct {
+	// Done is true if the command finished, State is then the state it
+	// returned.
+	Done  bool
+	State api.DebuggerState
+}
+
+// CommandResult returns the result of the command started by StartCommand
+// identified by arg.Token, waiting at most arg.Wait milliseconds for it to
+// finish. Once the result of a finished command is returned its token is
+// no longer valid.
+func (s *RPCServer) CommandResult(arg CommandResultIn, cb service.RPCCallback) {
+	close(cb.SetupDoneChan())
+	op, err := s.operation(arg.Token)
+	if err != nil {
+		cb.Return(nil, err)
+		return
+	}
+	if arg.Wait < 0 {
+		<-op.done
+	} else {
+		timer := time.NewTimer(time.Duration(arg.Wait) * time.Millisecond)
+		select {
+		case <-op.done:
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+	select {
+	case <-op.done:
+	default:
+		cb.Return(CommandResultOut{Done: false}, nil)
+		return
+	}
+	s.operationsMu.Lock()
+	delete(s.operations, arg.Token)
+	s.operationsMu.Unlock()
+	if op.err != nil {
+		cb.Return(nil, op.err)
+		return
+	}
+	cb.Return(CommandResultOut{Done: true, State: *op.state}, nil)
+}
+
+type CancelCommandIn struct {
+	Token int
+}
+
+type CancelCommandOut struct {
+}
+
+// CancelCommand interrupts the command started by StartCommand identified
+// by arg.Token, stopping the target, and returns once the command has
+// finished. Its result is still returned by CommandResult.
+func (s *RPCServer) CancelCommand(arg CancelCommandIn, cb service.RPCCallback) {
+	close(cb.SetupDoneChan())
+	op, err := s.operation(arg.Token)
+	if err != nil {
+		cb.Return(nil, err)
+		return
+	}
+	select {
+	case <-op.done:
+	default:
+		if op.command != api.SwitchThread && op.command != api.SwitchGoroutine {
+			if _, err := s.debugger.Command(&api.DebuggerCommand{Name: api.Halt}, nil); err != nil {
+				cb.Return(nil, err)
+				return
+			}
+		}
+		<-op.done
+	}
+	cb.Return(CancelCommandOut{}, nil)
+}
+
+// operation returns the operation identified by token.
+func (s *RPCServer) operation(token int) (*operation, error) {
+	s.operationsMu.Lock()
+	defer s.operationsMu.Unlock()
+	op := s.operations[token]
+	if op == nil {
+		return nil, fmt.Errorf("unknown operation %d", token)
+	}
+	return op, nil
+}
+
+type CancelCallIn struct {
+	GoroutineID int
+}
+
+type CancelCallOut struct {
+}
+
+// CancelCall abandons the function call in progress on the specified
+// goroutine, or all function calls in progress if GoroutineID is negative.
+// The return values of the call will be discarded and the registers of
+// the goroutine restored once the called function returns.
+func (s *RPCServer) CancelCall(arg CancelCallIn, out *CancelCallOut) error {
+	return s.debugger.CancelCall(arg.GoroutineID)
+}
+
 type GetBreakpointIn struct {
 	Id   int
 	Name string
@@ -163,6 +353,23 @@ func (s *RPCServer) GetBreakpoint(arg GetBreakpointIn, out *GetBreakpointOut) er
 	return nil
 }
 
+type GetBreakpointStacksIn struct {
+	Id int
+}
+
+type GetBreakpointStacksOut struct {
+	Stacks []api.StackRecord
+}
+
+// GetBreakpointStacks returns the stacks that reached the breakpoint with
+// the given ID, which must have AggregateStacks set, and how many times
+// each of them did. The most frequent stacks are returned first.
+func (s *RPCServer) GetBreakpointStacks(arg GetBreakpointStacksIn, out *GetBreakpointStacksOut) error {
+	var err error
+	out.Stacks, err = s.debugger.BreakpointStacks(arg.Id)
+	return err
+}
+
 type StacktraceIn struct {
 	Id     int
 	Depth  int
@@ -180,6 +387,10 @@ type StacktraceOut struct {
 //
 // If Full is set it will also the variable of all local variables
 // and function arguments of all stack frames.
+//
+// If the stack is deeper than Depth the last frame returned has Truncated
+// set and Remaining is an estimate of the number of frames that were not
+// returned.
 func (s *RPCServer) Stacktrace(arg StacktraceIn, out *StacktraceOut) error {
 	cfg := arg.Cfg
 	if cfg == nil && arg.Full {
@@ -197,6 +408,83 @@ func (s *RPCServer) Stacktrace(arg StacktraceIn, out *StacktraceOut) error {
 	return err
 }
 
+type GoroutineProfileIn struct {
+	Depth  int
+	Format api.GoroutineProfileFormat
+}
+
+type GoroutineProfileOut struct {
+	Data []byte
+}
+
+// GoroutineProfile returns the stacktraces of all goroutines, up to
+// arg.Depth frames each, encoded in arg.Format.
+// With api.GoroutineProfileProto the result can be opened with 'go tool
+// pprof', with api.GoroutineProfileFolded it can be used by flamegraph tools.
+func (s *RPCServer) GoroutineProfile(arg GoroutineProfileIn, out *GoroutineProfileOut) error {
+	var err error
+	out.Data, err = s.debugger.GoroutineProfile(arg.Depth, arg.Format)
+	return err
+}
+
+type SchedStateIn struct {
+}
+
+type SchedStateOut struct {
+	State *api.SchedState
+}
+
+// SchedState returns the state of the Go scheduler: the list of Ps with
+// their local run queues, the list of Ms with the P and goroutine they
+// are bound to and the global run queue.
+func (s *RPCServer) SchedState(arg SchedStateIn, out *SchedStateOut) error {
+	st, err := s.debugger.SchedState()
+	if err != nil {
+		return err
+	}
+	out.State = api.ConvertSchedState(st)
+	return nil
+}
+
+type ContentionProfileIn struct {
+	Kind api.ContentionProfileKind
+	Max  int
+}
+
+type ContentionProfileOut struct {
+	Profile *api.ContentionProfile
+}
+
+// ContentionProfile reads the mutex (or block) profile collected by the
+// runtime of the target and returns its call sites, sorted from the most
+// contended to the least contended. If arg.Max is not zero at most arg.Max
+// call sites are returned.
+// The profile is only collected by the target if it has called
+// runtime.SetMutexProfileFraction (or runtime.SetBlockProfileRate).
+func (s *RPCServer) ContentionProfile(arg ContentionProfileIn, out *ContentionProfileOut) error {
+	var err error
+	out.Profile, err = s.debugger.ContentionProfile(arg.Kind, arg.Max)
+	return err
+}
+
+type BugPatternsIn struct {
+	Scope api.EvalScope
+}
+
+type BugPatternsOut struct {
+	Patterns []api.BugPattern
+}
+
+// BugPatterns inspects the stack frame specified by arg.Scope looking for
+// likely mistakes, such as slices sharing the same backing array, writes
+// to nil maps on the current line and goroutines sharing the same loop
+// variable. Results are heuristic and can contain false positives.
+func (s *RPCServer) BugPatterns(arg BugPatternsIn, out *BugPatternsOut) error {
+	var err error
+	out.Patterns, err = s.debugger.BugPatterns(arg.Scope.GoroutineID, arg.Scope.Frame)
+	return err
+}
+
 type AncestorsIn struct {
 	GoroutineID  int
 	NumAncestors int
@@ -214,6 +502,28 @@ func (s *RPCServer) Ancestors(arg AncestorsIn, out *AncestorsOut) error {
 	return err
 }
 
+type ListDefersIn struct {
+	GoroutineID int
+	Cfg         *api.LoadConfig
+}
+
+type ListDefersOut struct {
+	Defers []api.Defer
+}
+
+// ListDefers returns the chain of deferred calls of a goroutine, starting
+// with the one that will be executed first. The arguments of each deferred
+// call are loaded using arg.Cfg, or a default configuration if it is nil.
+func (s *RPCServer) ListDefers(arg ListDefersIn, out *ListDefersOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	var err error
+	out.Defers, err = s.debugger.Defers(arg.GoroutineID, *api.LoadConfigToProc(cfg))
+	return err
+}
+
 type ListBreakpointsIn struct {
 }
 
@@ -227,6 +537,26 @@ func (s *RPCServer) ListBreakpoints(arg ListBreakpointsIn, out *ListBreakpointsO
 	return nil
 }
 
+type ListFileBreakpointsIn struct {
+	File string
+}
+
+type ListFileBreakpointsOut struct {
+	FileBreakpoints api.FileBreakpoints
+}
+
+// ListFileBreakpoints returns the lines of arg.File where a breakpoint can
+// be set and the breakpoints set on them, so that editors can draw their
+// gutter without cross-referencing the full list of breakpoints.
+func (s *RPCServer) ListFileBreakpoints(arg ListFileBreakpointsIn, out *ListFileBreakpointsOut) error {
+	fbps, err := s.debugger.FileBreakpoints(arg.File)
+	if err != nil {
+		return err
+	}
+	out.FileBreakpoints = *fbps
+	return nil
+}
+
 type CreateBreakpointIn struct {
 	Breakpoint api.Breakpoint
 }
@@ -451,6 +781,10 @@ func (s *RPCServer) ListRegisters(arg ListRegistersIn, out *ListRegistersOut) er
 type ListLocalVarsIn struct {
 	Scope api.EvalScope
 	Cfg   api.LoadConfig
+	// InterestPaths, if not empty, are the selector paths (for example
+	// "req.URL.Path") of the values loaded with Cfg, the other local
+	// variables are loaded shallowly.
+	InterestPaths []string
 }
 
 type ListLocalVarsOut struct {
@@ -459,7 +793,13 @@ type ListLocalVarsOut struct {
 
 // ListLocalVars lists all local variables in scope.
 func (s *RPCServer) ListLocalVars(arg ListLocalVarsIn, out *ListLocalVarsOut) error {
-	vars, err := s.debugger.LocalVariables(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, *api.LoadConfigToProc(&arg.Cfg))
+	var vars []*proc.Variable
+	var err error
+	if len(arg.InterestPaths) > 0 {
+		vars, err = s.debugger.LocalVariablesInterest(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, *api.LoadConfigToProc(&arg.Cfg), arg.InterestPaths)
+	} else {
+		vars, err = s.debugger.LocalVariables(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, *api.LoadConfigToProc(&arg.Cfg))
+	}
 	if err != nil {
 		return err
 	}
@@ -513,6 +853,201 @@ func (s *RPCServer) Eval(arg EvalIn, out *EvalOut) error {
 	return nil
 }
 
+type EvalChunkIn struct {
+	Scope  api.EvalScope
+	Expr   string
+	Offset int64
+	Count  int64
+	Cfg    *api.LoadConfig
+}
+
+type EvalChunkOut struct {
+	Chunk *api.ValueChunk
+}
+
+// EvalChunk returns at most arg.Count elements, starting at arg.Offset,
+// of the value of arg.Expr, which must be a string, an array or a slice.
+// Clients can use it to fetch very large values incrementally, instead
+// of loading them with a single call to Eval or ListLocalVars: the
+// length of the value is returned with each chunk.
+// The content of strings and of arrays and slices of bytes is returned
+// in Chunk.Data, the elements of other arrays and slices are returned in
+// Chunk.Elems, loaded with arg.Cfg.
+func (s *RPCServer) EvalChunk(arg EvalChunkIn, out *EvalChunkOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	chunk, err := s.debugger.EvalVariableChunk(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr, arg.Offset, arg.Count, *api.LoadConfigToProc(cfg))
+	if err != nil {
+		return err
+	}
+	out.Chunk = api.ConvertValueChunk(chunk)
+	return nil
+}
+
+type SaveVariableIn struct {
+	Scope api.EvalScope
+	Expr  string
+	// Path is the path of the file, on the machine running the server.
+	Path string
+}
+
+type SaveVariableOut struct {
+	Written int64
+}
+
+// SaveVariable writes the content of the elements of the value of
+// arg.Expr, which must be a string, an array or a slice, to the file
+// arg.Path on the machine running the server, without transferring it to
+// the client.
+func (s *RPCServer) SaveVariable(arg SaveVariableIn, out *SaveVariableOut) error {
+	var err error
+	out.Written, err = s.debugger.SaveVariable(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr, arg.Path)
+	return err
+}
+
+type EvalBatchIn struct {
+	// Scope is the scope of the expressions that do not specify one.
+	Scope api.EvalScope
+	Exprs []api.EvalBatchExpr
+	Cfg   *api.LoadConfig
+}
+
+type EvalBatchOut struct {
+	// Results has one element for each expression, in the same order.
+	Results []api.EvalBatchResult
+}
+
+// EvalBatch evaluates a list of expressions, like Eval, in a single call.
+// The evaluation of each expression succeeds or fails independently of the
+// others, the error of a failed evaluation is returned in its result.
+func (s *RPCServer) EvalBatch(arg EvalBatchIn, out *EvalBatchOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	scopes := make([]api.EvalScope, len(arg.Exprs))
+	exprs := make([]string, len(arg.Exprs))
+	for i := range arg.Exprs {
+		scopes[i] = arg.Scope
+		if arg.Exprs[i].Scope != nil {
+			scopes[i] = *arg.Exprs[i].Scope
+		}
+		exprs[i] = arg.Exprs[i].Expr
+	}
+	vars, errs := s.debugger.EvalVariablesInScopes(scopes, exprs, *api.LoadConfigToProc(cfg))
+	out.Results = make([]api.EvalBatchResult, len(arg.Exprs))
+	for i := range vars {
+		if errs[i] != nil {
+			out.Results[i].Err = errs[i].Error()
+			continue
+		}
+		out.Results[i].Variable = api.ConvertVar(vars[i])
+	}
+	return nil
+}
+
+type ValueProvenanceIn struct {
+	Scope   api.EvalScope
+	Name    string
+	Cfg     *api.LoadConfig
+	Flavour api.AssemblyFlavour
+}
+
+type ValueProvenanceOut struct {
+	Provenance *api.ValueProvenance
+}
+
+// ValueProvenance returns where the current value of the local variable
+// arg.Name came from, by analyzing the instructions of the current function
+// executed before the current PC and the DWARF location of the variable.
+// The instruction that wrote the variable is formatted using arg.Flavour.
+// Only supported on amd64, the result is a hint that can be wrong when
+// control flow is involved.
+func (s *RPCServer) ValueProvenance(arg ValueProvenanceIn, out *ValueProvenanceOut) error {
+	cfg := arg.Cfg
+	if cfg == nil {
+		cfg = &api.LoadConfig{FollowPointers: true, MaxVariableRecurse: 1, MaxStringLen: 64, MaxArrayValues: 64, MaxStructFields: -1}
+	}
+	p, err := s.debugger.ValueProvenance(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Name, *api.LoadConfigToProc(cfg))
+	if err != nil {
+		return err
+	}
+	var text string
+	if p.Writer != nil {
+		text = s.debugger.AsmInstructionText(p.Writer, proc.AssemblyFlavour(arg.Flavour))
+	}
+	out.Provenance = api.ConvertValueProvenance(p, text)
+	return nil
+}
+
+type ListVariableReferencesIn struct {
+	Scope   api.EvalScope
+	Expr    string
+	Flavour api.AssemblyFlavour
+}
+
+type ListVariableReferencesOut struct {
+	References []api.VariableReference
+}
+
+// ListVariableReferences returns the instructions of the program that
+// write to the package variable arg.Expr, or to the part of one it
+// evaluates to, or that compute its address, found by disassembling every
+// function of the program. The instructions are formatted using
+// arg.Flavour. Writes made through pointers stored in other variables are
+// not found, combine it with a write watchpoint with AggregateStacks set to
+// observe them. Only supported on amd64.
+func (s *RPCServer) ListVariableReferences(arg ListVariableReferencesIn, out *ListVariableReferencesOut) error {
+	refs, err := s.debugger.VariableReferences(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr, proc.AssemblyFlavour(arg.Flavour))
+	if err != nil {
+		return err
+	}
+	out.References = refs
+	return nil
+}
+
+type FrameUnwindInfoIn struct {
+	Scope api.EvalScope
+}
+
+type FrameUnwindInfoOut struct {
+	Info *api.FrameUnwindInfo
+}
+
+// FrameUnwindInfo returns the call frame information used to unwind the
+// stack frame arg.Scope: the rule used to compute its canonical frame
+// address (CFA) and the rules used to compute the registers of the
+// calling frame. The DeferredCall field of arg.Scope is ignored.
+func (s *RPCServer) FrameUnwindInfo(arg FrameUnwindInfoIn, out *FrameUnwindInfoOut) error {
+	info, err := s.debugger.FrameUnwindInfo(arg.Scope.GoroutineID, arg.Scope.Frame)
+	if err != nil {
+		return err
+	}
+	out.Info = api.ConvertFrameUnwindInfo(info)
+	return nil
+}
+
+type DiagnosticsIn struct {
+}
+
+type DiagnosticsOut struct {
+	Diagnostics *api.Diagnostics
+}
+
+// Diagnostics returns a report of the internal state of the debugger at
+// the current stop, meant to be attached to bug reports: the raw registers
+// of the current thread, the call frame information of the top stack
+// frames, the expressions recently evaluated and the packets recently
+// exchanged with the debugging stub. The home directory and the name of
+// the user, as well as the contents of the target's memory, are redacted.
+func (s *RPCServer) Diagnostics(arg DiagnosticsIn, out *DiagnosticsOut) error {
+	var err error
+	out.Diagnostics, err = s.debugger.Diagnostics()
+	return err
+}
+
 type SetIn struct {
 	Scope  api.EvalScope
 	Symbol string
@@ -524,8 +1059,13 @@ type SetOut struct {
 
 // Set sets the value of a variable. Only numerical types and
 // pointers are currently supported.
-func (s *RPCServer) Set(arg SetIn, out *SetOut) error {
-	return s.debugger.SetVariableInScope(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Symbol, arg.Value)
+// The writes made to the memory of the target are recorded, see
+// ListMemoryWrites.
+func (s *RPCServer) Set(arg SetIn, cb service.RPCCallback) {
+	// The server is not ready to receive other requests until the
+	// variable is set, like for synchronous methods.
+	err := s.debugger.SetVariableInScope(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Symbol, arg.Value, cb.Client())
+	cb.Return(SetOut{}, err)
 }
 
 type ListSourcesIn struct {
@@ -582,6 +1122,54 @@ func (s *RPCServer) ListTypes(arg ListTypesIn, out *ListTypesOut) error {
 	return nil
 }
 
+type DescribeTypeIn struct {
+	Type string
+}
+
+type DescribeTypeOut struct {
+	Layout *api.TypeLayout
+}
+
+// DescribeType returns the size, the alignment and the layout of the
+// fields of a type. arg.Type is a type expression, like the ones used in
+// type conversions, or one of the names returned by ListTypes.
+func (s *RPCServer) DescribeType(arg DescribeTypeIn, out *DescribeTypeOut) error {
+	l, err := s.debugger.TypeLayout(arg.Type)
+	if err != nil {
+		return err
+	}
+	out.Layout = api.ConvertTypeLayout(l)
+	return nil
+}
+
+type ListSymbolsIn struct {
+	Filter string
+	// Kind, if not empty, is either "function" or "variable" and restricts
+	// the list to functions or package variables.
+	Kind string
+}
+
+type ListSymbolsOut struct {
+	Symbols []api.Symbol
+}
+
+// ListSymbols lists the functions and the package variables whose name
+// matches arg.Filter with their address and size, without loading the
+// values of the variables.
+func (s *RPCServer) ListSymbols(arg ListSymbolsIn, out *ListSymbolsOut) error {
+	switch arg.Kind {
+	case "", api.SymbolFunction, api.SymbolVariable:
+	default:
+		return fmt.Errorf("unknown symbol kind %q", arg.Kind)
+	}
+	syms, err := s.debugger.Symbols(arg.Filter, arg.Kind)
+	if err != nil {
+		return err
+	}
+	out.Symbols = syms
+	return nil
+}
+
 type ListGoroutinesIn struct {
 	Start int
 	Count int
@@ -618,10 +1206,19 @@ type ListGoroutinesOut struct {
 //    ListGoroutineFilter{ Kind: ListGoroutinesFilterLabel, Negated: false, Arg: "key=value" }
 // this filter will only return goroutines that have a key=value label.
 //
+// Filters of kind GoroutineStack match goroutines that have at least one
+// frame in their stacktrace, formatted as above, matching the regular
+// expression in Arg. Filters of kind GoroutineStackArgs also match the
+// arguments of each frame, formatted as name=value.
+//
 // If arg.GroupBy is not GoroutineFieldNone then the goroutines will
 // be grouped with the specified criterion.
 // If the value of arg.GroupBy is GoroutineLabel goroutines will
 // be grouped by the value of the label with key GroupByKey.
+// If the value of arg.GroupBy is GoroutineStack goroutines with identical
+// stacktraces are grouped together, the name of each group is the
+// stacktrace, one frame per line, and groups are sorted by decreasing
+// size.
 // For each group a maximum of MaxExamples example goroutines are
 // returned, as well as the total number of goroutines in the group.
 func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut) error {
@@ -632,7 +1229,10 @@ func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut)
 	if err != nil {
 		return err
 	}
-	gs = s.debugger.FilterGoroutines(gs, arg.Filters)
+	gs, err = s.debugger.FilterGoroutines(gs, arg.Filters)
+	if err != nil {
+		return err
+	}
 	gs, out.Groups, out.TooManyGroups = s.debugger.GroupGoroutines(gs, &arg.GoroutineGroupingOptions)
 	s.debugger.LockTarget()
 	defer s.debugger.UnlockTarget()
@@ -641,6 +1241,69 @@ func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut)
 	return nil
 }
 
+type ListTerminatedGoroutinesIn struct {
+}
+
+type ListTerminatedGoroutinesOut struct {
+	Goroutines []api.TerminatedGoroutine
+}
+
+// ListTerminatedGoroutines lists the goroutines that existed the last
+// time all goroutines were listed at a previous stop and terminated since.
+// The list is only available after all goroutines have been listed with
+// ListGoroutines at the current stop.
+func (s *RPCServer) ListTerminatedGoroutines(arg ListTerminatedGoroutinesIn, out *ListTerminatedGoroutinesOut) error {
+	out.Goroutines = s.debugger.TerminatedGoroutines()
+	return nil
+}
+
+type SetGoroutineNameIn struct {
+	ID   int
+	Name string
+}
+
+type SetGoroutineNameOut struct {
+}
+
+// SetGoroutineName names a goroutine. Named goroutines are listed first by
+// ListGoroutines and their name is returned with them. An empty name
+// removes the name of the goroutine.
+func (s *RPCServer) SetGoroutineName(arg SetGoroutineNameIn, out *SetGoroutineNameOut) error {
+	return s.debugger.SetGoroutineName(arg.ID, arg.Name)
+}
+
+type ListAnnotationsIn struct {
+}
+
+type ListAnnotationsOut struct {
+	Annotations []api.Annotation
+}
+
+// ListAnnotations lists the names and notes assigned by the user to
+// goroutines and ranges of addresses. Notes on breakpoints are returned
+// with the breakpoints.
+func (s *RPCServer) ListAnnotations(arg ListAnnotationsIn, out *ListAnnotationsOut) error {
+	out.Annotations = s.debugger.Annotations()
+	return nil
+}
+
+type SetAnnotationIn struct {
+	Annotation api.Annotation
+}
+
+type SetAnnotationOut struct {
+}
+
+// SetAnnotation assigns a name and a note to a goroutine, if
+// Annotation.GoroutineID is not zero, or to the Annotation.Size bytes
+// starting at Annotation.Addr. The annotation replaces the annotation of
+// the same goroutine or starting at the same address, if both the name and
+// the note are empty the annotation is removed.
+// Notes on breakpoints are set with AmendBreakpoint.
+func (s *RPCServer) SetAnnotation(arg SetAnnotationIn, out *SetAnnotationOut) error {
+	return s.debugger.SetAnnotation(arg.Annotation)
+}
+
 type AttachedToExistingProcessIn struct {
 }
 
@@ -722,6 +1385,33 @@ func (c *RPCServer) Disassemble(arg DisassembleIn, out *DisassembleOut) error {
 	return nil
 }
 
+type PseudoSourceIn struct {
+	PC uint64
+}
+
+type PseudoSourceOut struct {
+	Function string
+	Lines    []api.PseudoSourceLine
+}
+
+// PseudoSource returns a structured pseudo-source listing of the function
+// containing PC, to be shown in place of its source code when it is not
+// available: its instructions are split in basic blocks and in lines
+// corresponding to the lines of the source code, calls and conditional
+// jumps are decoded.
+func (c *RPCServer) PseudoSource(arg PseudoSourceIn, out *PseudoSourceOut) error {
+	fnname, lines, err := c.debugger.PseudoSource(arg.PC)
+	if err != nil {
+		return err
+	}
+	out.Function = fnname
+	out.Lines = make([]api.PseudoSourceLine, len(lines))
+	for i := range lines {
+		out.Lines[i] = api.ConvertPseudoSourceLine(lines[i])
+	}
+	return nil
+}
+
 type RecordedIn struct {
 }
 
@@ -784,13 +1474,15 @@ type IsMulticlientIn struct {
 }
 
 type IsMulticlientOut struct {
-	// IsMulticlient returns true if the headless instance was started with --accept-multiclient
+	// IsMulticlient returns true if the headless instance was started with
+	// --accept-multiclient, or with --continue, and keeps running when its
+	// clients disconnect.
 	IsMulticlient bool
 }
 
 func (s *RPCServer) IsMulticlient(arg IsMulticlientIn, out *IsMulticlientOut) error {
 	*out = IsMulticlientOut{
-		IsMulticlient: s.config.AcceptMulti,
+		IsMulticlient: s.config.AcceptMulti || s.config.KeepAlive,
 	}
 	return nil
 }
@@ -971,6 +1663,43 @@ func (s *RPCServer) DumpCancel(arg DumpCancelIn, out *DumpCancelOut) error {
 	return s.debugger.DumpCancel()
 }
 
+type ListArtifactsIn struct {
+}
+
+type ListArtifactsOut struct {
+	Artifacts []api.Artifact
+}
+
+// ListArtifacts returns the files created by the server, like core dumps,
+// that can be downloaded with ReadArtifact.
+func (s *RPCServer) ListArtifacts(arg ListArtifactsIn, out *ListArtifactsOut) error {
+	var err error
+	out.Artifacts, err = s.debugger.Artifacts()
+	return err
+}
+
+type ReadArtifactIn struct {
+	Path   string
+	Offset int64
+	Length int
+}
+
+type ReadArtifactOut struct {
+	Data []byte
+	// Size is the total size of the artifact.
+	Size int64
+}
+
+// ReadArtifact reads arg.Length bytes starting at arg.Offset from the
+// artifact arg.Path. At most debugger.MaxArtifactChunk bytes are returned
+// by each call, a shorter result does not mean that the end of the file
+// was reached.
+func (s *RPCServer) ReadArtifact(arg ReadArtifactIn, out *ReadArtifactOut) error {
+	var err error
+	out.Data, out.Size, err = s.debugger.ReadArtifact(arg.Path, arg.Offset, arg.Length)
+	return err
+}
+
 type CreateWatchpointIn struct {
 	Scope api.EvalScope
 	Expr  string
@@ -986,3 +1715,93 @@ func (s *RPCServer) CreateWatchpoint(arg CreateWatchpointIn, out *CreateWatchpoi
 	out.Breakpoint, err = s.debugger.CreateWatchpoint(arg.Scope.GoroutineID, arg.Scope.Frame, arg.Scope.DeferredCall, arg.Expr, arg.Type)
 	return err
 }
+
+type GetProjectIn struct {
+}
+
+type GetProjectOut struct {
+	Project api.Project
+}
+
+// GetProject returns the launch parameters and the breakpoints of the
+// debugging session, to be saved in a project file by the client.
+func (s *RPCServer) GetProject(arg GetProjectIn, out *GetProjectOut) error {
+	out.Project = *s.debugger.Project()
+	return nil
+}
+
+type WaitForEventsIn struct {
+	// After is the sequence number of the last event received by the
+	// client, zero to receive all the events kept by the server.
+	After int64
+	// Wait is the maximum number of milliseconds to wait for an event.
+	Wait int
+}
+
+type WaitForEventsOut struct {
+	Events []api.Event
+}
+
+// WaitForEvents returns the events of the debugging session (breakpoint
+// hits, the target stopping, resuming, writing output, exiting and
+// loading images) with a sequence number greater than arg.After, waiting
+// at most arg.Wait milliseconds for one to happen if there are none.
+// Calling it in a loop, with After set to the sequence number of the last
+// event received, lets clients follow the session while other requests are
+// served: the call does not block the connection.
+// Output events are only reported if the output of the target is
+// captured, see the --capture-output flag.
+func (s *RPCServer) WaitForEvents(arg WaitForEventsIn, cb service.RPCCallback) {
+	close(cb.SetupDoneChan())
+	cb.Return(WaitForEventsOut{Events: s.debugger.WaitForEvents(arg.After, time.Duration(arg.Wait)*time.Millisecond)}, nil)
+}
+
+type GetOutputIn struct {
+	// Offset is the offset of the first byte to return, a negative offset
+	// is relative to the end of the output.
+	Offset int64
+	// Max is the maximum number of bytes to return, zero for no limit.
+	Max int
+}
+
+type GetOutputOut struct {
+	Output api.TargetOutput
+}
+
+// GetOutput returns the output of the target kept by the server, starting
+// at arg.Offset, so that clients connecting late or reconnecting can show
+// what the target printed. Only the most recent output is kept, and only
+// if the output of the target is captured, see the --capture-output flag.
+func (s *RPCServer) GetOutput(arg GetOutputIn, out *GetOutputOut) error {
+	out.Output = *s.debugger.TargetOutput(arg.Offset, arg.Max)
+	return nil
+}
+
+type ListMemoryWritesIn struct {
+}
+
+type ListMemoryWritesOut struct {
+	Writes []api.MemoryWrite
+}
+
+// ListMemoryWrites returns the writes made by the debugger to the memory
+// of the target, by setting variables and calling functions, with the
+// client that requested them.
+func (s *RPCServer) ListMemoryWrites(arg ListMemoryWritesIn, out *ListMemoryWritesOut) error {
+	out.Writes = s.debugger.MemoryWrites()
+	return nil
+}
+
+type UndoMemoryWriteIn struct {
+	ID int
+}
+
+type UndoMemoryWriteOut struct {
+}
+
+// UndoMemoryWrite restores the memory overwritten by the write arg.ID,
+// listed by ListMemoryWrites. It fails if the memory was changed since the
+// write.
+func (s *RPCServer) UndoMemoryWrite(arg UndoMemoryWriteIn, cb service.RPCCallback) {
+	cb.Return(UndoMemoryWriteOut{}, s.debugger.UndoMemoryWrite(arg.ID, cb.Client()))
+}
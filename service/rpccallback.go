@@ -8,4 +8,8 @@ type RPCCallback interface {
 	// asynchornous method has completed setup and the server is ready to
 	// receive other requests.
 	SetupDoneChan() chan struct{}
+
+	// Client identifies the client that sent the request, it is the
+	// address of the client if it is known.
+	Client() string
 }
@@ -2,7 +2,9 @@ package rpccommon
 
 import (
 	"bytes"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -44,6 +46,10 @@ type ServerImpl struct {
 	// maps of served methods, one for each supported API.
 	methodMaps []map[string]*methodType
 	log        *logrus.Entry
+
+	// clients is the number of connected clients.
+	clientsMu sync.Mutex
+	clients   int
 }
 
 type RPCCallback struct {
@@ -52,6 +58,7 @@ type RPCCallback struct {
 	codec     rpc.ServerCodec
 	req       rpc.Request
 	setupDone chan struct{}
+	client    string
 }
 
 var _ service.RPCCallback = &RPCCallback{}
@@ -77,7 +84,7 @@ func NewServer(config *service.Config) *ServerImpl {
 	}
 	if config.Debugger.Foreground {
 		// Print listener address
-		logflags.WriteAPIListeningMessage(config.Listener.Addr().String())
+		logflags.WriteAPIListeningMessage(service.ListenAddress(config.Listener.Addr()))
 		logger.Debug("API server pid = ", os.Getpid())
 	}
 	return &ServerImpl{
@@ -91,10 +98,10 @@ func NewServer(config *service.Config) *ServerImpl {
 // Stop stops the JSON-RPC server.
 func (s *ServerImpl) Stop() error {
 	close(s.stopChan)
-	if s.config.AcceptMulti {
+	if s.config.AcceptMulti || s.config.KeepAlive {
 		s.listener.Close()
 	}
-	kill := s.config.Debugger.AttachPid == 0
+	kill := s.config.Debugger.AttachPid == 0 && s.config.Debugger.StubAddr == ""
 	return s.debugger.Detach(kill)
 }
 
@@ -152,9 +159,15 @@ func (s *ServerImpl) Run() error {
 				}
 			}
 
-			go s.serveJSONCodec(c)
-			if !s.config.AcceptMulti {
-				break
+			switch {
+			case s.config.AcceptMulti:
+				go s.serveJSONCodec(c)
+			case s.config.KeepAlive:
+				s.serveJSONCodec(c)
+				s.log.Debug("client disconnected, waiting for a new client")
+			default:
+				go s.serveJSONCodec(c)
+				return
 			}
 		}
 	}()
@@ -250,12 +263,42 @@ func suitableMethods(rcvr interface{}, methods map[string]*methodType, log *logr
 }
 
 func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
+	s.clientsMu.Lock()
+	s.clients++
+	s.clientsMu.Unlock()
 	defer func() {
-		if !s.config.AcceptMulti && s.config.DisconnectChan != nil {
+		s.clientsMu.Lock()
+		s.clients--
+		lastClient := s.clients == 0
+		s.clientsMu.Unlock()
+		if !s.config.AcceptMulti && !s.config.KeepAlive && s.config.DisconnectChan != nil {
 			close(s.config.DisconnectChan)
+		} else if lastClient && s.config.Debugger.AutoDetach > 0 && (s.config.Debugger.AttachPid != 0 || s.config.Debugger.StubAddr != "") {
+			// The target must not stay stopped with no client to resume
+			// it, detach from it. If it is running the auto-detach timer,
+			// armed when it stops, will.
+			go func() {
+				// A client could have connected in the meantime, keep
+				// clientsMu locked until the detach is done so that new
+				// clients do not see the target half detached.
+				s.clientsMu.Lock()
+				defer s.clientsMu.Unlock()
+				if s.clients != 0 || s.debugger.IsRunning() {
+					return
+				}
+				s.log.Warn("all clients disconnected, detaching")
+				if err := s.debugger.Detach(false); err != nil {
+					s.log.Errorf("could not detach: %v", err)
+				}
+			}()
 		}
 	}()
 
+	var client string
+	if conn, ok := conn.(net.Conn); ok {
+		client = conn.RemoteAddr().String()
+	}
+	authenticated := s.config.AuthToken == ""
 	sending := new(sync.Mutex)
 	codec := jsonrpc.NewServerCodec(conn)
 	var req rpc.Request
@@ -277,6 +320,11 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 			continue
 		}
 
+		if !authenticated && req.ServiceMethod != "RPCServer.Authenticate" {
+			s.sendResponse(sending, &req, &rpc.Response{}, nil, codec, "authentication required, call RPCServer.Authenticate first")
+			continue
+		}
+
 		var argv, replyv reflect.Value
 
 		// Decode the argument value.
@@ -324,6 +372,13 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 				s.log.Debugf("-> %T%s error: %q", replyv.Interface(), replyvbytes, errmsg)
 			}
 			s.sendResponse(sending, &req, &resp, replyv.Interface(), codec, errmsg)
+			if req.ServiceMethod == "RPCServer.Authenticate" {
+				if errmsg == "" {
+					authenticated = true
+				} else {
+					s.log.Warnf("authentication failed for connection from %s", client)
+				}
+			}
 			if req.ServiceMethod == "RPCServer.Detach" && s.config.DisconnectChan != nil {
 				close(s.config.DisconnectChan)
 				s.config.DisconnectChan = nil
@@ -334,7 +389,7 @@ func (s *ServerImpl) serveJSONCodec(conn io.ReadWriteCloser) {
 				s.log.Debugf("(async %d) <- %s(%T%s)", req.Seq, req.ServiceMethod, argv.Interface(), argvbytes)
 			}
 			function := mtype.method.Func
-			ctl := &RPCCallback{s, sending, codec, req, make(chan struct{})}
+			ctl := &RPCCallback{s, sending, codec, req, make(chan struct{}), client}
 			go func() {
 				defer func() {
 					if ierr := recover(); ierr != nil {
@@ -392,6 +447,10 @@ func (cb *RPCCallback) SetupDoneChan() chan struct{} {
 	return cb.setupDone
 }
 
+func (cb *RPCCallback) Client() string {
+	return cb.client
+}
+
 // GetVersion returns the version of delve as well as the API version
 // currently served.
 func (s *RPCServer) GetVersion(args api.GetVersionIn, out *api.GetVersionOut) error {
@@ -400,6 +459,20 @@ func (s *RPCServer) GetVersion(args api.GetVersionIn, out *api.GetVersionOut) er
 	return s.s.debugger.GetVersion(out)
 }
 
+// Authenticate authenticates the connection with the token the server
+// was started with. When the server requires authentication it refuses
+// every other request until this call succeeds, otherwise any token is
+// accepted.
+func (s *RPCServer) Authenticate(args api.AuthenticateIn, out *api.AuthenticateOut) error {
+	if s.s.config.AuthToken == "" {
+		return nil
+	}
+	if subtle.ConstantTimeCompare([]byte(args.Token), []byte(s.s.config.AuthToken)) != 1 {
+		return errors.New("authentication failed")
+	}
+	return nil
+}
+
 // Changes version of the API being served.
 func (s *RPCServer) SetApiVersion(args api.SetAPIVersionIn, out *api.SetAPIVersionOut) error {
 	if args.APIVersion < 2 {
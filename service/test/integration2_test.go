@@ -3,6 +3,7 @@ package service_test
 import (
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"net"
@@ -2407,6 +2408,60 @@ func TestGoroutinesGrouping(t *testing.T) {
 	})
 }
 
+func TestGoroutinesStackFilter(t *testing.T) {
+	withTestClient2("goroutinestackprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.stacktraceme"})
+		assertNoError(err, t, "CreateBreakpoint")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+
+		gs, _, _, _, err := c.ListGoroutinesWithFilter(0, 0, []api.ListGoroutinesFilter{{Kind: api.GoroutineStack, Arg: `in main\.agoroutine$`}}, nil)
+		assertNoError(err, t, "ListGoroutinesWithFilter (stack)")
+		if len(gs) != 10 {
+			t.Errorf("wrong number of goroutines returned by stack filter: %d (expected 10)", len(gs))
+		}
+
+		gs, _, _, _, err = c.ListGoroutinesWithFilter(0, 0, []api.ListGoroutinesFilter{{Kind: api.GoroutineStackArgs, Arg: `^i=3$`}}, nil)
+		assertNoError(err, t, "ListGoroutinesWithFilter (stack arguments)")
+		if len(gs) != 1 {
+			t.Errorf("wrong number of goroutines returned by stack arguments filter: %d (expected 1)", len(gs))
+		}
+
+		_, _, _, _, err = c.ListGoroutinesWithFilter(0, 0, []api.ListGoroutinesFilter{{Kind: api.GoroutineStack, Arg: `(`}}, nil)
+		if err == nil {
+			t.Errorf("invalid regular expression did not return an error")
+		}
+	})
+}
+
+func TestGoroutinesStackGrouping(t *testing.T) {
+	withTestClient2("goroutinestackprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.stacktraceme"})
+		assertNoError(err, t, "CreateBreakpoint")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+
+		gs, groups, _, _, err := c.ListGoroutinesWithFilter(0, 0, nil, &api.GoroutineGroupingOptions{GroupBy: api.GoroutineStack, MaxGroupMembers: 3, MaxGroups: 10})
+		assertNoError(err, t, "ListGoroutinesWithFilter (group by stack)")
+		if len(groups) == 0 {
+			t.Fatal("no groups")
+		}
+		// The goroutines running main.agoroutine are all blocked at the same
+		// line, they are the biggest group.
+		if groups[0].Total != 10 || groups[0].Count != 3 || !strings.Contains(groups[0].Name, "in main.agoroutine") {
+			t.Errorf("wrong first group: %#v", groups[0])
+		}
+		for i := 1; i < len(groups); i++ {
+			if groups[i].Total > groups[i-1].Total {
+				t.Errorf("groups not sorted by size: %#v", groups)
+			}
+		}
+		if len(gs) != groups[len(groups)-1].Offset+groups[len(groups)-1].Count {
+			t.Errorf("wrong number of goroutines %d for groups %#v", len(gs), groups)
+		}
+	})
+}
+
 func TestLongStringArg(t *testing.T) {
 	// Test the ability to load more elements of a string argument, this could
 	// be broken if registerized variables are not handled correctly.
@@ -2441,3 +2496,632 @@ func TestLongStringArg(t *testing.T) {
 		}
 	})
 }
+
+func TestWaitForEvents(t *testing.T) {
+	withTestClient2("continuetestprog", t, func(c service.Client) {
+		bp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.sayhi"})
+		assertNoError(err, t, "CreateBreakpoint")
+
+		// Events are reported to clients waiting for them while the
+		// target runs.
+		done := make(chan []api.Event)
+		go func() {
+			events, err := c.WaitForEvents(0, 10*time.Second)
+			if err != nil {
+				t.Errorf("WaitForEvents: %v", err)
+			}
+			done <- events
+		}()
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+		events := <-done
+		if len(events) == 0 || events[0].Kind != api.EventResumed || events[0].Seq != 1 {
+			t.Fatalf("wrong first event: %#v", events)
+		}
+
+		events, err = c.WaitForEvents(0, 0)
+		assertNoError(err, t, "WaitForEvents")
+		kinds := map[string]int{}
+		for i, ev := range events {
+			if ev.Seq != int64(i+1) {
+				t.Errorf("wrong sequence number for event %d: %d", i, ev.Seq)
+			}
+			kinds[ev.Kind]++
+			if ev.Kind == api.EventBreakpoint && ev.BreakpointID != bp.ID {
+				t.Errorf("wrong breakpoint ID: %d", ev.BreakpointID)
+			}
+		}
+		if kinds[api.EventResumed] != 1 || kinds[api.EventStopped] != 1 || kinds[api.EventBreakpoint] != 1 || kinds[api.EventImageLoaded] == 0 {
+			t.Fatalf("wrong events: %#v", events)
+		}
+
+		last := events[len(events)-1].Seq
+		events, err = c.WaitForEvents(last, 10*time.Millisecond)
+		assertNoError(err, t, "WaitForEvents")
+		if len(events) != 0 {
+			t.Fatalf("unexpected events: %#v", events)
+		}
+
+		_, err = c.ClearBreakpoint(bp.ID)
+		assertNoError(err, t, "ClearBreakpoint")
+		state = <-c.Continue()
+		if !state.Exited {
+			t.Fatalf("target did not exit: %#v", state)
+		}
+		events, err = c.WaitForEvents(last, 0)
+		assertNoError(err, t, "WaitForEvents")
+		if len(events) != 2 || events[0].Kind != api.EventResumed || events[1].Kind != api.EventExited {
+			t.Fatalf("wrong events: %#v", events)
+		}
+	})
+}
+
+func TestStartCommand(t *testing.T) {
+	withTestClient2("loopprog", t, func(c service.Client) {
+		token, err := c.StartCommand(&api.DebuggerCommand{Name: api.Continue})
+		assertNoError(err, t, "StartCommand")
+
+		// The command runs until it is cancelled, other requests are served
+		// on the same connection in the meantime.
+		_, done, err := c.CommandResult(token, 100*time.Millisecond)
+		assertNoError(err, t, "CommandResult")
+		if done {
+			t.Fatal("command finished before being cancelled")
+		}
+		state, err := c.GetStateNonBlocking()
+		assertNoError(err, t, "GetStateNonBlocking")
+		if !state.Running {
+			t.Fatal("target not running")
+		}
+
+		assertNoError(c.CancelCommand(token), t, "CancelCommand")
+		state, done, err = c.CommandResult(token, 0)
+		assertNoError(err, t, "CommandResult")
+		if !done || state.Exited || state.Running {
+			t.Fatalf("wrong result after CancelCommand: %v %#v", done, state)
+		}
+
+		// The token of a command whose result was returned is forgotten.
+		if _, _, err := c.CommandResult(token, 0); err == nil {
+			t.Fatal("CommandResult succeeded for a returned command")
+		}
+	})
+}
+
+func TestEvalBatch(t *testing.T) {
+	withTestClient2("continuetestprog", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.sayhi"})
+		assertNoError(err, t, "CreateBreakpoint")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+
+		results, err := c.EvalBatch(api.EvalScope{GoroutineID: -1}, []api.EvalBatchExpr{
+			{Expr: "1 + 2"},
+			{Expr: "nonexistent"},
+			{Expr: "1 + 2", Scope: &api.EvalScope{GoroutineID: 1000}},
+			{Expr: "3 * 3", Scope: &api.EvalScope{GoroutineID: -1, Frame: 1}},
+		}, normalLoadConfig)
+		assertNoError(err, t, "EvalBatch")
+		if len(results) != 4 {
+			t.Fatalf("wrong number of results: %d", len(results))
+		}
+		for i, tc := range []struct {
+			value string
+			err   bool
+		}{{"3", false}, {"", true}, {"", true}, {"9", false}} {
+			r := results[i]
+			if tc.err {
+				if r.Err == "" || r.Variable != nil {
+					t.Errorf("%d: expected an error, got %#v", i, r)
+				}
+				continue
+			}
+			if r.Err != "" || r.Variable == nil || r.Variable.Value != tc.value {
+				t.Errorf("%d: expected %s, got %#v", i, tc.value, r)
+			}
+		}
+	})
+}
+
+func TestMemoryWrites(t *testing.T) {
+	withTestClient2("testvariables", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+
+		assertNoError(c.SetVariable(api.EvalScope{GoroutineID: -1}, "a2", "8"), t, "SetVariable()")
+
+		writes, err := c.ListMemoryWrites()
+		assertNoError(err, t, "ListMemoryWrites")
+		if len(writes) != 1 {
+			t.Fatalf("wrong number of writes: %#v", writes)
+		}
+		w := writes[0]
+		if w.ID != 1 || w.Old == nil || len(w.Old) != len(w.New) || w.Origin != "set a2 = 8" || w.Client == "" || w.Undone {
+			t.Fatalf("wrong write: %#v", w)
+		}
+
+		assertNoError(c.UndoMemoryWrite(w.ID), t, "UndoMemoryWrite")
+		a2, err := c.EvalVariable(api.EvalScope{GoroutineID: -1}, "a2", normalLoadConfig)
+		assertNoError(err, t, "EvalVariable")
+		if a2.Value != "6" {
+			t.Errorf("wrong value of a2 after undo: %s", a2.Value)
+		}
+		assertError(c.UndoMemoryWrite(w.ID), t, "UndoMemoryWrite (second time)")
+
+		writes, err = c.ListMemoryWrites()
+		assertNoError(err, t, "ListMemoryWrites")
+		if len(writes) != 2 || !writes[0].Undone || writes[1].Origin != "undo write 1" {
+			t.Fatalf("wrong writes after undo: %#v", writes)
+		}
+	})
+}
+
+func TestListFileBreakpoints(t *testing.T) {
+	withTestClient2("testprog", t, func(c service.Client) {
+		bp1, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.helloworld", Line: 1})
+		assertNoError(err, t, "CreateBreakpoint")
+		bp2, err := c.CreateBreakpoint(&api.Breakpoint{File: bp1.File, Line: 20, Cond: "i == 3"})
+		assertNoError(err, t, "CreateBreakpoint")
+		_, err = c.CreateBreakpoint(&api.Breakpoint{File: bp1.File, Line: 3})
+		assertError(err, t, "CreateBreakpoint on import")
+
+		fbps, err := c.ListFileBreakpoints(bp1.File)
+		assertNoError(err, t, "ListFileBreakpoints")
+		lines := make(map[int]bool)
+		for _, line := range fbps.Lines {
+			lines[line] = true
+		}
+		for _, line := range []int{10, 14, 18, 19, 20} {
+			if !lines[line] {
+				t.Errorf("line %d missing from %v", line, fbps.Lines)
+			}
+		}
+		for _, line := range []int{3, 7, 16} {
+			if lines[line] {
+				t.Errorf("line %d should not be in %v", line, fbps.Lines)
+			}
+		}
+		if len(fbps.Breakpoints) != 2 || fbps.Breakpoints[0].ID != bp1.ID || fbps.Breakpoints[0].Line != 10 || fbps.Breakpoints[1].ID != bp2.ID || fbps.Breakpoints[1].Cond != "i == 3" {
+			t.Errorf("wrong breakpoints: %#v", fbps.Breakpoints)
+		}
+
+		_, err = c.ListFileBreakpoints("/nonexistent.go")
+		assertError(err, t, "ListFileBreakpoints on nonexistent file")
+	})
+}
+
+func TestEvalChunk(t *testing.T) {
+	withTestClient2("longstrings", t, func(c service.Client) {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+		scope := api.EvalScope{GoroutineID: -1}
+
+		chunk, err := c.EvalChunk(scope, "s4097", 4000, 200, normalLoadConfig)
+		assertNoError(err, t, "EvalChunk")
+		if chunk.Len != 4097 || chunk.Offset != 4000 || string(chunk.Data) != strings.Repeat("x", 97) {
+			t.Errorf("wrong chunk: %#v", chunk)
+		}
+
+		chunk, err = c.EvalChunk(scope, "s4097", 5000, 200, normalLoadConfig)
+		assertNoError(err, t, "EvalChunk (past the end)")
+		if chunk.Len != 4097 || len(chunk.Data) != 0 {
+			t.Errorf("wrong chunk past the end: %#v", chunk)
+		}
+
+		_, err = c.EvalChunk(scope, "len(s4097)", 0, 10, normalLoadConfig)
+		assertError(err, t, "EvalChunk (not a sequence)")
+
+		path := filepath.Join(t.TempDir(), "s4097")
+		n, err := c.SaveVariable(scope, "s4097", path)
+		assertNoError(err, t, "SaveVariable")
+		buf, err := os.ReadFile(path)
+		assertNoError(err, t, "ReadFile")
+		if n != 4097 || string(buf) != strings.Repeat("x", 4097) {
+			t.Errorf("wrong content saved: %d bytes written, %d bytes read", n, len(buf))
+		}
+	})
+}
+
+func TestDescribeTypeAndListSymbols(t *testing.T) {
+	withTestClient2("fncall", t, func(c service.Client) {
+		l, err := c.DescribeType("main.astruct")
+		assertNoError(err, t, "DescribeType")
+		if l.Name != "main.astruct" || l.Kind != reflect.Struct || l.Size != 8 || len(l.Fields) != 1 {
+			t.Fatalf("wrong layout: %#v", l)
+		}
+		if f := l.Fields[0]; f.Name != "X" || f.Type != "int" || f.Offset != 0 || f.Size != 8 {
+			t.Errorf("wrong field: %#v", f)
+		}
+
+		l, err = c.DescribeType("[]main.astruct")
+		assertNoError(err, t, "DescribeType")
+		if l.Kind != reflect.Slice || l.Elem != "main.astruct" || len(l.Fields) != 3 {
+			t.Errorf("wrong slice layout: %#v", l)
+		}
+
+		_, err = c.DescribeType("main.nonexistent")
+		assertError(err, t, "DescribeType (nonexistent)")
+
+		syms, err := c.ListSymbols(`^main\.call0$`, api.SymbolFunction)
+		assertNoError(err, t, "ListSymbols")
+		if len(syms) != 1 || syms[0].Kind != api.SymbolFunction || syms[0].Addr == 0 || syms[0].Size <= 0 || !strings.HasSuffix(syms[0].File, "fncall.go") {
+			t.Errorf("wrong function symbols: %#v", syms)
+		}
+
+		syms, err = c.ListSymbols(`^main\.globalPA2$`, "")
+		assertNoError(err, t, "ListSymbols")
+		if len(syms) != 1 || syms[0].Kind != api.SymbolVariable || syms[0].Addr == 0 || syms[0].Type != "*main.a2struct" || syms[0].Size != 8 {
+			t.Errorf("wrong variable symbols: %#v", syms)
+		}
+	})
+}
+
+func TestDiagnosticsAsyncPreemptSignals(t *testing.T) {
+	if runtime.GOOS == "windows" || runtime.GOOS == "freebsd" || runtime.GOOS == "darwin" {
+		t.Skip("asynchronous preemption disabled")
+	}
+	protest.AllowRecording(t)
+	withTestClient2("asyncpreempt", t, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.done", Line: -1})
+		assertNoError(err, t, "CreateBreakpoint")
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+
+		r, err := c.Diagnostics()
+		assertNoError(err, t, "Diagnostics")
+		if r.AsyncPreemptSignals == nil {
+			t.Logf("asynchronous preemption signals not counted")
+		} else {
+			t.Logf("asynchronous preemption signals hidden: %d", *r.AsyncPreemptSignals)
+		}
+		if testBackend == "native" && runtime.GOOS == "linux" && (r.AsyncPreemptSignals == nil || *r.AsyncPreemptSignals == 0) {
+			t.Errorf("expected asynchronous preemption signals to be counted")
+		}
+	})
+}
+
+func TestAuthToken(t *testing.T) {
+	if testBackend == "rr" {
+		t.Skip("recording not allowed for TestAuthToken")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assertNoError(err, t, "Listen")
+	serverDone := make(chan struct{})
+	go func() {
+		defer close(serverDone)
+		defer listener.Close()
+		disconnectChan := make(chan struct{})
+		server := rpccommon.NewServer(&service.Config{
+			Listener:       listener,
+			ProcessArgs:    []string{protest.BuildFixture("testvariables2", 0).Path},
+			AcceptMulti:    true,
+			AuthToken:      "secret",
+			DisconnectChan: disconnectChan,
+			Debugger: debugger.Config{
+				Backend:     testBackend,
+				ExecuteKind: debugger.ExecutingGeneratedTest,
+			},
+		})
+		if err := server.Run(); err != nil {
+			panic(err)
+		}
+		<-disconnectChan
+		server.Stop()
+	}()
+
+	// requests of clients that did not authenticate are refused
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assertNoError(err, t, "Dial")
+	client1 := jsonrpc.NewClient(conn)
+	err = client1.Call("RPCServer.ProcessPid", rpc2.ProcessPidIn{}, &rpc2.ProcessPidOut{})
+	if err == nil || !strings.Contains(err.Error(), "authentication required") {
+		t.Errorf("wrong error for unauthenticated request: %v", err)
+	}
+	client1.Close()
+
+	conn, err = net.Dial("tcp", listener.Addr().String())
+	assertNoError(err, t, "Dial")
+	_, err = rpc2.NewAuthenticatedClient(conn, "wrong")
+	if err == nil || !strings.Contains(err.Error(), "authentication failed") {
+		t.Errorf("wrong error for wrong token: %v", err)
+	}
+
+	conn, err = net.Dial("tcp", listener.Addr().String())
+	assertNoError(err, t, "Dial")
+	client2, err := rpc2.NewAuthenticatedClient(conn, "secret")
+	assertNoError(err, t, "NewAuthenticatedClient")
+	if pid := client2.ProcessPid(); pid == 0 {
+		t.Errorf("wrong pid %d", pid)
+	}
+	client2.Detach(true)
+	<-serverDone
+}
+
+func TestUnixSocketListener(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix domain sockets not supported")
+	}
+	if testBackend == "rr" {
+		t.Skip("recording not allowed for TestUnixSocketListener")
+	}
+	dir, err := ioutil.TempDir("", "dlv-socket")
+	assertNoError(err, t, "TempDir")
+	defer os.RemoveAll(dir)
+	addrs := []string{"unix:" + filepath.Join(dir, "dlv.sock")}
+	if runtime.GOOS == "linux" {
+		addrs = append(addrs, fmt.Sprintf("unix:@dlv-test-%d", os.Getpid()))
+	}
+	for _, addr := range addrs {
+		t.Run(addr, func(t *testing.T) {
+			listener, err := service.Listen(addr)
+			assertNoError(err, t, "Listen")
+			if got := service.ListenAddress(listener.Addr()); got != addr {
+				t.Errorf("wrong listen address %q, expected %q", got, addr)
+			}
+			serverDone := make(chan struct{})
+			go func() {
+				defer close(serverDone)
+				defer listener.Close()
+				disconnectChan := make(chan struct{})
+				server := rpccommon.NewServer(&service.Config{
+					Listener:       listener,
+					ProcessArgs:    []string{protest.BuildFixture("testvariables2", 0).Path},
+					DisconnectChan: disconnectChan,
+					Debugger: debugger.Config{
+						Backend:     testBackend,
+						ExecuteKind: debugger.ExecutingGeneratedTest,
+					},
+				})
+				if err := server.Run(); err != nil {
+					panic(err)
+				}
+				<-disconnectChan
+				server.Stop()
+			}()
+
+			conn, err := service.Dial(addr)
+			assertNoError(err, t, "Dial")
+			client := rpc2.NewClientFromConn(conn)
+			if pid := client.ProcessPid(); pid == 0 {
+				t.Errorf("wrong pid %d", pid)
+			}
+			client.Detach(true)
+			<-serverDone
+		})
+	}
+}
+
+// withFollowForkClient starts a debugger following the forks of the
+// followfork fixture, started with args.
+func withFollowForkClient(t *testing.T, args []string, fn func(c service.Client)) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" || testBackend != "native" {
+		t.Skip("following forks is only supported by the native backend on linux")
+	}
+	listener, clientConn := service.ListenerPipe()
+	defer listener.Close()
+	fixture := protest.BuildFixture("followfork", 0)
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		ProcessArgs: append([]string{fixture.Path}, args...),
+		Debugger: debugger.Config{
+			Backend:     testBackend,
+			ExecuteKind: debugger.ExecutingGeneratedFile,
+			FollowFork:  true,
+		},
+	})
+	if err := server.Run(); err != nil {
+		t.Fatal(err)
+	}
+	client := rpc2.NewClientFromConn(clientConn)
+	defer client.Detach(true)
+	fn(client)
+}
+
+func TestFollowFork(t *testing.T) {
+	withFollowForkClient(t, nil, func(c service.Client) {
+		_, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.child"})
+		assertNoError(err, t, "CreateBreakpoint(main.child)")
+		_, err = c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.parent"})
+		assertNoError(err, t, "CreateBreakpoint(main.parent)")
+
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+		if state.CurrentThread.Function.Name() != "main.parent" {
+			t.Fatalf("parent stopped in %s", state.CurrentThread.Function.Name())
+		}
+		parentPid := c.ProcessPid()
+
+		targets, err := c.ListTargets()
+		assertNoError(err, t, "ListTargets")
+		if len(targets) != 2 || targets[0].Pid != parentPid || !targets[0].Current || targets[1].ParentPid != parentPid || targets[1].Current {
+			t.Fatalf("wrong targets %#v", targets)
+		}
+		childPid := targets[1].Pid
+
+		// the child is stopped until it is resumed, independently of the
+		// parent
+		_, err = c.SwitchThread(targets[1].CurrentThread.ID)
+		assertNoError(err, t, "SwitchThread(child)")
+		if pid := c.ProcessPid(); pid != childPid {
+			t.Fatalf("current process %d, expected %d", pid, childPid)
+		}
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue(child)")
+		if state.CurrentThread.Function.Name() != "main.child" {
+			t.Fatalf("child stopped in %s", state.CurrentThread.Function.Name())
+		}
+		state = <-c.Continue()
+		if !state.Exited || state.ExitStatus != 0 {
+			t.Fatalf("child did not exit: %#v", state)
+		}
+
+		targets, err = c.ListTargets()
+		assertNoError(err, t, "ListTargets")
+		if len(targets) != 1 || targets[0].Pid != parentPid || targets[0].Current {
+			t.Fatalf("wrong targets after the child exited %#v", targets)
+		}
+		_, err = c.SwitchThread(targets[0].CurrentThread.ID)
+		assertNoError(err, t, "SwitchThread(parent)")
+		state = <-c.Continue()
+		if !state.Exited || state.Pid != parentPid {
+			t.Fatalf("parent did not exit: %#v", state)
+		}
+	})
+}
+
+func TestFollowExec(t *testing.T) {
+	withFollowForkClient(t, []string{"exec"}, func(c service.Client) {
+		bp, err := c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.execed"})
+		assertNoError(err, t, "CreateBreakpoint(main.execed)")
+		pid := c.ProcessPid()
+
+		// the process is stopped after exec, debugged by a new target
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+		if state.Exited || c.ProcessPid() != pid {
+			t.Fatalf("wrong state after exec: %#v", state)
+		}
+		targets, err := c.ListTargets()
+		assertNoError(err, t, "ListTargets")
+		if len(targets) != 1 || targets[0].Pid != pid || targets[0].StopReason != "exec" {
+			t.Fatalf("wrong targets %#v", targets)
+		}
+
+		state = <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+		if state.CurrentThread.Breakpoint == nil || state.CurrentThread.Breakpoint.ID != bp.ID {
+			t.Fatalf("breakpoint %d not hit after exec: %#v", bp.ID, state.CurrentThread)
+		}
+	})
+}
+
+func TestAttachChildren(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" || testBackend != "native" {
+		t.Skip("attaching to child processes is only supported by the native backend on linux")
+	}
+	fixture := protest.BuildFixture("attachchildren", 0)
+	trigger := filepath.Join(fixture.BuildDir, fmt.Sprintf("attachchildren-%d", os.Getpid()))
+	defer os.Remove(trigger)
+	cmd := exec.Command(fixture.Path, "master", trigger)
+	stdout, err := cmd.StdoutPipe()
+	assertNoError(err, t, "StdoutPipe")
+	assertNoError(cmd.Start(), t, "Start")
+	// the children of the fixture are killed with it
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	// wait for the first worker to start
+	buf := make([]byte, len("ready\n"))
+	_, err = io.ReadFull(stdout, buf)
+	assertNoError(err, t, "reading the output of the fixture")
+
+	listener, clientConn := service.ListenerPipe()
+	defer listener.Close()
+	server := rpccommon.NewServer(&service.Config{
+		Listener: listener,
+		Debugger: debugger.Config{
+			AttachPid:      cmd.Process.Pid,
+			Backend:        testBackend,
+			AttachChildren: "worker$",
+		},
+	})
+	assertNoError(server.Run(), t, "Run")
+	c := rpc2.NewClientFromConn(clientConn)
+	defer c.Detach(false)
+
+	targets, err := c.ListTargets()
+	assertNoError(err, t, "ListTargets")
+	if len(targets) != 2 || targets[0].Pid != cmd.Process.Pid || targets[1].ParentPid != cmd.Process.Pid || targets[1].StopReason != "attached" {
+		t.Fatalf("wrong targets after attaching %#v", targets)
+	}
+
+	// future children are attached to if they match
+	_, err = c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.spawned"})
+	assertNoError(err, t, "CreateBreakpoint(main.spawned)")
+	_, err = c.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.work"})
+	assertNoError(err, t, "CreateBreakpoint(main.work)")
+	assertNoError(ioutil.WriteFile(trigger, nil, 0600), t, "WriteFile")
+	for i := 0; i < 2; i++ {
+		state := <-c.Continue()
+		assertNoError(state.Err, t, "Continue")
+		if state.CurrentThread.Function.Name() != "main.spawned" {
+			t.Fatalf("master stopped in %s", state.CurrentThread.Function.Name())
+		}
+	}
+	targets, err = c.ListTargets()
+	assertNoError(err, t, "ListTargets")
+	if len(targets) != 3 || targets[2].ParentPid != cmd.Process.Pid {
+		t.Fatalf("wrong targets after starting children %#v", targets)
+	}
+
+	_, err = c.SwitchThread(targets[2].CurrentThread.ID)
+	assertNoError(err, t, "SwitchThread")
+	state := <-c.Continue()
+	assertNoError(state.Err, t, "Continue(worker)")
+	if state.CurrentThread.Function.Name() != "main.work" {
+		t.Fatalf("worker stopped in %s", state.CurrentThread.Function.Name())
+	}
+}
+
+func TestAutoDetachLastClient(t *testing.T) {
+	// When the last client disconnects from a server started with
+	// --auto-detach and --accept-multiclient the attached process must be
+	// detached, and resumed, immediately.
+	if runtime.GOOS != "linux" || testBackend == "rr" {
+		t.Skip("N/A")
+	}
+	fixture := protest.BuildFixture("loopprog", 0)
+	cmd := exec.Command(fixture.Path)
+	stdout, err := cmd.StdoutPipe()
+	assertNoError(err, t, "StdoutPipe")
+	assertNoError(cmd.Start(), t, "Start")
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	output := make(chan struct{}, 1)
+	go func() {
+		buf := make([]byte, 1024)
+		for {
+			if _, err := stdout.Read(buf); err != nil {
+				return
+			}
+			select {
+			case output <- struct{}{}:
+			default:
+			}
+		}
+	}()
+	<-output
+
+	listener, clientConn := service.ListenerPipe()
+	defer listener.Close()
+	server := rpccommon.NewServer(&service.Config{
+		Listener:    listener,
+		APIVersion:  2,
+		AcceptMulti: true,
+		Debugger: debugger.Config{
+			AttachPid:  cmd.Process.Pid,
+			Backend:    testBackend,
+			AutoDetach: time.Hour,
+		},
+	})
+	assertNoError(server.Run(), t, "Run")
+	defer server.Stop()
+	c := rpc2.NewClientFromConn(clientConn)
+	state, err := c.GetState()
+	assertNoError(err, t, "GetState")
+	if state.Running {
+		t.Fatal("target running after attach")
+	}
+	// discard the output of the target before it was stopped
+	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-output:
+	default:
+	}
+
+	assertNoError(c.Disconnect(false), t, "Disconnect")
+	select {
+	case <-output:
+	case <-time.After(10 * time.Second):
+		t.Fatal("target not resumed after the last client disconnected")
+	}
+}
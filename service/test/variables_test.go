@@ -1288,6 +1288,27 @@ func TestCallFunction(t *testing.T) {
 		{`regabistacktest2(1, 2, 3, 4, 5, 6, 7, 8, 9, 10)`, []string{":int:3", ":int:5", ":int:7", ":int:9", ":int:11", ":int:13", ":int:15", ":int:17", ":int:19", ":int:11"}, nil},
 	}
 
+	var testcasesBoxing = []testCaseCallFunction{
+		// Automatic conversion of arguments to interfaces
+		{`describe(one)`, []string{`:string:"int 1"`}, nil},
+		{`describe(one+two)`, []string{`:string:"int 3"`}, nil},
+		{`describe(a)`, []string{`:string:"main.astruct {3}"`}, nil},
+		{`describe(pa)`, []string{`:string:"*main.astruct &{6}"`}, nil},
+		{`describe(vable_a)`, []string{`:string:"main.astruct {3}"`}, nil},
+		{`describe(2)`, []string{`:string:"int 2"`}, nil},
+		{`describe(2.5)`, []string{`:string:"float64 2.5"`}, nil},
+		{`describe("boxed")`, []string{`:string:"string boxed"`}, nil},
+		{`describeVRcvrable(a)`, []string{`:string:"1 + 3 = 4"`}, nil},
+		{`describeVRcvrable(pa)`, []string{`:string:"1 + 6 = 7"`}, nil},
+		{`describeVRcvrable(x)`, nil, errors.New("cannot use x as argument v in function main.describeVRcvrable: main.X does not implement main.VRcvrable or the conversion is never performed by the program")},
+
+		// Allocation of new objects
+		{`pa2 = new(main.astruct); pa2`, []string{`pa2:*main.astruct:*main.astruct {X: 0}`}, nil},
+		{`new(main.astruct).VRcvr(3)`, []string{`:string:"3 + 0 = 3"`}, nil},
+		{`describe(new(main.astruct))`, []string{`:string:"*main.astruct &{0}"`}, nil},
+		{`new(main.astruct, 2)`, nil, errors.New("wrong number of arguments to new: 2")},
+	}
+
 	withTestProcessArgs("fncall", t, ".", nil, protest.AllNonOptimized, func(p *proc.Target, fixture protest.Fixture) {
 		testCallFunctionSetBreakpoint(t, p, fixture)
 
@@ -1325,6 +1346,12 @@ func TestCallFunction(t *testing.T) {
 			}
 		}
 
+		if goversion.VersionAfterOrEqual(runtime.Version(), 1, 12) {
+			for _, tc := range testcasesBoxing {
+				testCallFunction(t, p, tc)
+			}
+		}
+
 		// LEAVE THIS AS THE LAST ITEM, IT BREAKS THE TARGET PROCESS!!!
 		testCallFunction(t, p, testCaseCallFunction{"-unsafe escapeArg(&a2)", nil, nil})
 	})
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Listen announces on addr, which can be specified as:
+//
+//	host:port                  a TCP address
+//	unix:/path/to/socket       a unix domain socket
+//	unix:@name                 an abstract unix domain socket (Linux only)
+//	npipe:///pipe/name         the named pipe \\.\pipe\name (Windows only)
+//
+// Unix domain sockets and named pipes are not reachable from other
+// machines and, unlike TCP ports, access to them can be restricted to the
+// user running Delve, which makes them preferable on multi-user machines.
+// Note that abstract unix domain sockets are not protected by file system
+// permissions.
+func Listen(addr string) (net.Listener, error) {
+	network, address, err := splitAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "npipe" {
+		return listenPipe(address)
+	}
+	return net.Listen(network, address)
+}
+
+// Dial connects to the server listening at addr, specified like for
+// Listen.
+func Dial(addr string) (net.Conn, error) {
+	return DialTimeout(addr, 0)
+}
+
+// DialTimeout is like Dial but fails if the connection can not be
+// established within the timeout, zero means no timeout.
+func DialTimeout(addr string, timeout time.Duration) (net.Conn, error) {
+	network, address, err := splitAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	if network == "npipe" {
+		return dialPipe(address, timeout)
+	}
+	return net.DialTimeout(network, address, timeout)
+}
+
+// ListenAddress returns the address of a listener returned by Listen in
+// the format accepted by Listen and Dial.
+func ListenAddress(addr net.Addr) string {
+	switch addr := addr.(type) {
+	case *net.UnixAddr:
+		return "unix:" + addr.Name
+	case pipeAddr:
+		return "npipe:" + strings.Replace(strings.TrimPrefix(string(addr), `\\.`), `\`, "/", -1)
+	}
+	return addr.String()
+}
+
+// IsTCPAddress returns true if addr, specified like for Listen, is a TCP
+// address.
+func IsTCPAddress(addr string) bool {
+	network, _, err := splitAddr(addr)
+	return err == nil && network == "tcp"
+}
+
+// splitAddr returns the network and the address, in the format used by
+// the net package, of addr.
+func splitAddr(addr string) (network, address string, err error) {
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		address = addr[len("unix:"):]
+		if address == "" {
+			return "", "", errors.New("missing unix domain socket path")
+		}
+		if strings.HasPrefix(address, "@") && runtime.GOOS != "linux" {
+			return "", "", errors.New("abstract unix domain sockets are only supported on Linux")
+		}
+		return "unix", address, nil
+	case strings.HasPrefix(addr, "npipe:"):
+		if runtime.GOOS != "windows" {
+			return "", "", errors.New("named pipes are only supported on Windows")
+		}
+		// npipe://host/pipe/name, an empty host is the local machine.
+		uri := strings.TrimPrefix(addr[len("npipe:"):], "//")
+		slash := strings.Index(uri, "/")
+		if slash < 0 || !strings.HasPrefix(uri[slash:], "/pipe/") || len(uri[slash:]) == len("/pipe/") {
+			return "", "", fmt.Errorf("malformed named pipe address %q, expected npipe:///pipe/name", addr)
+		}
+		host := uri[:slash]
+		if host == "" {
+			host = "."
+		}
+		return "npipe", `\\` + host + strings.Replace(uri[slash:], "/", `\`, -1), nil
+	}
+	return "tcp", addr, nil
+}
+
+// pipeAddr is the path of a named pipe.
+type pipeAddr string
+
+func (addr pipeAddr) Network() string { return "npipe" }
+func (addr pipeAddr) String() string  { return string(addr) }
@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package service
+
+import (
+	"errors"
+	"net"
+	"time"
+)
+
+func listenPipe(path string) (net.Listener, error) {
+	return nil, errors.New("named pipes are only supported on Windows")
+}
+
+func dialPipe(path string, timeout time.Duration) (net.Conn, error) {
+	return nil, errors.New("named pipes are only supported on Windows")
+}
@@ -0,0 +1,251 @@
+package service
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modkernel32          = windows.NewLazySystemDLL("kernel32.dll")
+	procCreateNamedPipeW = modkernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = modkernel32.NewProc("ConnectNamedPipe")
+)
+
+const (
+	_PIPE_ACCESS_DUPLEX             = 0x00000003
+	_FILE_FLAG_FIRST_PIPE_INSTANCE  = 0x00080000
+	_PIPE_TYPE_BYTE                 = 0x00000000
+	_PIPE_READMODE_BYTE             = 0x00000000
+	_PIPE_WAIT                      = 0x00000000
+	_PIPE_REJECT_REMOTE_CLIENTS     = 0x00000008
+	_PIPE_UNLIMITED_INSTANCES       = 255
+	_PIPE_BUFFER_SIZE               = 64 * 1024
+	_PIPE_DIAL_RETRY_INTERVAL       = 10 * time.Millisecond
+	_PIPE_DIAL_DEFAULT_MAX_DURATION = 2 * time.Second
+)
+
+var errPipeClosed = errors.New("use of closed named pipe")
+
+func createNamedPipe(path string, first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	openMode := uint32(_PIPE_ACCESS_DUPLEX | windows.FILE_FLAG_OVERLAPPED)
+	if first {
+		// Fail if the pipe already exists, so that another process can not
+		// receive the connections meant for us.
+		openMode |= _FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+	r, _, err := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(name)),
+		uintptr(openMode),
+		uintptr(_PIPE_TYPE_BYTE|_PIPE_READMODE_BYTE|_PIPE_WAIT|_PIPE_REJECT_REMOTE_CLIENTS),
+		_PIPE_UNLIMITED_INSTANCES,
+		_PIPE_BUFFER_SIZE,
+		_PIPE_BUFFER_SIZE,
+		0,
+		0)
+	if windows.Handle(r) == windows.InvalidHandle {
+		return windows.InvalidHandle, err
+	}
+	return windows.Handle(r), nil
+}
+
+// overlappedIO runs the overlapped operation op on h and waits for it to
+// complete.
+func overlappedIO(h windows.Handle, op func(*windows.Overlapped) error) (uint32, error) {
+	ev, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(ev)
+	o := &windows.Overlapped{HEvent: ev}
+	err = op(o)
+	if err != nil && err != windows.ERROR_IO_PENDING {
+		return 0, err
+	}
+	var n uint32
+	err = windows.GetOverlappedResult(h, o, &n, true)
+	return n, err
+}
+
+// pipeListener accepts connections on a named pipe. An instance of the
+// pipe is always kept open, waiting for the next client.
+type pipeListener struct {
+	path string
+
+	mu     sync.Mutex
+	next   windows.Handle
+	closed bool
+}
+
+func listenPipe(path string) (net.Listener, error) {
+	h, err := createNamedPipe(path, true)
+	if err != nil {
+		return nil, err
+	}
+	return &pipeListener{path: path, next: h}, nil
+}
+
+// Accept waits for a client to connect to the pipe.
+func (l *pipeListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil, errPipeClosed
+	}
+	h := l.next
+	l.mu.Unlock()
+
+	_, err := overlappedIO(h, func(o *windows.Overlapped) error {
+		r, _, err := procConnectNamedPipe.Call(uintptr(h), uintptr(unsafe.Pointer(o)))
+		if r != 0 {
+			return nil
+		}
+		return err
+	})
+	if err == windows.ERROR_PIPE_CONNECTED {
+		// The client connected between CreateNamedPipe and ConnectNamedPipe.
+		err = nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil, errPipeClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.next, err = createNamedPipe(l.path, false)
+	if err != nil {
+		l.next = windows.InvalidHandle
+		l.closed = true
+	}
+	return newPipeConn(h, l.path), nil
+}
+
+// Close stops listening on the pipe, a pending Accept is interrupted.
+func (l *pipeListener) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	windows.CancelIoEx(l.next, nil)
+	return windows.CloseHandle(l.next)
+}
+
+// Addr returns the path of the pipe.
+func (l *pipeListener) Addr() net.Addr {
+	return pipeAddr(l.path)
+}
+
+func dialPipe(path string, timeout time.Duration) (net.Conn, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	if timeout == 0 {
+		timeout = _PIPE_DIAL_DEFAULT_MAX_DURATION
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		h, err := windows.CreateFile(name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING, windows.FILE_FLAG_OVERLAPPED, 0)
+		if err == nil {
+			return newPipeConn(h, path), nil
+		}
+		// All instances of the pipe are busy while the server is creating
+		// the next one.
+		if err != windows.ERROR_PIPE_BUSY || time.Now().After(deadline) {
+			return nil, &net.OpError{Op: "dial", Net: "npipe", Addr: pipeAddr(path), Err: err}
+		}
+		time.Sleep(_PIPE_DIAL_RETRY_INTERVAL)
+	}
+}
+
+// pipeConn is a connection over a named pipe. Its handle is opened for
+// overlapped I/O so that reads and writes do not wait for each other.
+type pipeConn struct {
+	h    windows.Handle
+	path string
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func newPipeConn(h windows.Handle, path string) *pipeConn {
+	return &pipeConn{h: h, path: path, closed: make(chan struct{})}
+}
+
+func (c *pipeConn) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	n, err := overlappedIO(c.h, func(o *windows.Overlapped) error {
+		return windows.ReadFile(c.h, b, nil, o)
+	})
+	if err != nil {
+		return int(n), c.ioError(err)
+	}
+	if n == 0 {
+		return 0, io.EOF
+	}
+	return int(n), nil
+}
+
+func (c *pipeConn) Write(b []byte) (int, error) {
+	written := 0
+	for written < len(b) {
+		n, err := overlappedIO(c.h, func(o *windows.Overlapped) error {
+			return windows.WriteFile(c.h, b[written:], nil, o)
+		})
+		written += int(n)
+		if err != nil {
+			return written, c.ioError(err)
+		}
+	}
+	return written, nil
+}
+
+// ioError converts the errors returned when the pipe is closed, by us or
+// by the other end, to io.EOF.
+func (c *pipeConn) ioError(err error) error {
+	select {
+	case <-c.closed:
+		return errPipeClosed
+	default:
+	}
+	switch err {
+	case windows.ERROR_BROKEN_PIPE, windows.ERROR_PIPE_NOT_CONNECTED, windows.ERROR_NO_DATA, windows.ERROR_OPERATION_ABORTED:
+		return io.EOF
+	}
+	return err
+}
+
+func (c *pipeConn) Close() error {
+	err := errPipeClosed
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		windows.CancelIoEx(c.h, nil)
+		err = windows.CloseHandle(c.h)
+	})
+	return err
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return pipeAddr(c.path) }
+func (c *pipeConn) RemoteAddr() net.Addr { return pipeAddr(c.path) }
+
+var errPipeDeadline = errors.New("deadlines are not supported on named pipes")
+
+func (c *pipeConn) SetDeadline(t time.Time) error      { return errPipeDeadline }
+func (c *pipeConn) SetReadDeadline(t time.Time) error  { return errPipeDeadline }
+func (c *pipeConn) SetWriteDeadline(t time.Time) error { return errPipeDeadline }